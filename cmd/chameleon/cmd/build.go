@@ -19,22 +19,32 @@ var buildCmd = &cobra.Command{
 
 func init() {
 	buildCmd.Flags().StringP("output", "o", "", "output file path")
+	buildCmd.Flags().String("backend", "native", "how the host runs the plugin: native (shared object) or process (executable)")
+	addSymbolFlags(buildCmd)
 }
 
 // runBuild handles the plugin build process
 func runBuild(cmd *cobra.Command, args []string) error {
 	pluginDir := args[0]
 	outputPath, _ := cmd.Flags().GetString("output")
+	backend, _ := cmd.Flags().GetString("backend")
+	if backend != "native" && backend != "process" {
+		return fmt.Errorf("unknown backend %q, want native or process", backend)
+	}
 
 	if err := validatePluginDir(pluginDir); err != nil {
 		return err
 	}
 
-	if err := generator.Generate(pluginDir); err != nil {
+	if err := generator.GenerateWithOptions(pluginDir, generatorOptions(cmd)); err != nil {
 		return fmt.Errorf("failed to generate wrapper: %w", err)
 	}
 
-	if err := buildPlugin(pluginDir, outputPath); err != nil {
+	build := buildPlugin
+	if backend == "process" {
+		build = buildProcessPlugin
+	}
+	if err := build(pluginDir, outputPath); err != nil {
 		return fmt.Errorf("failed to build plugin: %w", err)
 	}
 
@@ -74,3 +84,21 @@ func buildPlugin(dir, output string) error {
 
 	return cmd.Run()
 }
+
+// buildProcessPlugin compiles the plugin into an executable the host runs as a child process
+func buildProcessPlugin(dir, output string) error {
+	if output == "" {
+		output = filepath.Join(dir, "plugin")
+	}
+
+	cmd := exec.Command("go", "build",
+		"-tags", generator.ProcessBuildTag,
+		"-o", output,
+		".",
+	)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
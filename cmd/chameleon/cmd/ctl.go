@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zyanho/chameleon/pkg/plugin"
+)
+
+// tokenEnv holds the admin token when --token is not given
+const tokenEnv = "CHAMELEON_ADMIN_TOKEN"
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control a running host through its admin API",
+	Long: `ctl talks to the admin API a host serves with Manager.ServeAdmin or
+Manager.AdminHandler. Mutating commands send the token from --token or the
+` + tokenEnv + ` environment variable.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Usage does not help with errors reported by the server
+		cmd.SilenceUsage = true
+		output, _ := cmd.Flags().GetString("output")
+		if output != "table" && output != "json" {
+			return fmt.Errorf("unknown output %q, want table or json", output)
+		}
+		return nil
+	},
+}
+
+var ctlPluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Inspect and reload loaded plugins",
+}
+
+var ctlPluginsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List loaded plugins",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var plugins []plugin.AdminPlugin
+		if err := newCtlClient(cmd).do(http.MethodGet, "/plugins", &plugins); err != nil {
+			return err
+		}
+		return printCtl(cmd, plugins, func(w io.Writer) {
+			fmt.Fprintln(w, "NAME\tVERSION\tSTATE\tREPLICAS\tCALLS\tPATH")
+			for _, p := range plugins {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", p.Name, p.Version, p.State, len(p.Replicas), p.RefCount, p.Path)
+			}
+		})
+	},
+}
+
+var ctlPluginsInfoCmd = &cobra.Command{
+	Use:   "info [name]",
+	Short: "Show a plugin with its circuit breaker and functions",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var detail plugin.AdminPluginDetail
+		if err := newCtlClient(cmd).do(http.MethodGet, pluginRoute(args[0], ""), &detail); err != nil {
+			return err
+		}
+		return printCtl(cmd, detail, func(w io.Writer) { printPluginDetail(w, detail) })
+	},
+}
+
+var ctlPluginsReloadCmd = &cobra.Command{
+	Use:   "reload [name]",
+	Short: "Load a plugin's file again, whatever version it holds",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var result plugin.AdminReloadResult
+		if err := newCtlClient(cmd).do(http.MethodPost, pluginRoute(args[0], "/reload"), &result); err != nil {
+			return err
+		}
+		return printCtl(cmd, result, func(w io.Writer) {
+			if result.Reloaded {
+				fmt.Fprintf(w, "Reloaded %s, now at version %s\n", result.Plugin.Name, result.Plugin.Version)
+			} else {
+				fmt.Fprintf(w, "%s is unchanged at version %s\n", result.Plugin.Name, result.Plugin.Version)
+			}
+		})
+	},
+}
+
+var ctlMetricsCmd = &cobra.Command{
+	Use:   "metrics [name]",
+	Short: "Show a plugin's call metrics by function",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var detail plugin.AdminPluginDetail
+		if err := newCtlClient(cmd).do(http.MethodGet, pluginRoute(args[0], ""), &detail); err != nil {
+			return err
+		}
+		return printCtl(cmd, detail.Metrics, func(w io.Writer) {
+			fmt.Fprintln(w, "FUNCTION\tCALLS\tERRORS\tTIMEOUTS\tAVG\tMIN\tMAX\tP99\tERROR AVG")
+			for _, name := range sortedKeys(detail.Metrics) {
+				mm := detail.Metrics[name]
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%v\t%v\t%v\t%v\t%v\n", name, mm.Count, mm.Errors, mm.Timeouts,
+					time.Duration(mm.AvgNs), time.Duration(mm.MinNs), time.Duration(mm.MaxNs),
+					time.Duration(mm.P99Ns), time.Duration(mm.ErrorAvgNs))
+			}
+		})
+	},
+}
+
+func init() {
+	flags := ctlCmd.PersistentFlags()
+	flags.String("addr", "localhost:9123", "address of the admin API, host:port or a URL")
+	flags.String("token", "", "admin token (default $"+tokenEnv+")")
+	flags.StringP("output", "o", "table", "output format: table or json")
+	flags.Duration("timeout", 30*time.Second, "how long to wait for the host")
+
+	ctlPluginsCmd.AddCommand(ctlPluginsListCmd, ctlPluginsInfoCmd, ctlPluginsReloadCmd)
+	ctlCmd.AddCommand(ctlPluginsCmd, ctlMetricsCmd)
+	rootCmd.AddCommand(ctlCmd)
+}
+
+// ctlClient sends admin API requests
+type ctlClient struct {
+	base   string
+	token  string
+	client *http.Client
+}
+
+func newCtlClient(cmd *cobra.Command) *ctlClient {
+	addr, _ := cmd.Flags().GetString("addr")
+	token, _ := cmd.Flags().GetString("token")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if token == "" {
+		token = os.Getenv(tokenEnv)
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &ctlClient{
+		base:   strings.TrimSuffix(addr, "/"),
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// do sends a request and decodes the JSON response into v. Error responses are returned
+// with the server's message.
+func (c *ctlClient) do(method, route string, v interface{}) error {
+	req, err := http.NewRequest(method, c.base+route, nil)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr plugin.AdminError
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			return fmt.Errorf("%s %s: %s", method, route, resp.Status)
+		}
+		return errors.New(apiErr.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// pluginRoute returns the admin route of a plugin
+func pluginRoute(name, suffix string) string {
+	return "/plugins/" + url.PathEscape(name) + suffix
+}
+
+// printCtl writes v as JSON or, for table output, with the table function
+func printCtl(cmd *cobra.Command, v interface{}, table func(w io.Writer)) error {
+	out := cmd.OutOrStdout()
+	if output, _ := cmd.Flags().GetString("output"); output == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+func printPluginDetail(w io.Writer, d plugin.AdminPluginDetail) {
+	fmt.Fprintf(w, "Name:\t%s\n", d.Name)
+	fmt.Fprintf(w, "Version:\t%s\n", d.Version)
+	fmt.Fprintf(w, "State:\t%s\n", d.State)
+	fmt.Fprintf(w, "Path:\t%s\n", d.Path)
+	if d.Hash != "" {
+		fmt.Fprintf(w, "Hash:\t%s\n", d.Hash)
+	}
+	if d.SourceURL != "" {
+		fmt.Fprintf(w, "Source:\t%s\n", d.SourceURL)
+	}
+	fmt.Fprintf(w, "Calls in flight:\t%d\n", d.RefCount)
+	if b := d.BreakerDetail; b.Enabled {
+		fmt.Fprintf(w, "Circuit breaker:\t%s, %d trips, %d calls rejected, %d failures, %d timeouts, %d consecutive failures\n",
+			b.State, b.Trips, b.Rejected, b.Failures, b.Timeouts, b.ConsecutiveFailures)
+		if b.Reason != "" {
+			fmt.Fprintf(w, "Tripped because:\t%s\n", b.Reason)
+		}
+		if b.NextProbeNs > 0 {
+			fmt.Fprintf(w, "Next probe in:\t%v\n", time.Duration(b.NextProbeNs).Round(time.Millisecond))
+		}
+	} else {
+		fmt.Fprintf(w, "Circuit breaker:\tdisabled\n")
+	}
+	if c := d.Concurrency; c.Limit > 0 {
+		fmt.Fprintf(w, "Concurrency limit:\t%d (%s), %d calls rejected\n", c.Limit, c.Mode, c.Rejected)
+		if c.BaselineNs > 0 {
+			fmt.Fprintf(w, "Baseline latency:\t%v\n", time.Duration(c.BaselineNs).Round(time.Microsecond))
+		}
+	}
+	if len(d.Replicas) > 1 {
+		for i, r := range d.Replicas {
+			fmt.Fprintf(w, "Replica %d:\t%s %s, breaker %s, %d calls in flight\n", i, r.Version, r.State, r.Breaker, r.RefCount)
+		}
+	}
+	fmt.Fprintf(w, "Functions:\t%s\n", strings.Join(d.Functions, ", "))
+	for _, name := range sortedKeys(d.Metrics) {
+		mm := d.Metrics[name]
+		fmt.Fprintf(w, "  %s:\tlast call %s, last success %s, last error %s\n", name,
+			formatLastTime(mm.LastCall), formatLastTime(mm.LastSuccess), formatLastTime(mm.LastError))
+		if mm.LastErrorMessage != "" {
+			fmt.Fprintf(w, "  \tlast error: %s\n", mm.LastErrorMessage)
+		}
+	}
+}
+
+// formatLastTime formats the time of a latest call, which may never have happened
+func formatLastTime(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+func sortedKeys(m map[string]plugin.AdminMethodMetrics) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
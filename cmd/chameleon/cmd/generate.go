@@ -10,10 +10,28 @@ var generateCmd = &cobra.Command{
 	Short: "Generate plugin wrapper code",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return generator.Generate(args[0])
+		return generator.GenerateWithOptions(args[0], generatorOptions(cmd))
 	},
 }
 
 func init() {
+	addSymbolFlags(generateCmd)
 	rootCmd.AddCommand(generateCmd)
 }
+
+// addSymbolFlags registers the flags controlling the exported symbol names
+func addSymbolFlags(cmd *cobra.Command) {
+	defaults := generator.DefaultOptions()
+	cmd.Flags().String("export-symbol", defaults.ExportSymbol, "name of the symbol holding the Bureau implementation")
+	cmd.Flags().String("functions-symbol", defaults.FunctionsSymbol, "name of the symbol the function map is emitted under")
+}
+
+// generatorOptions reads the symbol name flags
+func generatorOptions(cmd *cobra.Command) generator.Options {
+	exportSymbol, _ := cmd.Flags().GetString("export-symbol")
+	functionsSymbol, _ := cmd.Flags().GetString("functions-symbol")
+	return generator.Options{
+		ExportSymbol:    exportSymbol,
+		FunctionsSymbol: functionsSymbol,
+	}
+}
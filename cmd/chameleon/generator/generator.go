@@ -10,21 +10,39 @@ import (
 	"html/template"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// Options controls the names used in the generated wrapper
+type Options struct {
+	ExportSymbol    string // Symbol holding the Bureau implementation, defaults to "Export"
+	FunctionsSymbol string // Symbol the function map is emitted under, defaults to "Functions"
+}
+
+// DefaultOptions returns the options matching the host's default symbol names
+func DefaultOptions() Options {
+	return Options{
+		ExportSymbol:    "Export",
+		FunctionsSymbol: "Functions",
+	}
+}
+
 // pluginInfo stores plugin analysis information
 type pluginInfo struct {
-	Package    string         // Package name
-	PluginType string         // Plugin type name
-	Functions  []functionInfo // Exported function list
+	Package         string         // Package name
+	PluginType      string         // Plugin type name
+	Functions       []functionInfo // Exported function list
+	ExportSymbol    string         // Name of the Bureau symbol
+	FunctionsSymbol string         // Name of the function map symbol
+	HasMetadata     bool           // Whether the plugin already declares a Metadata variable
 }
 
 // functionInfo stores function metadata
 type functionInfo struct {
-	Name    string      // Function name
-	Params  []paramInfo // Parameter list
-	Results []paramInfo // Return value list
-	IsInit  bool        // Whether it's an Init method
+	Name     string      // Function name
+	Params   []paramInfo // Parameter list
+	Results  []paramInfo // Return value list
+	Variadic bool        // Whether the last parameter is variadic
 }
 
 // paramInfo stores parameter metadata
@@ -34,21 +52,20 @@ type paramInfo struct {
 	IsVariadic bool   // Whether it's a variadic parameter
 }
 
+// lifecycleMethods are Bureau methods that are never exported as plugin functions
+var lifecycleMethods = map[string]bool{
+	"Name":    true,
+	"Version": true,
+	"Init":    true,
+	"Free":    true,
+}
+
 // analyzeFuncDecl extracts function information from AST
 func analyzeFuncDecl(fn *ast.FuncDecl) functionInfo {
 	f := functionInfo{
 		Name: fn.Name.Name,
 	}
 
-	// Special handling for Bureau interface methods
-	switch f.Name {
-	case "Name", "Version", "Free":
-		return f
-	case "Init":
-		f.IsInit = true
-		return f
-	}
-
 	// Analyze method parameters
 	if fn.Type.Params != nil {
 		for _, param := range fn.Type.Params.List {
@@ -58,6 +75,7 @@ func analyzeFuncDecl(fn *ast.FuncDecl) functionInfo {
 
 			// Check if it's a variadic parameter
 			if _, ok := param.Type.(*ast.Ellipsis); ok {
+				f.Variadic = true
 				for _, name := range param.Names {
 					f.Params = append(f.Params, paramInfo{
 						Name:       name.Name,
@@ -111,34 +129,21 @@ import (
     "github.com/zyanho/chameleon/pkg/plugin"
 )
 
-// Functions exports plugin functions
-var Functions = map[string]plugin.InvokeFunc{
+{{- if not .HasMetadata }}
+// Metadata describes the plugin to the host; declare your own to fill it in
+var Metadata = plugin.Metadata{}
+{{ end }}
+// ChameleonAPIVersion declares the host API version this plugin was built against
+var ChameleonAPIVersion = plugin.APIVersion
+
+// {{ .FunctionsSymbol }} exports plugin functions
+var {{ .FunctionsSymbol }} = map[string]plugin.InvokeFunc{
     {{- range .Functions }}
     "{{ .Name }}": func(ctx context.Context, args ...interface{}) (interface{}, error) {
-        impl := Export.(*{{ $.PluginType }})
+        impl := {{ $.ExportSymbol }}.(*{{ $.PluginType }})
         
-        {{- if eq .Name "Name" }}
-        if len(args) != 0 {
-            return nil, fmt.Errorf("Name requires 0 arguments")
-        }
-        return impl.Name(), nil
-        {{- else if eq .Name "Version" }}
-        if len(args) != 0 {
-            return nil, fmt.Errorf("Version requires 0 arguments")
-        }
-        return impl.Version(), nil
-        {{- else if eq .Name "Init" }}
-        // Init method passes all parameters directly
-        return nil, impl.Init(args...)
-        {{- else if eq .Name "Free" }}
-        if len(args) != 0 {
-            return nil, fmt.Errorf("Free requires 0 arguments")
-        }
-        return nil, impl.Free()
-        {{- else }}
-        // Normal method handling
         if len(args) != {{ len .Params | add -1 }} {
-            return nil, fmt.Errorf("{{ .Name }} requires {{ len .Params | add -1 }} arguments")
+            return nil, plugin.ErrInvalidArgument{Func: "{{ .Name }}", Err: fmt.Errorf("{{ .Name }} requires {{ len .Params | add -1 }} arguments")}
         }
 
         // Parameter type conversion
@@ -146,7 +151,7 @@ var Functions = map[string]plugin.InvokeFunc{
         {{- if ne $i 0 }}
         {{ $param.Name }}, ok{{ $i }} := args[{{ add $i -1 }}].({{ $param.Type }})
         if !ok{{ $i }} {
-            return nil, fmt.Errorf("argument {{ add $i -1 }} must be {{ $param.Type }}")
+            return nil, plugin.ErrInvalidArgument{Func: "{{ .Name }}", Err: fmt.Errorf("argument {{ add $i -1 }} must be {{ $param.Type }}")}
         }
         {{- end }}
         {{- end }}
@@ -161,19 +166,64 @@ var Functions = map[string]plugin.InvokeFunc{
         {{- else if eq (len .Results) 2 }}
         return impl.{{ .Name }}(ctx{{ range $i, $param := .Params }}{{ if ne $i 0 }}, {{ $param.Name }}{{ end }}{{ end }})
         {{- end }}
-        {{- end }}
     },
     {{- end }}
 }
+
+// FunctionSignatures describes the exported functions so the host can check contracts
+var FunctionSignatures = map[string]plugin.FuncSignature{
+    {{- range .Functions }}
+    "{{ .Name }}": {
+        Params:   []string{ {{- range $i, $param := .Params }}{{ if ne $i 0 }}{{ if ne $i 1 }}, {{ end }}"{{ sigType $param.Type }}"{{ end }}{{ end -}} },
+        Results:  []string{ {{- range $i, $result := .Results }}{{ if ne $i 0 }}, {{ end }}"{{ $result.Type }}"{{ end -}} },
+        Variadic: {{ .Variadic }},
+    },
+    {{- end }}
+}
+`
+
+// ProcessBuildTag selects the main function that runs a plugin as a child process
+const ProcessBuildTag = "chameleon_process"
+
+const processMainTpl = `//go:build {{ .BuildTag }}
+
+package {{ .Package }}
+
+import "github.com/zyanho/chameleon/pkg/plugin"
+
+// main serves the plugin to a host that runs it as a child process
+func main() {
+    plugin.ServeProcess(plugin.ProcessPlugin{
+        Bureau:     {{ .ExportSymbol }},
+        Functions:  {{ .FunctionsSymbol }},
+        Signatures: FunctionSignatures,
+        Metadata:   &Metadata,
+    })
+}
 `
 
 // Generate analyzes plugin source code and generates wrapper code
 func Generate(pluginDir string) error {
+	return GenerateWithOptions(pluginDir, DefaultOptions())
+}
+
+// GenerateWithOptions generates wrapper code using custom symbol names
+func GenerateWithOptions(pluginDir string, opts Options) error {
+	defaults := DefaultOptions()
+	if opts.ExportSymbol == "" {
+		opts.ExportSymbol = defaults.ExportSymbol
+	}
+	if opts.FunctionsSymbol == "" {
+		opts.FunctionsSymbol = defaults.FunctionsSymbol
+	}
+
 	// 1. Analyze plugin source code
 	info, err := analyzePlugin(pluginDir)
 	if err != nil {
 		return err
 	}
+	info.ExportSymbol = opts.ExportSymbol
+	info.FunctionsSymbol = opts.FunctionsSymbol
 
 	// 2. Generate wrapper code
 	return generateWrapper(pluginDir, info)
@@ -192,6 +242,16 @@ func analyzePlugin(dir string) (*pluginInfo, error) {
 	for pkgName, pkg := range pkgs {
 		info := &pluginInfo{Package: pkgName}
 
+		// Detect a user-declared Metadata variable so no stub is emitted
+		for fileName, file := range pkg.Files {
+			if base := filepath.Base(fileName); base == "plugin_wrapper.go" || base == "plugin_process.go" {
+				continue
+			}
+			if obj := file.Scope.Lookup("Metadata"); obj != nil && obj.Kind == ast.Var {
+				info.HasMetadata = true
+			}
+		}
+
 		// Find types that implement the Bureau interface
 		ast.Inspect(pkg, func(n ast.Node) bool {
 			switch t := n.(type) {
@@ -199,8 +259,8 @@ func analyzePlugin(dir string) (*pluginInfo, error) {
 				// TODO: Check if it implements the Bureau interface
 				info.PluginType = t.Name.Name
 			case *ast.FuncDecl:
-				// Collect exported methods
-				if t.Recv != nil && t.Name.IsExported() {
+				// Collect exported methods; Bureau lifecycle methods are called by the host directly
+				if t.Recv != nil && t.Name.IsExported() && !lifecycleMethods[t.Name.Name] {
 					info.Functions = append(info.Functions, analyzeFuncDecl(t))
 				}
 			}
@@ -221,6 +281,13 @@ func generateWrapper(dir string, info *pluginInfo) error {
 		"add": func(a, b int) int {
 			return a + b
 		},
+		// sigType writes a variadic parameter as its slice type
+		"sigType": func(typ string) string {
+			if strings.HasPrefix(typ, "...") {
+				return "[]" + strings.TrimPrefix(typ, "...")
+			}
+			return typ
+		},
 	}
 
 	tmpl, err := template.New("plugin").Funcs(funcMap).Parse(pluginTpl)
@@ -234,5 +301,29 @@ func generateWrapper(dir string, info *pluginInfo) error {
 	}
 
 	outputPath := filepath.Join(dir, "plugin_wrapper.go")
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return generateProcessMain(dir, info)
+}
+
+// generateProcessMain generates the main function of process builds; the build tag keeps
+// it out of shared object builds
+func generateProcessMain(dir string, info *pluginInfo) error {
+	tmpl, err := template.New("process").Parse(processMainTpl)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		*pluginInfo
+		BuildTag string
+	}{info, ProcessBuildTag}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	outputPath := filepath.Join(dir, "plugin_process.go")
 	return os.WriteFile(outputPath, buf.Bytes(), 0644)
 }
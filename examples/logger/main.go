@@ -30,6 +30,12 @@ func (l *zapLogger) Error(msg string, args ...any) {
 	l.log.Error(msg, convertToZapFields(args...)...)
 }
 
+// With makes zapLogger a plugin.FieldLogger: the manager's per-plugin loggers become
+// zap child loggers instead of repeating the fields on every entry
+func (l *zapLogger) With(args ...any) plugin.Logger {
+	return &zapLogger{l.log.With(convertToZapFields(args...)...)}
+}
+
 // convertToZapFields converts interface arguments to zap fields
 func convertToZapFields(args ...any) []zap.Field {
 	if len(args) == 0 {
@@ -53,11 +59,10 @@ func main() {
 
 	// Example 2: Using slog
 	slogger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	slogAdapter := &slogLogger{slogger}
 	manager2, _ := plugin.NewManager(
 		context.Background(),
 		config,
-		plugin.WithLogger(slogAdapter),
+		plugin.WithLogger(plugin.NewSlogLogger(slogger)),
 	)
 	defer manager2.Close()
 
@@ -71,24 +76,3 @@ func main() {
 	)
 	defer manager3.Close()
 }
-
-// slog adapter
-type slogLogger struct {
-	log *slog.Logger
-}
-
-func (l *slogLogger) Debug(msg string, args ...any) {
-	l.log.Debug(msg, args...)
-}
-
-func (l *slogLogger) Info(msg string, args ...any) {
-	l.log.Info(msg, args...)
-}
-
-func (l *slogLogger) Warn(msg string, args ...any) {
-	l.log.Warn(msg, args...)
-}
-
-func (l *slogLogger) Error(msg string, args ...any) {
-	l.log.Error(msg, args...)
-}
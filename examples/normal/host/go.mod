@@ -2,12 +2,28 @@ module example-host
 
 go 1.23.3
 
-require github.com/zyanho/chameleon v0.0.0-20241116174048-9d04c40ebaf1
+require (
+	github.com/prometheus/client_golang v1.20.5
+	github.com/zyanho/chameleon v0.0.0-20241116174048-9d04c40ebaf1
+	github.com/zyanho/chameleon/pkg/plugin/promexport v0.0.0-00010101000000-000000000000
+)
 
 require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/fsnotify/fsnotify v1.8.0 // indirect
-	golang.org/x/sync v0.9.0 // indirect
-	golang.org/x/sys v0.27.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.55.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	google.golang.org/protobuf v1.36.4 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
-replace github.com/zyanho/chameleon => ../../..
+replace (
+	github.com/zyanho/chameleon => ../../..
+	github.com/zyanho/chameleon/pkg/plugin/promexport => ../../../pkg/plugin/promexport
+)
@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"path/filepath"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	"github.com/zyanho/chameleon/pkg/plugin"
+	"github.com/zyanho/chameleon/pkg/plugin/promexport"
 )
 
 func main() {
@@ -33,10 +39,10 @@ func main() {
 		PluginTimeout:      30 * time.Second,
 		MaxConcurrentCalls: 100,
 		CircuitBreaker: plugin.CircuitBreakerConfig{
-			Enabled:         true,
-			MaxFailures:     5,
-			ResetInterval:   60 * time.Second,
-			TimeoutDuration: 5 * time.Second,
+			Enabled:       true,
+			MaxFailures:   5,
+			ResetInterval: 60 * time.Second,
+			OpenDuration:  5 * time.Second,
 		},
 		Options: make(map[string]interface{}),
 	}
@@ -51,6 +57,17 @@ func main() {
 	// Enable performance statistics
 	manager.EnableMetrics()
 
+	// Expose them to Prometheus at http://localhost:2112/metrics while the example runs
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(promexport.NewCollector(manager))
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		if err := http.ListenAndServe(":2112", mux); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+
 	// List all loaded plugins
 	fmt.Println("\nLoaded plugins:")
 	plugins := manager.ListPlugins()
@@ -86,6 +103,22 @@ func main() {
 	}
 	fmt.Printf("Some1111 Result: %v\n", result)
 
+	// The call ID reaches the plugin and its logs; without one the manager generates it
+	result, err = manager.Call(plugin.WithCallID(ctx, "request-42"), "example-plugin", "Trace", "hello")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Trace Result: %v\n", result)
+
+	// Plugin errors carry the plugin, version and function that produced them
+	if _, err := manager.Call(ctx, "example-plugin", "Add", "1", 2); err != nil {
+		var callErr plugin.CallError
+		if errors.As(err, &callErr) {
+			fmt.Printf("%s@%s %s (call %s) failed after %v: %v\n",
+				callErr.Plugin, callErr.Version, callErr.Function, callErr.CallID, callErr.Duration, callErr.Err)
+		}
+	}
+
 	// Print detailed plugin information
 	printPluginInfo(manager, "Current State")
 
@@ -121,40 +154,27 @@ func printMetrics(manager *plugin.Manager) {
 	plugins := manager.ListPlugins()
 
 	for _, p := range plugins {
-		metrics, err := manager.GetMetrics(p.Name)
+		snapshot, err := manager.GetMetricsSnapshot(p.Name)
 		if err != nil {
 			fmt.Printf("Error getting metrics for plugin %s: %v\n", p.Name, err)
 			continue
 		}
 
-		fmt.Printf("\nPlugin: %s\n", p.Name)
+		fmt.Printf("\nPlugin: %s (captured at %s)\n", p.Name, snapshot.CapturedAt.Format(time.TimeOnly))
 		fmt.Printf("Methods:\n")
+		for name, s := range snapshot.Methods {
+			fmt.Printf("  %s: %d calls, total %v, min %v, avg %v, max %v, p99 %v\n",
+				name, s.Count, s.TotalTime, s.MinTime, s.AvgTime, s.MaxTime, s.Quantile(0.99))
+			fmt.Printf("    Errors: %d (%d timeouts), error rate %.1f%%\n", s.Errors, s.Timeouts, 100*s.ErrorRate())
+		}
+		if rejected := snapshot.BreakerRejections; rejected > 0 {
+			fmt.Printf("  Rejected by the circuit breaker: %d\n", rejected)
+		}
 
-		// use Range to iterate over sync.Map
-		metrics.Methods.Range(func(key, value interface{}) bool {
-			methodName := key.(string)
-			methodMetrics := value.(*plugin.MethodMetrics)
-
-			fmt.Printf("  %s:\n", methodName)
-			fmt.Printf("    Call Count: %d\n", methodMetrics.Count.Load())
-			fmt.Printf("    Total Time: %v\n", time.Duration(methodMetrics.TotalTime.Load()))
-			fmt.Printf("    Min Time: %v\n", time.Duration(methodMetrics.MinTime.Load()))
-			fmt.Printf("    Max Time: %v\n", time.Duration(methodMetrics.MaxTime.Load()))
-
-			count := methodMetrics.Count.Load()
-			if count > 0 {
-				avgTime := time.Duration(methodMetrics.TotalTime.Load()) / time.Duration(count)
-				fmt.Printf("    Avg Time: %v\n", avgTime)
-			}
-			return true
-		})
-
-		// Print circuit breaker status
-		breakerStatus := "Closed"
-		if manager.GetBreakerStatus(p.Name) {
-			breakerStatus = "Open"
+		// Print circuit breaker state
+		if state, err := manager.GetBreakerState(p.Name); err == nil {
+			fmt.Printf("\n  Circuit Breaker State: %s\n", state)
 		}
-		fmt.Printf("\n  Circuit Breaker Status: %s\n", breakerStatus)
 	}
 }
 
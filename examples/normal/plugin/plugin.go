@@ -74,5 +74,14 @@ func (p *ExamplePlugin) Some1111(ctx context.Context, a int, b int, c string) (i
 	}
 }
 
+// Trace reports the ID of the call it serves, set by the host or generated by the manager
+func (p *ExamplePlugin) Trace(ctx context.Context, msg string) (string, error) {
+	id, ok := plugin.CallIDFromContext(ctx)
+	if !ok {
+		id = "none"
+	}
+	return fmt.Sprintf("call %s: %s", id, msg), nil
+}
+
 // Export exposes the plugin instance
 var Export plugin.Bureau = &ExamplePlugin{}
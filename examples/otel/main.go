@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/zyanho/chameleon/pkg/plugin"
+	"github.com/zyanho/chameleon/pkg/plugin/otelexport"
+)
+
+func main() {
+	ctx := context.Background()
+
+	// Print the metrics to stdout every few seconds and once more at shutdown
+	exporter, err := stdoutmetric.New(stdoutmetric.WithPrettyPrint())
+	if err != nil {
+		log.Fatal(err)
+	}
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(5*time.Second))),
+	)
+	defer provider.Shutdown(ctx)
+
+	// Use the plugins of the normal example
+	pluginDir, err := filepath.Abs(filepath.Join("..", "normal", "plugins"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	config := plugin.DefaultConfig()
+	config.PluginDir = pluginDir
+
+	manager, err := plugin.NewManager(ctx, config, otelexport.WithMetrics(provider))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer manager.Close()
+
+	for i := 0; i < 10; i++ {
+		result, err := manager.Call(ctx, "example-plugin", "Add", i, 1)
+		fmt.Printf("Add(%d, 1) = %v, %v\n", i, result, err)
+	}
+	// A failing call is recorded with the error outcome
+	if _, err := manager.Call(ctx, "example-plugin", "Add", "1", 2); err != nil {
+		fmt.Printf("Add(\"1\", 2) failed: %v\n", err)
+	}
+}
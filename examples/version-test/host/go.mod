@@ -6,8 +6,9 @@ require github.com/zyanho/chameleon v0.0.0-00010101000000-000000000000
 
 require (
 	github.com/fsnotify/fsnotify v1.8.0 // indirect
-	golang.org/x/sync v0.9.0 // indirect
-	golang.org/x/sys v0.27.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace github.com/zyanho/chameleon => ../../..
@@ -25,10 +25,10 @@ func main() {
 		PluginTimeout:      30 * time.Second,
 		MaxConcurrentCalls: 100,
 		CircuitBreaker: plugin.CircuitBreakerConfig{
-			Enabled:         true,
-			MaxFailures:     5,
-			ResetInterval:   60 * time.Second,
-			TimeoutDuration: 5 * time.Second,
+			Enabled:       true,
+			MaxFailures:   5,
+			ResetInterval: 60 * time.Second,
+			OpenDuration:  5 * time.Second,
 		},
 		Options: make(map[string]interface{}),
 	}
@@ -38,10 +38,10 @@ func main() {
 		InitArgs:      []interface{}{"init-arg1", "init-arg2"},
 		PluginTimeout: 30 * time.Second,
 		CircuitBreaker: plugin.CircuitBreakerConfig{
-			Enabled:         true,
-			MaxFailures:     3,
-			ResetInterval:   30 * time.Second,
-			TimeoutDuration: 5 * time.Second,
+			Enabled:       true,
+			MaxFailures:   3,
+			ResetInterval: 30 * time.Second,
+			OpenDuration:  5 * time.Second,
 		},
 	}
 
@@ -156,19 +156,19 @@ func printMetrics(manager *plugin.Manager) {
 			fmt.Printf("    Min Time: %v\n", time.Duration(methodMetrics.MinTime.Load()))
 			fmt.Printf("    Max Time: %v\n", time.Duration(methodMetrics.MaxTime.Load()))
 
-			count := methodMetrics.Count.Load()
-			if count > 0 {
-				avgTime := time.Duration(methodMetrics.TotalTime.Load()) / time.Duration(count)
-				fmt.Printf("    Avg Time: %v\n", avgTime)
-			}
+			fmt.Printf("    Avg Time: %v (%v when successful, %v when failed)\n", methodMetrics.AvgTime(),
+				methodMetrics.SuccessAvgTime(), methodMetrics.ErrorAvgTime())
+			fmt.Printf("    Errors: %d (%d timeouts), error rate %.1f%%\n", methodMetrics.ErrorCount.Load(),
+				methodMetrics.TimeoutCount.Load(), 100*methodMetrics.ErrorRate())
 			return true
 		})
+		if rejected := metrics.BreakerRejections.Load(); rejected > 0 {
+			fmt.Printf("  Rejected by the circuit breaker: %d\n", rejected)
+		}
 
-		// Print circuit breaker status
-		breakerStatus := "Closed"
-		if manager.GetBreakerStatus(p.Name) {
-			breakerStatus = "Open"
+		// Print circuit breaker state
+		if state, err := manager.GetBreakerState(p.Name); err == nil {
+			fmt.Printf("\n  Circuit Breaker State: %s\n", state)
 		}
-		fmt.Printf("\n  Circuit Breaker Status: %s\n", breakerStatus)
 	}
 }
@@ -0,0 +1,142 @@
+// Package clocktest provides a fake clock.Clock whose time only moves when a test
+// advances it
+package clocktest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock"
+)
+
+// Fake is a clock.Clock that stands still until Advance or Set moves it. Timers fire,
+// in deadline order, when the clock reaches their deadline. It is safe for concurrent use.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer // active timers
+}
+
+var _ clock.Clock = (*Fake)(nil)
+
+// NewFake returns a fake clock set to now; a zero now starts at an arbitrary fixed time
+func NewFake(now time.Time) *Fake {
+	if now.IsZero() {
+		now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return &Fake{now: now}
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer returns a timer that fires once the clock has advanced by d
+func (f *Fake) NewTimer(d time.Duration) clock.Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	f.schedule(t, d)
+	return t
+}
+
+// After returns a channel that receives the time once the clock has advanced by d
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Advance moves the clock forward by d, firing the timers due by then
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the clock to t, firing the timers due by then. The clock never goes back:
+// a t before the current time is ignored.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t.Before(f.now) {
+		return
+	}
+	f.now = t
+	sort.SliceStable(f.timers, func(i, j int) bool { return f.timers[i].when.Before(f.timers[j].when) })
+	var pending []*fakeTimer
+	for _, timer := range f.timers {
+		if timer.when.After(t) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.active = false
+		select {
+		case timer.c <- t:
+		default:
+			// Like time.Timer, an unread tick is not queued twice
+		}
+	}
+	f.timers = pending
+}
+
+// Timers returns the number of timers waiting to fire, for tests that must wait until
+// a goroutine has armed its timer before advancing the clock
+func (f *Fake) Timers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// schedule arms t to fire d from now; the caller holds mu
+func (f *Fake) schedule(t *fakeTimer, d time.Duration) {
+	t.when = f.now.Add(d)
+	t.active = true
+	if d <= 0 {
+		t.active = false
+		select {
+		case t.c <- f.now:
+		default:
+		}
+		return
+	}
+	f.timers = append(f.timers, t)
+}
+
+// unschedule disarms t, reporting whether it was active; the caller holds mu
+func (f *Fake) unschedule(t *fakeTimer) bool {
+	if !t.active {
+		return false
+	}
+	t.active = false
+	for i, timer := range f.timers {
+		if timer == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+type fakeTimer struct {
+	clock  *Fake
+	c      chan time.Time
+	when   time.Time
+	active bool // guarded by clock.mu
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.unschedule(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.clock.unschedule(t)
+	t.clock.schedule(t, d)
+	return active
+}
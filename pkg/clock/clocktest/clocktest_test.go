@@ -0,0 +1,62 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake_TimersFireInOrder(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	late := f.NewTimer(2 * time.Second)
+	early := f.After(time.Second)
+
+	f.Advance(999 * time.Millisecond)
+	select {
+	case <-early:
+		t.Fatal("Timer fired before its deadline")
+	default:
+	}
+
+	f.Advance(time.Millisecond)
+	if got := <-early; !got.Equal(start.Add(time.Second)) {
+		t.Errorf("Timer fired at %v, want %v", got, start.Add(time.Second))
+	}
+	if f.Timers() != 1 {
+		t.Errorf("Timers() = %d, want the later timer still pending", f.Timers())
+	}
+	f.Advance(time.Hour)
+	<-late.C()
+	if f.Timers() != 0 {
+		t.Errorf("Timers() = %d after every deadline passed", f.Timers())
+	}
+}
+
+func TestFake_StopAndReset(t *testing.T) {
+	f := NewFake(time.Time{})
+	timer := f.NewTimer(time.Second)
+	if !timer.Stop() || timer.Stop() {
+		t.Fatal("Stop() did not report the timer active exactly once")
+	}
+	f.Advance(time.Minute)
+	select {
+	case <-timer.C():
+		t.Fatal("Stopped timer fired")
+	default:
+	}
+
+	if timer.Reset(time.Second) {
+		t.Error("Reset() of a stopped timer reported it active")
+	}
+	f.Advance(time.Second)
+	<-timer.C()
+}
+
+func TestFake_NeverGoesBack(t *testing.T) {
+	f := NewFake(time.Time{})
+	now := f.Now()
+	f.Set(now.Add(-time.Hour))
+	if !f.Now().Equal(now) {
+		t.Errorf("Now() = %v after setting an earlier time, want %v", f.Now(), now)
+	}
+}
@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// accessLogArgLimit is the most bytes of each argument value an access log line holds
+const accessLogArgLimit = 128
+
+// accessLog holds a plugin's access log settings, read by every call
+type accessLog struct {
+	enabled atomic.Bool
+	args    atomic.Bool
+}
+
+// configureAccessLog applies a plugin's access log settings, creating them on first load
+func (m *Manager) configureAccessLog(name string, config *PluginSpecificConfig) {
+	val, _ := m.accessLogs.LoadOrStore(name, &accessLog{})
+	l := val.(*accessLog)
+	l.enabled.Store(config.AccessLog)
+	l.args.Store(config.AccessLogArgs)
+}
+
+// accessLogFor returns the access log settings of a plugin whose calls are logged, or nil
+func (m *Manager) accessLogFor(name string) *accessLog {
+	val, ok := m.accessLogs.Load(name)
+	if !ok || !val.(*accessLog).enabled.Load() {
+		return nil
+	}
+	return val.(*accessLog)
+}
+
+// logAccess writes the access log line of a call. The arguments are counted, and their
+// values logged only with AccessLogArgs, each cut to accessLogArgLimit bytes.
+func (m *Manager) logAccess(l *accessLog, pluginName, version, funcName, callID string, args []interface{},
+	duration time.Duration, err error) {
+	fields := []interface{}{"version", version, "function", funcName, "call_id", callID, "duration", duration,
+		"args", len(args), "success", err == nil}
+	if l.args.Load() {
+		values := make([]string, len(args))
+		for i, arg := range args {
+			values[i] = truncateArg(fmt.Sprint(arg))
+		}
+		fields = append(fields, "arg_values", values)
+	}
+	if err != nil {
+		var callErr CallError
+		if errors.As(err, &callErr) {
+			// The line already names the plugin, version and function
+			err = callErr.Err
+		}
+		fields = append(fields, "error", err)
+	}
+	m.pluginLogger(pluginName).Info("Plugin call", fields...)
+}
+
+// truncateArg cuts s to accessLogArgLimit bytes without splitting a character
+func truncateArg(s string) string {
+	if len(s) <= accessLogArgLimit {
+		return s
+	}
+	cut := accessLogArgLimit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManager_AccessLog(t *testing.T) {
+	logger := &testLogger{}
+	m := newSlowCallManager(t, PluginSpecificConfig{AccessLog: true}, logger)
+	ctx := WithCallID(context.Background(), "request-42")
+
+	m.Call(ctx, "payments", "Fast")
+	m.Call(ctx, "payments", "Slow", "fail", "card-4242")
+	lines := logger.fields("INFO: Plugin call")
+	if len(lines) != 2 {
+		t.Fatalf("access log = %v, want one line per call", lines)
+	}
+	ok := lines[0]
+	if ok["plugin"] != "payments" || ok["version"] != "1.0.0" || ok["function"] != "Fast" ||
+		ok["call_id"] != "request-42" || ok["args"] != 0 || ok["success"] != true {
+		t.Errorf("access log line = %v, want a successful call to payments@1.0.0 Fast", ok)
+	}
+	failed := lines[1]
+	if failed["success"] != false || failed["args"] != 2 || failed["error"].(error).Error() != "declined" {
+		t.Errorf("access log line = %v, want a failed call with 2 arguments", failed)
+	}
+	for _, v := range failed {
+		if v == "card-4242" {
+			t.Errorf("access log line %v logs an argument", failed)
+		}
+	}
+
+	// Calls the breaker rejects are logged as well
+	if err := m.TripBreaker("payments", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	m.Call(ctx, "payments", "Fast")
+	if lines := logger.fields("INFO: Plugin call"); len(lines) != 3 || lines[2]["success"] != false {
+		t.Errorf("access log = %v, want the rejected call", lines)
+	}
+}
+
+func TestManager_AccessLogArgs(t *testing.T) {
+	logger := &testLogger{}
+	m := newSlowCallManager(t, PluginSpecificConfig{}, logger)
+	ctx := context.Background()
+
+	m.Call(ctx, "payments", "Fast")
+	if logger.has("INFO: Plugin call") {
+		t.Fatal("calls logged without AccessLog")
+	}
+
+	// The settings apply to the next call
+	m.configureAccessLog("payments", &PluginSpecificConfig{AccessLog: true, AccessLogArgs: true})
+	long := strings.Repeat("é", accessLogArgLimit)
+	m.Call(ctx, "payments", "Fast", 42, long)
+	lines := logger.fields("INFO: Plugin call")
+	if len(lines) != 1 {
+		t.Fatalf("access log = %v, want one line", lines)
+	}
+	want := []string{"42", strings.Repeat("é", accessLogArgLimit/2) + "..."}
+	if got := lines[0]["arg_values"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("arg_values = %q, want %q", got, want)
+	}
+}
+
+func TestMergeConfig_AccessLog(t *testing.T) {
+	merged := mergeConfig(PluginSpecificConfig{AccessLog: true}, PluginSpecificConfig{AccessLogArgs: true})
+	if !merged.AccessLog || !merged.AccessLogArgs {
+		t.Errorf("mergeConfig() = %+v, want the default AccessLog and the plugin's AccessLogArgs", merged)
+	}
+}
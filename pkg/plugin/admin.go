@@ -0,0 +1,456 @@
+package plugin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The admin API serves JSON under these routes:
+//
+//	GET    /plugins                  loaded plugins
+//	GET    /plugins/{name}           one plugin with its breaker and metrics
+//	POST   /plugins/{name}/reload    load the plugin's file again
+//	POST   /plugins/{name}/disable   not supported yet
+//	POST   /plugins/{name}/enable    not supported yet
+//	DELETE /plugins/{name}           not supported yet
+//	GET    /healthz, /readyz         liveness and readiness probes
+//
+// Mutating routes require "Authorization: Bearer <Config.AdminToken>". The JSON field
+// names of the Admin types are part of the API and do not change.
+
+// adminShutdownTimeout bounds how long ServeAdmin waits for requests when the manager closes
+const adminShutdownTimeout = 5 * time.Second
+
+// AdminPlugin describes a loaded plugin in admin API responses
+type AdminPlugin struct {
+	Name       string         `json:"name"`
+	Version    string         `json:"version"`
+	State      string         `json:"state"`
+	RefCount   int32          `json:"ref_count"`
+	Path       string         `json:"path"`
+	Hash       string         `json:"hash,omitempty"`
+	ShadowPath string         `json:"shadow_path,omitempty"`
+	SourceURL  string         `json:"source_url,omitempty"`
+	Functions  []string       `json:"functions"`
+	Breaker    string         `json:"breaker"`
+	Replicas   []AdminReplica `json:"replicas"`
+	// Size, OpenNs, InitNs, LoadedAt and PreviousLoadAt are those of PluginInfo
+	Size           int64      `json:"size,omitempty"`
+	OpenNs         int64      `json:"open_ns,omitempty"`
+	InitNs         int64      `json:"init_ns"`
+	LoadedAt       time.Time  `json:"loaded_at"`
+	PreviousLoadAt *time.Time `json:"previous_load_at,omitempty"`
+}
+
+// AdminReplica describes one instance serving a plugin
+type AdminReplica struct {
+	Version  string `json:"version"`
+	State    string `json:"state"`
+	RefCount int32  `json:"ref_count"`
+	Breaker  string `json:"breaker"`
+}
+
+// AdminPluginDetail is the response of GET /plugins/{name}
+type AdminPluginDetail struct {
+	AdminPlugin
+	// BreakerDetail has the statistics of the breaker whose state is AdminPlugin.Breaker
+	BreakerDetail AdminBreaker     `json:"breaker_detail"`
+	Concurrency   AdminConcurrency `json:"concurrency"`
+	// Metrics is keyed by function name; it is empty while metrics are disabled
+	Metrics map[string]AdminMethodMetrics `json:"metrics"`
+}
+
+// AdminConcurrency describes a plugin's concurrency limit; a zero limit is unlimited
+type AdminConcurrency struct {
+	Mode       string `json:"mode"`
+	Limit      int    `json:"limit"`
+	InFlight   int    `json:"in_flight"`
+	Rejected   int64  `json:"rejected"`
+	BaselineNs int64  `json:"baseline_ns,omitempty"`
+}
+
+// AdminBreaker describes a plugin's circuit breaker and its statistics
+type AdminBreaker struct {
+	Enabled             bool       `json:"enabled"`
+	State               string     `json:"state"`
+	Trips               int64      `json:"trips"`
+	Rejected            int64      `json:"rejected"`
+	Failures            int32      `json:"failures"`
+	Timeouts            int32      `json:"timeouts"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastTransition      time.Time  `json:"last_transition"`
+	// NextProbeNs is the time until an open breaker admits a trial call
+	NextProbeNs int64  `json:"next_probe_ns,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// AdminMethodMetrics holds the call metrics of one function; durations are in nanoseconds.
+// Count and the durations cover every call, the Errors and error durations the calls
+// that failed, and the Timeouts those of them that timed out.
+type AdminMethodMetrics struct {
+	Count      int64 `json:"count"`
+	Errors     int64 `json:"errors"`
+	Timeouts   int64 `json:"timeouts"`
+	TotalNs    int64 `json:"total_ns"`
+	MinNs      int64 `json:"min_ns"`
+	MaxNs      int64 `json:"max_ns"`
+	AvgNs      int64 `json:"avg_ns"`
+	P50Ns      int64 `json:"p50_ns"`
+	P95Ns      int64 `json:"p95_ns"`
+	P99Ns      int64 `json:"p99_ns"`
+	ErrorMinNs int64 `json:"error_min_ns,omitempty"`
+	ErrorMaxNs int64 `json:"error_max_ns,omitempty"`
+	ErrorAvgNs int64 `json:"error_avg_ns,omitempty"`
+	// LastCall, LastSuccess and LastError are omitted if no such call returned
+	LastCall         *time.Time `json:"last_call,omitempty"`
+	LastSuccess      *time.Time `json:"last_success,omitempty"`
+	LastError        *time.Time `json:"last_error,omitempty"`
+	LastErrorMessage string     `json:"last_error_message,omitempty"`
+}
+
+// AdminReloadResult is the response of POST /plugins/{name}/reload
+type AdminReloadResult struct {
+	// Reloaded is false when the file still held the loaded plugin
+	Reloaded bool              `json:"reloaded"`
+	Plugin   AdminPluginDetail `json:"plugin"`
+}
+
+// AdminError is the body of every admin API error response
+type AdminError struct {
+	Error string `json:"error"`
+}
+
+// AdminHandler returns the admin API handler, to be mounted on a server of the caller's
+// choosing. Routes are relative to the handler; use http.StripPrefix to mount it below a path.
+func (m *Manager) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /plugins", m.adminListPlugins)
+	mux.HandleFunc("GET /plugins/{name}", m.adminGetPlugin)
+	mux.HandleFunc("POST /plugins/{name}/reload", m.adminAuthorized("reload", m.adminReloadPlugin))
+	mux.HandleFunc("POST /plugins/{name}/disable", m.adminAuthorized("disable", m.adminNotSupported("disable", "disabling plugins")))
+	mux.HandleFunc("POST /plugins/{name}/enable", m.adminAuthorized("enable", m.adminNotSupported("enable", "enabling plugins")))
+	mux.HandleFunc("DELETE /plugins/{name}", m.adminAuthorized("unload", m.adminNotSupported("unload", "unloading plugins")))
+	mux.Handle("GET /healthz", m.HealthzHandler())
+	mux.Handle("GET /readyz", m.ReadyzHandler())
+	return mux
+}
+
+// ServeAdmin serves AdminHandler on addr until the manager closes
+func (m *Manager) ServeAdmin(addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           m.AdminHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(_ net.Listener) context.Context { return m.ctx },
+	}
+	stop := context.AfterFunc(m.ctx, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+		defer cancel()
+		srv.Shutdown(ctx)
+	})
+	defer stop()
+
+	m.logger.Info("Serving admin API", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) adminListPlugins(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, m.AdminList())
+}
+
+func (m *Manager) adminGetPlugin(w http.ResponseWriter, r *http.Request) {
+	detail, err := m.AdminGet(r.PathValue("name"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, detail)
+}
+
+func (m *Manager) adminReloadPlugin(w http.ResponseWriter, r *http.Request) {
+	result, err := m.AdminReload(r.PathValue("name"), AdminCaller{Actor: ActorAdminToken, Remote: r.RemoteAddr})
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, result)
+}
+
+// AdminCaller identifies the client of an admin operation in the audit log
+type AdminCaller struct {
+	Actor  string // one of the Actor constants
+	Remote string // the client's address
+}
+
+// AuditAdmin records an admin operation and its outcome, e.g. one refused before it
+// reached the manager
+func (m *Manager) AuditAdmin(operation, name, path string, caller AdminCaller, err error) {
+	record := AuditEvent{Action: AuditAdmin, Operation: operation, Actor: caller.Actor, Remote: caller.Remote,
+		Plugin: name, Path: path}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	m.record(record)
+}
+
+// CheckAdminToken reports whether token is Config.AdminToken. It returns
+// ErrAdminTokenNotConfigured when no token is configured and ErrInvalidAdminToken when
+// token does not match.
+func (m *Manager) CheckAdminToken(token string) error {
+	want := m.currentConfig().AdminToken
+	if want == "" {
+		return ErrAdminTokenNotConfigured
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+		return ErrInvalidAdminToken
+	}
+	return nil
+}
+
+// AdminList returns the loaded plugins by name, as served by GET /plugins
+func (m *Manager) AdminList() []AdminPlugin {
+	infos := m.ListPlugins()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	plugins := make([]AdminPlugin, 0, len(infos))
+	for _, info := range infos {
+		plugins = append(plugins, m.adminPlugin(info))
+	}
+	return plugins
+}
+
+// AdminLoad loads the plugin at path for an admin client and records the operation in
+// the audit log. It returns the plugin loaded from path afterwards, zero if there is none.
+func (m *Manager) AdminLoad(path string, force bool, caller AdminCaller) (AdminPluginDetail, error) {
+	err := m.loadPlugin(path, nil, loadOptions{force: force, actor: caller.Actor})
+	m.AuditAdmin("load", "", path, caller, err)
+	if err != nil {
+		return AdminPluginDetail{}, err
+	}
+	resolved := resolvePath(path)
+	for _, info := range m.ListPlugins() {
+		if resolvePath(info.Path) != resolved {
+			continue
+		}
+		if detail, err := m.AdminGet(info.Name); err == nil {
+			return detail, nil
+		}
+		break
+	}
+	return AdminPluginDetail{}, nil
+}
+
+// AdminReload loads a plugin's file again for an admin client and records the operation
+// in the audit log. A forced load takes whatever the file holds now, even an older version.
+func (m *Manager) AdminReload(name string, caller AdminCaller) (AdminReloadResult, error) {
+	before, ok := m.plugins.Load(name)
+	path, _ := m.GetPluginPath(name)
+	if !ok || path == "" {
+		err := ErrPluginNotFound{Name: name}
+		m.AuditAdmin("reload", name, "", caller, err)
+		return AdminReloadResult{}, err
+	}
+	err := m.loadPlugin(path, nil, loadOptions{force: true, actor: caller.Actor})
+	m.AuditAdmin("reload", name, path, caller, err)
+	if err != nil {
+		return AdminReloadResult{}, err
+	}
+	after, _ := m.plugins.Load(name)
+	detail, err := m.AdminGet(name)
+	if err != nil {
+		return AdminReloadResult{}, err
+	}
+	return AdminReloadResult{Reloaded: after != before, Plugin: detail}, nil
+}
+
+// adminAuthorized refuses requests that do not carry Config.AdminToken and records the
+// refusal of the operation in the audit log
+func (m *Manager) adminAuthorized(operation string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		refuse := func(status int, msg string) {
+			m.AuditAdmin(operation, r.PathValue("name"), "", AdminCaller{Actor: ActorAnonymous, Remote: r.RemoteAddr},
+				errors.New(msg))
+			writeAdminJSON(w, status, AdminError{Error: msg})
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			got = ""
+		}
+		switch err := m.CheckAdminToken(got); {
+		case errors.Is(err, ErrAdminTokenNotConfigured):
+			refuse(http.StatusForbidden, err.Error())
+			return
+		case err != nil:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chameleon"`)
+			refuse(http.StatusUnauthorized, err.Error())
+			return
+		}
+		next(w, r)
+	}
+}
+
+// adminNotSupported answers routes whose Manager operation does not exist yet
+func (m *Manager) adminNotSupported(operation, what string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := errors.New(what + " is not supported")
+		m.AuditAdmin(operation, r.PathValue("name"), "", AdminCaller{Actor: ActorAdminToken, Remote: r.RemoteAddr}, err)
+		writeAdminJSON(w, http.StatusNotImplemented, AdminError{Error: err.Error()})
+	}
+}
+
+func (m *Manager) adminPlugin(info PluginInfo) AdminPlugin {
+	functions, _ := m.GetPluginFunctions(info.Name)
+	sort.Strings(functions)
+	if functions == nil {
+		functions = []string{}
+	}
+	replicas := make([]AdminReplica, 0, len(info.Replicas))
+	for _, r := range info.Replicas {
+		replicas = append(replicas, AdminReplica{
+			Version:  r.Version,
+			State:    r.State.String(),
+			RefCount: r.RefCount,
+			Breaker:  r.Breaker.String(),
+		})
+	}
+	p := AdminPlugin{
+		Name:       info.Name,
+		Version:    info.Version,
+		State:      info.State.String(),
+		RefCount:   info.RefCount,
+		Path:       info.Path,
+		Hash:       info.Hash,
+		ShadowPath: info.ShadowPath,
+		SourceURL:  info.SourceURL,
+		Functions:  functions,
+		Breaker:    info.Breaker.String(),
+		Replicas:   replicas,
+		Size:       info.Size,
+		OpenNs:     int64(info.OpenDuration),
+		InitNs:     int64(info.InitDuration),
+		LoadedAt:   info.LoadedAt,
+	}
+	if !info.PreviousLoadAt.IsZero() {
+		p.PreviousLoadAt = &info.PreviousLoadAt
+	}
+	return p
+}
+
+// AdminGet returns a plugin with its breaker and metrics, as served by GET /plugins/{name}
+func (m *Manager) AdminGet(name string) (AdminPluginDetail, error) {
+	var info *PluginInfo
+	for _, p := range m.ListPlugins() {
+		if p.Name == name {
+			info = &p
+			break
+		}
+	}
+	if info == nil {
+		return AdminPluginDetail{}, ErrPluginNotFound{Name: name}
+	}
+
+	detail := AdminPluginDetail{
+		AdminPlugin: m.adminPlugin(*info),
+		Metrics:     m.adminMetrics(name),
+	}
+	if c, err := m.GetConcurrencyInfo(name); err == nil {
+		detail.Concurrency = adminConcurrency(c)
+	}
+	if info, err := m.GetBreakerInfo(name); err == nil {
+		detail.BreakerDetail = AdminBreaker{
+			Enabled:             info.Enabled,
+			State:               info.State.String(),
+			Trips:               info.Trips,
+			Rejected:            info.Rejected,
+			Failures:            info.Failures,
+			Timeouts:            info.Timeouts,
+			ConsecutiveFailures: info.ConsecutiveFailures,
+			LastTransition:      info.LastTransition,
+			NextProbeNs:         int64(info.NextProbeIn),
+			Reason:              info.Reason,
+		}
+		if !info.LastFailure.IsZero() {
+			detail.BreakerDetail.LastFailure = &info.LastFailure
+		}
+	}
+	return detail, nil
+}
+
+func adminConcurrency(c ConcurrencyInfo) AdminConcurrency {
+	return AdminConcurrency{
+		Mode:       string(c.Mode),
+		Limit:      c.Limit,
+		InFlight:   c.InFlight,
+		Rejected:   c.Rejected,
+		BaselineNs: int64(c.Baseline),
+	}
+}
+
+// adminMetrics returns a plugin's call metrics by function; it is empty while metrics
+// are disabled or nothing was recorded
+func (m *Manager) adminMetrics(name string) map[string]AdminMethodMetrics {
+	methods := make(map[string]AdminMethodMetrics)
+	snapshot, err := m.GetMetricsSnapshot(name)
+	if err != nil {
+		return methods
+	}
+	for fn, s := range snapshot.Methods {
+		mm := AdminMethodMetrics{
+			Count:      s.Count,
+			Errors:     s.Errors,
+			Timeouts:   s.Timeouts,
+			TotalNs:    int64(s.TotalTime),
+			MinNs:      int64(s.MinTime),
+			MaxNs:      int64(s.MaxTime),
+			AvgNs:      int64(s.AvgTime),
+			ErrorMinNs: int64(s.ErrorMinTime),
+			ErrorMaxNs: int64(s.ErrorMaxTime),
+			ErrorAvgNs: int64(s.ErrorAvgTime()),
+			P50Ns:      int64(s.Quantile(0.5)),
+			P95Ns:      int64(s.Quantile(0.95)),
+			P99Ns:      int64(s.Quantile(0.99)),
+
+			LastErrorMessage: s.LastErrorMessage,
+		}
+		if !s.LastCall.IsZero() {
+			mm.LastCall = &s.LastCall
+		}
+		if !s.LastSuccess.IsZero() {
+			mm.LastSuccess = &s.LastSuccess
+		}
+		if !s.LastError.IsZero() {
+			mm.LastError = &s.LastError
+		}
+		methods[fn] = mm
+	}
+	return methods
+}
+
+// writeAdminError answers with the status that fits err
+func writeAdminError(w http.ResponseWriter, err error) {
+	var notFound ErrPluginNotFound
+	status := http.StatusInternalServerError
+	switch {
+	case errors.As(err, &notFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrManagerClosed):
+		status = http.StatusServiceUnavailable
+	}
+	writeAdminJSON(w, status, AdminError{Error: err.Error()})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
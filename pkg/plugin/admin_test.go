@@ -0,0 +1,170 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
+)
+
+// newAdminManager creates a manager that has payments 1.0.0 loaded and called once
+func newAdminManager(t *testing.T, token string) (*Manager, string) {
+	t.Helper()
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Refund": returning("ok"), "Pay": returning("ok")}),
+		"v2": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, map[string]InvokeFunc{"Pay": returning("ok")}),
+	})
+	m := newTestManager(t, func(config *Config) {
+		config.EnableMetrics = true
+		config.AdminToken = token
+	})
+	path := loadTestPlugin(t, m, "payments", "v1")
+	if _, err := m.Call(context.Background(), "payments", "Pay"); err != nil {
+		t.Fatal(err)
+	}
+	return m, path
+}
+
+// newAdminServer serves the admin API of newAdminManager's manager
+func newAdminServer(t *testing.T, token string) (*httptest.Server, string) {
+	t.Helper()
+	m, path := newAdminManager(t, token)
+	srv := httptest.NewServer(m.AdminHandler())
+	t.Cleanup(srv.Close)
+	return srv, path
+}
+
+// adminRequest sends a request and decodes the JSON response into v
+func adminRequest(t *testing.T, method, url, token string, v interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s Content-Type = %q", method, url, ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("%s %s: decoding response: %v", method, url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestAdminHandler_Read(t *testing.T) {
+	srv, path := newAdminServer(t, "")
+
+	var plugins []AdminPlugin
+	if status := adminRequest(t, http.MethodGet, srv.URL+"/plugins", "", &plugins); status != http.StatusOK {
+		t.Fatalf("GET /plugins status = %d", status)
+	}
+	if len(plugins) != 1 {
+		t.Fatalf("GET /plugins = %+v, want payments", plugins)
+	}
+	p := plugins[0]
+	if p.Name != "payments" || p.Version != "1.0.0" || p.State != "active" || p.Path != path || p.Hash == "" ||
+		len(p.Functions) != 2 || p.Functions[0] != "Pay" || len(p.Replicas) != 1 {
+		t.Errorf("GET /plugins = %+v", p)
+	}
+
+	var detail AdminPluginDetail
+	if status := adminRequest(t, http.MethodGet, srv.URL+"/plugins/payments", "", &detail); status != http.StatusOK {
+		t.Fatalf("GET /plugins/payments status = %d", status)
+	}
+	if detail.Name != "payments" || detail.BreakerDetail.State != "closed" || detail.Metrics["Pay"].Count != 1 {
+		t.Errorf("GET /plugins/payments = %+v", detail)
+	}
+	if pay := detail.Metrics["Pay"]; pay.LastCall == nil || pay.LastSuccess == nil || pay.LastError != nil {
+		t.Errorf("Pay last call %v, success %v, error %v; want a successful call", pay.LastCall, pay.LastSuccess, pay.LastError)
+	}
+
+	var apiErr AdminError
+	if status := adminRequest(t, http.MethodGet, srv.URL+"/plugins/orders", "", &apiErr); status != http.StatusNotFound || apiErr.Error == "" {
+		t.Errorf("GET /plugins/orders = %d, %+v; want 404 with an error", status, apiErr)
+	}
+}
+
+func TestAdminHandler_HalfOpenBreaker(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning("ok")}),
+	})
+	clk := clocktest.NewFake(time.Time{})
+	m := newTestManager(t, nil, WithClock(clk))
+	loadTestPlugin(t, m, "payments", "v1")
+	srv := httptest.NewServer(m.AdminHandler())
+	t.Cleanup(srv.Close)
+
+	if err := m.TripBreaker("payments", ""); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(m.currentConfig().DefaultPluginConfig.CircuitBreaker.OpenDuration)
+	waitFor(t, "the breaker to half-open", func() bool {
+		state, _ := m.GetBreakerState("payments")
+		return state == StateHalfOpen
+	})
+
+	var detail AdminPluginDetail
+	if status := adminRequest(t, http.MethodGet, srv.URL+"/plugins/payments", "", &detail); status != http.StatusOK {
+		t.Fatalf("GET /plugins/payments status = %d", status)
+	}
+	if detail.Breaker != "half-open" || detail.BreakerDetail.State != "half-open" {
+		t.Errorf("GET /plugins/payments breaker %q, detail state %q; want half-open", detail.Breaker, detail.BreakerDetail.State)
+	}
+}
+
+func TestAdminHandler_Mutations(t *testing.T) {
+	srv, path := newAdminServer(t, "s3cret")
+
+	var apiErr AdminError
+	if status := adminRequest(t, http.MethodPost, srv.URL+"/plugins/payments/reload", "", &apiErr); status != http.StatusUnauthorized {
+		t.Errorf("Reload without token status = %d, want 401", status)
+	}
+	if status := adminRequest(t, http.MethodPost, srv.URL+"/plugins/payments/reload", "wrong", &apiErr); status != http.StatusUnauthorized {
+		t.Errorf("Reload with a wrong token status = %d, want 401", status)
+	}
+
+	var result AdminReloadResult
+	if status := adminRequest(t, http.MethodPost, srv.URL+"/plugins/payments/reload", "s3cret", &result); status != http.StatusOK || result.Reloaded {
+		t.Errorf("Reload of an unchanged file = %d, %+v; want 200 without a reload", status, result)
+	}
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status := adminRequest(t, http.MethodPost, srv.URL+"/plugins/payments/reload", "s3cret", &result); status != http.StatusOK ||
+		!result.Reloaded || result.Plugin.Version != "2.0.0" {
+		t.Errorf("Reload = %d, %+v; want payments 2.0.0", status, result)
+	}
+	if status := adminRequest(t, http.MethodPost, srv.URL+"/plugins/orders/reload", "s3cret", &apiErr); status != http.StatusNotFound {
+		t.Errorf("Reload of an unknown plugin status = %d, want 404", status)
+	}
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/plugins/payments/disable"},
+		{http.MethodPost, "/plugins/payments/enable"},
+		{http.MethodDelete, "/plugins/payments"},
+	} {
+		if status := adminRequest(t, route.method, srv.URL+route.path, "s3cret", &apiErr); status != http.StatusNotImplemented {
+			t.Errorf("%s %s status = %d, want 501", route.method, route.path, status)
+		}
+	}
+}
+
+func TestAdminHandler_NoToken(t *testing.T) {
+	srv, _ := newAdminServer(t, "")
+	var apiErr AdminError
+	if status := adminRequest(t, http.MethodPost, srv.URL+"/plugins/payments/reload", "anything", &apiErr); status != http.StatusForbidden {
+		t.Errorf("Reload without a configured token status = %d, want 403", status)
+	}
+}
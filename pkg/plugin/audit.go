@@ -0,0 +1,315 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditRotatedLayout is the timestamp suffix of rotated audit logs
+const auditRotatedLayout = "20060102T150405.000000000"
+
+// AuditAction identifies an audited operation
+type AuditAction string
+
+const (
+	AuditLoad            AuditAction = "load"
+	AuditUpgrade         AuditAction = "upgrade"
+	AuditLoadFailed      AuditAction = "load_failed"
+	AuditUpgradeFailed   AuditAction = "upgrade_failed"
+	AuditChecksumFailed  AuditAction = "checksum_failed"
+	AuditSignatureFailed AuditAction = "signature_failed"
+	AuditBlocked         AuditAction = "blocked"
+	AuditUnload          AuditAction = "unload"
+	AuditRestart         AuditAction = "restart"
+	AuditGaveUp          AuditAction = "gave_up"
+	AuditBreakerReset    AuditAction = "breaker_reset"
+	AuditBreakerTripped  AuditAction = "breaker_tripped"
+	AuditBreakerConfig   AuditAction = "breaker_config_updated"
+	AuditConfigReload    AuditAction = "config_reloaded"
+	// AuditAdmin is a call to a mutating admin operation, named by AuditEvent.Operation
+	AuditAdmin AuditAction = "admin"
+)
+
+// Actors recorded in AuditEvent.Actor
+const (
+	// ActorStartup is the scan of PluginDir in NewManager
+	ActorStartup = "startup"
+	// ActorRestore reloads the plugins saved in Config.StateFile in NewManager
+	ActorRestore = "restore"
+	// ActorWatcher is hot reload
+	ActorWatcher = "watcher"
+	// ActorHost is the host application calling Manager methods
+	ActorHost = "host"
+	// ActorSupervisor restarts crashed process plugins
+	ActorSupervisor = "supervisor"
+	// ActorGC frees replaced instances
+	ActorGC = "gc"
+	// ActorShutdown frees every plugin in Manager.Close
+	ActorShutdown = "shutdown"
+	// ActorAdminToken is an admin API call authenticated with Config.AdminToken
+	ActorAdminToken = "admin_token"
+	// ActorAdmin is a gRPC admin call allowed by an authorizer set with WithAdminAuthorizer
+	ActorAdmin = "admin"
+	// ActorAnonymous is an admin API call that was refused for lack of a valid token
+	ActorAnonymous = "anonymous"
+)
+
+// AuditEvent is one record of the audit log
+type AuditEvent struct {
+	Time    time.Time   `json:"time"`
+	Action  AuditAction `json:"action"`
+	Actor   string      `json:"actor"`
+	Plugin  string      `json:"plugin,omitempty"`
+	Version string      `json:"version,omitempty"`
+	Path    string      `json:"path,omitempty"`
+	// Hash is the SHA-256 of the artifact, when known
+	Hash string `json:"hash,omitempty"`
+	// Operation is the admin operation of AuditAdmin records, e.g. "reload"
+	Operation string `json:"operation,omitempty"`
+	// Reason is why a breaker was reset or tripped by hand
+	Reason string `json:"reason,omitempty"`
+	// Remote is the client address of admin API calls
+	Remote string `json:"remote,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AuditSink receives audit events. Record is called synchronously by the goroutine that
+// performed the action, before the action's lifecycle event is published, so it must be
+// safe for concurrent use.
+type AuditSink interface {
+	Record(e AuditEvent)
+}
+
+// noopAuditSink drops audit events when no audit log is configured
+type noopAuditSink struct{}
+
+func (noopAuditSink) Record(AuditEvent) {}
+
+// WithAuditSink sets the sink receiving audit events, in place of the file configured
+// by Config.Audit
+func WithAuditSink(sink AuditSink) ManagerOption {
+	return func(m *Manager) {
+		if sink != nil {
+			m.audit = sink
+		}
+	}
+}
+
+// record stamps and records an audit event
+func (m *Manager) record(e AuditEvent) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Actor == "" {
+		e.Actor = ActorHost
+	}
+	m.audit.Record(e)
+}
+
+// auditEventFor returns the audit record of a lifecycle event, or false for events that
+// are not audited
+func auditEventFor(e Event) (AuditEvent, bool) {
+	record := AuditEvent{
+		Time:    e.Time,
+		Actor:   e.actor,
+		Plugin:  e.Plugin,
+		Version: e.Version,
+		Path:    e.Path,
+		Hash:    e.hash,
+	}
+	if e.Err != nil {
+		record.Error = e.Err.Error()
+	}
+
+	switch e.Type {
+	case EventLoaded:
+		record.Action = AuditLoad
+	case EventUpgraded:
+		record.Action = AuditUpgrade
+	case EventUpgradeFailed:
+		record.Action = AuditUpgradeFailed
+	case EventBlocked:
+		record.Action = AuditBlocked
+	case EventFreed:
+		record.Action = AuditUnload
+	case EventRestarted:
+		record.Action = AuditRestart
+	case EventGaveUp:
+		record.Action = AuditGaveUp
+	case EventBreakerReset:
+		record.Action = AuditBreakerReset
+		record.Reason = e.Reason
+	case EventBreakerTripped:
+		record.Action = AuditBreakerTripped
+		record.Reason = e.Reason
+	case EventBreakerConfigUpdated:
+		record.Action = AuditBreakerConfig
+	case EventConfigReloaded:
+		record.Action = AuditConfigReload
+	case EventLoadFailed:
+		var mismatch ErrChecksumMismatch
+		var signature ErrInvalidSignature
+		switch {
+		case errors.As(e.Err, &mismatch):
+			record.Action = AuditChecksumFailed
+			record.Hash = mismatch.Actual
+		case errors.As(e.Err, &signature):
+			record.Action = AuditSignatureFailed
+			if record.Hash == "" {
+				// The artifact was rejected before it was hashed for the cache
+				record.Hash, _ = fileSHA256(resolvePath(signature.Path))
+			}
+		default:
+			record.Action = AuditLoadFailed
+		}
+	default:
+		return AuditEvent{}, false
+	}
+	return record, true
+}
+
+// FileAuditSink appends audit events to a file as JSON lines. Each record is synced to
+// disk before Record returns. Once the file would grow beyond AuditConfig.MaxSize it is
+// renamed with a timestamp suffix and a new file is started.
+type FileAuditSink struct {
+	mu     sync.Mutex
+	config AuditConfig
+	logger Logger
+	file   *os.File // nil once closed
+	size   int64
+}
+
+// NewFileAuditSink opens or creates the audit log at config.Path. Write failures are
+// reported to logger, which may be nil.
+func NewFileAuditSink(config AuditConfig, logger Logger) (*FileAuditSink, error) {
+	if config.Path == "" {
+		return nil, errors.New("audit log path is required")
+	}
+	s := &FileAuditSink{config: config, logger: logger}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileAuditSink) open() error {
+	f, err := os.OpenFile(s.config.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// Record appends e to the log
+func (s *FileAuditSink) Record(e AuditEvent) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		s.fail("Failed to encode audit event", err)
+		return
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		s.fail("Dropping audit event recorded after the log was closed", os.ErrClosed)
+		return
+	}
+	if s.config.MaxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.config.MaxSize {
+		if err := s.rotate(); err != nil {
+			s.fail("Failed to rotate audit log", err)
+			if s.file == nil {
+				return
+			}
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		s.fail("Failed to write audit event", err)
+	}
+}
+
+// rotate moves the current log aside and starts a new one; the caller holds the lock
+func (s *FileAuditSink) rotate() error {
+	// Every record has been synced, so a failed close loses nothing
+	if err := s.file.Close(); err != nil {
+		s.fail("Failed to close audit log", err)
+	}
+	s.file = nil
+	rotated := s.config.Path + "." + time.Now().UTC().Format(auditRotatedLayout)
+	if err := os.Rename(s.config.Path, rotated); err != nil {
+		// Keep appending to the current log rather than losing events
+		if openErr := s.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		return err
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	s.pruneBackups()
+	return nil
+}
+
+// pruneBackups removes the oldest rotated logs beyond AuditConfig.MaxBackups
+func (s *FileAuditSink) pruneBackups() {
+	if s.config.MaxBackups <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(s.config.Path + ".*")
+	if err != nil {
+		return
+	}
+	var backups []string
+	for _, match := range matches {
+		if _, err := time.Parse(auditRotatedLayout, strings.TrimPrefix(match, s.config.Path+".")); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	if len(backups) <= s.config.MaxBackups {
+		return
+	}
+	// Timestamp suffixes sort chronologically
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-s.config.MaxBackups] {
+		if err := os.Remove(old); err != nil {
+			s.fail("Failed to remove rotated audit log", err)
+		}
+	}
+}
+
+func (s *FileAuditSink) fail(msg string, err error) {
+	if s.logger != nil {
+		s.logger.Error(msg, "path", s.config.Path, "error", err)
+	}
+}
+
+// Close closes the log; later events are dropped
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// auditRecorder is an AuditSink that keeps every event
+type auditRecorder struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+func (r *auditRecorder) Record(e AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// find returns the recorded events with the given action
+func (r *auditRecorder) find(action AuditAction) []AuditEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []AuditEvent
+	for _, e := range r.events {
+		if e.Action == action {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func TestAudit_Lifecycle(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+		"v2": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, map[string]InvokeFunc{}),
+	})
+	var path string
+	rec := &auditRecorder{}
+	m := newTestManager(t, func(config *Config) {
+		config.GCInterval = 0
+		config.GCGracePeriod = 0
+		path = writeTestPlugin(t, config.PluginDir, "payments", "v1")
+	}, WithAuditSink(rec))
+	v1Hash, _ := fileSHA256(path)
+	if loads := rec.find(AuditLoad); len(loads) != 1 || loads[0].Actor != ActorStartup || loads[0].Plugin != "payments" ||
+		loads[0].Version != "1.0.0" || loads[0].Hash != v1Hash || loads[0].Time.IsZero() {
+		t.Fatalf("Load records = %+v, want payments 1.0.0 loaded at startup", loads)
+	}
+
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	if upgrades := rec.find(AuditUpgrade); len(upgrades) != 1 || upgrades[0].Actor != ActorHost || upgrades[0].Version != "2.0.0" {
+		t.Errorf("Upgrade records = %+v, want 2.0.0 by the host", upgrades)
+	}
+
+	if _, err := m.GCNow(); err != nil {
+		t.Fatal(err)
+	}
+	if unloads := rec.find(AuditUnload); len(unloads) != 1 || unloads[0].Actor != ActorGC || unloads[0].Hash != v1Hash {
+		t.Errorf("Unload records after GC = %+v, want 1.0.0 freed by the collector", unloads)
+	}
+
+	m.Close()
+	if unloads := rec.find(AuditUnload); len(unloads) != 2 || unloads[1].Actor != ActorShutdown || unloads[1].Version != "2.0.0" {
+		t.Errorf("Unload records after Close = %+v, want 2.0.0 freed at shutdown", unloads)
+	}
+}
+
+func TestAudit_ChecksumFailure(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+	})
+	rec := &auditRecorder{}
+	m := newTestManager(t, func(config *Config) { config.VerifyChecksums = true }, WithAuditSink(rec))
+	path := writeTestPlugin(t, m.currentConfig().PluginDir, "payments", "v1")
+	if err := os.WriteFile(path+".sha256", []byte("deadbeef  payments.so\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := fileSHA256(path)
+	if err := m.LoadPlugin(path); err == nil {
+		t.Fatal("LoadPlugin() succeeded with a wrong checksum")
+	}
+	if failures := rec.find(AuditChecksumFailed); len(failures) != 1 || failures[0].Hash != hash || failures[0].Error == "" {
+		t.Errorf("Checksum failure records = %+v, want one with the artifact's hash", failures)
+	}
+}
+
+func TestAudit_Admin(t *testing.T) {
+	m, _ := newAdminManager(t, "s3cret")
+	rec := &auditRecorder{}
+	m.audit = rec
+	srv := httptest.NewServer(m.AdminHandler())
+	defer srv.Close()
+
+	var apiErr AdminError
+	adminRequest(t, http.MethodPost, srv.URL+"/plugins/payments/reload", "wrong", &apiErr)
+	var result AdminReloadResult
+	adminRequest(t, http.MethodPost, srv.URL+"/plugins/payments/reload", "s3cret", &result)
+
+	admin := rec.find(AuditAdmin)
+	if len(admin) != 2 {
+		t.Fatalf("Admin records = %+v, want a refused and an accepted reload", admin)
+	}
+	if admin[0].Actor != ActorAnonymous || admin[0].Operation != "reload" || admin[0].Plugin != "payments" ||
+		admin[0].Error == "" || admin[0].Remote == "" {
+		t.Errorf("Refused reload record = %+v", admin[0])
+	}
+	if admin[1].Actor != ActorAdminToken || admin[1].Error != "" {
+		t.Errorf("Reload record = %+v", admin[1])
+	}
+}
+
+func TestFileAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger := &testLogger{}
+	sink, err := NewFileAuditSink(AuditConfig{Path: path, MaxSize: 300, MaxBackups: 1}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"1.0.0", "1.0.1", "1.0.2", "1.0.3", "1.0.4"} {
+		sink.Record(AuditEvent{Action: AuditLoad, Actor: ActorHost, Plugin: "payments", Version: version,
+			Hash: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"})
+
+		// Every record is on disk before Record returns
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var last AuditEvent
+		for scanner := bufio.NewScanner(f); scanner.Scan(); {
+			if err := json.Unmarshal(scanner.Bytes(), &last); err != nil {
+				t.Fatalf("Audit log line %q: %v", scanner.Text(), err)
+			}
+		}
+		f.Close()
+		if last.Version != version {
+			t.Fatalf("Last record version = %q, want %q", last.Version, version)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.entries) > 0 {
+		t.Errorf("Sink logged %v", logger.entries)
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 1 {
+		t.Errorf("Rotated logs = %v, want MaxBackups of them", backups)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() > 300 {
+		t.Errorf("Audit log size = %v, %v; want at most MaxSize", info, err)
+	}
+}
@@ -0,0 +1,222 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// Backend loads plugins of one kind, such as shared objects opened in-process or
+// executables run as child processes. The Loader hashes, caches and verifies artifacts;
+// a backend only turns a path into a Plugin.
+type Backend interface {
+	// Load opens the plugin at path. It must return once ctx ends, which happens when the
+	// plugin's PluginTimeout passes or the manager closes.
+	Load(ctx context.Context, path string, cfg PluginSpecificConfig) (*Plugin, error)
+	// Unload releases what Load acquired once the plugin has been freed
+	Unload(p *Plugin) error
+	// Capabilities describes how the Loader and Manager treat the backend's plugins
+	Capabilities() BackendCaps
+}
+
+// BackendCaps describes a Backend
+type BackendCaps struct {
+	// Artifacts means Load reads a file, which the Loader hashes, caches and verifies
+	// against checksums, signatures and manifests. Without it the path is only a key
+	// and the backend returns the same Plugin for as long as it should stay loaded.
+	Artifacts bool
+	// InProcess means plugin code runs in the host process: a crash takes the host down
+	// and artifacts are shadow copied when Config.ShadowCopy is set
+	InProcess bool
+	// Unloadable means Unload releases the plugin's code or process
+	Unloadable bool
+}
+
+// WithBackend registers a backend under name, which PluginSpecificConfig.Options["backend"]
+// selects. Registering BackendNative or BackendProcess replaces the built-in backend.
+func WithBackend(name string, b Backend) ManagerOption {
+	return func(m *Manager) {
+		if name != "" && b != nil {
+			m.backends[name] = b
+		}
+	}
+}
+
+// registerDefaultBackends adds the built-in backends that options did not replace
+func (m *Manager) registerDefaultBackends() {
+	if _, ok := m.backends[BackendNative]; !ok {
+		m.backends[BackendNative] = &nativeBackend{loader: m.loader}
+	}
+	if _, ok := m.backends[BackendProcess]; !ok {
+		m.backends[BackendProcess] = &processBackend{loader: m.loader}
+	}
+}
+
+// checkBackends verifies that every configured backend is registered
+func (m *Manager) checkBackends() error {
+	check := func(scope string, cfg PluginSpecificConfig) error {
+		name, ok := cfg.Options[OptionBackend].(string)
+		if !ok {
+			return nil
+		}
+		if _, registered := m.backends[name]; !registered {
+			return fmt.Errorf("%s: no backend registered as %q", scope, name)
+		}
+		return nil
+	}
+	if err := check("default plugin config", m.currentConfig().DefaultPluginConfig); err != nil {
+		return err
+	}
+	for name, cfg := range m.currentConfig().PluginConfigs {
+		if err := check("plugin "+name, cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pluginBackend selects how the plugin at path is run: by Options["backend"] when set,
+// otherwise executables are run as processes
+func pluginBackend(path string, pluginConfig PluginSpecificConfig) string {
+	if backend, ok := pluginConfig.Options[OptionBackend].(string); ok && backend != "" {
+		return backend
+	}
+	if isExecutable(path) {
+		return BackendProcess
+	}
+	return BackendNative
+}
+
+// backendFor returns the backend that loads the plugin at path
+func (m *Manager) backendFor(path string, cfg PluginSpecificConfig) (Backend, error) {
+	name := pluginBackend(path, cfg)
+	if b, ok := m.backends[name]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("no backend registered as %q", name)
+}
+
+// nativeBackend opens shared objects with the standard library's plugin package
+type nativeBackend struct {
+	loader *Loader
+}
+
+// Load opens the plugin. plugin.Open cannot be interrupted, so an abandoned load goes on
+// in the background.
+func (b *nativeBackend) Load(ctx context.Context, path string, cfg PluginSpecificConfig) (*Plugin, error) {
+	type openResult struct {
+		plug symbolLookup
+		err  error
+	}
+	done := make(chan openResult, 1)
+	open := openPlugin
+
+	go func() {
+		plug, err := open(path)
+		done <- openResult{plug: plug, err: err}
+	}()
+
+	var plug symbolLookup
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("plugin load timeout: %w", ctx.Err())
+	case res := <-done:
+		if res.err != nil {
+			return nil, classifyOpenError(path, res.err)
+		}
+		plug = res.plug
+	}
+
+	exportSymbol, functionsSymbol := b.loader.manager.currentConfig().symbolNames(cfg)
+	return b.loader.validateAndCreatePlugin(plug, exportSymbol, functionsSymbol)
+}
+
+// Unload does nothing: the Go runtime cannot unload a plugin
+func (b *nativeBackend) Unload(p *Plugin) error {
+	return nil
+}
+
+func (b *nativeBackend) Capabilities() BackendCaps {
+	return BackendCaps{Artifacts: true, InProcess: true}
+}
+
+// processBackend runs executables built with 'chameleon build --backend process'
+type processBackend struct {
+	loader *Loader
+}
+
+func (b *processBackend) Load(ctx context.Context, path string, cfg PluginSpecificConfig) (*Plugin, error) {
+	exportSymbol, functionsSymbol := b.loader.manager.currentConfig().symbolNames(cfg)
+	plug, err := openProcess(ctx, path, exportSymbol, functionsSymbol, b.loader.logger)
+	if err != nil {
+		return nil, err
+	}
+	p, err := b.loader.validateAndCreatePlugin(plug, exportSymbol, functionsSymbol)
+	if err != nil {
+		plug.bureau.kill()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Unload stops the plugin's process
+func (b *processBackend) Unload(p *Plugin) error {
+	if pb, ok := p.bureau.(*processBureau); ok {
+		pb.kill()
+	}
+	return nil
+}
+
+func (b *processBackend) Capabilities() BackendCaps {
+	return BackendCaps{Artifacts: true, Unloadable: true}
+}
+
+// MemoryBackend serves Bureaus registered in the host process, so plugins can be loaded
+// without building artifacts, e.g. in tests. Register it with WithBackend and select it
+// with PluginSpecificConfig.Options["backend"].
+type MemoryBackend struct {
+	mu      sync.Mutex
+	plugins map[string]*Plugin // by cleaned path
+}
+
+// NewMemoryBackend creates an empty MemoryBackend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{plugins: make(map[string]*Plugin)}
+}
+
+// Register makes b loadable from path with the given functions; nil functions are built
+// from b's methods via reflection. Registering a path again affects later loads only.
+func (mb *MemoryBackend) Register(path string, b Bureau, funcs map[string]InvokeFunc) {
+	p := NewPlugin(b)
+	if funcs == nil {
+		funcs, _ = reflectFunctions(b)
+		p.signatures = reflectSignatures(b, funcs)
+	}
+	for name, fn := range funcs {
+		p.RegisterFunc(name, fn)
+	}
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.plugins[filepath.Clean(path)] = p
+}
+
+func (mb *MemoryBackend) Load(ctx context.Context, path string, cfg PluginSpecificConfig) (*Plugin, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	p, ok := mb.plugins[filepath.Clean(path)]
+	if !ok {
+		return nil, fmt.Errorf("no plugin registered at %s", path)
+	}
+	return p, nil
+}
+
+// Unload does nothing; registered plugins stay loadable
+func (mb *MemoryBackend) Unload(p *Plugin) error {
+	return nil
+}
+
+func (mb *MemoryBackend) Capabilities() BackendCaps {
+	return BackendCaps{InProcess: true}
+}
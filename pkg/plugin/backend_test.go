@@ -0,0 +1,66 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// countingBackend counts the plugins its MemoryBackend unloads
+type countingBackend struct {
+	*MemoryBackend
+	unloads atomic.Int32
+}
+
+func (b *countingBackend) Unload(p *Plugin) error {
+	b.unloads.Add(1)
+	return b.MemoryBackend.Unload(p)
+}
+
+func TestMemoryBackend(t *testing.T) {
+	backend := &countingBackend{MemoryBackend: NewMemoryBackend()}
+	v1 := &fakeBureau{name: "payments", version: "1.0.0"}
+	v2 := &fakeBureau{name: "payments", version: "2.0.0"}
+	backend.Register("mem/payments", v1, map[string]InvokeFunc{"Pay": returning("v1")})
+
+	m := newTestManager(t, func(config *Config) {
+		config.GCInterval = 0
+		config.GCGracePeriod = 0
+		config.PluginConfigs["payments"] = PluginSpecificConfig{Options: map[string]interface{}{OptionBackend: "memory"}}
+	}, WithBackend("memory", backend))
+
+	// No artifact is needed, and loading the same registration again is a no-op
+	for i := 0; i < 2; i++ {
+		if err := m.LoadPlugin("mem/payments"); err != nil {
+			t.Fatalf("LoadPlugin() error = %v", err)
+		}
+	}
+	if result, err := m.Call(context.Background(), "payments", "Pay"); err != nil || result != "v1" {
+		t.Errorf("Call() = %v, %v, want v1", result, err)
+	}
+
+	// A new registration upgrades the plugin; the replaced one is freed and unloaded
+	backend.Register("mem/payments", v2, map[string]InvokeFunc{"Pay": returning("v2")})
+	if err := m.LoadPlugin("mem/payments"); err != nil {
+		t.Fatalf("LoadPlugin(v2) error = %v", err)
+	}
+	if result, _ := m.Call(context.Background(), "payments", "Pay"); result != "v2" {
+		t.Errorf("Call() = %v, want v2", result)
+	}
+	if freed, err := m.GCNow(); freed != 1 || err != nil {
+		t.Fatalf("GCNow() = %d, %v; want 1, nil", freed, err)
+	}
+	if !v1.isFreed() || backend.unloads.Load() != 1 {
+		t.Errorf("Expected v1 to be freed and unloaded once, freed = %v, unloads = %d", v1.isFreed(), backend.unloads.Load())
+	}
+}
+
+func TestNewManager_UnknownBackend(t *testing.T) {
+	config := DefaultConfig()
+	config.AllowHotReload = false
+	config.PluginConfigs["payments"] = PluginSpecificConfig{Options: map[string]interface{}{OptionBackend: "wasm"}}
+	if _, err := NewManager(context.Background(), config); err == nil || !strings.Contains(err.Error(), `"wasm"`) {
+		t.Errorf("NewManager() error = %v, want an unknown backend error", err)
+	}
+}
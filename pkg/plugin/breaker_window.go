@@ -0,0 +1,154 @@
+package plugin
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// windowBuckets is the number of buckets a time-based window is divided into; calls
+// leave the window one bucket at a time
+const windowBuckets = 10
+
+// callWindow counts the outcomes of recent calls for TripOnFailureRate. Implementations
+// use only atomics, since every call records into the window: concurrent updates may
+// briefly skew the counts, which the failure-rate decision tolerates.
+type callWindow interface {
+	record(failed bool)
+	// counts returns the calls and failures in the window
+	counts() (calls, failures int64)
+	reset()
+}
+
+// newCallWindow returns the window configured by config, a time-based one telling time
+// by now when WindowDuration is set
+func newCallWindow(config CircuitBreakerConfig, now func() time.Time) callWindow {
+	if config.WindowDuration > 0 {
+		return newTimeWindow(config.WindowDuration, now)
+	}
+	return newCountWindow(config.WindowSize)
+}
+
+// countWindow keeps the outcomes of the last size calls in a ring
+type countWindow struct {
+	slots    []atomic.Uint32 // outcome of each slot: slotEmpty, slotSuccess or slotFailure
+	next     atomic.Uint64
+	calls    atomic.Int64
+	failures atomic.Int64
+}
+
+const (
+	slotEmpty uint32 = iota
+	slotSuccess
+	slotFailure
+)
+
+func newCountWindow(size int) *countWindow {
+	if size <= 0 {
+		size = 1
+	}
+	return &countWindow{slots: make([]atomic.Uint32, size)}
+}
+
+func (w *countWindow) record(failed bool) {
+	outcome := slotSuccess
+	if failed {
+		outcome = slotFailure
+	}
+	slot := &w.slots[(w.next.Add(1)-1)%uint64(len(w.slots))]
+	w.account(slot.Swap(outcome), -1)
+	w.account(outcome, 1)
+}
+
+// account adds delta to the totals of an outcome
+func (w *countWindow) account(outcome uint32, delta int64) {
+	switch outcome {
+	case slotFailure:
+		w.failures.Add(delta)
+		fallthrough
+	case slotSuccess:
+		w.calls.Add(delta)
+	}
+}
+
+func (w *countWindow) counts() (int64, int64) {
+	return w.calls.Load(), w.failures.Load()
+}
+
+func (w *countWindow) reset() {
+	for i := range w.slots {
+		w.account(w.slots[i].Swap(slotEmpty), -1)
+	}
+}
+
+// timeWindow counts the calls of the last duration in windowBuckets buckets. Each bucket
+// packs the calls and failures of its interval into one word, and the window keeps the
+// interval of its newest bucket: moving it on clears the buckets of the intervals skipped.
+type timeWindow struct {
+	buckets [windowBuckets]atomic.Uint64 // failures in the high 32 bits, calls in the low 32
+	epoch   atomic.Int64                 // index of the bucket-width interval of the newest bucket
+	width   int64                        // bucket width in nanoseconds
+	now     func() time.Time
+}
+
+// failureCount is added to a bucket for a failed call, on top of the call itself
+const failureCount = 1 << 32
+
+func newTimeWindow(duration time.Duration, now func() time.Time) *timeWindow {
+	width := int64(duration) / windowBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return &timeWindow{width: width, now: now}
+}
+
+// advance moves the window on to the current interval and returns its index
+func (w *timeWindow) advance() int64 {
+	epoch := w.now().UnixNano() / w.width
+	for {
+		last := w.epoch.Load()
+		if epoch <= last {
+			return epoch
+		}
+		if w.epoch.CompareAndSwap(last, epoch) {
+			// The buckets of the intervals since last have left the window
+			for e := max(last+1, epoch-windowBuckets+1); e <= epoch; e++ {
+				w.buckets[e%windowBuckets].Store(0)
+			}
+			return epoch
+		}
+	}
+}
+
+func (w *timeWindow) record(failed bool) {
+	delta := uint64(1)
+	if failed {
+		delta += failureCount
+	}
+	w.buckets[w.advance()%windowBuckets].Add(delta)
+}
+
+func (w *timeWindow) counts() (calls, failures int64) {
+	w.advance()
+	for i := range w.buckets {
+		b := w.buckets[i].Load()
+		calls += int64(uint32(b))
+		failures += int64(b >> 32)
+	}
+	return calls, failures
+}
+
+// copy returns a window holding the counts so far, telling time by the same clock
+func (w *timeWindow) copy() *timeWindow {
+	c := &timeWindow{width: w.width, now: w.now}
+	c.epoch.Store(w.epoch.Load())
+	for i := range w.buckets {
+		c.buckets[i].Store(w.buckets[i].Load())
+	}
+	return c
+}
+
+func (w *timeWindow) reset() {
+	for i := range w.buckets {
+		w.buckets[i].Store(0)
+	}
+}
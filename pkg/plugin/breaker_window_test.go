@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
+)
+
+func TestCountWindow(t *testing.T) {
+	w := newCountWindow(4)
+	for _, failed := range []bool{true, true, false, false, false, false} {
+		w.record(failed)
+	}
+	if calls, failures := w.counts(); calls != 4 || failures != 0 {
+		t.Errorf("counts() = %d, %d; want the failures pushed out of the window", calls, failures)
+	}
+	w.record(true)
+	if calls, failures := w.counts(); calls != 4 || failures != 1 {
+		t.Errorf("counts() = %d, %d; want 4, 1", calls, failures)
+	}
+	w.reset()
+	if calls, failures := w.counts(); calls != 0 || failures != 0 {
+		t.Errorf("counts() after reset = %d, %d", calls, failures)
+	}
+}
+
+func TestCountWindow_Concurrent(t *testing.T) {
+	w := newCountWindow(100)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				w.record(g%2 == 0)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if calls, failures := w.counts(); calls != 100 || failures < 0 || failures > 100 {
+		t.Errorf("counts() = %d, %d; want 100 calls", calls, failures)
+	}
+}
+
+func TestTimeWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	w := newTimeWindow(10*time.Second, func() time.Time { return now })
+	w.record(true)
+	w.record(false)
+	now = now.Add(5 * time.Second)
+	w.record(true)
+	if calls, failures := w.counts(); calls != 3 || failures != 2 {
+		t.Errorf("counts() = %d, %d; want 3, 2", calls, failures)
+	}
+	now = now.Add(6 * time.Second)
+	if calls, failures := w.counts(); calls != 1 || failures != 1 {
+		t.Errorf("counts() = %d, %d; want the first calls expired", calls, failures)
+	}
+	now = now.Add(time.Minute)
+	w.record(false)
+	if calls, failures := w.counts(); calls != 1 || failures != 0 {
+		t.Errorf("counts() = %d, %d; want only the latest call", calls, failures)
+	}
+}
+
+func TestTimeWindow_Concurrent(t *testing.T) {
+	var now atomic.Int64
+	now.Store(time.Unix(1000, 0).UnixNano())
+	w := newTimeWindow(10*time.Second, func() time.Time { return time.Unix(0, now.Load()) })
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				w.record(g%2 == 0)
+				w.counts()
+			}
+		}(g)
+	}
+	wg.Wait()
+	if calls, failures := w.counts(); calls != 8000 || failures != 4000 {
+		t.Errorf("counts() = %d, %d; want 8000, 4000", calls, failures)
+	}
+
+	// Moving on while calls are recorded keeps the buckets still in the window
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				w.record(false)
+				now.Add(int64(time.Millisecond))
+			}
+		}()
+	}
+	wg.Wait()
+	if calls, _ := w.counts(); calls <= 8000 || calls > 16000 {
+		t.Errorf("counts() = %d calls after the clock moved 8s, want the first 8000 and about as many more", calls)
+	}
+}
+
+func TestCircuitBreaker_FailureRate(t *testing.T) {
+	clk := clocktest.NewFake(time.Time{})
+	cb := NewCircuitBreaker(context.Background(), CircuitBreakerConfig{
+		Enabled:              true,
+		TripPolicy:           TripOnFailureRate,
+		FailureRateThreshold: 0.5,
+		MinimumCalls:         10,
+		WindowSize:           20,
+		ResetInterval:        time.Hour,
+		OpenDuration:         20 * time.Millisecond,
+	}, &testLogger{}, WithBreakerClock(clk))
+	defer cb.Close()
+
+	// Sparse failures among many successes never trip it
+	for i := 0; i < 1000; i++ {
+		if i%10 == 0 {
+			cb.RecordFailure()
+		} else {
+			cb.RecordSuccess()
+		}
+	}
+	if cb.State() != StateClosed {
+		t.Fatalf("State = %v at a 10%% failure rate, want closed", cb.State())
+	}
+
+	for i := 0; i < 20 && cb.State() == StateClosed; i++ {
+		cb.RecordFailure()
+	}
+	if cb.State() != StateOpen {
+		t.Fatalf("State = %v after consecutive failures, want open", cb.State())
+	}
+
+	// Closing through a probe starts a fresh window
+	clk.Advance(40 * time.Millisecond)
+	if !cb.Allow() {
+		t.Fatal("Breaker did not admit a probe")
+	}
+	cb.RecordSuccess()
+	cb.RecordFailure()
+	if cb.State() != StateClosed {
+		t.Errorf("State = %v after one failure in a fresh window, want closed", cb.State())
+	}
+}
+
+func TestCircuitBreaker_FailureRateNeedsMinimumCalls(t *testing.T) {
+	cb := NewCircuitBreaker(context.Background(), CircuitBreakerConfig{
+		Enabled:              true,
+		TripPolicy:           TripOnFailureRate,
+		FailureRateThreshold: 0.5,
+		MinimumCalls:         5,
+		WindowDuration:       time.Minute,
+		ResetInterval:        time.Hour,
+		OpenDuration:         time.Minute,
+	}, &testLogger{})
+	defer cb.Close()
+
+	for i := 0; i < 4; i++ {
+		cb.RecordFailure()
+	}
+	if cb.State() != StateClosed {
+		t.Fatalf("State = %v with fewer than MinimumCalls calls, want closed", cb.State())
+	}
+	cb.RecordFailure()
+	if cb.State() != StateOpen {
+		t.Errorf("State = %v, want open", cb.State())
+	}
+}
@@ -0,0 +1,39 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+)
+
+// callIDKey is the context key of the call ID
+type callIDKey struct{}
+
+// WithCallID returns a copy of ctx carrying id, to correlate a call to a plugin with the
+// request that made it. Manager.Call passes it on to the plugin function and into its
+// logs and errors; calls whose context has none are given a new one.
+func WithCallID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, callIDKey{}, id)
+}
+
+// CallIDFromContext returns the call ID carried by ctx, if any. Inside a plugin function
+// it is the ID of the call being served.
+func CallIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(callIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// ensureCallID returns ctx with a call ID, adding a new one when it has none
+func ensureCallID(ctx context.Context) (context.Context, string) {
+	if id, ok := CallIDFromContext(ctx); ok {
+		return ctx, id
+	}
+	id := newCallID()
+	return WithCallID(ctx, id), id
+}
+
+// newCallID returns 16 random hex digits; IDs correlate logs, so they need not be
+// unpredictable
+func newCallID() string {
+	return fmt.Sprintf("%016x", rand.Uint64())
+}
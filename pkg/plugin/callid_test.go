@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManager_CallID(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{
+			"CallID": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				id, _ := CallIDFromContext(ctx)
+				return id, nil
+			},
+			"Fail": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				time.Sleep(20 * time.Millisecond)
+				return nil, errors.New("declined")
+			},
+		}),
+	})
+	logger := &testLogger{}
+	m := newTestManager(t, func(config *Config) {
+		config.PluginConfigs["payments"] = PluginSpecificConfig{SlowCallThreshold: 10 * time.Millisecond}
+	}, WithLogger(logger))
+	loadTestPlugin(t, m, "payments", "v1")
+	ctx := context.Background()
+
+	// Calls without an ID are each given a new one
+	first, err := m.Call(ctx, "payments", "CallID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := m.Call(ctx, "payments", "CallID")
+	if id, _ := first.(string); len(id) != 16 || first == second {
+		t.Errorf("generated call IDs = %q, %q, want two distinct 16-digit IDs", first, second)
+	}
+
+	// The caller's ID reaches the plugin, its error and the slow call log
+	ctx = WithCallID(ctx, "request-42")
+	if id, _ := m.Call(ctx, "payments", "CallID"); id != "request-42" {
+		t.Errorf("plugin saw call ID %q, want request-42", id)
+	}
+	_, err = m.Call(ctx, "payments", "Fail")
+	var callErr CallError
+	if !errors.As(err, &callErr) || callErr.CallID != "request-42" {
+		t.Errorf("Call(Fail) error = %#v, want a CallError for call request-42", err)
+	}
+	warnings := logger.fields("WARN: Slow plugin call")
+	if len(warnings) != 1 || warnings[0]["call_id"] != "request-42" {
+		t.Errorf("Slow call warnings = %v, want one for call request-42", warnings)
+	}
+}
+
+func TestCallIDFromContext(t *testing.T) {
+	if id, ok := CallIDFromContext(context.Background()); ok {
+		t.Errorf("CallIDFromContext(Background) = %q, want none", id)
+	}
+	if _, ok := CallIDFromContext(WithCallID(context.Background(), "")); ok {
+		t.Error("CallIDFromContext reports an empty call ID")
+	}
+}
@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// checksumSuffix is appended to a plugin path to locate its checksum sidecar
+const checksumSuffix = ".sha256"
+
+// fileSHA256 returns the hex encoded SHA-256 of the file content
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readChecksumFile reads the expected digest from a sidecar file. Both a bare digest
+// and the "<digest>  <filename>" format written by sha256sum are accepted.
+func readChecksumFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file %s is empty", path)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// verifyChecksum compares the plugin's digest with its .sha256 sidecar
+func verifyChecksum(path, actual string) error {
+	expected, err := readChecksumFile(path + checksumSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrChecksumMismatch{Path: path, Actual: actual}
+		}
+		return fmt.Errorf("failed to read checksum: %w", err)
+	}
+	if expected != actual {
+		return ErrChecksumMismatch{Path: path, Expected: expected, Actual: actual}
+	}
+	return nil
+}
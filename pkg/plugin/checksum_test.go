@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPlugin_VerifyChecksums(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+	})
+	shadowBase := t.TempDir()
+	m, cleanup := setupTestManager(t, func(config *Config) {
+		config.VerifyChecksums = true
+		config.ShadowDir = shadowBase
+	})
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := fileSHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing sidecar
+	var mismatch ErrChecksumMismatch
+	if err := m.LoadPlugin(path); !errors.As(err, &mismatch) || mismatch.Expected != "" {
+		t.Fatalf("Expected missing checksum error, got %v", err)
+	}
+
+	// Wrong digest
+	if err := os.WriteFile(path+".sha256", []byte("deadbeef  payments.so\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); !errors.As(err, &mismatch) || mismatch.Expected != "deadbeef" || mismatch.Actual != hash {
+		t.Fatalf("Expected checksum mismatch, got %v", err)
+	}
+
+	// Matching digest in sha256sum format
+	if err := os.WriteFile(path+".sha256", []byte(hash+"  payments.so\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	if info := m.ListPlugins(); len(info) != 1 || info[0].Hash != hash {
+		t.Errorf("Expected verified hash in PluginInfo, got %+v", info)
+	}
+
+	// The verified plugin is opened from its private copy; rejected copies are removed
+	shadow := shadowPathOf(t, m, "payments")
+	if content, err := os.ReadFile(shadow); err != nil || string(content) != "v1" {
+		t.Errorf("Shadow copy content = %q, %v, want v1", content, err)
+	}
+	copies, err := filepath.Glob(filepath.Join(filepath.Dir(shadow), "*"))
+	if err != nil || len(copies) != 1 {
+		t.Errorf("Expected only the opened copy in the shadow directory, got %v", copies)
+	}
+}
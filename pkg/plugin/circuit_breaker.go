@@ -2,8 +2,13 @@ package plugin
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock"
 )
 
 type CircuitState int32
@@ -14,30 +19,119 @@ const (
 	StateHalfOpen CircuitState = 2
 )
 
-// CircuitBreaker implements the circuit breaker pattern
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int32(s))
+	}
+}
+
+// CircuitBreaker implements the circuit breaker pattern. Its states change as follows:
+//
+//   - Closed to Open: after MaxFailures failures, or MaxTimeouts timeouts, since the
+//     breaker closed or last cleared its counts, which it does every ResetInterval; under
+//     TripOnFailureRate when the failure rate of the window exceeds FailureRateThreshold;
+//     or by Trip.
+//   - Open to HalfOpen: once OpenDuration has passed since the breaker opened, at the
+//     first call to Allow or when the reset loop wakes, whichever comes first. Calls
+//     before then are rejected.
+//   - HalfOpen to Closed: once HalfOpenMaxCalls trial calls have succeeded.
+//   - HalfOpen to Open: as soon as a trial call fails or times out; OpenDuration starts over.
+//   - Any state to Closed: by Reset, or by Reconfigure disabling the breaker.
 type CircuitBreaker struct {
 	state       atomic.Int32 // use int32 to represent state
 	failures    atomic.Int32
+	timeouts    atomic.Int32 // timed-out calls counted toward MaxTimeouts
 	lastFailure atomic.Int64 // store Unix nanosecond timestamp
-	config      CircuitBreakerConfig
-	resetTimer  *time.Timer
+	trippedAt   atomic.Int64 // Unix nanoseconds when the breaker last left StateClosed, 0 while closed
+	openedAt    atomic.Int64 // Unix nanoseconds when the breaker last opened, which OpenDuration counts from
+	clearedAt   atomic.Int64 // Unix nanoseconds when a closed breaker last cleared its counts
+	settings    atomic.Pointer[breakerSettings]
+	resetTimer  clock.Timer
+	wake        chan struct{} // tells the reset loop to rearm its timer after a state change
+	clock       clock.Clock
 	cancel      context.CancelFunc
 	done        chan struct{}
+	closeOnce   sync.Once
 	logger      Logger
+	// ctx is the parent of the reset loops of method breakers
+	ctx     context.Context
+	methods sync.Map // map[string]*CircuitBreaker, per function under BreakerScopeMethod
+
+	// mu guards leaving StateOpen and the probe counts of StateHalfOpen
+	mu        sync.Mutex
+	probes    int    // trial calls admitted since the breaker went half-open
+	succeeded int    // of which succeeded
+	reason    string // why the breaker was tripped by hand, until it closes
+	// tripped is the last Trip under BreakerScopeMethod; breakers of functions first
+	// called within its OpenDuration start open
+	tripped breakerTrip
+
+	// Statistics reported by StateSnapshot
+	trips        atomic.Int64 // times the breaker opened
+	rejected     atomic.Int64 // calls refused by Allow
+	consecutive  atomic.Int32 // failures since the last successful call
+	transitionAt atomic.Int64 // Unix nanoseconds of the last state change
+}
+
+// breakerSettings is the configuration of a breaker with the window it implies; Reconfigure
+// replaces both at once
+type breakerSettings struct {
+	config CircuitBreakerConfig
+	// window counts recent calls when the breaker trips on TripOnFailureRate, nil otherwise
+	window callWindow
+}
+
+// breakerTrip records a Trip: its reason and when, in Unix nanoseconds
+type breakerTrip struct {
+	reason string
+	at     int64
 }
 
-func NewCircuitBreaker(ctx context.Context, config CircuitBreakerConfig, logger Logger) *CircuitBreaker {
+// BreakerOption configures a CircuitBreaker
+type BreakerOption func(*CircuitBreaker)
+
+// WithBreakerClock makes the breaker tell time, and time out, by c instead of the
+// system clock
+func WithBreakerClock(c clock.Clock) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		if c != nil {
+			cb.clock = c
+		}
+	}
+}
+
+func NewCircuitBreaker(ctx context.Context, config CircuitBreakerConfig, logger Logger, opts ...BreakerOption) *CircuitBreaker {
 	ctx, cancel := context.WithCancel(ctx)
 	cb := &CircuitBreaker{
-		config: config,
+		clock:  clock.Real(),
+		ctx:    ctx,
 		cancel: cancel,
+		wake:   make(chan struct{}, 1),
 		done:   make(chan struct{}),
 		logger: logger,
 	}
+	for _, opt := range opts {
+		opt(cb)
+	}
+	config = migrateBreakerConfig(config)
+	cb.settings.Store(&breakerSettings{config: config, window: cb.newWindow(config)})
 	cb.state.Store(int32(StateClosed))
+	now := cb.clock.Now().UnixNano()
+	cb.transitionAt.Store(now)
+	cb.clearedAt.Store(now)
 
 	// Start the reset timer
-	cb.resetTimer = time.NewTimer(config.ResetInterval)
+	cb.resetTimer = cb.clock.NewTimer(config.ResetInterval)
+	if config.ResetInterval <= 0 {
+		cb.resetTimer.Stop()
+	}
 	go func() {
 		cb.resetLoop(ctx)
 	}()
@@ -45,6 +139,25 @@ func NewCircuitBreaker(ctx context.Context, config CircuitBreakerConfig, logger
 	return cb
 }
 
+// newWindow returns the call window config trips on, nil under TripOnFailureCount
+func (cb *CircuitBreaker) newWindow(config CircuitBreakerConfig) callWindow {
+	if config.TripPolicy != TripOnFailureRate {
+		return nil
+	}
+	return newCallWindow(config, cb.clock.Now)
+}
+
+// config returns the breaker's current configuration
+func (cb *CircuitBreaker) config() *CircuitBreakerConfig {
+	return &cb.settings.Load().config
+}
+
+// enabled reports whether the breaker guards calls; a disabled breaker lets every call
+// through and records nothing
+func (cb *CircuitBreaker) enabled() bool {
+	return cb != nil && cb.settings.Load().config.Enabled
+}
+
 func (cb *CircuitBreaker) resetLoop(ctx context.Context) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -56,64 +169,465 @@ func (cb *CircuitBreaker) resetLoop(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		case <-cb.resetTimer.C:
-			if cb.state.Load() == int32(StateOpen) {
-				cb.state.CompareAndSwap(int32(StateOpen), int32(StateHalfOpen))
-				cb.failures.Store(0)
-			}
-			cb.resetTimer.Reset(cb.config.ResetInterval)
+		case <-cb.wake:
+		case <-cb.resetTimer.C():
+			cb.tick()
+		}
+		if wait, ok := cb.nextTick(); ok {
+			cb.resetTimer.Reset(wait)
+		} else {
+			cb.resetTimer.Stop()
+		}
+	}
+}
+
+// tick moves an open breaker whose OpenDuration has passed to StateHalfOpen, and clears
+// the counts of a closed breaker whose ResetInterval has. The timer may fire late or for
+// a state since left, so both are checked against the clock.
+func (cb *CircuitBreaker) tick() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch CircuitState(cb.state.Load()) {
+	case StateOpen:
+		if cb.timedOut() {
+			cb.halfOpen()
+		}
+	case StateClosed:
+		interval := cb.config().ResetInterval
+		if now := cb.clock.Now(); interval > 0 && !now.Before(time.Unix(0, cb.clearedAt.Load()).Add(interval)) {
+			cb.failures.Store(0)
+			cb.timeouts.Store(0)
+			cb.clearedAt.Store(now.UnixNano())
 		}
 	}
 }
 
+// nextTick returns how long the reset loop sleeps: until an open breaker's OpenDuration
+// ends, or a closed breaker's ResetInterval. A half-open or disabled breaker has no
+// deadline; its next state change wakes the loop.
+func (cb *CircuitBreaker) nextTick() (time.Duration, bool) {
+	config := cb.config()
+	if !config.Enabled {
+		return 0, false
+	}
+	now := cb.clock.Now()
+	switch CircuitState(cb.state.Load()) {
+	case StateOpen:
+		return cb.openUntil().Sub(now), true
+	case StateClosed:
+		if config.ResetInterval > 0 {
+			return time.Unix(0, cb.clearedAt.Load()).Add(config.ResetInterval).Sub(now), true
+		}
+	}
+	return 0, false
+}
+
+// rearm wakes the reset loop to recompute its deadline
+func (cb *CircuitBreaker) rearm() {
+	select {
+	case cb.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Allow reports whether a call may go through. A half-open breaker admits at most
+// HalfOpenMaxCalls trial calls and rejects the rest until their outcome closes or
+// reopens it, so every call admitted must be followed by RecordSuccess or RecordFailure.
 func (cb *CircuitBreaker) Allow() bool {
-	if cb == nil {
+	if cb == nil || cb.state.Load() == int32(StateClosed) || !cb.enabled() {
 		return true
 	}
 
-	currentState := CircuitState(cb.state.Load())
-	switch currentState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch CircuitState(cb.state.Load()) {
 	case StateClosed:
 		return true
-	case StateHalfOpen:
-		return true
 	case StateOpen:
-		lastFailureTime := time.Unix(0, cb.lastFailure.Load())
-		if time.Since(lastFailureTime) > cb.config.TimeoutDuration {
-			if cb.state.CompareAndSwap(int32(StateOpen), int32(StateHalfOpen)) {
-				cb.failures.Store(0)
-			}
-			return true
+		if !cb.timedOut() {
+			cb.rejected.Add(1)
+			return false
 		}
+		cb.halfOpen()
+	}
+	if cb.probes >= cb.halfOpenMaxCalls() {
+		cb.rejected.Add(1)
+		return false
+	}
+	cb.probes++
+	return true
+}
+
+// rejecting reports whether Allow would refuse a call, without admitting one
+func (cb *CircuitBreaker) rejecting() bool {
+	if !cb.enabled() {
 		return false
+	}
+	switch CircuitState(cb.state.Load()) {
+	case StateOpen:
+		return !cb.timedOut()
+	case StateHalfOpen:
+		cb.mu.Lock()
+		defer cb.mu.Unlock()
+		return cb.state.Load() == int32(StateHalfOpen) && cb.probes >= cb.halfOpenMaxCalls()
 	default:
-		return true
+		return false
 	}
 }
 
+// timedOut reports whether an open breaker has waited OpenDuration since it opened
+func (cb *CircuitBreaker) timedOut() bool {
+	return !cb.clock.Now().Before(cb.openUntil())
+}
+
+// openUntil returns when an open breaker's OpenDuration ends
+func (cb *CircuitBreaker) openUntil() time.Time {
+	return time.Unix(0, cb.openedAt.Load()).Add(cb.config().OpenDuration)
+}
+
+// halfOpen moves an open breaker to StateHalfOpen; the caller holds mu
+func (cb *CircuitBreaker) halfOpen() {
+	cb.probes = 0
+	cb.succeeded = 0
+	cb.failures.Store(0)
+	cb.timeouts.Store(0)
+	cb.setState(StateHalfOpen)
+}
+
+// setState changes the state, counting transitions for StateSnapshot
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	if CircuitState(cb.state.Swap(int32(state))) != state {
+		cb.transitioned(state)
+	}
+}
+
+func (cb *CircuitBreaker) transitioned(state CircuitState) {
+	now := cb.clock.Now().UnixNano()
+	cb.transitionAt.Store(now)
+	if state == StateOpen {
+		cb.openedAt.Store(now)
+		cb.trips.Add(1)
+	}
+	cb.rearm()
+}
+
+func (cb *CircuitBreaker) halfOpenMaxCalls() int {
+	if max := cb.config().HalfOpenMaxCalls; max > 0 {
+		return max
+	}
+	return 1
+}
+
+// RecordSuccess records a successful call. A half-open breaker closes once all of its
+// trial calls have succeeded.
 func (cb *CircuitBreaker) RecordSuccess() {
+	if !cb.enabled() {
+		return
+	}
+	if cb.consecutive.Load() != 0 {
+		cb.consecutive.Store(0)
+	}
+	if cb.state.Load() != int32(StateHalfOpen) {
+		if window := cb.settings.Load().window; window != nil && cb.state.Load() == int32(StateClosed) {
+			window.record(false)
+		}
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state.Load() != int32(StateHalfOpen) {
+		return
+	}
+	cb.succeeded++
+	if cb.succeeded >= cb.halfOpenMaxCalls() {
+		cb.close()
+	}
+}
+
+// close moves the breaker to StateClosed with no failure history; the caller holds mu
+func (cb *CircuitBreaker) close() {
+	if window := cb.settings.Load().window; window != nil {
+		// Failures from before the breaker opened would trip it again at once
+		window.reset()
+	}
+	cb.reason = ""
+	cb.tripped = breakerTrip{}
+	cb.setState(StateClosed)
+	cb.trippedAt.Store(0)
+	cb.failures.Store(0)
+	cb.timeouts.Store(0)
+	cb.consecutive.Store(0)
+	cb.clearedAt.Store(cb.clock.Now().UnixNano())
+	cb.rearm()
+}
+
+// Reset closes the breaker and clears its failure count, whatever its state. Under
+// BreakerScopeMethod the breakers of all functions are reset.
+func (cb *CircuitBreaker) Reset() {
 	if cb == nil {
 		return
 	}
+	cb.mu.Lock()
+	cb.close()
+	cb.mu.Unlock()
+	cb.eachMethod(func(_ string, method *CircuitBreaker) { method.Reset() })
+}
 
-	currentState := CircuitState(cb.state.Load())
-	if currentState == StateHalfOpen {
-		cb.state.CompareAndSwap(int32(StateHalfOpen), int32(StateClosed))
-		cb.failures.Store(0)
+// Trip opens the breaker for reason. Like a breaker opened by failures, it goes
+// half-open once OpenDuration has passed; tripping an open breaker starts it over.
+// Under BreakerScopeMethod the breakers of the functions called so far are tripped,
+// and those of functions first called within OpenDuration start tripped.
+func (cb *CircuitBreaker) Trip(reason string) {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	now := cb.clock.Now().UnixNano()
+	if cb.config().Scope == BreakerScopeMethod {
+		// Recorded before the existing breakers are tripped, so forMethod either sees
+		// the trip or creates a breaker this loop reaches
+		cb.tripped = breakerTrip{reason: reason, at: now}
+		cb.mu.Unlock()
+		cb.eachMethod(func(_ string, method *CircuitBreaker) { method.Trip(reason) })
+		return
 	}
+	defer cb.mu.Unlock()
+	cb.open(reason, now)
 }
 
-func (cb *CircuitBreaker) RecordFailure() {
+// open opens the breaker for reason as of at, in Unix nanoseconds; the caller holds mu
+func (cb *CircuitBreaker) open(reason string, at int64) {
+	cb.lastFailure.Store(at)
+	if cb.state.Load() == int32(StateClosed) {
+		cb.trippedAt.Store(at)
+	}
+	cb.reason = reason
+	cb.setState(StateOpen)
+	cb.openedAt.Store(at)
+	cb.rearm()
+}
+
+// methodTrip returns the Trip new function breakers start from under BreakerScopeMethod,
+// or false once its OpenDuration has passed; the caller holds mu
+func (cb *CircuitBreaker) methodTrip() (breakerTrip, bool) {
+	if cb.tripped.at == 0 {
+		return breakerTrip{}, false
+	}
+	until := time.Unix(0, cb.tripped.at).Add(cb.config().OpenDuration)
+	return cb.tripped, cb.clock.Now().Before(until)
+}
+
+// forMethod returns the breaker guarding calls to fn: the breaker itself, or under
+// BreakerScopeMethod the function's own breaker, created on first use, tripped if the
+// breaker was tripped within OpenDuration
+func (cb *CircuitBreaker) forMethod(fn string) *CircuitBreaker {
+	if cb == nil || cb.config().Scope != BreakerScopeMethod {
+		return cb
+	}
+	if val, ok := cb.methods.Load(fn); ok {
+		return val.(*CircuitBreaker)
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if val, ok := cb.methods.Load(fn); ok {
+		return val.(*CircuitBreaker)
+	}
+	method := NewCircuitBreaker(cb.ctx, methodConfig(*cb.config()), cb.logger, WithBreakerClock(cb.clock))
+	if trip, ok := cb.methodTrip(); ok {
+		method.mu.Lock()
+		method.open(trip.reason, trip.at)
+		method.mu.Unlock()
+	}
+	cb.methods.Store(fn, method)
+	return method
+}
+
+// method returns the breaker of fn under BreakerScopeMethod, or nil if fn has not been called
+func (cb *CircuitBreaker) method(fn string) *CircuitBreaker {
 	if cb == nil {
+		return nil
+	}
+	if val, ok := cb.methods.Load(fn); ok {
+		return val.(*CircuitBreaker)
+	}
+	return nil
+}
+
+// rejectingMethod reports whether the breaker of fn under BreakerScopeMethod would refuse a
+// call, counting a Trip that a breaker created now would start from
+func (cb *CircuitBreaker) rejectingMethod(fn string) bool {
+	if method := cb.method(fn); method != nil {
+		return method.rejecting()
+	}
+	if cb == nil || !cb.enabled() {
+		return false
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	_, ok := cb.methodTrip()
+	return ok
+}
+
+// methodConfig returns the configuration of the breakers of functions under config
+func methodConfig(config CircuitBreakerConfig) CircuitBreakerConfig {
+	config.Scope = BreakerScopePlugin
+	return config
+}
+
+func (cb *CircuitBreaker) eachMethod(f func(fn string, method *CircuitBreaker)) {
+	cb.methods.Range(func(key, value interface{}) bool {
+		f(key.(string), value.(*CircuitBreaker))
+		return true
+	})
+}
+
+// methodBreaker returns the breaker guarding a call to fn on instance. Calls to functions
+// the plugin does not export share the plugin breaker, so callers cannot create method
+// breakers without bound.
+func methodBreaker(breaker *CircuitBreaker, instance *PluginInstance, fn string) *CircuitBreaker {
+	if breaker == nil || breaker.config().Scope != BreakerScopeMethod || !instance.hasFunction(fn) {
+		return breaker
+	}
+	return breaker.forMethod(fn)
+}
+
+// tripReason returns the reason given to Trip while the breaker has not closed since
+func (cb *CircuitBreaker) tripReason() string {
+	if cb == nil {
+		return ""
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.reason
+}
+
+// IsBreakerFailure is the default CircuitBreakerConfig.IsFailure. Errors that are the
+// caller's doing do not count: ErrFuncNotFound, ErrInvalidArgument and context.Canceled.
+// Every other error counts, including context.DeadlineExceeded and errors returned by the
+// plugin.
+func IsBreakerFailure(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, new(ErrFuncNotFound)),
+		errors.As(err, new(ErrInvalidArgument)),
+		errors.Is(err, context.Canceled):
+		return false
+	default:
+		return true
+	}
+}
+
+// isTimeout reports whether err is a call that timed out, which RecordTimeout accounts
+// for apart from other failures
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, new(ErrPluginTimeout))
+}
+
+// isFailure reports whether err counts against the breaker
+func (cb *CircuitBreaker) isFailure(err error) bool {
+	if isFailure := cb.config().IsFailure; isFailure != nil {
+		return isFailure(err)
+	}
+	return IsBreakerFailure(err)
+}
+
+// recordIgnored records a call whose error does not count against the breaker. A trial
+// call of a half-open breaker gives its place to another.
+func (cb *CircuitBreaker) recordIgnored() {
+	if !cb.enabled() || cb.state.Load() != int32(StateHalfOpen) {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state.Load() == int32(StateHalfOpen) && cb.probes > 0 {
+		cb.probes--
+	}
+}
+
+// RecordFailure records a failed call. The breaker opens after MaxFailures of them, or
+// when the failure rate exceeds FailureRateThreshold under TripOnFailureRate, and at once
+// when a trial call of a half-open breaker fails.
+func (cb *CircuitBreaker) RecordFailure() {
+	if !cb.enabled() {
 		return
 	}
 
-	cb.lastFailure.Store(time.Now().UnixNano())
+	cb.lastFailure.Store(cb.clock.Now().UnixNano())
+	cb.consecutive.Add(1)
 	failures := cb.failures.Add(1)
 
-	if failures >= int32(cb.config.MaxFailures) {
-		cb.state.CompareAndSwap(int32(StateClosed), int32(StateOpen))
+	if cb.reopen() {
+		return
+	}
+	if cb.shouldTrip(failures) {
+		cb.trip()
+	}
+}
+
+// RecordTimeout records a call that timed out. With MaxTimeouts set the breaker opens
+// after that many of them, whatever the count of other failures; otherwise a timeout is
+// an ordinary failure. A timed-out trial call reopens a half-open breaker either way.
+func (cb *CircuitBreaker) RecordTimeout() {
+	if !cb.enabled() {
+		return
+	}
+	max := cb.config().MaxTimeouts
+	if max <= 0 {
+		cb.RecordFailure()
+		return
+	}
+
+	cb.lastFailure.Store(cb.clock.Now().UnixNano())
+	cb.consecutive.Add(1)
+	timeouts := cb.timeouts.Add(1)
+
+	if cb.reopen() {
+		return
+	}
+	if timeouts >= int32(max) {
+		cb.trip()
+	}
+}
+
+// reopen opens a half-open breaker after a failed trial call, reporting whether the
+// breaker was half-open
+func (cb *CircuitBreaker) reopen() bool {
+	if cb.state.Load() != int32(StateHalfOpen) {
+		return false
+	}
+	cb.mu.Lock()
+	if cb.state.Load() == int32(StateHalfOpen) {
+		cb.setState(StateOpen)
+	}
+	cb.mu.Unlock()
+	return true
+}
+
+// trip opens a closed breaker
+func (cb *CircuitBreaker) trip() {
+	if cb.state.CompareAndSwap(int32(StateClosed), int32(StateOpen)) {
+		cb.trippedAt.Store(cb.clock.Now().UnixNano())
+		cb.transitioned(StateOpen)
+	}
+}
+
+// shouldTrip records a failure of a closed breaker and reports whether it should open
+func (cb *CircuitBreaker) shouldTrip(failures int32) bool {
+	settings := cb.settings.Load()
+	if settings.window == nil {
+		return failures >= int32(settings.config.MaxFailures)
+	}
+	if cb.state.Load() != int32(StateClosed) {
+		return false
 	}
+	settings.window.record(true)
+	calls, failed := settings.window.counts()
+	if calls == 0 || calls < int64(settings.config.MinimumCalls) {
+		return false
+	}
+	return float64(failed)/float64(calls) > settings.config.FailureRateThreshold
 }
 
 func (cb *CircuitBreaker) State() CircuitState {
@@ -123,14 +637,167 @@ func (cb *CircuitBreaker) State() CircuitState {
 	return CircuitState(cb.state.Load())
 }
 
+// trippedSince returns when the breaker opened, or zero while it is closed. A breaker
+// probing in StateHalfOpen counts as tripped until a call succeeds.
+func (cb *CircuitBreaker) trippedSince() time.Time {
+	if cb == nil {
+		return time.Time{}
+	}
+	if at := cb.trippedAt.Load(); at != 0 && cb.state.Load() != int32(StateClosed) {
+		return time.Unix(0, at)
+	}
+	return time.Time{}
+}
+
+// inherit copies the state and failure history of the breaker being replaced, including
+// the breakers of its functions when both are scoped per method. A trip holds across a
+// change of scope: function breakers start from the old plugin breaker's, and the plugin
+// breaker opens for a Trip of the old function breakers.
+func (cb *CircuitBreaker) inherit(old *CircuitBreaker) {
+	if cb == nil || old == nil {
+		return
+	}
+	toMethod, fromMethod := cb.config().Scope == BreakerScopeMethod, old.config().Scope == BreakerScopeMethod
+	trip, tripped := old.lastTrip()
+	if toMethod {
+		if tripped {
+			cb.mu.Lock()
+			cb.tripped = trip
+			cb.mu.Unlock()
+		}
+		if fromMethod {
+			old.eachMethod(func(fn string, method *CircuitBreaker) { cb.forMethod(fn).inherit(method) })
+		}
+	}
+	cb.trips.Store(old.trips.Load())
+	cb.rejected.Store(old.rejected.Load())
+	if toMethod && !fromMethod {
+		// The function breakers took over the plugin breaker's trip
+		return
+	}
+	reason := old.tripReason()
+	cb.mu.Lock()
+	cb.reason = reason
+	cb.mu.Unlock()
+	cb.failures.Store(old.failures.Load())
+	cb.timeouts.Store(old.timeouts.Load())
+	cb.lastFailure.Store(old.lastFailure.Load())
+	cb.trippedAt.Store(old.trippedAt.Load())
+	cb.openedAt.Store(old.openedAt.Load())
+	cb.clearedAt.Store(old.clearedAt.Load())
+	cb.state.Store(old.state.Load())
+	cb.consecutive.Store(old.consecutive.Load())
+	cb.transitionAt.Store(old.transitionAt.Load())
+	if fromMethod && !toMethod && tripped {
+		cb.mu.Lock()
+		cb.open(trip.reason, trip.at)
+		cb.mu.Unlock()
+	}
+	cb.rearm()
+}
+
+// lastTrip returns the trip the breaker holds: under BreakerScopeMethod the last Trip,
+// until its OpenDuration has passed, otherwise the breaker's own while it is open
+func (cb *CircuitBreaker) lastTrip() (breakerTrip, bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.config().Scope == BreakerScopeMethod {
+		return cb.methodTrip()
+	}
+	if cb.state.Load() != int32(StateOpen) {
+		return breakerTrip{}, false
+	}
+	return breakerTrip{reason: cb.reason, at: cb.openedAt.Load()}, true
+}
+
+// StateSnapshot returns the state and statistics of the breaker. Unlike Allow it only
+// reads: an open breaker past its timeout stays open, and no counter is reset, until a
+// call arrives. Status and observability code must use it, or State, rather than Allow.
+func (cb *CircuitBreaker) StateSnapshot() BreakerInfo {
+	info := BreakerInfo{
+		Enabled:             cb.config().Enabled,
+		State:               cb.State(),
+		Trips:               cb.trips.Load(),
+		Rejected:            cb.rejected.Load(),
+		Failures:            cb.failures.Load(),
+		Timeouts:            cb.timeouts.Load(),
+		ConsecutiveFailures: cb.consecutive.Load(),
+		LastTransition:      time.Unix(0, cb.transitionAt.Load()),
+		Reason:              cb.tripReason(),
+	}
+	if at := cb.lastFailure.Load(); at != 0 {
+		info.LastFailure = time.Unix(0, at)
+	}
+	if info.State == StateOpen {
+		if wait := cb.openUntil().Sub(cb.clock.Now()); wait > 0 {
+			info.NextProbeIn = wait
+		}
+	}
+	cb.eachMethod(func(fn string, method *CircuitBreaker) {
+		if info.Methods == nil {
+			info.Methods = make(map[string]BreakerInfo)
+		}
+		info.Methods[fn] = method.StateSnapshot()
+	})
+	return info
+}
+
+// Reconfigure replaces the breaker's configuration, keeping its state and failure history.
+// The failure window starts over if the trip policy or window size changes, an open
+// breaker's OpenDuration and a closed one's ResetInterval apply from when it opened or
+// last cleared its counts, and a breaker being disabled is reset so it lets calls through
+// at once. Under BreakerScopeMethod the breakers of the functions are
+// reconfigured too; under BreakerScopePlugin they are discarded. A trip holds across
+// a change of scope, as it does for inherit.
+func (cb *CircuitBreaker) Reconfigure(config CircuitBreakerConfig) {
+	if cb == nil {
+		return
+	}
+	config = migrateBreakerConfig(config)
+	cb.mu.Lock()
+	old := cb.settings.Load()
+	next := &breakerSettings{config: config, window: old.window}
+	if config.TripPolicy != old.config.TripPolicy || config.WindowSize != old.config.WindowSize ||
+		config.WindowDuration != old.config.WindowDuration {
+		next.window = cb.newWindow(config)
+	}
+	cb.settings.Store(next)
+	switch {
+	case !config.Enabled:
+		cb.close()
+	case config.Scope == BreakerScopeMethod && old.config.Scope != BreakerScopeMethod:
+		// The function breakers take over the trip of an open plugin breaker
+		if cb.state.Load() == int32(StateOpen) {
+			trip := breakerTrip{reason: cb.reason, at: cb.openedAt.Load()}
+			cb.close()
+			cb.tripped = trip
+		}
+	case config.Scope != BreakerScopeMethod && old.config.Scope == BreakerScopeMethod:
+		if trip, ok := cb.methodTrip(); ok {
+			cb.open(trip.reason, trip.at)
+		}
+		cb.tripped = breakerTrip{}
+	}
+	cb.mu.Unlock()
+	cb.rearm()
+
+	if config.Scope == BreakerScopeMethod {
+		cb.eachMethod(func(_ string, method *CircuitBreaker) { method.Reconfigure(methodConfig(config)) })
+		return
+	}
+	cb.eachMethod(func(fn string, method *CircuitBreaker) {
+		cb.methods.Delete(fn)
+		method.Close()
+	})
+}
+
+// Close stops the reset loop; it is safe to call more than once
 func (cb *CircuitBreaker) Close() {
 	if cb != nil {
-		cb.cancel()
-		select {
-		case <-cb.done:
-			return
-		default:
+		cb.closeOnce.Do(func() {
+			cb.cancel()
 			close(cb.done)
-		}
+			cb.eachMethod(func(_ string, method *CircuitBreaker) { method.Close() })
+		})
 	}
 }
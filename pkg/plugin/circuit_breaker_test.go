@@ -0,0 +1,724 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
+)
+
+// newTrippedBreaker returns a breaker that has just opened and goes half-open once its
+// fake clock has advanced past timeout
+func newTrippedBreaker(t *testing.T, halfOpenMaxCalls int, timeout time.Duration) (*CircuitBreaker, *clocktest.Fake) {
+	t.Helper()
+	clk := clocktest.NewFake(time.Time{})
+	cb := NewCircuitBreaker(context.Background(), CircuitBreakerConfig{
+		Enabled:          true,
+		MaxFailures:      1,
+		ResetInterval:    time.Hour,
+		OpenDuration:     timeout,
+		HalfOpenMaxCalls: halfOpenMaxCalls,
+	}, &testLogger{}, WithBreakerClock(clk))
+	t.Cleanup(cb.Close)
+	cb.RecordFailure()
+	if cb.State() != StateOpen {
+		t.Fatalf("State = %v, want open", cb.State())
+	}
+	return cb, clk
+}
+
+func TestCircuitBreaker_HalfOpenAdmitsLimitedProbes(t *testing.T) {
+	for _, max := range []int{1, 3} {
+		cb, clk := newTrippedBreaker(t, max, 20*time.Millisecond)
+		clk.Advance(40 * time.Millisecond)
+
+		// Callers racing at the open to half-open boundary
+		var admitted atomic.Int32
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				if cb.Allow() {
+					admitted.Add(1)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+		if got := admitted.Load(); got != int32(max) {
+			t.Errorf("HalfOpenMaxCalls %d: admitted %d probes", max, got)
+		}
+		if !cb.rejecting() || cb.State() != StateHalfOpen {
+			t.Errorf("HalfOpenMaxCalls %d: state %v, want half-open rejecting calls", max, cb.State())
+		}
+
+		for i := 0; i < max-1; i++ {
+			cb.RecordSuccess()
+			if cb.State() != StateHalfOpen {
+				t.Fatalf("HalfOpenMaxCalls %d: closed after %d of the probes succeeded", max, i+1)
+			}
+		}
+		cb.RecordSuccess()
+		if cb.State() != StateClosed || !cb.Allow() {
+			t.Errorf("HalfOpenMaxCalls %d: state %v after every probe succeeded, want closed", max, cb.State())
+		}
+	}
+}
+
+func TestCircuitBreaker_FailedProbeReopens(t *testing.T) {
+	cb, clk := newTrippedBreaker(t, 2, 20*time.Millisecond)
+	clk.Advance(40 * time.Millisecond)
+
+	if !cb.Allow() || !cb.Allow() || cb.Allow() {
+		t.Fatal("Half-open breaker did not admit exactly two probes")
+	}
+	cb.RecordSuccess()
+	cb.RecordFailure()
+	if cb.State() != StateOpen || cb.Allow() {
+		t.Fatalf("State = %v after a failed probe, want open", cb.State())
+	}
+	if cb.trippedSince().IsZero() {
+		t.Error("Reopened breaker lost its trip time")
+	}
+
+	// The next half-open period starts with fresh probes
+	clk.Advance(40 * time.Millisecond)
+	if !cb.Allow() || !cb.Allow() || cb.Allow() {
+		t.Error("Reopened breaker did not admit two new probes")
+	}
+}
+
+func TestCircuitBreaker_RejectingAdmitsNothing(t *testing.T) {
+	cb, clk := newTrippedBreaker(t, 1, 20*time.Millisecond)
+	clk.Advance(40 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if cb.rejecting() {
+			t.Fatal("Breaker past its timeout rejects calls")
+		}
+	}
+	if !cb.Allow() {
+		t.Error("Status checks used up the probe")
+	}
+}
+
+func TestCircuitState_String(t *testing.T) {
+	tests := map[CircuitState]string{
+		StateClosed:     "closed",
+		StateOpen:       "open",
+		StateHalfOpen:   "half-open",
+		CircuitState(7): "CircuitState(7)",
+	}
+	for state, want := range tests {
+		if got := state.String(); got != want {
+			t.Errorf("CircuitState(%d).String() = %q, want %q", int32(state), got, want)
+		}
+	}
+}
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	const openDuration = 10 * time.Second
+	fail := func(cb *CircuitBreaker, _ *clocktest.Fake) { cb.RecordFailure() }
+	succeed := func(cb *CircuitBreaker, _ *clocktest.Fake) { cb.RecordSuccess() }
+	timeout := func(cb *CircuitBreaker, _ *clocktest.Fake) { cb.RecordTimeout() }
+	allow := func(cb *CircuitBreaker, _ *clocktest.Fake) { cb.Allow() }
+	trip := func(cb *CircuitBreaker, _ *clocktest.Fake) { cb.Trip("maintenance") }
+	reset := func(cb *CircuitBreaker, _ *clocktest.Fake) { cb.Reset() }
+	advance := func(d time.Duration) func(*CircuitBreaker, *clocktest.Fake) {
+		return func(_ *CircuitBreaker, clk *clocktest.Fake) { clk.Advance(d) }
+	}
+	tests := []struct {
+		name  string
+		steps []func(*CircuitBreaker, *clocktest.Fake)
+		want  CircuitState
+		// allows is whether Allow admits a call in the final state
+		allows bool
+	}{
+		{name: "closed below MaxFailures", steps: []func(*CircuitBreaker, *clocktest.Fake){fail}, want: StateClosed, allows: true},
+		{name: "MaxFailures opens", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail}, want: StateOpen},
+		{name: "open within OpenDuration", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail, advance(openDuration - time.Millisecond)}, want: StateOpen},
+		{name: "call after OpenDuration half-opens", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail, advance(openDuration), allow}, want: StateHalfOpen},
+		{name: "late failure keeps OpenDuration", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail, advance(openDuration / 2), fail, advance(openDuration / 2), allow}, want: StateHalfOpen},
+		{name: "half-open admits one probe", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail, advance(openDuration), allow}, want: StateHalfOpen, allows: false},
+		{name: "successful probe closes", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail, advance(openDuration), allow, succeed}, want: StateClosed, allows: true},
+		{name: "failed probe reopens", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail, advance(openDuration), allow, fail}, want: StateOpen},
+		{name: "timed-out probe reopens", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail, advance(openDuration), allow, timeout}, want: StateOpen},
+		{name: "reopened breaker waits OpenDuration again", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail, advance(openDuration), allow, fail, advance(openDuration - time.Millisecond)}, want: StateOpen},
+		{name: "Trip opens", steps: []func(*CircuitBreaker, *clocktest.Fake){trip}, want: StateOpen},
+		{name: "Trip restarts OpenDuration", steps: []func(*CircuitBreaker, *clocktest.Fake){trip, advance(openDuration / 2), trip, advance(openDuration / 2)}, want: StateOpen},
+		{name: "Reset closes an open breaker", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail, reset}, want: StateClosed, allows: true},
+		{name: "Reset closes a half-open breaker", steps: []func(*CircuitBreaker, *clocktest.Fake){fail, fail, advance(openDuration), allow, reset}, want: StateClosed, allows: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clocktest.NewFake(time.Time{})
+			cb := NewCircuitBreaker(context.Background(), CircuitBreakerConfig{
+				Enabled:      true,
+				MaxFailures:  2,
+				OpenDuration: openDuration,
+			}, &testLogger{}, WithBreakerClock(clk))
+			defer cb.Close()
+			for _, step := range tt.steps {
+				step(cb, clk)
+			}
+			if got := cb.State(); got != tt.want {
+				t.Fatalf("State = %v, want %v", got, tt.want)
+			}
+			if got := cb.Allow(); got != tt.allows {
+				t.Errorf("Allow() = %v in state %v, want %v", got, tt.want, tt.allows)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_ResetLoopHalfOpensAfterOpenDuration(t *testing.T) {
+	clk := clocktest.NewFake(time.Time{})
+	cb := NewCircuitBreaker(context.Background(), CircuitBreakerConfig{
+		Enabled:       true,
+		MaxFailures:   1,
+		ResetInterval: time.Second,
+		OpenDuration:  time.Minute,
+	}, &testLogger{}, WithBreakerClock(clk))
+	defer cb.Close()
+	cb.RecordFailure()
+
+	// ResetInterval ticks no longer cut OpenDuration short
+	for i := 0; i < 59; i++ {
+		clk.Advance(time.Second)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if cb.State() != StateOpen {
+		t.Fatalf("State = %v before OpenDuration passed, want open", cb.State())
+	}
+
+	// Without any call, the reset loop half-opens the breaker when OpenDuration ends
+	clk.Advance(time.Second)
+	waitFor(t, "the reset loop to half-open the breaker", func() bool { return cb.State() == StateHalfOpen })
+}
+
+func TestCircuitBreaker_ResetIntervalClearsFailures(t *testing.T) {
+	clk := clocktest.NewFake(time.Time{})
+	cb := NewCircuitBreaker(context.Background(), CircuitBreakerConfig{
+		Enabled:       true,
+		MaxFailures:   2,
+		ResetInterval: time.Minute,
+		OpenDuration:  time.Second,
+	}, &testLogger{}, WithBreakerClock(clk))
+	defer cb.Close()
+
+	cb.RecordFailure()
+	clk.Advance(time.Minute)
+	waitFor(t, "the failure count to clear", func() bool { return cb.failures.Load() == 0 })
+	cb.RecordFailure()
+	if cb.State() != StateClosed {
+		t.Fatal("Failures from separate intervals opened the breaker")
+	}
+	cb.RecordFailure()
+	if cb.State() != StateOpen {
+		t.Errorf("State = %v after MaxFailures within an interval, want open", cb.State())
+	}
+}
+
+func TestCircuitBreaker_TimeoutDurationShim(t *testing.T) {
+	clk := clocktest.NewFake(time.Time{})
+	legacy := CircuitBreakerConfig{Enabled: true, MaxFailures: 1, ResetInterval: time.Hour, TimeoutDuration: time.Second}
+	if err := validateBreakerConfig(legacy); err != nil {
+		t.Fatalf("Config with only TimeoutDuration rejected: %v", err)
+	}
+	cb := NewCircuitBreaker(context.Background(), legacy, &testLogger{}, WithBreakerClock(clk))
+	defer cb.Close()
+	if got := cb.config().OpenDuration; got != time.Second {
+		t.Fatalf("OpenDuration = %v, want TimeoutDuration 1s", got)
+	}
+	cb.RecordFailure()
+	clk.Advance(time.Second)
+	if !cb.Allow() {
+		t.Error("Breaker configured by TimeoutDuration did not half-open after it")
+	}
+
+	// OpenDuration wins when both are set
+	both := legacy
+	both.OpenDuration = time.Minute
+	if got := migrateBreakerConfig(both).OpenDuration; got != time.Minute {
+		t.Errorf("OpenDuration = %v with both set, want 1m", got)
+	}
+}
+
+func TestCircuitBreaker_Reconfigure(t *testing.T) {
+	cb, clk := newTrippedBreaker(t, 1, time.Minute)
+
+	// Switching to a failure-rate window keeps the breaker open, with a fresh window
+	rate := *cb.config()
+	rate.TripPolicy = TripOnFailureRate
+	rate.FailureRateThreshold = 0.5
+	rate.WindowSize = 10
+	cb.Reconfigure(rate)
+	if cb.State() != StateOpen || cb.settings.Load().window == nil {
+		t.Fatalf("State = %v after reconfiguring, want open with a window", cb.State())
+	}
+
+	// A shorter timeout applies to the breaker already open
+	rate.OpenDuration = time.Second
+	cb.Reconfigure(rate)
+	clk.Advance(2 * time.Second)
+	if !cb.Allow() {
+		t.Error("Breaker did not go half-open after the new OpenDuration")
+	}
+
+	rate.Enabled = false
+	cb.Reconfigure(rate)
+	cb.RecordFailure()
+	if cb.State() != StateClosed || cb.failures.Load() != 0 || !cb.Allow() {
+		t.Errorf("Disabled breaker = %+v, want closed and counting nothing", cb.StateSnapshot())
+	}
+}
+
+func TestCircuitBreaker_ReconfigureScope(t *testing.T) {
+	config := CircuitBreakerConfig{
+		Enabled:       true,
+		Scope:         BreakerScopeMethod,
+		MaxFailures:   3,
+		ResetInterval: time.Hour,
+		OpenDuration:  time.Hour,
+	}
+	cb := NewCircuitBreaker(context.Background(), config, &testLogger{})
+	defer cb.Close()
+	pay := cb.forMethod("Pay")
+
+	config.MaxFailures = 1
+	cb.Reconfigure(config)
+	if pay.config().MaxFailures != 1 || pay.config().Scope != BreakerScopePlugin {
+		t.Errorf("Method breaker config = %+v, want the new MaxFailures under plugin scope", *pay.config())
+	}
+
+	config.Scope = BreakerScopePlugin
+	cb.Reconfigure(config)
+	if cb.method("Pay") != nil || cb.forMethod("Pay") != cb {
+		t.Error("Method breakers outlived the switch to plugin scope")
+	}
+	select {
+	case <-pay.done:
+	default:
+		t.Error("Discarded method breaker was not closed")
+	}
+}
+
+func TestCircuitBreaker_InheritMethodBreakers(t *testing.T) {
+	config := CircuitBreakerConfig{
+		Enabled:       true,
+		Scope:         BreakerScopeMethod,
+		MaxFailures:   1,
+		ResetInterval: time.Hour,
+		OpenDuration:  time.Hour,
+	}
+	old := NewCircuitBreaker(context.Background(), config, &testLogger{})
+	defer old.Close()
+	old.forMethod("Pay").RecordFailure()
+	old.forMethod("Refund").Trip("maintenance")
+
+	cb := NewCircuitBreaker(context.Background(), config, &testLogger{})
+	defer cb.Close()
+	cb.inherit(old)
+	if pay := cb.method("Pay"); pay.State() != StateOpen || pay.failures.Load() != 1 {
+		t.Errorf("Pay breaker = %v with %d failures, want it carried over open", pay.State(), pay.failures.Load())
+	}
+	if refund := cb.method("Refund"); refund.State() != StateOpen || refund.tripReason() != "maintenance" {
+		t.Errorf("Refund breaker lost its manual trip: %+v", refund.StateSnapshot())
+	}
+}
+
+func TestCircuitBreaker_InheritTrip(t *testing.T) {
+	plugin := CircuitBreakerConfig{
+		Enabled:       true,
+		Scope:         BreakerScopePlugin,
+		MaxFailures:   1,
+		ResetInterval: time.Hour,
+		OpenDuration:  time.Hour,
+	}
+	method := plugin
+	method.Scope = BreakerScopeMethod
+	newBreaker := func(config CircuitBreakerConfig) *CircuitBreaker {
+		cb := NewCircuitBreaker(context.Background(), config, &testLogger{})
+		t.Cleanup(cb.Close)
+		return cb
+	}
+
+	for _, tt := range []struct {
+		name     string
+		old, new CircuitBreakerConfig
+	}{
+		{"method to method", method, method},
+		{"plugin to method", plugin, method},
+		{"method to plugin", method, plugin},
+		{"plugin to plugin", plugin, plugin},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			old := newBreaker(tt.old)
+			old.Trip("maintenance")
+			cb := newBreaker(tt.new)
+			cb.inherit(old)
+			// Pay was never called before the trip
+			if pay := cb.forMethod("Pay"); pay.State() != StateOpen || pay.tripReason() != "maintenance" {
+				t.Errorf("Pay breaker = %+v, want it tripped for maintenance", pay.StateSnapshot())
+			}
+			if tt.new.Scope == BreakerScopeMethod && cb.State() != StateClosed {
+				t.Errorf("Plugin breaker = %v under method scope, want the trip left to the functions", cb.State())
+			}
+		})
+	}
+
+	t.Run("reconfigure", func(t *testing.T) {
+		cb := newBreaker(plugin)
+		cb.Trip("maintenance")
+		cb.Reconfigure(method)
+		if pay := cb.forMethod("Pay"); pay.State() != StateOpen || cb.State() != StateClosed {
+			t.Errorf("Pay breaker = %v, plugin breaker = %v; want the trip moved to Pay", pay.State(), cb.State())
+		}
+		cb.Reconfigure(plugin)
+		if cb.State() != StateOpen || cb.tripReason() != "maintenance" {
+			t.Errorf("Plugin breaker = %+v, want the trip back on it", cb.StateSnapshot())
+		}
+	})
+}
+
+func TestCircuitBreaker_MethodScope(t *testing.T) {
+	failing := func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return nil, errors.New("downstream unavailable")
+	}
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "calc", version: "1.0.0"},
+			map[string]InvokeFunc{"Add": returning(3), "Some1111": failing}),
+	})
+	m := newTestManager(t, func(config *Config) {
+		config.DefaultPluginConfig.CircuitBreaker.Scope = BreakerScopeMethod
+		config.DefaultPluginConfig.CircuitBreaker.MaxFailures = 2
+		writeTestPlugin(t, config.PluginDir, "calc", "v1")
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		m.Call(ctx, "calc", "Some1111")
+	}
+	if _, err := m.Call(ctx, "calc", "Some1111"); !errors.As(err, new(ErrCircuitOpen)) {
+		t.Errorf("Some1111 error = %v, want ErrCircuitOpen", err)
+	}
+	if result, err := m.Call(ctx, "calc", "Add"); err != nil || result != 3 {
+		t.Errorf("Add = %v, %v; want it unaffected by the open Some1111 breaker", result, err)
+	}
+	if !m.GetMethodBreakerStatus("calc", "Some1111") || m.GetMethodBreakerStatus("calc", "Add") {
+		t.Error("GetMethodBreakerStatus does not tell Some1111 apart from Add")
+	}
+	if m.GetBreakerStatus("calc") {
+		t.Error("Plugin breaker opened under method scope")
+	}
+
+	// Unknown functions do not get a breaker each
+	for i := 0; i < 10; i++ {
+		m.Call(ctx, "calc", fmt.Sprintf("Typo%d", i))
+	}
+	val, _ := m.breakers.Load("calc")
+	breaker := val.(*CircuitBreaker)
+	methods := 0
+	breaker.eachMethod(func(string, *CircuitBreaker) { methods++ })
+	if methods != 2 {
+		t.Errorf("Method breakers = %d, want 2", methods)
+	}
+
+	if err := m.ResetBreaker("calc"); err != nil {
+		t.Fatal(err)
+	}
+	if m.GetMethodBreakerStatus("calc", "Some1111") {
+		t.Error("ResetBreaker left the Some1111 breaker open")
+	}
+
+	// Closing the plugin breaker stops the method breakers with it
+	some := breaker.method("Some1111")
+	breaker.Close()
+	select {
+	case <-some.done:
+	default:
+		t.Error("Closing the plugin breaker left its method breakers running")
+	}
+}
+
+func TestCircuitBreaker_MethodScopeTrip(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "calc", version: "1.0.0"},
+			map[string]InvokeFunc{"Add": returning(3), "Sub": returning(1), "Mul": returning(2), "Div": returning(4)}),
+	})
+	clk := clocktest.NewFake(time.Time{})
+	m := newTestManager(t, func(config *Config) {
+		config.DefaultPluginConfig.CircuitBreaker.Scope = BreakerScopeMethod
+		writeTestPlugin(t, config.PluginDir, "calc", "v1")
+	}, WithClock(clk))
+
+	ctx := context.Background()
+	if _, err := m.Call(ctx, "calc", "Add"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.TripBreaker("calc", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	// Sub has never been called, so it had no breaker to trip
+	if !m.GetMethodBreakerStatus("calc", "Sub") {
+		t.Error("GetMethodBreakerStatus(Sub) = false after the trip")
+	}
+	for _, fn := range []string{"Add", "Sub"} {
+		if _, err := m.Call(ctx, "calc", fn); !errors.As(err, new(ErrCircuitOpen)) {
+			t.Errorf("%s error = %v, want ErrCircuitOpen", fn, err)
+		}
+	}
+	val, _ := m.breakers.Load("calc")
+	if sub := val.(*CircuitBreaker).method("Sub"); sub.tripReason() != "maintenance" {
+		t.Errorf("Sub breaker = %+v, want it tripped for maintenance", sub.StateSnapshot())
+	}
+
+	// The trip holds for OpenDuration from when it was made, not from the first call
+	clk.Advance(m.currentConfig().DefaultPluginConfig.CircuitBreaker.OpenDuration)
+	for _, fn := range []string{"Sub", "Mul"} {
+		if m.GetMethodBreakerStatus("calc", fn) {
+			t.Errorf("GetMethodBreakerStatus(%s) = true after OpenDuration", fn)
+		}
+	}
+	if result, err := m.Call(ctx, "calc", "Mul"); err != nil || result != 2 {
+		t.Errorf("Mul = %v, %v; want it called once the trip has passed", result, err)
+	}
+
+	if err := m.TripBreaker("calc", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ResetBreaker("calc"); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := m.Call(ctx, "calc", "Div"); err != nil || result != 4 {
+		t.Errorf("Div = %v, %v; want ResetBreaker to clear the trip for functions not called yet", result, err)
+	}
+}
+
+func TestCircuitBreaker_CallerErrorsDoNotCount(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "calc", version: "1.0.0"}, map[string]InvokeFunc{
+			"Add": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				if len(args) != 2 {
+					return nil, ErrInvalidArgument{Func: "Add", Err: errors.New("Add requires 2 arguments")}
+				}
+				return nil, ctx.Err()
+			},
+		}),
+	})
+	m := newTestManager(t, func(config *Config) {
+		config.DefaultPluginConfig.CircuitBreaker.MaxFailures = 3
+		writeTestPlugin(t, config.PluginDir, "calc", "v1")
+	})
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 20; i++ {
+		m.Call(context.Background(), "calc", fmt.Sprintf("Ad%d", i))
+		m.Call(context.Background(), "calc", "Add", 1)
+		m.Call(canceled, "calc", "Add", 1, 2)
+	}
+	if m.GetBreakerStatus("calc") {
+		t.Fatal("Caller errors opened the breaker")
+	}
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		m.Call(expired, "calc", "Add", 1, 2)
+	}
+	if !m.GetBreakerStatus("calc") {
+		t.Error("Deadline errors did not open the breaker")
+	}
+}
+
+func TestCircuitBreaker_Timeouts(t *testing.T) {
+	config := CircuitBreakerConfig{
+		Enabled:       true,
+		MaxFailures:   10,
+		MaxTimeouts:   3,
+		ResetInterval: time.Hour,
+		OpenDuration:  time.Hour,
+	}
+	cb := NewCircuitBreaker(context.Background(), config, &testLogger{})
+	defer cb.Close()
+	for i := 0; i < 9; i++ {
+		cb.RecordFailure()
+	}
+	cb.RecordTimeout()
+	cb.RecordTimeout()
+	if cb.State() != StateClosed {
+		t.Fatalf("State = %v below both thresholds, want closed", cb.State())
+	}
+	cb.RecordTimeout()
+	if snap := cb.StateSnapshot(); snap.State != StateOpen || snap.Failures != 9 || snap.Timeouts != 3 {
+		t.Errorf("StateSnapshot() = %+v, want open with 9 failures and 3 timeouts", snap)
+	}
+
+	// Without MaxTimeouts a timeout is an ordinary failure
+	config.MaxFailures = 2
+	config.MaxTimeouts = 0
+	cb = NewCircuitBreaker(context.Background(), config, &testLogger{})
+	defer cb.Close()
+	cb.RecordTimeout()
+	cb.RecordTimeout()
+	if snap := cb.StateSnapshot(); snap.State != StateOpen || snap.Failures != 2 || snap.Timeouts != 0 {
+		t.Errorf("StateSnapshot() = %+v, want open with the timeouts counted as failures", snap)
+	}
+}
+
+func TestManager_CallClassifiesTimeouts(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "calc", version: "1.0.0"}, map[string]InvokeFunc{
+			"Add": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				return nil, ctx.Err()
+			},
+			"Div": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				return nil, errors.New("division by zero")
+			},
+		}),
+	})
+	m := newTestManager(t, func(config *Config) {
+		config.DefaultPluginConfig.CircuitBreaker.MaxFailures = 5
+		config.DefaultPluginConfig.CircuitBreaker.MaxTimeouts = 2
+		writeTestPlugin(t, config.PluginDir, "calc", "v1")
+	})
+
+	for i := 0; i < 4; i++ {
+		m.Call(context.Background(), "calc", "Div")
+	}
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	m.Call(expired, "calc", "Add")
+	if state, _ := m.GetBreakerState("calc"); state != StateClosed {
+		t.Fatalf("GetBreakerState() = %v after 4 errors and a timeout, want closed", state)
+	}
+	m.Call(expired, "calc", "Add")
+	info, err := m.GetBreakerInfo("calc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State != StateOpen || info.Failures != 4 || info.Timeouts != 2 {
+		t.Errorf("GetBreakerInfo() = %+v, want open with 4 failures and 2 timeouts", info)
+	}
+}
+
+func TestCircuitBreaker_IgnoredProbeFreesItsPlace(t *testing.T) {
+	cb, clk := newTrippedBreaker(t, 1, 20*time.Millisecond)
+	clk.Advance(40 * time.Millisecond)
+
+	if !cb.Allow() || cb.Allow() {
+		t.Fatal("Half-open breaker did not admit exactly one probe")
+	}
+	if cb.isFailure(context.Canceled) {
+		t.Fatal("context.Canceled counts as a failure")
+	}
+	cb.recordIgnored()
+	if !cb.Allow() {
+		t.Error("Ignored probe did not free its place")
+	}
+}
+
+func TestCircuitBreaker_CustomClassifier(t *testing.T) {
+	errThrottled := errors.New("throttled")
+	cb := NewCircuitBreaker(context.Background(), CircuitBreakerConfig{
+		Enabled:       true,
+		MaxFailures:   1,
+		ResetInterval: time.Hour,
+		OpenDuration:  time.Hour,
+		IsFailure: func(err error) bool {
+			return !errors.Is(err, errThrottled) && IsBreakerFailure(err)
+		},
+	}, &testLogger{})
+	defer cb.Close()
+
+	if cb.isFailure(errThrottled) || !cb.isFailure(errors.New("boom")) {
+		t.Error("Custom classifier was not consulted")
+	}
+}
+
+func TestCircuitBreaker_Info(t *testing.T) {
+	cb, clk := newTrippedBreaker(t, 1, time.Hour)
+	tripped := clk.Now()
+	clk.Advance(time.Minute)
+	cb.Allow()
+	cb.Allow()
+
+	info := cb.StateSnapshot()
+	if info.State != StateOpen || info.Trips != 1 || info.Rejected != 2 || info.ConsecutiveFailures != 1 {
+		t.Errorf("StateSnapshot() = %+v, want open after one trip with two rejected calls", info)
+	}
+	if !info.LastFailure.Equal(tripped) || !info.LastTransition.Equal(tripped) {
+		t.Errorf("LastFailure %v, LastTransition %v; want both %v", info.LastFailure, info.LastTransition, tripped)
+	}
+	if info.NextProbeIn != time.Hour-time.Minute {
+		t.Errorf("NextProbeIn = %v, want %v", info.NextProbeIn, time.Hour-time.Minute)
+	}
+
+	cb.Reset()
+	cb.RecordSuccess()
+	info = cb.StateSnapshot()
+	if info.State != StateClosed || info.Trips != 1 || info.ConsecutiveFailures != 0 || info.NextProbeIn != 0 {
+		t.Errorf("StateSnapshot() after reset = %+v", info)
+	}
+	cb.Trip("")
+	if info := cb.StateSnapshot(); info.Trips != 2 {
+		t.Errorf("Trips = %d after a manual trip, want 2", info.Trips)
+	}
+}
+
+func TestManager_GetBreakerInfo(t *testing.T) {
+	m, _ := newAdminManager(t, "")
+	if err := m.TripBreaker("payments", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	m.Call(context.Background(), "payments", "Pay")
+
+	info, err := m.GetBreakerInfo("payments")
+	if err != nil {
+		t.Fatalf("GetBreakerInfo() error = %v", err)
+	}
+	if info.State != StateOpen || info.Trips != 1 || info.Rejected != 1 || info.Reason != "maintenance" {
+		t.Errorf("GetBreakerInfo() = %+v", info)
+	}
+	detail, err := m.AdminGet("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.BreakerDetail.Trips != 1 || detail.BreakerDetail.Rejected != 1 || detail.BreakerDetail.NextProbeNs <= 0 {
+		t.Errorf("Admin breaker = %+v", detail.BreakerDetail)
+	}
+	if _, err := m.GetBreakerInfo("orders"); !errors.As(err, new(ErrPluginNotFound)) {
+		t.Errorf("GetBreakerInfo() of an unknown plugin error = %v", err)
+	}
+}
+
+func TestManager_WithClock(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "calc", version: "1.0.0"}, map[string]InvokeFunc{"Add": returning(3)}),
+	})
+	clk := clocktest.NewFake(time.Time{})
+	m := newTestManager(t, nil, WithClock(clk))
+	loadTestPlugin(t, m, "calc", "v1")
+
+	if err := m.TripBreaker("calc", ""); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(m.currentConfig().DefaultPluginConfig.CircuitBreaker.OpenDuration - time.Millisecond)
+	if !m.GetBreakerStatus("calc") {
+		t.Fatal("Breaker timed out before its OpenDuration had passed on the manager's clock")
+	}
+	clk.Advance(time.Millisecond)
+	if m.GetBreakerStatus("calc") {
+		t.Error("Breaker still open after OpenDuration on the manager's clock")
+	}
+}
@@ -0,0 +1,231 @@
+package plugin
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrencyMode selects how the number of concurrent calls to a plugin is limited
+type ConcurrencyMode string
+
+const (
+	// ConcurrencyStatic rejects calls beyond MaxConcurrentCalls; it is the default
+	ConcurrencyStatic ConcurrencyMode = "static"
+	// ConcurrencyAdaptive adjusts the limit to the plugin's latency, see AdaptiveConcurrencyConfig
+	ConcurrencyAdaptive ConcurrencyMode = "adaptive"
+)
+
+// AdaptiveConcurrencyConfig tunes ConcurrencyAdaptive. The limit grows by one for every
+// limit calls that complete within LatencyTolerance times the baseline latency, a slow
+// moving average of calls that were not slow, and is multiplied by Backoff when a call
+// is slower. Once the limit is at MinLimit slow calls move the baseline too, so that a
+// plugin that became slower for good is let back up.
+type AdaptiveConcurrencyConfig struct {
+	// MinLimit and MaxLimit bound the limit; zero means 1 and MaxConcurrentCalls
+	MinLimit int
+	MaxLimit int
+	// InitialLimit is the limit until calls have been observed; zero means MaxLimit
+	InitialLimit int
+	// LatencyTolerance is the multiple of the baseline above which a call is slow; zero means 2
+	LatencyTolerance float64
+	// Backoff is the factor, between 0 and 1, the limit is cut by; zero means 0.75
+	Backoff float64
+}
+
+// ConcurrencyInfo describes the concurrency limit of a plugin
+type ConcurrencyInfo struct {
+	Mode ConcurrencyMode
+	// Limit is the number of calls allowed at once; zero means unlimited
+	Limit    int
+	InFlight int
+	// Rejected counts calls refused with ErrTooManyConcurrentCalls
+	Rejected int64
+	// Baseline is the latency the adaptive limiter compares calls against; zero in static mode
+	Baseline time.Duration
+}
+
+const (
+	// baselineWeight is the weight of each call in the adaptive baseline, which so follows
+	// roughly the last hundred calls
+	baselineWeight          = 0.01
+	defaultLatencyTolerance = 2
+	defaultBackoff          = 0.75
+)
+
+// concurrencyLimiter bounds the calls in flight to a plugin
+type concurrencyLimiter struct {
+	adaptiveOn atomic.Bool  // ConcurrencyAdaptive rather than ConcurrencyStatic
+	limit      atomic.Int64 // zero means unlimited
+	inFlight   atomic.Int64
+	rejected   atomic.Int64
+	baselineNs atomic.Int64 // the adaptive baseline, for ConcurrencyInfo
+
+	// adaptive state, guarded by mu
+	mu       sync.Mutex
+	adaptive AdaptiveConcurrencyConfig // with defaults applied
+	current  float64                   // the limit before rounding
+	baseline float64                   // nanoseconds
+	sinceCut int                       // calls observed since the limit was last cut
+}
+
+func newConcurrencyLimiter(config PluginSpecificConfig) *concurrencyLimiter {
+	l := &concurrencyLimiter{}
+	l.configure(config)
+	return l
+}
+
+// configure applies config, keeping an adaptive limit learned so far within the new bounds
+func (l *concurrencyLimiter) configure(config PluginSpecificConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	mode := config.ConcurrencyMode
+	if mode == "" {
+		mode = ConcurrencyStatic
+	}
+	if mode != ConcurrencyAdaptive {
+		l.adaptiveOn.Store(false)
+		l.limit.Store(int64(config.MaxConcurrentCalls))
+		return
+	}
+
+	adaptive := config.AdaptiveConcurrency
+	if adaptive.MinLimit <= 0 {
+		adaptive.MinLimit = 1
+	}
+	if adaptive.MaxLimit <= 0 {
+		adaptive.MaxLimit = config.MaxConcurrentCalls
+	}
+	if adaptive.MaxLimit < adaptive.MinLimit {
+		adaptive.MaxLimit = adaptive.MinLimit
+	}
+	if adaptive.InitialLimit <= 0 {
+		adaptive.InitialLimit = adaptive.MaxLimit
+	}
+	if adaptive.LatencyTolerance <= 0 {
+		adaptive.LatencyTolerance = defaultLatencyTolerance
+	}
+	if adaptive.Backoff <= 0 {
+		adaptive.Backoff = defaultBackoff
+	}
+	if !l.adaptiveOn.Load() {
+		l.current = float64(adaptive.InitialLimit)
+		l.baseline = 0
+		l.baselineNs.Store(0)
+	}
+	l.adaptive = adaptive
+	l.current = math.Min(math.Max(l.current, float64(adaptive.MinLimit)), float64(adaptive.MaxLimit))
+	l.sinceCut = int(l.current)
+	l.limit.Store(int64(l.current))
+	l.adaptiveOn.Store(true)
+}
+
+// acquire takes a place for a call, reporting false if the limit is reached
+func (l *concurrencyLimiter) acquire() bool {
+	if l == nil {
+		return true
+	}
+	for {
+		n, limit := l.inFlight.Load(), l.limit.Load()
+		if limit > 0 && n >= limit {
+			l.rejected.Add(1)
+			return false
+		}
+		if l.inFlight.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// release gives back the place of a call
+func (l *concurrencyLimiter) release() {
+	if l != nil {
+		l.inFlight.Add(-1)
+	}
+}
+
+// observe adjusts an adaptive limit to the latency of a completed call
+func (l *concurrencyLimiter) observe(latency time.Duration) {
+	if l == nil || !l.adaptiveOn.Load() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.adaptiveOn.Load() {
+		return
+	}
+	sample := float64(latency)
+	if l.baseline == 0 {
+		l.baseline = sample
+		l.baselineNs.Store(int64(sample))
+		return
+	}
+
+	l.sinceCut++
+	if sample > l.adaptive.LatencyTolerance*l.baseline {
+		atFloor := l.current <= float64(l.adaptive.MinLimit)
+		// Calls started before the last cut are still draining; cut again only once a
+		// full limit's worth of calls has completed since
+		if l.sinceCut >= int(l.current) {
+			l.current = math.Max(l.current*l.adaptive.Backoff, float64(l.adaptive.MinLimit))
+			l.sinceCut = 0
+		}
+		// Slow calls are kept out of the baseline so that overload cannot pass for normal,
+		// unless the limit is already at its floor and the plugin is simply slower now
+		if !atFloor {
+			l.limit.Store(int64(l.current))
+			return
+		}
+	} else {
+		l.current = math.Min(l.current+1/l.current, float64(l.adaptive.MaxLimit))
+	}
+	l.limit.Store(int64(l.current))
+
+	l.baseline += baselineWeight * (sample - l.baseline)
+	l.baselineNs.Store(int64(l.baseline))
+}
+
+func (l *concurrencyLimiter) info() ConcurrencyInfo {
+	mode := ConcurrencyStatic
+	if l.adaptiveOn.Load() {
+		mode = ConcurrencyAdaptive
+	}
+	return ConcurrencyInfo{
+		Mode:     mode,
+		Limit:    int(l.limit.Load()),
+		InFlight: int(l.inFlight.Load()),
+		Rejected: l.rejected.Load(),
+		Baseline: time.Duration(l.baselineNs.Load()),
+	}
+}
+
+// configureLimiter applies a plugin's concurrency settings, creating its limiter on first load
+func (m *Manager) configureLimiter(name string, config *PluginSpecificConfig) {
+	if val, ok := m.limiters.Load(name); ok {
+		val.(*concurrencyLimiter).configure(*config)
+		return
+	}
+	m.limiters.Store(name, newConcurrencyLimiter(*config))
+}
+
+// limiterFor returns the concurrency limiter of a plugin, nil if it has none
+func (m *Manager) limiterFor(name string) *concurrencyLimiter {
+	if val, ok := m.limiters.Load(name); ok {
+		return val.(*concurrencyLimiter)
+	}
+	return nil
+}
+
+// GetConcurrencyInfo returns the concurrency limit of a plugin with the calls in flight.
+// Under ConcurrencyAdaptive it shows the limit as it adapts.
+func (m *Manager) GetConcurrencyInfo(pluginName string) (ConcurrencyInfo, error) {
+	if _, ok := m.plugins.Load(pluginName); !ok {
+		return ConcurrencyInfo{}, ErrPluginNotFound{Name: pluginName}
+	}
+	limiter := m.limiterFor(pluginName)
+	if limiter == nil {
+		return ConcurrencyInfo{Mode: ConcurrencyStatic}, nil
+	}
+	return limiter.info(), nil
+}
@@ -0,0 +1,185 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiter_Static(t *testing.T) {
+	l := newConcurrencyLimiter(PluginSpecificConfig{MaxConcurrentCalls: 2})
+	if !l.acquire() || !l.acquire() {
+		t.Fatal("acquire() refused a call below the limit")
+	}
+	if l.acquire() {
+		t.Fatal("acquire() admitted a call over the limit")
+	}
+	l.release()
+	if !l.acquire() {
+		t.Fatal("acquire() refused a call after a release")
+	}
+	info := l.info()
+	if info.Mode != ConcurrencyStatic || info.Limit != 2 || info.InFlight != 2 || info.Rejected != 1 {
+		t.Errorf("info() = %+v, want static, limit 2, 2 in flight, 1 rejected", info)
+	}
+
+	// Latencies leave a static limit alone
+	l.observe(time.Second)
+	if got := l.info().Limit; got != 2 {
+		t.Errorf("Static limit = %d after observe, want 2", got)
+	}
+}
+
+func TestConcurrencyLimiter_Unlimited(t *testing.T) {
+	l := newConcurrencyLimiter(PluginSpecificConfig{})
+	for i := 0; i < 1000; i++ {
+		if !l.acquire() {
+			t.Fatalf("acquire() refused call %d without a limit", i)
+		}
+	}
+}
+
+func TestConcurrencyLimiter_Adaptive(t *testing.T) {
+	l := newConcurrencyLimiter(PluginSpecificConfig{
+		ConcurrencyMode: ConcurrencyAdaptive,
+		AdaptiveConcurrency: AdaptiveConcurrencyConfig{
+			MinLimit:     2,
+			MaxLimit:     20,
+			InitialLimit: 10,
+		},
+	})
+	if got := l.info().Limit; got != 10 {
+		t.Fatalf("Initial limit = %d, want 10", got)
+	}
+
+	// Fast calls grow the limit up to MaxLimit
+	for i := 0; i < 500; i++ {
+		l.observe(10 * time.Millisecond)
+	}
+	if got := l.info().Limit; got != 20 {
+		t.Fatalf("Limit = %d after fast calls, want 20", got)
+	}
+	if got := l.info().Baseline; got != 10*time.Millisecond {
+		t.Errorf("Baseline = %v, want 10ms", got)
+	}
+
+	// A slow call cuts the limit by Backoff, and calls draining after it do not cut again
+	l.observe(time.Second)
+	if got := l.info().Limit; got != 15 {
+		t.Fatalf("Limit = %d after a slow call, want 15", got)
+	}
+	for i := 0; i < 5; i++ {
+		l.observe(time.Second)
+	}
+	if got := l.info().Limit; got != 15 {
+		t.Fatalf("Limit = %d while the cut drains, want 15", got)
+	}
+
+	// Slowness that persists brings the limit down to MinLimit
+	for i := 0; i < 60; i++ {
+		l.observe(time.Second)
+	}
+	if got := l.info().Limit; got != 2 {
+		t.Fatalf("Limit = %d under sustained slowness, want MinLimit 2", got)
+	}
+
+	// At the floor the slower latency becomes the baseline and the limit recovers
+	for i := 0; i < 1000; i++ {
+		l.observe(time.Second)
+	}
+	if got := l.info().Limit; got <= 2 {
+		t.Errorf("Limit = %d once the plugin settled at a slower latency, want above MinLimit", got)
+	}
+}
+
+func TestConcurrencyLimiter_Reconfigure(t *testing.T) {
+	adaptive := PluginSpecificConfig{
+		ConcurrencyMode:     ConcurrencyAdaptive,
+		AdaptiveConcurrency: AdaptiveConcurrencyConfig{MaxLimit: 8},
+	}
+	l := newConcurrencyLimiter(adaptive)
+	l.observe(10 * time.Millisecond)
+	l.observe(time.Second)
+	if got := l.info().Limit; got != 6 {
+		t.Fatalf("Limit = %d after a slow call, want 6", got)
+	}
+
+	// A reload keeps the learned limit
+	l.configure(adaptive)
+	if got := l.info().Limit; got != 6 {
+		t.Errorf("Limit = %d after reconfiguring, want the learned 6", got)
+	}
+	l.configure(PluginSpecificConfig{MaxConcurrentCalls: 3})
+	if info := l.info(); info.Mode != ConcurrencyStatic || info.Limit != 3 {
+		t.Errorf("info() = %+v after switching to static, want static limit 3", info)
+	}
+}
+
+func TestManager_CallConcurrencyLimit(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{
+			"Pay": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				started <- struct{}{}
+				<-release
+				return "ok", nil
+			},
+		}),
+	})
+	m := newTestManager(t, func(config *Config) {
+		config.PluginConfigs["payments"] = PluginSpecificConfig{MaxConcurrentCalls: 1}
+	})
+	loadTestPlugin(t, m, "payments", "v1")
+
+	ctx := context.Background()
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.Call(ctx, "payments", "Pay")
+		done <- err
+	}()
+	<-started
+
+	_, err := m.Call(ctx, "payments", "Pay")
+	var tooMany ErrTooManyConcurrentCalls
+	if !errors.As(err, &tooMany) || tooMany.Limit != 1 {
+		t.Fatalf("Call() error = %v, want ErrTooManyConcurrentCalls with limit 1", err)
+	}
+	info, err := m.GetConcurrencyInfo("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.InFlight != 1 || info.Rejected != 1 {
+		t.Errorf("GetConcurrencyInfo() = %+v, want 1 in flight and 1 rejected", info)
+	}
+	if m.GetBreakerState("payments"); m.GetBreakerStatus("payments") {
+		t.Error("A rejected call counted against the breaker")
+	}
+	breaker, err := m.GetBreakerInfo("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if breaker.ConcurrencyRejections != 1 || breaker.Rejected != 0 {
+		t.Errorf("GetBreakerInfo() = %+v, want 1 concurrency rejection and none by the breaker", breaker)
+	}
+	snapshot, err := m.GetMetricsSnapshot("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.ConcurrencyRejections != 1 || snapshot.BreakerRejections != 0 {
+		t.Errorf("GetMetricsSnapshot() rejections = %d by the limit and %d by the breaker, want 1 and 0",
+			snapshot.ConcurrencyRejections, snapshot.BreakerRejections)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Blocked call error = %v", err)
+	}
+	if _, err := m.Call(ctx, "payments", "Pay"); err != nil {
+		t.Errorf("Call() error = %v once the first call finished", err)
+	}
+	if _, err := m.GetConcurrencyInfo("missing"); !errors.As(err, new(ErrPluginNotFound)) {
+		t.Errorf("GetConcurrencyInfo() error = %v for an unknown plugin, want ErrPluginNotFound", err)
+	}
+}
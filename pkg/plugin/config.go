@@ -1,7 +1,11 @@
 package plugin
 
 import (
+	"crypto/ed25519"
 	"fmt"
+	"maps"
+	"path"
+	"strings"
 	"time"
 )
 
@@ -15,12 +19,176 @@ const (
 	LogLevelError
 )
 
+// VersionPolicy decides how upgrades are handled when plugin versions cannot be compared
+type VersionPolicy int
+
+const (
+	// VersionPolicyReject keeps the loaded version when either version is not valid semver
+	VersionPolicyReject VersionPolicy = iota
+	// VersionPolicyAccept replaces the loaded version when either version is not valid semver
+	VersionPolicyAccept
+)
+
+// NameCollisionPolicy decides what happens when a different artifact declares the name of a loaded plugin
+type NameCollisionPolicy int
+
+const (
+	// NameCollisionReject refuses the new artifact and keeps the loaded plugin
+	NameCollisionReject NameCollisionPolicy = iota
+	// NameCollisionPreferNewerVersion replaces the loaded plugin only if the new artifact has a higher version
+	NameCollisionPreferNewerVersion
+	// NameCollisionPreferNewerMtime replaces the loaded plugin if the new artifact was modified more recently
+	NameCollisionPreferNewerMtime
+)
+
+// StartupFailurePolicy decides how NewManager treats plugins that fail to load from PluginDir
+type StartupFailurePolicy int
+
+const (
+	// FailFast aborts startup on the first plugin that fails to load
+	FailFast StartupFailurePolicy = iota
+	// ContinueAndReport loads every plugin it can and records failures in the LoadReport
+	ContinueAndReport
+	// RequireNamed continues past failures unless the failed plugin is listed in RequiredPlugins
+	RequireNamed
+)
+
+// Defaults for the hot reload timing settings in Config
+const (
+	DefaultReloadDebounce       = 200 * time.Millisecond
+	DefaultFileStabilityWindow  = time.Second
+	DefaultFileStabilityTimeout = 30 * time.Second
+)
+
+// DefaultFreeTimeout bounds how long a plugin's Free may run before it is abandoned
+const DefaultFreeTimeout = 5 * time.Second
+
+// Defaults for collecting deprecated plugin instances
+const (
+	DefaultGCInterval    = time.Minute
+	DefaultGCGracePeriod = 30 * time.Second
+)
+
+// Default names of the symbols a plugin exports
+const (
+	DefaultExportSymbol    = "Export"
+	DefaultFunctionsSymbol = "Functions"
+)
+
+// Option keys in PluginSpecificConfig.Options overriding the exported symbol names per plugin
+const (
+	OptionExportSymbol    = "export_symbol"
+	OptionFunctionsSymbol = "functions_symbol"
+)
+
+// OptionBackend is the PluginSpecificConfig.Options key naming the Backend that loads a
+// plugin. When it is unset, executables run as process plugins and anything else is
+// opened in-process.
+const OptionBackend = "backend"
+
+// Names of the built-in backends
+const (
+	BackendNative  = "native"  // opened in-process with the plugin package
+	BackendProcess = "process" // run as a child process built with 'chameleon build --backend process'
+)
+
+// TripPolicy decides when a closed circuit breaker opens
+type TripPolicy int
+
+const (
+	// TripOnFailureCount opens the breaker once MaxFailures calls have failed
+	TripOnFailureCount TripPolicy = iota
+	// TripOnFailureRate opens the breaker when the share of failed calls in a sliding
+	// window exceeds FailureRateThreshold, once the window holds MinimumCalls calls
+	TripOnFailureRate
+)
+
+// BreakerScope decides which calls share a circuit breaker
+type BreakerScope int
+
+const (
+	// BreakerScopePlugin guards all functions of a plugin with one breaker
+	BreakerScopePlugin BreakerScope = iota
+	// BreakerScopeMethod gives every function its own breaker, created on its first call,
+	// so a failing function does not get calls to the others rejected
+	BreakerScopeMethod
+)
+
 // CircuitBreakerConfig defines configuration for the circuit breaker
 type CircuitBreakerConfig struct {
-	Enabled         bool
-	MaxFailures     int
-	ResetInterval   time.Duration
+	Enabled    bool
+	Scope      BreakerScope
+	TripPolicy TripPolicy
+	// MaxFailures is the number of failures that opens the breaker under TripOnFailureCount
+	MaxFailures int
+	// MaxTimeouts is the number of timed-out calls that opens the breaker, counted apart
+	// from other failures. Zero counts timeouts as ordinary failures.
+	MaxTimeouts int
+	// ResetInterval is how often a closed breaker clears its failure and timeout counts, so
+	// that MaxFailures and MaxTimeouts count within an interval. Zero never clears them
+	// before the breaker opens.
+	ResetInterval time.Duration
+	// OpenDuration is how long an open breaker rejects calls before it goes half-open and
+	// admits trial calls, counted from when it opened
+	OpenDuration time.Duration
+	// TimeoutDuration is the former name of OpenDuration, read in its place when
+	// OpenDuration is zero. It never bounded how long a call may take; PluginTimeout does.
+	//
+	// Deprecated: use OpenDuration.
 	TimeoutDuration time.Duration
+	// HalfOpenMaxCalls is how many trial calls a half-open breaker lets through; the
+	// breaker closes once they all succeed and reopens if one fails. Zero means 1.
+	HalfOpenMaxCalls int
+	// FailureRateThreshold is the share of failed calls, between 0 and 1, above which the
+	// breaker opens under TripOnFailureRate
+	FailureRateThreshold float64
+	// MinimumCalls is the number of calls the window must hold before the failure rate is
+	// considered
+	MinimumCalls int
+	// WindowSize makes the window the last WindowSize calls
+	WindowSize int
+	// WindowDuration makes the window the calls of the last WindowDuration instead of a
+	// number of calls
+	WindowDuration time.Duration
+	// IsFailure decides which call errors count against the breaker; nil means
+	// IsBreakerFailure. Calls whose error does not count are neither failures nor successes.
+	IsFailure func(error) bool `json:"-"`
+	// CarryOverOnReload starts the breaker of a reloaded plugin in the state and with the
+	// failure count of the breaker it replaces; by default that history is discarded
+	CarryOverOnReload bool
+}
+
+// RestartPolicy defines how a plugin whose process crashed is restarted. It applies to
+// plugins running outside the host, such as process plugins.
+type RestartPolicy struct {
+	// MaxRestarts is how many restarts are attempted within Window before the manager
+	// gives up on the plugin. Zero inherits the default policy; negative disables restarts.
+	MaxRestarts int
+	// Backoff is the wait before the first restart; it doubles with every further restart
+	// within Window
+	Backoff time.Duration
+	// Window is the period restarts are counted over; zero counts every restart
+	Window time.Duration
+}
+
+// ReadinessConfig tunes Manager.Readyz
+type ReadinessConfig struct {
+	// IgnoreOpenBreakers keeps the manager ready while breakers of required plugins are open
+	IgnoreOpenBreakers bool
+	// OpenBreakerGrace is how long the breaker of a required plugin may stay open before
+	// the manager is unready. Zero makes it unready as soon as the breaker opens.
+	OpenBreakerGrace time.Duration
+}
+
+// AuditConfig configures the JSON-lines audit log written when no sink is set with
+// WithAuditSink
+type AuditConfig struct {
+	// Path of the audit log; empty disables it
+	Path string
+	// MaxSize rotates the log before it grows beyond this many bytes; zero never rotates
+	MaxSize int64
+	// MaxBackups is how many rotated logs are kept; zero keeps all of them
+	MaxBackups int
 }
 
 // PluginSpecificConfig defines configuration for a specific plugin
@@ -29,26 +197,192 @@ type PluginSpecificConfig struct {
 	CircuitBreaker     CircuitBreakerConfig
 	MaxConcurrentCalls int
 	PluginTimeout      time.Duration
-	Options            map[string]interface{}
+	// ConcurrencyMode is ConcurrencyStatic, which holds calls to MaxConcurrentCalls, or
+	// ConcurrencyAdaptive, which adjusts the limit to the plugin's latency. Empty means static.
+	ConcurrencyMode ConcurrencyMode
+	// AdaptiveConcurrency tunes ConcurrencyAdaptive
+	AdaptiveConcurrency AdaptiveConcurrencyConfig
+	// RestartPolicy restarts the plugin when its process crashes
+	RestartPolicy RestartPolicy
+	// Replicas is how many instances of the plugin serve calls; Call sends each call to the
+	// healthy replica with the fewest calls in flight. More than one needs a backend that runs
+	// plugins outside the host. Zero means one.
+	Replicas int
+	// InitRetries is how many more times a failed Init is attempted before the load fails
+	InitRetries int
+	// InitRetryBackoff is the wait before the first retry; it doubles with every further retry
+	InitRetryBackoff time.Duration
+	// InitTimeout bounds all Init attempts together; no retry starts once it has passed.
+	// A single Init call is not interrupted. Zero means no bound.
+	InitTimeout time.Duration
+	// VersionConstraint limits which plugin versions may load, e.g. ">=1.2.0 <2.0.0", "^1.2" or "1.4.2"
+	VersionConstraint string
+	// RequiredFunctions must all be exported by the plugin; a load or upgrade missing any is rejected
+	RequiredFunctions []string
+	// AllowDowngrade lets a reload replace the plugin with a version that is not higher,
+	// e.g. when a current link is rolled back to an older release
+	AllowDowngrade bool
+	// CurrentLink is a symlink pointing at the active release of this plugin, such as
+	// "payments/current" -> "releases/1.4.0/payments.so". Relative paths are resolved
+	// against PluginDir. Retargeting the link reloads the plugin from the new release.
+	CurrentLink string
+	// Persist controls whether the plugin is saved to Config.StateFile; nil means it is
+	Persist *bool
+	// HotReload overrides Config.AllowHotReload for the plugin; nil inherits it. With it
+	// false, files the watcher sees for the loaded plugin are ignored and only explicit
+	// loads replace it. With it true the plugin directory is watched even if
+	// AllowHotReload is off.
+	HotReload *bool
+	// SlowCallThreshold logs a warning for every call that takes longer, with the plugin,
+	// function, duration, number of arguments and whether it succeeded. Zero disables it.
+	SlowCallThreshold time.Duration
+	// SlowCallLogRate caps the slow-call warnings at that many a second, so that a plugin
+	// that is slow throughout does not flood the log; the next warning counts the calls left
+	// out. Zero logs every slow call.
+	SlowCallLogRate float64
+	// AccessLog logs every call at Info, with its version, function, call ID, duration,
+	// number of arguments and outcome. Set it in Config.DefaultPluginConfig to log the
+	// calls to all plugins.
+	AccessLog bool
+	// AccessLogArgs adds the argument values to the access log, each cut to 128 bytes.
+	// They may hold data that must not reach the logs.
+	AccessLogArgs bool
+	// MetricLabels are added to every series the promexport and otelexport modules
+	// export for the plugin, e.g. {"team": "billing"}. Names must be legal Prometheus label
+	// names other than those the metrics set. Plugin labels are added to the default ones.
+	MetricLabels map[string]string
+	Options      map[string]interface{}
 }
 
 // Config defines the configuration for plugin manager
 type Config struct {
-	PluginDir           string
-	AllowHotReload      bool
-	LogLevel            LogLevel
+	PluginDir string
+	// PluginFilePatterns are doublestar-style globs selecting plugin artifacts in
+	// PluginDir. A "{name}" placeholder captures the plugin name, e.g.
+	// "{name}-*.linux-amd64.plugin"; without it the base name minus extension is used.
+	PluginFilePatterns []string
+	// ExcludePatterns are globs for files that are never treated as plugins
+	ExcludePatterns []string
+	// FollowSymlinkDirs descends into symlinked directories under PluginDir; links that
+	// would form a cycle are skipped. Symlinked files are always resolved before loading.
+	FollowSymlinkDirs bool
+	// AllowedPlugins restricts loading to the listed names or globs; empty allows all
+	AllowedPlugins []string
+	// BlockedPlugins lists names or globs that are never loaded, even if allowed
+	BlockedPlugins []string
+	// VerifyChecksums requires a "<file>.sha256" sidecar matching every plugin before it is
+	// opened. Verified plugins are opened from a private copy under ShadowDir, so the file
+	// cannot change between the check and the open.
+	VerifyChecksums bool
+	// TrustedPublicKeys are ed25519 public keys; when set every plugin must carry a
+	// "<file>.sig" detached signature made by one of them. Like VerifyChecksums, it opens
+	// plugins from a private copy.
+	TrustedPublicKeys [][]byte
+	// UnparseableVersionPolicy applies when an upgrade involves a version that is not valid semver
+	UnparseableVersionPolicy VersionPolicy
+	// StartupFailurePolicy applies when a plugin in PluginDir fails to load at startup
+	StartupFailurePolicy StartupFailurePolicy
+	// RequiredPlugins lists plugins that must be loaded once PluginDir has been scanned,
+	// as "name" or "name@constraint" (e.g. "auth@>=1.2.0 <2.0.0"). NewManager fails with
+	// ErrRequiredPluginMissing if any is missing, failed or has a non-matching version.
+	RequiredPlugins []string
+	// LoadOrder lists plugin names to load first, in order, when a directory or a batch of
+	// files is loaded; the remaining plugins follow sorted by path. Names that are not found
+	// are logged and skipped.
+	LoadOrder []string
+	// NameCollisionPolicy applies when an artifact at a different path declares the name of
+	// a loaded plugin. Manager.ForceLoadPlugin bypasses it.
+	NameCollisionPolicy NameCollisionPolicy
+	// ExportSymbolName is the symbol holding the plugin's Bureau (default "Export").
+	// A plugin can override it with PluginSpecificConfig.Options["export_symbol"].
+	ExportSymbolName string
+	// FunctionsSymbolName is the symbol holding the plugin's function map (default "Functions").
+	// A plugin can override it with PluginSpecificConfig.Options["functions_symbol"].
+	FunctionsSymbolName string
+	// StrictFunctionNames rejects plugins whose function map uses a reserved name
+	// (Name, Version, Init, Free or a "__" prefix) instead of dropping those entries
+	StrictFunctionNames bool
+	// RawCallErrors returns errors from plugin functions as-is instead of wrapping them in CallError
+	RawCallErrors bool
+	// ShadowCopy opens a private copy of each plugin artifact instead of the file itself,
+	// so in-place deploys that reuse a path can be reloaded. Copies live under ShadowDir.
+	ShadowCopy bool
+	// ShadowDir holds shadow copies (default "<os.TempDir()>/chameleon")
+	ShadowDir      string
+	AllowHotReload bool
+	// ReloadDebounce is how long a plugin file must see no further watcher events before
+	// it is reloaded, so multi-step deploys trigger one reload. Zero reloads on every event.
+	ReloadDebounce time.Duration
+	// FileStabilityWindow is how long a new plugin file's size and modification time must
+	// stay unchanged before it is loaded, so partially copied files are never opened.
+	// Zero loads immediately.
+	FileStabilityWindow time.Duration
+	// FileStabilityTimeout bounds the wait for a file to stabilize (default 30s)
+	FileStabilityTimeout time.Duration
+	// FreeTimeout bounds each plugin's Free during Close and unloads (default 5s). A plugin
+	// whose Free does not return in time is abandoned and reported as an ErrPluginFree.
+	FreeTimeout time.Duration
+	// FetchTimeout bounds each LoadPluginFromURL download (default 5m)
+	FetchTimeout time.Duration
+	// FetchMaxSize is the largest artifact LoadPluginFromURL accepts, in bytes (default 256 MiB)
+	FetchMaxSize int64
+	// FetchBearerToken is sent as an Authorization header with LoadPluginFromURL requests over HTTPS
+	FetchBearerToken string
+	// AdminToken must be sent as a bearer token to the mutating endpoints of
+	// Manager.AdminHandler; while it is empty those endpoints are refused
+	AdminToken string
+	// Readiness tunes what Manager.Readyz reports as unready
+	Readiness ReadinessConfig
+	// Audit configures the audit log of plugin lifecycle and admin actions
+	Audit AuditConfig
+	// StateFile, if set, is where the manager saves the loaded plugins after every load,
+	// upgrade and restart. NewManager restores the saved plugins that the PluginDir scan
+	// did not load, as long as their files still match the saved hashes.
+	StateFile string
+	// GCInterval is how often deprecated plugin instances are collected (default 1m).
+	// Zero disables the background collector; Manager.GCNow still works.
+	GCInterval time.Duration
+	// GCGracePeriod is how long a replaced instance is kept before it may be freed (default 30s)
+	GCGracePeriod time.Duration
+	LogLevel      LogLevel
+	// LogFormat is the format of the default logger, unused when WithLogger sets another
+	LogFormat           LogFormat
 	EnableMetrics       bool
 	DefaultPluginConfig PluginSpecificConfig
 	PluginConfigs       map[string]PluginSpecificConfig
+	// MetricsBuckets are the upper bounds, in increasing order, of the buckets of the
+	// latency histograms kept per function; nil means DefaultLatencyBuckets
+	MetricsBuckets []time.Duration
+	// RetainMetricsOnUnload keeps the metrics of plugins that are unloaded, or freed with
+	// the manager, so they can still be read; by default they are dropped. A reload or an
+	// upgrade keeps them either way.
+	RetainMetricsOnUnload bool
+	// MetricsVersions is how many versions of each plugin keep metrics of their own, so
+	// that a canary can be compared with the version it replaces; the version called
+	// least recently is dropped first. Zero means DefaultMetricsVersions.
+	MetricsVersions int
+	// MetricsReportInterval is how often a summary of each plugin's calls since the last
+	// one is logged at Info, for deployments without a metrics backend. Zero disables it.
+	MetricsReportInterval time.Duration
 }
 
 // DefaultCircuitBreakerConfig returns the default circuit breaker configuration
 func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
 	return CircuitBreakerConfig{
-		Enabled:         true,
-		MaxFailures:     5,
-		ResetInterval:   60 * time.Second,
-		TimeoutDuration: 5 * time.Second,
+		Enabled:          true,
+		MaxFailures:      5,
+		ResetInterval:    60 * time.Second,
+		OpenDuration:     5 * time.Second,
+		HalfOpenMaxCalls: 1,
+	}
+}
+
+// DefaultRestartPolicy returns the default restart policy
+func DefaultRestartPolicy() RestartPolicy {
+	return RestartPolicy{
+		MaxRestarts: 5,
+		Backoff:     time.Second,
+		Window:      10 * time.Minute,
 	}
 }
 
@@ -57,6 +391,7 @@ func DefaultPluginSpecificConfig() PluginSpecificConfig {
 	return PluginSpecificConfig{
 		InitArgs:           []interface{}{},
 		CircuitBreaker:     DefaultCircuitBreakerConfig(),
+		RestartPolicy:      DefaultRestartPolicy(),
 		MaxConcurrentCalls: 100,
 		PluginTimeout:      30 * time.Second,
 		Options:            make(map[string]interface{}),
@@ -67,7 +402,20 @@ func DefaultPluginSpecificConfig() PluginSpecificConfig {
 func DefaultConfig() *Config {
 	return &Config{
 		PluginDir:           "",
+		PluginFilePatterns:  append([]string(nil), DefaultPluginFilePatterns...),
+		ExcludePatterns:     []string{},
+		AllowedPlugins:      []string{},
+		BlockedPlugins:      []string{},
+		ExportSymbolName:    DefaultExportSymbol,
+		FunctionsSymbolName: DefaultFunctionsSymbol,
 		AllowHotReload:      true,
+		ReloadDebounce:      DefaultReloadDebounce,
+		FileStabilityWindow: DefaultFileStabilityWindow,
+		FreeTimeout:         DefaultFreeTimeout,
+		GCInterval:          DefaultGCInterval,
+		GCGracePeriod:       DefaultGCGracePeriod,
+		FetchTimeout:        DefaultFetchTimeout,
+		FetchMaxSize:        DefaultFetchMaxSize,
 		LogLevel:            LogLevelInfo,
 		EnableMetrics:       true,
 		DefaultPluginConfig: DefaultPluginSpecificConfig(),
@@ -83,9 +431,83 @@ func (c *Config) GetPluginConfig(pluginName string) PluginSpecificConfig {
 	return c.DefaultPluginConfig
 }
 
+// symbolNames resolves the exported symbol names for a plugin, applying per-plugin overrides
+func (c *Config) symbolNames(pluginConfig PluginSpecificConfig) (export, functions string) {
+	export, functions = c.ExportSymbolName, c.FunctionsSymbolName
+	if export == "" {
+		export = DefaultExportSymbol
+	}
+	if functions == "" {
+		functions = DefaultFunctionsSymbol
+	}
+	if name, ok := pluginConfig.Options[OptionExportSymbol].(string); ok && name != "" {
+		export = name
+	}
+	if name, ok := pluginConfig.Options[OptionFunctionsSymbol].(string); ok && name != "" {
+		functions = name
+	}
+	return export, functions
+}
+
+// IsPluginAllowed reports whether the allow and block lists permit loading the named plugin
+func (c *Config) IsPluginAllowed(pluginName string) bool {
+	if matchesAnyName(c.BlockedPlugins, pluginName) {
+		return false
+	}
+	return len(c.AllowedPlugins) == 0 || matchesAnyName(c.AllowedPlugins, pluginName)
+}
+
+// IsPluginRequired reports whether the named plugin is listed in RequiredPlugins
+func (c *Config) IsPluginRequired(pluginName string) bool {
+	for _, entry := range c.RequiredPlugins {
+		if req, err := parseRequiredPlugin(entry); err == nil && req.name == pluginName {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredPlugin is a parsed RequiredPlugins entry
+type requiredPlugin struct {
+	name       string
+	constraint string
+}
+
+// parseRequiredPlugin parses a RequiredPlugins entry of the form "name" or "name@constraint"
+func parseRequiredPlugin(entry string) (requiredPlugin, error) {
+	name, constraint, _ := strings.Cut(entry, "@")
+	req := requiredPlugin{name: strings.TrimSpace(name), constraint: strings.TrimSpace(constraint)}
+	if req.name == "" {
+		return req, fmt.Errorf("required plugin %q has no name", entry)
+	}
+	if req.constraint != "" {
+		if _, err := parseVersionConstraint(req.constraint); err != nil {
+			return req, fmt.Errorf("required plugin %s: %w", req.name, err)
+		}
+	}
+	return req, nil
+}
+
+// matchesAnyName checks a plugin name against a list of exact names or globs
+func matchesAnyName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if pattern == name {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // mergeConfig merges two configurations, using the specific configuration to override the default configuration
 func mergeConfig(defaultConfig, specificConfig PluginSpecificConfig) PluginSpecificConfig {
 	merged := defaultConfig
+	merged.Options = make(map[string]interface{}, len(defaultConfig.Options)+len(specificConfig.Options))
+	for k, v := range defaultConfig.Options {
+		merged.Options[k] = v
+	}
 
 	// If the specific configuration provides initialization arguments, use the arguments from the specific configuration
 	if len(specificConfig.InitArgs) > 0 {
@@ -97,6 +519,13 @@ func mergeConfig(defaultConfig, specificConfig PluginSpecificConfig) PluginSpeci
 		merged.CircuitBreaker = specificConfig.CircuitBreaker
 	}
 
+	if specificConfig.RestartPolicy.MaxRestarts != 0 {
+		merged.RestartPolicy = specificConfig.RestartPolicy
+	}
+	if specificConfig.Replicas > 0 {
+		merged.Replicas = specificConfig.Replicas
+	}
+
 	// If the specific configuration provides a maximum number of concurrent calls, use the value from the specific configuration
 	if specificConfig.MaxConcurrentCalls > 0 {
 		merged.MaxConcurrentCalls = specificConfig.MaxConcurrentCalls
@@ -104,6 +533,58 @@ func mergeConfig(defaultConfig, specificConfig PluginSpecificConfig) PluginSpeci
 	if specificConfig.PluginTimeout > 0 {
 		merged.PluginTimeout = specificConfig.PluginTimeout
 	}
+	if specificConfig.ConcurrencyMode != "" {
+		merged.ConcurrencyMode = specificConfig.ConcurrencyMode
+		merged.AdaptiveConcurrency = specificConfig.AdaptiveConcurrency
+	}
+	if specificConfig.InitRetries > 0 {
+		merged.InitRetries = specificConfig.InitRetries
+	}
+	if specificConfig.InitRetryBackoff > 0 {
+		merged.InitRetryBackoff = specificConfig.InitRetryBackoff
+	}
+	if specificConfig.InitTimeout > 0 {
+		merged.InitTimeout = specificConfig.InitTimeout
+	}
+	if specificConfig.VersionConstraint != "" {
+		merged.VersionConstraint = specificConfig.VersionConstraint
+	}
+	if len(specificConfig.RequiredFunctions) > 0 {
+		merged.RequiredFunctions = specificConfig.RequiredFunctions
+	}
+	if specificConfig.AllowDowngrade {
+		merged.AllowDowngrade = true
+	}
+	if specificConfig.CurrentLink != "" {
+		merged.CurrentLink = specificConfig.CurrentLink
+	}
+	if specificConfig.Persist != nil {
+		merged.Persist = specificConfig.Persist
+	}
+	if specificConfig.HotReload != nil {
+		merged.HotReload = specificConfig.HotReload
+	}
+	if specificConfig.SlowCallThreshold > 0 {
+		merged.SlowCallThreshold = specificConfig.SlowCallThreshold
+	}
+	if specificConfig.SlowCallLogRate > 0 {
+		merged.SlowCallLogRate = specificConfig.SlowCallLogRate
+	}
+	if specificConfig.AccessLog {
+		merged.AccessLog = true
+	}
+	if specificConfig.AccessLogArgs {
+		merged.AccessLogArgs = true
+	}
+	if len(defaultConfig.MetricLabels)+len(specificConfig.MetricLabels) > 0 {
+		merged.MetricLabels = make(map[string]string, len(defaultConfig.MetricLabels)+len(specificConfig.MetricLabels))
+		for k, v := range defaultConfig.MetricLabels {
+			merged.MetricLabels[k] = v
+		}
+		for k, v := range specificConfig.MetricLabels {
+			merged.MetricLabels[k] = v
+		}
+	}
 
 	// If the specific configuration provides options, use the options from the specific configuration
 	for k, v := range specificConfig.Options {
@@ -119,7 +600,61 @@ func ValidateConfig(config *Config) error {
 		return fmt.Errorf("config cannot be nil")
 	}
 
+	// Validate the plugin file patterns
+	if _, err := compileFilePatterns(config.PluginFilePatterns, config.ExcludePatterns); err != nil {
+		return err
+	}
+
+	// Validate the allow and block lists
+	for _, pattern := range append(append([]string(nil), config.AllowedPlugins...), config.BlockedPlugins...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid plugin name pattern %q: %w", pattern, err)
+		}
+	}
+
+	if config.FreeTimeout < 0 {
+		return fmt.Errorf("FreeTimeout cannot be negative")
+	}
+	if config.GCInterval < 0 || config.GCGracePeriod < 0 {
+		return fmt.Errorf("GCInterval and GCGracePeriod cannot be negative")
+	}
+	if config.Readiness.OpenBreakerGrace < 0 {
+		return fmt.Errorf("Readiness OpenBreakerGrace cannot be negative")
+	}
+	if config.Audit.MaxSize < 0 || config.Audit.MaxBackups < 0 {
+		return fmt.Errorf("Audit MaxSize and MaxBackups cannot be negative")
+	}
+	if config.FetchTimeout < 0 || config.FetchMaxSize < 0 {
+		return fmt.Errorf("FetchTimeout and FetchMaxSize cannot be negative")
+	}
+	if err := validateLatencyBuckets(config.MetricsBuckets); err != nil {
+		return err
+	}
+	if config.MetricsVersions < 0 {
+		return fmt.Errorf("MetricsVersions cannot be negative")
+	}
+	if config.MetricsReportInterval < 0 {
+		return fmt.Errorf("MetricsReportInterval cannot be negative")
+	}
+
+	// Validate the required plugins
+	for _, entry := range config.RequiredPlugins {
+		if _, err := parseRequiredPlugin(entry); err != nil {
+			return err
+		}
+	}
+
+	// Validate the trusted signing keys
+	for i, key := range config.TrustedPublicKeys {
+		if len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("trusted public key %d must be %d bytes, got %d", i, ed25519.PublicKeySize, len(key))
+		}
+	}
+
 	// Validate the default configuration
+	if config.DefaultPluginConfig.CurrentLink != "" {
+		return fmt.Errorf("invalid default plugin config: current link must be configured per plugin")
+	}
 	if err := validatePluginSpecificConfig(config.DefaultPluginConfig); err != nil {
 		return fmt.Errorf("invalid default plugin config: %w", err)
 	}
@@ -142,16 +677,138 @@ func validatePluginSpecificConfig(config PluginSpecificConfig) error {
 	if config.PluginTimeout < 0 {
 		return fmt.Errorf("PluginTimeout cannot be negative")
 	}
-	if config.CircuitBreaker.Enabled {
-		if config.CircuitBreaker.MaxFailures <= 0 {
+	if config.InitRetries < 0 {
+		return fmt.Errorf("InitRetries cannot be negative")
+	}
+	if config.InitRetryBackoff < 0 {
+		return fmt.Errorf("InitRetryBackoff cannot be negative")
+	}
+	if config.InitTimeout < 0 {
+		return fmt.Errorf("InitTimeout cannot be negative")
+	}
+	if config.SlowCallThreshold < 0 || config.SlowCallLogRate < 0 {
+		return fmt.Errorf("SlowCallThreshold and SlowCallLogRate cannot be negative")
+	}
+	if err := validateMetricLabels(config.MetricLabels); err != nil {
+		return err
+	}
+	if config.VersionConstraint != "" {
+		if _, err := parseVersionConstraint(config.VersionConstraint); err != nil {
+			return err
+		}
+	}
+	for _, name := range config.RequiredFunctions {
+		if name == "" {
+			return fmt.Errorf("RequiredFunctions cannot contain an empty name")
+		}
+	}
+	if err := validateConcurrency(config); err != nil {
+		return err
+	}
+	if backend, ok := config.Options[OptionBackend]; ok {
+		if s, _ := backend.(string); s == "" {
+			return fmt.Errorf("backend must be a non-empty string, got %v", backend)
+		}
+	}
+	if config.RestartPolicy.Backoff < 0 || config.RestartPolicy.Window < 0 {
+		return fmt.Errorf("RestartPolicy Backoff and Window cannot be negative")
+	}
+	if config.Replicas < 0 {
+		return fmt.Errorf("Replicas cannot be negative")
+	}
+	return validateBreakerConfig(config.CircuitBreaker)
+}
+
+// validateBreakerConfig validates an enabled circuit breaker's settings
+func validateBreakerConfig(config CircuitBreakerConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+	if err := validateTripPolicy(config); err != nil {
+		return err
+	}
+	if scope := config.Scope; scope != BreakerScopePlugin && scope != BreakerScopeMethod {
+		return fmt.Errorf("unknown CircuitBreaker Scope %d", scope)
+	}
+	if config.ResetInterval < 0 {
+		return fmt.Errorf("CircuitBreaker ResetInterval cannot be negative")
+	}
+	if config.OpenDuration < 0 || config.TimeoutDuration < 0 {
+		return fmt.Errorf("CircuitBreaker OpenDuration cannot be negative")
+	}
+	if migrateBreakerConfig(config).OpenDuration == 0 {
+		return fmt.Errorf("CircuitBreaker OpenDuration must be positive")
+	}
+	if config.HalfOpenMaxCalls < 0 {
+		return fmt.Errorf("CircuitBreaker HalfOpenMaxCalls cannot be negative")
+	}
+	if config.MaxTimeouts < 0 {
+		return fmt.Errorf("CircuitBreaker MaxTimeouts cannot be negative")
+	}
+	return nil
+}
+
+// migrateBreakerConfig moves a TimeoutDuration set by a config predating OpenDuration
+// to OpenDuration
+func migrateBreakerConfig(config CircuitBreakerConfig) CircuitBreakerConfig {
+	if config.OpenDuration == 0 {
+		config.OpenDuration = config.TimeoutDuration
+	}
+	return config
+}
+
+// usesTimeoutDuration reports whether a breaker config sets only the deprecated TimeoutDuration
+func usesTimeoutDuration(config CircuitBreakerConfig) bool {
+	return config.Enabled && config.OpenDuration == 0 && config.TimeoutDuration > 0
+}
+
+// validateConcurrency checks the concurrency mode and the adaptive limiter's settings
+func validateConcurrency(config PluginSpecificConfig) error {
+	switch config.ConcurrencyMode {
+	case "", ConcurrencyStatic:
+		return nil
+	case ConcurrencyAdaptive:
+	default:
+		return fmt.Errorf("unknown ConcurrencyMode %q", config.ConcurrencyMode)
+	}
+	adaptive := config.AdaptiveConcurrency
+	if adaptive.MinLimit < 0 || adaptive.MaxLimit < 0 || adaptive.InitialLimit < 0 {
+		return fmt.Errorf("AdaptiveConcurrency limits cannot be negative")
+	}
+	if adaptive.MaxLimit == 0 && config.MaxConcurrentCalls == 0 {
+		return fmt.Errorf("adaptive concurrency needs AdaptiveConcurrency.MaxLimit or MaxConcurrentCalls")
+	}
+	if adaptive.MaxLimit > 0 && adaptive.MinLimit > adaptive.MaxLimit {
+		return fmt.Errorf("AdaptiveConcurrency MinLimit cannot exceed MaxLimit")
+	}
+	if adaptive.LatencyTolerance != 0 && adaptive.LatencyTolerance <= 1 {
+		return fmt.Errorf("AdaptiveConcurrency LatencyTolerance must be above 1")
+	}
+	if adaptive.Backoff < 0 || adaptive.Backoff >= 1 {
+		return fmt.Errorf("AdaptiveConcurrency Backoff must be in (0, 1)")
+	}
+	return nil
+}
+
+// validateTripPolicy checks the settings of the breaker's trip policy
+func validateTripPolicy(config CircuitBreakerConfig) error {
+	switch config.TripPolicy {
+	case TripOnFailureCount:
+		if config.MaxFailures <= 0 {
 			return fmt.Errorf("CircuitBreaker MaxFailures must be positive")
 		}
-		if config.CircuitBreaker.ResetInterval <= 0 {
-			return fmt.Errorf("CircuitBreaker ResetInterval must be positive")
+	case TripOnFailureRate:
+		if config.FailureRateThreshold <= 0 || config.FailureRateThreshold > 1 {
+			return fmt.Errorf("CircuitBreaker FailureRateThreshold must be in (0, 1]")
+		}
+		if config.MinimumCalls < 0 || config.WindowSize < 0 || config.WindowDuration < 0 {
+			return fmt.Errorf("CircuitBreaker MinimumCalls, WindowSize and WindowDuration cannot be negative")
 		}
-		if config.CircuitBreaker.TimeoutDuration <= 0 {
-			return fmt.Errorf("CircuitBreaker TimeoutDuration must be positive")
+		if (config.WindowSize > 0) == (config.WindowDuration > 0) {
+			return fmt.Errorf("CircuitBreaker needs exactly one of WindowSize and WindowDuration")
 		}
+	default:
+		return fmt.Errorf("unknown CircuitBreaker TripPolicy %d", config.TripPolicy)
 	}
 	return nil
 }
@@ -159,17 +816,56 @@ func validatePluginSpecificConfig(config PluginSpecificConfig) error {
 // Clone creates a deep copy of the configuration
 func (c *Config) Clone() *Config {
 	clone := &Config{
-		PluginDir:           c.PluginDir,
-		AllowHotReload:      c.AllowHotReload,
-		LogLevel:            c.LogLevel,
-		EnableMetrics:       c.EnableMetrics,
-		DefaultPluginConfig: clonePluginSpecificConfig(c.DefaultPluginConfig),
-		PluginConfigs:       make(map[string]PluginSpecificConfig),
+		PluginDir:                c.PluginDir,
+		PluginFilePatterns:       append([]string(nil), c.PluginFilePatterns...),
+		ExcludePatterns:          append([]string(nil), c.ExcludePatterns...),
+		FollowSymlinkDirs:        c.FollowSymlinkDirs,
+		AllowedPlugins:           append([]string(nil), c.AllowedPlugins...),
+		BlockedPlugins:           append([]string(nil), c.BlockedPlugins...),
+		VerifyChecksums:          c.VerifyChecksums,
+		TrustedPublicKeys:        make([][]byte, 0, len(c.TrustedPublicKeys)),
+		UnparseableVersionPolicy: c.UnparseableVersionPolicy,
+		StartupFailurePolicy:     c.StartupFailurePolicy,
+		RequiredPlugins:          append([]string(nil), c.RequiredPlugins...),
+		LoadOrder:                append([]string(nil), c.LoadOrder...),
+		NameCollisionPolicy:      c.NameCollisionPolicy,
+		ExportSymbolName:         c.ExportSymbolName,
+		FunctionsSymbolName:      c.FunctionsSymbolName,
+		StrictFunctionNames:      c.StrictFunctionNames,
+		RawCallErrors:            c.RawCallErrors,
+		ShadowCopy:               c.ShadowCopy,
+		ShadowDir:                c.ShadowDir,
+		AllowHotReload:           c.AllowHotReload,
+		ReloadDebounce:           c.ReloadDebounce,
+		FileStabilityWindow:      c.FileStabilityWindow,
+		FileStabilityTimeout:     c.FileStabilityTimeout,
+		FreeTimeout:              c.FreeTimeout,
+		GCInterval:               c.GCInterval,
+		GCGracePeriod:            c.GCGracePeriod,
+		FetchTimeout:             c.FetchTimeout,
+		FetchMaxSize:             c.FetchMaxSize,
+		FetchBearerToken:         c.FetchBearerToken,
+		AdminToken:               c.AdminToken,
+		Readiness:                c.Readiness,
+		Audit:                    c.Audit,
+		StateFile:                c.StateFile,
+		LogLevel:                 c.LogLevel,
+		LogFormat:                c.LogFormat,
+		EnableMetrics:            c.EnableMetrics,
+		MetricsBuckets:           append([]time.Duration(nil), c.MetricsBuckets...),
+		RetainMetricsOnUnload:    c.RetainMetricsOnUnload,
+		MetricsVersions:          c.MetricsVersions,
+		MetricsReportInterval:    c.MetricsReportInterval,
+		DefaultPluginConfig:      clonePluginSpecificConfig(c.DefaultPluginConfig),
+		PluginConfigs:            make(map[string]PluginSpecificConfig),
 	}
 
 	for name, config := range c.PluginConfigs {
 		clone.PluginConfigs[name] = clonePluginSpecificConfig(config)
 	}
+	for _, key := range c.TrustedPublicKeys {
+		clone.TrustedPublicKeys = append(clone.TrustedPublicKeys, append([]byte(nil), key...))
+	}
 
 	return clone
 }
@@ -177,13 +873,37 @@ func (c *Config) Clone() *Config {
 // clonePluginSpecificConfig creates a deep copy of the plugin specific configuration
 func clonePluginSpecificConfig(config PluginSpecificConfig) PluginSpecificConfig {
 	clone := PluginSpecificConfig{
-		InitArgs:           make([]interface{}, len(config.InitArgs)),
-		CircuitBreaker:     config.CircuitBreaker,
-		MaxConcurrentCalls: config.MaxConcurrentCalls,
-		PluginTimeout:      config.PluginTimeout,
-		Options:            make(map[string]interface{}),
+		InitArgs:            make([]interface{}, len(config.InitArgs)),
+		CircuitBreaker:      config.CircuitBreaker,
+		RestartPolicy:       config.RestartPolicy,
+		Replicas:            config.Replicas,
+		MaxConcurrentCalls:  config.MaxConcurrentCalls,
+		PluginTimeout:       config.PluginTimeout,
+		ConcurrencyMode:     config.ConcurrencyMode,
+		AdaptiveConcurrency: config.AdaptiveConcurrency,
+		InitRetries:         config.InitRetries,
+		InitRetryBackoff:    config.InitRetryBackoff,
+		InitTimeout:         config.InitTimeout,
+		VersionConstraint:   config.VersionConstraint,
+		RequiredFunctions:   append([]string(nil), config.RequiredFunctions...),
+		AllowDowngrade:      config.AllowDowngrade,
+		CurrentLink:         config.CurrentLink,
+		SlowCallThreshold:   config.SlowCallThreshold,
+		SlowCallLogRate:     config.SlowCallLogRate,
+		AccessLog:           config.AccessLog,
+		AccessLogArgs:       config.AccessLogArgs,
+		MetricLabels:        maps.Clone(config.MetricLabels),
+		Options:             make(map[string]interface{}),
 	}
 
+	if config.Persist != nil {
+		persist := *config.Persist
+		clone.Persist = &persist
+	}
+	if config.HotReload != nil {
+		hotReload := *config.HotReload
+		clone.HotReload = &hotReload
+	}
 	copy(clone.InitArgs, config.InitArgs)
 	for k, v := range config.Options {
 		clone.Options[k] = v
@@ -191,3 +911,43 @@ func clonePluginSpecificConfig(config PluginSpecificConfig) PluginSpecificConfig
 
 	return clone
 }
+
+// hotReloadEnabled reports whether the watcher may reload the named plugin, from its
+// HotReload setting or else AllowHotReload
+func (c *Config) hotReloadEnabled(pluginName string) bool {
+	if hotReload := c.GetPluginConfig(pluginName).HotReload; hotReload != nil {
+		return *hotReload
+	}
+	return c.AllowHotReload
+}
+
+// hotReloadDisabled reports whether the named plugin turns HotReload off
+func (c *Config) hotReloadDisabled(pluginName string) bool {
+	hotReload := c.GetPluginConfig(pluginName).HotReload
+	return hotReload != nil && !*hotReload
+}
+
+// watchesPluginDir reports whether the plugin directory is watched: with AllowHotReload
+// or when a plugin config turns HotReload on
+func (c *Config) watchesPluginDir() bool {
+	if c.PluginDir == "" {
+		return false
+	}
+	if c.AllowHotReload {
+		return true
+	}
+	if hotReload := c.DefaultPluginConfig.HotReload; hotReload != nil && *hotReload {
+		return true
+	}
+	for _, pluginConfig := range c.PluginConfigs {
+		if pluginConfig.HotReload != nil && *pluginConfig.HotReload {
+			return true
+		}
+	}
+	return false
+}
+
+// persisted reports whether the plugin is saved to Config.StateFile
+func (c PluginSpecificConfig) persisted() bool {
+	return c.Persist == nil || *c.Persist
+}
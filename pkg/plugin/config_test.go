@@ -0,0 +1,217 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_IsPluginAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		blocked []string
+		plugin  string
+		want    bool
+	}{
+		{name: "empty lists allow all", plugin: "payments", want: true},
+		{name: "exact block", blocked: []string{"payments"}, plugin: "payments", want: false},
+		{name: "glob block", blocked: []string{"eu-*"}, plugin: "eu-payments", want: false},
+		{name: "allow list hit", allowed: []string{"payments", "auth"}, plugin: "auth", want: true},
+		{name: "allow list miss", allowed: []string{"payments"}, plugin: "auth", want: false},
+		{name: "block wins over allow", allowed: []string{"*"}, blocked: []string{"auth"}, plugin: "auth", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.AllowedPlugins = tt.allowed
+			c.BlockedPlugins = tt.blocked
+			if got := c.IsPluginAllowed(tt.plugin); got != tt.want {
+				t.Errorf("IsPluginAllowed(%q) = %v, want %v", tt.plugin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_InvalidNamePattern(t *testing.T) {
+	c := DefaultConfig()
+	c.BlockedPlugins = []string{"[payments"}
+	if err := ValidateConfig(c); err == nil {
+		t.Error("expected error for malformed block pattern")
+	}
+}
+
+func TestValidateConfig_RequiredPlugins(t *testing.T) {
+	tests := []struct {
+		name     string
+		required []string
+		wantErr  bool
+	}{
+		{name: "plain name", required: []string{"auth"}},
+		{name: "with constraint", required: []string{"auth@>=1.2.0 <2.0.0"}},
+		{name: "empty name", required: []string{"@1.0.0"}, wantErr: true},
+		{name: "bad constraint", required: []string{"auth@>=banana"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.RequiredPlugins = tt.required
+			if err := ValidateConfig(c); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !c.IsPluginRequired("auth") {
+				t.Error("expected auth to be required")
+			}
+		})
+	}
+}
+
+func TestValidateConfig_MetricsBuckets(t *testing.T) {
+	tests := []struct {
+		name    string
+		bounds  []time.Duration
+		wantErr bool
+	}{
+		{name: "default", bounds: nil},
+		{name: "custom", bounds: []time.Duration{time.Millisecond, time.Second}},
+		{name: "not increasing", bounds: []time.Duration{time.Second, time.Millisecond}, wantErr: true},
+		{name: "repeated", bounds: []time.Duration{time.Second, time.Second}, wantErr: true},
+		{name: "zero", bounds: []time.Duration{0, time.Second}, wantErr: true},
+		{name: "too many", bounds: make([]time.Duration, maxLatencyBuckets+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.MetricsBuckets = tt.bounds
+			if err := ValidateConfig(c); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_MetricLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		wantErr bool
+	}{
+		{name: "none", labels: nil},
+		{name: "valid", labels: map[string]string{"team": "billing", "_tier": "", "Zone2": "eu"}},
+		{name: "dash", labels: map[string]string{"cost-center": "42"}, wantErr: true},
+		{name: "leading digit", labels: map[string]string{"2tier": "1"}, wantErr: true},
+		{name: "reserved by Prometheus", labels: map[string]string{"__name__": "x"}, wantErr: true},
+		{name: "set by the metrics", labels: map[string]string{"version": "x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.PluginConfigs["payments"] = PluginSpecificConfig{MetricLabels: tt.labels}
+			if err := ValidateConfig(c); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_OpenDuration(t *testing.T) {
+	breaker := func(f func(*CircuitBreakerConfig)) CircuitBreakerConfig {
+		cb := DefaultCircuitBreakerConfig()
+		f(&cb)
+		return cb
+	}
+	tests := []struct {
+		name    string
+		breaker CircuitBreakerConfig
+		wantErr bool
+	}{
+		{name: "default", breaker: DefaultCircuitBreakerConfig()},
+		{name: "no ResetInterval", breaker: breaker(func(cb *CircuitBreakerConfig) { cb.ResetInterval = 0 })},
+		{name: "only TimeoutDuration", breaker: breaker(func(cb *CircuitBreakerConfig) { cb.OpenDuration, cb.TimeoutDuration = 0, time.Second })},
+		{name: "no open duration", breaker: breaker(func(cb *CircuitBreakerConfig) { cb.OpenDuration = 0 }), wantErr: true},
+		{name: "negative OpenDuration", breaker: breaker(func(cb *CircuitBreakerConfig) { cb.OpenDuration = -time.Second }), wantErr: true},
+		{name: "negative ResetInterval", breaker: breaker(func(cb *CircuitBreakerConfig) { cb.ResetInterval = -time.Second }), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.DefaultPluginConfig.CircuitBreaker = tt.breaker
+			if err := ValidateConfig(c); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Concurrency(t *testing.T) {
+	adaptive := func(a AdaptiveConcurrencyConfig) PluginSpecificConfig {
+		return PluginSpecificConfig{ConcurrencyMode: ConcurrencyAdaptive, AdaptiveConcurrency: a}
+	}
+	tests := []struct {
+		name    string
+		plugin  PluginSpecificConfig
+		wantErr bool
+	}{
+		{name: "static", plugin: PluginSpecificConfig{ConcurrencyMode: ConcurrencyStatic, MaxConcurrentCalls: 10}},
+		{name: "adaptive", plugin: adaptive(AdaptiveConcurrencyConfig{MinLimit: 2, MaxLimit: 50, LatencyTolerance: 1.5, Backoff: 0.5})},
+		{name: "adaptive up to MaxConcurrentCalls", plugin: PluginSpecificConfig{ConcurrencyMode: ConcurrencyAdaptive, MaxConcurrentCalls: 10}},
+		{name: "unknown mode", plugin: PluginSpecificConfig{ConcurrencyMode: "elastic"}, wantErr: true},
+		{name: "no upper bound", plugin: adaptive(AdaptiveConcurrencyConfig{}), wantErr: true},
+		{name: "negative limit", plugin: adaptive(AdaptiveConcurrencyConfig{MinLimit: -1, MaxLimit: 10}), wantErr: true},
+		{name: "min above max", plugin: adaptive(AdaptiveConcurrencyConfig{MinLimit: 20, MaxLimit: 10}), wantErr: true},
+		{name: "tolerance of 1", plugin: adaptive(AdaptiveConcurrencyConfig{MaxLimit: 10, LatencyTolerance: 1}), wantErr: true},
+		{name: "backoff of 1", plugin: adaptive(AdaptiveConcurrencyConfig{MaxLimit: 10, Backoff: 1}), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.PluginConfigs["payments"] = tt.plugin
+			if err := ValidateConfig(c); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_TripPolicy(t *testing.T) {
+	rate := func(threshold float64, size int, duration time.Duration) CircuitBreakerConfig {
+		cb := DefaultCircuitBreakerConfig()
+		cb.TripPolicy = TripOnFailureRate
+		cb.FailureRateThreshold = threshold
+		cb.WindowSize = size
+		cb.WindowDuration = duration
+		return cb
+	}
+	tests := []struct {
+		name    string
+		breaker CircuitBreakerConfig
+		wantErr bool
+	}{
+		{name: "failure count", breaker: DefaultCircuitBreakerConfig()},
+		{name: "count window", breaker: rate(0.5, 100, 0)},
+		{name: "time window", breaker: rate(0.5, 0, 30*time.Second)},
+		{name: "no window", breaker: rate(0.5, 0, 0), wantErr: true},
+		{name: "both windows", breaker: rate(0.5, 100, 30*time.Second), wantErr: true},
+		{name: "zero threshold", breaker: rate(0, 100, 0), wantErr: true},
+		{name: "threshold above 1", breaker: rate(1.5, 100, 0), wantErr: true},
+		{name: "unknown policy", breaker: CircuitBreakerConfig{Enabled: true, TripPolicy: 7}, wantErr: true},
+		{name: "negative max timeouts", breaker: func() CircuitBreakerConfig {
+			cb := DefaultCircuitBreakerConfig()
+			cb.MaxTimeouts = -1
+			return cb
+		}(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.DefaultPluginConfig.CircuitBreaker = tt.breaker
+			if err := ValidateConfig(c); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
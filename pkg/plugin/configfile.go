@@ -0,0 +1,255 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configEnumNames are the names config files use for the values of the enum types in Config
+var configEnumNames = map[reflect.Type][]string{
+	reflect.TypeOf(LogLevel(0)):             {"debug", "info", "warn", "error"},
+	reflect.TypeOf(LogFormat(0)):            {"text", "json"},
+	reflect.TypeOf(VersionPolicy(0)):        {"reject", "accept"},
+	reflect.TypeOf(NameCollisionPolicy(0)):  {"reject", "prefer-newer-version", "prefer-newer-mtime"},
+	reflect.TypeOf(StartupFailurePolicy(0)): {"fail-fast", "continue-and-report", "require-named"},
+	reflect.TypeOf(TripPolicy(0)):           {"failure-count", "failure-rate"},
+	reflect.TypeOf(BreakerScope(0)):         {"plugin", "method"},
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	bytesType    = reflect.TypeOf([]byte(nil))
+)
+
+// LoadConfigFromFile reads a manager configuration from a YAML (.yaml, .yml) or JSON
+// (.json) file and validates it. Keys are the names of the Config fields, e.g.
+// "DefaultPluginConfig" with a "CircuitBreaker" block; fields the file leaves out keep
+// their DefaultConfig values, and PluginConfigs entries are merged with
+// DefaultPluginConfig as usual. Durations are written as in Go ("30s", "1m30s"), the
+// policies and levels by name ("info", "continue-and-report", "failure-rate") and
+// TrustedPublicKeys in base64. A key that is not a field is an error naming it.
+func LoadConfigFromFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var tree interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &tree)
+	case ".yaml", ".yml":
+		err = yaml.NewDecoder(bytes.NewReader(data)).Decode(&tree)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	default:
+		return nil, fmt.Errorf("config %s: unsupported extension %q, want .yaml, .yml or .json", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	config := DefaultConfig()
+	if tree != nil {
+		if err := decodeConfigValue(reflect.ValueOf(config).Elem(), tree, ""); err != nil {
+			return nil, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	if err := ValidateConfig(config); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// decodeConfigValue stores the decoded YAML or JSON value node in v. key is the dotted
+// path of v in the file, for errors.
+func decodeConfigValue(v reflect.Value, node interface{}, key string) error {
+	if node == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if names, ok := configEnumNames[v.Type()]; ok {
+		return decodeConfigEnum(v, node, key, names)
+	}
+
+	switch {
+	case v.Type() == durationType:
+		s, ok := node.(string)
+		if !ok {
+			return fmt.Errorf("%s: want a duration such as \"30s\", got %v", key, node)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Type() == bytesType:
+		s, ok := node.(string)
+		if !ok {
+			return fmt.Errorf("%s: want a base64 string, got %v", key, node)
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		v.SetBytes(b)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fields, ok := node.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want a mapping, got %v", configKeyOrRoot(key), node)
+		}
+		// In order, so that the first error is always the same
+		for _, name := range slices.Sorted(maps.Keys(fields)) {
+			field, ok := configField(v, name)
+			if !ok {
+				return fmt.Errorf("unknown key %q", joinConfigKey(key, name))
+			}
+			if err := decodeConfigValue(field, fields[name], joinConfigKey(key, name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		entries, ok := node.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want a mapping, got %v", key, node)
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(entries))
+		for _, name := range slices.Sorted(maps.Keys(entries)) {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeConfigValue(elem, entries[name], joinConfigKey(key, name)); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(name), elem)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Slice:
+		items, ok := node.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want a list, got %v", key, node)
+		}
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeConfigValue(s.Index(i), item, fmt.Sprintf("%s[%d]", key, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := decodeConfigValue(elem.Elem(), node, key); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(node))
+		return nil
+	case reflect.String:
+		s, ok := node.(string)
+		if !ok {
+			return fmt.Errorf("%s: want a string, got %v", key, node)
+		}
+		v.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, ok := node.(bool)
+		if !ok {
+			return fmt.Errorf("%s: want true or false, got %v", key, node)
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int64:
+		n, ok := configNumber(node)
+		if !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: want an integer, got %v", key, node)
+		}
+		v.SetInt(int64(n))
+		return nil
+	case reflect.Float64:
+		n, ok := configNumber(node)
+		if !ok {
+			return fmt.Errorf("%s: want a number, got %v", key, node)
+		}
+		v.SetFloat(n)
+		return nil
+	}
+	return fmt.Errorf("%s: cannot be set from a config file", key)
+}
+
+// decodeConfigEnum stores the value named by node in v, an enum type of names
+func decodeConfigEnum(v reflect.Value, node interface{}, key string, names []string) error {
+	if s, ok := node.(string); ok {
+		for i, name := range names {
+			if strings.EqualFold(s, name) {
+				v.SetInt(int64(i))
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%s: invalid value %v, want one of %s", key, node, strings.Join(names, ", "))
+}
+
+// configField returns the field of the struct v that name sets, matched case-insensitively
+// as encoding/json does. Fields excluded from JSON, such as IsFailure, cannot be set.
+func configField(v reflect.Value, name string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.IsExported() && f.Tag.Get("json") != "-" && strings.EqualFold(f.Name, name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// configNumber returns node as a float64 if it is a number; YAML decodes integers as int
+// and JSON every number as float64
+func configNumber(node interface{}) (float64, bool) {
+	switch n := node.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func joinConfigKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
+
+func configKeyOrRoot(key string) string {
+	if key == "" {
+		return "top level"
+	}
+	return key
+}
@@ -0,0 +1,233 @@
+package plugin
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeConfigFile writes content to a file named name in a new temporary directory
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// configTree is the inverse of decodeConfigValue, turning v into the values a YAML or
+// JSON file decodes to
+func configTree(v reflect.Value) interface{} {
+	if names, ok := configEnumNames[v.Type()]; ok {
+		return names[v.Int()]
+	}
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Type() == bytesType:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.Tag.Get("json") != "-" {
+				fields[f.Name] = configTree(v.Field(i))
+			}
+		}
+		return fields
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := make(map[string]interface{})
+		for iter := v.MapRange(); iter.Next(); {
+			entries[iter.Key().String()] = configTree(iter.Value())
+		}
+		return entries
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = configTree(v.Index(i))
+		}
+		return items
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return configTree(v.Elem())
+	}
+	return v.Interface()
+}
+
+func TestLoadConfigFromFile_RoundTrip(t *testing.T) {
+	persist := false
+	key, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.PluginDir = "/opt/plugins"
+	config.PluginFilePatterns = []string{"{name}-*.so"}
+	config.BlockedPlugins = []string{"experimental-*"}
+	config.TrustedPublicKeys = [][]byte{key}
+	config.StartupFailurePolicy = RequireNamed
+	config.RequiredPlugins = []string{"auth@^1.2"}
+	config.NameCollisionPolicy = NameCollisionPreferNewerMtime
+	config.ReloadDebounce = 750 * time.Millisecond
+	config.FetchMaxSize = 1 << 30
+	config.Readiness.OpenBreakerGrace = 45 * time.Second
+	config.Audit = AuditConfig{Path: "/var/log/chameleon/audit.log", MaxSize: 1 << 20, MaxBackups: 3}
+	config.LogLevel = LogLevelDebug
+	config.LogFormat = LogFormatJSON
+	config.MetricsBuckets = []time.Duration{10 * time.Millisecond, time.Second}
+	config.MetricsReportInterval = 5 * time.Minute
+	config.DefaultPluginConfig.CircuitBreaker.MaxFailures = 8
+	config.DefaultPluginConfig.CircuitBreaker.Scope = BreakerScopeMethod
+	config.DefaultPluginConfig.AccessLog = true
+	config.PluginConfigs["auth"] = PluginSpecificConfig{
+		InitArgs: []interface{}{"https://auth.internal"},
+		CircuitBreaker: CircuitBreakerConfig{
+			Enabled:              true,
+			TripPolicy:           TripOnFailureRate,
+			FailureRateThreshold: 0.25,
+			MinimumCalls:         10,
+			WindowDuration:       time.Minute,
+			OpenDuration:         10 * time.Second,
+			CarryOverOnReload:    true,
+		},
+		PluginTimeout:       1500 * time.Millisecond,
+		ConcurrencyMode:     ConcurrencyAdaptive,
+		AdaptiveConcurrency: AdaptiveConcurrencyConfig{MinLimit: 2, MaxLimit: 40, LatencyTolerance: 1.5},
+		RestartPolicy:       RestartPolicy{MaxRestarts: -1},
+		Persist:             &persist,
+		RequiredFunctions:   []string{"Authenticate"},
+		MetricLabels:        map[string]string{"team": "identity"},
+		Options:             map[string]interface{}{OptionBackend: BackendProcess},
+	}
+
+	tree := configTree(reflect.ValueOf(config).Elem())
+	yamlData, err := yaml.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{"chameleon.yaml": yamlData, "chameleon.json": jsonData} {
+		loaded, err := LoadConfigFromFile(writeConfigFile(t, name, string(data)))
+		if err != nil {
+			t.Fatalf("LoadConfigFromFile(%s) error = %v", name, err)
+		}
+		if !reflect.DeepEqual(loaded, config) {
+			t.Errorf("LoadConfigFromFile(%s) = %+v, want %+v", name, loaded, config)
+		}
+	}
+}
+
+func TestLoadConfigFromFile_Defaults(t *testing.T) {
+	for name, content := range map[string]string{
+		"empty.yaml":   "",
+		"comment.yml":  "# nothing set\n",
+		"empty.json":   "{}",
+		"partial.yaml": "PluginDir: ./plugins\n",
+	} {
+		config, err := LoadConfigFromFile(writeConfigFile(t, name, content))
+		if err != nil {
+			t.Fatalf("LoadConfigFromFile(%s) error = %v", name, err)
+		}
+		want := DefaultConfig()
+		want.PluginDir = config.PluginDir
+		if !reflect.DeepEqual(config, want) {
+			t.Errorf("LoadConfigFromFile(%s) = %+v, want the defaults", name, config)
+		}
+	}
+
+	// A block keeps the defaults of the fields it leaves out
+	config, err := LoadConfigFromFile(writeConfigFile(t, "breaker.yaml", `
+DefaultPluginConfig:
+  PluginTimeout: 2s
+  CircuitBreaker:
+    MaxFailures: 3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultPluginSpecificConfig()
+	want.PluginTimeout = 2 * time.Second
+	want.CircuitBreaker.MaxFailures = 3
+	if !reflect.DeepEqual(config.DefaultPluginConfig, want) {
+		t.Errorf("DefaultPluginConfig = %+v, want %+v", config.DefaultPluginConfig, want)
+	}
+}
+
+func TestLoadConfigFromFile_Example(t *testing.T) {
+	config, err := LoadConfigFromFile("../../examples/config/chameleon.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.PluginDir != "./plugins" || config.StartupFailurePolicy != ContinueAndReport {
+		t.Errorf("PluginDir, StartupFailurePolicy = %q, %v", config.PluginDir, config.StartupFailurePolicy)
+	}
+	auth := config.GetPluginConfig("auth")
+	if auth.PluginTimeout != 5*time.Second || auth.CircuitBreaker.TripPolicy != TripOnFailureRate ||
+		auth.CircuitBreaker.WindowDuration != time.Minute || auth.MetricLabels["team"] != "identity" {
+		t.Errorf("auth config = %+v", auth)
+	}
+	if !reflect.DeepEqual(auth.InitArgs, []interface{}{"https://auth.internal", 3}) {
+		t.Errorf("auth InitArgs = %#v", auth.InitArgs)
+	}
+	if reports := config.GetPluginConfig("reports"); reports.ConcurrencyMode != ConcurrencyAdaptive ||
+		reports.Options[OptionBackend] != BackendProcess || reports.CircuitBreaker.MaxFailures != 5 {
+		t.Errorf("reports config = %+v", reports)
+	}
+}
+
+func TestLoadConfigFromFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"typo.yaml", "DefaultPluginConfig:\n  CircuitBreaker:\n    MaxFailuers: 3\n", `unknown key "DefaultPluginConfig.CircuitBreaker.MaxFailuers"`},
+		{"typo.json", `{"PluginConfigs": {"auth": {"PluginTimout": "5s"}}}`, `unknown key "PluginConfigs.auth.PluginTimout"`},
+		{"func.yaml", "DefaultPluginConfig:\n  CircuitBreaker:\n    IsFailure: x\n", `unknown key "DefaultPluginConfig.CircuitBreaker.IsFailure"`},
+		{"duration.yaml", "FreeTimeout: 30\n", `FreeTimeout: want a duration such as "30s"`},
+		{"badduration.json", `{"GCInterval": "1 minute"}`, "GCInterval: time: unknown unit"},
+		{"enum.yaml", "LogLevel: verbose\n", "LogLevel: invalid value verbose, want one of debug, info, warn, error"},
+		{"int.json", `{"MetricsVersions": 1.5}`, "MetricsVersions: want an integer"},
+		{"list.yaml", "AllowedPlugins: auth\n", "AllowedPlugins: want a list"},
+		{"key.yaml", "TrustedPublicKeys: [\"not base64!\"]\n", "TrustedPublicKeys[0]: illegal base64"},
+		{"top.yaml", "- PluginDir\n", "top level: want a mapping"},
+		{"invalid.yaml", "FreeTimeout: -1s\n", "FreeTimeout cannot be negative"},
+		{"syntax.json", `{"PluginDir": }`, "failed to parse config"},
+		{"config.toml", "PluginDir = 'x'", `unsupported extension ".toml"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfigFromFile(writeConfigFile(t, tt.name, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfigFromFile() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadConfigFromFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadConfigFromFile() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,392 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// hotConfigFields are the Config fields a config reload applies to the running manager;
+// the others need a restart. The manager reads them where they are used, except LogLevel,
+// which is passed to SetLogLevel.
+var hotConfigFields = map[string]bool{
+	"LogLevel":             true,
+	"AllowedPlugins":       true,
+	"BlockedPlugins":       true,
+	"FileStabilityWindow":  true,
+	"FileStabilityTimeout": true,
+	"FreeTimeout":          true,
+	"FetchTimeout":         true,
+	"GCGracePeriod":        true,
+	"Readiness":            true,
+}
+
+// hotPluginConfigFields are the PluginSpecificConfig fields a config reload applies. The
+// breaker, concurrency, slow-call and access log settings, the metric labels and the
+// options are applied to loaded plugins at once, as UpdatePluginConfig does; the timeouts
+// apply from their next load.
+var hotPluginConfigFields = map[string]bool{
+	"CircuitBreaker":      true,
+	"PluginTimeout":       true,
+	"InitTimeout":         true,
+	"MaxConcurrentCalls":  true,
+	"ConcurrencyMode":     true,
+	"AdaptiveConcurrency": true,
+	"SlowCallThreshold":   true,
+	"SlowCallLogRate":     true,
+	"AccessLog":           true,
+	"AccessLogArgs":       true,
+	"MetricLabels":        true,
+	"Options":             true,
+}
+
+// currentConfig returns the active configuration. It is replaced whole when settings
+// change at runtime, so callers must not modify it.
+func (m *Manager) currentConfig() *Config {
+	return m.config.Load()
+}
+
+// NewManagerFromFile creates a manager from the configuration file at path, read with
+// LoadConfigFromFile, and watches the file as WatchConfig does
+func NewManagerFromFile(ctx context.Context, path string, opts ...ManagerOption) (*Manager, error) {
+	config, err := LoadConfigFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := NewManager(ctx, config, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.WatchConfig(path); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// WatchConfig applies the configuration file at path, read with LoadConfigFromFile, and
+// again whenever it changes, until the manager is closed. Only the settings that can
+// change safely at runtime are applied: the log level, the allow and block lists, the
+// file stability, free, fetch and GC timeouts, the readiness settings and, per plugin,
+// the circuit breaker, PluginTimeout, InitTimeout and concurrency limit. Changes to
+// other settings, such as PluginDir, are logged and ignored. An entry added to
+// PluginConfigs is taken whole, its other settings applying from the plugin's next load,
+// and the runtime settings of a removed one go back to DefaultPluginConfig. A file that
+// cannot be read or is invalid is logged and leaves the running configuration as it is.
+//
+// Every reload that applies changes emits an EventConfigReloaded listing them. The
+// manager watches one file at most.
+func (m *Manager) WatchConfig(path string) error {
+	if m.ctx.Err() != nil {
+		return ErrManagerClosed
+	}
+	path = filepath.Clean(path)
+	if !m.configWatched.CompareAndSwap(false, true) {
+		return errors.New("a config file is already watched")
+	}
+	if err := m.reloadConfigFile(path, ActorHost); err != nil {
+		m.configWatched.Store(false)
+		return err
+	}
+
+	// Editors and deploy tools replace the file rather than write it, so the directory
+	// is watched
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		m.configWatched.Store(false)
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		m.configWatched.Store(false)
+		return fmt.Errorf("failed to watch config file: %w", err)
+	}
+	m.eg.Go(func() error {
+		return m.watchConfig(path, watcher)
+	})
+	return nil
+}
+
+// watchConfig reloads the config file at path on every change the watcher reports
+func (m *Manager) watchConfig(path string, watcher *fsnotify.Watcher) error {
+	defer watcher.Close()
+	defer func() {
+		if r := recover(); r != nil {
+			m.logger.Error("Panic in watchConfig", "error", r)
+		}
+	}()
+
+	reloads := newDebouncer(m.currentConfig().ReloadDebounce, func(path string) {
+		if err := m.reloadConfigFile(path, ActorWatcher); err != nil {
+			m.logger.Error("Failed to reload config, keeping the running one", "path", path, "error", err)
+		}
+	})
+	defer reloads.stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) == path && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				reloads.trigger(path)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			m.logger.Error("Config watcher error", "path", path, "error", err)
+		case <-m.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// reloadConfigFile reads the config file at path and applies its runtime settings
+func (m *Manager) reloadConfigFile(path, actor string) error {
+	config, err := LoadConfigFromFile(path)
+	if err != nil {
+		return err
+	}
+	changes, err := m.applyConfig(config)
+	if err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	if len(changes) > 0 {
+		m.logger.Info("Config reloaded", "path", path, "changes", changes)
+		m.emit(Event{Type: EventConfigReloaded, Path: path, Changes: changes, actor: actor})
+	}
+	return nil
+}
+
+// applyConfig replaces the active configuration with one taking the runtime settings
+// from config, applies them to the loaded plugins and returns the settings that changed
+func (m *Manager) applyConfig(config *Config) ([]string, error) {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+
+	current := m.currentConfig()
+	next := current.Clone()
+	var changes []string
+
+	cur, src, dst := reflect.ValueOf(current).Elem(), reflect.ValueOf(config).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		name := dst.Type().Field(i).Name
+		switch name {
+		case "DefaultPluginConfig":
+			changes = append(changes, m.mergeHotPluginConfig(name, &next.DefaultPluginConfig, config.DefaultPluginConfig)...)
+		case "PluginConfigs":
+			for _, plugin := range slices.Sorted(maps.Keys(mergedKeys(current.PluginConfigs, config.PluginConfigs))) {
+				key := "PluginConfigs." + plugin
+				entry, existed := next.PluginConfigs[plugin]
+				src, kept := config.PluginConfigs[plugin]
+				switch {
+				case !existed:
+					// Nothing has read a new entry yet, so it is taken whole
+					next.PluginConfigs[plugin] = clonePluginSpecificConfig(src)
+					changes = append(changes, key)
+					m.logDeferredPluginSettings(plugin, src)
+				case !kept:
+					// A removed entry goes back to the defaults; the settings that cannot change
+					// at runtime stay until restart
+					changes = append(changes, m.mergeHotPluginConfig(key, &entry, hotPluginSettings(next.DefaultPluginConfig))...)
+					next.PluginConfigs[plugin] = entry
+				default:
+					changes = append(changes, m.mergeHotPluginConfig(key, &entry, src)...)
+					next.PluginConfigs[plugin] = entry
+				}
+			}
+		default:
+			if sameConfigValue(cur.Field(i), src.Field(i)) {
+				continue
+			}
+			if !hotConfigFields[name] {
+				m.logger.Warn("Ignoring config change that cannot be applied at runtime", "setting", name)
+				continue
+			}
+			dst.Field(i).Set(src.Field(i))
+			changes = append(changes, name)
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := ValidateConfig(next); err != nil {
+		return nil, err
+	}
+
+	m.config.Store(next)
+	if next.LogLevel != current.LogLevel {
+		m.SetLogLevel(next.LogLevel)
+	}
+	m.plugins.Range(func(key, _ interface{}) bool {
+		m.applyPluginConfig(key.(string), current.GetPluginConfig(key.(string)), next.GetPluginConfig(key.(string)))
+		return true
+	})
+	return changes, nil
+}
+
+// mergeHotPluginConfig copies the runtime settings of src into dst, logging changes to
+// the others, and returns the settings that changed, prefixed with key
+func (m *Manager) mergeHotPluginConfig(key string, dst *PluginSpecificConfig, src PluginSpecificConfig) []string {
+	var changes []string
+	to, from := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src)
+	for i := 0; i < to.NumField(); i++ {
+		name := to.Type().Field(i).Name
+		if name == "CircuitBreaker" {
+			// IsFailure cannot be set from a file, so the configured one is kept
+			src.CircuitBreaker.IsFailure = dst.CircuitBreaker.IsFailure
+			if !sameBreakerConfig(dst.CircuitBreaker, src.CircuitBreaker) {
+				dst.CircuitBreaker = src.CircuitBreaker
+				changes = append(changes, key+"."+name)
+			}
+			continue
+		}
+		if sameConfigValue(to.Field(i), from.Field(i)) {
+			continue
+		}
+		if !hotPluginConfigFields[name] {
+			m.logger.Warn("Ignoring config change that cannot be applied at runtime", "setting", key+"."+name)
+			continue
+		}
+		to.Field(i).Set(from.Field(i))
+		changes = append(changes, key+"."+name)
+	}
+	return changes
+}
+
+// hotPluginSettings returns a config holding only the runtime settings of config
+func hotPluginSettings(config PluginSpecificConfig) PluginSpecificConfig {
+	var hot PluginSpecificConfig
+	from, to := reflect.ValueOf(config), reflect.ValueOf(&hot).Elem()
+	for i := 0; i < to.NumField(); i++ {
+		if hotPluginConfigFields[to.Type().Field(i).Name] {
+			to.Field(i).Set(from.Field(i))
+		}
+	}
+	return hot
+}
+
+// logDeferredPluginSettings logs the settings of a new config entry that a loaded plugin
+// only reads when it is next loaded
+func (m *Manager) logDeferredPluginSettings(plugin string, config PluginSpecificConfig) {
+	if _, loaded := m.plugins.Load(plugin); !loaded {
+		return
+	}
+	var deferred []string
+	from := reflect.ValueOf(config)
+	for i := 0; i < from.NumField(); i++ {
+		name, field := from.Type().Field(i).Name, from.Field(i)
+		if !hotPluginConfigFields[name] && !sameConfigValue(field, reflect.Zero(field.Type())) {
+			deferred = append(deferred, "PluginConfigs."+plugin+"."+name)
+		}
+	}
+	if len(deferred) > 0 {
+		m.pluginLogger(plugin).Info("Config settings apply from the plugin's next reload", "settings", deferred)
+	}
+}
+
+// UpdatePluginConfig replaces the entry of a plugin in Config.PluginConfigs, merged over
+// DefaultPluginConfig as usual, and applies it to the plugin if it is loaded: the circuit
+// breaker as UpdateBreakerConfig does, the concurrency limit, the slow-call and access
+// logs and the metric labels change at once, changed Options are passed to a Configurable
+// plugin, and PluginTimeout bounds its next load. InitArgs, the backend and symbol name
+// options and the other settings read when the plugin is loaded take effect when it is
+// next reloaded. A plugin that is not loaded uses the entry when it is.
+func (m *Manager) UpdatePluginConfig(pluginName string, config PluginSpecificConfig) error {
+	if err := validatePluginSpecificConfig(config); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+	current := m.currentConfig()
+	next := current.Clone()
+	next.PluginConfigs[pluginName] = clonePluginSpecificConfig(config)
+	m.config.Store(next)
+
+	if _, loaded := m.plugins.Load(pluginName); loaded {
+		m.applyPluginConfig(pluginName, current.GetPluginConfig(pluginName), next.GetPluginConfig(pluginName))
+	}
+	m.pluginLogger(pluginName).Info("Plugin config updated")
+	return nil
+}
+
+// applyPluginConfig applies the runtime settings that changed from old to config to a
+// loaded plugin
+func (m *Manager) applyPluginConfig(name string, old, config PluginSpecificConfig) {
+	if !sameBreakerConfig(old.CircuitBreaker, config.CircuitBreaker) {
+		if err := m.UpdateBreakerConfig(name, config.CircuitBreaker); err != nil && !errors.As(err, new(ErrPluginNotFound)) {
+			m.pluginLogger(name).Error("Failed to apply circuit breaker config", "error", err)
+		}
+	}
+	if old.MaxConcurrentCalls != config.MaxConcurrentCalls || old.ConcurrencyMode != config.ConcurrencyMode ||
+		old.AdaptiveConcurrency != config.AdaptiveConcurrency {
+		m.configureLimiter(name, &config)
+	}
+	if old.SlowCallThreshold != config.SlowCallThreshold || old.SlowCallLogRate != config.SlowCallLogRate {
+		m.configureSlowCalls(name, &config)
+	}
+	if old.AccessLog != config.AccessLog || old.AccessLogArgs != config.AccessLogArgs {
+		m.configureAccessLog(name, &config)
+	}
+	m.configureMetricLabels(name, &config)
+	if !sameConfigValue(reflect.ValueOf(old.Options), reflect.ValueOf(config.Options)) {
+		m.configureOptions(name, &config)
+	}
+}
+
+// configureOptions passes the options of a plugin to its loaded instances
+func (m *Manager) configureOptions(name string, config *PluginSpecificConfig) {
+	var plugins []*Plugin
+	if set := m.replicaSetFor(name); set != nil {
+		for _, r := range set.snapshot() {
+			plugins = append(plugins, r.instance.Plugin)
+		}
+	} else if plugin := m.currentPlugin(name); plugin != nil {
+		plugins = append(plugins, plugin)
+	}
+	for _, plugin := range plugins {
+		if err := plugin.Configure(config.Options); err != nil {
+			m.pluginLogger(name).Error("Failed to apply plugin options", "error", err)
+		}
+	}
+}
+
+// sameBreakerConfig reports whether two breaker configs have the same settings;
+// IsFailure functions cannot be compared and are left out
+func sameBreakerConfig(a, b CircuitBreakerConfig) bool {
+	a.IsFailure, b.IsFailure = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// sameConfigValue reports whether two config values are equal, counting nil and empty
+// slices and maps as equal, as Clone and config files do not tell them apart
+func sameConfigValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Slice, reflect.Map:
+		if a.Len() == 0 && b.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// mergedKeys returns the set of names in a or b
+func mergedKeys(a, b map[string]PluginSpecificConfig) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for name := range a {
+		keys[name] = true
+	}
+	for name := range b {
+		keys[name] = true
+	}
+	return keys
+}
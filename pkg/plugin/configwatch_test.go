@@ -0,0 +1,378 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeWatchedConfig writes a config file for the plugins in pluginDir, with top added to
+// its top level and payments to the config of the payments plugin
+func writeWatchedConfig(t *testing.T, path, pluginDir, top, payments string) {
+	t.Helper()
+	content := fmt.Sprintf(`PluginDir: %s
+AllowHotReload: false
+ReloadDebounce: 10ms
+%s
+DefaultPluginConfig:
+  CircuitBreaker:
+    MaxFailures: 5
+PluginConfigs:
+  payments:
+%s
+`, pluginDir, top, payments)
+	// Replace the file as deploy tools do, so the watcher never reads it half-written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewManagerFromFile_WatchConfig(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning("ok")}),
+	})
+	dir := t.TempDir()
+	pluginDir := filepath.Join(dir, "plugins")
+	if err := os.Mkdir(pluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, "payments.so"), []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "chameleon.yaml")
+	writeWatchedConfig(t, path, pluginDir, "", "    MaxConcurrentCalls: 10")
+
+	logger := &testLogger{}
+	m, err := NewManagerFromFile(context.Background(), path, WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if info, err := m.GetConcurrencyInfo("payments"); err != nil || info.Limit != 10 {
+		t.Fatalf("GetConcurrencyInfo() = %+v, %v; want limit 10", info, err)
+	}
+	if err := m.WatchConfig(path); err == nil {
+		t.Error("WatchConfig() of a second file succeeded")
+	}
+
+	events, unsubscribe := m.Subscribe(16)
+	defer unsubscribe()
+	writeWatchedConfig(t, path, filepath.Join(dir, "elsewhere"), "LogLevel: debug\nBlockedPlugins: [orders]\nFreeTimeout: 2s",
+		"    MaxConcurrentCalls: 3\n    InitArgs: [eu]\n    CircuitBreaker:\n      Enabled: true\n      MaxFailures: 2\n      OpenDuration: 5s\n"+
+			"    SlowCallThreshold: 2s\n    AccessLog: true\n    MetricLabels:\n      team: billing\n    Options:\n      region: eu")
+
+	var reloaded Event
+	timeout := time.After(5 * time.Second)
+	for reloaded.Type != EventConfigReloaded {
+		select {
+		case reloaded = <-events:
+		case <-timeout:
+			t.Fatal("Timed out waiting for EventConfigReloaded")
+		}
+	}
+	want := []string{"BlockedPlugins", "FreeTimeout", "LogLevel", "PluginConfigs.payments.CircuitBreaker",
+		"PluginConfigs.payments.MaxConcurrentCalls", "PluginConfigs.payments.SlowCallThreshold",
+		"PluginConfigs.payments.AccessLog", "PluginConfigs.payments.MetricLabels", "PluginConfigs.payments.Options"}
+	if reloaded.Path != path || !reflect.DeepEqual(reloaded.Changes, want) {
+		t.Errorf("EventConfigReloaded = %+v, want changes %v", reloaded, want)
+	}
+
+	config := m.currentConfig()
+	if config.LogLevel != LogLevelDebug || config.FreeTimeout != 2*time.Second || config.IsPluginAllowed("orders") {
+		t.Errorf("LogLevel, FreeTimeout = %v, %v and orders allowed %v; want the reloaded settings",
+			config.LogLevel, config.FreeTimeout, config.IsPluginAllowed("orders"))
+	}
+	if info, _ := m.GetConcurrencyInfo("payments"); info.Limit != 3 {
+		t.Errorf("concurrency limit = %d, want 3", info.Limit)
+	}
+	if breaker, _ := m.breakers.Load("payments"); breaker.(*CircuitBreaker).config().MaxFailures != 2 {
+		t.Errorf("breaker MaxFailures = %d, want 2", breaker.(*CircuitBreaker).config().MaxFailures)
+	}
+	if labels := m.MetricLabels("payments"); labels["team"] != "billing" {
+		t.Errorf("MetricLabels() = %v, want team billing", labels)
+	}
+	if got := config.GetPluginConfig("payments"); got.SlowCallThreshold != 2*time.Second || !got.AccessLog ||
+		got.Options["region"] != "eu" {
+		t.Errorf("payments config = %+v, want the reloaded slow-call, access log and options settings", got)
+	}
+
+	// Settings that cannot change live are logged and kept
+	if config.PluginDir != pluginDir || len(config.PluginConfigs["payments"].InitArgs) != 0 {
+		t.Errorf("PluginDir, InitArgs = %q, %v; want them unchanged", config.PluginDir, config.PluginConfigs["payments"].InitArgs)
+	}
+	var ignored []interface{}
+	for _, fields := range logger.fields("WARN: Ignoring config change that cannot be applied at runtime") {
+		ignored = append(ignored, fields["setting"])
+	}
+	if !reflect.DeepEqual(ignored, []interface{}{"PluginDir", "PluginConfigs.payments.InitArgs"}) {
+		t.Errorf("ignored settings = %v, want PluginDir and PluginConfigs.payments.InitArgs", ignored)
+	}
+
+	// A broken file leaves the running config alone
+	if err := os.WriteFile(path, []byte("DefaultPluginConfig: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the failed reload", func() bool {
+		return logger.has("ERROR: Failed to reload config, keeping the running one")
+	})
+	if m.currentConfig() != config {
+		t.Error("A broken config file replaced the running config")
+	}
+	if _, err := m.Call(context.Background(), "payments", "Pay"); err != nil {
+		t.Errorf("Call() after a broken config file error = %v", err)
+	}
+}
+
+func TestManager_ApplyConfig(t *testing.T) {
+	logger := &testLogger{}
+	m := newSlowCallManager(t, PluginSpecificConfig{}, logger)
+	isFailure := func(err error) bool { return !errors.Is(err, context.Canceled) }
+	config := m.currentConfig().Clone()
+	config.DefaultPluginConfig.CircuitBreaker.IsFailure = isFailure
+	config.AllowedPlugins = nil
+	m.config.Store(config)
+
+	// An equal config, with empty lists for nil ones, changes nothing
+	same := config.Clone()
+	same.AllowedPlugins = []string{}
+	same.DefaultPluginConfig.CircuitBreaker.IsFailure = nil
+	if changes, err := m.applyConfig(same); err != nil || changes != nil {
+		t.Errorf("applyConfig() of the same config = %v, %v; want no changes", changes, err)
+	}
+	if m.currentConfig() != config {
+		t.Error("applyConfig() of the same config replaced it")
+	}
+
+	// Breaker settings from a file keep the configured IsFailure
+	next := config.Clone()
+	next.DefaultPluginConfig.CircuitBreaker.IsFailure = nil
+	next.DefaultPluginConfig.CircuitBreaker.MaxFailures = 1
+	changes, err := m.applyConfig(next)
+	if err != nil || !reflect.DeepEqual(changes, []string{"DefaultPluginConfig.CircuitBreaker"}) {
+		t.Fatalf("applyConfig() = %v, %v; want the breaker change", changes, err)
+	}
+	breaker, _ := m.breakers.Load("payments")
+	if cfg := breaker.(*CircuitBreaker).config(); cfg.MaxFailures != 1 || cfg.IsFailure == nil {
+		t.Errorf("breaker config = %+v, want MaxFailures 1 and the configured IsFailure", cfg)
+	}
+
+	// Invalid combinations are refused
+	invalid := m.currentConfig().Clone()
+	invalid.FreeTimeout = -time.Second
+	if _, err := m.applyConfig(invalid); err == nil {
+		t.Error("applyConfig() of a negative FreeTimeout succeeded")
+	}
+	if m.currentConfig().FreeTimeout < 0 {
+		t.Error("applyConfig() stored an invalid config")
+	}
+}
+
+func TestManager_ApplyConfig_AddAndRemoveEntries(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning("ok")}),
+	})
+	logger := &testLogger{}
+	m := newTestManager(t, nil, WithLogger(logger))
+	loadTestPlugin(t, m, "payments", "v1")
+	defaults := m.currentConfig().DefaultPluginConfig
+
+	// A new entry is taken whole; what the plugin reads at load waits for its next one
+	added := m.currentConfig().Clone()
+	added.PluginConfigs["payments"] = PluginSpecificConfig{
+		MaxConcurrentCalls: 3,
+		PluginTimeout:      time.Second,
+		CircuitBreaker:     CircuitBreakerConfig{Enabled: true, MaxFailures: 1, OpenDuration: time.Second},
+		InitArgs:           []interface{}{"eu"},
+	}
+	changes, err := m.applyConfig(added)
+	if err != nil || !reflect.DeepEqual(changes, []string{"PluginConfigs.payments"}) {
+		t.Fatalf("applyConfig() = %v, %v; want the new entry", changes, err)
+	}
+	if info, _ := m.GetConcurrencyInfo("payments"); info.Limit != 3 {
+		t.Errorf("concurrency limit = %d, want 3", info.Limit)
+	}
+	if got := m.currentConfig().GetPluginConfig("payments"); !reflect.DeepEqual(got.InitArgs, []interface{}{"eu"}) {
+		t.Errorf("InitArgs = %v, want the new entry's", got.InitArgs)
+	}
+	deferred := logger.fields("INFO: Config settings apply from the plugin's next reload")
+	if len(deferred) != 1 || !reflect.DeepEqual(deferred[0]["settings"], []string{"PluginConfigs.payments.InitArgs"}) {
+		t.Errorf("deferred settings logged = %v, want PluginConfigs.payments.InitArgs", deferred)
+	}
+
+	// A removed entry goes back to the defaults, not to zero values
+	removed := m.currentConfig().Clone()
+	delete(removed.PluginConfigs, "payments")
+	if _, err := m.applyConfig(removed); err != nil {
+		t.Fatal(err)
+	}
+	if entry := m.currentConfig().PluginConfigs["payments"]; entry.PluginTimeout != defaults.PluginTimeout ||
+		!sameBreakerConfig(entry.CircuitBreaker, defaults.CircuitBreaker) {
+		t.Errorf("payments entry after removal = %+v, want the default runtime settings", entry)
+	}
+	got := m.currentConfig().GetPluginConfig("payments")
+	if got.PluginTimeout != defaults.PluginTimeout || got.MaxConcurrentCalls != defaults.MaxConcurrentCalls ||
+		!sameBreakerConfig(got.CircuitBreaker, defaults.CircuitBreaker) {
+		t.Errorf("payments config after removal = %+v, want the defaults", got)
+	}
+	if info, _ := m.GetConcurrencyInfo("payments"); info.Limit != defaults.MaxConcurrentCalls {
+		t.Errorf("concurrency limit = %d, want %d", info.Limit, defaults.MaxConcurrentCalls)
+	}
+	if breaker, _ := m.breakers.Load("payments"); breaker.(*CircuitBreaker).config().MaxFailures != defaults.CircuitBreaker.MaxFailures {
+		t.Errorf("breaker MaxFailures = %d, want %d", breaker.(*CircuitBreaker).config().MaxFailures, defaults.CircuitBreaker.MaxFailures)
+	}
+	// InitArgs cannot change at runtime, so they stay until restart
+	if !reflect.DeepEqual(got.InitArgs, []interface{}{"eu"}) || !logger.has("WARN: Ignoring config change that cannot be applied at runtime") {
+		t.Errorf("InitArgs = %v, want them kept and the change logged as ignored", got.InitArgs)
+	}
+}
+
+func TestManager_UpdatePluginConfig(t *testing.T) {
+	logger := &testLogger{}
+	m := newSlowCallManager(t, PluginSpecificConfig{MaxConcurrentCalls: 10}, logger)
+	ctx := context.Background()
+
+	// Calls go on while the config changes
+	done := make(chan struct{})
+	defer func() { <-done }()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				m.Call(ctx, "payments", "Fast")
+			}
+		}
+	}()
+
+	err := m.UpdatePluginConfig("payments", PluginSpecificConfig{
+		InitArgs:           []interface{}{"eu"},
+		MaxConcurrentCalls: 2,
+		CircuitBreaker:     CircuitBreakerConfig{Enabled: true, MaxFailures: 1, OpenDuration: time.Second},
+		SlowCallThreshold:  time.Millisecond,
+		AccessLog:          true,
+		Options:            map[string]interface{}{"region": "eu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := m.GetConcurrencyInfo("payments"); info.Limit != 2 {
+		t.Errorf("concurrency limit = %d, want 2", info.Limit)
+	}
+	if breaker, _ := m.breakers.Load("payments"); breaker.(*CircuitBreaker).config().MaxFailures != 1 {
+		t.Errorf("breaker MaxFailures = %d, want 1", breaker.(*CircuitBreaker).config().MaxFailures)
+	}
+	if _, err := m.Call(ctx, "payments", "Slow"); err != nil {
+		t.Fatal(err)
+	}
+	if !logger.has("WARN: Slow plugin call") || !logger.has("INFO: Plugin call") {
+		t.Error("The slow-call and access logs were not turned on")
+	}
+	// InitArgs wait for the next load, but the config holds them
+	if got := m.currentConfig().GetPluginConfig("payments"); !reflect.DeepEqual(got.InitArgs, []interface{}{"eu"}) ||
+		got.Options["region"] != "eu" {
+		t.Errorf("payments config = %+v, want the updated InitArgs and Options", got)
+	}
+
+	// An invalid config changes nothing
+	config := m.currentConfig()
+	if err := m.UpdatePluginConfig("payments", PluginSpecificConfig{MaxConcurrentCalls: -1}); err == nil {
+		t.Error("UpdatePluginConfig() with a negative MaxConcurrentCalls succeeded")
+	}
+	if m.currentConfig() != config {
+		t.Error("An invalid config replaced the running one")
+	}
+
+	// Plugins that are not loaded just get the entry
+	if err := m.UpdatePluginConfig("orders", PluginSpecificConfig{PluginTimeout: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.currentConfig().GetPluginConfig("orders"); got.PluginTimeout != time.Second {
+		t.Errorf("orders PluginTimeout = %v, want 1s", got.PluginTimeout)
+	}
+}
+
+// configurableBureau records the options it was configured with
+type configurableBureau struct {
+	fakeBureau
+	configureErr error
+	options      []map[string]interface{}
+}
+
+func (b *configurableBureau) Configure(options map[string]interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.options = append(b.options, options)
+	return b.configureErr
+}
+
+func (b *configurableBureau) configured() []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.options
+}
+
+func TestManager_UpdatePluginConfig_Options(t *testing.T) {
+	bureau := &configurableBureau{fakeBureau: fakeBureau{name: "payments", version: "1.0.0"}}
+	useFakeOpener(t, map[string]fakeLib{"payments": newFakeLib(bureau, map[string]InvokeFunc{})})
+	logger := &testLogger{}
+	m := newTestManager(t, func(config *Config) {
+		config.PluginConfigs = map[string]PluginSpecificConfig{
+			"payments": {Options: map[string]interface{}{"region": "us"}},
+		}
+	}, WithLogger(logger))
+	loadTestPlugin(t, m, "payments", "payments")
+
+	// The options follow Init on load
+	if got := bureau.configured(); len(got) != 1 || got[0]["region"] != "us" {
+		t.Fatalf("Configure() calls after load = %v, want one with region us", got)
+	}
+
+	if err := m.UpdatePluginConfig("payments", PluginSpecificConfig{
+		Options: map[string]interface{}{"region": "eu"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := bureau.configured(); len(got) != 2 || got[1]["region"] != "eu" {
+		t.Errorf("Configure() calls after update = %v, want a second one with region eu", got)
+	}
+
+	// Unchanged options are not passed again
+	if err := m.UpdatePluginConfig("payments", PluginSpecificConfig{
+		MaxConcurrentCalls: 5,
+		Options:            map[string]interface{}{"region": "eu"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := bureau.configured(); len(got) != 2 {
+		t.Errorf("Configure() called %d times, want 2", len(got))
+	}
+
+	// A plugin rejecting its options keeps running and the failure is logged
+	bureau.mu.Lock()
+	bureau.configureErr = errors.New("unknown region")
+	bureau.mu.Unlock()
+	if err := m.UpdatePluginConfig("payments", PluginSpecificConfig{
+		Options: map[string]interface{}{"region": "mars"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !logger.has("ERROR: Failed to apply plugin options") {
+		t.Error("The rejected options were not logged")
+	}
+	if m.currentPlugin("payments") == nil {
+		t.Error("The plugin was unloaded after rejecting its options")
+	}
+}
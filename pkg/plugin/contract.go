@@ -0,0 +1,157 @@
+package plugin
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// contract is a host interface a plugin's functions must satisfy
+type contract struct {
+	name    string
+	methods []contractMethod
+}
+
+// contractMethod is one interface method, described like a plugin function
+type contractMethod struct {
+	name string
+	sig  FuncSignature
+}
+
+// RegisterContract requires the named plugin to satisfy a host interface, passed as a nil
+// pointer to it, e.g. (*PaymentProvider)(nil). Every interface method must take a
+// context.Context first and is matched against a plugin function of the same name: by
+// argument count and types where the plugin describes its signatures (generated wrappers
+// and reflected plugins do), and by name otherwise.
+//
+// Loads and upgrades that violate the contract are rejected. A plugin that is already
+// loaded is checked immediately; if it violates the contract, the contract is not
+// registered and the violation is returned.
+func (m *Manager) RegisterContract(pluginName string, iface interface{}) error {
+	c, err := newContract(iface)
+	if err != nil {
+		return err
+	}
+	if p := m.currentPlugin(pluginName); p != nil {
+		if err := c.check(pluginName, p); err != nil {
+			return err
+		}
+	}
+	m.contracts.Store(pluginName, c)
+	return nil
+}
+
+// checkContract verifies a plugin against the contract registered for its name, if any
+func (m *Manager) checkContract(pluginName string, plugin *Plugin) error {
+	val, ok := m.contracts.Load(pluginName)
+	if !ok {
+		return nil
+	}
+	return val.(*contract).check(pluginName, plugin)
+}
+
+// newContract describes the interface behind iface
+func newContract(iface interface{}) (*contract, error) {
+	t, ok := iface.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(iface)
+		if t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	if t == nil || t.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("contract must be a pointer to an interface type, got %T", iface)
+	}
+
+	c := &contract{name: t.String()}
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+		if method.Type.NumIn() == 0 || method.Type.In(0) != contextType {
+			return nil, fmt.Errorf("contract %s: method %s must take a context.Context first", c.name, method.Name)
+		}
+		c.methods = append(c.methods, contractMethod{name: method.Name, sig: reflectSignature(method.Type)})
+	}
+	return c, nil
+}
+
+// check lists every way the plugin's functions differ from the contract
+func (c *contract) check(pluginName string, plugin *Plugin) error {
+	funcs := make(map[string]bool)
+	for _, name := range plugin.GetFunctions() {
+		funcs[name] = true
+	}
+
+	var violations []string
+	for _, method := range c.methods {
+		if !funcs[method.name] {
+			violations = append(violations, fmt.Sprintf("%s: not exported", method.name))
+			continue
+		}
+		sig, ok := plugin.Signature(method.name)
+		if !ok {
+			continue
+		}
+		violations = append(violations, compareSignatures(method.name, method.sig, sig)...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return ErrContractViolation{Name: pluginName, Contract: c.name, Violations: violations}
+}
+
+// compareSignatures describes how a plugin function signature differs from the expected one
+func compareSignatures(name string, want, got FuncSignature) []string {
+	var violations []string
+	if len(got.Params) != len(want.Params) || got.Variadic != want.Variadic {
+		violations = append(violations, fmt.Sprintf("%s: takes %s, contract expects %s",
+			name, describeParams(got), describeParams(want)))
+	} else {
+		for i := range want.Params {
+			if !sameTypeName(got.Params[i], want.Params[i]) {
+				violations = append(violations, fmt.Sprintf("%s: argument %d is %s, contract expects %s",
+					name, i, got.Params[i], want.Params[i]))
+			}
+		}
+	}
+	if len(got.Results) != len(want.Results) {
+		violations = append(violations, fmt.Sprintf("%s: returns (%s), contract expects (%s)",
+			name, strings.Join(got.Results, ", "), strings.Join(want.Results, ", ")))
+	} else {
+		for i := range want.Results {
+			if !sameTypeName(got.Results[i], want.Results[i]) {
+				violations = append(violations, fmt.Sprintf("%s: result %d is %s, contract expects %s",
+					name, i, got.Results[i], want.Results[i]))
+			}
+		}
+	}
+	return violations
+}
+
+// describeParams formats a parameter count for violation messages
+func describeParams(sig FuncSignature) string {
+	desc := fmt.Sprintf("%d arguments", len(sig.Params))
+	if sig.Variadic {
+		desc += " (variadic)"
+	}
+	return desc
+}
+
+// Patterns rewritten when comparing type names
+var (
+	packageQualifier = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*\.`)
+	anyAlias         = regexp.MustCompile(`\bany\b`)
+)
+
+// sameTypeName compares type names written in source and by reflection. Package
+// qualifiers are ignored because the plugin's source refers to its own types unqualified.
+func sameTypeName(a, b string) bool {
+	return normalizeTypeName(a) == normalizeTypeName(b)
+}
+
+// normalizeTypeName reduces a type name to a canonical spelling for comparison
+func normalizeTypeName(name string) string {
+	name = strings.Join(strings.Fields(name), "")
+	name = packageQualifier.ReplaceAllString(name, "")
+	return anyAlias.ReplaceAllString(name, "interface{}")
+}
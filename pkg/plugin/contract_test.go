@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type paymentProvider interface {
+	Charge(ctx context.Context, amount int) (string, error)
+	Refund(ctx context.Context, id string) error
+}
+
+// paymentsLib builds a fake generated plugin exporting the given signatures
+func paymentsLib(b Bureau, sigs map[string]FuncSignature) fakeLib {
+	funcs := make(map[string]InvokeFunc)
+	for name := range sigs {
+		funcs[name] = returning(name)
+	}
+	lib := newFakeLib(b, funcs)
+	lib[signaturesSymbol] = &sigs
+	return lib
+}
+
+func TestRegisterContract(t *testing.T) {
+	v1 := &fakeBureau{name: "payments", version: "1.0.0"}
+	v2 := &fakeBureau{name: "payments", version: "2.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": paymentsLib(v1, map[string]FuncSignature{
+			"Charge": {Params: []string{"int"}, Results: []string{"string", "error"}},
+			"Refund": {Params: []string{"string"}, Results: []string{"error"}},
+		}),
+		"v2": paymentsLib(v2, map[string]FuncSignature{
+			"Charge": {Params: []string{"string"}, Results: []string{"string", "error"}},
+		}),
+	})
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	if err := m.RegisterContract("payments", (*paymentProvider)(nil)); err != nil {
+		t.Fatalf("RegisterContract() error = %v", err)
+	}
+
+	path := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatalf("LoadPlugin(v1) error = %v", err)
+	}
+
+	// v2 changed Charge and dropped Refund, so it must not replace v1
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := m.LoadPlugin(path)
+	var violation ErrContractViolation
+	if !errors.As(err, &violation) {
+		t.Fatalf("LoadPlugin(v2) error = %v, want ErrContractViolation", err)
+	}
+	want := []string{
+		"Charge: argument 0 is string, contract expects int",
+		"Refund: not exported",
+	}
+	if !reflect.DeepEqual(violation.Violations, want) {
+		t.Errorf("Violations = %q, want %q", violation.Violations, want)
+	}
+	if !strings.Contains(err.Error(), "\n  - Refund: not exported") {
+		t.Errorf("Expected one violation per line, got %q", err.Error())
+	}
+	if plugins := m.ListPlugins(); len(plugins) != 1 || plugins[0].Version != "1.0.0" {
+		t.Errorf("Expected v1 to remain active, got %+v", plugins)
+	}
+
+	// A contract the loaded plugin already violates is refused
+	type refunder interface {
+		Refund(ctx context.Context, id string, reason string) error
+	}
+	if err := m.RegisterContract("payments", (*refunder)(nil)); !errors.As(err, &violation) {
+		t.Errorf("RegisterContract(refunder) error = %v, want ErrContractViolation", err)
+	}
+	if err := m.RegisterContract("payments", paymentProvider(nil)); err == nil {
+		t.Error("Expected an error for a contract that is not an interface pointer")
+	}
+}
+
+func TestRegisterContract_ReflectedPlugin(t *testing.T) {
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	b := Bureau(&reflectBureau{fakeBureau{name: "reflected", version: "1.0.0"}})
+	p, err := m.loader.validateAndCreatePlugin(fakeLib{"Export": &b}, DefaultExportSymbol, DefaultFunctionsSymbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type calculator interface {
+		Add(ctx context.Context, a, b int) (int, error)
+		Scale(ctx context.Context, f float32) float64
+	}
+	c, err := newContract((*calculator)(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.check("reflected", p)
+	var violation ErrContractViolation
+	if !errors.As(err, &violation) || len(violation.Violations) != 1 ||
+		violation.Violations[0] != "Scale: argument 0 is float64, contract expects float32" {
+		t.Errorf("check() error = %v, want a Scale argument mismatch", err)
+	}
+}
+
+func TestNormalizeTypeName(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"map[string]interface {}", "map[string]interface{}", true},
+		{"[]any", "[]interface{}", true},
+		{"*main.Order", "*Order", true},
+		{"Company", "Company", true},
+		{"int", "int64", false},
+	}
+	for _, tt := range tests {
+		if got := sameTypeName(tt.a, tt.b); got != tt.same {
+			t.Errorf("sameTypeName(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package plugin
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// currentLinks maps the absolute path of every configured CurrentLink to its plugin name
+func (c *Config) currentLinks() map[string]string {
+	links := make(map[string]string)
+	for name, pc := range c.PluginConfigs {
+		if pc.CurrentLink == "" {
+			continue
+		}
+		link := pc.CurrentLink
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(c.PluginDir, link)
+		}
+		links[filepath.Clean(link)] = name
+	}
+	return links
+}
+
+// linkManaged reports whether a directory entry belongs to a plugin deployed through a
+// current link: the link itself, or the directory holding it and its releases. The
+// plugin directory itself is never treated as managed.
+func (m *Manager) linkManaged(path string, isDir bool) (string, bool) {
+	path = filepath.Clean(path)
+	for link, name := range m.currentLinks {
+		if path == link {
+			return name, true
+		}
+		linkDir := filepath.Dir(link)
+		if isDir && path == linkDir && linkDir != filepath.Clean(m.currentConfig().PluginDir) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// loadCurrentLink loads the release a current link points to. Retargeting the link is
+// a reload of the same plugin, so moving it back to an older release is a downgrade.
+func (m *Manager) loadCurrentLink(name, link, actor string) error {
+	target, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		return fmt.Errorf("failed to resolve current link %s of plugin %s: %w", link, name, err)
+	}
+	if val, ok := m.plugins.Load(name); ok && val.(*PluginInstance).path == target {
+		return nil
+	}
+	m.pluginLogger(name).Info("Loading release from current link", "link", link,
+		"release", strings.TrimPrefix(target, filepath.Dir(link)+string(filepath.Separator)))
+	return m.loadPlugin(target, nil, loadOptions{sameLineage: true, actor: actor})
+}
@@ -0,0 +1,110 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCurrentLink_CutoverAndRollback(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning("v1")}),
+		"v2": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, map[string]InvokeFunc{"Pay": returning("v2")}),
+	})
+
+	pluginDir := t.TempDir()
+	releases := filepath.Join(pluginDir, "payments", "releases")
+	for version, content := range map[string]string{"1.0.0": "v1", "2.0.0": "v2"} {
+		dir := filepath.Join(releases, version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "payments.so"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(pluginDir, "payments", "current")
+	retarget := func(version string) {
+		t.Helper()
+		tmp := link + ".tmp"
+		if err := os.Symlink(filepath.Join("releases", version, "payments.so"), tmp); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	retarget("2.0.0")
+
+	config := DefaultConfig()
+	config.PluginDir = pluginDir
+	config.ReloadDebounce = 0
+	config.PluginConfigs["payments"] = PluginSpecificConfig{CurrentLink: "payments/current", AllowDowngrade: true}
+
+	// The releases are not loaded as competing artifacts of the same name
+	m, report, err := NewManagerWithReport(context.Background(), config)
+	if err != nil {
+		t.Fatalf("NewManagerWithReport() error = %v", err)
+	}
+	defer m.Close()
+	if len(report.Skipped) != 1 || report.Skipped[0].Path != filepath.Join(pluginDir, "payments") {
+		t.Errorf("Skipped = %+v, want the link-managed payments directory", report.Skipped)
+	}
+	versionIs := func(want string) func() bool {
+		return func() bool {
+			for _, info := range m.ListPlugins() {
+				if info.Name == "payments" {
+					return info.Version == want && info.State == StateActive
+				}
+			}
+			return false
+		}
+	}
+	if !versionIs("2.0.0")() {
+		t.Fatalf("Expected the release behind the link to load, got %+v", m.ListPlugins())
+	}
+
+	// Rolling the link back is a downgrade that AllowDowngrade permits
+	waitFor(t, "watch to start", m.HotReloadHealthy)
+	retarget("1.0.0")
+	waitFor(t, "rollback to 1.0.0", versionIs("1.0.0"))
+	if result, err := m.Call(context.Background(), "payments", "Pay"); err != nil || result != "v1" {
+		t.Errorf("Call() = %v, %v, want v1", result, err)
+	}
+}
+
+func TestCurrentLink_DowngradeRequiresAllowDowngrade(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, nil),
+		"v2": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, nil),
+	})
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	for version, content := range map[string]string{"1.0.0": "v1", "2.0.0": "v2"} {
+		if err := os.WriteFile(filepath.Join(dir, version+".so"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(dir, "current")
+	if err := os.Symlink(filepath.Join(dir, "2.0.0.so"), link); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.loadCurrentLink("payments", link, ActorHost); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Remove(link)
+	if err := os.Symlink(filepath.Join(dir, "1.0.0.so"), link); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.loadCurrentLink("payments", link, ActorHost); err != nil {
+		t.Fatal(err)
+	}
+	if infos := m.ListPlugins(); len(infos) != 1 || infos[0].Version != "2.0.0" {
+		t.Errorf("Expected 2.0.0 to stay active without AllowDowngrade, got %+v", infos)
+	}
+}
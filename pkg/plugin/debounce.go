@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"sync"
+	"time"
+)
+
+// debouncer coalesces bursts of events per key into a single call once the key
+// has been quiet for the window
+type debouncer struct {
+	mu      sync.Mutex
+	window  time.Duration
+	timers  map[string]*time.Timer
+	fn      func(key string)
+	stopped bool
+}
+
+func newDebouncer(window time.Duration, fn func(key string)) *debouncer {
+	return &debouncer{
+		window: window,
+		timers: make(map[string]*time.Timer),
+		fn:     fn,
+	}
+}
+
+// trigger schedules fn for key after the window, restarting the window if one is pending.
+// A zero window calls fn immediately.
+func (d *debouncer) trigger(key string) {
+	if d.window <= 0 {
+		d.fn(key)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	if t, ok := d.timers[key]; ok {
+		t.Reset(d.window)
+		return
+	}
+	d.timers[key] = time.AfterFunc(d.window, func() {
+		d.mu.Lock()
+		delete(d.timers, key)
+		stopped := d.stopped
+		d.mu.Unlock()
+		if !stopped {
+			d.fn(key)
+		}
+	})
+}
+
+// stop cancels pending calls and ignores further triggers
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	for key, t := range d.timers {
+		t.Stop()
+		delete(d.timers, key)
+	}
+}
@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Test that atomic rename deploys trigger exactly one reload of the final file
+func TestHandleWatchEvent_AtomicRenameDeploy(t *testing.T) {
+	sequences := map[string][]fsnotify.Op{
+		// inotify: the temp file moves away and the final name is created
+		"linux": {fsnotify.Create, fsnotify.Write, fsnotify.Rename, fsnotify.Create},
+		// kqueue: the replaced file reports a rename before the directory rescan creates it
+		"darwin": {fsnotify.Create, fsnotify.Write, fsnotify.Rename, fsnotify.Rename, fsnotify.Create},
+	}
+	// The path each event in a sequence lands on: temp file events come first
+	targets := map[string][]bool{
+		"linux":  {true, true, true, false},
+		"darwin": {true, true, true, false, false},
+	}
+
+	for platform, ops := range sequences {
+		t.Run(platform, func(t *testing.T) {
+			var mu sync.Mutex
+			opened := map[string]int{}
+			lib := newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, nil)
+			orig := openPlugin
+			openPlugin = func(path string) (symbolLookup, error) {
+				mu.Lock()
+				opened[filepath.Base(path)]++
+				mu.Unlock()
+				return lib, nil
+			}
+			t.Cleanup(func() { openPlugin = orig })
+
+			m, cleanup := setupTestManager(t, func(config *Config) { config.PluginFilePatterns = []string{"*"} })
+			defer cleanup()
+			patterns, err := compileFilePatterns(m.currentConfig().PluginFilePatterns, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.patterns = patterns
+
+			final := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+			tmp := final + ".tmp"
+			if err := os.WriteFile(final, []byte("payments"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			reloads := newDebouncer(50*time.Millisecond, m.handleNewPlugin)
+			defer reloads.stop()
+			for i, op := range ops {
+				name := final
+				if targets[platform][i] {
+					name = tmp
+				}
+				m.handleWatchEvent(fsnotify.Event{Name: name, Op: op}, reloads)
+			}
+
+			waitFor(t, "reload", func() bool {
+				_, ok := m.GetPluginPath("payments")
+				return ok
+			})
+			time.Sleep(100 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if opened["payments.so"] != 1 || opened["payments.so.tmp"] != 0 {
+				t.Errorf("opened = %v, want one load of payments.so only", opened)
+			}
+		})
+	}
+}
+
+func TestIsTempFile(t *testing.T) {
+	for path, want := range map[string]bool{
+		"plugins/payments.so":         false,
+		"plugins/payments.so.tmp":     true,
+		"plugins/payments.so.partial": true,
+		"plugins/.payments.so":        true,
+	} {
+		if got := isTempFile(path); got != want {
+			t.Errorf("isTempFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// differentPackageVersion matches the runtime's "plugin was built with a different version
+// of package X" error, which a plugin built by another Go version also gets for a package
+// of the toolchain
+var differentPackageVersion = regexp.MustCompile(`plugin was built with a different version of package ([^\s:"]+)`)
+
+// classifyOpenError turns known plugin.Open failures into an ErrBuildMismatch with an
+// actionable hint, and wraps everything else unchanged
+func classifyOpenError(path string, err error) error {
+	msg := err.Error()
+
+	if match := differentPackageVersion.FindStringSubmatch(msg); match != nil {
+		pkg := match[1]
+		hint := fmt.Sprintf("align the version of %s in the go.mod files of host and plugin and rebuild both", pkg)
+		if isToolchainPackage(pkg) {
+			hint = fmt.Sprintf("package %s belongs to the Go toolchain: rebuild the plugin with the same Go version "+
+				"as the host and check that GOFLAGS (-trimpath, -race, build tags) match", pkg)
+		}
+		return ErrBuildMismatch{Path: path, Detail: msg, Hint: hint}
+	}
+
+	// Only the runtime's own messages: others, such as an error of the file system, may
+	// contain the same words
+	switch {
+	case strings.Contains(msg, "plugin already loaded"):
+		return ErrBuildMismatch{Path: path, Detail: msg,
+			Hint: "a plugin with the same package path is already loaded; give each plugin a unique module path"}
+	case strings.Contains(msg, "not built with -buildmode=plugin"):
+		return ErrBuildMismatch{Path: path, Detail: msg,
+			Hint: "build the artifact with 'chameleon build' or 'go build -buildmode=plugin'"}
+	}
+
+	return fmt.Errorf("failed to open plugin: %w", err)
+}
+
+// isToolchainPackage reports whether a package belongs to the standard library, whose
+// import paths never contain a dot in the first element
+func isToolchainPackage(pkg string) bool {
+	first, _, _ := strings.Cut(pkg, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyOpenError(t *testing.T) {
+	tests := []struct {
+		msg      string
+		mismatch bool
+		hint     string
+	}{
+		{
+			msg:      `plugin.Open("p"): plugin was built with a different version of package github.com/acme/shared`,
+			mismatch: true,
+			hint:     "align the version of github.com/acme/shared",
+		},
+		{
+			msg:      `plugin.Open("p"): plugin was built with a different version of package internal/goarch`,
+			mismatch: true,
+			hint:     "same Go version",
+		},
+		{
+			msg:      `plugin.Open("p"): plugin was built with a different version of package runtime/internal/sys`,
+			mismatch: true,
+			hint:     "GOFLAGS",
+		},
+		{
+			msg:      `plugin.Open("p"): not built with -buildmode=plugin`,
+			mismatch: true,
+			hint:     "-buildmode=plugin",
+		},
+		{
+			msg: `plugin.Open("p"): invalid ELF header`,
+		},
+		// Errors that only share words with the runtime's
+		{
+			msg: `plugin.Open("/plugins/go version/p.so"): realpath failed`,
+		},
+		{
+			msg: `plugin.Open("p"): p.so: file does not contain a valid ELF header`,
+		},
+		{
+			msg: `fetch p: a different version of package github.com/acme/shared is pinned`,
+		},
+	}
+
+	for _, tt := range tests {
+		err := classifyOpenError("p", errors.New(tt.msg))
+		var mismatch ErrBuildMismatch
+		if errors.As(err, &mismatch) != tt.mismatch {
+			t.Errorf("classifyOpenError(%q) = %v, want mismatch %v", tt.msg, err, tt.mismatch)
+			continue
+		}
+		if tt.mismatch && !strings.Contains(mismatch.Hint, tt.hint) {
+			t.Errorf("hint %q does not contain %q", mismatch.Hint, tt.hint)
+		}
+	}
+}
+
+func TestLoadPlugin_BuildMismatchEvent(t *testing.T) {
+	orig := openPlugin
+	openPlugin = func(path string) (symbolLookup, error) {
+		return nil, errors.New(`plugin.Open("x"): plugin was built with a different version of package golang.org/x/sync`)
+	}
+	t.Cleanup(func() { openPlugin = orig })
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+	events, unsubscribe := m.Subscribe(1)
+	defer unsubscribe()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+	if err := os.WriteFile(path, []byte("so"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err == nil {
+		t.Fatal("Expected load to fail")
+	}
+
+	e := <-events
+	if e.Type != EventLoadFailed || e.Reason != ReasonBuildMismatch {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+}
@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultPluginFilePatterns are the globs used when Config.PluginFilePatterns is empty
+var DefaultPluginFilePatterns = []string{"*.so"}
+
+// namePlaceholder marks the part of a file pattern that holds the plugin name
+const namePlaceholder = "{name}"
+
+// filePatterns matches plugin artifact filenames against the configured globs
+type filePatterns struct {
+	include []fileMatcher
+	exclude []fileMatcher
+}
+
+// fileMatcher is a single compiled pattern. Patterns containing a separator
+// match the path relative to the plugin directory, all others the base name.
+type fileMatcher struct {
+	re       *regexp.Regexp
+	fullPath bool
+}
+
+func (fm fileMatcher) subject(rel, base string) string {
+	if fm.fullPath {
+		return rel
+	}
+	return base
+}
+
+// compileFilePatterns compiles include and exclude globs into matchers
+func compileFilePatterns(include, exclude []string) (*filePatterns, error) {
+	if len(include) == 0 {
+		include = DefaultPluginFilePatterns
+	}
+
+	fp := &filePatterns{}
+	for _, pattern := range include {
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plugin file pattern %q: %w", pattern, err)
+		}
+		fp.include = append(fp.include, fileMatcher{re: re, fullPath: strings.Contains(pattern, "/")})
+	}
+	for _, pattern := range exclude {
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		fp.exclude = append(fp.exclude, fileMatcher{re: re, fullPath: strings.Contains(pattern, "/")})
+	}
+	return fp, nil
+}
+
+// Match reports whether the path (relative to the plugin directory) is a plugin
+// artifact and returns the plugin name derived from it
+func (fp *filePatterns) Match(rel string) (string, bool) {
+	rel = filepath.ToSlash(rel)
+	base := rel[strings.LastIndex(rel, "/")+1:]
+	if strings.HasPrefix(base, downloadPrefix) {
+		return "", false
+	}
+
+	for _, fm := range fp.exclude {
+		if fm.re.MatchString(fm.subject(rel, base)) {
+			return "", false
+		}
+	}
+
+	for _, fm := range fp.include {
+		match := fm.re.FindStringSubmatch(fm.subject(rel, base))
+		if match == nil {
+			continue
+		}
+		if idx := fm.re.SubexpIndex("name"); idx > 0 && match[idx] != "" {
+			return match[idx], true
+		}
+		return getPluginNameFromPath(base), true
+	}
+	return "", false
+}
+
+// globToRegexp converts a doublestar-style glob into an anchored regular expression.
+// "**" matches across directories, "*" and "?" stay within a path segment,
+// "[...]" is a character class and "{name}" captures the plugin name.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	hasName := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], namePlaceholder):
+			if hasName {
+				return nil, fmt.Errorf("%s may only appear once", namePlaceholder)
+			}
+			hasName = true
+			sb.WriteString(`(?P<name>[^/]+?)`)
+			i += len(namePlaceholder) - 1
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString(`(?:.*/)?`)
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(`.*`)
+			i++
+		case c == '*':
+			sb.WriteString(`[^/]*`)
+		case c == '?':
+			sb.WriteString(`[^/]`)
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// TempFilePatterns match files that deploy tools write before renaming them into
+// place; the watcher never treats them as plugins
+var TempFilePatterns = []string{"*.tmp", "*.partial", ".*"}
+
+// isTempFile reports whether the base name of path matches TempFilePatterns
+func isTempFile(path string) bool {
+	base := filepath.Base(path)
+	for _, pattern := range TempFilePatterns {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilePatterns_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		path     string
+		wantName string
+		wantOK   bool
+	}{
+		{
+			name:     "default pattern",
+			path:     "example-plugin.so",
+			wantName: "example-plugin",
+			wantOK:   true,
+		},
+		{
+			name:   "default pattern ignores backups",
+			path:   "example-plugin.so.bak",
+			wantOK: false,
+		},
+		{
+			name:     "versioned filename with name capture",
+			include:  []string{"{name}-*.linux-amd64.plugin"},
+			path:     "payments-2.3.1.linux-amd64.plugin",
+			wantName: "payments",
+			wantOK:   true,
+		},
+		{
+			name:     "unversioned filename alongside versioned pattern",
+			include:  []string{"{name}-*.plugin", "*.plugin"},
+			path:     "payments.plugin",
+			wantName: "payments",
+			wantOK:   true,
+		},
+		{
+			name:    "excluded file",
+			include: []string{"*.so"},
+			exclude: []string{"*-debug.so"},
+			path:    "payments-debug.so",
+			wantOK:  false,
+		},
+		{
+			name:     "doublestar matches nested paths",
+			include:  []string{"**/{name}.so"},
+			path:     "team/payments/payments.so",
+			wantName: "payments",
+			wantOK:   true,
+		},
+		{
+			name:     "doublestar matches top level",
+			include:  []string{"**/*.so"},
+			path:     "payments.so",
+			wantName: "payments",
+			wantOK:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp, err := compileFilePatterns(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name, ok := fp.Match(tt.path)
+			if ok != tt.wantOK {
+				t.Fatalf("Match(%q) ok = %v, want %v", tt.path, ok, tt.wantOK)
+			}
+			if name != tt.wantName {
+				t.Errorf("Match(%q) name = %q, want %q", tt.path, name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestCompileFilePatterns_Invalid(t *testing.T) {
+	for _, pattern := range []string{"", "[abc", "{name}-{name}.so"} {
+		if _, err := compileFilePatterns([]string{pattern}, nil); err == nil {
+			t.Errorf("compileFilePatterns(%q) expected error", pattern)
+		}
+	}
+}
+
+func TestLoadPluginsFromDir_Symlinks(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"a": newFakeLib(&fakeBureau{name: "a", version: "1.0.0"}, nil),
+		"b": newFakeLib(&fakeBureau{name: "b", version: "1.0.0"}, nil),
+		"c": newFakeLib(&fakeBureau{name: "c", version: "1.0.0"}, nil),
+	})
+
+	base := t.TempDir()
+	pluginDir := filepath.Join(base, "plugins")
+	external := filepath.Join(base, "external")
+	teamDir := filepath.Join(external, "team")
+	for _, dir := range []string{pluginDir, teamDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(pluginDir, "a.so"): "a",
+		filepath.Join(external, "b.so"):  "b",
+		filepath.Join(teamDir, "c.so"):   "c",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		filepath.Join(pluginDir, "b.so"):      filepath.Join(external, "b.so"),
+		filepath.Join(pluginDir, "broken.so"): filepath.Join(base, "missing.so"),
+		filepath.Join(pluginDir, "team"):      teamDir,
+		filepath.Join(teamDir, "loop"):        teamDir,
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, follow := range []bool{false, true} {
+		t.Run(fmt.Sprintf("follow=%v", follow), func(t *testing.T) {
+			m := newTestManager(t, func(config *Config) {
+				config.PluginDir = pluginDir
+				config.FollowSymlinkDirs = follow
+			})
+
+			loaded := map[string]string{}
+			for _, info := range m.ListPlugins() {
+				loaded[info.Name] = info.Path
+			}
+			if loaded["b"] != filepath.Join(external, "b.so") {
+				t.Errorf("Symlinked file should load from its target, got path %q", loaded["b"])
+			}
+			if _, ok := loaded["c"]; ok != follow {
+				t.Errorf("Plugin behind directory symlink loaded = %v, want %v", ok, follow)
+			}
+
+			reasons := map[string]string{}
+			for _, entry := range m.LoadReport().Skipped {
+				reasons[entry.Path] = entry.Reason
+			}
+			if !strings.HasPrefix(reasons[filepath.Join(pluginDir, "broken.so")], "broken symlink") {
+				t.Errorf("Expected broken symlink to be reported, got %v", reasons)
+			}
+			if follow {
+				if !strings.HasPrefix(reasons[filepath.Join(pluginDir, "team", "loop")], "symlink cycle") {
+					t.Errorf("Expected symlink cycle to be reported, got %v", reasons)
+				}
+			} else if reasons[filepath.Join(pluginDir, "team")] != "directory symlink not followed" {
+				t.Errorf("Expected unfollowed directory symlink to be reported, got %v", reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+// Package plugin loads, hot-reloads and calls plugins through a Manager. A plugin is a
+// Bureau with a map of functions, built as a Go plugin and opened in-process, run as a
+// child process, or served by another Backend.
+//
+// # Process plugin protocol
+//
+// A process plugin is an executable that calls ServeProcess. The protocol is JSON-RPC
+// 1.0 as implemented by net/rpc/jsonrpc, not gRPC: it needs nothing beyond the standard
+// library on either side, and 'chameleon build --backend process' has no protobuf code
+// to generate.
+//
+//   - The host starts the executable with CHAMELEON_PLUGIN=process-v1 in its environment;
+//     without it ServeProcess exits, so the plugin cannot be run by hand by mistake.
+//   - Requests go to the child's stdin and replies come back on its stdout, one JSON
+//     object each. The child's stderr, and anything it prints to os.Stdout, is logged by
+//     the host line by line.
+//   - The methods are Plugin.Describe, Plugin.Init, Plugin.Configure, Plugin.Call and
+//     Plugin.Free. Describe is the handshake: the child replies with its API version,
+//     name, version, functions, their signatures and its Metadata, and is killed if it
+//     does not answer within 10 seconds. The host then checks it like a native plugin.
+//   - A Call carries the function name, its arguments as JSON, the caller's deadline and
+//     call ID. Results travel as JSON and are decoded into the types of the function's
+//     signature. Errors come back as text, flagged when the deadline passed or the
+//     arguments were refused. Cancellation without a deadline does not reach the child.
+//   - On unload the host calls Free and closes the pipes, which lets the child exit. It
+//     kills the child if it is still running a second later.
+//
+// A version of the protocol that is not compatible changes the environment value.
+package plugin
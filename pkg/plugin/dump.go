@@ -0,0 +1,310 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redacted replaces secrets in a StateDump
+const redacted = "[redacted]"
+
+// StateDump is a point-in-time snapshot of a manager for debugging and support bundles.
+// It is a plain value that marshals to JSON; secrets in the configuration are redacted.
+type StateDump struct {
+	Time   time.Time `json:"time"`
+	Closed bool      `json:"closed"`
+	// HotReload reports whether hot reload is enabled and the plugin directory is watched
+	HotReload  DumpHotReload  `json:"hot_reload"`
+	Health     ProbeResult    `json:"health"`
+	Readiness  ProbeResult    `json:"readiness"`
+	Plugins    []DumpPlugin   `json:"plugins"`
+	Deprecated []DumpInstance `json:"deprecated"`
+	Cache      CacheStats     `json:"cache"`
+	// LoadReport is the most recent directory scan, nil if none ran
+	LoadReport *DumpLoadReport `json:"load_report"`
+	Config     *Config         `json:"config"`
+}
+
+// DumpHotReload describes the plugin directory watch
+type DumpHotReload struct {
+	Enabled bool `json:"enabled"`
+	Healthy bool `json:"healthy"`
+}
+
+// DumpPlugin describes a registered plugin with the instances serving it
+type DumpPlugin struct {
+	Name      string   `json:"name"`
+	Functions []string `json:"functions"`
+	// Instances holds the registered instance, followed by further replicas if any
+	Instances   []DumpInstance                `json:"instances"`
+	Concurrency AdminConcurrency              `json:"concurrency"`
+	Metrics     map[string]AdminMethodMetrics `json:"metrics"`
+}
+
+// DumpInstance describes one loaded instance of a plugin
+type DumpInstance struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	State      string `json:"state"`
+	Restarting bool   `json:"restarting,omitempty"`
+	RefCount   int32  `json:"ref_count"`
+	Path       string `json:"path"`
+	Hash       string `json:"hash,omitempty"`
+	ShadowPath string `json:"shadow_path,omitempty"`
+	SourceURL  string `json:"source_url,omitempty"`
+	// Size, OpenNs, InitNs, LoadedAt and PreviousLoadAt are those of PluginInfo
+	Size           int64      `json:"size,omitempty"`
+	OpenNs         int64      `json:"open_ns,omitempty"`
+	InitNs         int64      `json:"init_ns"`
+	LoadedAt       time.Time  `json:"loaded_at"`
+	PreviousLoadAt *time.Time `json:"previous_load_at,omitempty"`
+	// DeprecatedAt is when a newer instance replaced this one
+	DeprecatedAt *time.Time   `json:"deprecated_at,omitempty"`
+	Breaker      *DumpBreaker `json:"breaker,omitempty"`
+}
+
+// DumpBreaker describes a circuit breaker and its statistics
+type DumpBreaker struct {
+	Enabled             bool       `json:"enabled"`
+	State               string     `json:"state"`
+	Trips               int64      `json:"trips"`
+	Rejected            int64      `json:"rejected"`
+	Failures            int32      `json:"failures"`
+	Timeouts            int32      `json:"timeouts"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastTransition      time.Time  `json:"last_transition"`
+	// TrippedAt is when the breaker opened; nil while it is closed
+	TrippedAt *time.Time `json:"tripped_at,omitempty"`
+	// NextProbeNs is the time until an open breaker admits a trial call
+	NextProbeNs int64 `json:"next_probe_ns,omitempty"`
+	// Reason is the reason given to TripBreaker, until the breaker closes
+	Reason string `json:"reason,omitempty"`
+	// Methods holds the breakers of the functions called so far under BreakerScopeMethod
+	Methods map[string]*DumpBreaker `json:"methods,omitempty"`
+}
+
+// DumpLoadReport is a LoadReport with its errors as strings
+type DumpLoadReport struct {
+	Loaded   []string          `json:"loaded"`
+	Restored []string          `json:"restored,omitempty"`
+	Failed   []DumpLoadFailure `json:"failed"`
+	Skipped  []SkippedEntry    `json:"skipped"`
+}
+
+// DumpLoadFailure is a LoadFailure with its error as a string
+type DumpLoadFailure struct {
+	Path  string `json:"path"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// DumpRedactor returns what a StateDump shows for an option of a plugin's configuration.
+// plugin is empty for Config.DefaultPluginConfig.
+type DumpRedactor func(plugin, key string, value interface{}) interface{}
+
+// WithDumpRedactor replaces the redaction of plugin options in DumpState. By default the
+// values of options whose name contains "token", "secret", "password", "credential" or
+// "key" are redacted.
+func WithDumpRedactor(redact DumpRedactor) ManagerOption {
+	return func(m *Manager) {
+		if redact != nil {
+			m.dumpRedactor = redact
+		}
+	}
+}
+
+// redactSecretOptions is the default DumpRedactor
+func redactSecretOptions(plugin, key string, value interface{}) interface{} {
+	lower := strings.ToLower(key)
+	for _, secret := range []string{"token", "secret", "password", "credential", "key"} {
+		if strings.Contains(lower, secret) {
+			return redacted
+		}
+	}
+	return value
+}
+
+// DumpState captures the manager's plugins, breakers, metrics, health and configuration.
+// It only reads state and takes no lock for longer than a single field read, so it is
+// safe to call while plugins are serving calls.
+func (m *Manager) DumpState() StateDump {
+	dump := StateDump{
+		Time:   time.Now(),
+		Closed: m.ctx.Err() != nil,
+		HotReload: DumpHotReload{
+			Enabled: m.currentConfig().AllowHotReload,
+			Healthy: m.HotReloadHealthy(),
+		},
+		Health:     m.Healthz(),
+		Readiness:  m.Readyz(),
+		Plugins:    []DumpPlugin{},
+		Deprecated: []DumpInstance{},
+		Cache:      m.CacheStats(),
+		Config:     m.redactedConfig(),
+	}
+
+	m.plugins.Range(func(key, value interface{}) bool {
+		name, instance := key.(string), value.(*PluginInstance)
+		functions := instance.GetFunctions()
+		sort.Strings(functions)
+		plugin := DumpPlugin{Name: name, Functions: functions, Metrics: m.adminMetrics(name)}
+		if c, err := m.GetConcurrencyInfo(name); err == nil {
+			plugin.Concurrency = adminConcurrency(c)
+		}
+		if set := m.replicaSetFor(name); set != nil {
+			for _, r := range set.snapshot() {
+				plugin.Instances = append(plugin.Instances, dumpInstance(name, r.instance, r.breaker))
+			}
+		} else {
+			var breaker *CircuitBreaker
+			if val, ok := m.breakers.Load(name); ok {
+				breaker = val.(*CircuitBreaker)
+			}
+			plugin.Instances = []DumpInstance{dumpInstance(name, instance, breaker)}
+		}
+		dump.Plugins = append(dump.Plugins, plugin)
+		return true
+	})
+	sort.Slice(dump.Plugins, func(i, j int) bool { return dump.Plugins[i].Name < dump.Plugins[j].Name })
+
+	m.deprecated.Range(func(key, value interface{}) bool {
+		dump.Deprecated = append(dump.Deprecated, dumpInstance(value.(string), key.(*PluginInstance), nil))
+		return true
+	})
+	sort.Slice(dump.Deprecated, func(i, j int) bool {
+		a, b := dump.Deprecated[i], dump.Deprecated[j]
+		return a.Name < b.Name || a.Name == b.Name && a.Version < b.Version
+	})
+
+	if report := m.LoadReport(); report != nil {
+		dump.LoadReport = &DumpLoadReport{
+			Loaded:   report.Loaded,
+			Restored: report.Restored,
+			Failed:   make([]DumpLoadFailure, 0, len(report.Failed)),
+			Skipped:  report.Skipped,
+		}
+		for _, f := range report.Failed {
+			dump.LoadReport.Failed = append(dump.LoadReport.Failed, DumpLoadFailure{Path: f.Path, Name: f.Name, Error: f.Err.Error()})
+		}
+	}
+	return dump
+}
+
+// WriteStateDump writes DumpState to w as indented JSON
+func (m *Manager) WriteStateDump(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m.DumpState())
+}
+
+func dumpInstance(name string, instance *PluginInstance, breaker *CircuitBreaker) DumpInstance {
+	d := DumpInstance{
+		Name:       name,
+		Version:    instance.version,
+		State:      instance.State().String(),
+		Restarting: instance.restarting.Load(),
+		RefCount:   instance.GetRefs(),
+		Path:       instance.path,
+		Hash:       instance.hash,
+		ShadowPath: instance.ShadowPath(),
+		SourceURL:  instance.source,
+		Size:       instance.size,
+		OpenNs:     int64(instance.openDuration),
+		InitNs:     int64(instance.initDuration),
+		LoadedAt:   instance.loadedAt,
+	}
+	if !instance.previousLoadAt.IsZero() {
+		d.PreviousLoadAt = &instance.previousLoadAt
+	}
+	if at := instance.DeprecatedAt(); !at.IsZero() {
+		d.DeprecatedAt = &at
+	}
+	if breaker != nil {
+		d.Breaker = dumpBreaker(breaker)
+	}
+	return d
+}
+
+func dumpBreaker(breaker *CircuitBreaker) *DumpBreaker {
+	d := dumpBreakerInfo(breaker.StateSnapshot())
+	if at := breaker.trippedSince(); !at.IsZero() {
+		d.TrippedAt = &at
+	}
+	return d
+}
+
+func dumpBreakerInfo(info BreakerInfo) *DumpBreaker {
+	d := &DumpBreaker{
+		Enabled:             info.Enabled,
+		State:               info.State.String(),
+		Trips:               info.Trips,
+		Rejected:            info.Rejected,
+		Failures:            info.Failures,
+		Timeouts:            info.Timeouts,
+		ConsecutiveFailures: info.ConsecutiveFailures,
+		LastTransition:      info.LastTransition,
+		NextProbeNs:         int64(info.NextProbeIn),
+		Reason:              info.Reason,
+	}
+	if !info.LastFailure.IsZero() {
+		d.LastFailure = &info.LastFailure
+	}
+	for fn, method := range info.Methods {
+		if d.Methods == nil {
+			d.Methods = make(map[string]*DumpBreaker)
+		}
+		d.Methods[fn] = dumpBreakerInfo(method)
+	}
+	return d
+}
+
+// unixNanoTime converts a stored timestamp, returning nil for zero
+func unixNanoTime(ns int64) *time.Time {
+	if ns == 0 {
+		return nil
+	}
+	t := time.Unix(0, ns)
+	return &t
+}
+
+// redactedConfig returns a copy of the configuration that marshals to JSON without
+// secrets: tokens are redacted, options pass through the dump redactor and init
+// arguments are shown by type only
+func (m *Manager) redactedConfig() *Config {
+	config := m.currentConfig().Clone()
+	if config.AdminToken != "" {
+		config.AdminToken = redacted
+	}
+	if config.FetchBearerToken != "" {
+		config.FetchBearerToken = redacted
+	}
+	config.DefaultPluginConfig = m.redactPluginConfig("", config.DefaultPluginConfig)
+	for name, pc := range config.PluginConfigs {
+		config.PluginConfigs[name] = m.redactPluginConfig(name, pc)
+	}
+	return config
+}
+
+func (m *Manager) redactPluginConfig(plugin string, config PluginSpecificConfig) PluginSpecificConfig {
+	for i, arg := range config.InitArgs {
+		config.InitArgs[i] = fmt.Sprintf("%T", arg)
+	}
+	redact := m.dumpRedactor
+	if redact == nil {
+		redact = redactSecretOptions
+	}
+	for key, value := range config.Options {
+		value = redact(plugin, key, value)
+		if _, err := json.Marshal(value); err != nil {
+			// Keep the dump encodable whatever the option holds
+			value = fmt.Sprintf("%v", value)
+		}
+		config.Options[key] = value
+	}
+	return config
+}
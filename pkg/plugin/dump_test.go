@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDumpState(t *testing.T) {
+	m, path := newAdminManager(t, "s3cret")
+	err := m.UpdatePluginConfig("payments", PluginSpecificConfig{
+		InitArgs: []interface{}{make(chan int)},
+		Options:  map[string]interface{}{"region": "eu", "apiKey": "k-123", "hook": func() {}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+
+	dump := m.DumpState()
+	if len(dump.Plugins) != 1 || len(dump.Plugins[0].Instances) != 1 {
+		t.Fatalf("Plugins = %+v, want payments", dump.Plugins)
+	}
+	instance := dump.Plugins[0].Instances[0]
+	if instance.Version != "2.0.0" || instance.Hash == "" || instance.Breaker == nil || instance.Breaker.State != "closed" {
+		t.Errorf("Instance = %+v, want 2.0.0 with a closed breaker", instance)
+	}
+	if len(dump.Deprecated) != 1 || dump.Deprecated[0].Version != "1.0.0" || dump.Deprecated[0].DeprecatedAt == nil {
+		t.Errorf("Deprecated = %+v, want 1.0.0", dump.Deprecated)
+	}
+	if pay := dump.Plugins[0].Metrics["Pay"]; pay.Count != 1 || pay.LastCall == nil {
+		t.Errorf("Metrics = %+v, want one Pay call", dump.Plugins[0].Metrics)
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteStateDump(&buf); err != nil {
+		t.Fatalf("WriteStateDump() error = %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "s3cret") || strings.Contains(out, "k-123") || !strings.Contains(out, `"eu"`) {
+		t.Errorf("Dump does not redact secrets:\n%s", out)
+	}
+	if !json.Valid(buf.Bytes()) {
+		t.Errorf("Dump is not valid JSON:\n%s", buf.String())
+	}
+}
+
+func TestDumpState_Redactor(t *testing.T) {
+	m, _ := newAdminManager(t, "")
+	m.dumpRedactor = func(plugin, key string, value interface{}) interface{} {
+		if plugin == "payments" && key == "region" {
+			return "hidden"
+		}
+		return value
+	}
+	if err := m.UpdatePluginConfig("payments", PluginSpecificConfig{Options: map[string]interface{}{"region": "eu", "apiKey": "k-123"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	options := m.DumpState().Config.PluginConfigs["payments"].Options
+	if options["region"] != "hidden" || options["apiKey"] != "k-123" {
+		t.Errorf("Options = %v, want only region redacted", options)
+	}
+	if m.currentConfig().PluginConfigs["payments"].Options["region"] != "eu" {
+		t.Error("DumpState changed the manager's configuration")
+	}
+}
@@ -1,6 +1,20 @@
 package plugin
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrManagerClosed is returned by operations on a Manager after Close
+var ErrManagerClosed = errors.New("plugin manager is closed")
+
+// ErrAdminTokenNotConfigured is returned by CheckAdminToken when Config.AdminToken is empty
+var ErrAdminTokenNotConfigured = errors.New("admin token is not configured")
+
+// ErrInvalidAdminToken is returned by CheckAdminToken for a token other than Config.AdminToken
+var ErrInvalidAdminToken = errors.New("invalid or missing admin token")
 
 // ErrPluginNotFound represents an error when a plugin cannot be found
 type ErrPluginNotFound struct {
@@ -20,13 +34,250 @@ func (e ErrPluginExists) Error() string {
 	return fmt.Sprintf("plugin already exists: %s", e.Name)
 }
 
-// ErrFuncNotFound represents an error when a function cannot be found
-type ErrFuncNotFound struct {
+// ErrPluginBlocked represents an error when the allow/block lists refuse a plugin
+type ErrPluginBlocked struct {
+	Name string
+}
+
+func (e ErrPluginBlocked) Error() string {
+	return fmt.Sprintf("plugin is blocked by configuration: %s", e.Name)
+}
+
+// ErrNameCollision represents an error when a different artifact already provides the plugin name
+type ErrNameCollision struct {
+	Name         string
+	Path         string
+	ExistingPath string
+}
+
+func (e ErrNameCollision) Error() string {
+	return fmt.Sprintf("plugin %s from %s collides with the plugin already loaded from %s", e.Name, e.Path, e.ExistingPath)
+}
+
+// RequiredPluginGap describes a required plugin that is not available after startup
+type RequiredPluginGap struct {
+	Name       string
+	Constraint string // empty when any version satisfies the requirement
+	Version    string // the loaded version, when it does not satisfy Constraint
+	Err        error  // the load error, when the plugin failed to load
+}
+
+func (g RequiredPluginGap) String() string {
+	switch {
+	case g.Err != nil:
+		return fmt.Sprintf("%s: failed to load: %v", g.Name, g.Err)
+	case g.Version != "":
+		return fmt.Sprintf("%s: version %s does not satisfy %q", g.Name, g.Version, g.Constraint)
+	default:
+		return fmt.Sprintf("%s: not loaded", g.Name)
+	}
+}
+
+// ErrRequiredPluginMissing represents an error when plugins listed in Config.RequiredPlugins
+// are missing, failed to load or have the wrong version
+type ErrRequiredPluginMissing struct {
+	Gaps []RequiredPluginGap
+}
+
+func (e ErrRequiredPluginMissing) Error() string {
+	gaps := make([]string, len(e.Gaps))
+	for i, g := range e.Gaps {
+		gaps[i] = g.String()
+	}
+	return fmt.Sprintf("required plugins unavailable: %s", strings.Join(gaps, "; "))
+}
+
+// Unwrap returns the load errors of the required plugins that failed to load
+func (e ErrRequiredPluginMissing) Unwrap() []error {
+	var errs []error
+	for _, g := range e.Gaps {
+		if g.Err != nil {
+			errs = append(errs, g.Err)
+		}
+	}
+	return errs
+}
+
+// ErrFileUnstable represents an error when a plugin file kept changing for longer than the stability timeout
+type ErrFileUnstable struct {
+	Path    string
+	Timeout time.Duration
+}
+
+func (e ErrFileUnstable) Error() string {
+	return fmt.Sprintf("plugin file %s did not stop changing within %v", e.Path, e.Timeout)
+}
+
+// ErrChecksumMismatch represents an error when a plugin does not match its .sha256 sidecar.
+// Expected is empty when the sidecar is missing.
+type ErrChecksumMismatch struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e ErrChecksumMismatch) Error() string {
+	if e.Expected == "" {
+		return fmt.Sprintf("checksum file missing for plugin: %s", e.Path)
+	}
+	return fmt.Sprintf("checksum mismatch for plugin %s: expected %s, got %s", e.Path, e.Expected, e.Actual)
+}
+
+// ErrPluginRestarting represents an error when a plugin is called while its crashed
+// process is being restarted
+type ErrPluginRestarting struct {
+	Name string
+}
+
+func (e ErrPluginRestarting) Error() string {
+	return fmt.Sprintf("plugin %s is restarting after its process exited", e.Name)
+}
+
+// ErrFetch represents an error when a plugin cannot be downloaded or fails verification
+type ErrFetch struct {
+	URL string
+	Err error
+}
+
+func (e ErrFetch) Error() string {
+	return fmt.Sprintf("failed to fetch plugin from %s: %v", e.URL, e.Err)
+}
+
+func (e ErrFetch) Unwrap() error {
+	return e.Err
+}
+
+// ErrInvalidSignature represents an error when a plugin is unsigned or its signature is not trusted
+type ErrInvalidSignature struct {
+	Path   string
+	Reason string
+}
+
+func (e ErrInvalidSignature) Error() string {
+	return fmt.Sprintf("invalid signature for plugin %s: %s", e.Path, e.Reason)
+}
+
+// ErrVersionConstraint represents an error when a plugin version is outside the configured constraint
+type ErrVersionConstraint struct {
+	Name       string
+	Version    string
+	Constraint string
+}
+
+func (e ErrVersionConstraint) Error() string {
+	return fmt.Sprintf("plugin %s version %s does not satisfy constraint %q", e.Name, e.Version, e.Constraint)
+}
+
+// ErrMissingFunctions represents an error when a plugin does not export functions its
+// configuration requires. Exported lists the functions the plugin does provide.
+type ErrMissingFunctions struct {
+	Name     string
+	Missing  []string
+	Exported []string
+}
+
+func (e ErrMissingFunctions) Error() string {
+	return fmt.Sprintf("plugin %s is missing required functions %s (exports: %s)",
+		e.Name, strings.Join(e.Missing, ", "), strings.Join(e.Exported, ", "))
+}
+
+// ErrContractViolation represents an error when a plugin does not satisfy the interface
+// contract registered for it. Each violation names the function it concerns.
+type ErrContractViolation struct {
+	Name       string
+	Contract   string
+	Violations []string
+}
+
+func (e ErrContractViolation) Error() string {
+	return fmt.Sprintf("plugin %s does not satisfy contract %s:\n  - %s",
+		e.Name, e.Contract, strings.Join(e.Violations, "\n  - "))
+}
+
+// ErrReservedFuncName represents an error when a plugin exports a function under a reserved name
+type ErrReservedFuncName struct {
 	Name string
 }
 
+func (e ErrReservedFuncName) Error() string {
+	return fmt.Sprintf("function name %q is reserved for the plugin lifecycle", e.Name)
+}
+
+// ErrIncompatibleAPIVersion represents an error when a plugin was built against an unsupported API version
+type ErrIncompatibleAPIVersion struct {
+	PluginAPI int
+	HostAPI   int
+}
+
+func (e ErrIncompatibleAPIVersion) Error() string {
+	return fmt.Sprintf("plugin API version %d is not compatible with host API version %d (supported %d-%d); "+
+		"rebuild the plugin with a chameleon version matching the host",
+		e.PluginAPI, e.HostAPI, MinSupportedAPIVersion, e.HostAPI)
+}
+
+// ErrBuildMismatch represents a plugin.Open failure caused by a toolchain or dependency mismatch
+// between host and plugin builds, as opposed to a bug in the plugin itself
+type ErrBuildMismatch struct {
+	Path   string
+	Detail string
+	Hint   string
+}
+
+func (e ErrBuildMismatch) Error() string {
+	return fmt.Sprintf("plugin %s was built incompatibly with the host: %s (hint: %s)", e.Path, e.Detail, e.Hint)
+}
+
+// ErrFuncNotFound represents an error when a function cannot be found.
+// Suggestions lists the plugin's functions with names close to the requested one.
+type ErrFuncNotFound struct {
+	Name        string
+	Plugin      string
+	Suggestions []string
+}
+
 func (e ErrFuncNotFound) Error() string {
-	return fmt.Sprintf("function not found: %s", e.Name)
+	msg := fmt.Sprintf("function not found: %s", e.Name)
+	if e.Plugin != "" {
+		msg += fmt.Sprintf(" (plugin %s)", e.Plugin)
+	}
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %s?", strings.Join(e.Suggestions, ", "))
+	}
+	return msg
+}
+
+// ErrInvalidArgument represents an error when a function is called with the wrong number
+// or types of arguments. Generated and reflected functions return it before running.
+type ErrInvalidArgument struct {
+	Func string
+	Err  error
+}
+
+func (e ErrInvalidArgument) Error() string {
+	return e.Err.Error()
+}
+
+func (e ErrInvalidArgument) Unwrap() error {
+	return e.Err
+}
+
+// CallError wraps an error returned by a plugin function with the call that produced it
+type CallError struct {
+	Plugin   string
+	Version  string
+	Function string
+	CallID   string // see WithCallID
+	Duration time.Duration
+	Err      error
+}
+
+func (e CallError) Error() string {
+	return fmt.Sprintf("plugin %s@%s: %s (call %s) failed after %v: %v", e.Plugin, e.Version, e.Function, e.CallID,
+		e.Duration, e.Err)
+}
+
+func (e CallError) Unwrap() error {
+	return e.Err
 }
 
 // ErrCircuitOpen represents an error when the circuit breaker is open
@@ -38,6 +289,17 @@ func (e ErrCircuitOpen) Error() string {
 	return fmt.Sprintf("circuit breaker is open for plugin: %s", e.Name)
 }
 
+// ErrTooManyConcurrentCalls is returned when a call would exceed the concurrency limit of
+// a plugin, MaxConcurrentCalls or the adaptive limit
+type ErrTooManyConcurrentCalls struct {
+	Name  string
+	Limit int
+}
+
+func (e ErrTooManyConcurrentCalls) Error() string {
+	return fmt.Sprintf("too many concurrent calls to plugin %s (limit %d)", e.Name, e.Limit)
+}
+
 // ErrPluginTimeout represents an error when a plugin operation times out
 type ErrPluginTimeout struct {
 	Name string
@@ -57,6 +319,10 @@ func (e ErrPluginInit) Error() string {
 	return fmt.Sprintf("failed to initialize plugin %s: %v", e.Name, e.Err)
 }
 
+func (e ErrPluginInit) Unwrap() error {
+	return e.Err
+}
+
 // ErrPluginFree represents an error during plugin cleanup
 type ErrPluginFree struct {
 	Name string
@@ -67,35 +333,64 @@ func (e ErrPluginFree) Error() string {
 	return fmt.Sprintf("failed to free plugin %s: %v", e.Name, e.Err)
 }
 
+func (e ErrPluginFree) Unwrap() error {
+	return e.Err
+}
+
+// ErrProcessExited is returned by calls to a process plugin whose process is gone
+type ErrProcessExited struct {
+	Name string
+	Err  error // the transport error or exit status, if known
+}
+
+func (e ErrProcessExited) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("plugin process %s has exited", e.Name)
+	}
+	return fmt.Sprintf("plugin process %s has exited: %v", e.Name, e.Err)
+}
+
+func (e ErrProcessExited) Unwrap() error {
+	return e.Err
+}
+
 // IsCircuitOpenError checks if the error is a circuit breaker open error
+//
+// Deprecated: use errors.As with ErrCircuitOpen.
 func IsCircuitOpenError(err error) bool {
-	_, ok := err.(ErrCircuitOpen)
-	return ok
+	var target ErrCircuitOpen
+	if errors.As(err, &target) {
+		return true
+	}
+	var ptr *ErrCircuitOpen
+	return errors.As(err, &ptr)
 }
 
 // IsPluginNotFoundError checks if the error is a plugin not found error
+//
+// Deprecated: use errors.As with ErrPluginNotFound.
 func IsPluginNotFoundError(err error) bool {
-	_, ok := err.(ErrPluginNotFound)
-	return ok
+	var target ErrPluginNotFound
+	return errors.As(err, &target)
 }
 
 // IsFuncNotFoundError checks if the error is a function not found error
+//
+// Deprecated: use errors.As with ErrFuncNotFound.
 func IsFuncNotFoundError(err error) bool {
-	_, ok := err.(ErrFuncNotFound)
-	return ok
+	var target ErrFuncNotFound
+	return errors.As(err, &target)
 }
 
 // IsPluginTimeoutError checks if the error is a plugin timeout error
+//
+// Deprecated: use errors.As with ErrPluginTimeout.
 func IsPluginTimeoutError(err error) bool {
-	_, ok := err.(ErrPluginTimeout)
-	return ok
-}
-
-// ErrCircuitBreakerOpen represents a circuit breaker open error
-type ErrCircuitBreakerOpen struct {
-	Name string
+	var target ErrPluginTimeout
+	return errors.As(err, &target)
 }
 
-func (e *ErrCircuitBreakerOpen) Error() string {
-	return fmt.Sprintf("circuit breaker is open for plugin: %s", e.Name)
-}
+// ErrCircuitBreakerOpen is the former name of ErrCircuitOpen
+//
+// Deprecated: use ErrCircuitOpen.
+type ErrCircuitBreakerOpen = ErrCircuitOpen
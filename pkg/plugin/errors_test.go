@@ -0,0 +1,222 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var errDatabaseDown = errors.New("database down")
+
+func TestErrors_IsAsThroughManagerCall(t *testing.T) {
+	ctx := context.Background()
+	b := &fakeBureau{name: "orders", version: "1.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"orders": newFakeLib(b, map[string]InvokeFunc{
+			"Fetch": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				return nil, fmt.Errorf("fetch order: %w", fmt.Errorf("query: %w", errDatabaseDown))
+			},
+		}),
+	})
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "orders.so")
+	if err := os.WriteFile(path, []byte("orders"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// The plugin's own cause is reachable through the caller's wrapping
+	_, err := m.Call(ctx, "orders", "Fetch")
+	wrapped := fmt.Errorf("handle request: %w", err)
+	if !errors.Is(wrapped, errDatabaseDown) {
+		t.Errorf("errors.Is(%v, errDatabaseDown) = false", wrapped)
+	}
+
+	_, err = m.Call(ctx, "orders", "Fetc")
+	wrapped = fmt.Errorf("handle request: %w", fmt.Errorf("dispatch: %w", err))
+	var funcErr ErrFuncNotFound
+	if !errors.As(wrapped, &funcErr) || funcErr.Name != "Fetc" {
+		t.Errorf("errors.As(ErrFuncNotFound) failed for %v", wrapped)
+	}
+	if !IsFuncNotFoundError(wrapped) {
+		t.Error("IsFuncNotFoundError should see through wrapping")
+	}
+
+	_, err = m.Call(ctx, "missing", "Fetch")
+	wrapped = fmt.Errorf("handle request: %w", fmt.Errorf("dispatch: %w", err))
+	var notFound ErrPluginNotFound
+	if !errors.As(wrapped, &notFound) || notFound.Name != "missing" {
+		t.Errorf("errors.As(ErrPluginNotFound) failed for %v", wrapped)
+	}
+	if !IsPluginNotFoundError(wrapped) {
+		t.Error("IsPluginNotFoundError should see through wrapping")
+	}
+
+	m.Close()
+	if _, err := m.Call(ctx, "orders", "Fetch"); !errors.Is(err, ErrManagerClosed) {
+		t.Errorf("Call after Close error = %v, want ErrManagerClosed", err)
+	}
+	if err := m.LoadPlugin(path); !errors.Is(err, ErrManagerClosed) {
+		t.Errorf("LoadPlugin after Close error = %v, want ErrManagerClosed", err)
+	}
+}
+
+func TestErrors_UnwrapInitCause(t *testing.T) {
+	b := &fakeBureau{name: "broken", version: "1.0.0", initErr: errDatabaseDown}
+	useFakeOpener(t, map[string]fakeLib{"broken": newFakeLib(b, nil)})
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "broken.so")
+	if err := os.WriteFile(path, []byte("broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := fmt.Errorf("startup: %w", m.LoadPlugin(path))
+	var initErr ErrPluginInit
+	if !errors.As(err, &initErr) || initErr.Name != "broken" {
+		t.Errorf("errors.As(ErrPluginInit) failed for %v", err)
+	}
+	if !errors.Is(err, errDatabaseDown) {
+		t.Errorf("errors.Is(%v, errDatabaseDown) = false", err)
+	}
+
+	freeErr := ErrPluginFree{Name: "broken", Err: errDatabaseDown}
+	if !errors.Is(fmt.Errorf("close: %w", freeErr), errDatabaseDown) {
+		t.Error("ErrPluginFree should unwrap to its cause")
+	}
+}
+
+func TestErrors_CircuitOpenFromManagerCall(t *testing.T) {
+	ctx := context.Background()
+	b := &fakeBureau{name: "flaky", version: "1.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"flaky": newFakeLib(b, map[string]InvokeFunc{
+			"Do": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				return nil, errDatabaseDown
+			},
+		}),
+	})
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "flaky.so")
+	if err := os.WriteFile(path, []byte("flaky"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	for i := 0; i < m.currentConfig().DefaultPluginConfig.CircuitBreaker.MaxFailures+1; i++ {
+		_, err = m.Call(ctx, "flaky", "Do")
+	}
+
+	if !IsCircuitOpenError(err) {
+		t.Fatalf("IsCircuitOpenError(%v) = false", err)
+	}
+	var open ErrCircuitOpen
+	if !errors.As(fmt.Errorf("retry: %w", err), &open) || open.Name != "flaky" {
+		t.Errorf("errors.As(ErrCircuitOpen) failed for %v", err)
+	}
+	var legacy ErrCircuitBreakerOpen
+	if !errors.As(err, &legacy) {
+		t.Error("Deprecated ErrCircuitBreakerOpen alias should match")
+	}
+	if !IsCircuitOpenError(&ErrCircuitOpen{Name: "flaky"}) {
+		t.Error("IsCircuitOpenError should accept a pointer")
+	}
+}
+
+func TestErrors_CallErrorWrapsPluginErrors(t *testing.T) {
+	ctx := context.Background()
+	b := &fakeBureau{name: "orders", version: "1.2.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"orders": newFakeLib(b, map[string]InvokeFunc{
+			"Fetch": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				return nil, errDatabaseDown
+			},
+		}),
+	})
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "orders.so")
+	if err := os.WriteFile(path, []byte("orders"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := m.Call(ctx, "orders", "Fetch")
+	var callErr CallError
+	if !errors.As(err, &callErr) {
+		t.Fatalf("Expected CallError, got %T: %v", err, err)
+	}
+	if callErr.Plugin != "orders" || callErr.Version != "1.2.0" || callErr.Function != "Fetch" {
+		t.Errorf("CallError = %+v", callErr)
+	}
+	if !errors.Is(err, errDatabaseDown) {
+		t.Error("CallError should unwrap to the plugin's error")
+	}
+
+	updateTestConfig(m, func(config *Config) { config.RawCallErrors = true })
+	if _, err := m.Call(ctx, "orders", "Fetch"); err != errDatabaseDown {
+		t.Errorf("RawCallErrors: got %v, want the plugin's error unchanged", err)
+	}
+}
+
+func TestErrors_CloseJoinsFreeErrors(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"orders":   newFakeLib(&fakeBureau{name: "orders", version: "1.0.0", freeErr: errors.New("pool busy")}, nil),
+		"billing":  newFakeLib(&fakeBureau{name: "billing", version: "1.0.0", freeErr: errors.New("flush failed")}, nil),
+		"shipping": newFakeLib(&fakeBureau{name: "shipping", version: "1.0.0"}, nil),
+	})
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	for _, name := range []string{"orders", "billing", "shipping"} {
+		path := filepath.Join(m.currentConfig().PluginDir, name+".so")
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.LoadPlugin(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := m.Close()
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Close() error = %v, want a joined error", err)
+	}
+	failed := map[string]bool{}
+	for _, e := range joined.Unwrap() {
+		var freeErr ErrPluginFree
+		if errors.As(e, &freeErr) {
+			failed[freeErr.Name] = true
+		}
+	}
+	if len(failed) != 2 || !failed["orders"] || !failed["billing"] {
+		t.Errorf("Failed frees = %v, want orders and billing", failed)
+	}
+
+	var freeErr ErrPluginFree
+	if !errors.As(err, &freeErr) {
+		t.Error("errors.As(ErrPluginFree) failed on the joined error")
+	}
+}
@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// EventType identifies a plugin lifecycle event
+type EventType string
+
+const (
+	EventLoaded        EventType = "loaded"
+	EventLoadFailed    EventType = "load_failed"
+	EventUpgraded      EventType = "upgraded"
+	EventUpgradeFailed EventType = "upgrade_failed"
+	EventBlocked       EventType = "blocked"
+	EventNameCollision EventType = "name_collision"
+	EventFreed         EventType = "freed"
+	EventRestarted     EventType = "restarted"
+	EventGaveUp        EventType = "gave_up"
+	// EventBreakerReset and EventBreakerTripped report a circuit breaker closed or opened
+	// by ResetBreaker or TripBreaker
+	EventBreakerReset   EventType = "breaker_reset"
+	EventBreakerTripped EventType = "breaker_tripped"
+	// EventBreakerConfigUpdated reports a breaker reconfigured by UpdateBreakerConfig
+	EventBreakerConfigUpdated EventType = "breaker_config_updated"
+	// EventConfigReloaded reports settings applied from the file given to WatchConfig
+	EventConfigReloaded EventType = "config_reloaded"
+)
+
+// Event describes a change in a plugin's lifecycle
+type Event struct {
+	Type    EventType
+	Plugin  string
+	Version string
+	Path    string
+	Time    time.Time
+	Err     error
+	// Reason classifies failures, e.g. ReasonBuildMismatch for toolchain or dependency
+	// mismatches. For breaker events it is the reason given, or ReasonManual.
+	Reason string
+	// ConflictPath is the artifact already registered under the name, for EventNameCollision
+	ConflictPath string
+	// Changes lists the settings applied by EventConfigReloaded, e.g. "LogLevel" or
+	// "PluginConfigs.auth.CircuitBreaker"
+	Changes []string
+
+	// actor and hash are recorded in the audit log
+	actor string
+	hash  string
+}
+
+// Failure reasons attached to LoadFailed events
+const (
+	ReasonBuildMismatch     = "build_mismatch"
+	ReasonUnstableFile      = "unstable_file"
+	ReasonMissingFunctions  = "missing_functions"
+	ReasonContractViolation = "contract_violation"
+	ReasonFetchFailed       = "fetch_failed"
+	// ReasonManual marks breakers reset, or tripped without a reason, through the Manager
+	ReasonManual = "manual"
+)
+
+// failureReason classifies a load error for lifecycle events
+func failureReason(err error) string {
+	var mismatch ErrBuildMismatch
+	if errors.As(err, &mismatch) {
+		return ReasonBuildMismatch
+	}
+	return ""
+}
+
+// eventBus fans lifecycle events out to subscribers without blocking the emitter
+type eventBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]chan Event
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[int]chan Event)}
+}
+
+// subscribe registers a new subscriber and returns its channel and an unsubscribe function
+func (b *eventBus) subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// publish delivers the event to every subscriber, dropping it for subscribers whose buffer is full
+func (b *eventBus) publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving plugin lifecycle events and a function that
+// cancels the subscription. Events are dropped when the channel buffer is full.
+func (m *Manager) Subscribe(buffer int) (<-chan Event, func()) {
+	return m.events.subscribe(buffer)
+}
+
+// emit records a lifecycle event in the audit log, saves the plugin state after changes
+// to the loaded set and publishes the event
+func (m *Manager) emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if record, ok := auditEventFor(e); ok {
+		m.record(record)
+	}
+	switch e.Type {
+	case EventUpgraded:
+		m.upgrades.Add(1)
+		m.metrics.RecordOperation(e.Plugin, OpUpgrade, 0, nil)
+	case EventUpgradeFailed:
+		m.metrics.RecordOperation(e.Plugin, OpUpgrade, 0, e.Err)
+	}
+	if m.state != nil {
+		switch e.Type {
+		case EventLoaded, EventUpgraded, EventRestarted:
+			m.saveState()
+		}
+	}
+	m.events.publish(e)
+}
@@ -0,0 +1,429 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Defaults for downloading plugins with LoadPluginFromURL
+const (
+	DefaultFetchTimeout = 5 * time.Minute
+	DefaultFetchMaxSize = 256 << 20
+)
+
+// downloadPrefix names in-progress downloads in PluginDir; such files are never plugins
+const downloadPrefix = ".chameleon-download-"
+
+// maxSidecarSize bounds downloaded checksum and signature files
+const maxSidecarSize = 64 << 10
+
+// FetchOptions controls how LoadPluginFromURL downloads a plugin
+type FetchOptions struct {
+	// SHA256 is the expected hex digest of the artifact. When empty the digest is read
+	// from ChecksumURL, which defaults to the artifact URL with ".sha256" appended.
+	SHA256      string
+	ChecksumURL string
+	// SignatureURL is downloaded as the artifact's ".sig" sidecar, which
+	// Config.TrustedPublicKeys requires
+	SignatureURL string
+	// FileName is the artifact's name in PluginDir (default: the name the source reports,
+	// for HTTP(S) the last element of the URL path)
+	FileName string
+	// MaxSize and Timeout override Config.FetchMaxSize and Config.FetchTimeout. The timeout
+	// covers every download of the call.
+	MaxSize int64
+	Timeout time.Duration
+	// AllowHTTP accepts plain HTTP URLs. The checksum is still enforced, but the bearer
+	// token is only ever sent over HTTPS.
+	AllowHTTP bool
+	// Config is passed to LoadPluginWithConfig; nil resolves the plugin's configured one
+	Config *PluginSpecificConfig
+}
+
+// ArtifactSource fetches plugin artifacts for LoadPluginFromURL from URLs of a scheme
+// other than HTTP(S), e.g. an object store or an OCI registry
+type ArtifactSource interface {
+	// Fetch opens the artifact ref names. The caller closes the reader.
+	Fetch(ctx context.Context, ref string) (io.ReadCloser, Descriptor, error)
+}
+
+// Descriptor describes an artifact returned by an ArtifactSource. Fields the source
+// cannot tell are left empty.
+type Descriptor struct {
+	// Digest is the artifact's digest as "sha256:<hex>", which the download must match.
+	// Digests of other algorithms are ignored.
+	Digest string
+	// Size is the artifact's size in bytes; zero or negative when unknown
+	Size int64
+	// Name is the file name used when FetchOptions.FileName is empty
+	Name string
+}
+
+// WithArtifactSource lets LoadPluginFromURL fetch URLs of the given scheme from src.
+// Registering "http" or "https" replaces the built-in HTTP client for that scheme.
+func WithArtifactSource(scheme string, src ArtifactSource) ManagerOption {
+	return func(m *Manager) {
+		if scheme != "" && src != nil {
+			m.sources[strings.ToLower(scheme)] = src
+		}
+	}
+}
+
+// WithHTTPClient sets the client LoadPluginFromURL downloads with
+func WithHTTPClient(client *http.Client) ManagerOption {
+	return func(m *Manager) {
+		if client != nil {
+			m.httpClient = client
+		}
+	}
+}
+
+// LoadPluginFromURL downloads a plugin into PluginDir and loads it. HTTPS URLs are
+// fetched directly, other schemes from the ArtifactSource registered for them. The
+// artifact is written to a temporary file, checked against the expected SHA-256 and only
+// then moved into place together with its ".sha256" sidecar, so a failed download never
+// leaves a partial file behind. The URL is reported as PluginInfo.SourceURL.
+func (m *Manager) LoadPluginFromURL(ctx context.Context, rawURL string, opts FetchOptions) error {
+	if m.ctx.Err() != nil {
+		return ErrManagerClosed
+	}
+	fail := func(name string, err error) error {
+		err = ErrFetch{URL: rawURL, Err: err}
+		m.emit(Event{Type: EventLoadFailed, Plugin: name, Path: rawURL, Err: err, Reason: ReasonFetchFailed})
+		return err
+	}
+	if m.currentConfig().PluginDir == "" {
+		return fail("", errors.New("Config.PluginDir is not set"))
+	}
+	if opts.FileName != "" && !validFileName(opts.FileName) {
+		return fail("", fmt.Errorf("invalid plugin file name %q", opts.FileName))
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = m.currentConfig().FetchTimeout
+	}
+	if timeout <= 0 {
+		timeout = DefaultFetchTimeout
+	}
+	// Downloads end with the manager as well as with the caller's context
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	stop := context.AfterFunc(m.ctx, cancel)
+	defer stop()
+
+	body, desc, err := m.openArtifact(ctx, rawURL, opts.AllowHTTP)
+	if err != nil {
+		return fail("", err)
+	}
+	defer body.Close()
+
+	fileName := opts.FileName
+	if fileName == "" {
+		fileName = desc.Name
+	}
+	if !validFileName(fileName) {
+		return fail("", fmt.Errorf("invalid plugin file name %q; set FetchOptions.FileName", fileName))
+	}
+	dest := filepath.Join(m.currentConfig().PluginDir, fileName)
+
+	// Refuse blocked plugins before downloading them; the declared name is checked on load
+	name := m.pluginNameFromPath(dest)
+	if !m.currentConfig().IsPluginAllowed(name) {
+		err := ErrPluginBlocked{Name: name}
+		m.emit(Event{Type: EventBlocked, Plugin: name, Path: rawURL, Err: err})
+		return err
+	}
+
+	if err := m.fetchPlugin(ctx, rawURL, body, desc, dest, opts); err != nil {
+		return fail(name, err)
+	}
+	return m.loadPlugin(dest, opts.Config, loadOptions{})
+}
+
+// validFileName reports whether name can be used for a downloaded artifact in PluginDir
+func validFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && name == filepath.Base(name) &&
+		!strings.ContainsAny(name, `/\`) && !strings.HasPrefix(name, downloadPrefix)
+}
+
+// fetchPlugin downloads the artifact in body to dest after verifying its digest
+func (m *Manager) fetchPlugin(ctx context.Context, ref string, body io.Reader, desc Descriptor, dest string, opts FetchOptions) error {
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = m.currentConfig().FetchMaxSize
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultFetchMaxSize
+	}
+	if desc.Size > maxSize {
+		return fmt.Errorf("artifact is %d bytes, over the %d byte limit", desc.Size, maxSize)
+	}
+
+	// Every known digest must match: the caller's, the source's, or else the sidecar's
+	var expected []string
+	if opts.SHA256 != "" {
+		expected = append(expected, strings.ToLower(strings.TrimSpace(opts.SHA256)))
+	}
+	if digest, ok := strings.CutPrefix(desc.Digest, "sha256:"); ok {
+		expected = append(expected, strings.ToLower(digest))
+	}
+	if len(expected) == 0 {
+		checksumURL := opts.ChecksumURL
+		if checksumURL == "" {
+			u, err := url.Parse(ref)
+			if err != nil {
+				return err
+			}
+			u.Path += checksumSuffix
+			u.RawPath = ""
+			checksumURL = u.String()
+		}
+		data, err := m.fetchBytes(ctx, checksumURL, opts.AllowHTTP)
+		if err != nil {
+			return fmt.Errorf("failed to fetch checksum: %w", err)
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) == 0 {
+			return fmt.Errorf("checksum at %s is empty", checksumURL)
+		}
+		expected = append(expected, strings.ToLower(fields[0]))
+	}
+	for _, digest := range expected {
+		if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("invalid SHA-256 digest %q", digest)
+		}
+	}
+
+	var signature []byte
+	if opts.SignatureURL != "" {
+		var err error
+		if signature, err = m.fetchBytes(ctx, opts.SignatureURL, opts.AllowHTTP); err != nil {
+			return fmt.Errorf("failed to fetch signature: %w", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), downloadPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create download file: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to download artifact: %w", err)
+	}
+	if n > maxSize {
+		return fmt.Errorf("artifact is over the %d byte limit", maxSize)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write download file: %w", err)
+	}
+	actual := hex.EncodeToString(h.Sum(nil))
+	for _, digest := range expected {
+		if actual != digest {
+			return ErrChecksumMismatch{Path: ref, Expected: digest, Actual: actual}
+		}
+	}
+	// Process plugins must stay executable
+	mode := os.FileMode(0644)
+	if isExecutable(tmp.Name()) {
+		mode = 0755
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write download file: %w", err)
+	}
+
+	// The sidecars go in first so a load triggered by the rename finds them; the path lock
+	// keeps loads out until the artifact and its sidecars agree
+	m.fetched.Store(actual, ref)
+	unlock := m.pathLocks.lock(resolvePath(dest))
+	defer unlock()
+	sidecars := map[string][]byte{dest + checksumSuffix: []byte(actual + "  " + filepath.Base(dest) + "\n")}
+	if signature != nil {
+		sidecars[dest+signatureSuffix] = signature
+	}
+	restore, err := replaceSidecars(sidecars)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		// The artifact already in place still needs its own sidecars
+		restore()
+		return fmt.Errorf("failed to move plugin into place: %w", err)
+	}
+	committed = true
+	m.logger.Info("Downloaded plugin", "url", ref, "path", dest, "sha256", actual)
+	return nil
+}
+
+// openArtifact opens ref with the ArtifactSource registered for its scheme, or over HTTP(S)
+func (m *Manager) openArtifact(ctx context.Context, ref string, allowHTTP bool) (io.ReadCloser, Descriptor, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, Descriptor{}, err
+	}
+	if src, ok := m.sources[strings.ToLower(u.Scheme)]; ok {
+		return src.Fetch(ctx, ref)
+	}
+	resp, err := m.get(ctx, ref, allowHTTP)
+	if err != nil {
+		return nil, Descriptor{}, err
+	}
+	return resp.Body, Descriptor{Size: resp.ContentLength, Name: path.Base(u.Path)}, nil
+}
+
+// fetchBytes downloads a small file such as a checksum or signature sidecar
+func (m *Manager) fetchBytes(ctx context.Context, ref string, allowHTTP bool) ([]byte, error) {
+	body, _, err := m.openArtifact(ctx, ref, allowHTTP)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, maxSidecarSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", ref, err)
+	}
+	if len(data) > maxSidecarSize {
+		return nil, fmt.Errorf("%s is over the %d byte limit", ref, maxSidecarSize)
+	}
+	return data, nil
+}
+
+// get requests rawURL, sending Config.FetchBearerToken over HTTPS. Redirects to plain
+// HTTP are refused unless allowHTTP is set.
+func (m *Manager) get(ctx context.Context, rawURL string, allowHTTP bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScheme(req.URL, allowHTTP); err != nil {
+		return nil, err
+	}
+	if m.currentConfig().FetchBearerToken != "" && req.URL.Scheme == "https" {
+		req.Header.Set("Authorization", "Bearer "+m.currentConfig().FetchBearerToken)
+	}
+
+	client := *m.httpClient
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := checkScheme(req.URL, allowHTTP); err != nil {
+			return err
+		}
+		// The client drops the token on redirects to other hosts, but not on downgrades
+		if req.URL.Scheme != "https" {
+			req.Header.Del("Authorization")
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	return resp, nil
+}
+
+// checkScheme accepts HTTPS URLs, and HTTP ones when allowHTTP is set
+func checkScheme(u *url.URL, allowHTTP bool) error {
+	switch {
+	case u.Scheme == "https":
+		return nil
+	case u.Scheme == "http" && allowHTTP:
+		return nil
+	case u.Scheme == "http":
+		return fmt.Errorf("refusing plain HTTP URL %s; set FetchOptions.AllowHTTP to accept it", u.Redacted())
+	default:
+		return fmt.Errorf("unsupported URL scheme %q; register an ArtifactSource for it", u.Scheme)
+	}
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the same directory
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), downloadPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// replaceSidecars writes the sidecar files, returning a func that puts back the files they
+// replaced and removes the rest. A failed write puts them back itself.
+func replaceSidecars(files map[string][]byte) (restore func(), err error) {
+	var undo []func()
+	restore = func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+	for path, data := range files {
+		old, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			undo = append(undo, func() { writeFileAtomic(path, old) })
+		case os.IsNotExist(err):
+			undo = append(undo, func() { os.Remove(path) })
+		default:
+			restore()
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := writeFileAtomic(path, data); err != nil {
+			restore()
+			return nil, err
+		}
+	}
+	return restore, nil
+}
+
+// fetchedSource returns the URL an artifact with the given digest was downloaded from
+func (m *Manager) fetchedSource(hash string) string {
+	if hash == "" {
+		return ""
+	}
+	if source, ok := m.fetched.Load(hash); ok {
+		return source.(string)
+	}
+	return ""
+}
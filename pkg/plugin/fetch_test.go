@@ -0,0 +1,200 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// registryServer serves files over TLS, requiring the bearer token "secret"
+func registryServer(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// dirEntries lists the names in dir
+func dirEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestLoadPluginFromURL(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning("v1")}),
+	})
+	srv := registryServer(t, map[string]string{
+		"/plugins/payments.so":        "v1",
+		"/plugins/payments.so.sha256": sha256Hex("v1") + "  payments.so\n",
+	})
+	m, cleanup := setupTestManager(t, func(config *Config) {
+		config.FetchBearerToken = "secret"
+		config.VerifyChecksums = true
+	})
+	defer cleanup()
+	m.httpClient = srv.Client()
+
+	// The digest comes from the .sha256 sidecar next to the artifact
+	url := srv.URL + "/plugins/payments.so"
+	if err := m.LoadPluginFromURL(context.Background(), url, FetchOptions{}); err != nil {
+		t.Fatalf("LoadPluginFromURL() error = %v", err)
+	}
+	info := m.ListPlugins()
+	if len(info) != 1 || info[0].SourceURL != url || info[0].Hash != sha256Hex("v1") {
+		t.Fatalf("Expected the source URL and digest in PluginInfo, got %+v", info)
+	}
+	if got := dirEntries(t, m.currentConfig().PluginDir); strings.Join(got, ",") != "payments.so,payments.so.sha256" {
+		t.Errorf("PluginDir holds %v, want the artifact and its sidecar", got)
+	}
+	if result, err := m.Call(context.Background(), "payments", "Pay"); err != nil || result != "v1" {
+		t.Errorf("Call() = %v, %v, want v1", result, err)
+	}
+}
+
+func TestLoadPluginFromURL_Rejected(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+	})
+	srv := registryServer(t, map[string]string{"/payments.so": "v1"})
+	m, cleanup := setupTestManager(t, func(config *Config) { config.FetchBearerToken = "secret" })
+	defer cleanup()
+	m.httpClient = srv.Client()
+
+	tests := []struct {
+		name string
+		url  string
+		opts FetchOptions
+		want string
+	}{
+		{"wrong digest", srv.URL + "/payments.so", FetchOptions{SHA256: sha256Hex("v2")}, "checksum mismatch"},
+		{"missing sidecar", srv.URL + "/payments.so", FetchOptions{}, "404"},
+		{"too large", srv.URL + "/payments.so", FetchOptions{SHA256: sha256Hex("v1"), MaxSize: 1}, "byte limit"},
+		{"plain http", "http://registry.invalid/payments.so", FetchOptions{SHA256: sha256Hex("v1")}, "plain HTTP"},
+		{"bad file name", srv.URL + "/payments.so", FetchOptions{SHA256: sha256Hex("v1"), FileName: "../payments.so"}, "invalid plugin file name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.LoadPluginFromURL(context.Background(), tt.url, tt.opts)
+			var fetchErr ErrFetch
+			if !errors.As(err, &fetchErr) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadPluginFromURL() error = %v, want an ErrFetch mentioning %q", err, tt.want)
+			}
+			if got := dirEntries(t, m.currentConfig().PluginDir); len(got) != 0 {
+				t.Errorf("Expected no files left in PluginDir, got %v", got)
+			}
+		})
+	}
+	if info := m.ListPlugins(); len(info) != 0 {
+		t.Errorf("Expected no plugins loaded, got %+v", info)
+	}
+}
+
+func TestLoadPluginFromURL_KeepsSidecarsOnFailedMove(t *testing.T) {
+	srv := registryServer(t, map[string]string{"/payments.so": "v2"})
+	m, cleanup := setupTestManager(t, func(config *Config) { config.FetchBearerToken = "secret" })
+	defer cleanup()
+	m.httpClient = srv.Client()
+
+	// A directory in the artifact's place makes the final rename fail
+	dir := m.currentConfig().PluginDir
+	dest := filepath.Join(dir, "payments.so")
+	if err := os.MkdirAll(filepath.Join(dest, "busy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	oldSidecar := sha256Hex("v1") + "  payments.so\n"
+	if err := os.WriteFile(dest+checksumSuffix, []byte(oldSidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := m.LoadPluginFromURL(context.Background(), srv.URL+"/payments.so", FetchOptions{SHA256: sha256Hex("v2")})
+	if err == nil || !strings.Contains(err.Error(), "move plugin into place") {
+		t.Fatalf("LoadPluginFromURL() error = %v, want the move to fail", err)
+	}
+	if got, err := os.ReadFile(dest + checksumSuffix); err != nil || string(got) != oldSidecar {
+		t.Errorf("Checksum sidecar = %q, %v; want the old one back", got, err)
+	}
+	if got := dirEntries(t, dir); strings.Join(got, ",") != "payments.so,payments.so.sha256" {
+		t.Errorf("PluginDir holds %v, want only what was there before", got)
+	}
+}
+
+// memorySource serves artifacts from a map and reports the given descriptors
+type memorySource struct {
+	content map[string]string
+	descs   map[string]Descriptor
+}
+
+func (s *memorySource) Fetch(ctx context.Context, ref string) (io.ReadCloser, Descriptor, error) {
+	content, ok := s.content[ref]
+	if !ok {
+		return nil, Descriptor{}, fmt.Errorf("%s not found", ref)
+	}
+	return io.NopCloser(strings.NewReader(content)), s.descs[ref], nil
+}
+
+func TestLoadPluginFromURL_ArtifactSource(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+	})
+	src := &memorySource{
+		content: map[string]string{"mem://repo/payments:1": "v1", "mem://repo/payments:tampered": "v1"},
+		descs: map[string]Descriptor{
+			"mem://repo/payments:1":        {Digest: "sha256:" + sha256Hex("v1"), Size: 2, Name: "payments.so"},
+			"mem://repo/payments:tampered": {Digest: "sha256:" + sha256Hex("v2"), Size: 2, Name: "payments.so"},
+		},
+	}
+	m := newTestManager(t, nil, WithArtifactSource("mem", src))
+	config := m.currentConfig()
+
+	// The source's digest is enforced like a caller-supplied one
+	var mismatch ErrChecksumMismatch
+	if err := m.LoadPluginFromURL(context.Background(), "mem://repo/payments:tampered", FetchOptions{}); !errors.As(err, &mismatch) {
+		t.Fatalf("LoadPluginFromURL(tampered) error = %v, want ErrChecksumMismatch", err)
+	}
+	if got := dirEntries(t, config.PluginDir); len(got) != 0 {
+		t.Errorf("Expected no files left in PluginDir, got %v", got)
+	}
+
+	// The file name comes from the descriptor
+	if err := m.LoadPluginFromURL(context.Background(), "mem://repo/payments:1", FetchOptions{}); err != nil {
+		t.Fatalf("LoadPluginFromURL() error = %v", err)
+	}
+	if info := m.ListPlugins(); len(info) != 1 || info[0].Path != filepath.Join(config.PluginDir, "payments.so") ||
+		info[0].SourceURL != "mem://repo/payments:1" {
+		t.Errorf("Expected payments.so loaded from the source, got %+v", info)
+	}
+}
@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"errors"
+	"time"
+)
+
+// gcLoop collects deprecated plugin instances every interval until the manager closes
+func (m *Manager) gcLoop(interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return nil
+		case <-ticker.C:
+			freed, err := m.GCNow()
+			if err != nil {
+				m.logger.Warn("Failed to free deprecated plugins", "freed", freed, "error", err)
+			} else if freed > 0 {
+				m.logger.Debug("Freed deprecated plugins", "freed", freed)
+			}
+		}
+	}
+}
+
+// GCNow frees deprecated plugin instances that have no calls in flight and were replaced
+// at least Config.GCGracePeriod ago, and drops them from the loader cache. It returns how
+// many instances were freed and the joined Free errors; failed instances are not retried.
+func (m *Manager) GCNow() (int, error) {
+	grace := m.currentConfig().GCGracePeriod
+	freed := 0
+	var errs []error
+
+	m.deprecated.Range(func(key, value interface{}) bool {
+		instance, name := key.(*PluginInstance), value.(string)
+		if instance.GetRefs() > 0 || time.Since(instance.DeprecatedAt()) < grace {
+			return true
+		}
+		m.deprecated.Delete(key)
+		m.loader.evictPlugin(instance.Plugin)
+		if err := m.freePlugin(name, instance.Plugin); err != nil {
+			errs = append(errs, err)
+			return true
+		}
+		freed++
+		m.pluginLogger(name).Debug("Freed deprecated plugin", "version", instance.version)
+		m.emit(Event{Type: EventFreed, Plugin: name, Version: instance.version, Path: instance.path,
+			actor: ActorGC, hash: instance.hash})
+		if m.unloaded(name) {
+			m.pruneMetrics(name)
+		}
+		return true
+	})
+
+	return freed, errors.Join(errs...)
+}
+
+// unloaded reports whether no instance of a plugin is left, registered or deprecated
+func (m *Manager) unloaded(name string) bool {
+	if _, ok := m.plugins.Load(name); ok {
+		return false
+	}
+	left := false
+	m.deprecated.Range(func(_, value interface{}) bool {
+		left = value.(string) == name
+		return !left
+	})
+	return !left
+}
@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGCNow_FreesDeprecatedInstances(t *testing.T) {
+	v1 := &fakeBureau{name: "payments", version: "1.0.0"}
+	v2 := &fakeBureau{name: "payments", version: "2.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(v1, map[string]InvokeFunc{}),
+		"v2": newFakeLib(v2, map[string]InvokeFunc{}),
+	})
+
+	m := newTestManager(t, func(config *Config) {
+		config.GCInterval = 0
+		config.GCGracePeriod = time.Hour
+	})
+	loadTestPlugin(t, m, "payments", "v1")
+	loadTestPlugin(t, m, "payments", "v2")
+
+	var old *PluginInstance
+	m.deprecated.Range(func(key, _ interface{}) bool {
+		old = key.(*PluginInstance)
+		return false
+	})
+	if old == nil || old.State() != StateDeprecated || old.DeprecatedAt().IsZero() {
+		t.Fatalf("Expected v1 to be tracked as deprecated, got %+v", old)
+	}
+
+	// Within the grace period nothing is freed
+	if freed, err := m.GCNow(); freed != 0 || err != nil {
+		t.Errorf("GCNow() = %d, %v; want 0, nil", freed, err)
+	}
+
+	// Calls in flight keep the instance alive past the grace period
+	updateTestConfig(m, func(config *Config) { config.GCGracePeriod = 0 })
+	old.AddRef()
+	if freed, _ := m.GCNow(); freed != 0 || v1.isFreed() {
+		t.Error("Expected an instance with calls in flight to be kept")
+	}
+	old.DecRef()
+
+	events, unsubscribe := m.Subscribe(4)
+	defer unsubscribe()
+	if freed, err := m.GCNow(); freed != 1 || err != nil {
+		t.Fatalf("GCNow() = %d, %v; want 1, nil", freed, err)
+	}
+	if !v1.isFreed() || v2.isFreed() {
+		t.Error("Expected only v1 to be freed")
+	}
+	select {
+	case e := <-events:
+		if e.Type != EventFreed || e.Plugin != "payments" || e.Version != "1.0.0" {
+			t.Errorf("Unexpected event: %+v", e)
+		}
+	default:
+		t.Error("Expected a freed event")
+	}
+	if freed, _ := m.GCNow(); freed != 0 {
+		t.Error("Expected a freed instance to be collected only once")
+	}
+	if _, err := m.GetMetricsSnapshot("payments"); err != nil {
+		t.Errorf("GetMetricsSnapshot() error = %v while v2 is registered", err)
+	}
+
+	// Once the last instance of a plugin that is gone is freed, so are its metrics
+	val, _ := m.plugins.LoadAndDelete("payments")
+	current := val.(*PluginInstance)
+	current.deprecate()
+	m.deprecated.Store(current, "payments")
+	if freed, err := m.GCNow(); freed != 1 || err != nil {
+		t.Fatalf("GCNow() = %d, %v; want 1, nil", freed, err)
+	}
+	if _, err := m.GetMetricsSnapshot("payments"); err == nil {
+		t.Error("GetMetricsSnapshot() succeeded for a plugin whose instances were all freed")
+	}
+}
+
+func TestGC_BackgroundCollector(t *testing.T) {
+	v1 := &fakeBureau{name: "payments", version: "1.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(v1, map[string]InvokeFunc{}),
+		"v2": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, map[string]InvokeFunc{}),
+	})
+
+	m := newTestManager(t, func(config *Config) {
+		config.GCInterval = 10 * time.Millisecond
+		config.GCGracePeriod = 0
+	})
+	loadTestPlugin(t, m, "payments", "v1")
+	loadTestPlugin(t, m, "payments", "v2")
+	waitFor(t, "v1 to be collected", v1.isFreed)
+}
@@ -0,0 +1,370 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: admin.proto
+
+// Remote control of a running chameleon plugin manager. The messages mirror the JSON
+// types of the HTTP admin API.
+
+package adminpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PluginAdmin_ListPlugins_FullMethodName  = "/chameleon.admin.v1.PluginAdmin/ListPlugins"
+	PluginAdmin_GetPlugin_FullMethodName    = "/chameleon.admin.v1.PluginAdmin/GetPlugin"
+	PluginAdmin_LoadPlugin_FullMethodName   = "/chameleon.admin.v1.PluginAdmin/LoadPlugin"
+	PluginAdmin_ReloadPlugin_FullMethodName = "/chameleon.admin.v1.PluginAdmin/ReloadPlugin"
+	PluginAdmin_UnloadPlugin_FullMethodName = "/chameleon.admin.v1.PluginAdmin/UnloadPlugin"
+	PluginAdmin_GetMetrics_FullMethodName   = "/chameleon.admin.v1.PluginAdmin/GetMetrics"
+	PluginAdmin_StreamEvents_FullMethodName = "/chameleon.admin.v1.PluginAdmin/StreamEvents"
+)
+
+// PluginAdminClient is the client API for PluginAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PluginAdminClient interface {
+	// ListPlugins returns the loaded plugins, sorted by name
+	ListPlugins(ctx context.Context, in *ListPluginsRequest, opts ...grpc.CallOption) (*ListPluginsResponse, error)
+	// GetPlugin returns one plugin with its circuit breaker and call metrics
+	GetPlugin(ctx context.Context, in *GetPluginRequest, opts ...grpc.CallOption) (*PluginDetail, error)
+	// LoadPlugin loads a plugin file
+	LoadPlugin(ctx context.Context, in *LoadPluginRequest, opts ...grpc.CallOption) (*LoadPluginResponse, error)
+	// ReloadPlugin loads a plugin's file again, whatever version it holds
+	ReloadPlugin(ctx context.Context, in *ReloadPluginRequest, opts ...grpc.CallOption) (*ReloadPluginResponse, error)
+	// UnloadPlugin is not supported yet and returns UNIMPLEMENTED
+	UnloadPlugin(ctx context.Context, in *UnloadPluginRequest, opts ...grpc.CallOption) (*UnloadPluginResponse, error)
+	// GetMetrics returns a plugin's call metrics by function
+	GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error)
+	// StreamEvents sends plugin lifecycle events until the call ends or the manager closes
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type pluginAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPluginAdminClient(cc grpc.ClientConnInterface) PluginAdminClient {
+	return &pluginAdminClient{cc}
+}
+
+func (c *pluginAdminClient) ListPlugins(ctx context.Context, in *ListPluginsRequest, opts ...grpc.CallOption) (*ListPluginsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPluginsResponse)
+	err := c.cc.Invoke(ctx, PluginAdmin_ListPlugins_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginAdminClient) GetPlugin(ctx context.Context, in *GetPluginRequest, opts ...grpc.CallOption) (*PluginDetail, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PluginDetail)
+	err := c.cc.Invoke(ctx, PluginAdmin_GetPlugin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginAdminClient) LoadPlugin(ctx context.Context, in *LoadPluginRequest, opts ...grpc.CallOption) (*LoadPluginResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoadPluginResponse)
+	err := c.cc.Invoke(ctx, PluginAdmin_LoadPlugin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginAdminClient) ReloadPlugin(ctx context.Context, in *ReloadPluginRequest, opts ...grpc.CallOption) (*ReloadPluginResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReloadPluginResponse)
+	err := c.cc.Invoke(ctx, PluginAdmin_ReloadPlugin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginAdminClient) UnloadPlugin(ctx context.Context, in *UnloadPluginRequest, opts ...grpc.CallOption) (*UnloadPluginResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnloadPluginResponse)
+	err := c.cc.Invoke(ctx, PluginAdmin_UnloadPlugin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginAdminClient) GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMetricsResponse)
+	err := c.cc.Invoke(ctx, PluginAdmin_GetMetrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginAdminClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PluginAdmin_ServiceDesc.Streams[0], PluginAdmin_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PluginAdmin_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+// PluginAdminServer is the server API for PluginAdmin service.
+// All implementations must embed UnimplementedPluginAdminServer
+// for forward compatibility.
+type PluginAdminServer interface {
+	// ListPlugins returns the loaded plugins, sorted by name
+	ListPlugins(context.Context, *ListPluginsRequest) (*ListPluginsResponse, error)
+	// GetPlugin returns one plugin with its circuit breaker and call metrics
+	GetPlugin(context.Context, *GetPluginRequest) (*PluginDetail, error)
+	// LoadPlugin loads a plugin file
+	LoadPlugin(context.Context, *LoadPluginRequest) (*LoadPluginResponse, error)
+	// ReloadPlugin loads a plugin's file again, whatever version it holds
+	ReloadPlugin(context.Context, *ReloadPluginRequest) (*ReloadPluginResponse, error)
+	// UnloadPlugin is not supported yet and returns UNIMPLEMENTED
+	UnloadPlugin(context.Context, *UnloadPluginRequest) (*UnloadPluginResponse, error)
+	// GetMetrics returns a plugin's call metrics by function
+	GetMetrics(context.Context, *GetMetricsRequest) (*GetMetricsResponse, error)
+	// StreamEvents sends plugin lifecycle events until the call ends or the manager closes
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedPluginAdminServer()
+}
+
+// UnimplementedPluginAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPluginAdminServer struct{}
+
+func (UnimplementedPluginAdminServer) ListPlugins(context.Context, *ListPluginsRequest) (*ListPluginsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPlugins not implemented")
+}
+func (UnimplementedPluginAdminServer) GetPlugin(context.Context, *GetPluginRequest) (*PluginDetail, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPlugin not implemented")
+}
+func (UnimplementedPluginAdminServer) LoadPlugin(context.Context, *LoadPluginRequest) (*LoadPluginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LoadPlugin not implemented")
+}
+func (UnimplementedPluginAdminServer) ReloadPlugin(context.Context, *ReloadPluginRequest) (*ReloadPluginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReloadPlugin not implemented")
+}
+func (UnimplementedPluginAdminServer) UnloadPlugin(context.Context, *UnloadPluginRequest) (*UnloadPluginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnloadPlugin not implemented")
+}
+func (UnimplementedPluginAdminServer) GetMetrics(context.Context, *GetMetricsRequest) (*GetMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetrics not implemented")
+}
+func (UnimplementedPluginAdminServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedPluginAdminServer) mustEmbedUnimplementedPluginAdminServer() {}
+func (UnimplementedPluginAdminServer) testEmbeddedByValue()                     {}
+
+// UnsafePluginAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PluginAdminServer will
+// result in compilation errors.
+type UnsafePluginAdminServer interface {
+	mustEmbedUnimplementedPluginAdminServer()
+}
+
+func RegisterPluginAdminServer(s grpc.ServiceRegistrar, srv PluginAdminServer) {
+	// If the following call pancis, it indicates UnimplementedPluginAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PluginAdmin_ServiceDesc, srv)
+}
+
+func _PluginAdmin_ListPlugins_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPluginsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginAdminServer).ListPlugins(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginAdmin_ListPlugins_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginAdminServer).ListPlugins(ctx, req.(*ListPluginsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginAdmin_GetPlugin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPluginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginAdminServer).GetPlugin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginAdmin_GetPlugin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginAdminServer).GetPlugin(ctx, req.(*GetPluginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginAdmin_LoadPlugin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoadPluginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginAdminServer).LoadPlugin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginAdmin_LoadPlugin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginAdminServer).LoadPlugin(ctx, req.(*LoadPluginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginAdmin_ReloadPlugin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadPluginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginAdminServer).ReloadPlugin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginAdmin_ReloadPlugin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginAdminServer).ReloadPlugin(ctx, req.(*ReloadPluginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginAdmin_UnloadPlugin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnloadPluginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginAdminServer).UnloadPlugin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginAdmin_UnloadPlugin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginAdminServer).UnloadPlugin(ctx, req.(*UnloadPluginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginAdmin_GetMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginAdminServer).GetMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginAdmin_GetMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginAdminServer).GetMetrics(ctx, req.(*GetMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginAdmin_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PluginAdminServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PluginAdmin_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+// PluginAdmin_ServiceDesc is the grpc.ServiceDesc for PluginAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PluginAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "chameleon.admin.v1.PluginAdmin",
+	HandlerType: (*PluginAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListPlugins",
+			Handler:    _PluginAdmin_ListPlugins_Handler,
+		},
+		{
+			MethodName: "GetPlugin",
+			Handler:    _PluginAdmin_GetPlugin_Handler,
+		},
+		{
+			MethodName: "LoadPlugin",
+			Handler:    _PluginAdmin_LoadPlugin_Handler,
+		},
+		{
+			MethodName: "ReloadPlugin",
+			Handler:    _PluginAdmin_ReloadPlugin_Handler,
+		},
+		{
+			MethodName: "UnloadPlugin",
+			Handler:    _PluginAdmin_UnloadPlugin_Handler,
+		},
+		{
+			MethodName: "GetMetrics",
+			Handler:    _PluginAdmin_GetMetrics_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _PluginAdmin_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "admin.proto",
+}
@@ -0,0 +1,5 @@
+// Package adminpb holds the gRPC definition of the plugin manager's admin service,
+// served by grpcadmin.New.
+package adminpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative admin.proto
@@ -0,0 +1,326 @@
+// Package grpcadmin serves the admin API of a plugin.Manager over gRPC, with the service
+// defined in adminpb:
+//
+//	s := grpc.NewServer()
+//	adminpb.RegisterPluginAdminServer(s, grpcadmin.New(manager))
+//
+// It is a module of its own, so programs that do not use it do not depend on gRPC.
+package grpcadmin
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/zyanho/chameleon/pkg/plugin"
+	"github.com/zyanho/chameleon/pkg/plugin/grpcadmin/adminpb"
+)
+
+// eventBuffer is how many events a StreamEvents call buffers for a slow client
+const eventBuffer = 64
+
+// Authorizer decides whether a call to an admin method may proceed. fullMethod is
+// the gRPC method name, e.g. "/chameleon.admin.v1.PluginAdmin/ReloadPlugin"; a non-nil
+// error refuses the call and should be a gRPC status.
+type Authorizer func(ctx context.Context, fullMethod string) error
+
+// TokenAuthorizer requires "authorization: Bearer <token>" metadata on the methods that
+// change the manager: LoadPlugin, ReloadPlugin and UnloadPlugin. Other methods are
+// allowed. An empty token refuses every such call.
+func TokenAuthorizer(token string) Authorizer {
+	return bearerAuthorizer(func(got string) error {
+		if token == "" {
+			return plugin.ErrAdminTokenNotConfigured
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return plugin.ErrInvalidAdminToken
+		}
+		return nil
+	})
+}
+
+// bearerAuthorizer checks the bearer tokens of the mutating methods with check
+func bearerAuthorizer(check func(token string) error) Authorizer {
+	return func(ctx context.Context, fullMethod string) error {
+		switch fullMethod {
+		case adminpb.PluginAdmin_LoadPlugin_FullMethodName,
+			adminpb.PluginAdmin_ReloadPlugin_FullMethodName,
+			adminpb.PluginAdmin_UnloadPlugin_FullMethodName:
+		default:
+			return nil
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		err := check("")
+		for _, value := range md.Get("authorization") {
+			got, ok := strings.CutPrefix(value, "Bearer ")
+			if !ok {
+				continue
+			}
+			if err = check(got); err == nil {
+				return nil
+			}
+		}
+		if errors.Is(err, plugin.ErrAdminTokenNotConfigured) {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+		return status.Error(codes.Unauthenticated, plugin.ErrInvalidAdminToken.Error())
+	}
+}
+
+// UnaryInterceptor applies the authorizer to unary calls, e.g. with grpc.ChainUnaryInterceptor
+func (a Authorizer) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := a(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor applies the authorizer to streaming calls
+func (a Authorizer) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// Server implements the adminpb.PluginAdmin service for a Manager. Register it with
+// adminpb.RegisterPluginAdminServer.
+type Server struct {
+	adminpb.UnimplementedPluginAdminServer
+	m         *plugin.Manager
+	authorize Authorizer
+	// actor records authorized callers in the audit log
+	actor string
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithAuthorizer replaces the default authorizer, which checks tokens against
+// Config.AdminToken like TokenAuthorizer. Pass a no-op authorizer when an interceptor
+// already checks calls.
+func WithAuthorizer(a Authorizer) Option {
+	return func(g *Server) {
+		if a != nil {
+			g.authorize = a
+			g.actor = plugin.ActorAdmin
+		}
+	}
+}
+
+// New creates the admin service of m
+func New(m *plugin.Manager, opts ...Option) *Server {
+	g := &Server{m: m, authorize: bearerAuthorizer(m.CheckAdminToken), actor: plugin.ActorAdminToken}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+func (g *Server) ListPlugins(ctx context.Context, req *adminpb.ListPluginsRequest) (*adminpb.ListPluginsResponse, error) {
+	if err := g.authorize(ctx, adminpb.PluginAdmin_ListPlugins_FullMethodName); err != nil {
+		return nil, err
+	}
+	plugins := g.m.AdminList()
+	resp := &adminpb.ListPluginsResponse{Plugins: make([]*adminpb.Plugin, 0, len(plugins))}
+	for _, p := range plugins {
+		resp.Plugins = append(resp.Plugins, pluginToProto(p))
+	}
+	return resp, nil
+}
+
+func (g *Server) GetPlugin(ctx context.Context, req *adminpb.GetPluginRequest) (*adminpb.PluginDetail, error) {
+	if err := g.authorize(ctx, adminpb.PluginAdmin_GetPlugin_FullMethodName); err != nil {
+		return nil, err
+	}
+	detail, err := g.m.AdminGet(req.GetName())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return detailToProto(detail), nil
+}
+
+func (g *Server) LoadPlugin(ctx context.Context, req *adminpb.LoadPluginRequest) (*adminpb.LoadPluginResponse, error) {
+	if err := g.authorize(ctx, adminpb.PluginAdmin_LoadPlugin_FullMethodName); err != nil {
+		g.m.AuditAdmin("load", "", req.GetPath(), g.refused(ctx), err)
+		return nil, err
+	}
+	if req.GetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "path is required")
+	}
+	detail, err := g.m.AdminLoad(req.GetPath(), req.GetForce(), g.caller(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &adminpb.LoadPluginResponse{}
+	if detail.Name != "" {
+		resp.Plugin = detailToProto(detail)
+	}
+	return resp, nil
+}
+
+func (g *Server) ReloadPlugin(ctx context.Context, req *adminpb.ReloadPluginRequest) (*adminpb.ReloadPluginResponse, error) {
+	if err := g.authorize(ctx, adminpb.PluginAdmin_ReloadPlugin_FullMethodName); err != nil {
+		g.m.AuditAdmin("reload", req.GetName(), "", g.refused(ctx), err)
+		return nil, err
+	}
+	result, err := g.m.AdminReload(req.GetName(), g.caller(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &adminpb.ReloadPluginResponse{Reloaded: result.Reloaded, Plugin: detailToProto(result.Plugin)}, nil
+}
+
+func (g *Server) UnloadPlugin(ctx context.Context, req *adminpb.UnloadPluginRequest) (*adminpb.UnloadPluginResponse, error) {
+	if err := g.authorize(ctx, adminpb.PluginAdmin_UnloadPlugin_FullMethodName); err != nil {
+		g.m.AuditAdmin("unload", req.GetName(), "", g.refused(ctx), err)
+		return nil, err
+	}
+	err := status.Error(codes.Unimplemented, "unloading plugins is not supported")
+	g.m.AuditAdmin("unload", req.GetName(), "", g.caller(ctx), err)
+	return nil, err
+}
+
+func (g *Server) GetMetrics(ctx context.Context, req *adminpb.GetMetricsRequest) (*adminpb.GetMetricsResponse, error) {
+	if err := g.authorize(ctx, adminpb.PluginAdmin_GetMetrics_FullMethodName); err != nil {
+		return nil, err
+	}
+	detail, err := g.m.AdminGet(req.GetName())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &adminpb.GetMetricsResponse{Methods: metricsToProto(detail.Metrics)}, nil
+}
+
+// StreamEvents forwards the manager's lifecycle events. Like other subscribers, a client
+// that falls more than eventBuffer events behind misses events.
+func (g *Server) StreamEvents(req *adminpb.StreamEventsRequest, stream grpc.ServerStreamingServer[adminpb.Event]) error {
+	ctx := stream.Context()
+	if err := g.authorize(ctx, adminpb.PluginAdmin_StreamEvents_FullMethodName); err != nil {
+		return err
+	}
+	events, unsubscribe := g.m.Subscribe(eventBuffer)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-g.m.Done():
+			return status.Error(codes.Unavailable, plugin.ErrManagerClosed.Error())
+		case e := <-events:
+			if len(req.GetPlugins()) > 0 && !slices.Contains(req.GetPlugins(), e.Plugin) {
+				continue
+			}
+			if err := stream.Send(eventToProto(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// caller identifies an authorized client in the audit log
+func (g *Server) caller(ctx context.Context) plugin.AdminCaller {
+	return plugin.AdminCaller{Actor: g.actor, Remote: peerAddr(ctx)}
+}
+
+// refused identifies a client whose call was refused
+func (g *Server) refused(ctx context.Context) plugin.AdminCaller {
+	return plugin.AdminCaller{Actor: plugin.ActorAnonymous, Remote: peerAddr(ctx)}
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// grpcError converts a manager error to a gRPC status
+func grpcError(err error) error {
+	var notFound plugin.ErrPluginNotFound
+	var blocked plugin.ErrPluginBlocked
+	switch {
+	case errors.As(err, &notFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.As(err, &blocked):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, plugin.ErrManagerClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+}
+
+func pluginToProto(p plugin.AdminPlugin) *adminpb.Plugin {
+	pb := &adminpb.Plugin{
+		Name:       p.Name,
+		Version:    p.Version,
+		State:      p.State,
+		RefCount:   p.RefCount,
+		Path:       p.Path,
+		Hash:       p.Hash,
+		ShadowPath: p.ShadowPath,
+		SourceUrl:  p.SourceURL,
+		Functions:  p.Functions,
+	}
+	for _, r := range p.Replicas {
+		pb.Replicas = append(pb.Replicas, &adminpb.Replica{
+			Version:  r.Version,
+			State:    r.State,
+			RefCount: r.RefCount,
+			Breaker:  r.Breaker,
+		})
+	}
+	return pb
+}
+
+func detailToProto(d plugin.AdminPluginDetail) *adminpb.PluginDetail {
+	return &adminpb.PluginDetail{
+		Plugin:  pluginToProto(d.AdminPlugin),
+		Breaker: &adminpb.Breaker{Enabled: d.BreakerDetail.Enabled, State: d.BreakerDetail.State},
+		Metrics: metricsToProto(d.Metrics),
+	}
+}
+
+func metricsToProto(metrics map[string]plugin.AdminMethodMetrics) map[string]*adminpb.MethodMetrics {
+	pb := make(map[string]*adminpb.MethodMetrics, len(metrics))
+	for name, mm := range metrics {
+		pb[name] = &adminpb.MethodMetrics{
+			Count:   mm.Count,
+			TotalNs: mm.TotalNs,
+			MinNs:   mm.MinNs,
+			MaxNs:   mm.MaxNs,
+			AvgNs:   mm.AvgNs,
+		}
+	}
+	return pb
+}
+
+func eventToProto(e plugin.Event) *adminpb.Event {
+	pb := &adminpb.Event{
+		Type:         string(e.Type),
+		Plugin:       e.Plugin,
+		Version:      e.Version,
+		Path:         e.Path,
+		TimeUnixNano: e.Time.UnixNano(),
+		Reason:       e.Reason,
+		ConflictPath: e.ConflictPath,
+	}
+	if e.Err != nil {
+		pb.Error = e.Err.Error()
+	}
+	return pb
+}
@@ -0,0 +1,219 @@
+package grpcadmin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/zyanho/chameleon/pkg/plugin"
+	"github.com/zyanho/chameleon/pkg/plugin/grpcadmin/adminpb"
+)
+
+// payments is a plugin served from memory
+type payments struct{ version string }
+
+func (p payments) Name() string                 { return "payments" }
+func (p payments) Version() string              { return p.version }
+func (payments) Init(args ...interface{}) error { return nil }
+func (payments) Free() error                    { return nil }
+
+// paymentsPath is where the memory backend serves payments
+const paymentsPath = "mem/payments"
+
+// returning builds an InvokeFunc returning a fixed value
+func returning(v interface{}) plugin.InvokeFunc {
+	return func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return v, nil
+	}
+}
+
+// newTestManager creates a manager with admin token token that has payments 1.0.0
+// loaded from backend and called once
+func newTestManager(t *testing.T, token string) (*plugin.Manager, *plugin.MemoryBackend) {
+	t.Helper()
+	backend := plugin.NewMemoryBackend()
+	backend.Register(paymentsPath, payments{version: "1.0.0"},
+		map[string]plugin.InvokeFunc{"Refund": returning("ok"), "Pay": returning("ok")})
+	config := plugin.DefaultConfig()
+	config.PluginDir = t.TempDir()
+	config.AllowHotReload = false
+	config.EnableMetrics = true
+	config.AdminToken = token
+	config.PluginConfigs["payments"] = plugin.PluginSpecificConfig{
+		Options: map[string]interface{}{plugin.OptionBackend: "memory"},
+	}
+
+	m, err := plugin.NewManager(context.Background(), config, plugin.WithBackend("memory", backend))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	if err := m.LoadPlugin(paymentsPath); err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	if _, err := m.Call(context.Background(), "payments", "Pay"); err != nil {
+		t.Fatal(err)
+	}
+	return m, backend
+}
+
+// newTestClient serves the admin service of a newTestManager manager over an in-memory
+// connection
+func newTestClient(t *testing.T, token string, opts ...grpc.ServerOption) (adminpb.PluginAdminClient, *plugin.Manager, *plugin.MemoryBackend) {
+	t.Helper()
+	m, backend := newTestManager(t, token)
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(opts...)
+	var adminOpts []Option
+	if len(opts) > 0 {
+		// The server's interceptors authorize calls
+		adminOpts = append(adminOpts, WithAuthorizer(func(context.Context, string) error { return nil }))
+	}
+	adminpb.RegisterPluginAdminServer(s, New(m, adminOpts...))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return adminpb.NewPluginAdminClient(conn), m, backend
+}
+
+func TestServer(t *testing.T) {
+	client, _, backend := newTestClient(t, "s3cret")
+	ctx := context.Background()
+
+	list, err := client.ListPlugins(ctx, &adminpb.ListPluginsRequest{})
+	if err != nil {
+		t.Fatalf("ListPlugins() error = %v", err)
+	}
+	if len(list.Plugins) != 1 || list.Plugins[0].Name != "payments" || list.Plugins[0].State != "active" ||
+		len(list.Plugins[0].Functions) != 2 {
+		t.Fatalf("ListPlugins() = %v", list)
+	}
+
+	detail, err := client.GetPlugin(ctx, &adminpb.GetPluginRequest{Name: "payments"})
+	if err != nil || detail.Breaker.State != "closed" || detail.Metrics["Pay"].GetCount() != 1 {
+		t.Errorf("GetPlugin() = %v, %v", detail, err)
+	}
+	if _, err := client.GetPlugin(ctx, &adminpb.GetPluginRequest{Name: "orders"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetPlugin(orders) error = %v, want NotFound", err)
+	}
+	metrics, err := client.GetMetrics(ctx, &adminpb.GetMetricsRequest{Name: "payments"})
+	if err != nil || metrics.Methods["Pay"].GetCount() != 1 {
+		t.Errorf("GetMetrics() = %v, %v", metrics, err)
+	}
+	if _, err := client.GetMetrics(ctx, &adminpb.GetMetricsRequest{Name: "orders"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetMetrics(orders) error = %v, want NotFound", err)
+	}
+
+	// Mutations need the token
+	if _, err := client.ReloadPlugin(ctx, &adminpb.ReloadPluginRequest{Name: "payments"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("ReloadPlugin() without token error = %v, want Unauthenticated", err)
+	}
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer s3cret")
+	backend.Register(paymentsPath, payments{version: "2.0.0"}, map[string]plugin.InvokeFunc{"Pay": returning("ok")})
+	reload, err := client.ReloadPlugin(authCtx, &adminpb.ReloadPluginRequest{Name: "payments"})
+	if err != nil || !reload.Reloaded || reload.Plugin.Plugin.Version != "2.0.0" {
+		t.Errorf("ReloadPlugin() = %v, %v; want payments 2.0.0", reload, err)
+	}
+	load, err := client.LoadPlugin(authCtx, &adminpb.LoadPluginRequest{Path: paymentsPath})
+	if err != nil || load.Plugin.GetPlugin().GetName() != "payments" {
+		t.Errorf("LoadPlugin() = %v, %v", load, err)
+	}
+	if _, err := client.LoadPlugin(authCtx, &adminpb.LoadPluginRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("LoadPlugin() without path error = %v, want InvalidArgument", err)
+	}
+	if _, err := client.UnloadPlugin(authCtx, &adminpb.UnloadPluginRequest{Name: "payments"}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("UnloadPlugin() error = %v, want Unimplemented", err)
+	}
+}
+
+func TestServer_NoToken(t *testing.T) {
+	client, _, _ := newTestClient(t, "")
+	authCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cret")
+	if _, err := client.ReloadPlugin(authCtx, &adminpb.ReloadPluginRequest{Name: "payments"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("ReloadPlugin() error = %v, want PermissionDenied without a configured token", err)
+	}
+}
+
+func TestServer_StreamEvents(t *testing.T) {
+	client, m, backend := newTestClient(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamEvents(ctx, &adminpb.StreamEventsRequest{Plugins: []string{"payments"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The subscription starts with the call; keep upgrading until the stream sees an event
+	received := make(chan *adminpb.Event, 1)
+	go func() {
+		if e, err := stream.Recv(); err == nil {
+			received <- e
+		}
+	}()
+	for i := 2; ; i++ {
+		backend.Register(paymentsPath, payments{version: fmt.Sprintf("1.0.%d", i)}, map[string]plugin.InvokeFunc{"Pay": returning("ok")})
+		if err := m.ForceLoadPlugin(paymentsPath); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case e := <-received:
+			if e.Plugin != "payments" || e.Type == "" || e.TimeUnixNano == 0 {
+				t.Errorf("Received %v, want an event of payments", e)
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for an event")
+		}
+	}
+}
+
+func TestServer_ManagerClosed(t *testing.T) {
+	client, m, _ := newTestClient(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamEvents(ctx, &adminpb.StreamEventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Recv() after Close error = %v, want Unavailable", err)
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	auth := TokenAuthorizer("s3cret")
+	client, _, _ := newTestClient(t, "", grpc.ChainUnaryInterceptor(auth.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(auth.StreamInterceptor()))
+	ctx := context.Background()
+
+	if _, err := client.ListPlugins(ctx, &adminpb.ListPluginsRequest{}); err != nil {
+		t.Errorf("ListPlugins() error = %v, want reads allowed", err)
+	}
+	if _, err := client.ReloadPlugin(ctx, &adminpb.ReloadPluginRequest{Name: "payments"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("ReloadPlugin() without token error = %v, want Unauthenticated", err)
+	}
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer s3cret")
+	if _, err := client.ReloadPlugin(authCtx, &adminpb.ReloadPluginRequest{Name: "payments"}); err != nil {
+		t.Errorf("ReloadPlugin() error = %v", err)
+	}
+}
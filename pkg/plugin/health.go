@@ -0,0 +1,138 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Probe statuses
+const (
+	ProbeOK   = "ok"
+	ProbeFail = "fail"
+)
+
+// ProbeResult is the outcome of a health or readiness check and the JSON body of the
+// probe handlers
+type ProbeResult struct {
+	Status   string         `json:"status"`
+	Failures []ProbeFailure `json:"failures"`
+}
+
+// ProbeFailure names a component that failed a check
+type ProbeFailure struct {
+	// Component is "manager", "watcher" or "plugin/<name>"
+	Component string `json:"component"`
+	Reason    string `json:"reason"`
+}
+
+// OK reports whether the check passed
+func (r ProbeResult) OK() bool {
+	return r.Status == ProbeOK
+}
+
+func newProbeResult(failures []ProbeFailure) ProbeResult {
+	if len(failures) == 0 {
+		return ProbeResult{Status: ProbeOK, Failures: []ProbeFailure{}}
+	}
+	return ProbeResult{Status: ProbeFail, Failures: failures}
+}
+
+// Healthz checks that the manager is alive: it has not been closed and, with hot
+// reload enabled, the plugin directory is being watched
+func (m *Manager) Healthz() ProbeResult {
+	var failures []ProbeFailure
+	if m.ctx.Err() != nil {
+		failures = append(failures, ProbeFailure{Component: "manager", Reason: "closed"})
+	}
+	if m.currentConfig().AllowHotReload && !m.HotReloadHealthy() {
+		failures = append(failures, ProbeFailure{Component: "watcher", Reason: "plugin directory is not being watched"})
+	}
+	return newProbeResult(failures)
+}
+
+// Readyz checks that the manager can serve: every plugin in Config.RequiredPlugins is
+// loaded with a matching version and active, and, unless Config.Readiness ignores them,
+// none has had its breaker open for longer than the grace period. A plugin with replicas
+// is ready while any replica is.
+func (m *Manager) Readyz() ProbeResult {
+	if m.ctx.Err() != nil {
+		return newProbeResult([]ProbeFailure{{Component: "manager", Reason: "closed"}})
+	}
+
+	var failures []ProbeFailure
+	now := time.Now()
+	for _, entry := range m.currentConfig().RequiredPlugins {
+		req, err := parseRequiredPlugin(entry)
+		if err != nil {
+			continue
+		}
+		component := "plugin/" + req.name
+		val, ok := m.plugins.Load(req.name)
+		if !ok {
+			failures = append(failures, ProbeFailure{Component: component, Reason: "not loaded"})
+			continue
+		}
+		instance := val.(*PluginInstance)
+		if err := checkVersionConstraint(req.name, instance.version, req.constraint); err != nil {
+			failures = append(failures, ProbeFailure{Component: component, Reason: err.Error()})
+			continue
+		}
+		if reason := m.unreadyReason(req.name, instance, now); reason != "" {
+			failures = append(failures, ProbeFailure{Component: component, Reason: reason})
+		}
+	}
+	return newProbeResult(failures)
+}
+
+// unreadyReason tells why a loaded plugin cannot serve, or returns an empty string
+func (m *Manager) unreadyReason(name string, instance *PluginInstance, now time.Time) string {
+	var replicas []*replica
+	if set := m.replicaSetFor(name); set != nil {
+		replicas = set.snapshot()
+	} else {
+		breaker, _ := m.breakers.Load(name)
+		r := &replica{instance: instance}
+		if breaker != nil {
+			r.breaker = breaker.(*CircuitBreaker)
+		}
+		replicas = []*replica{r}
+	}
+
+	reason := ""
+	for _, r := range replicas {
+		if state := r.instance.State(); state != StateActive {
+			reason = "plugin is " + state.String()
+			continue
+		}
+		if tripped := r.breaker.trippedSince(); !m.currentConfig().Readiness.IgnoreOpenBreakers && !tripped.IsZero() &&
+			now.Sub(tripped) >= m.currentConfig().Readiness.OpenBreakerGrace {
+			reason = fmt.Sprintf("circuit breaker open for %v", now.Sub(tripped).Round(time.Second))
+			continue
+		}
+		return ""
+	}
+	return reason
+}
+
+// HealthzHandler serves Healthz as JSON, with status 200 when it passes and 503 otherwise
+func (m *Manager) HealthzHandler() http.Handler {
+	return probeHandler(m.Healthz)
+}
+
+// ReadyzHandler serves Readyz as JSON, with status 200 when it passes and 503 otherwise
+func (m *Manager) ReadyzHandler() http.Handler {
+	return probeHandler(m.Readyz)
+}
+
+func probeHandler(check func() ProbeResult) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := check()
+		status := http.StatusOK
+		if !result.OK() {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeAdminJSON(w, status, result)
+	})
+}
@@ -0,0 +1,88 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newProbedManager creates a manager that requires payments, whose Fail function fails
+func newProbedManager(t *testing.T, readiness ReadinessConfig) *Manager {
+	t.Helper()
+	useFakeOpener(t, map[string]fakeLib{
+		"payments": newFakeLib(&fakeBureau{name: "payments", version: "1.2.0"}, map[string]InvokeFunc{
+			"Fail": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				return nil, errors.New("declined")
+			},
+		}),
+	})
+	return newTestManager(t, func(config *Config) {
+		config.RequiredPlugins = []string{"payments@^1.0"}
+		config.Readiness = readiness
+		config.DefaultPluginConfig.CircuitBreaker = CircuitBreakerConfig{Enabled: true, MaxFailures: 1, ResetInterval: time.Hour, OpenDuration: time.Hour}
+		writeTestPlugin(t, config.PluginDir, "payments", "payments")
+	})
+}
+
+// probe requests a probe handler and decodes its body
+func probe(t *testing.T, h http.Handler) (int, ProbeResult) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var result ProbeResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, result
+}
+
+func TestReadyz_OpenBreaker(t *testing.T) {
+	m := newProbedManager(t, ReadinessConfig{})
+	if code, result := probe(t, m.ReadyzHandler()); code != http.StatusOK || result.Status != ProbeOK || len(result.Failures) != 0 {
+		t.Errorf("Readyz = %d, %+v; want ready", code, result)
+	}
+
+	m.Call(context.Background(), "payments", "Fail")
+	code, result := probe(t, m.ReadyzHandler())
+	if code != http.StatusServiceUnavailable || result.Status != ProbeFail || len(result.Failures) != 1 ||
+		result.Failures[0].Component != "plugin/payments" {
+		t.Errorf("Readyz with an open breaker = %d, %+v; want payments unready", code, result)
+	}
+	// Liveness does not depend on plugins
+	if code, _ := probe(t, m.HealthzHandler()); code != http.StatusOK {
+		t.Errorf("Healthz = %d, want 200", code)
+	}
+}
+
+func TestReadyz_BreakerThresholds(t *testing.T) {
+	for name, readiness := range map[string]ReadinessConfig{
+		"ignored": {IgnoreOpenBreakers: true},
+		"grace":   {OpenBreakerGrace: time.Hour},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := newProbedManager(t, readiness)
+			m.Call(context.Background(), "payments", "Fail")
+			if !m.IsCircuitBreakerOpen("payments") {
+				t.Fatal("Expected the breaker to be open")
+			}
+			if result := m.Readyz(); !result.OK() {
+				t.Errorf("Readyz() = %+v, want ready", result)
+			}
+		})
+	}
+}
+
+func TestProbes_Closed(t *testing.T) {
+	m := newProbedManager(t, ReadinessConfig{})
+	m.Close()
+	for name, h := range map[string]http.Handler{"healthz": m.HealthzHandler(), "readyz": m.ReadyzHandler()} {
+		code, result := probe(t, h)
+		if code != http.StatusServiceUnavailable || len(result.Failures) != 1 || result.Failures[0].Component != "manager" {
+			t.Errorf("%s after Close = %d, %+v; want the manager failing", name, code, result)
+		}
+	}
+}
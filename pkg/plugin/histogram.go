@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// maxLatencyBuckets bounds the memory a method's histogram takes
+const maxLatencyBuckets = 64
+
+// DefaultLatencyBuckets returns the default upper bounds of the latency histogram buckets,
+// 100µs to 10s in steps of 1, 2.5 and 5
+func DefaultLatencyBuckets() []time.Duration {
+	return []time.Duration{
+		100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+		time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+		10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+		100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+		time.Second, 2500 * time.Millisecond, 5 * time.Second,
+		10 * time.Second,
+	}
+}
+
+// validateLatencyBuckets checks that bounds are positive and increasing
+func validateLatencyBuckets(bounds []time.Duration) error {
+	if len(bounds) > maxLatencyBuckets {
+		return fmt.Errorf("MetricsBuckets cannot hold more than %d bounds", maxLatencyBuckets)
+	}
+	for i, bound := range bounds {
+		if bound <= 0 {
+			return fmt.Errorf("MetricsBuckets bounds must be positive")
+		}
+		if i > 0 && bound <= bounds[i-1] {
+			return fmt.Errorf("MetricsBuckets bounds must be increasing")
+		}
+	}
+	return nil
+}
+
+// LatencyHistogram counts calls by duration
+type LatencyHistogram struct {
+	// Bounds are the upper bounds, inclusive, of all buckets but the last, which holds the
+	// calls slower than every bound
+	Bounds []time.Duration
+	// Counts holds the calls of each bucket, len(Bounds)+1 of them; they are not cumulative
+	Counts []int64
+}
+
+// Total returns the number of calls in the histogram
+func (h LatencyHistogram) Total() int64 {
+	var total int64
+	for _, n := range h.Counts {
+		total += n
+	}
+	return total
+}
+
+// Quantile estimates the duration below which a share q of the calls fall, interpolating
+// linearly within the bucket it lands in. Calls in the last bucket are taken to be no
+// slower than slowest; pass zero when it is unknown to get the last bound instead.
+func (h LatencyHistogram) Quantile(q float64, slowest time.Duration) time.Duration {
+	total := h.Total()
+	if total == 0 || len(h.Bounds) == 0 || q < 0 || q > 1 {
+		return 0
+	}
+	rank := q * float64(total)
+	var seen int64
+	for i, n := range h.Counts {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+		var lower, upper time.Duration
+		if i > 0 {
+			lower = h.Bounds[i-1]
+		}
+		if i < len(h.Bounds) {
+			upper = h.Bounds[i]
+		} else if upper = slowest; upper < lower {
+			return lower
+		}
+		return lower + time.Duration(float64(upper-lower)*(rank-float64(seen))/float64(n))
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
+
+// latencyBuckets is the live histogram of a method; bounds are shared and never change
+type latencyBuckets struct {
+	bounds []time.Duration
+	counts []atomic.Int64
+}
+
+func newLatencyBuckets(bounds []time.Duration) *latencyBuckets {
+	if len(bounds) == 0 {
+		return nil
+	}
+	return &latencyBuckets{bounds: bounds, counts: make([]atomic.Int64, len(bounds)+1)}
+}
+
+func (b *latencyBuckets) observe(d time.Duration) {
+	if b == nil {
+		return
+	}
+	i := sort.Search(len(b.bounds), func(i int) bool { return d <= b.bounds[i] })
+	b.counts[i].Add(1)
+}
+
+func (b *latencyBuckets) snapshot() LatencyHistogram {
+	if b == nil {
+		return LatencyHistogram{}
+	}
+	h := LatencyHistogram{Bounds: b.bounds, Counts: make([]int64, len(b.counts))}
+	for i := range b.counts {
+		h.Counts[i] = b.counts[i].Load()
+	}
+	return h
+}
+
+// copy returns a histogram holding the counts so far, for snapshots
+func (b *latencyBuckets) copy() *latencyBuckets {
+	if b == nil {
+		return nil
+	}
+	c := newLatencyBuckets(b.bounds)
+	for i := range b.counts {
+		c.counts[i].Store(b.counts[i].Load())
+	}
+	return c
+}
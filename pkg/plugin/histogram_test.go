@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram_Quantile(t *testing.T) {
+	h := LatencyHistogram{
+		Bounds: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond},
+		Counts: []int64{50, 40, 9, 1},
+	}
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{q: 0, want: 0},
+		{q: 0.25, want: 5 * time.Millisecond},
+		{q: 0.5, want: 10 * time.Millisecond},
+		{q: 0.7, want: 15 * time.Millisecond},
+		{q: 0.99, want: 40 * time.Millisecond},
+		// The call slower than every bound is placed up to the slowest call seen
+		{q: 1, want: time.Second},
+	}
+	for _, tt := range tests {
+		if got := h.Quantile(tt.q, time.Second); got != tt.want {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if got := h.Quantile(1, 0); got != 40*time.Millisecond {
+		t.Errorf("Quantile(1) = %v without the slowest call, want the last bound", got)
+	}
+	if got := (LatencyHistogram{}).Quantile(0.5, 0); got != 0 {
+		t.Errorf("Quantile() of an empty histogram = %v", got)
+	}
+}
+
+func TestMethodMetrics_Histogram(t *testing.T) {
+	metrics := newPluginMetrics(true, []time.Duration{time.Millisecond, 10 * time.Millisecond})
+	for _, d := range []time.Duration{500 * time.Microsecond, time.Millisecond, 5 * time.Millisecond, time.Second} {
+		metrics.RecordCall("calc", "Add", d, nil)
+	}
+	snapshot, err := metrics.GetPluginMetrics("calc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, _ := snapshot.Methods.Load("Add")
+	mm := val.(*MethodMetrics)
+
+	// Bounds are inclusive, and the last bucket holds the calls slower than all of them
+	h := mm.Histogram()
+	if want := []int64{2, 1, 1}; len(h.Counts) != 3 || h.Counts[0] != want[0] || h.Counts[1] != want[1] || h.Counts[2] != want[2] {
+		t.Errorf("Counts = %v, want %v", h.Counts, want)
+	}
+	if got := mm.Quantile(1); got != time.Second {
+		t.Errorf("Quantile(1) = %v, want the slowest call", got)
+	}
+	if got := mm.Quantile(0.1); got < 500*time.Microsecond {
+		t.Errorf("Quantile(0.1) = %v, below the fastest call", got)
+	}
+
+	// The snapshot does not move with later calls
+	metrics.RecordCall("calc", "Add", time.Millisecond, nil)
+	if got := mm.Histogram().Total(); got != 4 {
+		t.Errorf("Snapshot holds %d calls after a later call, want 4", got)
+	}
+}
+
+func TestNewPluginMetrics_DefaultBuckets(t *testing.T) {
+	metrics := NewPluginMetrics(true)
+	metrics.RecordCall("calc", "Add", time.Millisecond, nil)
+	snapshot, _ := metrics.GetPluginMetrics("calc")
+	val, _ := snapshot.Methods.Load("Add")
+	h := val.(*MethodMetrics).Histogram()
+	if len(h.Bounds) != len(DefaultLatencyBuckets()) || h.Bounds[0] != 100*time.Microsecond || h.Bounds[len(h.Bounds)-1] != 10*time.Second {
+		t.Errorf("Bounds = %v, want 100µs to 10s", h.Bounds)
+	}
+}
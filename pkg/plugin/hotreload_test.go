@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// loadedVersion returns the version of the named plugin, or "" if it is not loaded
+func loadedVersion(m *Manager, name string) string {
+	if val, ok := m.plugins.Load(name); ok {
+		return val.(*PluginInstance).version
+	}
+	return ""
+}
+
+// deployPlugin renames a file holding content over path, as deploy tools do
+func deployPlugin(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManager_PerPluginHotReload(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name       string
+		global     bool
+		hotReload  *bool
+		wantReload bool
+	}{
+		{"inherits enabled", true, nil, true},
+		{"inherits disabled", false, nil, false},
+		{"forced on", false, &on, true},
+		{"forced off", true, &off, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeOpener(t, map[string]fakeLib{
+				"v1": newFakeLib(&fakeBureau{name: "auth", version: "1.0.0"}, nil),
+				"v2": newFakeLib(&fakeBureau{name: "auth", version: "2.0.0"}, nil),
+				"o1": newFakeLib(&fakeBureau{name: "orders", version: "1.0.0"}, nil),
+			})
+			logger := &testLogger{}
+			var path string
+			m := newTestManager(t, func(config *Config) {
+				config.AllowHotReload = tt.global
+				config.FileStabilityWindow = 0
+				config.ReloadDebounce = 10 * time.Millisecond
+				config.PluginConfigs["auth"] = PluginSpecificConfig{HotReload: tt.hotReload}
+				path = writeTestPlugin(t, config.PluginDir, "auth", "v1")
+			}, WithLogger(logger))
+			// The directory is watched when any plugin may hot reload
+			if watched := tt.global || tt.hotReload != nil && *tt.hotReload; m.HotReloadHealthy() != watched {
+				t.Errorf("HotReloadHealthy() = %v, want %v", m.HotReloadHealthy(), watched)
+			}
+
+			// The watcher loads new plugins only when AllowHotReload is on
+			deployPlugin(t, filepath.Join(m.currentConfig().PluginDir, "orders.so"), "o1")
+			deployPlugin(t, path, "v2")
+			switch {
+			case tt.wantReload:
+				waitFor(t, "the reload", func() bool { return loadedVersion(m, "auth") == "2.0.0" })
+			case m.HotReloadHealthy():
+				waitFor(t, "the ignored file", func() bool { return logger.has("INFO: Hot reload disabled, ignoring file") })
+			}
+			if tt.global {
+				waitFor(t, "the new plugin", func() bool { return loadedVersion(m, "orders") != "" })
+			} else {
+				time.Sleep(100 * time.Millisecond)
+				if loadedVersion(m, "orders") != "" {
+					t.Error("The watcher loaded a new plugin with AllowHotReload off")
+				}
+			}
+			if !tt.wantReload {
+				if got := loadedVersion(m, "auth"); got != "1.0.0" {
+					t.Errorf("version after the file changed = %s, want 1.0.0", got)
+				}
+			}
+
+			// Explicit loads bypass the setting
+			if err := m.LoadPlugin(path); err != nil {
+				t.Fatal(err)
+			}
+			if got := loadedVersion(m, "auth"); got != "2.0.0" {
+				t.Errorf("version after LoadPlugin = %s, want 2.0.0", got)
+			}
+		})
+	}
+}
+
+func TestManager_HotReloadDisabledLogsIgnoredFile(t *testing.T) {
+	on := true
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "auth", version: "1.0.0"}, nil),
+		"v2": newFakeLib(&fakeBureau{name: "auth", version: "2.0.0"}, nil),
+	})
+	logger := &testLogger{}
+	var path string
+	m := newTestManager(t, func(config *Config) {
+		config.FileStabilityWindow = 0
+		config.ReloadDebounce = 10 * time.Millisecond
+		// Another plugin hot reloads, so the directory is watched for auth too
+		config.PluginConfigs["experimental"] = PluginSpecificConfig{HotReload: &on}
+		path = writeTestPlugin(t, config.PluginDir, "auth", "v1")
+	}, WithLogger(logger))
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+
+	deployPlugin(t, path, "v2")
+	const entry = "INFO: Hot reload disabled, ignoring file"
+	waitFor(t, "the ignored file to be logged", func() bool { return logger.has(entry) })
+	if fields := logger.fields(entry)[0]; fields["plugin"] != "auth" || fields["path"] != path {
+		t.Errorf("%s logged with %v, want plugin auth and path %s", entry, fields, path)
+	}
+	if got := loadedVersion(m, "auth"); got != "1.0.0" {
+		t.Errorf("version after the file changed = %s, want 1.0.0", got)
+	}
+}
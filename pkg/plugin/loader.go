@@ -2,16 +2,77 @@ package plugin
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"plugin"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// symbolLookup is the part of *plugin.Plugin used by the Loader
+type symbolLookup interface {
+	Lookup(symName string) (plugin.Symbol, error)
+}
+
+// openPlugin opens a plugin shared object; tests replace it to avoid building .so files
+var openPlugin = func(path string) (symbolLookup, error) {
+	return plugin.Open(path)
+}
+
+// metadataSymbol is the optional symbol a plugin exports its Metadata under
+const metadataSymbol = "Metadata"
+
+// signaturesSymbol is the optional symbol describing the plugin's function signatures
+const signaturesSymbol = "FunctionSignatures"
+
+// reservedFuncPrefix marks function names kept for chameleon's own use
+const reservedFuncPrefix = "__"
+
+// isReservedFuncName reports whether a function name collides with the Bureau
+// lifecycle or the internal namespace
+func isReservedFuncName(name string) bool {
+	return lifecycleMethods[name] || strings.HasPrefix(name, reservedFuncPrefix)
+}
+
 // Loader handles plugin loading and validation
 type Loader struct {
 	manager *Manager
-	cache   sync.Map
+	cache   sync.Map // map[cacheKey]*Plugin
+	hits    atomic.Uint64
+	misses  atomic.Uint64
 	logger  Logger
+	shadows *shadowStore // nil unless Config.ShadowCopy or a verification option is set
+}
+
+// cacheKey identifies an artifact by its resolved location and content, so a path
+// holding new bytes misses while a symlink and its target share one entry
+type cacheKey struct {
+	path string // absolute path with symlinks resolved
+	hash string // SHA-256 of the content
+}
+
+// CacheStats reports Loader cache usage
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Size   int
+}
+
+// resolvePath returns the absolute, symlink-free form of path. If the file no longer
+// exists it falls back to the cleaned absolute path.
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = filepath.Clean(path)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
 }
 
 // NewLoader creates a new plugin loader
@@ -22,79 +83,302 @@ func NewLoader(manager *Manager) *Loader {
 	}
 }
 
-// Load loads a plugin from the specified path
-func (l *Loader) Load(ctx context.Context, path string) (*Plugin, error) {
-	if cached, ok := l.cache.Load(path); ok {
-		l.logger.Debug("Using cached plugin", "path", path)
+// Load loads a plugin from the specified path with the backend selected for it, using the
+// plugin's resolved configuration
+func (l *Loader) Load(ctx context.Context, path string, pluginConfig PluginSpecificConfig) (*Plugin, error) {
+	backend, err := l.manager.backendFor(path, pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	caps := backend.Capabilities()
+	if !caps.Artifacts {
+		// Nothing on disk to hash, cache or verify; the backend keeps track of its plugins
+		return l.open(ctx, backend, path, pluginConfig)
+	}
+
+	resolved := resolvePath(path)
+
+	// The cache is keyed by content, so hash before anything else
+	hash, err := fileSHA256(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash plugin: %w", err)
+	}
+	key := cacheKey{path: resolved, hash: hash}
+	if cached, ok := l.cache.Load(key); ok {
+		l.hits.Add(1)
+		l.logger.Debug("Using cached plugin", "path", path, "resolved", resolved)
 		return cached.(*Plugin), nil
 	}
+	l.misses.Add(1)
 
-	pluginConfig := l.manager.config.DefaultPluginConfig
-	timeoutCtx, cancel := context.WithTimeout(ctx, pluginConfig.PluginTimeout)
-	defer cancel()
+	// Read the manifest first: a malformed one rejects the plugin before it is opened
+	manifest, err := ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
 
-	done := make(chan struct{})
-	var plug *plugin.Plugin
-	var err error
+	// Open a private copy so the runtime never sees the same path twice, and so that
+	// the integrity checks cover exactly the bytes that are opened. Plugins running
+	// outside the host do not share its runtime and only need the copy when verified.
+	config := l.manager.currentConfig()
+	verify := config.VerifyChecksums || len(config.TrustedPublicKeys) > 0
+	var shadow string
+	if l.shadows != nil && (caps.InProcess || verify) {
+		if shadow, err = l.shadows.copy(path, hash); err != nil {
+			return nil, err
+		}
+		l.logger.Debug("Opening shadow copy", "path", path, "shadow", shadow)
+	}
+	openPath := path
+	if shadow != "" {
+		openPath = shadow
+	}
 
-	go func() {
-		plug, err = plugin.Open(path)
-		close(done)
-	}()
+	// Integrity checks must pass before the backend executes any plugin code
+	if err := l.verify(config, path, openPath, hash); err != nil {
+		l.removeShadow(shadow)
+		return nil, err
+	}
 
-	select {
-	case <-timeoutCtx.Done():
-		return nil, fmt.Errorf("plugin load timeout: %w", timeoutCtx.Err())
-	case <-done:
+	start := time.Now()
+	p, err := l.open(ctx, backend, openPath, pluginConfig)
+	if err != nil {
+		l.removeShadow(shadow)
+		return nil, err
+	}
+	p.openDuration = time.Since(start)
+
+	if manifest != nil {
+		if err := manifest.verify(p.bureau); err != nil {
+			l.unload(p)
+			l.removeShadow(shadow)
+			return nil, err
+		}
+		p.manifest = manifest
+	}
+	p.hash = hash
+	if info, err := os.Stat(openPath); err == nil {
+		p.size = info.Size()
+	}
+	p.shadow = shadow
+
+	// Older content at the same location is superseded
+	l.Evict(resolved)
+	l.cache.Store(key, p)
+	return p, nil
+}
+
+// verify checks the plugin's checksum sidecar and signature as configured. The sidecars
+// sit next to path; the signature is checked over the content of openPath, the file
+// that is going to be opened.
+func (l *Loader) verify(config *Config, path, openPath, hash string) error {
+	if config.VerifyChecksums {
+		if err := verifyChecksum(path, hash); err != nil {
+			return err
+		}
+	}
+	if len(config.TrustedPublicKeys) > 0 {
+		content, err := os.ReadFile(openPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to open plugin: %w", err)
+			return fmt.Errorf("failed to read plugin: %w", err)
+		}
+		if err := verifySignature(path, content, trustedKeys(config.TrustedPublicKeys)); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// open loads a plugin from its backend, giving up after the plugin's PluginTimeout
+func (l *Loader) open(ctx context.Context, backend Backend, path string, pluginConfig PluginSpecificConfig) (*Plugin, error) {
+	timeoutCtx, cancel := context.WithCancel(ctx)
+	if pluginConfig.PluginTimeout > 0 {
+		timeoutCtx, cancel = context.WithTimeout(ctx, pluginConfig.PluginTimeout)
+	}
+	defer cancel()
 
-	p, err := l.validateAndCreatePlugin(plug)
+	p, err := backend.Load(timeoutCtx, path, pluginConfig)
 	if err != nil {
 		return nil, err
 	}
+	// A backend without artifacts hands out the same plugin on every load
+	if p.backend == nil {
+		p.backend = backend
+	}
+	return p, nil
+}
+
+// loadReplica opens another instance of a loaded plugin from the same artifact, bypassing
+// the cache. The artifact must still hold the content template was loaded from.
+func (l *Loader) loadReplica(ctx context.Context, template *Plugin, path string, pluginConfig PluginSpecificConfig) (*Plugin, error) {
+	if template.backend == nil {
+		return nil, fmt.Errorf("plugin %s was not loaded by a backend", template.Name())
+	}
+	if template.backend.Capabilities().Artifacts {
+		hash, err := fileSHA256(resolvePath(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash plugin: %w", err)
+		}
+		if hash != template.hash {
+			return nil, fmt.Errorf("plugin %s changed on disk while starting replicas", path)
+		}
+	}
 
-	l.cache.Store(path, p)
+	start := time.Now()
+	p, err := l.open(ctx, template.backend, path, pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	if template.backend.Capabilities().Artifacts {
+		p.openDuration = time.Since(start)
+		p.size = template.size
+	}
+	if template.manifest != nil {
+		if err := template.manifest.verify(p.bureau); err != nil {
+			l.unload(p)
+			return nil, err
+		}
+		p.manifest = template.manifest
+	}
+	p.hash = template.hash
 	return p, nil
 }
 
-func (l *Loader) validateAndCreatePlugin(plug *plugin.Plugin) (*Plugin, error) {
+// unload releases a plugin that is not going to be used
+func (l *Loader) unload(p *Plugin) {
+	if err := p.unload(); err != nil {
+		LoggerWith(l.logger, "plugin", p.Name()).Warn("Failed to unload plugin", "error", err)
+	}
+}
+
+// Evict drops every cached plugin loaded from path, whatever its content
+func (l *Loader) Evict(path string) {
+	resolved := resolvePath(path)
+	l.cache.Range(func(k, _ interface{}) bool {
+		if k.(cacheKey).path == resolved {
+			l.cache.Delete(k)
+		}
+		return true
+	})
+}
+
+// evictPlugin drops the cache entries holding a specific plugin
+func (l *Loader) evictPlugin(p *Plugin) {
+	l.cache.Range(func(k, v interface{}) bool {
+		if v.(*Plugin) == p {
+			l.cache.Delete(k)
+		}
+		return true
+	})
+}
+
+// CacheStats returns the cache hit and miss counts and the number of cached plugins
+func (l *Loader) CacheStats() CacheStats {
+	stats := CacheStats{Hits: l.hits.Load(), Misses: l.misses.Load()}
+	l.cache.Range(func(_, _ interface{}) bool {
+		stats.Size++
+		return true
+	})
+	return stats
+}
+
+// removeShadow deletes a shadow copy that is no longer needed
+func (l *Loader) removeShadow(shadowPath string) {
+	if l.shadows == nil || shadowPath == "" {
+		return
+	}
+	if err := l.shadows.remove(shadowPath); err != nil {
+		l.logger.Warn("Failed to remove shadow copy", "shadow", shadowPath, "error", err)
+	}
+}
+
+// clear drops all cached plugins
+func (l *Loader) clear() {
+	l.cache.Range(func(key, _ interface{}) bool {
+		l.cache.Delete(key)
+		return true
+	})
+}
+
+func (l *Loader) validateAndCreatePlugin(plug symbolLookup, exportSymbol, functionsSymbol string) (*Plugin, error) {
+	// check the API handshake before touching any other symbol
+	apiVersion, ok, err := lookupAPIVersion(plug)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		l.logger.Warn("Plugin does not declare an API version, rebuild it with the current chameleon",
+			"symbol", apiVersionSymbol, "hostAPI", APIVersion)
+	} else if err := checkAPIVersion(apiVersion); err != nil {
+		return nil, err
+	}
+
 	// find the Export symbol
-	sym, err := plug.Lookup("Export")
+	sym, err := plug.Lookup(exportSymbol)
 	if err != nil {
-		return nil, fmt.Errorf("plugin does not export 'Export' symbol: %w", err)
+		return nil, fmt.Errorf("plugin does not export '%s' symbol: %w", exportSymbol, err)
 	}
 
-	l.logger.Debug("Found Export symbol", "type", fmt.Sprintf("%T", sym))
+	l.logger.Debug("Found Export symbol", "symbol", exportSymbol, "type", fmt.Sprintf("%T", sym))
 
 	// validate and convert to Bureau interface
 	bureau, ok := sym.(*Bureau)
 	if !ok {
-		return nil, fmt.Errorf("exported symbol is not a *Bureau: got type %T", sym)
+		return nil, fmt.Errorf("exported symbol '%s' is not a *Bureau: got type %T", exportSymbol, sym)
 	}
 
 	// create plugin instance
 	p := NewPlugin(*bureau)
 
-	// find and validate the Functions symbol
-	funcsSym, err := plug.Lookup("Functions")
-	if err != nil {
-		return nil, fmt.Errorf("plugin does not export 'Functions' symbol: %w", err)
+	// the Metadata symbol is optional
+	if metaSym, err := plug.Lookup(metadataSymbol); err == nil {
+		switch md := metaSym.(type) {
+		case *Metadata:
+			p.metadata = md
+		default:
+			l.logger.Warn("Ignoring Metadata symbol of unexpected type", "type", fmt.Sprintf("%T", metaSym))
+		}
 	}
 
-	l.logger.Debug("Found Functions symbol", "type", fmt.Sprintf("%T", funcsSym))
+	// find and validate the Functions symbol, falling back to reflection when it is absent
+	funcsSym, err := plug.Lookup(functionsSymbol)
+	if err != nil {
+		funcs, skipped := reflectFunctions(*bureau)
+		names := make([]string, 0, len(funcs))
+		for name := range funcs {
+			names = append(names, name)
+		}
+		l.logger.Debug("Plugin has no functions symbol, built functions via reflection",
+			"symbol", functionsSymbol, "functions", names, "skipped", skipped)
+		funcsSym = &funcs
+		p.signatures = reflectSignatures(*bureau, funcs)
+	} else {
+		l.logger.Debug("Found Functions symbol", "symbol", functionsSymbol, "type", fmt.Sprintf("%T", funcsSym))
+		// the signatures symbol is optional and only generated alongside a function map
+		if sigSym, err := plug.Lookup(signaturesSymbol); err == nil {
+			switch sigs := sigSym.(type) {
+			case *map[string]FuncSignature:
+				p.signatures = *sigs
+			default:
+				l.logger.Warn("Ignoring FunctionSignatures symbol of unexpected type", "type", fmt.Sprintf("%T", sigSym))
+			}
+		}
+	}
 
 	// validate and convert to map[string]InvokeFunc
 	funcsMap, ok := funcsSym.(*map[string]InvokeFunc)
 	if !ok {
-		return nil, fmt.Errorf("Functions is not a *map[string]InvokeFunc: got type %T", funcsSym)
+		return nil, fmt.Errorf("'%s' is not a *map[string]InvokeFunc: got type %T", functionsSymbol, funcsSym)
 	}
 
 	// register functions
 	for name, fn := range *funcsMap {
 		if err := l.validateFunc(name, fn); err != nil {
+			var reserved ErrReservedFuncName
+			if errors.As(err, &reserved) && !l.manager.currentConfig().StrictFunctionNames {
+				LoggerWith(l.logger, "plugin", p.Name()).Warn("Dropping plugin function with reserved name", "function", name)
+				continue
+			}
 			return nil, fmt.Errorf("invalid function %s: %w", name, err)
 		}
 		p.RegisterFunc(name, fn)
@@ -110,5 +394,8 @@ func (l *Loader) validateFunc(name string, fn InvokeFunc) error {
 	if fn == nil {
 		return fmt.Errorf("nil function")
 	}
+	if isReservedFuncName(name) {
+		return ErrReservedFuncName{Name: name}
+	}
 	return nil
 }
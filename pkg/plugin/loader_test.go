@@ -0,0 +1,441 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"plugin"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBureau is a configurable Bureau used with the fake opener
+type fakeBureau struct {
+	name    string
+	version string
+	initErr error
+	freeErr error
+
+	mu    sync.Mutex
+	freed bool
+}
+
+func (b *fakeBureau) Name() string                   { return b.name }
+func (b *fakeBureau) Version() string                { return b.version }
+func (b *fakeBureau) Init(args ...interface{}) error { return b.initErr }
+
+func (b *fakeBureau) Free() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.freed = true
+	return b.freeErr
+}
+
+func (b *fakeBureau) isFreed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.freed
+}
+
+// fakeLib stands in for an opened shared object
+type fakeLib map[string]plugin.Symbol
+
+func (l fakeLib) Lookup(name string) (plugin.Symbol, error) {
+	if sym, ok := l[name]; ok {
+		return sym, nil
+	}
+	return nil, fmt.Errorf("symbol %s not found", name)
+}
+
+// newFakeLib exports the bureau and functions the way generated plugins do
+func newFakeLib(b Bureau, funcs map[string]InvokeFunc) fakeLib {
+	apiVersion := APIVersion
+	return fakeLib{
+		"Export":              &b,
+		"Functions":           &funcs,
+		"ChameleonAPIVersion": &apiVersion,
+	}
+}
+
+// returning builds an InvokeFunc returning a fixed value
+func returning(v interface{}) InvokeFunc {
+	return func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return v, nil
+	}
+}
+
+// useFakeOpener replaces plugin.Open with a lookup keyed by file content for the test duration
+func useFakeOpener(t testing.TB, libs map[string]fakeLib) {
+	t.Helper()
+	orig := openPlugin
+	openPlugin = func(path string) (symbolLookup, error) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		lib, ok := libs[string(content)]
+		if !ok {
+			return nil, fmt.Errorf("plugin.Open(%q): invalid ELF header", path)
+		}
+		return lib, nil
+	}
+	t.Cleanup(func() { openPlugin = orig })
+}
+
+func TestLoader_APIVersion(t *testing.T) {
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+	loader := NewLoader(m)
+
+	lib := newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{})
+	if _, err := loader.validateAndCreatePlugin(lib, DefaultExportSymbol, DefaultFunctionsSymbol); err != nil {
+		t.Fatalf("validateAndCreatePlugin() error = %v", err)
+	}
+
+	// Plugins built before the handshake still load
+	delete(lib, "ChameleonAPIVersion")
+	if _, err := loader.validateAndCreatePlugin(lib, DefaultExportSymbol, DefaultFunctionsSymbol); err != nil {
+		t.Fatalf("validateAndCreatePlugin() without API version error = %v", err)
+	}
+
+	future := APIVersion + 1
+	lib["ChameleonAPIVersion"] = &future
+	var apiErr ErrIncompatibleAPIVersion
+	if _, err := loader.validateAndCreatePlugin(lib, DefaultExportSymbol, DefaultFunctionsSymbol); !errors.As(err, &apiErr) || apiErr.PluginAPI != future {
+		t.Fatalf("Expected ErrIncompatibleAPIVersion, got %v", err)
+	}
+}
+
+func TestLoader_ReloadChangedFile(t *testing.T) {
+	ctx := context.Background()
+	useFakeOpener(t, map[string]fakeLib{
+		"v1":     newFakeLib(&fakeBureau{name: "test-plugin", version: "1.0.0"}, map[string]InvokeFunc{"TestFunc": returning("v1 result")}),
+		"v2.0.0": newFakeLib(&fakeBureau{name: "test-plugin", version: "2.0.0"}, map[string]InvokeFunc{"TestFunc": returning("v2 result")}),
+	})
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "test-plugin.so")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	// Reloading the unchanged file is served from the cache and keeps the instance
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("v2.0.0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := m.Call(ctx, "test-plugin", "TestFunc")
+	if err != nil || result != "v2 result" {
+		t.Errorf("Call() = %v, %v; want v2 result", result, err)
+	}
+}
+
+func TestLoader_UsesPluginSpecificTimeout(t *testing.T) {
+	lib := newFakeLib(&fakeBureau{name: "slow", version: "1.0.0"}, map[string]InvokeFunc{})
+	orig := openPlugin
+	openPlugin = func(path string) (symbolLookup, error) {
+		time.Sleep(200 * time.Millisecond)
+		return lib, nil
+	}
+	t.Cleanup(func() { openPlugin = orig })
+
+	m, cleanup := setupTestManager(t, func(config *Config) { config.DefaultPluginConfig.PluginTimeout = 5 * time.Second })
+	defer cleanup()
+
+	for _, name := range []string{"slow", "fast-timeout"} {
+		if err := os.WriteFile(filepath.Join(m.currentConfig().PluginDir, name+".so"), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The default timeout leaves enough room for the slow open
+	if err := m.LoadPlugin(filepath.Join(m.currentConfig().PluginDir, "slow.so")); err != nil {
+		t.Fatalf("LoadPlugin() with default timeout error = %v", err)
+	}
+
+	// A shorter per-plugin timeout wins over the default
+	if err := m.UpdatePluginConfig("fast-timeout", PluginSpecificConfig{PluginTimeout: 50 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	err := m.LoadPlugin(filepath.Join(m.currentConfig().PluginDir, "fast-timeout.so"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected load timeout, got %v", err)
+	}
+}
+
+func TestLoader_CustomSymbolNames(t *testing.T) {
+	b := Bureau(&fakeBureau{name: "third-party", version: "1.0.0"})
+	funcs := map[string]InvokeFunc{"Handle": returning("ok")}
+	lib := fakeLib{"Plugin": &b, "Handlers": &funcs}
+	useFakeOpener(t, map[string]fakeLib{"third-party": lib})
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "third-party.so")
+	if err := os.WriteFile(path, []byte("third-party"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Default names are attempted and reported in the error
+	if err := m.LoadPlugin(path); err == nil || !strings.Contains(err.Error(), "'Export'") {
+		t.Fatalf("Expected missing Export symbol error, got %v", err)
+	}
+
+	// Global export name plus a per-plugin functions override
+	updateTestConfig(m, func(config *Config) {
+		config.ExportSymbolName = "Plugin"
+		config.PluginConfigs = map[string]PluginSpecificConfig{
+			"third-party": {Options: map[string]interface{}{OptionFunctionsSymbol: "Handlers"}},
+		}
+	})
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	if result, err := m.Call(context.Background(), "third-party", "Handle"); err != nil || result != "ok" {
+		t.Errorf("Call() = %v, %v", result, err)
+	}
+}
+
+// reflectBureau is a hand-written plugin without a Functions map
+type reflectBureau struct {
+	fakeBureau
+}
+
+func (b *reflectBureau) Add(ctx context.Context, a, c int) (int, error) { return a + c, nil }
+func (b *reflectBureau) Scale(ctx context.Context, f float64) float64   { return f * 2 }
+func (b *reflectBureau) Fail(ctx context.Context) error                 { return errors.New("boom") }
+func (b *reflectBureau) NoContext(a int) int                            { return a }
+
+func TestLoader_ReflectionFallback(t *testing.T) {
+	ctx := context.Background()
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	b := Bureau(&reflectBureau{fakeBureau{name: "reflected", version: "1.0.0"}})
+	p, err := m.loader.validateAndCreatePlugin(fakeLib{"Export": &b}, DefaultExportSymbol, DefaultFunctionsSymbol)
+	if err != nil {
+		t.Fatalf("validateAndCreatePlugin() error = %v", err)
+	}
+
+	funcs := p.GetFunctions()
+	sort.Strings(funcs)
+	if want := []string{"Add", "Fail", "Scale"}; !reflect.DeepEqual(funcs, want) {
+		t.Errorf("GetFunctions() = %v, want %v", funcs, want)
+	}
+
+	// float64 from JSON-decoded input is coerced to int when integral
+	if got, err := p.Call(ctx, "Add", 1, float64(2)); err != nil || got != 3 {
+		t.Errorf("Add() = %v, %v", got, err)
+	}
+	if got, err := p.Call(ctx, "Scale", 2); err != nil || got != 4.0 {
+		t.Errorf("Scale() = %v, %v", got, err)
+	}
+	if _, err := p.Call(ctx, "Fail"); err == nil || err.Error() != "boom" {
+		t.Errorf("Fail() error = %v", err)
+	}
+
+	// Bad input returns errors instead of panicking
+	if _, err := p.Call(ctx, "Add", 1); err == nil {
+		t.Error("Expected argument count error")
+	}
+	if _, err := p.Call(ctx, "Add", 1, 2.5); err == nil {
+		t.Error("Expected fractional float to be rejected")
+	}
+	if _, err := p.Call(ctx, "Add", 1, "2"); err == nil {
+		t.Error("Expected type error")
+	}
+}
+
+func TestCoerceNumber(t *testing.T) {
+	tests := []struct {
+		in      interface{}
+		target  reflect.Type
+		want    interface{}
+		wantErr bool
+	}{
+		{float64(2), reflect.TypeOf(0), 2, false},
+		{2.5, reflect.TypeOf(0), nil, true},
+		{float64(-1 << 63), reflect.TypeOf(int64(0)), int64(math.MinInt64), false},
+		// float64(math.MaxInt64) is 1<<63, one past the largest int64
+		{float64(math.MaxInt64), reflect.TypeOf(int64(0)), nil, true},
+		{float64(1<<63 - 1024), reflect.TypeOf(int64(0)), int64(1<<63 - 1024), false},
+		{float64(math.MaxUint64), reflect.TypeOf(uint64(0)), nil, true},
+		{float64(1<<64 - 2048), reflect.TypeOf(uint64(0)), uint64(1<<64 - 2048), false},
+		{math.Inf(1), reflect.TypeOf(uint64(0)), nil, true},
+		{math.NaN(), reflect.TypeOf(0), nil, true},
+		{float64(-1), reflect.TypeOf(uint(0)), nil, true},
+		{uint64(math.MaxUint64), reflect.TypeOf(int64(0)), nil, true},
+		{300, reflect.TypeOf(int8(0)), nil, true},
+	}
+	for _, tt := range tests {
+		got, err := coerceNumber(reflect.ValueOf(tt.in), tt.target)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("coerceNumber(%v, %s) = %v, want an error", tt.in, tt.target, got)
+			}
+			continue
+		}
+		if err != nil || got.Interface() != tt.want {
+			t.Errorf("coerceNumber(%v, %s) = %v, %v; want %v", tt.in, tt.target, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoader_Metadata(t *testing.T) {
+	withMeta := newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning(true)})
+	withMeta["Metadata"] = &Metadata{Author: "billing", Capabilities: []string{"payments", "refunds"}}
+	withoutMeta := newFakeLib(&fakeBureau{name: "search", version: "1.0.0"}, map[string]InvokeFunc{"Find": returning(nil)})
+	useFakeOpener(t, map[string]fakeLib{"payments": withMeta, "search": withoutMeta})
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	for _, name := range []string{"payments", "search"} {
+		path := filepath.Join(m.currentConfig().PluginDir, name+".so")
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.LoadPlugin(path); err != nil {
+			t.Fatalf("LoadPlugin(%s) error = %v", name, err)
+		}
+	}
+
+	md, err := m.GetMetadata("payments")
+	if err != nil || md == nil || md.Author != "billing" {
+		t.Fatalf("GetMetadata(payments) = %+v, %v", md, err)
+	}
+	if md, err := m.GetMetadata("search"); err != nil || md != nil {
+		t.Errorf("GetMetadata(search) = %+v, %v, want nil metadata", md, err)
+	}
+	if _, err := m.GetMetadata("missing"); err == nil {
+		t.Error("Expected error for unknown plugin")
+	}
+	if got := m.PluginsWithCapability("refunds"); !reflect.DeepEqual(got, []string{"payments"}) {
+		t.Errorf("PluginsWithCapability(refunds) = %v", got)
+	}
+}
+
+func TestLoader_ReservedFunctionNames(t *testing.T) {
+	ctx := context.Background()
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	freed := false
+	b := &fakeBureau{name: "greedy", version: "1.0.0"}
+	lib := newFakeLib(b, map[string]InvokeFunc{
+		"Greet": returning("hi"),
+		"Free": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			freed = true
+			return nil, nil
+		},
+		"__debug": returning("internal"),
+	})
+
+	p, err := m.loader.validateAndCreatePlugin(lib, DefaultExportSymbol, DefaultFunctionsSymbol)
+	if err != nil {
+		t.Fatalf("validateAndCreatePlugin() error = %v", err)
+	}
+	if funcs := p.GetFunctions(); !reflect.DeepEqual(funcs, []string{"Greet"}) {
+		t.Errorf("GetFunctions() = %v, want [Greet]", funcs)
+	}
+	if _, err := p.Call(ctx, "Free"); err == nil {
+		t.Error("Expected reserved Free to be uncallable")
+	}
+	if freed {
+		t.Error("Reserved Free function was invoked")
+	}
+
+	updateTestConfig(m, func(config *Config) { config.StrictFunctionNames = true })
+	_, err = m.loader.validateAndCreatePlugin(lib, DefaultExportSymbol, DefaultFunctionsSymbol)
+	var reserved ErrReservedFuncName
+	if !errors.As(err, &reserved) {
+		t.Fatalf("Expected ErrReservedFuncName in strict mode, got %v", err)
+	}
+}
+
+func TestLoader_CacheKeyedByResolvedPathAndContent(t *testing.T) {
+	ctx := context.Background()
+	opens := 0
+	libs := map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+		"v2": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, map[string]InvokeFunc{}),
+	}
+	orig := openPlugin
+	openPlugin = func(path string) (symbolLookup, error) {
+		opens++
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return libs[string(content)], nil
+	}
+	t.Cleanup(func() { openPlugin = orig })
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+	config := m.currentConfig().GetPluginConfig("payments")
+
+	dir := t.TempDir()
+	real := filepath.Join(dir, "payments.so")
+	link := filepath.Join(dir, "current.so")
+	if err := os.WriteFile(real, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatal(err)
+	}
+
+	p1, err := m.loader.Load(ctx, real, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The symlink aliases the same artifact and is served from the cache
+	p2, err := m.loader.Load(ctx, link, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p1 != p2 || opens != 1 {
+		t.Errorf("Expected symlink to share the cached plugin, opens = %d", opens)
+	}
+	if stats := m.loader.CacheStats(); stats != (CacheStats{Hits: 1, Misses: 1, Size: 1}) {
+		t.Errorf("CacheStats() = %+v", stats)
+	}
+
+	// New bytes at the same path miss and replace the old entry
+	if err := os.WriteFile(real, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p3, err := m.loader.Load(ctx, link, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p3 == p1 || p3.Version() != "2.0.0" {
+		t.Errorf("Expected a fresh load for changed content, got version %s", p3.Version())
+	}
+	if stats := m.CacheStats(); stats.Misses != 2 || stats.Size != 1 {
+		t.Errorf("CacheStats() = %+v, want 2 misses and 1 entry", stats)
+	}
+
+	m.loader.Evict(link)
+	if stats := m.CacheStats(); stats.Size != 0 {
+		t.Errorf("Evict() left %d entries", stats.Size)
+	}
+}
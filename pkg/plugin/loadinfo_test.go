@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
+)
+
+// slowInitBureau takes a while to initialize
+type slowInitBureau struct {
+	*fakeBureau
+}
+
+func (b *slowInitBureau) Init(args ...interface{}) error {
+	time.Sleep(5 * time.Millisecond)
+	return nil
+}
+
+func TestManager_PluginLoadInfo(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1":   newFakeLib(&slowInitBureau{&fakeBureau{name: "payments", version: "1.0.0"}}, map[string]InvokeFunc{}),
+		"v2.0": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, map[string]InvokeFunc{}),
+	})
+	first := time.Date(2024, 11, 16, 12, 0, 0, 0, time.UTC)
+	fake := clocktest.NewFake(first)
+	m := newTestManager(t, nil, WithClock(fake))
+	path := loadTestPlugin(t, m, "payments", "v1")
+
+	info := m.ListPlugins()[0]
+	if info.Size != 2 || !info.LoadedAt.Equal(first) || !info.PreviousLoadAt.IsZero() {
+		t.Errorf("loaded plugin size %d, loaded at %v after %v, want 2 bytes at %v after nothing",
+			info.Size, info.LoadedAt, info.PreviousLoadAt, first)
+	}
+	if info.InitDuration < 5*time.Millisecond || info.OpenDuration <= 0 {
+		t.Errorf("open took %v and init %v, want init to take at least 5ms", info.OpenDuration, info.InitDuration)
+	}
+
+	// The upgrade's numbers replace the old ones, which keep their load time
+	fake.Advance(time.Hour)
+	if err := os.WriteFile(path, []byte("v2.0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.handleNewPlugin(path)
+	info = m.ListPlugins()[0]
+	sum := sha256.Sum256([]byte("v2.0"))
+	if info.Version != "2.0.0" || info.Size != 4 || info.Hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("upgraded plugin = %s, %d bytes, hash %s, want 2.0.0 of 4 bytes", info.Version, info.Size, info.Hash)
+	}
+	if !info.LoadedAt.Equal(first.Add(time.Hour)) || !info.PreviousLoadAt.Equal(first) || info.InitDuration >= 5*time.Millisecond {
+		t.Errorf("upgraded plugin loaded at %v after %v, init took %v, want %v after %v and a fast init",
+			info.LoadedAt, info.PreviousLoadAt, info.InitDuration, first.Add(time.Hour), first)
+	}
+
+	detail, err := m.AdminGet("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Size != 4 || detail.PreviousLoadAt == nil || !detail.PreviousLoadAt.Equal(first) || detail.InitNs != int64(info.InitDuration) {
+		t.Errorf("admin detail = %+v, want the upgraded plugin's load info", detail.AdminPlugin)
+	}
+	dump := m.DumpState()
+	if got := dump.Plugins[0].Instances[0]; got.Size != 4 || !got.LoadedAt.Equal(info.LoadedAt) || got.PreviousLoadAt == nil {
+		t.Errorf("dumped instance = %+v, want the upgraded plugin's load info", got)
+	}
+}
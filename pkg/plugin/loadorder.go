@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"errors"
+	"sort"
+)
+
+// loadCandidate is a plugin waiting to be loaded in a batch
+type loadCandidate struct {
+	path string
+	name string // preliminary name, matched against Config.LoadOrder
+	load func() error
+}
+
+// orderCandidates sorts candidates for loading: names listed in Config.LoadOrder first, in
+// that order, then everything else by path
+func (m *Manager) orderCandidates(candidates []loadCandidate) {
+	rank := make(map[string]int, len(m.currentConfig().LoadOrder))
+	for i, name := range m.currentConfig().LoadOrder {
+		if _, ok := rank[name]; !ok {
+			rank[name] = i
+		}
+	}
+	position := func(c loadCandidate) int {
+		if r, ok := rank[c.name]; ok {
+			return r
+		}
+		return len(m.currentConfig().LoadOrder)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		pi, pj := position(candidates[i]), position(candidates[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return candidates[i].path < candidates[j].path
+	})
+}
+
+// warnUnknownLoadOrder logs LoadOrder entries that match none of the candidates
+func (m *Manager) warnUnknownLoadOrder(candidates []loadCandidate) {
+	found := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		found[c.name] = true
+	}
+	for _, name := range m.currentConfig().LoadOrder {
+		if !found[name] {
+			m.pluginLogger(name).Warn("LoadOrder lists a plugin that was not found")
+		}
+	}
+}
+
+// LoadPlugins loads several plugin files, those named in Config.LoadOrder first and the
+// rest in path order. It keeps going past failures and returns them joined.
+func (m *Manager) LoadPlugins(paths ...string) error {
+	candidates := make([]loadCandidate, 0, len(paths))
+	for _, path := range paths {
+		path := path
+		candidates = append(candidates, loadCandidate{
+			path: path,
+			name: m.preliminaryName(path),
+			load: func() error { return m.LoadPlugin(path) },
+		})
+	}
+	m.orderCandidates(candidates)
+
+	var errs []error
+	for _, c := range candidates {
+		if err := c.load(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// initLog records the order in which bureaus are initialized
+type initLog struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (l *initLog) order() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.names...)
+}
+
+type orderedBureau struct {
+	fakeBureau
+	log *initLog
+}
+
+func (b *orderedBureau) Init(args ...interface{}) error {
+	b.log.mu.Lock()
+	defer b.log.mu.Unlock()
+	b.log.names = append(b.log.names, b.name)
+	return nil
+}
+
+// useOrderedPlugins installs fake plugins that record their Init order; each file's
+// content is the plugin name
+func useOrderedPlugins(t *testing.T, names ...string) *initLog {
+	log := &initLog{}
+	libs := make(map[string]fakeLib, len(names))
+	for _, name := range names {
+		b := &orderedBureau{fakeBureau: fakeBureau{name: name, version: "1.0.0"}, log: log}
+		libs[name] = newFakeLib(b, map[string]InvokeFunc{})
+	}
+	useFakeOpener(t, libs)
+	return log
+}
+
+func writePlugins(t *testing.T, dir string, names ...string) []string {
+	t.Helper()
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name+".so")
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestLoadPluginsFromDir_LoadOrder(t *testing.T) {
+	log := useOrderedPlugins(t, "auth", "billing", "cache", "db")
+	logger := &testLogger{}
+	newTestManager(t, func(config *Config) {
+		config.LoadOrder = []string{"db", "missing", "cache"}
+		writePlugins(t, config.PluginDir, "billing", "db", "auth", "cache")
+	}, WithLogger(logger))
+
+	want := []string{"db", "cache", "auth", "billing"}
+	if got := log.order(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Init order = %v, want %v", got, want)
+	}
+	if !logger.has("WARN: LoadOrder lists a plugin that was not found") {
+		t.Error("Expected a warning for the unknown LoadOrder entry")
+	}
+}
+
+func TestLoadPlugins_LoadOrder(t *testing.T) {
+	log := useOrderedPlugins(t, "auth", "billing", "cache")
+	m, cleanup := setupTestManager(t, func(config *Config) { config.LoadOrder = []string{"cache"} })
+	defer cleanup()
+
+	paths := writePlugins(t, m.currentConfig().PluginDir, "billing", "cache", "auth")
+	paths = append(paths, filepath.Join(m.currentConfig().PluginDir, "absent.so"))
+	if err := m.LoadPlugins(paths...); err == nil {
+		t.Error("Expected the absent file to fail")
+	}
+
+	want := []string{"cache", "auth", "billing"}
+	if got := log.order(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Init order = %v, want %v", got, want)
+	}
+	if got := len(m.ListPlugins()); got != 3 {
+		t.Errorf("Expected 3 plugins loaded past the failure, got %d", got)
+	}
+}
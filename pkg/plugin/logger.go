@@ -1,7 +1,16 @@
 package plugin
 
 import (
-	"log"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zyanho/chameleon/pkg/clock"
 )
 
 // Logger defines the interface for plugin logging
@@ -12,24 +21,196 @@ type Logger interface {
 	Error(msg string, args ...interface{})
 }
 
-// DefaultLogger provides a basic implementation of the Logger interface
+// FieldLogger is a Logger that derives child loggers with fields of their own, as
+// slog.Logger.With and zap.Logger.With do. Implementing it is optional: LoggerWith wraps
+// the loggers that do not.
+type FieldLogger interface {
+	Logger
+	// With returns a logger adding args, as key-value pairs, to every entry
+	With(args ...interface{}) Logger
+}
+
+// LoggerWith returns a logger adding args, as key-value pairs, to every entry of l,
+// ahead of the entry's own arguments. It calls l.With when l is a FieldLogger.
+func LoggerWith(l Logger, args ...interface{}) Logger {
+	if fl, ok := l.(FieldLogger); ok {
+		return fl.With(args...)
+	}
+	return &fieldLogger{logger: l, fields: args}
+}
+
+// fieldLogger binds fields to a Logger that cannot
+type fieldLogger struct {
+	logger Logger
+	fields []interface{}
+}
+
+func (l *fieldLogger) With(args ...interface{}) Logger {
+	return &fieldLogger{logger: l.logger, fields: append(l.fields[:len(l.fields):len(l.fields)], args...)}
+}
+
+// args returns the bound fields followed by args
+func (l *fieldLogger) args(args []interface{}) []interface{} {
+	return append(l.fields[:len(l.fields):len(l.fields)], args...)
+}
+
+func (l *fieldLogger) Debug(msg string, args ...interface{}) { l.logger.Debug(msg, l.args(args)...) }
+func (l *fieldLogger) Info(msg string, args ...interface{})  { l.logger.Info(msg, l.args(args)...) }
+func (l *fieldLogger) Warn(msg string, args ...interface{})  { l.logger.Warn(msg, l.args(args)...) }
+func (l *fieldLogger) Error(msg string, args ...interface{}) { l.logger.Error(msg, l.args(args)...) }
+
+// LogFormat selects how DefaultLogger writes its entries
+type LogFormat int
+
+const (
+	// LogFormatText writes "2024-11-16T12:00:00.000Z [INFO] msg [key value ...]" lines
+	LogFormatText LogFormat = iota
+	// LogFormatJSON writes one JSON object per line, with the arguments as key-value
+	// pairs: {"ts":"...","level":"info","msg":"...","plugin":"payments"}
+	LogFormatJSON
+)
+
+// badKey is the key of an argument that is not a string key followed by its value, as in
+// log/slog
+const badKey = "!BADKEY"
+
+// DefaultLogTimeFormat is RFC 3339 with milliseconds
+const DefaultLogTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// DefaultLoggerOptions configures NewDefaultLoggerWithOptions
+type DefaultLoggerOptions struct {
+	Level  LogLevel
+	Format LogFormat
+	// Writer receives the entries, one Write per line; nil means os.Stderr
+	Writer io.Writer
+	// TimeFormat formats the timestamps, as time.Time.Format; empty means
+	// DefaultLogTimeFormat
+	TimeFormat string
+	// Prefix starts every line of LogFormatText, e.g. "chameleon: "; JSON lines have none
+	Prefix string
+}
+
+// DefaultLogger provides a basic implementation of the Logger interface. It writes to a
+// writer of its own, leaving the log package's settings to the host, and is safe for
+// concurrent use: every entry is written whole, in a single Write.
 type DefaultLogger struct {
-	level LogLevel
+	level      atomic.Int64 // LogLevel
+	format     LogFormat
+	timeFormat string
+	prefix     string
+	clock      clock.Clock
+	mu         sync.Mutex // serializes writes to out
+	out        io.Writer
+}
+
+// DefaultLoggerOption configures a DefaultLogger
+type DefaultLoggerOption func(*DefaultLogger)
+
+// WithLogFormat sets the format of the entries, LogFormatText by default
+func WithLogFormat(format LogFormat) DefaultLoggerOption {
+	return func(l *DefaultLogger) {
+		l.format = format
+	}
 }
 
-// NewDefaultLogger creates a default logger implementation
-func NewDefaultLogger(level LogLevel) *DefaultLogger {
-	return &DefaultLogger{level: level}
+// NewDefaultLogger creates a default logger implementation writing to os.Stderr
+func NewDefaultLogger(level LogLevel, opts ...DefaultLoggerOption) *DefaultLogger {
+	l := NewDefaultLoggerWithOptions(DefaultLoggerOptions{Level: level})
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// NewDefaultLoggerWithOptions creates a default logger with its writer, timestamps and
+// prefix set by opts
+func NewDefaultLoggerWithOptions(opts DefaultLoggerOptions) *DefaultLogger {
+	l := &DefaultLogger{
+		format:     opts.Format,
+		timeFormat: opts.TimeFormat,
+		prefix:     opts.Prefix,
+		clock:      clock.Real(),
+		out:        opts.Writer,
+	}
+	l.level.Store(int64(opts.Level))
+	if l.timeFormat == "" {
+		l.timeFormat = DefaultLogTimeFormat
+	}
+	if l.out == nil {
+		l.out = os.Stderr
+	}
+	return l
+}
+
+// SetLevel changes the lowest level the logger writes; it is safe to call while logging
+func (l *DefaultLogger) SetLevel(level LogLevel) {
+	l.level.Store(int64(level))
 }
 
 func (l *DefaultLogger) log(level LogLevel, msg string, args ...interface{}) {
-	if level >= l.level {
+	if level < LogLevel(l.level.Load()) {
+		return
+	}
+	var buf bytes.Buffer
+	ts := l.clock.Now().Format(l.timeFormat)
+	if l.format == LogFormatJSON {
+		writeJSON(&buf, ts, level, msg, args)
+	} else {
+		buf.WriteString(l.prefix)
+		buf.WriteString(ts)
+		fmt.Fprintf(&buf, " [%s] %s", levelToString(level), msg)
 		if len(args) > 0 {
-			log.Printf("[%s] %s %v", levelToString(level), msg, args)
-		} else {
-			log.Printf("[%s] %s", levelToString(level), msg)
+			fmt.Fprintf(&buf, " %v", args)
 		}
+		buf.WriteByte('\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(buf.Bytes())
+}
+
+// writeJSON appends an entry to buf as one JSON line. Keys keep the order they were
+// given in.
+func writeJSON(buf *bytes.Buffer, ts string, level LogLevel, msg string, args []interface{}) {
+	buf.WriteString(`{"ts":`)
+	writeJSONValue(buf, ts)
+	buf.WriteString(`,"level":`)
+	writeJSONValue(buf, strings.ToLower(levelToString(level)))
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, msg)
+	for len(args) > 0 {
+		key, ok := args[0].(string)
+		if !ok || len(args) == 1 {
+			writeJSONField(buf, badKey, args[0])
+			args = args[1:]
+			continue
+		}
+		writeJSONField(buf, key, args[1])
+		args = args[2:]
+	}
+	buf.WriteString("}\n")
+}
+
+// writeJSONField appends ,"key":value to buf
+func writeJSONField(buf *bytes.Buffer, key string, value interface{}) {
+	buf.WriteByte(',')
+	writeJSONValue(buf, key)
+	buf.WriteByte(':')
+	writeJSONValue(buf, value)
+}
+
+// writeJSONValue appends value to buf as JSON. Errors are written as their message, and
+// values JSON cannot encode as they print with %+v.
+func writeJSONValue(buf *bytes.Buffer, value interface{}) {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("%+v", value))
 	}
+	buf.Write(data)
 }
 
 func (l *DefaultLogger) Debug(msg string, args ...interface{}) {
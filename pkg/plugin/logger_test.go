@@ -0,0 +1,193 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
+)
+
+// jsonLines decodes the JSON lines in buf
+func jsonLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestDefaultLogger_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewDefaultLogger(LogLevelInfo, WithLogFormat(LogFormatJSON))
+	l.out = &buf
+
+	l.Debug("Filtered out")
+	l.Info("Plugin loaded", "plugin", "payments", "version", "1.0.0", "replicas", 2)
+	l.Warn("Slow plugin call", "duration", 1500*time.Millisecond, "error", errors.New(`say "no"`))
+	l.Error("Odd arguments", "plugin", "payments", 42, "dangling")
+
+	entries := jsonLines(t, &buf)
+	if len(entries) != 3 {
+		t.Fatalf("wrote %d entries, want 3 at Info and above:\n%s", len(entries), buf.String())
+	}
+	loaded := entries[0]
+	if _, err := time.Parse(time.RFC3339, loaded["ts"].(string)); err != nil {
+		t.Errorf("ts = %v: %v", loaded["ts"], err)
+	}
+	if loaded["level"] != "info" || loaded["msg"] != "Plugin loaded" || loaded["plugin"] != "payments" ||
+		loaded["version"] != "1.0.0" || loaded["replicas"] != float64(2) {
+		t.Errorf("first entry = %v", loaded)
+	}
+	if slow := entries[1]; slow["level"] != "warn" || slow["duration"] != float64(1500*time.Millisecond) || slow["error"] != `say "no"` {
+		t.Errorf("second entry = %v, want the duration in nanoseconds and the error message", slow)
+	}
+	// A non-string key and a key without a value are kept under !BADKEY, the last one winning
+	if odd := entries[2]; odd["level"] != "error" || odd["plugin"] != "payments" || odd[badKey] != "dangling" {
+		t.Errorf("third entry = %v", odd)
+	}
+	if !strings.Contains(buf.String(), `"!BADKEY":42,"!BADKEY":"dangling"`) {
+		t.Errorf("output %s does not keep both odd arguments", buf.String())
+	}
+}
+
+func TestDefaultLogger_JSONUnencodable(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewDefaultLogger(LogLevelDebug, WithLogFormat(LogFormatJSON))
+	l.out = &buf
+
+	l.Debug("Channel", "ch", make(chan int))
+	entries := jsonLines(t, &buf)
+	if len(entries) != 1 || !strings.HasPrefix(entries[0]["ch"].(string), "0x") {
+		t.Errorf("entries = %v, want the channel printed as a string", entries)
+	}
+}
+
+// withLogger is a FieldLogger that records the fields it was derived with
+type withLogger struct {
+	testLogger
+	bound []interface{}
+}
+
+func (l *withLogger) With(args ...interface{}) Logger {
+	return &withLogger{bound: append(append([]interface{}(nil), l.bound...), args...)}
+}
+
+func TestLoggerWith(t *testing.T) {
+	logger := &testLogger{}
+	plugin := LoggerWith(logger, "plugin", "payments")
+	v1 := LoggerWith(plugin, "version", "1.0.0")
+	v2 := LoggerWith(plugin, "version", "2.0.0")
+	v1.Info("Plugin loaded", "path", "payments.so")
+	v2.Warn("Slow plugin call", "function", "Pay")
+	plugin.Error("Failed")
+
+	want := []map[string]interface{}{{"plugin": "payments", "version": "1.0.0", "path": "payments.so"}}
+	if got := logger.fields("INFO: Plugin loaded"); !reflect.DeepEqual(got, want) {
+		t.Errorf("child logger fields = %v, want %v", got, want)
+	}
+	want = []map[string]interface{}{{"plugin": "payments", "version": "2.0.0", "function": "Pay"}}
+	if got := logger.fields("WARN: Slow plugin call"); !reflect.DeepEqual(got, want) {
+		t.Errorf("sibling logger fields = %v, want %v", got, want)
+	}
+	want = []map[string]interface{}{{"plugin": "payments"}}
+	if got := logger.fields("ERROR: Failed"); !reflect.DeepEqual(got, want) {
+		t.Errorf("parent logger fields = %v after deriving children, want %v", got, want)
+	}
+
+	// Loggers that derive children themselves are left to do so
+	child, ok := LoggerWith(&withLogger{}, "plugin", "payments").(*withLogger)
+	if !ok || !reflect.DeepEqual(child.bound, []interface{}{"plugin", "payments"}) {
+		t.Errorf("LoggerWith(FieldLogger) = %#v, want the logger's own child", child)
+	}
+}
+
+func TestManager_LogsBindPlugin(t *testing.T) {
+	logger := &testLogger{}
+	m := newSlowCallManager(t, PluginSpecificConfig{}, logger)
+	if err := m.TripBreaker("payments", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{{"plugin": "payments", "reason": "maintenance"}}
+	if got := logger.fields("WARN: Circuit breaker tripped"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Circuit breaker tripped fields = %v, want %v", got, want)
+	}
+}
+
+func TestDefaultLogger_Text(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewDefaultLoggerWithOptions(DefaultLoggerOptions{Level: LogLevelWarn, Writer: &buf, Prefix: "chameleon: "})
+	l.clock = clocktest.NewFake(time.Date(2024, 11, 16, 12, 0, 0, 5e6, time.UTC))
+
+	l.Debug("Plugin file unchanged, skipping reload")
+	l.Info("Plugin loaded", "plugin", "payments")
+	l.Warn("Slow plugin call", "plugin", "payments", "duration", 1500*time.Millisecond)
+	l.Error("Watcher error")
+
+	want := "chameleon: 2024-11-16T12:00:00.005Z [WARN] Slow plugin call [plugin payments duration 1.5s]\n" +
+		"chameleon: 2024-11-16T12:00:00.005Z [ERROR] Watcher error\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDefaultLogger_Options(t *testing.T) {
+	if l := NewDefaultLogger(LogLevelInfo); l.out != os.Stderr || l.timeFormat != DefaultLogTimeFormat {
+		t.Errorf("NewDefaultLogger() writes to %v with times as %q, want os.Stderr and DefaultLogTimeFormat", l.out, l.timeFormat)
+	}
+
+	var buf bytes.Buffer
+	l := NewDefaultLoggerWithOptions(DefaultLoggerOptions{
+		Format:     LogFormatJSON,
+		Writer:     &buf,
+		TimeFormat: time.Kitchen,
+		Prefix:     "chameleon: ",
+	})
+	l.clock = clocktest.NewFake(time.Date(2024, 11, 16, 12, 0, 0, 0, time.UTC))
+	l.Debug("Plugin freed", "plugin", "payments")
+	entries := jsonLines(t, &buf)
+	if len(entries) != 1 || entries[0]["ts"] != "12:00PM" || entries[0]["level"] != "debug" {
+		t.Errorf("entries = %v, want one debug entry at 12:00PM without the prefix", entries)
+	}
+}
+
+func TestDefaultLogger_ConcurrentWrites(t *testing.T) {
+	// bytes.Buffer is not safe for concurrent use: the logger must serialize its writes
+	var buf bytes.Buffer
+	l := NewDefaultLoggerWithOptions(DefaultLoggerOptions{Writer: &buf})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Info("Plugin call", "plugin", "payments", "n", j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 800 {
+		t.Fatalf("wrote %d lines, want 800", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, " [INFO] Plugin call [plugin payments n ") {
+			t.Fatalf("line %q is garbled", line)
+		}
+	}
+}
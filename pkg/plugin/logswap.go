@@ -0,0 +1,93 @@
+package plugin
+
+import "sync/atomic"
+
+// swapLogger is the manager's logger. It forwards to the logger last set with WithLogger
+// or SetLogger, so that the loader, breakers and child loggers created before a swap
+// follow it.
+type swapLogger struct {
+	current atomic.Pointer[loggerBox]
+}
+
+// loggerBox holds a Logger for atomic.Pointer; a new box marks every swap
+type loggerBox struct {
+	Logger
+}
+
+func newSwapLogger(l Logger) *swapLogger {
+	s := &swapLogger{}
+	s.set(l)
+	return s
+}
+
+func (s *swapLogger) set(l Logger) {
+	s.current.Store(&loggerBox{Logger: l})
+}
+
+// load returns the logger entries go to now
+func (s *swapLogger) load() Logger {
+	return s.current.Load().Logger
+}
+
+func (s *swapLogger) Debug(msg string, args ...interface{}) { s.load().Debug(msg, args...) }
+func (s *swapLogger) Info(msg string, args ...interface{})  { s.load().Info(msg, args...) }
+func (s *swapLogger) Warn(msg string, args ...interface{})  { s.load().Warn(msg, args...) }
+func (s *swapLogger) Error(msg string, args ...interface{}) { s.load().Error(msg, args...) }
+
+func (s *swapLogger) With(args ...interface{}) Logger {
+	return &swapChild{root: s, fields: args}
+}
+
+// swapChild is a child of the manager's logger. It derives its logger from the current
+// one with LoggerWith, again after every swap.
+type swapChild struct {
+	root    *swapLogger
+	fields  []interface{}
+	derived atomic.Pointer[derivedLogger]
+}
+
+// derivedLogger is a child logger and the box of the logger it was derived from
+type derivedLogger struct {
+	from   *loggerBox
+	logger Logger
+}
+
+// load returns the child of the logger entries go to now
+func (c *swapChild) load() Logger {
+	from := c.root.current.Load()
+	if d := c.derived.Load(); d != nil && d.from == from {
+		return d.logger
+	}
+	d := &derivedLogger{from: from, logger: LoggerWith(from.Logger, c.fields...)}
+	c.derived.Store(d)
+	return d.logger
+}
+
+func (c *swapChild) Debug(msg string, args ...interface{}) { c.load().Debug(msg, args...) }
+func (c *swapChild) Info(msg string, args ...interface{})  { c.load().Info(msg, args...) }
+func (c *swapChild) Warn(msg string, args ...interface{})  { c.load().Warn(msg, args...) }
+func (c *swapChild) Error(msg string, args ...interface{}) { c.load().Error(msg, args...) }
+
+func (c *swapChild) With(args ...interface{}) Logger {
+	return &swapChild{root: c.root, fields: append(c.fields[:len(c.fields):len(c.fields)], args...)}
+}
+
+// SetLogger replaces the logger of the manager, its loader and its circuit breakers; nil
+// is ignored. Entries logged concurrently go to either logger, each whole.
+func (m *Manager) SetLogger(l Logger) {
+	if l != nil {
+		m.logger.set(l)
+	}
+}
+
+// SetLogLevel changes the lowest level the manager logs, e.g. to turn on debug logging
+// during an incident. It applies to a DefaultLogger, the manager's own or one set with
+// WithLogger or SetLogger; other loggers keep their level and a warning is logged.
+func (m *Manager) SetLogLevel(level LogLevel) {
+	l, ok := m.logger.load().(*DefaultLogger)
+	if !ok {
+		m.logger.Warn("Ignoring log level change, the logger is not a DefaultLogger", "level", levelToString(level))
+		return
+	}
+	l.SetLevel(level)
+}
@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestManager_SetLogger(t *testing.T) {
+	before := &testLogger{}
+	m := newSlowCallManager(t, PluginSpecificConfig{}, before)
+
+	// The breaker, created at load, logs to the new logger with its plugin bound
+	after := &testLogger{}
+	m.SetLogger(after)
+	if err := m.TripBreaker("payments", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	if before.has("WARN: Circuit breaker tripped") {
+		t.Error("breaker logged to the replaced logger")
+	}
+	if fields := after.fields("WARN: Circuit breaker tripped"); len(fields) != 1 || fields[0]["plugin"] != "payments" {
+		t.Errorf("Circuit breaker tripped entries = %v, want one for payments", fields)
+	}
+
+	m.SetLogger(nil)
+	m.logger.Info("Still logging")
+	if !after.has("INFO: Still logging") {
+		t.Error("SetLogger(nil) replaced the logger")
+	}
+}
+
+func TestManager_SetLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	m := newSlowCallManager(t, PluginSpecificConfig{}, NewDefaultLoggerWithOptions(DefaultLoggerOptions{
+		Level:  LogLevelInfo,
+		Writer: &buf,
+	}))
+	logger := m.pluginLogger("payments")
+	logger.Debug("Hidden")
+	m.SetLogLevel(LogLevelDebug)
+	logger.Debug("Shown")
+	if out := buf.String(); strings.Contains(out, "Hidden") || !strings.Contains(out, "[DEBUG] Shown [plugin payments]") {
+		t.Errorf("output = %q, want only the debug entry logged after SetLogLevel", out)
+	}
+
+	// Other loggers keep their level
+	other := &testLogger{}
+	m.SetLogger(other)
+	m.SetLogLevel(LogLevelError)
+	if fields := other.fields("WARN: Ignoring log level change, the logger is not a DefaultLogger"); len(fields) != 1 ||
+		fields[0]["level"] != "ERROR" {
+		t.Errorf("warnings = %v, want one for level ERROR", fields)
+	}
+}
+
+func TestManager_SetLoggerDuringCalls(t *testing.T) {
+	m := newSlowCallManager(t, PluginSpecificConfig{AccessLog: true}, &testLogger{})
+	loggers := []Logger{&testLogger{}, NewDefaultLoggerWithOptions(DefaultLoggerOptions{Writer: &bytes.Buffer{}})}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Call(context.Background(), "payments", "Fast")
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		m.SetLogger(loggers[i%len(loggers)])
+		m.SetLogLevel(LogLevelDebug)
+	}
+	wg.Wait()
+
+	m.SetLogger(loggers[0])
+	m.Call(context.Background(), "payments", "Fast")
+	if fields := loggers[0].(*testLogger).fields("INFO: Plugin call"); len(fields) == 0 || fields[len(fields)-1]["plugin"] != "payments" {
+		t.Errorf("access log = %v, want the last call logged for payments", fields)
+	}
+}
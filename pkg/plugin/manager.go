@@ -1,17 +1,25 @@
 package plugin
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"golang.org/x/sync/errgroup"
+
+	"github.com/zyanho/chameleon/pkg/clock"
 )
 
 // PluginState represents the state of a plugin
@@ -20,13 +28,59 @@ type PluginState int
 const (
 	StateActive PluginState = iota
 	StateDeprecated
+	// StateFailed marks a plugin whose process exited; it is being restarted or was given up on
+	StateFailed
 )
 
+func (s PluginState) String() string {
+	switch s {
+	case StateActive:
+		return "active"
+	case StateDeprecated:
+		return "deprecated"
+	case StateFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("PluginState(%d)", int(s))
+	}
+}
+
 // PluginInstance wraps a plugin with additional metadata
 type PluginInstance struct {
 	*Plugin
-	state   PluginState
-	version string
+	state        PluginState
+	version      string
+	path         string
+	hash         string    // SHA-256 of the artifact at load time
+	source       string    // URL the artifact was downloaded from, if any
+	deprecatedAt time.Time // when a newer instance replaced this one
+	restarting   atomic.Bool
+	// initDuration, loadedAt and previousLoadAt are fixed at registration, see PluginInfo
+	initDuration   time.Duration
+	loadedAt       time.Time
+	previousLoadAt time.Time
+}
+
+// State returns the lifecycle state of the instance
+func (pi *PluginInstance) State() PluginState {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.state
+}
+
+// deprecate marks the instance as replaced; instances are read concurrently once registered
+func (pi *PluginInstance) deprecate() {
+	pi.Lock()
+	pi.state = StateDeprecated
+	pi.deprecatedAt = time.Now()
+	pi.Unlock()
+}
+
+// DeprecatedAt returns when the instance was replaced, or the zero time if it is active
+func (pi *PluginInstance) DeprecatedAt() time.Time {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.deprecatedAt
 }
 
 // GetFunctions returns a list of available functions
@@ -41,11 +95,63 @@ type Manager struct {
 	watcher     *fsnotify.Watcher
 	ctx         context.Context
 	cancel      context.CancelFunc
-	config      *Config
-	logger      Logger
+	config      atomic.Pointer[Config] // replaced whole, never modified, once NewManager returns
+	configMu    sync.Mutex             // serializes config replacements
+	logger      *swapLogger
+	clock       clock.Clock
 	metrics     *PluginMetrics
-	breakers    sync.Map // map[string]*CircuitBreaker
+	breakers    sync.Map   // map[string]*CircuitBreaker
+	contracts   sync.Map   // map[string]*contract
+	deprecated  sync.Map   // map[*PluginInstance]string, replaced instances awaiting GC
+	pathLocks   keyedMutex // serializes loads of the same file
+	nameLocks   keyedMutex // serializes registration under the same name
 	eg          *errgroup.Group
+	patterns    *filePatterns
+	events      *eventBus
+	loader      *Loader
+	backends    map[string]Backend // fixed at construction
+	httpClient  *http.Client
+	sources     map[string]ArtifactSource // by URL scheme, fixed at construction
+	fetched     sync.Map                  // map[string]string, artifact SHA-256 to the URL it was downloaded from
+	restarts    sync.Map                  // map[string]*restartHistory
+	replicas    sync.Map                  // map[string]*replicaSet, for plugins running several instances
+	loadReport  atomic.Pointer[LoadReport]
+	audit       AuditSink
+	// auditLog is the sink opened from Config.Audit, closed with the manager
+	auditLog *FileAuditSink
+	// dumpRedactor rewrites plugin options in DumpState; nil redacts secret-looking keys
+	dumpRedactor DumpRedactor
+	// breakerConfigs holds the settings given to UpdateBreakerConfig, which replace the
+	// configured ones when the plugin is reloaded
+	breakerConfigs sync.Map // map[string]CircuitBreakerConfig
+	// limiters bound the calls in flight to each plugin
+	limiters sync.Map // map[string]*concurrencyLimiter
+	// state saves the loaded plugins to Config.StateFile; nil when it is not set
+	state *stateStore
+	// currentLinks maps configured current links to their plugin names; fixed at construction
+	currentLinks map[string]string
+	// inFlight counts the calls running in each plugin
+	inFlight sync.Map // map[string]*inFlightCalls
+	// slowCalls logs the calls slower than each plugin's SlowCallThreshold
+	slowCalls sync.Map // map[string]*slowCallLog
+	// accessLogs holds each plugin's AccessLog settings
+	accessLogs sync.Map // map[string]*accessLog
+	// metricLabels holds the MetricLabels each plugin was loaded with
+	metricLabels sync.Map // map[string]*pluginLabels
+	// observer is told of every call, for WithCallObserver; nil when it is not set
+	observer CallObserver
+	// configWatched is set once WatchConfig watches a config file
+	configWatched atomic.Bool
+	// watchHealthy is set while the plugin directory watch is active
+	watchHealthy atomic.Bool
+	// startedAt is when NewManager was called; the counters below are never reset
+	startedAt  time.Time
+	calls      atomic.Int64 // calls to plugin functions
+	callErrors atomic.Int64 // of which failed
+	hotReloads atomic.Int64 // successful loads of files the watcher saw change
+	upgrades   atomic.Int64 // loaded plugins replaced by a new instance
+	closeOnce  sync.Once
+	closeErr   error
 }
 
 // ManagerOption defines a function type for configuring Manager
@@ -55,15 +161,42 @@ type ManagerOption func(*Manager)
 func WithLogger(logger Logger) ManagerOption {
 	return func(m *Manager) {
 		if logger != nil {
-			m.logger = logger
+			m.logger.set(logger)
+		}
+	}
+}
+
+// WithClock sets the clock the manager's circuit breakers tell time by, for tests that
+// advance a fake clock instead of sleeping
+func WithClock(c clock.Clock) ManagerOption {
+	return func(m *Manager) {
+		if c != nil {
+			m.clock = c
 		}
 	}
 }
 
 // NewManager creates a new plugin manager
 func NewManager(ctx context.Context, config *Config, opts ...ManagerOption) (*Manager, error) {
+	m, _, err := NewManagerWithReport(ctx, config, opts...)
+	return m, err
+}
+
+// NewManagerWithReport creates a new plugin manager and returns the report of the
+// initial PluginDir scan. Under a lenient StartupFailurePolicy the manager is returned
+// together with the plugins that failed; the report is also returned when startup fails.
+//
+// The manager works on a copy of config, so changing config afterwards has no effect;
+// use WatchConfig or UpdatePluginConfig to change settings at runtime.
+func NewManagerWithReport(ctx context.Context, config *Config, opts ...ManagerOption) (*Manager, *LoadReport, error) {
 	if err := ValidateConfig(config); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
+		return nil, nil, fmt.Errorf("invalid config: %w", err)
+	}
+	config = config.Clone()
+
+	patterns, err := compileFilePatterns(config.PluginFilePatterns, config.ExcludePatterns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid config: %w", err)
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -72,7 +205,7 @@ func NewManager(ctx context.Context, config *Config, opts ...ManagerOption) (*Ma
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		cancel()
-		return nil, fmt.Errorf("failed to create watcher: %w", err)
+		return nil, nil, fmt.Errorf("failed to create watcher: %w", err)
 	}
 
 	m := &Manager{
@@ -81,34 +214,122 @@ func NewManager(ctx context.Context, config *Config, opts ...ManagerOption) (*Ma
 		watcher:     watcher,
 		ctx:         ctx,
 		cancel:      cancel,
-		config:      config,
-		logger:      NewDefaultLogger(config.LogLevel),
-		metrics:     NewPluginMetrics(config.EnableMetrics),
+		logger:      newSwapLogger(NewDefaultLogger(config.LogLevel, WithLogFormat(config.LogFormat))),
+		clock:       clock.Real(),
+		metrics:     newPluginMetrics(config.EnableMetrics, config.MetricsBuckets),
 		breakers:    sync.Map{},
 		eg:          eg,
+		patterns:    patterns,
+		events:      newEventBus(),
+		backends:    make(map[string]Backend),
+		httpClient:  http.DefaultClient,
+		sources:     make(map[string]ArtifactSource),
 	}
 
+	m.config.Store(config)
+
 	// Apply options
 	for _, opt := range opts {
 		opt(m)
 	}
+	m.metrics.clock = m.clock
+	m.startedAt = m.clock.Now()
+	if config.MetricsVersions > 0 {
+		m.metrics.maxVersions = config.MetricsVersions
+	}
+	m.loader = NewLoader(m)
+	m.registerDefaultBackends()
+	if err := m.checkBackends(); err != nil {
+		cancel()
+		watcher.Close()
+		return nil, nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if usesTimeoutDuration(config.DefaultPluginConfig.CircuitBreaker) {
+		m.logger.Warn("CircuitBreaker TimeoutDuration is deprecated, use OpenDuration", "plugin", "default")
+	}
+	for name, pluginConfig := range config.PluginConfigs {
+		if usesTimeoutDuration(pluginConfig.CircuitBreaker) {
+			m.pluginLogger(name).Warn("CircuitBreaker TimeoutDuration is deprecated, use OpenDuration")
+		}
+	}
+	m.currentLinks = config.currentLinks()
+	// Verified plugins are opened from the private copy that was verified
+	if config.ShadowCopy || config.VerifyChecksums || len(config.TrustedPublicKeys) > 0 {
+		shadows, err := newShadowStore(shadowDirBase(config.ShadowDir), m.logger)
+		if err != nil {
+			cancel()
+			watcher.Close()
+			return nil, nil, err
+		}
+		m.loader.shadows = shadows
+	}
+	if m.audit == nil {
+		m.audit = noopAuditSink{}
+		if config.Audit.Path != "" {
+			if m.auditLog, err = NewFileAuditSink(config.Audit, m.logger); err != nil {
+				m.Close()
+				return nil, nil, err
+			}
+			m.audit = m.auditLog
+		}
+	}
 
-	// Start plugin directory watcher if enabled
-	if config.AllowHotReload && config.PluginDir != "" {
+	if config.GCInterval > 0 {
+		m.eg.Go(func() error {
+			return m.gcLoop(config.GCInterval)
+		})
+	}
+	if config.MetricsReportInterval > 0 {
+		m.startMetricsReport(config.MetricsReportInterval)
+	}
+
+	// Start plugin directory watcher if enabled. The watch is added before the directory is
+	// scanned so a file dropped meanwhile is seen by the scan, the watcher or both; loads of
+	// the same file are serialized, so it is registered once either way.
+	if config.watchesPluginDir() {
+		if err := m.watcher.Add(config.PluginDir); err != nil {
+			m.Close()
+			return nil, nil, fmt.Errorf("failed to watch directory: %w", err)
+		}
+		for link := range m.currentLinks {
+			if linkDir := filepath.Dir(link); filepath.Clean(linkDir) != filepath.Clean(config.PluginDir) {
+				if err := m.watcher.Add(linkDir); err != nil {
+					m.logger.Warn("Failed to watch current link directory", "dir", linkDir, "error", err)
+				}
+			}
+		}
+		m.watchHealthy.Store(true)
 		m.eg.Go(func() error {
 			return m.watchPlugins(config.PluginDir)
 		})
 	}
 
 	// Load plugins from directory if specified
+	if config.StateFile != "" {
+		m.state = &stateStore{path: config.StateFile}
+	}
 	if config.PluginDir != "" {
 		if err := m.loadPluginsFromDir(config.PluginDir); err != nil {
 			m.Close()
-			return nil, fmt.Errorf("failed to load plugins: %w", err)
+			return nil, m.LoadReport(), fmt.Errorf("failed to load plugins: %w", err)
+		}
+	}
+	if m.state != nil {
+		report := m.LoadReport()
+		if report == nil {
+			report = &LoadReport{}
+			m.loadReport.Store(report)
 		}
+		m.restoreState(report)
 	}
 
-	return m, nil
+	if err := m.checkRequiredPlugins(m.LoadReport()); err != nil {
+		report := m.LoadReport()
+		m.Close()
+		return nil, report, err
+	}
+
+	return m, m.LoadReport(), nil
 }
 
 // LoadPlugin loads a plugin from the specified path
@@ -116,105 +337,508 @@ func (m *Manager) LoadPlugin(path string) error {
 	return m.LoadPluginWithConfig(path, nil)
 }
 
-// LoadPluginWithConfig loads a plugin with specific configuration
+// ForceLoadPlugin loads a plugin and registers it even if a plugin with the same
+// name was loaded from a different artifact, regardless of versions or NameCollisionPolicy
+func (m *Manager) ForceLoadPlugin(path string) error {
+	return m.loadPlugin(path, nil, loadOptions{force: true})
+}
+
+// LoadPluginWithConfig loads a plugin with specific configuration. A nil config
+// resolves to the plugin's entry in Config.PluginConfigs merged over the default.
+//
+// Plugins are registered under the name their Bureau reports. Before the plugin is
+// opened only the manifest name or the file name is known, so the load timeout and
+// symbol names are resolved from that; everything else uses the declared name.
 func (m *Manager) LoadPluginWithConfig(path string, config *PluginSpecificConfig) error {
-	pluginName := getPluginNameFromPath(path)
+	return m.loadPlugin(path, config, loadOptions{})
+}
+
+// loadOptions adjust how loadPlugin treats a plugin that is already registered
+type loadOptions struct {
+	// force replaces the loaded plugin unconditionally
+	force bool
+	// sameLineage treats an artifact at a different path as a new release of the loaded
+	// plugin rather than a name collision, as when a current link is retargeted
+	sameLineage bool
+	// actor is recorded in the audit log; empty means ActorHost
+	actor string
+}
 
-	// if no specific config is provided, use default config
-	if config == nil {
-		defaultConfig := m.config.DefaultPluginConfig
-		config = &defaultConfig
+// loadPlugin implements LoadPluginWithConfig and ForceLoadPlugin
+func (m *Manager) loadPlugin(path string, config *PluginSpecificConfig, opts loadOptions) error {
+	if m.ctx.Err() != nil {
+		return ErrManagerClosed
+	}
+	// The watcher, the startup scan and callers may load the same file at once;
+	// only one of them opens it, the others then find it registered
+	unlockPath := m.pathLocks.lock(resolvePath(path))
+	defer unlockPath()
+
+	fileName := m.pluginNameFromPath(path)
+	pluginName := m.preliminaryName(path)
+
+	if !m.currentConfig().IsPluginAllowed(pluginName) {
+		err := ErrPluginBlocked{Name: pluginName}
+		m.emit(Event{Type: EventBlocked, Plugin: pluginName, Path: path, Err: err, actor: opts.actor})
+		return err
+	}
+
+	// if no specific config is provided, resolve the plugin's configured one
+	explicitConfig := config != nil
+	if !explicitConfig {
+		resolved := m.currentConfig().GetPluginConfig(pluginName)
+		config = &resolved
 	}
 
 	// use Loader to load plugin first to get version
-	loader := NewLoader(m)
-	plugin, err := loader.Load(m.ctx, path)
+	start := time.Now()
+	plugin, err := m.loader.Load(m.ctx, path, *config)
 	if err != nil {
-		return fmt.Errorf("failed to load plugin: %w", err)
+		m.metrics.RecordOperation(pluginName, OpLoad, time.Since(start), err)
+		err = fmt.Errorf("failed to load plugin: %w", err)
+		m.emit(Event{Type: EventLoadFailed, Plugin: pluginName, Path: path, Err: err, Reason: failureReason(err),
+			actor: opts.actor})
+		return err
+	}
+
+	// Register under the declared name, falling back to the file name
+	declared := plugin.Name()
+	if name := cmp.Or(declared, pluginName); plugin != m.currentPlugin(name) {
+		// The loader hands back the running plugin for an unchanged file without opening it
+		m.metrics.RecordOperation(name, OpLoad, time.Since(start), nil)
+	}
+	if declared != "" && declared != fileName {
+		m.pluginLogger(declared).Warn("Plugin file name differs from its declared name, registering under the declared name",
+			"file", fileName, "path", path)
+	}
+	if declared != "" && declared != pluginName {
+		pluginName = declared
+		if !m.currentConfig().IsPluginAllowed(pluginName) {
+			if m.currentPlugin(pluginName) != plugin {
+				m.discard(path, plugin)
+			}
+			err := ErrPluginBlocked{Name: pluginName}
+			m.emit(Event{Type: EventBlocked, Plugin: pluginName, Path: path, Err: err, actor: opts.actor, hash: plugin.hash})
+			return err
+		}
+		if !explicitConfig {
+			resolved := m.currentConfig().GetPluginConfig(pluginName)
+			config = &resolved
+		}
 	}
 
-	// Check for existing plugin
+	if reason, err := m.checkPlugin(pluginName, plugin, config); err != nil {
+		if m.currentPlugin(pluginName) != plugin {
+			m.discard(path, plugin)
+		}
+		m.emit(Event{Type: EventLoadFailed, Plugin: pluginName, Version: plugin.Version(), Path: path, Err: err,
+			Reason: reason, actor: opts.actor, hash: plugin.hash})
+		return err
+	}
+
+	// Different files may declare the same name, so the version check and swap are
+	// serialized per name as well
+	unlockName := m.nameLocks.lock(pluginName)
+	defer unlockName()
+
+	// Check for existing plugin. The old instance is left untouched until the
+	// new one is fully initialized so a failed upgrade never disturbs it.
+	var oldInstance *PluginInstance
 	if oldVal, exists := m.plugins.Load(pluginName); exists {
-		oldInstance := oldVal.(*PluginInstance)
-		// If new version is not higher, skip loading
-		if !isHigherVersion(plugin.Version(), oldInstance.version) {
-			plugin.Free()
+		oldInstance = oldVal.(*PluginInstance)
+		// The loader hands back the running plugin when the file is unchanged
+		if oldInstance.Plugin == plugin {
+			return nil
+		}
+
+		replace := opts.force
+		if !opts.force && !opts.sameLineage && resolvePath(oldInstance.path) != resolvePath(path) {
+			var err error
+			if replace, err = m.resolveNameCollision(pluginName, path, plugin, oldInstance); err != nil {
+				m.discard(path, plugin)
+				return err
+			}
+		} else if !opts.force {
+			replace = m.isUpgrade(pluginName, plugin, oldInstance)
+			if !replace && config.AllowDowngrade {
+				m.pluginLogger(pluginName).Info("Replacing plugin with a version that is not higher",
+					"new", plugin.Version(), "current", oldInstance.version)
+				replace = true
+			}
+		}
+		if !replace {
+			m.discard(path, plugin)
 			return nil
 		}
-		// Mark old version as deprecated
-		oldInstance.state = StateDeprecated
+	}
+
+	m.configureLimiter(pluginName, config)
+	m.configureSlowCalls(pluginName, config)
+	m.configureAccessLog(pluginName, config)
+	m.configureMetricLabels(pluginName, config)
+	if config.replicaCount() > 1 || m.replicaSetFor(pluginName) != nil {
+		return m.registerReplicas(pluginName, path, plugin, config, oldInstance, opts.actor)
 	}
 
 	// initialize plugin
-	if err := plugin.Init(config.InitArgs...); err != nil {
-		plugin.Free()
-		return fmt.Errorf("failed to initialize plugin: %w", err)
+	initDuration, err := m.initPlugin(pluginName, plugin, config)
+	if err != nil {
+		m.discard(path, plugin)
+		err = ErrPluginInit{Name: pluginName, Err: err}
+		failure := Event{Type: EventLoadFailed, Plugin: pluginName, Version: plugin.Version(), Path: path, Err: err,
+			actor: opts.actor, hash: plugin.hash}
+		if oldInstance != nil {
+			failure.Type = EventUpgradeFailed
+		}
+		m.emit(failure)
+		return err
 	}
 
 	// create circuit breaker
-	breaker := NewCircuitBreaker(m.ctx, config.CircuitBreaker, m.logger)
+	breaker := m.newBreaker(pluginName, config.CircuitBreaker)
 
 	instance := &PluginInstance{
-		Plugin:  plugin,
-		state:   StateActive,
-		version: plugin.Version(), // Use version from plugin
+		Plugin:       plugin,
+		state:        StateActive,
+		version:      plugin.Version(), // Use version from plugin
+		path:         path,
+		hash:         plugin.hash,
+		source:       m.fetchedSource(plugin.hash),
+		initDuration: initDuration,
+		loadedAt:     m.clock.Now(),
+	}
+	if oldInstance != nil {
+		instance.previousLoadAt = oldInstance.loadedAt
 	}
 
-	m.plugins.Store(pluginName, instance)
+	// Swap in the new instance and its breaker, then retire whatever they replaced
+	if config.CircuitBreaker.CarryOverOnReload {
+		if prev, ok := m.breakers.Load(pluginName); ok {
+			breaker.inherit(prev.(*CircuitBreaker))
+		}
+	}
+	if prev, loaded := m.breakers.Swap(pluginName, breaker); loaded {
+		prev.(*CircuitBreaker).Close()
+	}
 	m.pluginPaths.Store(pluginName, path)
-	m.breakers.Store(pluginName, breaker)
+	if prev, loaded := m.plugins.Swap(pluginName, instance); loaded {
+		prevInstance := prev.(*PluginInstance)
+		prevInstance.deprecate()
+		m.deprecated.Store(prevInstance, pluginName)
+		m.loader.removeShadow(prevInstance.ShadowPath())
+		m.supervise(pluginName, instance, *config)
+		m.emit(Event{Type: EventUpgraded, Plugin: pluginName, Version: instance.version, Path: path,
+			actor: opts.actor, hash: instance.hash})
+		return nil
+	}
+
+	m.supervise(pluginName, instance, *config)
+	m.emit(Event{Type: EventLoaded, Plugin: pluginName, Version: instance.version, Path: path,
+		actor: opts.actor, hash: instance.hash})
+	return nil
+}
+
+// checkPlugin checks a loaded plugin against the version constraint and required
+// functions of config and its contract, returning the Reason of the failure with it
+func (m *Manager) checkPlugin(name string, plugin *Plugin, config *PluginSpecificConfig) (string, error) {
+	if err := checkVersionConstraint(name, plugin.Version(), config.VersionConstraint); err != nil {
+		return "", err
+	}
+	if err := checkRequiredFunctions(name, plugin, config.RequiredFunctions); err != nil {
+		return ReasonMissingFunctions, err
+	}
+	if err := m.checkContract(name, plugin); err != nil {
+		return ReasonContractViolation, err
+	}
+	return "", nil
+}
+
+// initPlugin calls Init and then Configure with the plugin's options, retrying failures
+// with backoff as the plugin config allows. It returns how long the attempts took and the
+// error of the last one.
+func (m *Manager) initPlugin(pluginName string, plugin *Plugin, config *PluginSpecificConfig) (took time.Duration, err error) {
+	start := time.Now()
+	defer func() {
+		took = time.Since(start)
+		m.metrics.RecordOperation(pluginName, OpInit, took, err)
+	}()
+
+	var deadline time.Time
+	if config.InitTimeout > 0 {
+		deadline = time.Now().Add(config.InitTimeout)
+	}
+	attempts := config.InitRetries + 1
+	backoff := config.InitRetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := plugin.Init(config.InitArgs...)
+		if err == nil {
+			err = plugin.Configure(config.Options)
+		}
+		if err == nil {
+			if attempt > 1 {
+				m.pluginLogger(pluginName).Info("Plugin initialized after retrying", "attempt", attempt)
+			}
+			return 0, nil
+		}
+		if attempt == attempts {
+			m.pluginLogger(pluginName).Error("Plugin initialization failed",
+				"attempt", attempt, "attempts", attempts, "error", err)
+			return 0, err
+		}
+		if !deadline.IsZero() && time.Now().Add(backoff).After(deadline) {
+			m.pluginLogger(pluginName).Error("Plugin initialization failed, init timeout leaves no time to retry",
+				"attempt", attempt, "attempts", attempts, "timeout", config.InitTimeout, "error", err)
+			return 0, err
+		}
+		m.pluginLogger(pluginName).Warn("Plugin initialization failed, retrying",
+			"attempt", attempt, "attempts", attempts, "retryIn", backoff, "error", err)
+
+		select {
+		case <-m.ctx.Done():
+			return 0, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isUpgrade reports whether plugin has a higher version than the loaded instance
+func (m *Manager) isUpgrade(pluginName string, plugin *Plugin, oldInstance *PluginInstance) bool {
+	higher, err := isHigherVersion(plugin.Version(), oldInstance.version)
+	if err != nil {
+		higher = m.currentConfig().UnparseableVersionPolicy == VersionPolicyAccept
+		m.pluginLogger(pluginName).Warn("Cannot compare plugin versions",
+			"new", plugin.Version(), "current", oldInstance.version, "accept", higher, "error", err)
+	}
+	return higher
+}
+
+// resolveNameCollision applies the NameCollisionPolicy to a plugin whose name is already
+// registered from a different artifact and reports whether the new one should replace it
+func (m *Manager) resolveNameCollision(pluginName, path string, plugin *Plugin, oldInstance *PluginInstance) (bool, error) {
+	var (
+		replace bool
+		err     error
+	)
+	switch m.currentConfig().NameCollisionPolicy {
+	case NameCollisionPreferNewerVersion:
+		replace = m.isUpgrade(pluginName, plugin, oldInstance)
+	case NameCollisionPreferNewerMtime:
+		replace = isNewerFile(path, oldInstance.path)
+	default:
+		err = ErrNameCollision{Name: pluginName, Path: path, ExistingPath: oldInstance.path}
+	}
 
+	m.pluginLogger(pluginName).Warn("Plugin name collision between artifacts",
+		"path", path, "existing", oldInstance.path, "replace", replace)
+	m.emit(Event{Type: EventNameCollision, Plugin: pluginName, Version: plugin.Version(), Path: path,
+		ConflictPath: oldInstance.path, Err: err})
+	return replace, err
+}
+
+// isNewerFile reports whether path was modified after existing; a missing existing file counts as older
+func isNewerFile(path, existing string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	existingInfo, err := os.Stat(existing)
+	if err != nil {
+		return true
+	}
+	return info.ModTime().After(existingInfo.ModTime())
+}
+
+// currentPlugin returns the registered plugin for a name, or nil
+func (m *Manager) currentPlugin(name string) *Plugin {
+	if val, ok := m.plugins.Load(name); ok {
+		return val.(*PluginInstance).Plugin
+	}
+	return nil
+}
+
+// discard frees a plugin that was loaded but not registered and drops it from the loader cache
+func (m *Manager) discard(path string, plugin *Plugin) {
+	m.loader.evictPlugin(plugin)
+	m.loader.removeShadow(plugin.ShadowPath())
+	if err := m.freePlugin(plugin.Name(), plugin); err != nil {
+		m.logger.Warn("Failed to free discarded plugin", "path", path, "error", err)
+	}
+}
+
+// freePlugin calls Free, giving up after Config.FreeTimeout, and then unloads the plugin
+// from its backend. An abandoned Free keeps running in the background unless unloading
+// ends it; the plugin must not be used again either way.
+func (m *Manager) freePlugin(name string, plugin *Plugin) error {
+	start := time.Now()
+	timeout := m.currentConfig().FreeTimeout
+	if timeout <= 0 {
+		timeout = DefaultFreeTimeout
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- plugin.Free()
+	}()
+
+	var err error
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err = <-done:
+	case <-timer.C:
+		m.pluginLogger(name).Error("Plugin Free did not return in time, abandoning it", "timeout", timeout)
+		err = fmt.Errorf("free did not return within %v: %w", timeout, context.DeadlineExceeded)
+	}
+
+	if unloadErr := plugin.unload(); unloadErr != nil {
+		err = errors.Join(err, fmt.Errorf("unload: %w", unloadErr))
+	}
+	m.metrics.RecordOperation(name, OpFree, time.Since(start), err)
+	if err != nil {
+		return ErrPluginFree{Name: name, Err: err}
+	}
 	return nil
 }
 
 // Call invokes a plugin function with the given arguments
-func (m *Manager) Call(ctx context.Context, pluginName, funcName string, args ...interface{}) (interface{}, error) {
+func (m *Manager) Call(ctx context.Context, pluginName, funcName string, args ...interface{}) (result interface{}, err error) {
+	if m.ctx.Err() != nil {
+		return nil, ErrManagerClosed
+	}
+	ctx, callID := ensureCallID(ctx)
+
 	// get plugin instance
 	instanceVal, exists := m.plugins.Load(pluginName)
 	if !exists {
-		return nil, &ErrPluginNotFound{Name: pluginName}
+		return nil, ErrPluginNotFound{Name: pluginName}
 	}
 	instance := instanceVal.(*PluginInstance)
+	if access := m.accessLogFor(pluginName); access != nil {
+		// Rejected calls are logged too; instance is the replica that served the call
+		callStart := time.Now()
+		defer func() {
+			m.logAccess(access, pluginName, instance.version, funcName, callID, args, time.Since(callStart), err)
+		}()
+	}
 
-	// get circuit breaker
-	breakerVal, _ := m.breakers.Load(pluginName)
-	breaker := breakerVal.(*CircuitBreaker)
+	// Take a place before the breaker, which counts every call it admits
+	limiter := m.limiterFor(pluginName)
+	if !limiter.acquire() {
+		err := ErrTooManyConcurrentCalls{Name: pluginName, Limit: int(limiter.limit.Load())}
+		m.metrics.RecordConcurrencyRejection(pluginName)
+		m.observeRejection(ctx, pluginName, instance, funcName, err)
+		return nil, err
+	}
+	defer limiter.release()
+	if m.observer != nil {
+		m.observer.Begin(ctx, pluginName)
+		defer m.observer.End(ctx, pluginName)
+	}
 
-	if breaker != nil && !breaker.Allow() {
-		return nil, &ErrCircuitBreakerOpen{Name: pluginName}
+	var breaker *CircuitBreaker
+	if set := m.replicaSetFor(pluginName); set != nil {
+		r, err := set.pick(pluginName, funcName)
+		if err != nil {
+			if errors.As(err, new(ErrCircuitOpen)) {
+				m.metrics.RecordRejection(pluginName)
+				m.observeRejection(ctx, pluginName, instance, funcName, err)
+			}
+			return nil, err
+		}
+		instance, breaker = r.instance, methodBreaker(r.breaker, r.instance, funcName)
+	} else {
+		if instance.restarting.Load() {
+			return nil, ErrPluginRestarting{Name: pluginName}
+		}
+
+		// get circuit breaker
+		breakerVal, _ := m.breakers.Load(pluginName)
+		breaker = methodBreaker(breakerVal.(*CircuitBreaker), instance, funcName)
+
+		if breaker != nil && !breaker.Allow() {
+			err := ErrCircuitOpen{Name: pluginName}
+			m.metrics.RecordRejection(pluginName)
+			m.observeRejection(ctx, pluginName, instance, funcName, err)
+			return nil, err
+		}
 	}
 
+	// The reference keeps the instance from being collected if it is replaced mid-call
+	instance.AddRef()
+	defer instance.DecRef()
+	defer m.startCall(pluginName, instance, funcName)()
+
 	start := time.Now()
-	result, err := instance.Call(ctx, funcName, args...)
+	result, err = instance.Call(ctx, funcName, args...)
 	duration := time.Since(start)
+	if err == nil || IsBreakerFailure(err) {
+		// Calls rejected for the caller's mistakes return at once and say nothing of latency
+		limiter.observe(duration)
+	}
+	m.logSlowCall(pluginName, instance.version, funcName, callID, len(args), duration, err)
+	if !errors.As(err, new(ErrFuncNotFound)) {
+		// Unknown function names are the caller's to choose and would grow the metrics without bound
+		m.metrics.RecordVersionCall(pluginName, instance.version, funcName, duration, err)
+		m.calls.Add(1)
+		if err != nil {
+			m.callErrors.Add(1)
+		}
+		if m.observer != nil {
+			m.observer.Record(ctx, pluginName, instance.version, funcName, duration, err)
+		}
+	}
 
 	if err != nil {
 		if breaker != nil {
-			breaker.RecordFailure()
+			switch {
+			case !breaker.isFailure(err):
+				breaker.recordIgnored()
+			case isTimeout(err):
+				breaker.RecordTimeout()
+			default:
+				breaker.RecordFailure()
+			}
+		}
+		var notFound ErrFuncNotFound
+		if m.currentConfig().RawCallErrors || errors.As(err, &notFound) {
+			return nil, err
+		}
+		return nil, CallError{
+			Plugin:   pluginName,
+			Version:  instance.version,
+			Function: funcName,
+			CallID:   callID,
+			Duration: duration,
+			Err:      err,
 		}
-		return nil, err
 	}
 
 	if breaker != nil {
 		breaker.RecordSuccess()
 	}
 
-	if m.metrics.IsEnabled() {
-		m.metrics.RecordMetric(pluginName, funcName, duration)
-	}
-
 	return result, nil
 }
 
-// IsCircuitBreakerOpen checks if the circuit breaker is open for a plugin
+// IsCircuitBreakerOpen checks if the circuit breaker is open for a plugin. A plugin with
+// replicas counts as open only when the breakers of all its replicas are. Like the other
+// status queries it does not admit a trial call, so polling it leaves the breaker as is.
 func (m *Manager) IsCircuitBreakerOpen(pluginName string) bool {
-	breakerVal, _ := m.breakers.Load(pluginName)
-	breaker := breakerVal.(*CircuitBreaker)
-
-	if breaker == nil {
+	if set := m.replicaSetFor(pluginName); set != nil {
+		for _, r := range set.snapshot() {
+			if !r.breaker.rejecting() {
+				return false
+			}
+		}
+		return true
+	}
+	breakerVal, ok := m.breakers.Load(pluginName)
+	if !ok {
 		return false
 	}
-	return !breaker.Allow()
+	return breakerVal.(*CircuitBreaker).rejecting()
 }
 
 // ListPlugins returns a list of all loaded plugins
@@ -223,30 +847,67 @@ func (m *Manager) ListPlugins() []PluginInfo {
 	m.plugins.Range(func(key, value interface{}) bool {
 		name := key.(string)
 		instance := value.(*PluginInstance)
+		replicas := m.replicaInfo(name, instance)
 		plugins = append(plugins, PluginInfo{
-			Name:    name,
-			Version: instance.version,
-			State:   instance.state,
+			Name:       name,
+			Version:    instance.version,
+			State:      instance.State(),
+			RefCount:   instance.GetRefs(),
+			Path:       instance.path,
+			Hash:       instance.hash,
+			Manifest:   instance.Manifest(),
+			Metadata:   instance.Metadata(),
+			ShadowPath: instance.ShadowPath(),
+			SourceURL:  instance.source,
+			Breaker:    replicas[0].Breaker,
+			Replicas:   replicas,
+
+			Size:           instance.size,
+			OpenDuration:   instance.openDuration,
+			InitDuration:   instance.initDuration,
+			LoadedAt:       instance.loadedAt,
+			PreviousLoadAt: instance.previousLoadAt,
 		})
 		return true
 	})
 	return plugins
 }
 
-// Close gracefully shuts down the manager and all plugins
+// Done returns a channel that is closed when the manager is closed or the context it
+// was created with is done
+func (m *Manager) Done() <-chan struct{} {
+	return m.ctx.Done()
+}
+
+// Close gracefully shuts down the manager and all plugins. The returned error joins every
+// failure during shutdown; failed Frees are kept as ErrPluginFree values, one per plugin.
+// Close is safe to call more than once and from several goroutines: shutdown runs once
+// and every call returns its result.
 func (m *Manager) Close() error {
+	m.closeOnce.Do(func() {
+		m.closeErr = m.shutdown()
+	})
+	return m.closeErr
+}
+
+// shutdown implements Close
+func (m *Manager) shutdown() error {
+	var errs []error
+
 	// Cancel context to signal shutdown
 	m.cancel()
 
 	// Wait for all background tasks to complete
 	if err := m.eg.Wait(); err != nil {
 		m.logger.Error("Error waiting for background tasks", "error", err)
+		errs = append(errs, fmt.Errorf("background task failed: %w", err))
 	}
 
 	// Close watcher
 	if m.watcher != nil {
 		if err := m.watcher.Close(); err != nil {
 			m.logger.Error("Error closing watcher", "error", err)
+			errs = append(errs, fmt.Errorf("failed to close watcher: %w", err))
 		}
 	}
 
@@ -256,7 +917,7 @@ func (m *Manager) Close() error {
 		breaker := value.(*CircuitBreaker)
 		if breaker != nil {
 			breaker.Close()
-			m.logger.Debug("Circuit breaker closed", "plugin", name)
+			m.pluginLogger(name).Debug("Circuit breaker closed")
 		}
 		return true
 	})
@@ -264,36 +925,111 @@ func (m *Manager) Close() error {
 	// Wait a bit for ongoing calls to complete
 	time.Sleep(100 * time.Millisecond)
 
+	// Replicas other than the registered instance are freed along with it
+	m.replicas.Range(func(key, value interface{}) bool {
+		name := key.(string)
+		for _, r := range value.(*replicaSet).snapshot()[1:] {
+			r.breaker.Close()
+			if err := m.shutdownPlugin(name, r.instance); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		m.replicas.Delete(key)
+		return true
+	})
+
 	// Clean up plugins
-	var errs []error
 	m.plugins.Range(func(key, value interface{}) bool {
 		name := key.(string)
 		instance := value.(*PluginInstance)
-		if err := instance.Free(); err != nil {
-			errs = append(errs, &ErrPluginFree{Name: name, Err: err})
+		if err := m.shutdownPlugin(name, instance); err != nil {
+			errs = append(errs, err)
 		}
 		m.plugins.Delete(key) // Explicitly remove the plugin
-		m.logger.Debug("Plugin freed", "name", name)
+		m.pluginLogger(name).Debug("Plugin freed")
+		return true
+	})
+	m.deprecated.Range(func(key, value interface{}) bool {
+		if err := m.shutdownPlugin(value.(string), key.(*PluginInstance)); err != nil {
+			errs = append(errs, err)
+		}
+		m.deprecated.Delete(key)
+		return true
+	})
+	// Freeing records an operation, so the metrics go once every instance is freed
+	m.metrics.plugins.Range(func(key, value interface{}) bool {
+		m.pruneMetrics(key.(string))
 		return true
 	})
 
-	if len(errs) > 0 {
-		return fmt.Errorf("errors during cleanup: %v", errs)
+	if m.observer != nil {
+		m.observer.Close()
+	}
+	m.loader.clear()
+	if m.loader.shadows != nil {
+		if err := m.loader.shadows.close(); err != nil {
+			m.logger.Warn("Failed to remove shadow directory", "error", err)
+		}
+	}
+	if m.auditLog != nil {
+		if err := m.auditLog.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close audit log: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// shutdownPlugin frees an instance while the manager closes and records the unload
+func (m *Manager) shutdownPlugin(name string, instance *PluginInstance) error {
+	err := m.freePlugin(name, instance.Plugin)
+	record := AuditEvent{Action: AuditUnload, Actor: ActorShutdown, Plugin: name, Version: instance.version,
+		Path: instance.path, Hash: instance.hash}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	m.record(record)
+	return err
+}
+
+// pruneMetrics drops the metrics of a plugin that is gone, unless
+// Config.RetainMetricsOnUnload keeps them
+func (m *Manager) pruneMetrics(name string) {
+	if !m.currentConfig().RetainMetricsOnUnload {
+		m.metrics.RemovePlugin(name)
 	}
-	return nil
 }
 
 // Internal methods
+
+// pluginLogger returns the manager's logger with the plugin's name bound to every entry
+func (m *Manager) pluginLogger(pluginName string) Logger {
+	return LoggerWith(m.logger, "plugin", pluginName)
+}
+
+// watchPlugins handles events on the plugin directory and current link directories, which
+// NewManager has already added to the watcher
 func (m *Manager) watchPlugins(dir string) error {
 	defer func() {
 		if r := recover(); r != nil {
 			m.logger.Error("Panic in watchPlugins", "error", r)
 		}
 	}()
+	defer m.watchHealthy.Store(false)
 
-	if err := m.watcher.Add(dir); err != nil {
-		return fmt.Errorf("failed to watch directory: %w", err)
-	}
+	dirInfo, _ := os.Stat(dir)
+
+	reloads := newDebouncer(m.currentConfig().ReloadDebounce, func(path string) {
+		if m.hotReloadWanted(path) {
+			m.handleReload(path)
+		}
+	})
+	defer reloads.stop()
+
+	// fsnotify drops the watch silently when the directory is replaced; the
+	// periodic check catches replacements that produced no event on the directory
+	check := time.NewTicker(watchCheckInterval)
+	defer check.Stop()
 
 	for {
 		select {
@@ -301,8 +1037,21 @@ func (m *Manager) watchPlugins(dir string) error {
 			if !ok {
 				return nil
 			}
-			if event.Op&fsnotify.Create == fsnotify.Create && strings.HasSuffix(event.Name, ".so") {
-				m.handleNewPlugin(event.Name)
+			if filepath.Clean(event.Name) == filepath.Clean(dir) && event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				if dirInfo, ok = m.rewatchPluginDir(dir); !ok {
+					return nil
+				}
+				continue
+			}
+			m.handleWatchEvent(event, reloads)
+		case <-check.C:
+			info, err := os.Stat(dir)
+			if err == nil && info.IsDir() && (dirInfo == nil || os.SameFile(info, dirInfo)) {
+				continue
+			}
+			var ok bool
+			if dirInfo, ok = m.rewatchPluginDir(dir); !ok {
+				return nil
 			}
 		case err, ok := <-m.watcher.Errors:
 			if !ok {
@@ -315,33 +1064,468 @@ func (m *Manager) watchPlugins(dir string) error {
 	}
 }
 
+// handleWatchEvent schedules a reload for events landing on a plugin file. Atomic deploys
+// write a temp file and rename it over the plugin, which surfaces as Create and Rename
+// events whose order differs per platform; temp files are ignored and the debouncer
+// folds the rest into one reload.
+func (m *Manager) handleWatchEvent(event fsnotify.Event, reloads *debouncer) {
+	if event.Op&(fsnotify.Create|fsnotify.Rename) == 0 {
+		return
+	}
+	if _, ok := m.currentLinks[filepath.Clean(event.Name)]; ok {
+		reloads.trigger(event.Name)
+		return
+	}
+	if isTempFile(event.Name) {
+		m.logger.Debug("Ignoring temporary file", "path", event.Name, "op", event.Op.String())
+		return
+	}
+	if _, ok := m.matchPluginFile(event.Name); ok {
+		reloads.trigger(event.Name)
+	}
+}
+
+// waitForStableFile blocks until the size and modification time of path have not changed
+// for FileStabilityWindow, or fails once FileStabilityTimeout has passed
+func (m *Manager) waitForStableFile(path string) error {
+	window := m.currentConfig().FileStabilityWindow
+	if window <= 0 {
+		return nil
+	}
+	timeout := m.currentConfig().FileStabilityTimeout
+	if timeout <= 0 {
+		timeout = DefaultFileStabilityTimeout
+	}
+	poll := min(window/4, 250*time.Millisecond)
+
+	last, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	stableSince := start
+	for {
+		select {
+		case <-m.ctx.Done():
+			return ErrManagerClosed
+		case <-time.After(poll):
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if info.Size() != last.Size() || !info.ModTime().Equal(last.ModTime()) {
+			last, stableSince = info, now
+		} else if now.Sub(stableSince) >= window {
+			return nil
+		}
+		if now.Sub(start) >= timeout {
+			return ErrFileUnstable{Path: path, Timeout: timeout}
+		}
+	}
+}
+
+// Intervals used to detect and recover a lost plugin directory watch
+var (
+	watchCheckInterval = 5 * time.Second
+	watchRetryInitial  = 100 * time.Millisecond
+	watchRetryMax      = 5 * time.Second
+)
+
+// rewatchPluginDir waits with backoff for the plugin directory to exist again, re-adds the
+// watch and rescans for plugins that appeared meanwhile. It returns false if the manager
+// closed while waiting.
+func (m *Manager) rewatchPluginDir(dir string) (os.FileInfo, bool) {
+	m.watchHealthy.Store(false)
+	m.logger.Warn("Plugin directory watch lost, waiting for the directory to return", "dir", dir)
+	_ = m.watcher.Remove(dir)
+
+	delay := watchRetryInitial
+	for {
+		select {
+		case <-m.ctx.Done():
+			return nil, false
+		case <-time.After(delay):
+		}
+
+		info, err := os.Stat(dir)
+		if err == nil && !info.IsDir() {
+			err = fmt.Errorf("%s is not a directory", dir)
+		}
+		if err == nil {
+			if err = m.watcher.Add(dir); err == nil {
+				m.watchHealthy.Store(true)
+				m.logger.Info("Plugin directory watch re-established", "dir", dir)
+				m.rescanPluginDir(dir)
+				return info, true
+			}
+		}
+		m.logger.Debug("Plugin directory unavailable", "dir", dir, "retryIn", delay, "error", err)
+		delay = min(delay*2, watchRetryMax)
+	}
+}
+
+// rescanPluginDir loads plugins that were added or changed while the watch was down
+func (m *Manager) rescanPluginDir(dir string) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		m.logger.Error("Failed to rescan plugin directory", "dir", dir, "error", err)
+		return
+	}
+	report := &LoadReport{}
+	load := func(path string) error {
+		m.handleNewPlugin(path)
+		return nil
+	}
+	if err := m.walkPluginDir(dir, root, map[string]bool{root: true}, report, ActorWatcher, load); err != nil {
+		m.logger.Error("Failed to rescan plugin directory", "dir", dir, "error", err)
+	}
+}
+
+// HotReloadHealthy reports whether the plugin directory is currently being watched.
+// It is false when hot reload is disabled and while a lost watch is being re-established.
+func (m *Manager) HotReloadHealthy() bool {
+	return m.watchHealthy.Load()
+}
+
+// hotReloadWanted reports whether the watcher acts on a changed path. With AllowHotReload
+// off the directory is watched for the plugins that turn HotReload on, and only those are
+// loaded; new plugins included.
+func (m *Manager) hotReloadWanted(path string) bool {
+	config := m.currentConfig()
+	if config.AllowHotReload {
+		return true
+	}
+	name, ok := m.currentLinks[filepath.Clean(path)]
+	if !ok {
+		name = m.preliminaryName(path)
+	}
+	if !config.hotReloadEnabled(name) {
+		m.pluginLogger(name).Info("Hot reload disabled, ignoring file", "path", path)
+		return false
+	}
+	return true
+}
+
+// handleReload reloads the plugin behind a changed current link or plugin file
+func (m *Manager) handleReload(path string) {
+	if name, ok := m.currentLinks[filepath.Clean(path)]; ok {
+		if m.currentConfig().hotReloadDisabled(name) {
+			m.pluginLogger(name).Info("Hot reload disabled, ignoring current link change", "link", path)
+			return
+		}
+		start := time.Now()
+		err := m.loadCurrentLink(name, path, ActorWatcher)
+		m.metrics.RecordOperation(name, OpReload, time.Since(start), err)
+		if err != nil {
+			m.pluginLogger(name).Error("Failed to follow current link", "link", path, "error", err)
+			return
+		}
+		m.hotReloads.Add(1)
+		return
+	}
+	m.handleNewPlugin(path)
+}
+
 func (m *Manager) handleNewPlugin(path string) {
-	pluginName := getPluginNameFromPath(path)
-	if config, exists := m.config.PluginConfigs[pluginName]; exists {
-		if err := m.LoadPluginWithConfig(path, &config); err != nil {
-			m.logger.Error("Failed to load new plugin", "path", path, "error", err)
+	// A Rename may move the plugin away rather than into place
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		m.logger.Debug("Plugin file no longer exists, skipping reload", "path", path)
+		return
+	}
+
+	pluginName := m.pluginNameFromPath(path)
+	if !m.currentConfig().IsPluginAllowed(pluginName) {
+		m.pluginLogger(pluginName).Warn("Ignoring blocked plugin", "path", path)
+		m.emit(Event{Type: EventBlocked, Plugin: pluginName, Path: path, Err: ErrPluginBlocked{Name: pluginName},
+			actor: ActorWatcher})
+		return
+	}
+
+	// A loaded plugin may opt out of hot reload; only explicit loads replace it then
+	if name := m.preliminaryName(path); m.currentConfig().hotReloadDisabled(name) {
+		if _, known := m.plugins.Load(name); known {
+			m.pluginLogger(name).Info("Hot reload disabled, ignoring file", "path", path)
+			return
 		}
-	} else {
-		if err := m.LoadPlugin(path); err != nil {
-			m.logger.Error("Failed to load new plugin", "path", path, "error", err)
+	}
+
+	// Never open a file that is still being written
+	if err := m.waitForStableFile(path); err != nil {
+		var unstable ErrFileUnstable
+		switch {
+		case errors.As(err, &unstable):
+			m.logger.Error("Giving up on plugin file that is still changing", "path", path, "error", err)
+			m.emit(Event{Type: EventLoadFailed, Plugin: pluginName, Path: path, Err: err, Reason: ReasonUnstableFile,
+				actor: ActorWatcher})
+		case !errors.Is(err, ErrManagerClosed):
+			m.logger.Warn("Plugin file became unreadable while waiting for it to settle", "path", path, "error", err)
 		}
+		return
+	}
+
+	// Deploys often re-copy identical files; skip those before touching the loader.
+	// The file has settled by now, so it is hashed in its final state.
+	if m.isUnchanged(path) {
+		m.pluginLogger(pluginName).Debug("Plugin file unchanged, skipping reload", "path", path)
+		return
+	}
+
+	start := time.Now()
+	err := m.loadPlugin(path, nil, loadOptions{actor: ActorWatcher})
+	m.metrics.RecordOperation(pluginName, OpReload, time.Since(start), err)
+	if err != nil {
+		m.logger.Error("Failed to load new plugin", "path", path, "error", err)
+		return
 	}
+	m.hotReloads.Add(1)
 }
 
+// loadPluginsFromDir loads every plugin file under dir, records what was loaded, failed
+// or skipped, and applies the StartupFailurePolicy to failures
 func (m *Manager) loadPluginsFromDir(dir string) error {
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	report := &LoadReport{}
+	defer m.loadReport.Store(report)
+
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+
+	// Collect everything first so plugins load in a deterministic order
+	var candidates []loadCandidate
+	collect := func(path string) error {
+		candidates = append(candidates, loadCandidate{
+			path: path,
+			name: m.preliminaryName(path),
+			load: func() error { return m.loadDirPlugin(path, report) },
+		})
+		return nil
+	}
+	if err := m.walkPluginDir(dir, root, map[string]bool{root: true}, report, ActorStartup, collect); err != nil {
+		return err
+	}
+	for link, name := range m.currentLinks {
+		link, name := link, name
+		candidates = append(candidates, loadCandidate{
+			path: link,
+			name: name,
+			load: func() error {
+				if err := m.loadCurrentLink(name, link, ActorStartup); err != nil {
+					return m.recordLoadFailure(report, link, name, err)
+				}
+				return nil
+			},
+		})
+	}
+	m.orderCandidates(candidates)
+	m.warnUnknownLoadOrder(candidates)
+	for _, c := range candidates {
+		if err := c.load(); err != nil {
+			return err
+		}
+	}
+
+	m.plugins.Range(func(key, _ interface{}) bool {
+		report.Loaded = append(report.Loaded, key.(string))
+		return true
+	})
+	sort.Strings(report.Loaded)
+
+	// Under RequireNamed, failures of required plugins are reported by checkRequiredPlugins
+	if len(report.Failed) > 0 {
+		m.logger.Warn("Some plugins failed to load", "failed", len(report.Failed), "loaded", len(report.Loaded))
+	}
+	return nil
+}
+
+// checkRequiredPlugins verifies that every plugin in Config.RequiredPlugins is loaded with a
+// matching version, collecting all gaps into one ErrRequiredPluginMissing. The report of
+// the startup scan, if any, supplies the load errors of required plugins that failed.
+func (m *Manager) checkRequiredPlugins(report *LoadReport) error {
+	var gaps []RequiredPluginGap
+	for _, entry := range m.currentConfig().RequiredPlugins {
+		req, err := parseRequiredPlugin(entry)
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && strings.HasSuffix(path, ".so") {
-			pluginName := getPluginNameFromPath(path)
-			if config, exists := m.config.PluginConfigs[pluginName]; exists {
-				return m.LoadPluginWithConfig(path, &config)
+		gap := RequiredPluginGap{Name: req.name, Constraint: req.constraint}
+		if val, ok := m.plugins.Load(req.name); ok {
+			version := val.(*PluginInstance).version
+			if checkVersionConstraint(req.name, version, req.constraint) == nil {
+				continue
+			}
+			gap.Version = version
+		} else if report != nil {
+			for _, f := range report.Failed {
+				if f.Name == req.name {
+					gap.Err = f.Err
+					break
+				}
 			}
-			return m.LoadPlugin(path)
 		}
+		gaps = append(gaps, gap)
+	}
+	if len(gaps) == 0 {
 		return nil
+	}
+	return ErrRequiredPluginMissing{Gaps: gaps}
+}
+
+// loadDirPlugin loads one plugin found in the directory scan; failures are returned
+// under FailFast and recorded in the report otherwise
+func (m *Manager) loadDirPlugin(path string, report *LoadReport) error {
+	if err := m.loadPlugin(path, nil, loadOptions{actor: ActorStartup}); err != nil {
+		return m.recordLoadFailure(report, path, m.preliminaryName(path), err)
+	}
+	return nil
+}
+
+// recordLoadFailure returns err under FailFast and records it in the report otherwise
+func (m *Manager) recordLoadFailure(report *LoadReport, path, name string, err error) error {
+	if m.currentConfig().StartupFailurePolicy == FailFast {
+		return err
+	}
+	m.logger.Error("Failed to load plugin", "path", path, "error", err)
+	report.Failed = append(report.Failed, LoadFailure{Path: path, Name: name, Err: err})
+	return nil
+}
+
+// walkPluginDir walks the real directory root and calls load for every plugin file, reporting
+// entries under their path below displayRoot so patterns match the layout seen from PluginDir.
+// Per-entry errors are recorded in the report instead of aborting the walk.
+func (m *Manager) walkPluginDir(displayRoot, root string, visited map[string]bool, report *LoadReport, actor string, load func(string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		display := displayRoot
+		if rel, relErr := filepath.Rel(root, path); relErr == nil && rel != "." {
+			display = filepath.Join(displayRoot, rel)
+		}
+		if err != nil {
+			if path == root {
+				return err
+			}
+			m.skipEntry(report, display, fmt.Sprintf("cannot read entry: %v", err))
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if owner, ok := m.linkManaged(display, d.IsDir()); ok {
+			report.skip(display, "managed by the current link of plugin "+owner)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		target := path
+		if d.Type()&fs.ModeSymlink != 0 {
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				m.skipEntry(report, display, fmt.Sprintf("broken symlink: %v", err))
+				return nil
+			}
+			info, err := os.Stat(resolved)
+			if err != nil {
+				m.skipEntry(report, display, fmt.Sprintf("cannot stat symlink target: %v", err))
+				return nil
+			}
+			if info.IsDir() {
+				return m.followDirSymlink(display, path, resolved, visited, report, actor, load)
+			}
+			target = resolved
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+
+		pluginName, ok := m.matchPluginFile(display)
+		if !ok {
+			return nil
+		}
+		if !m.currentConfig().IsPluginAllowed(pluginName) {
+			m.pluginLogger(pluginName).Info("Skipping blocked plugin", "path", display)
+			m.emit(Event{Type: EventBlocked, Plugin: pluginName, Path: display, Err: ErrPluginBlocked{Name: pluginName}, actor: actor})
+			report.skip(display, "blocked by configuration")
+			return nil
+		}
+		return load(target)
+	})
+}
+
+// followDirSymlink descends into a symlinked directory when FollowSymlinkDirs is set,
+// refusing links back into a directory that is already being walked
+func (m *Manager) followDirSymlink(display, path, resolved string, visited map[string]bool, report *LoadReport, actor string, load func(string) error) error {
+	if !m.currentConfig().FollowSymlinkDirs {
+		m.skipEntry(report, display, "directory symlink not followed")
+		return nil
+	}
+	parent := filepath.Dir(path)
+	if visited[resolved] || strings.HasPrefix(parent+string(filepath.Separator), resolved+string(filepath.Separator)) {
+		m.skipEntry(report, display, "symlink cycle to "+resolved)
+		return nil
+	}
+	visited[resolved] = true
+	return m.walkPluginDir(display, resolved, visited, report, actor, load)
+}
+
+// skipEntry logs and records a directory entry that could not be considered for loading
+func (m *Manager) skipEntry(report *LoadReport, path, reason string) {
+	m.logger.Warn("Skipping plugin directory entry", "path", path, "reason", reason)
+	report.skip(path, reason)
+}
+
+// isUnchanged reports whether a registered plugin was loaded from path with identical content
+func (m *Manager) isUnchanged(path string) bool {
+	var instance *PluginInstance
+	m.plugins.Range(func(_, value interface{}) bool {
+		if pi := value.(*PluginInstance); pi.path == path {
+			instance = pi
+			return false
+		}
+		return true
 	})
+	if instance == nil || instance.hash == "" {
+		return false
+	}
+	hash, err := fileSHA256(path)
+	if err != nil {
+		return false
+	}
+	return hash == instance.hash
+}
+
+// matchPluginFile checks a path against the configured file patterns and returns the plugin name
+func (m *Manager) matchPluginFile(path string) (string, bool) {
+	rel := path
+	if m.currentConfig().PluginDir != "" {
+		if r, err := filepath.Rel(m.currentConfig().PluginDir, path); err == nil && !strings.HasPrefix(r, "..") {
+			rel = r
+		}
+	}
+	return m.patterns.Match(rel)
+}
+
+// preliminaryName is the name of a plugin known before it is opened: the manifest name if
+// there is a manifest, otherwise the name derived from the path
+func (m *Manager) preliminaryName(path string) string {
+	if manifest, err := ReadManifest(path); err == nil && manifest != nil {
+		return manifest.Name
+	}
+	return m.pluginNameFromPath(path)
+}
+
+// pluginNameFromPath derives the plugin name for a path, falling back to the base name
+// when the path does not match any configured file pattern
+func (m *Manager) pluginNameFromPath(path string) string {
+	if name, ok := m.matchPluginFile(path); ok {
+		return name
+	}
+	return getPluginNameFromPath(path)
 }
 
 // Helper functions
@@ -350,30 +1534,45 @@ func getPluginNameFromPath(path string) string {
 	return strings.TrimSuffix(base, filepath.Ext(base))
 }
 
-func isHigherVersion(new, current string) bool {
-	v1 := strings.Split(strings.TrimPrefix(new, "v"), ".")
-	v2 := strings.Split(strings.TrimPrefix(current, "v"), ".")
-
-	// Ensure both version numbers have the same number of parts
-	for len(v1) < 3 {
-		v1 = append(v1, "0")
+// checkVersionConstraint rejects versions outside the configured constraint
+func checkVersionConstraint(pluginName, version, constraint string) error {
+	if constraint == "" {
+		return nil
+	}
+	vc, err := parseVersionConstraint(constraint)
+	if err != nil {
+		return err
 	}
-	for len(v2) < 3 {
-		v2 = append(v2, "0")
+	ok, err := vc.check(version)
+	if err != nil || !ok {
+		return ErrVersionConstraint{Name: pluginName, Version: version, Constraint: constraint}
 	}
+	return nil
+}
 
-	// Compare each part
-	for i := 0; i < len(v1); i++ {
-		n1, _ := strconv.Atoi(v1[i])
-		n2, _ := strconv.Atoi(v2[i])
-		if n1 > n2 {
-			return true
-		}
-		if n1 < n2 {
-			return false
+// checkRequiredFunctions rejects plugins that do not export every required function
+func checkRequiredFunctions(pluginName string, plugin *Plugin, required []string) error {
+	exported := plugin.GetFunctions()
+	var missing []string
+	for _, name := range required {
+		if !slices.Contains(exported, name) {
+			missing = append(missing, name)
 		}
 	}
-	return false
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(exported)
+	return ErrMissingFunctions{Name: pluginName, Missing: missing, Exported: exported}
+}
+
+// isHigherVersion reports whether new has higher semver precedence than current
+func isHigherVersion(new, current string) (bool, error) {
+	c, err := compareVersions(new, current)
+	if err != nil {
+		return false, err
+	}
+	return c > 0, nil
 }
 
 // EnableMetrics enables metrics collection
@@ -396,6 +1595,18 @@ func (m *Manager) GetMetrics(pluginName string) (*PluginMethodMetrics, error) {
 	return m.metrics.GetPluginMetrics(pluginName)
 }
 
+// GetMetricsSnapshot returns the metrics of a plugin as plain values, stamped with the
+// time they were captured
+func (m *Manager) GetMetricsSnapshot(pluginName string) (MetricsSnapshot, error) {
+	metrics, err := m.metrics.collect(pluginName)
+	if err != nil {
+		return MetricsSnapshot{}, err
+	}
+	snapshot := metrics.snapshot(pluginName, m.clock.Now())
+	m.addInFlight(&snapshot)
+	return snapshot, nil
+}
+
 // ResetMetrics resets all metrics
 func (m *Manager) ResetMetrics() {
 	m.metrics.plugins.Range(func(key, value interface{}) bool {
@@ -404,13 +1615,151 @@ func (m *Manager) ResetMetrics() {
 	})
 }
 
+// GetBreakerStatus reports whether the circuit breaker of a plugin rejects calls.
+//
+// Deprecated: use GetBreakerState, which tells a half-open breaker from an open one.
 func (m *Manager) GetBreakerStatus(pluginName string) bool {
-	breakerVal, _ := m.breakers.Load(pluginName)
-	breaker := breakerVal.(*CircuitBreaker)
-	if breaker == nil {
+	breakerVal, ok := m.breakers.Load(pluginName)
+	if !ok {
 		return false
 	}
-	return !breaker.Allow()
+	return breakerVal.(*CircuitBreaker).rejecting()
+}
+
+// GetBreakerState returns the state of a plugin's circuit breaker. For a plugin with
+// replicas it is the state of the first replica; PluginInfo.Replicas has the others.
+func (m *Manager) GetBreakerState(pluginName string) (CircuitState, error) {
+	if _, ok := m.plugins.Load(pluginName); !ok {
+		return StateClosed, ErrPluginNotFound{Name: pluginName}
+	}
+	val, ok := m.breakers.Load(pluginName)
+	if !ok {
+		return StateClosed, ErrPluginNotFound{Name: pluginName}
+	}
+	return val.(*CircuitBreaker).State(), nil
+}
+
+// GetBreakerInfo returns the state and statistics of a plugin's circuit breaker. For a
+// plugin with replicas it describes the breaker of the first replica.
+func (m *Manager) GetBreakerInfo(pluginName string) (BreakerInfo, error) {
+	if _, ok := m.plugins.Load(pluginName); !ok {
+		return BreakerInfo{}, ErrPluginNotFound{Name: pluginName}
+	}
+	val, ok := m.breakers.Load(pluginName)
+	if !ok {
+		return BreakerInfo{}, ErrPluginNotFound{Name: pluginName}
+	}
+	info := val.(*CircuitBreaker).StateSnapshot()
+	if limiter := m.limiterFor(pluginName); limiter != nil {
+		info.ConcurrencyRejections = limiter.rejected.Load()
+	}
+	return info, nil
+}
+
+// GetMethodBreakerStatus reports whether the circuit breaker rejects calls to one function
+// of a plugin. It is GetBreakerStatus unless the breaker has BreakerScopeMethod.
+func (m *Manager) GetMethodBreakerStatus(pluginName, funcName string) bool {
+	val, ok := m.breakers.Load(pluginName)
+	if !ok {
+		return false
+	}
+	breaker := val.(*CircuitBreaker)
+	if breaker.config().Scope == BreakerScopeMethod {
+		return breaker.rejectingMethod(funcName)
+	}
+	return breaker.rejecting()
+}
+
+// newBreaker creates a circuit breaker for a plugin on the manager's clock, with the
+// settings last given to UpdateBreakerConfig if any
+func (m *Manager) newBreaker(pluginName string, config CircuitBreakerConfig) *CircuitBreaker {
+	if val, ok := m.breakerConfigs.Load(pluginName); ok {
+		config = val.(CircuitBreakerConfig)
+	}
+	return NewCircuitBreaker(m.ctx, config, m.pluginLogger(pluginName), WithBreakerClock(m.clock))
+}
+
+// breakersFor returns the circuit breakers of a plugin, one per replica
+func (m *Manager) breakersFor(name string) (*PluginInstance, []*CircuitBreaker, error) {
+	val, ok := m.plugins.Load(name)
+	if !ok {
+		return nil, nil, ErrPluginNotFound{Name: name}
+	}
+	instance := val.(*PluginInstance)
+	if set := m.replicaSetFor(name); set != nil {
+		var breakers []*CircuitBreaker
+		for _, r := range set.snapshot() {
+			breakers = append(breakers, r.breaker)
+		}
+		return instance, breakers, nil
+	}
+	if val, ok := m.breakers.Load(name); ok {
+		return instance, []*CircuitBreaker{val.(*CircuitBreaker)}, nil
+	}
+	return instance, nil, nil
+}
+
+// ResetBreaker closes the circuit breaker of a plugin, or of all its replicas, and clears
+// the failure count without waiting for the reset interval
+func (m *Manager) ResetBreaker(pluginName string) error {
+	instance, breakers, err := m.breakersFor(pluginName)
+	if err != nil {
+		return err
+	}
+	for _, breaker := range breakers {
+		breaker.Reset()
+	}
+	m.pluginLogger(pluginName).Info("Circuit breaker reset")
+	m.emit(Event{Type: EventBreakerReset, Plugin: pluginName, Version: instance.version, Path: instance.path, Reason: ReasonManual})
+	return nil
+}
+
+// TripBreaker opens the circuit breaker of a plugin, or of all its replicas, so calls are
+// rejected with ErrCircuitOpen. The breaker recovers through half-open trial calls after
+// OpenDuration as if it had tripped on failures.
+func (m *Manager) TripBreaker(pluginName string, reason string) error {
+	instance, breakers, err := m.breakersFor(pluginName)
+	if err != nil {
+		return err
+	}
+	if reason == "" {
+		reason = ReasonManual
+	}
+	for _, breaker := range breakers {
+		breaker.Trip(reason)
+	}
+	m.pluginLogger(pluginName).Warn("Circuit breaker tripped", "reason", reason)
+	m.emit(Event{Type: EventBreakerTripped, Plugin: pluginName, Version: instance.version, Path: instance.path, Reason: reason})
+	return nil
+}
+
+// UpdateBreakerConfig replaces the circuit breaker settings of a plugin, or of all its
+// replicas, without reloading it. The breaker keeps its state and failure count, so a
+// lower MaxFailures opens it sooner; disabling it lets calls through at once. The settings
+// also apply when the plugin is reloaded, in place of its configured ones.
+func (m *Manager) UpdateBreakerConfig(pluginName string, config CircuitBreakerConfig) error {
+	if err := validateBreakerConfig(config); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	instance, breakers, err := m.breakersFor(pluginName)
+	if err != nil {
+		return err
+	}
+	config = migrateBreakerConfig(config)
+	m.breakerConfigs.Store(pluginName, config)
+	for _, breaker := range breakers {
+		breaker.Reconfigure(config)
+	}
+	m.pluginLogger(pluginName).Info("Circuit breaker reconfigured", "enabled", config.Enabled,
+		"max_failures", config.MaxFailures, "open_duration", config.OpenDuration,
+		"reset_interval", config.ResetInterval)
+	m.emit(Event{Type: EventBreakerConfigUpdated, Plugin: pluginName, Version: instance.version, Path: instance.path})
+	return nil
+}
+
+// CacheStats returns the Loader cache statistics
+func (m *Manager) CacheStats() CacheStats {
+	return m.loader.CacheStats()
 }
 
 // GetPluginPath returns the path of a loaded plugin
@@ -423,6 +1772,37 @@ func (m *Manager) GetPluginPath(name string) (string, bool) {
 	return "", false
 }
 
+// GetManifest returns the manifest of a loaded plugin, or nil if it was loaded without one
+func (m *Manager) GetManifest(name string) (*Manifest, error) {
+	val, ok := m.plugins.Load(name)
+	if !ok {
+		return nil, ErrPluginNotFound{Name: name}
+	}
+	return val.(*PluginInstance).Manifest(), nil
+}
+
+// GetMetadata returns the metadata of a loaded plugin, or nil if it exports none
+func (m *Manager) GetMetadata(name string) (*Metadata, error) {
+	val, ok := m.plugins.Load(name)
+	if !ok {
+		return nil, ErrPluginNotFound{Name: name}
+	}
+	return val.(*PluginInstance).Metadata(), nil
+}
+
+// PluginsWithCapability returns the names of loaded plugins whose metadata lists the capability
+func (m *Manager) PluginsWithCapability(capability string) []string {
+	var names []string
+	m.plugins.Range(func(key, value interface{}) bool {
+		if value.(*PluginInstance).Metadata().HasCapability(capability) {
+			names = append(names, key.(string))
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
 // GetPluginFunctions returns a list of available functions for a plugin
 func (m *Manager) GetPluginFunctions(pluginName string) ([]string, error) {
 	val, ok := m.plugins.Load(pluginName)
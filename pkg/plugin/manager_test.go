@@ -2,12 +2,19 @@ package plugin
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
 )
 
 // mock plugin implementation
@@ -217,13 +224,14 @@ func TestCircuitBreaker(t *testing.T) {
 		version: plugin.Version(),
 	}
 
+	clk := clocktest.NewFake(time.Time{})
 	m.plugins.Store(pluginName, instance)
 	m.breakers.Store(pluginName, NewCircuitBreaker(ctx, CircuitBreakerConfig{
 		Enabled:         true,
 		MaxFailures:     5,
 		ResetInterval:   time.Second,
 		TimeoutDuration: time.Second,
-	}, m.logger))
+	}, m.logger, WithBreakerClock(clk)))
 
 	// Trigger circuit breaker
 	for i := 0; i < 6; i++ {
@@ -239,7 +247,7 @@ func TestCircuitBreaker(t *testing.T) {
 	}
 
 	// Wait for reset
-	time.Sleep(2 * time.Second)
+	clk.Advance(2 * time.Second)
 
 	// Verify circuit breaker is closed
 	if m.GetBreakerStatus(pluginName) {
@@ -247,7 +255,64 @@ func TestCircuitBreaker(t *testing.T) {
 	}
 }
 
-func setupTestManager(t testing.TB) (*Manager, func()) {
+// Test breakers configured by OpenDuration and by the deprecated TimeoutDuration
+func TestCircuitBreaker_OpenDuration(t *testing.T) {
+	tests := []struct {
+		name        string
+		breaker     CircuitBreakerConfig
+		wantOpen    time.Duration
+		wantWarning bool
+	}{
+		{
+			name:     "OpenDuration",
+			breaker:  CircuitBreakerConfig{Enabled: true, MaxFailures: 1, ResetInterval: time.Second, OpenDuration: 3 * time.Second},
+			wantOpen: 3 * time.Second,
+		},
+		{
+			name:        "TimeoutDuration",
+			breaker:     CircuitBreakerConfig{Enabled: true, MaxFailures: 1, ResetInterval: time.Second, TimeoutDuration: 3 * time.Second},
+			wantOpen:    3 * time.Second,
+			wantWarning: true,
+		},
+		{
+			name: "both",
+			breaker: CircuitBreakerConfig{Enabled: true, MaxFailures: 1, ResetInterval: time.Second,
+				OpenDuration: 3 * time.Second, TimeoutDuration: time.Minute},
+			wantOpen: 3 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeOpener(t, map[string]fakeLib{
+				"v1": newFakeLib(&fakeBureau{name: "calc", version: "1.0.0"}, map[string]InvokeFunc{"Add": returning(3)}),
+			})
+			logger := &testLogger{}
+			clk := clocktest.NewFake(time.Time{})
+			m := newTestManager(t, func(config *Config) {
+				config.DefaultPluginConfig.CircuitBreaker = tt.breaker
+			}, WithLogger(logger), WithClock(clk))
+			loadTestPlugin(t, m, "calc", "v1")
+
+			if got := logger.has("WARN: CircuitBreaker TimeoutDuration is deprecated, use OpenDuration"); got != tt.wantWarning {
+				t.Errorf("Deprecation warning logged = %v, want %v", got, tt.wantWarning)
+			}
+			if err := m.TripBreaker("calc", ""); err != nil {
+				t.Fatal(err)
+			}
+			clk.Advance(tt.wantOpen - time.Millisecond)
+			if !m.GetBreakerStatus("calc") {
+				t.Fatalf("Breaker closed before %v had passed", tt.wantOpen)
+			}
+			clk.Advance(time.Millisecond)
+			if m.GetBreakerStatus("calc") {
+				t.Errorf("Breaker still open after %v", tt.wantOpen)
+			}
+		})
+	}
+}
+
+func setupTestManager(t testing.TB, configure ...func(*Config)) (*Manager, func()) {
 	dir := t.TempDir()
 	config := &Config{
 		PluginDir:     dir,
@@ -261,6 +326,9 @@ func setupTestManager(t testing.TB) (*Manager, func()) {
 			},
 		},
 	}
+	for _, change := range configure {
+		change(config)
+	}
 
 	m, err := NewManager(context.Background(), config)
 	if err != nil {
@@ -275,6 +343,56 @@ func setupTestManager(t testing.TB) (*Manager, func()) {
 	return m, cleanup
 }
 
+// newTestManager creates a manager with the default config over an empty temporary
+// PluginDir and hot reload off, changed by configure if set, and closes it when the
+// test ends
+func newTestManager(t testing.TB, configure func(*Config), opts ...ManagerOption) *Manager {
+	t.Helper()
+	config := DefaultConfig()
+	config.PluginDir = t.TempDir()
+	config.AllowHotReload = false
+	if configure != nil {
+		configure(config)
+	}
+	m, err := NewManager(context.Background(), config, opts...)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+// updateTestConfig changes the running config of m as a reload does, by storing a
+// changed copy
+func updateTestConfig(m *Manager, change func(*Config)) {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+	config := m.currentConfig().Clone()
+	change(config)
+	m.config.Store(config)
+}
+
+// loadTestPlugin writes content to name.so in the manager's PluginDir, loads it and
+// returns its path
+func loadTestPlugin(t testing.TB, m *Manager, name, content string) string {
+	t.Helper()
+	path := writeTestPlugin(t, m.currentConfig().PluginDir, name, content)
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	return path
+}
+
+// writeTestPlugin writes content to name.so in dir and returns its path
+func writeTestPlugin(t testing.TB, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name+".so")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
 // Test plugin version upgrade
 func TestPluginUpgrade(t *testing.T) {
 	ctx := context.Background()
@@ -355,6 +473,75 @@ func TestPluginUpgrade(t *testing.T) {
 }
 
 // Test concurrent plugin calls
+// Test that replaced circuit breakers are closed instead of leaking their reset goroutines
+func TestPluginUpgrade_ClosesReplacedBreaker(t *testing.T) {
+	const upgrades = 100
+	libs := make(map[string]fakeLib, upgrades)
+	for i := 1; i <= upgrades; i++ {
+		b := &fakeBureau{name: "payments", version: fmt.Sprintf("1.0.%d", i)}
+		libs[fmt.Sprintf("v%d", i)] = newFakeLib(b, map[string]InvokeFunc{})
+	}
+	useFakeOpener(t, libs)
+
+	m := newTestManager(t, func(config *Config) { config.GCInterval = 0 })
+
+	path := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+	load := func(i int) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("v%d", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.LoadPlugin(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	load(1)
+	before := runtime.NumGoroutine()
+	for i := 2; i <= upgrades; i++ {
+		load(i)
+	}
+	waitFor(t, "replaced breakers to stop", func() bool {
+		return runtime.NumGoroutine() <= before+2
+	})
+}
+
+// Test that breaker history is discarded on reload unless CarryOverOnReload is set
+func TestPluginUpgrade_BreakerCarryOver(t *testing.T) {
+	for _, carryOver := range []bool{false, true} {
+		t.Run(fmt.Sprintf("carry over %v", carryOver), func(t *testing.T) {
+			useFakeOpener(t, map[string]fakeLib{
+				"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+				"v2": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, map[string]InvokeFunc{}),
+			})
+			m := newTestManager(t, func(config *Config) {
+				config.DefaultPluginConfig.CircuitBreaker.CarryOverOnReload = carryOver
+			})
+			path := loadTestPlugin(t, m, "payments", "v1")
+			val, _ := m.breakers.Load("payments")
+			old := val.(*CircuitBreaker)
+			for i := 0; i < m.currentConfig().DefaultPluginConfig.CircuitBreaker.MaxFailures; i++ {
+				old.RecordFailure()
+			}
+
+			if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.LoadPlugin(path); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-old.done:
+			default:
+				t.Error("Expected the replaced breaker to be closed")
+			}
+			if open := m.GetBreakerStatus("payments"); open != carryOver {
+				t.Errorf("breaker open after reload = %v, want %v", open, carryOver)
+			}
+		})
+	}
+}
+
 func TestConcurrentCalls(t *testing.T) {
 	ctx := context.Background()
 	m, cleanup := setupTestManager(t)
@@ -420,6 +607,11 @@ func TestConcurrentCalls(t *testing.T) {
 	for err := range errChan {
 		t.Error(err)
 	}
+	for name := range plugins {
+		if got := m.GetInFlight(name); got != 0 {
+			t.Errorf("GetInFlight(%s) = %d after every call returned, want 0", name, got)
+		}
+	}
 }
 
 // Test graceful shutdown
@@ -525,3 +717,1177 @@ func TestGracefulShutdown(t *testing.T) {
 		t.Error("Shutdown timed out")
 	}
 }
+
+// Test that Close runs once no matter how many goroutines call it mid-traffic
+func TestClose_ConcurrentAndIdempotent(t *testing.T) {
+	ctx := context.Background()
+	b := &fakeBureau{name: "orders", version: "1.0.0", freeErr: errors.New("pool busy")}
+	useFakeOpener(t, map[string]fakeLib{
+		"orders": newFakeLib(b, map[string]InvokeFunc{
+			"Fetch": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				time.Sleep(time.Millisecond)
+				return "order", nil
+			},
+		}),
+	})
+
+	m := newTestManager(t, nil)
+	path := loadTestPlugin(t, m, "orders", "orders")
+
+	stop := make(chan struct{})
+	var callers sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		callers.Add(1)
+		go func() {
+			defer callers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := m.Call(ctx, "orders", "Fetch"); err != nil && !errors.Is(err, ErrManagerClosed) {
+					t.Errorf("Call() error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	const closers = 5
+	errs := make([]error, closers)
+	var wg sync.WaitGroup
+	for i := 0; i < closers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = m.Close()
+		}(i)
+	}
+	wg.Wait()
+	close(stop)
+	callers.Wait()
+
+	for i, err := range errs {
+		var freeErr ErrPluginFree
+		if !errors.As(err, &freeErr) || err != errs[0] {
+			t.Errorf("Close() #%d = %v, want the first call's ErrPluginFree", i, err)
+		}
+	}
+	if err := m.Close(); err != errs[0] {
+		t.Errorf("Close() after shutdown = %v, want %v", err, errs[0])
+	}
+	if _, err := m.Call(ctx, "orders", "Fetch"); !errors.Is(err, ErrManagerClosed) {
+		t.Errorf("Call after Close error = %v, want ErrManagerClosed", err)
+	}
+	if err := m.LoadPlugin(path); !errors.Is(err, ErrManagerClosed) {
+		t.Errorf("LoadPlugin after Close error = %v, want ErrManagerClosed", err)
+	}
+}
+
+// blockingBureau is a plugin whose Free hangs until released
+type blockingBureau struct {
+	fakeBureau
+	release chan struct{}
+}
+
+func (b *blockingBureau) Free() error {
+	<-b.release
+	return b.fakeBureau.Free()
+}
+
+// Test that a plugin whose Free hangs cannot stall Close
+func TestClose_FreeTimeout(t *testing.T) {
+	stuck := &blockingBureau{fakeBureau: fakeBureau{name: "stuck", version: "1.0.0"}, release: make(chan struct{})}
+	defer close(stuck.release)
+	healthy := &fakeBureau{name: "healthy", version: "1.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"stuck":   newFakeLib(stuck, map[string]InvokeFunc{}),
+		"healthy": newFakeLib(healthy, map[string]InvokeFunc{}),
+	})
+
+	logger := &testLogger{}
+	m := newTestManager(t, func(config *Config) { config.FreeTimeout = 50 * time.Millisecond }, WithLogger(logger))
+	for _, name := range []string{"stuck", "healthy"} {
+		loadTestPlugin(t, m, name, name)
+	}
+
+	start := time.Now()
+	err := m.Close()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Close() took %v", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "failed to free plugin stuck") {
+		t.Errorf("Close() error = %v, want the abandoned Free reported", err)
+	}
+	if !healthy.isFreed() {
+		t.Error("Expected the remaining plugins to be freed")
+	}
+	if !logger.has("ERROR: Plugin Free did not return in time, abandoning it") {
+		t.Error("Expected the abandoned plugin to be logged")
+	}
+
+	// Discarded and unloaded plugins go through the same bounded free
+	updateTestConfig(m, func(config *Config) { config.FreeTimeout = 10 * time.Millisecond })
+	freeErr := m.freePlugin("stuck", NewPlugin(stuck))
+	var pluginFree ErrPluginFree
+	if !errors.As(freeErr, &pluginFree) || !errors.Is(freeErr, context.DeadlineExceeded) {
+		t.Errorf("freePlugin() error = %v, want ErrPluginFree wrapping context.DeadlineExceeded", freeErr)
+	}
+}
+
+// Test that blocked plugins are refused before loading
+func TestLoadPlugin_Blocked(t *testing.T) {
+	m, cleanup := setupTestManager(t, func(config *Config) { config.BlockedPlugins = []string{"payments"} })
+	defer cleanup()
+
+	events, unsubscribe := m.Subscribe(1)
+	defer unsubscribe()
+
+	err := m.LoadPlugin(filepath.Join(m.currentConfig().PluginDir, "payments.so"))
+	if _, ok := err.(ErrPluginBlocked); !ok {
+		t.Fatalf("Expected ErrPluginBlocked, got %v", err)
+	}
+
+	select {
+	case e := <-events:
+		if e.Type != EventBlocked || e.Plugin != "payments" {
+			t.Errorf("Unexpected event: %+v", e)
+		}
+	default:
+		t.Error("Expected a blocked event")
+	}
+}
+
+// testLogger records log messages for assertions
+type testLogger struct {
+	mu      sync.Mutex
+	entries []string
+	args    [][]interface{} // the arguments of each entry
+}
+
+func (l *testLogger) record(level, msg string, args []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, level+": "+msg)
+	l.args = append(l.args, args)
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.record("DEBUG", msg, args) }
+func (l *testLogger) Info(msg string, args ...interface{})  { l.record("INFO", msg, args) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.record("WARN", msg, args) }
+func (l *testLogger) Error(msg string, args ...interface{}) { l.record("ERROR", msg, args) }
+
+// fields returns the arguments of the entries equal to entry, as key-value maps
+func (l *testLogger) fields(entry string) []map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var all []map[string]interface{}
+	for i, e := range l.entries {
+		if e != entry {
+			continue
+		}
+		fields := make(map[string]interface{})
+		for j := 0; j+1 < len(l.args[i]); j += 2 {
+			fields[fmt.Sprint(l.args[i][j])] = l.args[i][j+1]
+		}
+		all = append(all, fields)
+	}
+	return all
+}
+
+func (l *testLogger) has(entry string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range l.entries {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// Test that identical plugin files do not trigger a reload
+func TestHandleNewPlugin_UnchangedContent(t *testing.T) {
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+	logger := &testLogger{}
+	m.SetLogger(logger)
+
+	path := filepath.Join(m.currentConfig().PluginDir, "test-plugin.so")
+	if err := os.WriteFile(path, []byte("plugin v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := fileSHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugin := NewMockPlugin("1.0.0", map[string]interface{}{"TestFunc": "result"})
+	m.plugins.Store("test-plugin", &PluginInstance{
+		Plugin:  plugin,
+		state:   StateActive,
+		version: plugin.Version(),
+		path:    path,
+		hash:    hash,
+	})
+
+	m.handleNewPlugin(path)
+	if !logger.has("DEBUG: Plugin file unchanged, skipping reload") {
+		t.Error("Expected unchanged file to be skipped")
+	}
+	if logger.has("ERROR: Failed to load new plugin") {
+		t.Error("Expected no load attempt for unchanged file")
+	}
+
+	// Changed content must go through the loader
+	if err := os.WriteFile(path, []byte("plugin v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.handleNewPlugin(path)
+	if !logger.has("ERROR: Failed to load new plugin") {
+		t.Error("Expected changed file to be loaded")
+	}
+
+	if info := m.ListPlugins(); len(info) != 1 || info[0].Hash != hash {
+		t.Errorf("Expected hash %s in PluginInfo, got %+v", hash, info)
+	}
+}
+
+// Test that a failed Init leaves the previous version active
+func TestPluginUpgrade_InitFailureKeepsOldVersion(t *testing.T) {
+	ctx := context.Background()
+	v1 := &fakeBureau{name: "test-plugin", version: "1.0.0"}
+	v2 := &fakeBureau{name: "test-plugin", version: "2.0.0", initErr: fmt.Errorf("dial failed")}
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(v1, map[string]InvokeFunc{"TestFunc": returning("v1 result")}),
+		"v2": newFakeLib(v2, map[string]InvokeFunc{"TestFunc": returning("v2 result")}),
+	})
+
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "test-plugin.so")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatalf("LoadPlugin(v1) error = %v", err)
+	}
+
+	events, unsubscribe := m.Subscribe(4)
+	defer unsubscribe()
+
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err == nil {
+		t.Fatal("Expected LoadPlugin(v2) to fail")
+	}
+
+	result, err := m.Call(ctx, "test-plugin", "TestFunc")
+	if err != nil || result != "v1 result" {
+		t.Errorf("Call() = %v, %v; want v1 result", result, err)
+	}
+	plugins := m.ListPlugins()
+	if len(plugins) != 1 || plugins[0].Version != "1.0.0" || plugins[0].State != StateActive {
+		t.Errorf("Expected v1 to remain active, got %+v", plugins)
+	}
+	if !v2.isFreed() {
+		t.Error("Expected failed v2 to be freed")
+	}
+
+	select {
+	case e := <-events:
+		if e.Type != EventUpgradeFailed || e.Err == nil {
+			t.Errorf("Unexpected event: %+v", e)
+		}
+	default:
+		t.Error("Expected an upgrade failed event")
+	}
+}
+
+// flakyBureau fails Init until it has been called succeedOn times
+type flakyBureau struct {
+	fakeBureau
+	succeedOn int
+	calls     int
+	freedAt   int // Init calls made when Free ran
+}
+
+func (b *flakyBureau) Init(args ...interface{}) error {
+	b.calls++
+	if b.calls < b.succeedOn {
+		return fmt.Errorf("dns lookup failed (attempt %d)", b.calls)
+	}
+	return nil
+}
+
+func (b *flakyBureau) Free() error {
+	b.freedAt = b.calls
+	return b.fakeBureau.Free()
+}
+
+// Test that Init is retried with backoff and the plugin is freed only after the last attempt
+func TestLoadPlugin_InitRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		succeedOn  int
+		config     PluginSpecificConfig
+		wantErr    bool
+		wantCalls  int
+		wantLogged string
+	}{
+		{name: "single attempt by default", succeedOn: 2, wantErr: true, wantCalls: 1},
+		{name: "succeeds on retry", succeedOn: 3,
+			config:    PluginSpecificConfig{InitRetries: 2, InitRetryBackoff: time.Millisecond},
+			wantCalls: 3, wantLogged: "WARN: Plugin initialization failed, retrying"},
+		{name: "retries exhausted", succeedOn: 5,
+			config:  PluginSpecificConfig{InitRetries: 2, InitRetryBackoff: time.Millisecond},
+			wantErr: true, wantCalls: 3, wantLogged: "ERROR: Plugin initialization failed"},
+		{name: "bounded by init timeout", succeedOn: 5,
+			config:  PluginSpecificConfig{InitRetries: 4, InitRetryBackoff: 20 * time.Millisecond, InitTimeout: 50 * time.Millisecond},
+			wantErr: true, wantCalls: 2, wantLogged: "ERROR: Plugin initialization failed, init timeout leaves no time to retry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &flakyBureau{fakeBureau: fakeBureau{name: "flaky", version: "1.0.0"}, succeedOn: tt.succeedOn}
+			useFakeOpener(t, map[string]fakeLib{
+				"flaky": newFakeLib(b, map[string]InvokeFunc{}),
+			})
+			logger := &testLogger{}
+			m := newTestManager(t, nil, WithLogger(logger))
+
+			path := writeTestPlugin(t, m.currentConfig().PluginDir, "flaky", "flaky")
+			err := m.LoadPluginWithConfig(path, &tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadPluginWithConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if b.calls != tt.wantCalls {
+				t.Errorf("Init called %d times, want %d", b.calls, tt.wantCalls)
+			}
+			if tt.wantErr && b.freedAt != tt.wantCalls {
+				t.Errorf("Free ran after %d Init calls, want only after the last (%d)", b.freedAt, tt.wantCalls)
+			}
+			if tt.wantLogged != "" && !logger.has(tt.wantLogged) {
+				t.Errorf("Expected log %q", tt.wantLogged)
+			}
+		})
+	}
+}
+
+// Test that versions outside the configured constraint are rejected before Init
+func TestLoadPlugin_VersionConstraint(t *testing.T) {
+	v3 := &fakeBureau{name: "example-plugin", version: "3.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"v3": newFakeLib(v3, map[string]InvokeFunc{}),
+	})
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "example-plugin.so")
+	if err := os.WriteFile(path, []byte("v3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := PluginSpecificConfig{VersionConstraint: ">=1.2.0 <2.0.0"}
+	err := m.LoadPluginWithConfig(path, &config)
+	var constraintErr ErrVersionConstraint
+	if !errors.As(err, &constraintErr) || constraintErr.Version != "3.0.0" {
+		t.Fatalf("Expected ErrVersionConstraint, got %v", err)
+	}
+	if !v3.isFreed() {
+		t.Error("Expected rejected plugin to be freed")
+	}
+	if len(m.ListPlugins()) != 0 {
+		t.Error("Expected no plugin to be registered")
+	}
+
+	// Tightening the constraint and loading the running plugin's file again leaves it running
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	v3.mu.Lock()
+	v3.freed = false // by the rejected load above
+	v3.mu.Unlock()
+	config.VersionConstraint = "<3.0.0"
+	if err := m.LoadPluginWithConfig(path, &config); !errors.As(err, &constraintErr) {
+		t.Fatalf("Expected ErrVersionConstraint, got %v", err)
+	}
+	if v3.isFreed() {
+		t.Error("Rejecting the running plugin's file freed it")
+	}
+	if plugins := m.ListPlugins(); len(plugins) != 1 || plugins[0].Version != "3.0.0" {
+		t.Errorf("ListPlugins() = %+v, want 3.0.0 still loaded", plugins)
+	}
+}
+
+// Test that plugins missing a required function are rejected on load and on upgrade
+func TestLoadPlugin_RequiredFunctions(t *testing.T) {
+	ctx := context.Background()
+	v1 := &fakeBureau{name: "worker", version: "1.0.0"}
+	v2 := &fakeBureau{name: "worker", version: "2.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(v1, map[string]InvokeFunc{"Process": returning("v1"), "Status": returning("ok")}),
+		"v2": newFakeLib(v2, map[string]InvokeFunc{"Status": returning("ok"), "Proces": returning("v2")}),
+	})
+
+	m := newTestManager(t, func(config *Config) {
+		config.PluginConfigs["worker"] = PluginSpecificConfig{RequiredFunctions: []string{"Process", "Status"}}
+	})
+
+	// Initial load of a plugin lacking Process
+	path := writeTestPlugin(t, m.currentConfig().PluginDir, "worker", "v2")
+	err := m.LoadPlugin(path)
+	var missing ErrMissingFunctions
+	if !errors.As(err, &missing) {
+		t.Fatalf("LoadPlugin() error = %v, want ErrMissingFunctions", err)
+	}
+	if !reflect.DeepEqual(missing.Missing, []string{"Process"}) || !reflect.DeepEqual(missing.Exported, []string{"Proces", "Status"}) {
+		t.Errorf("Missing = %v, Exported = %v", missing.Missing, missing.Exported)
+	}
+	if len(m.ListPlugins()) != 0 {
+		t.Error("Expected no plugin to be registered")
+	}
+
+	// A broken v2 must not displace a working v1
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatalf("LoadPlugin(v1) error = %v", err)
+	}
+
+	// Rejecting the unchanged file of the running plugin leaves that plugin alone
+	strict := PluginSpecificConfig{RequiredFunctions: []string{"Missing"}}
+	if err := m.LoadPluginWithConfig(path, &strict); !errors.As(err, &missing) {
+		t.Fatalf("LoadPluginWithConfig(v1) error = %v, want ErrMissingFunctions", err)
+	}
+	if v1.isFreed() {
+		t.Fatal("Rejecting the running plugin's file freed it")
+	}
+
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); !errors.As(err, &missing) {
+		t.Fatalf("LoadPlugin(v2) error = %v, want ErrMissingFunctions", err)
+	}
+	result, err := m.Call(ctx, "worker", "Process")
+	if err != nil || result != "v1" {
+		t.Errorf("Call() = %v, %v; want v1", result, err)
+	}
+	if v1.isFreed() {
+		t.Error("Expected v1 to stay loaded")
+	}
+}
+
+// Test that plugins are registered under the name their Bureau declares
+func TestLoadPlugin_RegistersDeclaredName(t *testing.T) {
+	ctx := context.Background()
+	b := &fakeBureau{name: "example-plugin", version: "1.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"example": newFakeLib(b, map[string]InvokeFunc{"Add": returning(3)}),
+	})
+
+	m, cleanup := setupTestManager(t, func(config *Config) {
+		config.PluginConfigs = map[string]PluginSpecificConfig{
+			"example-plugin": {VersionConstraint: ">=2.0.0"},
+		}
+	})
+	defer cleanup()
+	logger := &testLogger{}
+	m.SetLogger(logger)
+
+	path := filepath.Join(m.currentConfig().PluginDir, "plugin.so")
+	if err := os.WriteFile(path, []byte("example"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The per-plugin config is looked up by the declared name
+	var constraintErr ErrVersionConstraint
+	if err := m.LoadPlugin(path); !errors.As(err, &constraintErr) || constraintErr.Name != "example-plugin" {
+		t.Fatalf("Expected version constraint of example-plugin to apply, got %v", err)
+	}
+
+	updateTestConfig(m, func(config *Config) { config.PluginConfigs = nil })
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	if !logger.has("WARN: Plugin file name differs from its declared name, registering under the declared name") {
+		t.Error("Expected a warning about the mismatched file name")
+	}
+	if result, err := m.Call(ctx, "example-plugin", "Add"); err != nil || result != 3 {
+		t.Errorf("Call(example-plugin) = %v, %v", result, err)
+	}
+	if _, err := m.Call(ctx, "plugin", "Add"); !IsPluginNotFoundError(err) {
+		t.Errorf("Expected file name not to be registered, got %v", err)
+	}
+	if got, ok := m.GetPluginPath("example-plugin"); !ok || got != path {
+		t.Errorf("GetPluginPath(example-plugin) = %s, %v", got, ok)
+	}
+	if m.GetBreakerStatus("example-plugin") {
+		t.Error("Expected a closed breaker keyed by the declared name")
+	}
+}
+
+// Test that blocking the declared name applies even when the file name differs
+func TestLoadPlugin_BlocksDeclaredName(t *testing.T) {
+	b := &fakeBureau{name: "payments", version: "1.0.0"}
+	useFakeOpener(t, map[string]fakeLib{"payments": newFakeLib(b, nil)})
+
+	m, cleanup := setupTestManager(t, func(config *Config) { config.BlockedPlugins = []string{"payments"} })
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "plugin.so")
+	if err := os.WriteFile(path, []byte("payments"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var blocked ErrPluginBlocked
+	if err := m.LoadPlugin(path); !errors.As(err, &blocked) || blocked.Name != "payments" {
+		t.Fatalf("Expected ErrPluginBlocked for payments, got %v", err)
+	}
+	if !b.isFreed() {
+		t.Error("Expected blocked plugin to be freed")
+	}
+}
+
+// Test how a second artifact declaring a loaded plugin's name is handled
+func TestLoadPlugin_NameCollision(t *testing.T) {
+	ctx := context.Background()
+	original := &fakeBureau{name: "payments", version: "1.0.0"}
+	fork := &fakeBureau{name: "payments", version: "1.5.0"}
+	oldFork := &fakeBureau{name: "payments", version: "0.9.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"original": newFakeLib(original, map[string]InvokeFunc{"Pay": returning("original")}),
+		"fork":     newFakeLib(fork, map[string]InvokeFunc{"Pay": returning("fork")}),
+		"old-fork": newFakeLib(oldFork, map[string]InvokeFunc{"Pay": returning("old fork")}),
+	})
+
+	tests := []struct {
+		name     string
+		policy   NameCollisionPolicy
+		artifact string
+		force    bool
+		wantErr  bool
+		want     string
+	}{
+		{name: "reject", policy: NameCollisionReject, artifact: "fork", wantErr: true, want: "original"},
+		{name: "prefer newer version replaces", policy: NameCollisionPreferNewerVersion, artifact: "fork", want: "fork"},
+		{name: "prefer newer version keeps", policy: NameCollisionPreferNewerVersion, artifact: "old-fork", want: "original"},
+		{name: "force overrides reject", policy: NameCollisionReject, artifact: "old-fork", force: true, want: "old fork"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, cleanup := setupTestManager(t, func(config *Config) { config.NameCollisionPolicy = tt.policy })
+			defer cleanup()
+			events, unsubscribe := m.Subscribe(10)
+			defer unsubscribe()
+
+			first := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+			second := filepath.Join(m.currentConfig().PluginDir, "payments-"+tt.artifact+".so")
+			if err := os.WriteFile(first, []byte("original"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(second, []byte(tt.artifact), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.LoadPlugin(first); err != nil {
+				t.Fatal(err)
+			}
+
+			var err error
+			if tt.force {
+				err = m.ForceLoadPlugin(second)
+			} else {
+				err = m.LoadPlugin(second)
+			}
+			var collision ErrNameCollision
+			if gotErr := errors.As(err, &collision); gotErr != tt.wantErr {
+				t.Fatalf("LoadPlugin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && (collision.Path != second || collision.ExistingPath != first) {
+				t.Errorf("ErrNameCollision = %+v", collision)
+			}
+
+			if result, err := m.Call(ctx, "payments", "Pay"); err != nil || result != tt.want {
+				t.Errorf("Call() = %v, %v, want %s", result, err, tt.want)
+			}
+
+			if !tt.force {
+				var found bool
+				for len(events) > 0 {
+					if e := <-events; e.Type == EventNameCollision {
+						found = e.Path == second && e.ConflictPath == first
+					}
+				}
+				if !found {
+					t.Error("Expected a name collision event naming both paths")
+				}
+			}
+		})
+	}
+}
+
+// Test the startup failure policies with one good and one corrupt plugin
+func TestNewManager_StartupFailurePolicy(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"good": newFakeLib(&fakeBureau{name: "good", version: "1.0.0"}, nil),
+	})
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "good.so"), []byte("good"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "corrupt.so"), []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		policy   StartupFailurePolicy
+		required []string
+		wantErr  bool
+	}{
+		{name: "fail fast", policy: FailFast, wantErr: true},
+		{name: "continue and report", policy: ContinueAndReport},
+		{name: "require named, optional failure", policy: RequireNamed, required: []string{"good"}},
+		{name: "require named, required failure", policy: RequireNamed, required: []string{"corrupt"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.PluginDir = dir
+			config.AllowHotReload = false
+			config.StartupFailurePolicy = tt.policy
+			config.RequiredPlugins = tt.required
+
+			m, report, err := NewManagerWithReport(context.Background(), config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewManagerWithReport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if m != nil {
+					t.Error("Expected no manager on failure")
+				}
+				return
+			}
+			defer m.Close()
+
+			if !reflect.DeepEqual(report.Loaded, []string{"good"}) {
+				t.Errorf("Loaded = %v, want [good]", report.Loaded)
+			}
+			if len(report.Failed) != 1 || report.Failed[0].Name != "corrupt" {
+				t.Fatalf("Failed = %+v, want the corrupt plugin", report.Failed)
+			}
+			if report.Err() == nil {
+				t.Error("Expected Err() to report the failure")
+			}
+			if m.LoadReport() != report {
+				t.Error("Expected Manager.LoadReport() to return the startup report")
+			}
+		})
+	}
+}
+
+// Test that startup fails with every gap in RequiredPlugins and frees what did load
+func TestNewManager_RequiredPlugins(t *testing.T) {
+	good := &fakeBureau{name: "good", version: "1.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"good": newFakeLib(good, nil),
+	})
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "good.so"), []byte("good"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "corrupt.so"), []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DefaultConfig()
+	config.PluginDir = dir
+	config.AllowHotReload = false
+	config.StartupFailurePolicy = ContinueAndReport
+	config.RequiredPlugins = []string{"good", "corrupt", "auth"}
+
+	m, report, err := NewManagerWithReport(context.Background(), config)
+	var missing ErrRequiredPluginMissing
+	if !errors.As(err, &missing) {
+		t.Fatalf("NewManagerWithReport() error = %v, want ErrRequiredPluginMissing", err)
+	}
+	if m != nil {
+		t.Error("Expected no manager on failure")
+	}
+	if report == nil || len(report.Failed) != 1 {
+		t.Errorf("Expected the report of the scan, got %+v", report)
+	}
+	if len(missing.Gaps) != 2 {
+		t.Fatalf("Gaps = %+v, want corrupt and auth", missing.Gaps)
+	}
+	if gap := missing.Gaps[0]; gap.Name != "corrupt" || gap.Err == nil {
+		t.Errorf("Gaps[0] = %+v, want the load failure of corrupt", gap)
+	}
+	if gap := missing.Gaps[1]; gap.Name != "auth" || gap.Err != nil {
+		t.Errorf("Gaps[1] = %+v, want auth not loaded", gap)
+	}
+	if !good.isFreed() {
+		t.Error("Expected the loaded plugin to be freed")
+	}
+
+	// A loaded plugin outside the constraint is a gap too
+	config.RequiredPlugins = []string{"good@>=2.0.0"}
+	_, _, err = NewManagerWithReport(context.Background(), config)
+	if !errors.As(err, &missing) || len(missing.Gaps) != 1 || missing.Gaps[0].Version != "1.0.0" {
+		t.Fatalf("NewManagerWithReport() error = %v, want a version gap", err)
+	}
+
+	config.RequiredPlugins = []string{"good@^1.0"}
+	m, err = NewManager(context.Background(), config)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	m.Close()
+}
+
+// countingBureau counts Init calls
+type countingBureau struct {
+	fakeBureau
+	inits atomic.Int32
+}
+
+func (b *countingBureau) Init(args ...interface{}) error {
+	b.inits.Add(1)
+	return nil
+}
+
+// Test that files dropped while NewManager scans are loaded exactly once
+func TestNewManager_ConcurrentDropsDuringStartup(t *testing.T) {
+	const plugins = 20
+	bureaus := make([]*countingBureau, plugins+1)
+	libs := make(map[string]fakeLib, plugins+1)
+	for i := range bureaus {
+		name := fmt.Sprintf("plugin-%02d", i)
+		bureaus[i] = &countingBureau{fakeBureau: fakeBureau{name: name, version: "1.0.0"}}
+		libs[name] = newFakeLib(bureaus[i], map[string]InvokeFunc{})
+	}
+	useFakeOpener(t, libs)
+	// A slow open widens the window in which the scan and the watcher overlap
+	fastOpen := openPlugin
+	openPlugin = func(path string) (symbolLookup, error) {
+		time.Sleep(2 * time.Millisecond)
+		return fastOpen(path)
+	}
+
+	dir := t.TempDir()
+	config := DefaultConfig()
+	config.PluginDir = dir
+	config.AllowHotReload = true
+	config.ReloadDebounce = 0
+	config.FileStabilityWindow = 0
+	config.StartupFailurePolicy = ContinueAndReport
+
+	// Each file is renamed into place, so the watcher and the scan both see it whole
+	dropped := make(chan struct{})
+	go func() {
+		defer close(dropped)
+		for i := 0; i < plugins; i++ {
+			name := bureaus[i].name
+			tmp := filepath.Join(dir, "."+name+".tmp")
+			if err := os.WriteFile(tmp, []byte(name), 0644); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := os.Rename(tmp, filepath.Join(dir, name+".so")); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	m, err := NewManager(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	<-dropped
+
+	waitFor(t, "every dropped plugin to load", func() bool {
+		return len(m.ListPlugins()) == plugins
+	})
+
+	// Callers racing the watcher on a new file register it once as well
+	late := bureaus[plugins]
+	path := filepath.Join(dir, late.name+".so")
+	if err := os.WriteFile(path, []byte(late.name), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.LoadPlugin(path); err != nil {
+				t.Errorf("LoadPlugin() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	time.Sleep(50 * time.Millisecond) // let late watcher events settle
+
+	for _, b := range bureaus {
+		if n := b.inits.Load(); n != 1 {
+			t.Errorf("%s initialized %d times, want 1", b.name, n)
+		}
+		// A duplicate load discarded after losing the race would free the shared Bureau
+		if b.isFreed() {
+			t.Errorf("%s was freed while registered", b.name)
+		}
+	}
+	m.deprecated.Range(func(key, value interface{}) bool {
+		t.Errorf("Unexpected deprecated instance of %s", value)
+		return true
+	})
+}
+
+// Test that the directory watch recovers after the plugin dir is deleted and recreated
+func TestWatchPlugins_ReestablishesWatch(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"payments": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, nil),
+	})
+	origCheck, origInitial := watchCheckInterval, watchRetryInitial
+	watchCheckInterval, watchRetryInitial = 20*time.Millisecond, 10*time.Millisecond
+	t.Cleanup(func() { watchCheckInterval, watchRetryInitial = origCheck, origInitial })
+
+	dir := filepath.Join(t.TempDir(), "plugins")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultConfig()
+	config.PluginDir = dir
+	config.AllowHotReload = true
+
+	m, err := NewManager(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	waitFor(t, "watch to start", m.HotReloadHealthy)
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "watch loss to be detected", func() bool { return !m.HotReloadHealthy() })
+
+	// A plugin that arrives with the recreated directory is picked up by the rescan
+	staging := dir + ".new"
+	if err := os.Mkdir(staging, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(staging, "payments.so"), []byte("payments"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "watch to be re-established", m.HotReloadHealthy)
+	waitFor(t, "rescan to load the plugin", func() bool {
+		_, ok := m.GetPluginPath("payments")
+		return ok
+	})
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Test that a plugin file is only loaded once it stops changing
+func TestHandleNewPlugin_WaitsForStableFile(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"payments-complete": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, nil),
+	})
+
+	m, cleanup := setupTestManager(t, func(config *Config) { config.FileStabilityWindow = 100 * time.Millisecond })
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+	if err := os.WriteFile(path, []byte("payments"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Finish the "copy" while the manager waits for the file to settle
+	go func() {
+		time.Sleep(40 * time.Millisecond)
+		os.WriteFile(path, []byte("payments-complete"), 0644)
+	}()
+	m.handleNewPlugin(path)
+	if _, ok := m.GetPluginPath("payments"); !ok {
+		t.Fatal("Expected the completed file to be loaded")
+	}
+
+	// A file that never settles is given up with a LoadFailed event
+	updateTestConfig(m, func(config *Config) { config.FileStabilityTimeout = 200 * time.Millisecond })
+	events, unsubscribe := m.Subscribe(10)
+	defer unsubscribe()
+	growing := filepath.Join(m.currentConfig().PluginDir, "growing.so")
+	if err := os.WriteFile(growing, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		content := []byte("x")
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+				content = append(content, 'x')
+				os.WriteFile(growing, content, 0644)
+			}
+		}
+	}()
+	m.handleNewPlugin(growing)
+	close(stop)
+	<-done
+
+	select {
+	case e := <-events:
+		var unstable ErrFileUnstable
+		if e.Type != EventLoadFailed || e.Reason != ReasonUnstableFile || !errors.As(e.Err, &unstable) {
+			t.Errorf("Event = %+v, want LoadFailed for an unstable file", e)
+		}
+	default:
+		t.Error("Expected a LoadFailed event")
+	}
+}
+
+func TestManager_ResetAndTripBreaker(t *testing.T) {
+	m, _ := newAdminManager(t, "")
+	rec := &auditRecorder{}
+	m.audit = rec
+	events, unsubscribe := m.Subscribe(4)
+	defer unsubscribe()
+
+	if err := m.TripBreaker("payments", "bad deploy at 14:00"); err != nil {
+		t.Fatalf("TripBreaker() error = %v", err)
+	}
+	if e := awaitEvent(t, events, EventBreakerTripped); e.Plugin != "payments" || e.Reason != "bad deploy at 14:00" {
+		t.Errorf("Tripped event = %+v", e)
+	}
+	if _, err := m.Call(context.Background(), "payments", "Pay"); !errors.As(err, new(ErrCircuitOpen)) {
+		t.Errorf("Call() error = %v, want ErrCircuitOpen", err)
+	}
+	if b := m.DumpState().Plugins[0].Instances[0].Breaker; b.State != "open" || b.Reason != "bad deploy at 14:00" {
+		t.Errorf("Dumped breaker = %+v, want open with the reason", b)
+	}
+
+	if err := m.ResetBreaker("payments"); err != nil {
+		t.Fatalf("ResetBreaker() error = %v", err)
+	}
+	if e := awaitEvent(t, events, EventBreakerReset); e.Reason != ReasonManual {
+		t.Errorf("Reset event = %+v", e)
+	}
+	if _, err := m.Call(context.Background(), "payments", "Pay"); err != nil {
+		t.Errorf("Call() after reset error = %v", err)
+	}
+	if b := m.DumpState().Plugins[0].Instances[0].Breaker; b.State != "closed" || b.Failures != 0 || b.Reason != "" {
+		t.Errorf("Dumped breaker = %+v, want closed without failures", b)
+	}
+	if len(rec.find(AuditBreakerTripped)) != 1 || len(rec.find(AuditBreakerReset)) != 1 {
+		t.Errorf("Audit records = %+v", rec.events)
+	}
+
+	for _, err := range []error{m.ResetBreaker("orders"), m.TripBreaker("orders", "")} {
+		if !errors.As(err, new(ErrPluginNotFound)) {
+			t.Errorf("Unknown plugin error = %v, want ErrPluginNotFound", err)
+		}
+	}
+}
+
+// Test that polling breaker status neither admits a call to an open breaker nor takes
+// the trial call of a half-open one; only a call does
+func TestManager_StatusQueriesDoNotMutateBreaker(t *testing.T) {
+	failing := func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return nil, errors.New("downstream unavailable")
+	}
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": failing}),
+	})
+	clk := clocktest.NewFake(time.Time{})
+	m := newTestManager(t, func(config *Config) { config.EnableMetrics = true }, WithClock(clk))
+	loadTestPlugin(t, m, "payments", "v1")
+
+	ctx := context.Background()
+	maxFailures := m.currentConfig().DefaultPluginConfig.CircuitBreaker.MaxFailures
+	for i := 0; i < maxFailures; i++ {
+		m.Call(ctx, "payments", "Pay")
+	}
+
+	val, _ := m.breakers.Load("payments")
+	breaker := val.(*CircuitBreaker)
+	poll := func() {
+		for i := 0; i < 100; i++ {
+			m.GetBreakerStatus("payments")
+			m.IsCircuitBreakerOpen("payments")
+			m.GetMethodBreakerStatus("payments", "Pay")
+			m.GetBreakerState("payments")
+			m.GetBreakerInfo("payments")
+			m.ListPlugins()
+			m.DumpState()
+		}
+	}
+	poll()
+	if snap := breaker.StateSnapshot(); snap.State != StateOpen || snap.Failures != int32(maxFailures) || snap.Rejected != 0 {
+		t.Fatalf("Breaker after polling = %+v, want open with %d failures and nothing rejected", snap, maxFailures)
+	}
+
+	// Once OpenDuration has passed the reset loop half-opens the breaker on its own
+	clk.Advance(migrateBreakerConfig(m.currentConfig().DefaultPluginConfig.CircuitBreaker).OpenDuration + time.Second)
+	waitFor(t, "the breaker to half-open", func() bool { return breaker.StateSnapshot().State == StateHalfOpen })
+	poll()
+	if snap := breaker.StateSnapshot(); snap.State != StateHalfOpen || snap.Rejected != 0 {
+		t.Fatalf("Breaker after polling = %+v, want half-open with nothing rejected", snap)
+	}
+
+	// The first call is the trial call, and its failure reopens the breaker
+	if _, err := m.Call(ctx, "payments", "Pay"); err == nil || errors.As(err, new(ErrCircuitOpen)) {
+		t.Fatalf("Call() error = %v, want the plugin's error from a trial call", err)
+	}
+	if snap := breaker.StateSnapshot(); snap.State != StateOpen || snap.Trips != 2 {
+		t.Errorf("Breaker after a failed trial call = %+v, want reopened", snap)
+	}
+}
+
+func TestManager_GetBreakerState(t *testing.T) {
+	m, _ := newAdminManager(t, "")
+	if state, err := m.GetBreakerState("payments"); err != nil || state != StateClosed {
+		t.Fatalf("GetBreakerState() = %v, %v; want closed", state, err)
+	}
+	if err := m.TripBreaker("payments", ""); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := m.GetBreakerState("payments"); state != StateOpen {
+		t.Errorf("GetBreakerState() = %v after TripBreaker, want open", state)
+	}
+	if infos := m.ListPlugins(); len(infos) != 1 || infos[0].Breaker != StateOpen {
+		t.Errorf("ListPlugins() = %+v, want the open breaker", infos)
+	}
+	if _, err := m.GetBreakerState("orders"); !errors.As(err, new(ErrPluginNotFound)) {
+		t.Errorf("Unknown plugin error = %v, want ErrPluginNotFound", err)
+	}
+}
+
+func TestManager_UpdateBreakerConfig(t *testing.T) {
+	failing := func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return nil, errors.New("downstream unavailable")
+	}
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": failing}),
+		"v2": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, map[string]InvokeFunc{"Pay": failing}),
+	})
+	m := newTestManager(t, nil)
+	rec := &auditRecorder{}
+	m.audit = rec
+	path := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := m.Subscribe(4)
+	defer unsubscribe()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		m.Call(ctx, "payments", "Pay")
+	}
+
+	// Tightened from 5 to 3 failures, the third failure opens the breaker
+	tight := DefaultCircuitBreakerConfig()
+	tight.MaxFailures = 3
+	if err := m.UpdateBreakerConfig("payments", tight); err != nil {
+		t.Fatalf("UpdateBreakerConfig() error = %v", err)
+	}
+	awaitEvent(t, events, EventBreakerConfigUpdated)
+	m.Call(ctx, "payments", "Pay")
+	if _, err := m.Call(ctx, "payments", "Pay"); !errors.As(err, new(ErrCircuitOpen)) {
+		t.Fatalf("Call() error = %v after 3 failures, want ErrCircuitOpen", err)
+	}
+
+	// A disabled breaker lets calls through at once
+	disabled := tight
+	disabled.Enabled = false
+	if err := m.UpdateBreakerConfig("payments", disabled); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := m.Call(ctx, "payments", "Pay"); errors.As(err, new(ErrCircuitOpen)) {
+			t.Fatal("Disabled breaker rejected a call")
+		}
+	}
+	if m.GetBreakerStatus("payments") {
+		t.Error("Disabled breaker reports open")
+	}
+
+	// The settings outlive an upgrade
+	if err := m.UpdateBreakerConfig("payments", tight); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	if val, _ := m.breakers.Load("payments"); val.(*CircuitBreaker).config().MaxFailures != 3 {
+		t.Error("Upgraded plugin's breaker went back to the configured settings")
+	}
+	if got := len(rec.find(AuditBreakerConfig)); got != 3 {
+		t.Errorf("Audit records of breaker updates = %d, want 3", got)
+	}
+
+	invalid := tight
+	invalid.MaxFailures = 0
+	if err := m.UpdateBreakerConfig("payments", invalid); err == nil {
+		t.Error("Invalid settings were accepted")
+	}
+	if err := m.UpdateBreakerConfig("orders", tight); !errors.As(err, new(ErrPluginNotFound)) {
+		t.Errorf("Unknown plugin error = %v, want ErrPluginNotFound", err)
+	}
+}
+
+// Test that the manager keeps its own copy of the config it was created with
+func TestNewManager_CopiesConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.PluginDir = t.TempDir()
+	config.AllowHotReload = false
+	config.PluginConfigs["payments"] = PluginSpecificConfig{MaxConcurrentCalls: 3}
+	m, err := NewManager(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	config.BlockedPlugins = append(config.BlockedPlugins, "payments")
+	config.PluginConfigs["payments"] = PluginSpecificConfig{MaxConcurrentCalls: 1}
+	if !m.currentConfig().IsPluginAllowed("payments") || m.currentConfig().GetPluginConfig("payments").MaxConcurrentCalls != 3 {
+		t.Error("Changing the caller's config changed the running one")
+	}
+}
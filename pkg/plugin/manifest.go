@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestExtensions are the manifest file extensions looked up next to a plugin, in order
+var manifestExtensions = []string{".yaml", ".yml", ".json"}
+
+// Manifest describes a plugin without loading its shared object
+type Manifest struct {
+	Name           string                 `yaml:"name" json:"name"`
+	Version        string                 `yaml:"version" json:"version"`
+	Description    string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	MinHostVersion string                 `yaml:"minHostVersion,omitempty" json:"minHostVersion,omitempty"`
+	InitSchema     map[string]interface{} `yaml:"initSchema,omitempty" json:"initSchema,omitempty"`
+
+	// Path is the manifest file the values were read from
+	Path string `yaml:"-" json:"-"`
+}
+
+// ManifestPath returns the manifest file next to the plugin artifact,
+// or an empty string if there is none
+func ManifestPath(pluginPath string) string {
+	base := strings.TrimSuffix(pluginPath, filepath.Ext(pluginPath))
+	for _, ext := range manifestExtensions {
+		candidate := base + ext
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// ReadManifest reads the manifest next to the plugin artifact without opening the
+// plugin itself. It returns nil and no error when the plugin has no manifest.
+func ReadManifest(pluginPath string) (*Manifest, error) {
+	path := ManifestPath(pluginPath)
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	manifest := &Manifest{Path: path}
+	if filepath.Ext(path) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(manifest)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(manifest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	if manifest.Name == "" {
+		return nil, fmt.Errorf("manifest %s: name is required", path)
+	}
+	return manifest, nil
+}
+
+// verify cross-checks the manifest against the loaded plugin
+func (mf *Manifest) verify(b Bureau) error {
+	var errs []error
+	if mf.Name != b.Name() {
+		errs = append(errs, fmt.Errorf("manifest declares name %q but plugin reports %q", mf.Name, b.Name()))
+	}
+	if mf.Version != "" && mf.Version != b.Version() {
+		errs = append(errs, fmt.Errorf("manifest declares version %q but plugin reports %q", mf.Version, b.Version()))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("manifest %s does not match plugin: %w", mf.Path, errors.Join(errs...))
+	}
+	return nil
+}
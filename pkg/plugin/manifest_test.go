@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	dir := t.TempDir()
+	pluginPath := filepath.Join(dir, "payments.so")
+
+	// Missing manifests are not an error
+	if mf, err := ReadManifest(pluginPath); err != nil || mf != nil {
+		t.Fatalf("ReadManifest() = %v, %v; want nil, nil", mf, err)
+	}
+
+	yamlManifest := "name: payments\nversion: 1.2.0\ndescription: Card payments\nminHostVersion: 0.3.0\n"
+	if err := os.WriteFile(filepath.Join(dir, "payments.yaml"), []byte(yamlManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mf, err := ReadManifest(pluginPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf.Name != "payments" || mf.Version != "1.2.0" || mf.Description != "Card payments" || mf.MinHostVersion != "0.3.0" {
+		t.Errorf("Unexpected manifest: %+v", mf)
+	}
+
+	jsonPath := filepath.Join(dir, "auth.so")
+	if err := os.WriteFile(filepath.Join(dir, "auth.json"), []byte(`{"name":"auth","versoin":"1.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadManifest(jsonPath); err == nil || !strings.Contains(err.Error(), "versoin") {
+		t.Errorf("Expected unknown field error, got %v", err)
+	}
+}
+
+func TestLoadPlugin_ManifestMismatch(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+	})
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(m.currentConfig().PluginDir, "payments.yaml")
+	if err := os.WriteFile(manifest, []byte("name: payments\nversion: 2.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.LoadPlugin(path); err == nil || !strings.Contains(err.Error(), "manifest declares version") {
+		t.Fatalf("Expected manifest mismatch error, got %v", err)
+	}
+
+	if err := os.WriteFile(manifest, []byte("name: payments\nversion: 1.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	mf, err := m.GetManifest("payments")
+	if err != nil || mf == nil || mf.Version != "1.0.0" {
+		t.Errorf("GetManifest() = %+v, %v", mf, err)
+	}
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+)
+
+// metricLabelName matches the label names Prometheus accepts
+var metricLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedMetricLabels are the labels and attributes the exported metrics set themselves
+var reservedMetricLabels = map[string]bool{
+	"plugin": true, "version": true, "method": true, "function": true, "state": true,
+	"operation": true, "outcome": true, "le": true, "quantile": true,
+}
+
+// validateMetricLabels checks that labels can be added to a plugin's metrics
+func validateMetricLabels(labels map[string]string) error {
+	for name := range labels {
+		switch {
+		case !metricLabelName.MatchString(name):
+			return fmt.Errorf("MetricLabels: invalid label name %q", name)
+		case strings.HasPrefix(name, "__"):
+			return fmt.Errorf("MetricLabels: label name %q is reserved for Prometheus", name)
+		case reservedMetricLabels[name]:
+			return fmt.Errorf("MetricLabels: label name %q is set by chameleon", name)
+		}
+	}
+	return nil
+}
+
+// pluginLabels are the MetricLabels a plugin was loaded with. A reload with other labels
+// replaces them.
+type pluginLabels struct {
+	labels map[string]string
+}
+
+// configureMetricLabels records the MetricLabels a plugin is loaded with, keeping the
+// current ones when they did not change
+func (m *Manager) configureMetricLabels(name string, config *PluginSpecificConfig) {
+	if current := m.metricLabelsFor(name); current != nil && maps.Equal(current.labels, config.MetricLabels) {
+		return
+	}
+	if len(config.MetricLabels) == 0 {
+		m.metricLabels.Delete(name)
+		return
+	}
+	m.metricLabels.Store(name, &pluginLabels{labels: maps.Clone(config.MetricLabels)})
+}
+
+// MetricLabels returns the MetricLabels a plugin was loaded with, or nil if it has none.
+// Exporters add them to the plugin's series. The map is shared, so callers must not modify it.
+func (m *Manager) MetricLabels(pluginName string) map[string]string {
+	if labels := m.metricLabelsFor(pluginName); labels != nil {
+		return labels.labels
+	}
+	return nil
+}
+
+// metricLabelsFor returns the MetricLabels of a plugin, or nil if it has none
+func (m *Manager) metricLabelsFor(name string) *pluginLabels {
+	if val, ok := m.metricLabels.Load(name); ok {
+		return val.(*pluginLabels)
+	}
+	return nil
+}
@@ -1,34 +1,207 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"github.com/zyanho/chameleon/pkg/clock"
 )
 
-// MethodMetrics stores metrics for a single method using atomic operations
+// MethodMetrics stores metrics for a single method using atomic operations. Count,
+// TotalTime, MinTime and MaxTime cover every call that reached the plugin, whatever its
+// outcome; the Error fields cover the calls that failed, so that the durations of
+// successful calls are the difference.
 type MethodMetrics struct {
 	Count     atomic.Int64
 	TotalTime atomic.Int64 // save nanoseconds
 	MinTime   atomic.Int64 // save nanoseconds
 	MaxTime   atomic.Int64 // save nanoseconds
+	// ErrorCount counts the calls that returned an error, TimeoutCount those of them
+	// that timed out
+	ErrorCount   atomic.Int64
+	TimeoutCount atomic.Int64
+	ErrorTime    atomic.Int64 // save nanoseconds
+	ErrorMinTime atomic.Int64 // save nanoseconds
+	ErrorMaxTime atomic.Int64 // save nanoseconds
+	// MaxInFlight is the most calls that ran at once since the metrics were reset
+	MaxInFlight atomic.Int64
+	// LastCall, LastSuccess and LastError are when the latest call, successful call and
+	// failed call returned, zero if none did
+	LastCall    atomic.Int64 // save unix nanoseconds
+	LastSuccess atomic.Int64 // save unix nanoseconds
+	LastError   atomic.Int64 // save unix nanoseconds
+
+	latency          *latencyBuckets // nil for metrics not made by PluginMetrics
+	recent           *recentCalls    // nil for metrics not made by PluginMetrics
+	lastErrorMessage atomic.Pointer[string]
+}
+
+// maxErrorMessageLength caps the error message kept by LastErrorMessage, in bytes
+const maxErrorMessageLength = 256
+
+// LastErrorMessage returns the message of the latest failed call, cut to
+// maxErrorMessageLength bytes, or an empty string
+func (mm *MethodMetrics) LastErrorMessage() string {
+	if msg := mm.lastErrorMessage.Load(); msg != nil {
+		return *msg
+	}
+	return ""
+}
+
+// truncateMessage cuts msg to at most max bytes on a rune boundary, marking the cut with
+// an ellipsis
+func truncateMessage(msg string, max int) string {
+	if len(msg) <= max {
+		return msg
+	}
+	const ellipsis = "…"
+	cut := max - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut] + ellipsis
+}
+
+// Histogram returns the call durations by bucket
+func (mm *MethodMetrics) Histogram() LatencyHistogram {
+	return mm.latency.snapshot()
+}
+
+// Quantile estimates the duration below which a share q, between 0 and 1, of the calls
+// fall; Quantile(0.99) is the p99 latency. It is accurate to the width of a bucket and
+// stays within MinTime and MaxTime.
+func (mm *MethodMetrics) Quantile(q float64) time.Duration {
+	slowest := time.Duration(mm.MaxTime.Load())
+	return clampQuantile(mm.Histogram().Quantile(q, slowest), time.Duration(mm.MinTime.Load()), slowest)
+}
+
+// clampQuantile keeps an estimated quantile d within the fastest and slowest calls
+func clampQuantile(d, fastest, slowest time.Duration) time.Duration {
+	if d == 0 {
+		return 0
+	}
+	if d < fastest {
+		return fastest
+	}
+	if slowest > 0 && d > slowest {
+		return slowest
+	}
+	return d
+}
+
+// ErrorRate returns the share of calls that failed, between 0 and 1
+func (mm *MethodMetrics) ErrorRate() float64 {
+	count := mm.Count.Load()
+	if count == 0 {
+		return 0
+	}
+	return float64(mm.ErrorCount.Load()) / float64(count)
+}
+
+// AvgTime returns the average duration of all calls
+func (mm *MethodMetrics) AvgTime() time.Duration {
+	return average(mm.TotalTime.Load(), mm.Count.Load())
+}
+
+// SuccessAvgTime returns the average duration of the calls that succeeded
+func (mm *MethodMetrics) SuccessAvgTime() time.Duration {
+	return average(mm.TotalTime.Load()-mm.ErrorTime.Load(), mm.Count.Load()-mm.ErrorCount.Load())
+}
+
+// ErrorAvgTime returns the average duration of the calls that failed
+func (mm *MethodMetrics) ErrorAvgTime() time.Duration {
+	return average(mm.ErrorTime.Load(), mm.ErrorCount.Load())
+}
+
+func average(total, count int64) time.Duration {
+	if count <= 0 || total < 0 {
+		return 0
+	}
+	return time.Duration(total / count)
+}
+
+// Operation names a control-plane operation on a plugin, as opposed to its calls
+type Operation string
+
+// Operations recorded in PluginMethodMetrics.Operations
+const (
+	OpLoad    Operation = "load"    // opening the artifact and looking up its symbols
+	OpInit    Operation = "init"    // calling Init, retries included
+	OpFree    Operation = "free"    // calling Free and unloading the plugin
+	OpReload  Operation = "reload"  // loading a plugin file the watcher saw change
+	OpUpgrade Operation = "upgrade" // replacing a loaded plugin; counted, not timed
+)
+
+// OperationMetrics stores metrics for one operation on a plugin
+type OperationMetrics struct {
+	Count     atomic.Int64
+	Failures  atomic.Int64
+	TotalTime atomic.Int64 // save nanoseconds
+	MinTime   atomic.Int64 // save nanoseconds
+	MaxTime   atomic.Int64 // save nanoseconds
+}
+
+// AvgTime returns the average duration of the operation
+func (om *OperationMetrics) AvgTime() time.Duration {
+	return average(om.TotalTime.Load(), om.Count.Load())
 }
 
 // PluginMethodMetrics stores metrics for plugin methods
 type PluginMethodMetrics struct {
 	Methods sync.Map // map[string]*MethodMetrics
+	// BreakerRejections counts calls refused by an open circuit breaker and
+	// ConcurrencyRejections those refused by the concurrency limit; neither reached a method
+	BreakerRejections     atomic.Int64
+	ConcurrencyRejections atomic.Int64
+	// Operations holds the metrics of loading, initializing, freeing, reloading and
+	// upgrading the plugin
+	Operations sync.Map // map[Operation]*OperationMetrics
+	// Versions holds the calls by the version of the plugin that served them, for the
+	// versions called most recently; Methods holds those of every version together
+	Versions   sync.Map   // map[string]*VersionMetrics
+	versionsMu sync.Mutex // serializes adding and evicting versions
 }
 
+// VersionMetrics stores metrics for the methods of one plugin version
+type VersionMetrics struct {
+	Methods sync.Map // map[string]*MethodMetrics
+}
+
+// DefaultMetricsVersions is how many versions of a plugin keep metrics of their own
+// unless Config.MetricsVersions says otherwise
+const DefaultMetricsVersions = 3
+
 // PluginMetrics stores metrics for plugin calls
 type PluginMetrics struct {
 	plugins sync.Map // map[string]*PluginMethodMetrics
 	enabled atomic.Bool
+	bounds  []time.Duration // of the latency histograms
+	clock   clock.Clock     // stamps the last calls and moves the rolling windows on
+	// maxVersions is how many versions of each plugin keep metrics of their own
+	maxVersions int
 }
 
-// NewPluginMetrics creates a new plugin metrics collector
+// NewPluginMetrics creates a new plugin metrics collector with DefaultLatencyBuckets
 func NewPluginMetrics(enabled bool) *PluginMetrics {
-	m := &PluginMetrics{}
+	return newPluginMetrics(enabled, nil)
+}
+
+// newPluginMetrics creates a metrics collector whose latency histograms have the given
+// bucket bounds, DefaultLatencyBuckets if there are none
+func newPluginMetrics(enabled bool, bounds []time.Duration) *PluginMetrics {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBuckets()
+	}
+	m := &PluginMetrics{
+		bounds:      append([]time.Duration(nil), bounds...),
+		clock:       clock.Real(),
+		maxVersions: DefaultMetricsVersions,
+	}
 	m.enabled.Store(enabled)
 	return m
 }
@@ -51,53 +224,206 @@ func (m *PluginMetrics) AddPlugin(pluginName string) {
 	m.plugins.LoadOrStore(pluginName, &PluginMethodMetrics{})
 }
 
-// RecordMetric records a single method call
+// RemovePlugin drops the metrics of a plugin
+func (m *PluginMetrics) RemovePlugin(pluginName string) {
+	m.plugins.Delete(pluginName)
+}
+
+// RecordMetric records a single successful method call
 func (m *PluginMetrics) RecordMetric(pluginName, funcName string, duration time.Duration) {
+	m.RecordCall(pluginName, funcName, duration, nil)
+}
+
+// RecordCall records the outcome and duration of a method call, under an empty version
+func (m *PluginMetrics) RecordCall(pluginName, funcName string, duration time.Duration, err error) {
+	m.RecordVersionCall(pluginName, "", funcName, duration, err)
+}
+
+// RecordVersionCall records the outcome and duration of a method call, both with the
+// calls of every version and under the version of the plugin that served it
+func (m *PluginMetrics) RecordVersionCall(pluginName, version, funcName string, duration time.Duration, err error) {
 	if !m.enabled.Load() {
 		return
 	}
+	now := m.clock.Now().UnixNano()
+	pm := m.pluginMetrics(pluginName)
+	m.methodIn(&pm.Methods, funcName).record(duration, err, now)
+	m.methodIn(&m.versionMetrics(pm, version).Methods, funcName).record(duration, err, now)
+}
 
-	// Get or create plugin metrics
-	pluginMetrics, _ := m.plugins.LoadOrStore(pluginName, &PluginMethodMetrics{})
-	pMetrics := pluginMetrics.(*PluginMethodMetrics)
-
-	// Get or create method metrics
-	methodMetricsIface, _ := pMetrics.Methods.LoadOrStore(funcName, &MethodMetrics{})
-	metrics := methodMetricsIface.(*MethodMetrics)
-
+// record adds a call that returned at now, in Unix nanoseconds
+func (mm *MethodMetrics) record(duration time.Duration, err error, now int64) {
 	durationNanos := duration.Nanoseconds()
 
 	// Update count and total time
-	metrics.Count.Add(1)
-	metrics.TotalTime.Add(durationNanos)
+	mm.Count.Add(1)
+	mm.TotalTime.Add(durationNanos)
+	storeMin(&mm.MinTime, durationNanos)
+	storeMax(&mm.MaxTime, durationNanos)
+	mm.latency.observe(duration)
+	mm.recent.record(err != nil)
+	mm.LastCall.Store(now)
+
+	if err == nil {
+		mm.LastSuccess.Store(now)
+	} else {
+		msg := truncateMessage(err.Error(), maxErrorMessageLength)
+		mm.lastErrorMessage.Store(&msg)
+		mm.LastError.Store(now)
+		mm.ErrorCount.Add(1)
+		if isTimeout(err) {
+			mm.TimeoutCount.Add(1)
+		}
+		mm.ErrorTime.Add(durationNanos)
+		storeMin(&mm.ErrorMinTime, durationNanos)
+		storeMax(&mm.ErrorMaxTime, durationNanos)
+	}
+}
 
-	// Update min time using CAS loop
+// storeMin lowers a minimum to v using a CAS loop; zero means no value yet
+func storeMin(low *atomic.Int64, v int64) {
 	for {
-		current := metrics.MinTime.Load()
-		if current == 0 || durationNanos < current {
-			if metrics.MinTime.CompareAndSwap(current, durationNanos) {
-				break
-			}
-		} else {
-			break
+		current := low.Load()
+		if current != 0 && v >= current {
+			return
+		}
+		if low.CompareAndSwap(current, v) {
+			return
 		}
 	}
+}
 
-	// Update max time using CAS loop
+// storeMax raises a maximum to v using a CAS loop
+func storeMax(high *atomic.Int64, v int64) {
 	for {
-		current := metrics.MaxTime.Load()
-		if durationNanos > current {
-			if metrics.MaxTime.CompareAndSwap(current, durationNanos) {
-				break
+		current := high.Load()
+		if v <= current {
+			return
+		}
+		if high.CompareAndSwap(current, v) {
+			return
+		}
+	}
+}
+
+// RecordOperation records the outcome and duration of an operation on a plugin
+func (m *PluginMetrics) RecordOperation(pluginName string, op Operation, duration time.Duration, err error) {
+	if !m.enabled.Load() {
+		return
+	}
+	val, _ := m.pluginMetrics(pluginName).Operations.LoadOrStore(op, &OperationMetrics{})
+	metrics := val.(*OperationMetrics)
+	metrics.Count.Add(1)
+	if err != nil {
+		metrics.Failures.Add(1)
+	}
+	if duration > 0 {
+		metrics.TotalTime.Add(duration.Nanoseconds())
+		storeMin(&metrics.MinTime, duration.Nanoseconds())
+		storeMax(&metrics.MaxTime, duration.Nanoseconds())
+	}
+}
+
+// recordInFlight raises the high-water mark of calls running at once in a method
+func (m *PluginMetrics) recordInFlight(pluginName, funcName string, inFlight int64) {
+	if !m.enabled.Load() {
+		return
+	}
+	storeMax(&m.methodMetrics(pluginName, funcName).MaxInFlight, inFlight)
+}
+
+// RecordRejection records a call to a plugin refused by its circuit breaker
+func (m *PluginMetrics) RecordRejection(pluginName string) {
+	if !m.enabled.Load() {
+		return
+	}
+	m.pluginMetrics(pluginName).BreakerRejections.Add(1)
+}
+
+// RecordConcurrencyRejection records a call to a plugin refused by its concurrency limit
+func (m *PluginMetrics) RecordConcurrencyRejection(pluginName string) {
+	if !m.enabled.Load() {
+		return
+	}
+	m.pluginMetrics(pluginName).ConcurrencyRejections.Add(1)
+}
+
+// pluginMetrics returns the metrics of a plugin, creating them on first use
+func (m *PluginMetrics) pluginMetrics(pluginName string) *PluginMethodMetrics {
+	pluginMetrics, _ := m.plugins.LoadOrStore(pluginName, &PluginMethodMetrics{})
+	return pluginMetrics.(*PluginMethodMetrics)
+}
+
+// methodMetrics returns the metrics of a method, creating them on first use
+func (m *PluginMetrics) methodMetrics(pluginName, funcName string) *MethodMetrics {
+	return m.methodIn(&m.pluginMetrics(pluginName).Methods, funcName)
+}
+
+// methodIn returns the metrics of a method among methods, creating them on first use
+func (m *PluginMetrics) methodIn(methods *sync.Map, funcName string) *MethodMetrics {
+	if val, ok := methods.Load(funcName); ok {
+		return val.(*MethodMetrics)
+	}
+	val, _ := methods.LoadOrStore(funcName, &MethodMetrics{
+		latency: newLatencyBuckets(m.bounds),
+		recent:  newRecentCalls(m.clock.Now),
+	})
+	return val.(*MethodMetrics)
+}
+
+// versionMetrics returns the metrics of a plugin version, creating them on first use.
+// Beyond maxVersions, the version whose latest call is the oldest is dropped.
+func (m *PluginMetrics) versionMetrics(pm *PluginMethodMetrics, version string) *VersionMetrics {
+	if val, ok := pm.Versions.Load(version); ok {
+		return val.(*VersionMetrics)
+	}
+	pm.versionsMu.Lock()
+	defer pm.versionsMu.Unlock()
+	if val, ok := pm.Versions.Load(version); ok {
+		return val.(*VersionMetrics)
+	}
+
+	var versions []string
+	pm.Versions.Range(func(key, _ interface{}) bool {
+		versions = append(versions, key.(string))
+		return true
+	})
+	for len(versions) >= m.maxVersions && len(versions) > 0 {
+		oldest := 0
+		for i, v := range versions {
+			if lastCall(&pm.Versions, v) < lastCall(&pm.Versions, versions[oldest]) {
+				oldest = i
 			}
-		} else {
-			break
 		}
+		pm.Versions.Delete(versions[oldest])
+		versions = append(versions[:oldest], versions[oldest+1:]...)
 	}
+	vm := &VersionMetrics{}
+	pm.Versions.Store(version, vm)
+	return vm
 }
 
-// GetPluginMetrics returns metrics for a specific plugin
+// lastCall returns when the latest call to a plugin version returned, in Unix nanoseconds
+func lastCall(versions *sync.Map, version string) int64 {
+	val, ok := versions.Load(version)
+	if !ok {
+		return 0
+	}
+	var latest int64
+	val.(*VersionMetrics).Methods.Range(func(_, value interface{}) bool {
+		latest = max(latest, value.(*MethodMetrics).LastCall.Load())
+		return true
+	})
+	return latest
+}
+
+// GetPluginMetrics returns a copy of the metrics of a plugin
 func (m *PluginMetrics) GetPluginMetrics(pluginName string) (*PluginMethodMetrics, error) {
+	return m.collect(pluginName)
+}
+
+// collect copies the metrics of a plugin, so that they no longer change with its calls
+func (m *PluginMetrics) collect(pluginName string) (*PluginMethodMetrics, error) {
 	if !m.enabled.Load() {
 		return nil, fmt.Errorf("metrics are disabled")
 	}
@@ -113,22 +439,379 @@ func (m *PluginMetrics) GetPluginMetrics(pluginName string) (*PluginMethodMetric
 	snapshot := &PluginMethodMetrics{
 		Methods: sync.Map{},
 	}
+	snapshot.BreakerRejections.Store(pMetrics.BreakerRejections.Load())
+	snapshot.ConcurrencyRejections.Store(pMetrics.ConcurrencyRejections.Load())
 
-	// use Range to iterate over sync.Map
-	pMetrics.Methods.Range(func(key, value interface{}) bool {
-		methodName := key.(string)
+	copyMethods(&snapshot.Methods, &pMetrics.Methods)
+	pMetrics.Versions.Range(func(key, value interface{}) bool {
+		versionSnapshot := &VersionMetrics{}
+		copyMethods(&versionSnapshot.Methods, &value.(*VersionMetrics).Methods)
+		snapshot.Versions.Store(key, versionSnapshot)
+		return true
+	})
+	pMetrics.Operations.Range(func(key, value interface{}) bool {
+		metrics := value.(*OperationMetrics)
+		opSnapshot := &OperationMetrics{}
+		opSnapshot.Count.Store(metrics.Count.Load())
+		opSnapshot.Failures.Store(metrics.Failures.Load())
+		opSnapshot.TotalTime.Store(metrics.TotalTime.Load())
+		opSnapshot.MinTime.Store(metrics.MinTime.Load())
+		opSnapshot.MaxTime.Store(metrics.MaxTime.Load())
+		snapshot.Operations.Store(key, opSnapshot)
+		return true
+	})
+
+	return snapshot, nil
+}
+
+// copyMethods stores copies of the method metrics in src into dst
+func copyMethods(dst, src *sync.Map) {
+	src.Range(func(key, value interface{}) bool {
 		metrics := value.(*MethodMetrics)
 
 		// Create method snapshot
-		methodSnapshot := &MethodMetrics{}
+		methodSnapshot := &MethodMetrics{latency: metrics.latency.copy(), recent: metrics.recent.copy()}
 		methodSnapshot.Count.Store(metrics.Count.Load())
 		methodSnapshot.TotalTime.Store(metrics.TotalTime.Load())
 		methodSnapshot.MinTime.Store(metrics.MinTime.Load())
 		methodSnapshot.MaxTime.Store(metrics.MaxTime.Load())
+		methodSnapshot.ErrorCount.Store(metrics.ErrorCount.Load())
+		methodSnapshot.TimeoutCount.Store(metrics.TimeoutCount.Load())
+		methodSnapshot.ErrorTime.Store(metrics.ErrorTime.Load())
+		methodSnapshot.ErrorMinTime.Store(metrics.ErrorMinTime.Load())
+		methodSnapshot.ErrorMaxTime.Store(metrics.ErrorMaxTime.Load())
+		methodSnapshot.MaxInFlight.Store(metrics.MaxInFlight.Load())
+		methodSnapshot.LastCall.Store(metrics.LastCall.Load())
+		methodSnapshot.LastSuccess.Store(metrics.LastSuccess.Load())
+		methodSnapshot.LastError.Store(metrics.LastError.Load())
+		methodSnapshot.lastErrorMessage.Store(metrics.lastErrorMessage.Load())
 
-		snapshot.Methods.Store(methodName, methodSnapshot)
+		dst.Store(key, methodSnapshot)
 		return true
 	})
+}
 
-	return snapshot, nil
+// MetricsSnapshot holds the metrics of a plugin as plain values, which can be compared,
+// marshalled to JSON or sent elsewhere
+type MetricsSnapshot struct {
+	Plugin     string    `json:"plugin"`
+	CapturedAt time.Time `json:"captured_at"`
+	// BreakerRejections and ConcurrencyRejections count the calls refused by an open
+	// circuit breaker and by the concurrency limit: the demand shed rather than served
+	BreakerRejections     int64 `json:"breaker_rejections"`
+	ConcurrencyRejections int64 `json:"concurrency_rejections"`
+	// InFlight is the number of calls running when the snapshot was captured
+	InFlight int64                     `json:"in_flight"`
+	Methods  map[string]MethodSnapshot `json:"methods"`
+	// Operations holds the control-plane operations on the plugin, apart from its calls
+	Operations map[Operation]OperationSnapshot `json:"operations,omitempty"`
+	// Versions holds the calls by plugin version, of the versions called most recently;
+	// Methods has those of every version together
+	Versions map[string]VersionSnapshot `json:"versions,omitempty"`
+}
+
+// VersionSnapshot holds the metrics of the functions of one plugin version
+type VersionSnapshot struct {
+	Methods map[string]MethodSnapshot `json:"methods"`
+}
+
+// OperationSnapshot holds the metrics of one operation, as OperationMetrics does
+type OperationSnapshot struct {
+	Count     int64         `json:"count"`
+	Failures  int64         `json:"failures"`
+	TotalTime time.Duration `json:"total_time_ns"`
+	MinTime   time.Duration `json:"min_time_ns"`
+	MaxTime   time.Duration `json:"max_time_ns"`
+	AvgTime   time.Duration `json:"avg_time_ns"`
+}
+
+// MethodSnapshot holds the metrics of one function, as MethodMetrics does
+type MethodSnapshot struct {
+	Count        int64            `json:"count"`
+	Errors       int64            `json:"errors"`
+	Timeouts     int64            `json:"timeouts"`
+	TotalTime    time.Duration    `json:"total_time_ns"`
+	MinTime      time.Duration    `json:"min_time_ns"`
+	MaxTime      time.Duration    `json:"max_time_ns"`
+	AvgTime      time.Duration    `json:"avg_time_ns"`
+	ErrorTime    time.Duration    `json:"error_time_ns"`
+	ErrorMinTime time.Duration    `json:"error_min_time_ns"`
+	ErrorMaxTime time.Duration    `json:"error_max_time_ns"`
+	Histogram    LatencyHistogram `json:"histogram"`
+	InFlight     int64            `json:"in_flight"`
+	MaxInFlight  int64            `json:"max_in_flight"`
+	// Rate1m and Rate5m are the calls per second over the last minute and five minutes,
+	// ErrorRate1m and ErrorRate5m the share of those calls that failed
+	Rate1m      float64 `json:"rate_1m"`
+	Rate5m      float64 `json:"rate_5m"`
+	ErrorRate1m float64 `json:"error_rate_1m"`
+	ErrorRate5m float64 `json:"error_rate_5m"`
+	// LastCall, LastSuccess and LastError are zero if no such call returned
+	LastCall         time.Time `json:"last_call"`
+	LastSuccess      time.Time `json:"last_success"`
+	LastError        time.Time `json:"last_error"`
+	LastErrorMessage string    `json:"last_error_message,omitempty"`
+}
+
+// ErrorRate returns the share of calls that failed, between 0 and 1
+func (s MethodSnapshot) ErrorRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Count)
+}
+
+// ErrorAvgTime returns the average duration of the calls that failed
+func (s MethodSnapshot) ErrorAvgTime() time.Duration {
+	return average(int64(s.ErrorTime), s.Errors)
+}
+
+// Quantile estimates a latency quantile as MethodMetrics.Quantile does
+func (s MethodSnapshot) Quantile(q float64) time.Duration {
+	return clampQuantile(s.Histogram.Quantile(q, s.MaxTime), s.MinTime, s.MaxTime)
+}
+
+// MergeMethodSnapshots collapses the metrics of one function across plugin versions, such
+// as those in MetricsSnapshot.Versions, or across hosts. Counts and rates add up, the
+// extremes and the latest calls are kept, and the histograms add up when their bounds match.
+func MergeMethodSnapshots(snapshots ...MethodSnapshot) MethodSnapshot {
+	var merged MethodSnapshot
+	var errors1m, errors5m float64
+	for _, s := range snapshots {
+		merged.Count += s.Count
+		merged.Errors += s.Errors
+		merged.Timeouts += s.Timeouts
+		merged.TotalTime += s.TotalTime
+		merged.MinTime = minDuration(merged.MinTime, s.MinTime)
+		merged.MaxTime = max(merged.MaxTime, s.MaxTime)
+		merged.ErrorTime += s.ErrorTime
+		merged.ErrorMinTime = minDuration(merged.ErrorMinTime, s.ErrorMinTime)
+		merged.ErrorMaxTime = max(merged.ErrorMaxTime, s.ErrorMaxTime)
+		merged.Histogram = mergeHistograms(merged.Histogram, s.Histogram)
+		merged.InFlight += s.InFlight
+		merged.MaxInFlight = max(merged.MaxInFlight, s.MaxInFlight)
+		merged.Rate1m += s.Rate1m
+		merged.Rate5m += s.Rate5m
+		errors1m += s.Rate1m * s.ErrorRate1m
+		errors5m += s.Rate5m * s.ErrorRate5m
+		if s.LastCall.After(merged.LastCall) {
+			merged.LastCall = s.LastCall
+		}
+		if s.LastSuccess.After(merged.LastSuccess) {
+			merged.LastSuccess = s.LastSuccess
+		}
+		if s.LastError.After(merged.LastError) {
+			merged.LastError = s.LastError
+			merged.LastErrorMessage = s.LastErrorMessage
+		}
+	}
+	merged.AvgTime = average(int64(merged.TotalTime), merged.Count)
+	if merged.Rate1m > 0 {
+		merged.ErrorRate1m = errors1m / merged.Rate1m
+	}
+	if merged.Rate5m > 0 {
+		merged.ErrorRate5m = errors5m / merged.Rate5m
+	}
+	return merged
+}
+
+// minDuration returns the smaller of two minimums, where zero means no value yet
+func minDuration(a, b time.Duration) time.Duration {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// mergeHistograms adds the counts of b to a copy of a; a histogram whose bounds differ
+// from those of a non-empty a is left out
+func mergeHistograms(a, b LatencyHistogram) LatencyHistogram {
+	if len(a.Counts) == 0 {
+		return LatencyHistogram{
+			Bounds: append([]time.Duration(nil), b.Bounds...),
+			Counts: append([]int64(nil), b.Counts...),
+		}
+	}
+	if !slices.Equal(a.Bounds, b.Bounds) {
+		return a
+	}
+	counts := append([]int64(nil), a.Counts...)
+	for i, n := range b.Counts {
+		counts[i] += n
+	}
+	return LatencyHistogram{Bounds: a.Bounds, Counts: counts}
+}
+
+// snapshot converts collected metrics to plain values
+func (pm *PluginMethodMetrics) snapshot(pluginName string, at time.Time) MetricsSnapshot {
+	snapshot := MetricsSnapshot{
+		Plugin:                pluginName,
+		CapturedAt:            at,
+		BreakerRejections:     pm.BreakerRejections.Load(),
+		ConcurrencyRejections: pm.ConcurrencyRejections.Load(),
+		Methods:               methodSnapshots(&pm.Methods),
+	}
+	pm.Versions.Range(func(key, value interface{}) bool {
+		if snapshot.Versions == nil {
+			snapshot.Versions = make(map[string]VersionSnapshot)
+		}
+		snapshot.Versions[key.(string)] = VersionSnapshot{Methods: methodSnapshots(&value.(*VersionMetrics).Methods)}
+		return true
+	})
+	pm.Operations.Range(func(key, value interface{}) bool {
+		om := value.(*OperationMetrics)
+		if snapshot.Operations == nil {
+			snapshot.Operations = make(map[Operation]OperationSnapshot)
+		}
+		snapshot.Operations[key.(Operation)] = OperationSnapshot{
+			Count:     om.Count.Load(),
+			Failures:  om.Failures.Load(),
+			TotalTime: time.Duration(om.TotalTime.Load()),
+			MinTime:   time.Duration(om.MinTime.Load()),
+			MaxTime:   time.Duration(om.MaxTime.Load()),
+			AvgTime:   om.AvgTime(),
+		}
+		return true
+	})
+	return snapshot
+}
+
+// methodSnapshots converts the method metrics in methods to plain values
+func methodSnapshots(methods *sync.Map) map[string]MethodSnapshot {
+	snapshots := make(map[string]MethodSnapshot)
+	methods.Range(func(key, value interface{}) bool {
+		mm := value.(*MethodMetrics)
+		rate1m, errorRate1m, rate5m, errorRate5m := mm.recent.rates()
+		snapshots[key.(string)] = MethodSnapshot{
+			Count:            mm.Count.Load(),
+			Errors:           mm.ErrorCount.Load(),
+			Timeouts:         mm.TimeoutCount.Load(),
+			TotalTime:        time.Duration(mm.TotalTime.Load()),
+			MinTime:          time.Duration(mm.MinTime.Load()),
+			MaxTime:          time.Duration(mm.MaxTime.Load()),
+			AvgTime:          mm.AvgTime(),
+			ErrorTime:        time.Duration(mm.ErrorTime.Load()),
+			ErrorMinTime:     time.Duration(mm.ErrorMinTime.Load()),
+			ErrorMaxTime:     time.Duration(mm.ErrorMaxTime.Load()),
+			Histogram:        mm.Histogram(),
+			MaxInFlight:      mm.MaxInFlight.Load(),
+			Rate1m:           rate1m,
+			Rate5m:           rate5m,
+			ErrorRate1m:      errorRate1m,
+			ErrorRate5m:      errorRate5m,
+			LastCall:         unixNano(mm.LastCall.Load()),
+			LastSuccess:      unixNano(mm.LastSuccess.Load()),
+			LastError:        unixNano(mm.LastError.Load()),
+			LastErrorMessage: mm.LastErrorMessage(),
+		}
+		return true
+	})
+	return snapshots
+}
+
+// unixNano converts a stored timestamp to UTC, returning the zero time for zero
+func unixNano(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns).UTC()
+}
+
+// inFlightCalls counts the calls running in a plugin, in all and by function. It is
+// kept apart from PluginMetrics so that disabling or resetting metrics loses no count.
+type inFlightCalls struct {
+	total   atomic.Int64
+	methods sync.Map // map[string]*atomic.Int64
+}
+
+// method returns the counter of a function, creating it on first use
+func (c *inFlightCalls) method(funcName string) *atomic.Int64 {
+	if val, ok := c.methods.Load(funcName); ok {
+		return val.(*atomic.Int64)
+	}
+	val, _ := c.methods.LoadOrStore(funcName, new(atomic.Int64))
+	return val.(*atomic.Int64)
+}
+
+// inFlightFor returns the in-flight counters of a plugin, creating them on first use
+func (m *Manager) inFlightFor(pluginName string) *inFlightCalls {
+	if val, ok := m.inFlight.Load(pluginName); ok {
+		return val.(*inFlightCalls)
+	}
+	val, _ := m.inFlight.LoadOrStore(pluginName, &inFlightCalls{})
+	return val.(*inFlightCalls)
+}
+
+// startCall counts a call admitted to a plugin as running until the returned function
+// is called. Only functions the plugin exports are counted by name.
+func (m *Manager) startCall(pluginName string, instance *PluginInstance, funcName string) (done func()) {
+	calls := m.inFlightFor(pluginName)
+	calls.total.Add(1)
+	if !instance.hasFunction(funcName) {
+		return func() { calls.total.Add(-1) }
+	}
+	method := calls.method(funcName)
+	m.metrics.recordInFlight(pluginName, funcName, method.Add(1))
+	return func() {
+		method.Add(-1)
+		calls.total.Add(-1)
+	}
+}
+
+// GetInFlight returns the number of calls running in a plugin, counted from the time
+// its circuit breaker admits them until they return
+func (m *Manager) GetInFlight(pluginName string) int64 {
+	if val, ok := m.inFlight.Load(pluginName); ok {
+		return val.(*inFlightCalls).total.Load()
+	}
+	return 0
+}
+
+// addInFlight fills the in-flight counts of a snapshot
+func (m *Manager) addInFlight(snapshot *MetricsSnapshot) {
+	val, ok := m.inFlight.Load(snapshot.Plugin)
+	if !ok {
+		return
+	}
+	calls := val.(*inFlightCalls)
+	snapshot.InFlight = calls.total.Load()
+	for fn, s := range snapshot.Methods {
+		if count, ok := calls.methods.Load(fn); ok {
+			s.InFlight = count.(*atomic.Int64).Load()
+			snapshot.Methods[fn] = s
+		}
+	}
+}
+
+// CallObserver is told of the calls Manager.Call handles, to export them beyond
+// PluginMetrics, as the otelexport module does for OpenTelemetry. Its methods must be
+// safe for concurrent use.
+type CallObserver interface {
+	// Begin is called once a call is admitted by the concurrency limit, End when it returns
+	Begin(ctx context.Context, pluginName string)
+	End(ctx context.Context, pluginName string)
+	// Record is called with the outcome of a call to a function the plugin exports. A
+	// call refused by the circuit breaker or the concurrency limit has a zero duration.
+	Record(ctx context.Context, pluginName, version, funcName string, duration time.Duration, err error)
+	// Close is called when the manager closes
+	Close()
+}
+
+// WithCallObserver tells o of every call the manager handles, replacing any observer
+// set before
+func WithCallObserver(o CallObserver) ManagerOption {
+	return func(m *Manager) {
+		if o != nil {
+			m.observer = o
+		}
+	}
+}
+
+// observeRejection tells the observer of a refused call. Calls to functions the plugin
+// does not export are left out, since the caller chooses their names.
+func (m *Manager) observeRejection(ctx context.Context, pluginName string, instance *PluginInstance, funcName string, err error) {
+	if m.observer == nil || !instance.hasFunction(funcName) {
+		return
+	}
+	m.observer.Record(ctx, pluginName, instance.version, funcName, 0, err)
 }
@@ -0,0 +1,553 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
+)
+
+func TestManager_CallRecordsOutcomes(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{
+			"Pay": returning("ok"),
+			"Refund": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				time.Sleep(5 * time.Millisecond)
+				return nil, errors.New("declined")
+			},
+			"Settle": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				return nil, context.DeadlineExceeded
+			},
+		}),
+	})
+	m := newTestManager(t, func(config *Config) { config.EnableMetrics = true })
+	loadTestPlugin(t, m, "payments", "v1")
+
+	ctx := context.Background()
+	for _, fn := range []string{"Pay", "Pay", "Pay", "Refund", "Settle", "Missing"} {
+		m.Call(ctx, "payments", fn)
+	}
+	if err := m.TripBreaker("payments", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Call(ctx, "payments", "Pay"); !errors.As(err, new(ErrCircuitOpen)) {
+		t.Fatalf("Call() error = %v with the breaker tripped, want ErrCircuitOpen", err)
+	}
+
+	metrics, err := m.GetMetrics("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	method := func(fn string) *MethodMetrics {
+		t.Helper()
+		val, ok := metrics.Methods.Load(fn)
+		if !ok {
+			t.Fatalf("No metrics for %s", fn)
+		}
+		return val.(*MethodMetrics)
+	}
+	tests := []struct {
+		fn                      string
+		count, errors, timeouts int64
+	}{
+		{fn: "Pay", count: 3},
+		{fn: "Refund", count: 1, errors: 1},
+		{fn: "Settle", count: 1, errors: 1, timeouts: 1},
+	}
+	for _, tt := range tests {
+		mm := method(tt.fn)
+		if mm.Count.Load() != tt.count || mm.ErrorCount.Load() != tt.errors || mm.TimeoutCount.Load() != tt.timeouts {
+			t.Errorf("%s: count %d, errors %d, timeouts %d; want %d, %d, %d", tt.fn,
+				mm.Count.Load(), mm.ErrorCount.Load(), mm.TimeoutCount.Load(), tt.count, tt.errors, tt.timeouts)
+		}
+	}
+	if rate := method("Refund").ErrorRate(); rate != 1 {
+		t.Errorf("Refund error rate = %v, want 1", rate)
+	}
+	if refund := method("Refund"); refund.TotalTime.Load() < int64(5*time.Millisecond) ||
+		refund.ErrorMaxTime.Load() < int64(5*time.Millisecond) {
+		t.Errorf("Failed Refund took %v in total, %v at most; want at least 5ms",
+			time.Duration(refund.TotalTime.Load()), time.Duration(refund.ErrorMaxTime.Load()))
+	}
+	if avg := method("Refund").SuccessAvgTime(); avg != 0 {
+		t.Errorf("Refund success average time = %v with no successful call, want 0", avg)
+	}
+	if _, ok := metrics.Methods.Load("Missing"); ok {
+		t.Error("Call to an unknown function was recorded")
+	}
+	if got := metrics.BreakerRejections.Load(); got != 1 {
+		t.Errorf("BreakerRejections = %d, want 1", got)
+	}
+
+	admin := m.adminMetrics("payments")["Settle"]
+	if admin.Errors != 1 || admin.Timeouts != 1 {
+		t.Errorf("Admin metrics of Settle = %+v, want 1 error and 1 timeout", admin)
+	}
+}
+
+func TestMethodMetrics_DurationsByOutcome(t *testing.T) {
+	metrics := NewPluginMetrics(true)
+	metrics.RecordCall("calc", "Add", 10*time.Millisecond, nil)
+	metrics.RecordCall("calc", "Add", 30*time.Millisecond, nil)
+	metrics.RecordCall("calc", "Add", time.Second, errors.New("overflow"))
+	metrics.RecordCall("calc", "Add", 2*time.Second, context.DeadlineExceeded)
+
+	snapshot, err := metrics.GetPluginMetrics("calc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, _ := snapshot.Methods.Load("Add")
+	mm := val.(*MethodMetrics)
+
+	// The slow failures show in the overall durations
+	if mm.Count.Load() != 4 || mm.MaxTime.Load() != int64(2*time.Second) || mm.MinTime.Load() != int64(10*time.Millisecond) {
+		t.Errorf("Count %d, min %v, max %v; want 4, 10ms, 2s", mm.Count.Load(),
+			time.Duration(mm.MinTime.Load()), time.Duration(mm.MaxTime.Load()))
+	}
+	if got := mm.AvgTime(); got != 760*time.Millisecond {
+		t.Errorf("AvgTime() = %v, want 760ms", got)
+	}
+
+	// and can be told apart from the successful calls
+	if got := mm.SuccessAvgTime(); got != 20*time.Millisecond {
+		t.Errorf("SuccessAvgTime() = %v, want 20ms", got)
+	}
+	if got := mm.ErrorAvgTime(); got != 1500*time.Millisecond {
+		t.Errorf("ErrorAvgTime() = %v, want 1.5s", got)
+	}
+	if mm.ErrorMinTime.Load() != int64(time.Second) || mm.ErrorMaxTime.Load() != int64(2*time.Second) {
+		t.Errorf("Error min %v, max %v; want 1s, 2s",
+			time.Duration(mm.ErrorMinTime.Load()), time.Duration(mm.ErrorMaxTime.Load()))
+	}
+	if got := mm.ErrorRate(); got != 0.5 {
+		t.Errorf("ErrorRate() = %v, want 0.5", got)
+	}
+}
+
+func TestManager_GetMetricsSnapshot(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning("ok")}),
+	})
+	fake := clocktest.NewFake(time.Date(2024, 11, 16, 12, 0, 0, 0, time.UTC))
+	m := newTestManager(t, func(config *Config) { config.EnableMetrics = true }, WithClock(fake))
+	loadTestPlugin(t, m, "payments", "v1")
+
+	// Fixed durations keep the snapshot exact; the load and init are left out
+	m.ResetMetrics()
+	m.metrics.RecordVersionCall("payments", "1.0.0", "Pay", 2*time.Millisecond, nil)
+	m.metrics.RecordVersionCall("payments", "1.0.0", "Pay", 4*time.Millisecond, errors.New("declined"))
+	m.metrics.RecordRejection("payments")
+
+	snapshot, err := m.GetMetricsSnapshot("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pay := MethodSnapshot{
+		Count:        2,
+		Errors:       1,
+		TotalTime:    6 * time.Millisecond,
+		MinTime:      2 * time.Millisecond,
+		MaxTime:      4 * time.Millisecond,
+		AvgTime:      3 * time.Millisecond,
+		ErrorTime:    4 * time.Millisecond,
+		ErrorMinTime: 4 * time.Millisecond,
+		ErrorMaxTime: 4 * time.Millisecond,
+		Histogram:    snapshot.Methods["Pay"].Histogram,
+		Rate1m:       2.0 / 60,
+		Rate5m:       2.0 / 300,
+		ErrorRate1m:  0.5,
+		ErrorRate5m:  0.5,
+
+		LastCall:         fake.Now(),
+		LastSuccess:      fake.Now(),
+		LastError:        fake.Now(),
+		LastErrorMessage: "declined",
+	}
+	want := MetricsSnapshot{
+		Plugin:            "payments",
+		CapturedAt:        fake.Now(),
+		BreakerRejections: 1,
+		Methods:           map[string]MethodSnapshot{"Pay": pay},
+		Versions:          map[string]VersionSnapshot{"1.0.0": {Methods: map[string]MethodSnapshot{"Pay": pay}}},
+	}
+	if !reflect.DeepEqual(snapshot, want) {
+		t.Errorf("GetMetricsSnapshot() = %+v, want %+v", snapshot, want)
+	}
+	if total := snapshot.Methods["Pay"].Histogram.Total(); total != 2 {
+		t.Errorf("histogram holds %d calls, want 2", total)
+	}
+
+	// The snapshot is plain data: later calls leave it alone and it survives JSON
+	m.metrics.RecordCall("payments", "Pay", time.Millisecond, nil)
+	if got := snapshot.Methods["Pay"].Count; got != 2 {
+		t.Errorf("snapshot Count = %d after another call, want 2", got)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded MetricsSnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, snapshot) {
+		t.Errorf("decoded snapshot = %+v, want %+v", decoded, snapshot)
+	}
+
+	m.DisableMetrics()
+	if _, err := m.GetMetricsSnapshot("payments"); err == nil {
+		t.Error("GetMetricsSnapshot() error = nil with metrics disabled")
+	}
+}
+
+func TestManager_MetricsByVersion(t *testing.T) {
+	libs := make(map[string]fakeLib)
+	for _, v := range []string{"1.0.0", "2.0.0", "3.0.0"} {
+		libs[v] = newFakeLib(&fakeBureau{name: "payments", version: v}, map[string]InvokeFunc{"Pay": returning(v)})
+	}
+	useFakeOpener(t, libs)
+	fake := clocktest.NewFake(time.Date(2024, 11, 16, 12, 0, 0, 0, time.UTC))
+	m := newTestManager(t, func(config *Config) {
+		config.EnableMetrics = true
+		config.MetricsVersions = 2
+	}, WithClock(fake))
+	path := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+	upgrade := func(version string, calls int) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(version), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.LoadPlugin(path); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < calls; i++ {
+			if _, err := m.Call(context.Background(), "payments", "Pay"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fake.Advance(time.Second)
+	}
+
+	upgrade("1.0.0", 2)
+	upgrade("2.0.0", 1)
+	snapshot, err := m.GetMetricsSnapshot("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1, v2 := snapshot.Versions["1.0.0"].Methods["Pay"].Count, snapshot.Versions["2.0.0"].Methods["Pay"].Count; v1 != 2 || v2 != 1 {
+		t.Errorf("Pay calls by version = 1.0.0: %d, 2.0.0: %d, want 2 and 1", v1, v2)
+	}
+	merged := MergeMethodSnapshots(snapshot.Versions["1.0.0"].Methods["Pay"], snapshot.Versions["2.0.0"].Methods["Pay"])
+	if total := snapshot.Methods["Pay"]; merged.Count != total.Count || merged.Histogram.Total() != total.Histogram.Total() {
+		t.Errorf("merged versions = %d calls, want the %d of all versions", merged.Count, total.Count)
+	}
+
+	// A third version drops the one called least recently; the totals keep its calls
+	upgrade("3.0.0", 1)
+	snapshot, err = m.GetMetricsSnapshot("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := snapshot.Versions["1.0.0"]; ok || len(snapshot.Versions) != 2 {
+		t.Errorf("versions = %v, want 2.0.0 and 3.0.0", snapshot.Versions)
+	}
+	if got := snapshot.Methods["Pay"].Count; got != 4 {
+		t.Errorf("Pay Count = %d, want 4", got)
+	}
+}
+
+func TestManager_RecordsOperations(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning("ok")}),
+		"v2": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, map[string]InvokeFunc{"Pay": returning("ok")}),
+		"v3": newFakeLib(&fakeBureau{name: "payments", version: "3.0.0", initErr: errors.New("dial failed")},
+			map[string]InvokeFunc{"Pay": returning("ok")}),
+	})
+	m := newTestManager(t, func(config *Config) { config.EnableMetrics = true })
+	path := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+	load := func(content string) error {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return m.LoadPlugin(path)
+	}
+
+	if err := load("v1"); err != nil {
+		t.Fatal(err)
+	}
+	// An unchanged file is not opened again
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := load("v2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := load("v3"); err == nil {
+		t.Fatal("LoadPlugin() error = nil for a plugin whose Init fails")
+	}
+
+	snapshot, err := m.GetMetricsSnapshot("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[Operation][2]int64{ // count, failures
+		OpLoad:    {3, 0},
+		OpInit:    {3, 1},
+		OpUpgrade: {2, 1},
+		OpFree:    {1, 0}, // the v3 plugin, discarded after its Init failed
+	}
+	if len(snapshot.Operations) != len(want) {
+		t.Errorf("Operations = %+v, want %v", snapshot.Operations, want)
+	}
+	for op, counts := range want {
+		got := snapshot.Operations[op]
+		if got.Count != counts[0] || got.Failures != counts[1] {
+			t.Errorf("%s: count %d, failures %d, want %d and %d", op, got.Count, got.Failures, counts[0], counts[1])
+		}
+	}
+	if load := snapshot.Operations[OpLoad]; load.MinTime <= 0 || load.MaxTime < load.MinTime {
+		t.Errorf("load durations min %v max %v, want them timed", load.MinTime, load.MaxTime)
+	}
+	if upgrade := snapshot.Operations[OpUpgrade]; upgrade.TotalTime != 0 {
+		t.Errorf("upgrade total time = %v, want upgrades left untimed", upgrade.TotalTime)
+	}
+	if len(snapshot.Methods) != 0 {
+		t.Errorf("Methods = %v, want operations kept apart from calls", snapshot.Methods)
+	}
+}
+
+func TestManager_InFlight(t *testing.T) {
+	const calls = 20
+	release := make(chan struct{})
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{
+			"Pay": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				<-release
+				return "ok", nil
+			},
+			"Refund": returning("ok"),
+		}),
+	})
+	m := newTestManager(t, func(config *Config) { config.EnableMetrics = true })
+	loadTestPlugin(t, m, "payments", "v1")
+
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Call(context.Background(), "payments", "Pay")
+		}()
+	}
+	waitFor(t, "the calls to start", func() bool { return m.GetInFlight("payments") == calls })
+	m.Call(context.Background(), "payments", "Refund")
+	m.Call(context.Background(), "payments", "Missing")
+
+	snapshot, err := m.GetMetricsSnapshot("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.InFlight != calls {
+		t.Errorf("snapshot InFlight = %d, want %d", snapshot.InFlight, calls)
+	}
+	if pay := snapshot.Methods["Pay"]; pay.InFlight != calls || pay.MaxInFlight != calls {
+		t.Errorf("Pay in flight %d, max %d, want %d and %d", pay.InFlight, pay.MaxInFlight, calls, calls)
+	}
+	if refund := snapshot.Methods["Refund"]; refund.InFlight != 0 || refund.MaxInFlight != 1 {
+		t.Errorf("Refund in flight %d, max %d, want 0 and 1", refund.InFlight, refund.MaxInFlight)
+	}
+
+	close(release)
+	wg.Wait()
+	if got := m.GetInFlight("payments"); got != 0 {
+		t.Errorf("GetInFlight() = %d after the calls returned, want 0", got)
+	}
+	snapshot, err = m.GetMetricsSnapshot("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pay := snapshot.Methods["Pay"]; pay.InFlight != 0 || pay.MaxInFlight != calls {
+		t.Errorf("Pay in flight %d, max %d after the calls returned, want 0 and %d", pay.InFlight, pay.MaxInFlight, calls)
+	}
+
+	// The high-water marks go with a reset
+	m.ResetMetrics()
+	m.Call(context.Background(), "payments", "Refund")
+	snapshot, err = m.GetMetricsSnapshot("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := snapshot.Methods["Pay"]; ok {
+		t.Error("Pay metrics survived ResetMetrics")
+	}
+	if got := m.GetInFlight("unknown"); got != 0 {
+		t.Errorf("GetInFlight() = %d for an unknown plugin, want 0", got)
+	}
+}
+
+func TestMethodMetrics_LastCalls(t *testing.T) {
+	start := time.Date(2024, 11, 16, 12, 0, 0, 0, time.UTC)
+	fake := clocktest.NewFake(start)
+	metrics := NewPluginMetrics(true)
+	metrics.clock = fake
+
+	metrics.RecordCall("payments", "Pay", time.Millisecond, nil)
+	fake.Advance(time.Minute)
+	long := strings.Repeat("é", maxErrorMessageLength)
+	metrics.RecordCall("payments", "Pay", time.Millisecond, errors.New(long))
+	fake.Advance(time.Minute)
+	metrics.RecordCall("payments", "Pay", time.Millisecond, nil)
+
+	pm, err := metrics.GetPluginMetrics("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := pm.snapshot("payments", fake.Now()).Methods["Pay"]
+	if want := start.Add(2 * time.Minute); !s.LastCall.Equal(want) || !s.LastSuccess.Equal(want) {
+		t.Errorf("LastCall %v, LastSuccess %v, want both %v", s.LastCall, s.LastSuccess, want)
+	}
+	if want := start.Add(time.Minute); !s.LastError.Equal(want) {
+		t.Errorf("LastError = %v, want %v", s.LastError, want)
+	}
+	msg := s.LastErrorMessage
+	if len(msg) > maxErrorMessageLength || !utf8.ValidString(msg) || !strings.HasSuffix(msg, "…") {
+		t.Errorf("LastErrorMessage is %d bytes, valid UTF-8 %v, want it cut to %d with an ellipsis",
+			len(msg), utf8.ValidString(msg), maxErrorMessageLength)
+	}
+
+	// A method that never failed has no error time or message
+	metrics.RecordCall("payments", "Refund", time.Millisecond, nil)
+	pm, _ = metrics.GetPluginMetrics("payments")
+	if s := pm.snapshot("payments", fake.Now()).Methods["Refund"]; !s.LastError.IsZero() || s.LastErrorMessage != "" {
+		t.Errorf("Refund LastError %v, message %q, want none", s.LastError, s.LastErrorMessage)
+	}
+}
+
+func TestMethodMetrics_RollingWindows(t *testing.T) {
+	fake := clocktest.NewFake(time.Date(2024, 11, 16, 12, 0, 0, 0, time.UTC))
+	metrics := NewPluginMetrics(true)
+	metrics.clock = fake
+	rates := func() MethodSnapshot {
+		t.Helper()
+		pm, err := metrics.GetPluginMetrics("payments")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pm.snapshot("payments", fake.Now()).Methods["Pay"]
+	}
+
+	// 60 calls over the first minute, a quarter of them failing
+	for i := 0; i < 60; i++ {
+		var err error
+		if i%4 == 0 {
+			err = errors.New("declined")
+		}
+		metrics.RecordCall("payments", "Pay", time.Millisecond, err)
+		fake.Advance(time.Second)
+	}
+	if s := rates(); s.Rate5m != 60.0/300 || s.ErrorRate5m != 0.25 || s.Rate1m < 0.8 || s.ErrorRate1m == 0 {
+		t.Errorf("after a minute: rate %v/%v, error rate %v/%v; want all 60 calls in the 5m window",
+			s.Rate1m, s.Rate5m, s.ErrorRate1m, s.ErrorRate5m)
+	}
+
+	// Two quiet minutes empty the 1m window, not the 5m one
+	fake.Advance(2 * time.Minute)
+	if s := rates(); s.Rate1m != 0 || s.ErrorRate1m != 0 || s.Rate5m != 60.0/300 {
+		t.Errorf("after two quiet minutes: rate %v/%v, error rate %v; want only the 5m window to hold calls",
+			s.Rate1m, s.Rate5m, s.ErrorRate1m)
+	}
+
+	// A call now counts in both; the old ones leave the 5m window in time
+	metrics.RecordCall("payments", "Pay", time.Millisecond, nil)
+	if s := rates(); s.Rate1m != 1.0/60 || s.ErrorRate1m != 0 {
+		t.Errorf("rate 1m = %v, error rate %v; want one successful call", s.Rate1m, s.ErrorRate1m)
+	}
+	fake.Advance(5 * time.Minute)
+	if s := rates(); s.Rate5m != 0 || s.Count != 61 {
+		t.Errorf("after five quiet minutes: rate 5m %v, count %d; want an empty window and the total kept", s.Rate5m, s.Count)
+	}
+}
+
+func TestRecentCalls_Size(t *testing.T) {
+	// Every function of every plugin keeps its own windows
+	size := unsafe.Sizeof(recentCalls{}) + 2*unsafe.Sizeof(timeWindow{})
+	if size > 256 {
+		t.Errorf("recent calls of a function take %d bytes, want at most 256", size)
+	}
+}
+
+// recordingObserver keeps what a CallObserver is told
+type recordingObserver struct {
+	mu       sync.Mutex
+	inFlight int
+	records  []string
+	closed   bool
+}
+
+func (o *recordingObserver) Begin(ctx context.Context, pluginName string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.inFlight++
+}
+
+func (o *recordingObserver) End(ctx context.Context, pluginName string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.inFlight--
+}
+
+func (o *recordingObserver) Record(ctx context.Context, pluginName, version, funcName string, duration time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.records = append(o.records, pluginName+" "+version+" "+funcName+" "+fmt.Sprint(err != nil, duration == 0))
+}
+
+func (o *recordingObserver) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+}
+
+func TestWithCallObserver(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning("ok")}),
+	})
+	observer := &recordingObserver{}
+	m := newTestManager(t, func(config *Config) {
+		config.PluginConfigs["payments"] = PluginSpecificConfig{MetricLabels: map[string]string{"team": "billing"}}
+	}, WithCallObserver(observer))
+	loadTestPlugin(t, m, "payments", "v1")
+
+	ctx := context.Background()
+	m.Call(ctx, "payments", "Pay")
+	m.Call(ctx, "payments", "Unknown")
+	if err := m.TripBreaker("payments", ""); err != nil {
+		t.Fatal(err)
+	}
+	m.Call(ctx, "payments", "Pay")
+	m.Close()
+
+	// Refused calls are recorded without a duration, unknown functions not at all
+	want := []string{"payments 1.0.0 Pay false false", "payments 1.0.0 Pay true true"}
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	if !reflect.DeepEqual(observer.records, want) || observer.inFlight != 0 || !observer.closed {
+		t.Errorf("observer saw %q with %d calls in flight, closed %v; want %q, none in flight and closed",
+			observer.records, observer.inFlight, observer.closed, want)
+	}
+	if labels := m.MetricLabels("payments"); labels["team"] != "billing" {
+		t.Errorf("MetricLabels() = %v, want team billing", labels)
+	}
+}
@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// MetricsRow holds the metrics of one method of one plugin version, as written by
+// WriteMetricsCSV and WriteMetricsJSON. The fields and their order are stable: new ones
+// are only ever added at the end.
+type MetricsRow struct {
+	Plugin    string        `json:"plugin"`
+	Version   string        `json:"version"`
+	Method    string        `json:"method"`
+	Count     int64         `json:"count"`
+	Errors    int64         `json:"errors"`
+	Timeouts  int64         `json:"timeouts"`
+	ErrorRate float64       `json:"error_rate"`
+	TotalTime time.Duration `json:"total_time_ns"`
+	MinTime   time.Duration `json:"min_time_ns"`
+	AvgTime   time.Duration `json:"avg_time_ns"`
+	MaxTime   time.Duration `json:"max_time_ns"`
+	P50       time.Duration `json:"p50_ns"`
+	P95       time.Duration `json:"p95_ns"`
+	P99       time.Duration `json:"p99_ns"`
+}
+
+// metricsColumns is the CSV header, in the order of the fields of MetricsRow
+var metricsColumns = []string{
+	"plugin", "version", "method", "count", "errors", "timeouts", "error_rate",
+	"total_time_ns", "min_time_ns", "avg_time_ns", "max_time_ns", "p50_ns", "p95_ns", "p99_ns",
+}
+
+// record returns the CSV fields of r, matching metricsColumns
+func (r MetricsRow) record() []string {
+	return []string{
+		r.Plugin, r.Version, r.Method,
+		strconv.FormatInt(r.Count, 10),
+		strconv.FormatInt(r.Errors, 10),
+		strconv.FormatInt(r.Timeouts, 10),
+		strconv.FormatFloat(r.ErrorRate, 'g', -1, 64),
+		strconv.FormatInt(int64(r.TotalTime), 10),
+		strconv.FormatInt(int64(r.MinTime), 10),
+		strconv.FormatInt(int64(r.AvgTime), 10),
+		strconv.FormatInt(int64(r.MaxTime), 10),
+		strconv.FormatInt(int64(r.P50), 10),
+		strconv.FormatInt(int64(r.P95), 10),
+		strconv.FormatInt(int64(r.P99), 10),
+	}
+}
+
+// WriteMetricsCSV writes a header and one row per method of every version of the loaded
+// plugins that keeps metrics, ordered by plugin, version and method. Durations are in
+// nanoseconds. Rows are written as each plugin is read, so the export is never held in
+// memory whole.
+func (m *Manager) WriteMetricsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(metricsColumns); err != nil {
+		return err
+	}
+	err := m.metricsRows(func(row MetricsRow) error {
+		return cw.Write(row.record())
+	})
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteMetricsJSON writes the rows of WriteMetricsCSV as a JSON array of objects, one
+// object per line
+func (m *Manager) WriteMetricsJSON(w io.Writer) error {
+	sep := "[\n"
+	err := m.metricsRows(func(row MetricsRow) error {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		sep = ",\n"
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if sep == "[\n" {
+		_, err = io.WriteString(w, "[]\n")
+	} else {
+		_, err = io.WriteString(w, "\n]\n")
+	}
+	return err
+}
+
+// metricsRows calls fn with the rows of every loaded plugin in order, reading one plugin's
+// metrics at a time, and stops at the first error
+func (m *Manager) metricsRows(fn func(MetricsRow) error) error {
+	if !m.metrics.enabled.Load() {
+		return fmt.Errorf("metrics are disabled")
+	}
+	var names []string
+	for _, info := range m.ListPlugins() {
+		names = append(names, info.Name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		snapshot, err := m.GetMetricsSnapshot(name)
+		if err != nil {
+			continue
+		}
+		for _, version := range slices.Sorted(maps.Keys(snapshot.Versions)) {
+			methods := snapshot.Versions[version].Methods
+			for _, method := range slices.Sorted(maps.Keys(methods)) {
+				s := methods[method]
+				row := MetricsRow{
+					Plugin:    name,
+					Version:   version,
+					Method:    method,
+					Count:     s.Count,
+					Errors:    s.Errors,
+					Timeouts:  s.Timeouts,
+					ErrorRate: s.ErrorRate(),
+					TotalTime: s.TotalTime,
+					MinTime:   s.MinTime,
+					AvgTime:   s.AvgTime,
+					MaxTime:   s.MaxTime,
+					P50:       s.Quantile(0.5),
+					P95:       s.Quantile(0.95),
+					P99:       s.Quantile(0.99),
+				}
+				if err := fn(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
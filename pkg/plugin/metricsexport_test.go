@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// newExportManager loads two plugins with fixed call metrics, one of them across two
+// versions, the second of which needs quoting in CSV
+func newExportManager(t *testing.T) *Manager {
+	t.Helper()
+	useFakeOpener(t, map[string]fakeLib{
+		"payments": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+		"ledger":   newFakeLib(&fakeBureau{name: "ledger", version: "2.0.0"}, map[string]InvokeFunc{}),
+	})
+	m := newTestManager(t, func(config *Config) { config.EnableMetrics = true })
+	for _, name := range []string{"payments", "ledger"} {
+		loadTestPlugin(t, m, name, name)
+	}
+
+	m.metrics.RecordVersionCall("payments", "1.0.0", "Pay", 2*time.Millisecond, nil)
+	m.metrics.RecordVersionCall("payments", "1.0.0", "Pay", 4*time.Millisecond, errors.New("declined"))
+	m.metrics.RecordVersionCall("payments", "1.0.0", "Refund", 12*time.Millisecond, context.DeadlineExceeded)
+	m.metrics.RecordVersionCall("payments", `1.1.0+build "7",linux`, "Pay", 300*time.Microsecond, nil)
+	m.metrics.RecordVersionCall("ledger", "2.0.0", "Post", time.Second, nil)
+	return m
+}
+
+// checkGolden compares got with testdata/name, or rewrites the file under -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file; run go test -update if the change is intended\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestManager_WriteMetricsCSV(t *testing.T) {
+	m := newExportManager(t)
+	var buf bytes.Buffer
+	if err := m.WriteMetricsCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "metrics.csv.golden", buf.Bytes())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || records[4][1] != `1.1.0+build "7",linux` {
+		t.Errorf("parsed rows = %q, want a header, 4 rows and the quoted version intact", records)
+	}
+
+	if err := m.WriteMetricsCSV(failingWriter{}); err == nil {
+		t.Error("WriteMetricsCSV() error = nil with a failing writer")
+	}
+	m.DisableMetrics()
+	if err := m.WriteMetricsCSV(&bytes.Buffer{}); err == nil {
+		t.Error("WriteMetricsCSV() error = nil with metrics disabled")
+	}
+}
+
+func TestManager_WriteMetricsJSON(t *testing.T) {
+	m := newExportManager(t)
+	var buf bytes.Buffer
+	if err := m.WriteMetricsJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "metrics.json.golden", buf.Bytes())
+
+	var rows []MetricsRow
+	if err := json.Unmarshal(buf.Bytes(), &rows); err != nil {
+		t.Fatal(err)
+	}
+	want := MetricsRow{Plugin: "ledger", Version: "2.0.0", Method: "Post", Count: 1,
+		TotalTime: time.Second, MinTime: time.Second, AvgTime: time.Second, MaxTime: time.Second,
+		P50: time.Second, P95: time.Second, P99: time.Second}
+	if len(rows) != 4 || !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("decoded rows = %+v, want 4 starting with %+v", rows, want)
+	}
+
+	if err := m.WriteMetricsJSON(failingWriter{}); err == nil {
+		t.Error("WriteMetricsJSON() error = nil with a failing writer")
+	}
+	m.ResetMetrics()
+	buf.Reset()
+	if err := m.WriteMetricsJSON(&buf); err != nil || buf.String() != "[]\n" {
+		t.Errorf("WriteMetricsJSON() = %q, %v without metrics, want an empty array", buf.String(), err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock"
+)
+
+// metricsReport holds the totals of the last metrics summary, so that the next one covers
+// the calls made since
+type metricsReport struct {
+	at     time.Time
+	totals map[string]MethodSnapshot // by plugin, across its functions
+}
+
+// startMetricsReport logs a summary of each plugin's calls every interval until the
+// manager closes. The timer is armed before the goroutine starts, so that the first report
+// is due interval after the call however late the goroutine runs.
+func (m *Manager) startMetricsReport(interval time.Duration) {
+	report := &metricsReport{at: m.clock.Now(), totals: make(map[string]MethodSnapshot)}
+	timer := m.clock.NewTimer(interval)
+	m.eg.Go(func() error {
+		return m.metricsReportLoop(report, timer, interval)
+	})
+}
+
+// metricsReportLoop reports whenever timer fires, then arms it again for interval
+func (m *Manager) metricsReportLoop(report *metricsReport, timer clock.Timer, interval time.Duration) error {
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return nil
+		case <-timer.C():
+			m.reportMetrics(report)
+			timer.Reset(interval)
+		}
+	}
+}
+
+// reportMetrics logs one line per loaded plugin with its calls, errors and latency since
+// the last report and the state of its circuit breaker, then moves the report on
+func (m *Manager) reportMetrics(report *metricsReport) {
+	if !m.metrics.enabled.Load() {
+		return
+	}
+	now := m.clock.Now()
+	period := now.Sub(report.at)
+	totals := make(map[string]MethodSnapshot)
+
+	plugins := m.ListPlugins()
+	slices.SortFunc(plugins, func(a, b PluginInfo) int { return cmp.Compare(a.Name, b.Name) })
+	for _, info := range plugins {
+		snapshot, err := m.GetMetricsSnapshot(info.Name)
+		if err != nil {
+			continue
+		}
+		total := MergeMethodSnapshots(slices.Collect(maps.Values(snapshot.Methods))...)
+		totals[info.Name] = total
+		delta := callsSince(total, report.totals[info.Name])
+		m.pluginLogger(info.Name).Info("Plugin metrics",
+			"version", info.Version,
+			"period", period,
+			"calls", delta.Count,
+			"errors", delta.Errors,
+			"avg", delta.AvgTime,
+			"p95", clampQuantile(delta.Histogram.Quantile(0.95, total.MaxTime), 0, total.MaxTime),
+			"breaker", info.Breaker.String(),
+		)
+	}
+	report.at, report.totals = now, totals
+}
+
+// callsSince returns the calls in total that are not in previous. Totals that went down,
+// because the metrics were reset or dropped meanwhile, count in full.
+func callsSince(total, previous MethodSnapshot) MethodSnapshot {
+	if total.Count < previous.Count || !slices.Equal(total.Histogram.Bounds, previous.Histogram.Bounds) {
+		previous = MethodSnapshot{}
+	}
+	delta := MethodSnapshot{
+		Count:     total.Count - previous.Count,
+		Errors:    total.Errors - previous.Errors,
+		TotalTime: total.TotalTime - previous.TotalTime,
+		Histogram: LatencyHistogram{
+			Bounds: total.Histogram.Bounds,
+			Counts: append([]int64(nil), total.Histogram.Counts...),
+		},
+	}
+	for i, n := range previous.Histogram.Counts {
+		delta.Histogram.Counts[i] -= n
+	}
+	delta.AvgTime = average(int64(delta.TotalTime), delta.Count)
+	return delta
+}
@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
+)
+
+func TestManager_MetricsReport(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning("ok")}),
+	})
+	logger := &testLogger{}
+	fake := clocktest.NewFake(time.Date(2024, 11, 16, 12, 0, 0, 0, time.UTC))
+	m := newTestManager(t, func(config *Config) {
+		config.EnableMetrics = true
+		config.GCInterval = 0
+		config.MetricsReportInterval = time.Minute
+	}, WithClock(fake), WithLogger(logger))
+	loadTestPlugin(t, m, "payments", "v1")
+	armed := fake.Timers()
+
+	reports := func(n int) []map[string]interface{} {
+		t.Helper()
+		fake.Advance(time.Minute)
+		waitFor(t, "the metrics report", func() bool {
+			return len(logger.fields("INFO: Plugin metrics")) == n
+		})
+		// The next report is due once the reporter has armed its timer again
+		waitFor(t, "the reporter to wait for the next report", func() bool {
+			return fake.Timers() == armed
+		})
+		return logger.fields("INFO: Plugin metrics")
+	}
+
+	m.metrics.RecordVersionCall("payments", "1.0.0", "Pay", 2*time.Millisecond, nil)
+	m.metrics.RecordVersionCall("payments", "1.0.0", "Pay", 4*time.Millisecond, errors.New("declined"))
+	first := reports(1)[0]
+	if first["plugin"] != "payments" || first["version"] != "1.0.0" || first["period"] != time.Minute ||
+		first["calls"] != int64(2) || first["errors"] != int64(1) || first["avg"] != 3*time.Millisecond ||
+		first["breaker"] != "closed" {
+		t.Errorf("first report = %v, want 2 calls, 1 error, avg 3ms and a closed breaker over a minute", first)
+	}
+	if p95, _ := first["p95"].(time.Duration); p95 <= 2*time.Millisecond || p95 > 4*time.Millisecond {
+		t.Errorf("first report p95 = %v, want within (2ms, 4ms]", first["p95"])
+	}
+
+	// Each report covers the calls since the previous one, not the totals
+	m.metrics.RecordVersionCall("payments", "1.0.0", "Pay", 10*time.Millisecond, nil)
+	second := reports(2)[1]
+	if second["calls"] != int64(1) || second["errors"] != int64(0) || second["avg"] != 10*time.Millisecond {
+		t.Errorf("second report = %v, want 1 call, no error, avg 10ms", second)
+	}
+
+	// Metrics reset meanwhile count from zero rather than going negative
+	m.ResetMetrics()
+	m.metrics.RecordVersionCall("payments", "1.0.0", "Pay", time.Millisecond, nil)
+	third := reports(3)[2]
+	if third["calls"] != int64(1) || third["errors"] != int64(0) {
+		t.Errorf("report after a reset = %v, want 1 call, no error", third)
+	}
+}
@@ -0,0 +1,190 @@
+// Package otelexport records the plugin calls of a plugin.Manager with OpenTelemetry
+// instruments:
+//
+//	m, err := plugin.NewManager(ctx, config, otelexport.WithMetrics(provider))
+//
+// It is a module of its own, so programs that do not use it do not depend on the
+// OpenTelemetry packages.
+package otelexport
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/zyanho/chameleon/pkg/plugin"
+)
+
+// Instrument names recorded by WithMetrics. They, and their attributes, are stable:
+// dashboards and alerts may rely on them.
+const (
+	// InstrumentCalls counts calls; attributes plugin, version, function, outcome
+	InstrumentCalls = "chameleon.plugin.calls"
+	// InstrumentCallErrors counts the calls that failed or were refused; attributes
+	// plugin, version, function, outcome
+	InstrumentCallErrors = "chameleon.plugin.call.errors"
+	// InstrumentCallDuration is the histogram of the durations of the calls that reached
+	// a function; attributes plugin, version, function, outcome
+	InstrumentCallDuration = "chameleon.plugin.call.duration"
+	// InstrumentInFlight is the number of calls running; attribute plugin
+	InstrumentInFlight = "chameleon.plugin.calls.in_flight"
+	// InstrumentBreakerState is 0 while the circuit breaker is closed, 1 while it is open
+	// and 2 while it is half-open; attribute plugin
+	InstrumentBreakerState = "chameleon.plugin.breaker.state"
+)
+
+// Values of the outcome attribute
+const (
+	OutcomeSuccess  = "success"
+	OutcomeError    = "error"
+	OutcomeTimeout  = "timeout"
+	OutcomeRejected = "rejected" // by the circuit breaker or the concurrency limit
+)
+
+// WithMetrics records the plugin calls of the manager with instruments of the meter
+// provider mp. Calls are recorded whether or not EnableMetrics is set. The attributes
+// are the plugin, its version, the function and the outcome, so their cardinality is
+// bounded by the functions the plugins export; arguments are never recorded. The
+// plugin's MetricLabels are added to every attribute set. Errors creating the
+// instruments go to otel.Handle.
+func WithMetrics(mp metric.MeterProvider) plugin.ManagerOption {
+	return func(m *plugin.Manager) {
+		if mp == nil {
+			return
+		}
+		observer, err := newObserver(m, mp.Meter("github.com/zyanho/chameleon/pkg/plugin"))
+		if err != nil {
+			otel.Handle(err)
+		}
+		plugin.WithCallObserver(observer)(m)
+	}
+}
+
+// observer records calls with OpenTelemetry instruments
+type observer struct {
+	m            *plugin.Manager
+	labels       sync.Map // map[string]*pluginLabels
+	calls        metric.Int64Counter
+	callErrors   metric.Int64Counter
+	callDuration metric.Float64Histogram
+	inFlight     metric.Int64UpDownCounter
+	breakerState metric.Registration
+}
+
+// newObserver creates the instruments of meter. The API hands out working no-op
+// instruments along with an error, so the observer is usable whatever the error.
+func newObserver(m *plugin.Manager, meter metric.Meter) (*observer, error) {
+	var errs [4]error
+	o := &observer{m: m}
+	o.calls, errs[0] = meter.Int64Counter(InstrumentCalls,
+		metric.WithDescription("Plugin calls"), metric.WithUnit("{call}"))
+	o.callErrors, errs[1] = meter.Int64Counter(InstrumentCallErrors,
+		metric.WithDescription("Plugin calls that failed or were refused"), metric.WithUnit("{call}"))
+	o.callDuration, errs[2] = meter.Float64Histogram(InstrumentCallDuration,
+		metric.WithDescription("Duration of plugin calls"), metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBoundaries(plugin.DefaultLatencyBuckets())...))
+	o.inFlight, errs[3] = meter.Int64UpDownCounter(InstrumentInFlight,
+		metric.WithDescription("Plugin calls running"), metric.WithUnit("{call}"))
+
+	breakerState, err := meter.Int64ObservableGauge(InstrumentBreakerState,
+		metric.WithDescription("Circuit breaker state: 0 closed, 1 open, 2 half-open"))
+	if err != nil {
+		return o, errors.Join(append(errs[:], err)...)
+	}
+	o.breakerState, err = meter.RegisterCallback(func(_ context.Context, obs metric.Observer) error {
+		for _, info := range m.ListPlugins() {
+			obs.ObserveInt64(breakerState, int64(info.Breaker), o.pluginAttributes(info.Name))
+		}
+		return nil
+	}, breakerState)
+	return o, errors.Join(append(errs[:], err)...)
+}
+
+// durationBoundaries converts bucket bounds to seconds
+func durationBoundaries(bounds []time.Duration) []float64 {
+	seconds := make([]float64, len(bounds))
+	for i, b := range bounds {
+		seconds[i] = b.Seconds()
+	}
+	return seconds
+}
+
+// pluginLabels are a plugin's MetricLabels as attributes
+type pluginLabels struct {
+	from  map[string]string
+	attrs []attribute.KeyValue
+}
+
+// labelsFor returns the MetricLabels of a plugin as attributes, converted anew when they change
+func (o *observer) labelsFor(pluginName string) []attribute.KeyValue {
+	labels := o.m.MetricLabels(pluginName)
+	if len(labels) == 0 {
+		return nil
+	}
+	if val, ok := o.labels.Load(pluginName); ok && maps.Equal(val.(*pluginLabels).from, labels) {
+		return val.(*pluginLabels).attrs
+	}
+	l := &pluginLabels{from: labels, attrs: make([]attribute.KeyValue, 0, len(labels))}
+	for k, v := range labels {
+		l.attrs = append(l.attrs, attribute.String(k, v))
+	}
+	o.labels.Store(pluginName, l)
+	return l.attrs
+}
+
+// pluginAttributes returns the attributes of the per-plugin instruments
+func (o *observer) pluginAttributes(pluginName string) metric.MeasurementOption {
+	return metric.WithAttributes(append([]attribute.KeyValue{attribute.String("plugin", pluginName)},
+		o.labelsFor(pluginName)...)...)
+}
+
+func (o *observer) Begin(ctx context.Context, pluginName string) {
+	o.inFlight.Add(ctx, 1, o.pluginAttributes(pluginName))
+}
+
+func (o *observer) End(ctx context.Context, pluginName string) {
+	o.inFlight.Add(ctx, -1, o.pluginAttributes(pluginName))
+}
+
+func (o *observer) Record(ctx context.Context, pluginName, version, funcName string, duration time.Duration, err error) {
+	outcome := callOutcome(err)
+	attrs := metric.WithAttributes(append([]attribute.KeyValue{
+		attribute.String("plugin", pluginName),
+		attribute.String("version", version),
+		attribute.String("function", funcName),
+		attribute.String("outcome", outcome),
+	}, o.labelsFor(pluginName)...)...)
+	o.calls.Add(ctx, 1, attrs)
+	if err != nil {
+		o.callErrors.Add(ctx, 1, attrs)
+	}
+	if outcome != OutcomeRejected {
+		o.callDuration.Record(ctx, duration.Seconds(), attrs)
+	}
+}
+
+func (o *observer) Close() {
+	if o.breakerState != nil {
+		o.breakerState.Unregister()
+	}
+}
+
+// callOutcome classifies the error of a call for the outcome attribute
+func callOutcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.As(err, new(plugin.ErrCircuitOpen)), errors.As(err, new(plugin.ErrTooManyConcurrentCalls)):
+		return OutcomeRejected
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, new(plugin.ErrPluginTimeout)):
+		return OutcomeTimeout
+	default:
+		return OutcomeError
+	}
+}
@@ -0,0 +1,215 @@
+package otelexport
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/zyanho/chameleon/pkg/plugin"
+)
+
+// payments is a plugin served from memory
+type payments struct{}
+
+func (payments) Name() string                   { return "payments" }
+func (payments) Version() string                { return "1.0.0" }
+func (payments) Init(args ...interface{}) error { return nil }
+func (payments) Free() error                    { return nil }
+
+// newTestManager creates a manager recording to provider with payments loaded from
+// funcs, its config changed by configure
+func newTestManager(t *testing.T, provider *sdkmetric.MeterProvider, funcs map[string]plugin.InvokeFunc,
+	configure func(*plugin.PluginSpecificConfig)) *plugin.Manager {
+	t.Helper()
+	backend := plugin.NewMemoryBackend()
+	backend.Register("mem/payments", payments{}, funcs)
+	config := plugin.DefaultConfig()
+	config.PluginDir = t.TempDir()
+	config.AllowHotReload = false
+	pluginConfig := plugin.PluginSpecificConfig{Options: map[string]interface{}{plugin.OptionBackend: "memory"}}
+	if configure != nil {
+		configure(&pluginConfig)
+	}
+	config.PluginConfigs["payments"] = pluginConfig
+
+	m, err := plugin.NewManager(context.Background(), config,
+		plugin.WithBackend("memory", backend), WithMetrics(provider))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	if err := m.LoadPlugin("mem/payments"); err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	return m
+}
+
+// returning builds an InvokeFunc returning a fixed value
+func returning(v interface{}) plugin.InvokeFunc {
+	return func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return v, nil
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	m := newTestManager(t, provider, map[string]plugin.InvokeFunc{
+		"Pay": returning("ok"),
+		"Refund": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			return nil, errors.New("declined")
+		},
+		"Settle": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			return nil, context.DeadlineExceeded
+		},
+	}, nil)
+
+	ctx := context.Background()
+	m.Call(ctx, "payments", "Pay")
+	m.Call(ctx, "payments", "Pay")
+	m.Call(ctx, "payments", "Refund")
+	m.Call(ctx, "payments", "Settle")
+	m.Call(ctx, "payments", "Unknown")
+	if err := m.TripBreaker("payments", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	m.Call(ctx, "payments", "Pay")
+	m.Call(ctx, "payments", "Unknown")
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]metricdata.Metrics)
+	for _, sm := range rm.ScopeMetrics {
+		for _, metric := range sm.Metrics {
+			got[metric.Name] = metric
+		}
+	}
+
+	calls := sumByOutcome(t, got[InstrumentCalls])
+	wantCalls := map[string]int64{
+		"Pay/" + OutcomeSuccess:    2,
+		"Refund/" + OutcomeError:   1,
+		"Settle/" + OutcomeTimeout: 1,
+		"Pay/" + OutcomeRejected:   1,
+	}
+	if len(calls) != len(wantCalls) {
+		t.Errorf("calls = %v, want %v", calls, wantCalls)
+	}
+	for key, want := range wantCalls {
+		if calls[key] != want {
+			t.Errorf("calls[%s] = %d, want %d", key, calls[key], want)
+		}
+	}
+
+	callErrors := sumByOutcome(t, got[InstrumentCallErrors])
+	if len(callErrors) != 3 || callErrors["Pay/"+OutcomeSuccess] != 0 {
+		t.Errorf("call errors = %v, want the failed and refused calls", callErrors)
+	}
+
+	duration, ok := got[InstrumentCallDuration].Data.(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("%s is %T, want a histogram", InstrumentCallDuration, got[InstrumentCallDuration].Data)
+	}
+	var recorded uint64
+	for _, point := range duration.DataPoints {
+		if outcome, _ := point.Attributes.Value("outcome"); outcome.AsString() == OutcomeRejected {
+			t.Errorf("duration recorded for a refused call")
+		}
+		if version, _ := point.Attributes.Value("version"); version.AsString() != "1.0.0" {
+			t.Errorf("version = %q, want 1.0.0", version.AsString())
+		}
+		recorded += point.Count
+	}
+	if recorded != 4 {
+		t.Errorf("durations recorded = %d, want 4", recorded)
+	}
+
+	inFlight, ok := got[InstrumentInFlight].Data.(metricdata.Sum[int64])
+	if !ok || len(inFlight.DataPoints) != 1 || inFlight.DataPoints[0].Value != 0 {
+		t.Errorf("%s = %+v, want 0 once the calls returned", InstrumentInFlight, got[InstrumentInFlight].Data)
+	}
+
+	state, ok := got[InstrumentBreakerState].Data.(metricdata.Gauge[int64])
+	if !ok || len(state.DataPoints) != 1 || state.DataPoints[0].Value != int64(plugin.StateOpen) {
+		t.Fatalf("%s = %+v, want open", InstrumentBreakerState, got[InstrumentBreakerState].Data)
+	}
+	if name, _ := state.DataPoints[0].Attributes.Value("plugin"); name.AsString() != "payments" {
+		t.Errorf("breaker state plugin = %q, want payments", name.AsString())
+	}
+}
+
+// sumByOutcome returns the values of a counter by function and outcome
+func sumByOutcome(t *testing.T, metric metricdata.Metrics) map[string]int64 {
+	t.Helper()
+	sum, ok := metric.Data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("%s is %T, want a sum", metric.Name, metric.Data)
+	}
+	values := make(map[string]int64)
+	for _, point := range sum.DataPoints {
+		if point.Attributes.Len() != 4 {
+			t.Errorf("%s has attributes %v, want plugin, version, function and outcome", metric.Name, point.Attributes.ToSlice())
+		}
+		fn, _ := point.Attributes.Value(attribute.Key("function"))
+		outcome, _ := point.Attributes.Value(attribute.Key("outcome"))
+		values[fn.AsString()+"/"+outcome.AsString()] += point.Value
+	}
+	return values
+}
+
+func TestWithMetrics_MetricLabels(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	m := newTestManager(t, provider, map[string]plugin.InvokeFunc{"Pay": returning("ok")}, func(config *plugin.PluginSpecificConfig) {
+		config.MetricLabels = map[string]string{"team": "billing", "tier": "1"}
+	})
+	ctx := context.Background()
+	m.Call(ctx, "payments", "Pay")
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	var points int
+	for _, sm := range rm.ScopeMetrics {
+		for _, metric := range sm.Metrics {
+			var sets []attribute.Set
+			switch data := metric.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, p := range data.DataPoints {
+					sets = append(sets, p.Attributes)
+				}
+			case metricdata.Gauge[int64]:
+				for _, p := range data.DataPoints {
+					sets = append(sets, p.Attributes)
+				}
+			case metricdata.Histogram[float64]:
+				for _, p := range data.DataPoints {
+					sets = append(sets, p.Attributes)
+				}
+			}
+			for _, set := range sets {
+				team, _ := set.Value("team")
+				tier, _ := set.Value("tier")
+				if team.AsString() != "billing" || tier.AsString() != "1" {
+					t.Errorf("%s has attributes %v, want team billing and tier 1", metric.Name, set.ToSlice())
+				}
+				points++
+			}
+		}
+	}
+	// calls, call duration, calls in flight and breaker state
+	if points != 4 {
+		t.Errorf("recorded %d data points, want 4", points)
+	}
+}
@@ -2,8 +2,10 @@ package plugin
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // Bureau defines the interface that all plugins must implement
@@ -14,15 +16,32 @@ type Bureau interface {
 	Free() error
 }
 
+// Configurable is implemented by plugins that read PluginSpecificConfig.Options. The
+// manager calls Configure with the merged options after Init, and again on the loaded
+// instance whenever UpdatePluginConfig changes them.
+type Configurable interface {
+	Configure(options map[string]interface{}) error
+}
+
 // InvokeFunc represents a plugin function with a context as its first parameter
 type InvokeFunc func(ctx context.Context, args ...interface{}) (interface{}, error)
 
 // Plugin wraps a plugin instance
 type Plugin struct {
 	sync.RWMutex
-	bureau Bureau
-	funcs  map[string]InvokeFunc
-	refs   int32
+	bureau   Bureau
+	funcs    map[string]InvokeFunc
+	refs     int32
+	manifest *Manifest
+	metadata *Metadata
+	// signatures describe the functions when the plugin exports them or they were reflected
+	signatures map[string]FuncSignature
+	hash       string  // SHA-256 of the artifact the plugin was opened from
+	shadow     string  // shadow copy the plugin was opened from, if any
+	backend    Backend // the backend that loaded the plugin, nil for plugins built directly
+	// size and openDuration describe the artifact and its opening; zero without artifacts
+	size         int64
+	openDuration time.Duration
 }
 
 func NewPlugin(b Bureau) *Plugin {
@@ -40,14 +59,56 @@ func (p *Plugin) Version() string {
 	return p.bureau.Version()
 }
 
+// Manifest returns the manifest the plugin was loaded with, or nil
+func (p *Plugin) Manifest() *Manifest {
+	return p.manifest
+}
+
+// Metadata returns the metadata the plugin exported, or nil
+func (p *Plugin) Metadata() *Metadata {
+	return p.metadata
+}
+
+// Signature returns the signature of a function, if the plugin describes it
+func (p *Plugin) Signature(name string) (FuncSignature, bool) {
+	sig, ok := p.signatures[name]
+	return sig, ok
+}
+
+// Hash returns the SHA-256 of the artifact the plugin was loaded from
+func (p *Plugin) Hash() string {
+	return p.hash
+}
+
+// ShadowPath returns the shadow copy the plugin was opened from, or an empty string
+func (p *Plugin) ShadowPath() string {
+	return p.shadow
+}
+
 func (p *Plugin) Init(args ...interface{}) error {
 	return p.bureau.Init(args...)
 }
 
+// Configure passes options to the plugin if it is Configurable
+func (p *Plugin) Configure(options map[string]interface{}) error {
+	if c, ok := p.bureau.(Configurable); ok {
+		return c.Configure(options)
+	}
+	return nil
+}
+
 func (p *Plugin) Free() error {
 	return p.bureau.Free()
 }
 
+// unload releases the plugin from the backend that loaded it
+func (p *Plugin) unload() error {
+	if p.backend == nil {
+		return nil
+	}
+	return p.backend.Unload(p)
+}
+
 func (p *Plugin) RegisterFunc(name string, fn InvokeFunc) {
 	p.funcs[name] = fn
 }
@@ -74,18 +135,87 @@ func (p *Plugin) Call(ctx context.Context, name string, args ...interface{}) (in
 	p.RUnlock()
 
 	if !ok {
-		return nil, ErrFuncNotFound{Name: name}
+		return nil, ErrFuncNotFound{
+			Name:        name,
+			Plugin:      p.Name(),
+			Suggestions: suggestFuncNames(name, p.GetFunctions()),
+		}
 	}
 
 	result, err := fn(ctx, args...)
 	return result, err
 }
 
+// hasFunction reports whether the plugin exports a function
+func (p *Plugin) hasFunction(name string) bool {
+	p.RLock()
+	defer p.RUnlock()
+	_, ok := p.funcs[name]
+	return ok
+}
+
 // GetFunctions returns a list of available functions
 func (p *Plugin) GetFunctions() []string {
+	p.RLock()
+	defer p.RUnlock()
 	funcs := make([]string, 0, len(p.funcs))
 	for name := range p.funcs {
 		funcs = append(funcs, name)
 	}
 	return funcs
 }
+
+// Limits for "did you mean" suggestions on unknown function names
+const (
+	maxSuggestions        = 3
+	maxSuggestionDistance = 2
+)
+
+// suggestFuncNames returns up to maxSuggestions candidates within maxSuggestionDistance
+// edits of name, closest first
+func suggestFuncNames(name string, candidates []string) []string {
+	type match struct {
+		name string
+		dist int
+	}
+	var matches []match
+	for _, c := range candidates {
+		if d := editDistance(name, c); d <= maxSuggestionDistance {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].dist != matches[j].dist {
+			return matches[i].dist < matches[j].dist
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var names []string
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		names = append(names, matches[i].name)
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlugin_CallSuggestsCloseMatches(t *testing.T) {
+	p := NewPlugin(&fakeBureau{name: "example-plugin", version: "1.0.0"})
+	for _, name := range []string{"Some1111", "Add", "SetValue"} {
+		p.RegisterFunc(name, returning(nil))
+	}
+
+	_, err := p.Call(context.Background(), "Somme1111")
+	var notFound ErrFuncNotFound
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Expected ErrFuncNotFound, got %v", err)
+	}
+	if notFound.Plugin != "example-plugin" {
+		t.Errorf("Plugin = %q, want example-plugin", notFound.Plugin)
+	}
+	if want := "function not found: Somme1111 (plugin example-plugin); did you mean Some1111?"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	_, err = p.Call(context.Background(), "Completely")
+	if !errors.As(err, &notFound) || len(notFound.Suggestions) != 0 {
+		t.Errorf("Expected no suggestions for distant name, got %v", err)
+	}
+}
+
+func TestSuggestFuncNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		want       []string
+	}{
+		{"Ad", []string{"Add", "Sub", "Mul"}, []string{"Add"}},
+		{"Get", []string{"Gets", "Set", "Bet", "Pet", "Getter"}, []string{"Bet", "Gets", "Pet"}},
+		{"Add", []string{"Sub"}, nil},
+		{"héllo", []string{"hello"}, []string{"hello"}},
+	}
+	for _, tt := range tests {
+		if got := suggestFuncNames(tt.name, tt.candidates); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("suggestFuncNames(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
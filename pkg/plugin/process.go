@@ -0,0 +1,434 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"plugin"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A process plugin is an executable the Manager runs as a child process. Host and child
+// speak JSON-RPC over the child's stdin and stdout, as the package documentation
+// describes; the child's stderr is logged. The child serves the same Bureau and function
+// map a native build exports, so a plugin crash only takes down its own process.
+
+// The handshake environment variable tells a process plugin it was started by a host
+const (
+	processCookieKey   = "CHAMELEON_PLUGIN"
+	processCookieValue = "process-v1"
+)
+
+// processService is the name the child registers its RPC methods under
+const processService = "Plugin"
+
+// Bounds on talking to a child process
+const (
+	processHandshakeTimeout = 10 * time.Second
+	processExitGrace        = time.Second
+)
+
+// processCommand builds the command running a process plugin; tests replace it
+var processCommand = func(path string) *exec.Cmd {
+	return exec.Command(path)
+}
+
+// The RPC messages are aliases of unnamed structs because net/rpc only accepts
+// exported or builtin argument types
+
+// describeReply is the child's answer to the handshake
+type describeReply = struct {
+	APIVersion int
+	Name       string
+	Version    string
+	Functions  []string
+	Signatures map[string]FuncSignature
+	Metadata   *Metadata
+}
+
+// initRequest carries the Init arguments
+type initRequest = struct {
+	Args []interface{}
+}
+
+// configureRequest carries the Configure options
+type configureRequest = struct {
+	Options map[string]interface{}
+}
+
+// callRequest carries a function call. Deadline is the caller's context deadline, zero
+// when it has none; cancellation without a deadline is not propagated to the child.
+// CallID is the caller's call ID, restored in the child's context.
+type callRequest = struct {
+	Func     string
+	Args     []json.RawMessage
+	Deadline time.Time
+	CallID   string
+}
+
+// callReply carries a function result. Error is set when the function failed,
+// DeadlineExceeded when it failed because the call's deadline passed, and InvalidArgument
+// when it refused its arguments.
+type callReply = struct {
+	Result           json.RawMessage
+	Error            string
+	DeadlineExceeded bool
+	InvalidArgument  bool
+}
+
+// errorReply carries the result of Init and Free
+type errorReply = struct {
+	Error string
+}
+
+// isExecutable reports whether path is an executable rather than a shared object
+func isExecutable(path string) bool {
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		if f.Type == elf.ET_EXEC {
+			return true
+		}
+		// Position-independent executables are ET_DYN like shared objects, but name an interpreter
+		for _, prog := range f.Progs {
+			if prog.Type == elf.PT_INTERP {
+				return true
+			}
+		}
+		return false
+	}
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		return f.Type == macho.TypeExec
+	}
+	if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		return f.Characteristics&pe.IMAGE_FILE_DLL == 0
+	}
+	return false
+}
+
+// processConn joins the pipes to a child process into one connection
+type processConn struct {
+	io.Reader
+	io.Writer
+	closers []io.Closer
+}
+
+func (c processConn) Close() error {
+	var errs []error
+	for _, closer := range c.closers {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// processLog writes a child's stderr to the logger line by line
+type processLog struct {
+	logger Logger
+	path   string
+	buf    []byte
+}
+
+func (w *processLog) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.logger.Info("Plugin process output", "path", w.path, "line", string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// openProcess starts a process plugin and exposes it under the symbols the loader looks
+// up, so it is validated like a native plugin. The child is killed if ctx ends before it
+// has answered the handshake.
+func openProcess(ctx context.Context, path, exportSymbol, functionsSymbol string, logger Logger) (*processLookup, error) {
+	cmd := processCommand(path)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, processCookieKey+"="+processCookieValue)
+
+	// Pipes are created here rather than by exec so Wait never closes the host's ends
+	// while the RPC client still reads from them
+	childIn, hostOut, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start plugin process: %w", err)
+	}
+	hostIn, childOut, err := os.Pipe()
+	if err != nil {
+		childIn.Close()
+		hostOut.Close()
+		return nil, fmt.Errorf("failed to start plugin process: %w", err)
+	}
+	cmd.Stdin = childIn
+	cmd.Stdout = childOut
+	cmd.Stderr = &processLog{logger: logger, path: path}
+	err = cmd.Start()
+	childIn.Close()
+	childOut.Close()
+	if err != nil {
+		hostIn.Close()
+		hostOut.Close()
+		return nil, fmt.Errorf("failed to start plugin process: %w", err)
+	}
+
+	b := &processBureau{
+		path:   path,
+		cmd:    cmd,
+		exited: make(chan struct{}),
+	}
+	b.client = jsonrpc.NewClient(processConn{Reader: hostIn, Writer: hostOut, closers: []io.Closer{hostIn, hostOut}})
+	go func() {
+		b.exitErr = cmd.Wait()
+		close(b.exited)
+	}()
+
+	var desc describeReply
+	call := b.client.Go(processService+".Describe", struct{}{}, &desc, make(chan *rpc.Call, 1))
+	timer := time.NewTimer(processHandshakeTimeout)
+	defer timer.Stop()
+	select {
+	case <-call.Done:
+	case <-ctx.Done():
+		b.kill()
+		return nil, fmt.Errorf("plugin load timeout: %w", ctx.Err())
+	case <-timer.C:
+		b.kill()
+		return nil, fmt.Errorf("plugin process did not answer the handshake within %v", processHandshakeTimeout)
+	}
+	if call.Error != nil {
+		err := b.callError(call.Error)
+		b.kill()
+		return nil, fmt.Errorf("plugin process handshake failed: %w", err)
+	}
+	b.name, b.version = desc.Name, desc.Version
+
+	funcs := make(map[string]InvokeFunc, len(desc.Functions))
+	for _, name := range desc.Functions {
+		funcs[name] = b.invoker(name, desc.Signatures[name])
+	}
+	var bureau Bureau = b
+	symbols := map[string]interface{}{
+		apiVersionSymbol: &desc.APIVersion,
+		exportSymbol:     &bureau,
+		functionsSymbol:  &funcs,
+	}
+	if desc.Signatures != nil {
+		symbols[signaturesSymbol] = &desc.Signatures
+	}
+	if desc.Metadata != nil {
+		symbols[metadataSymbol] = desc.Metadata
+	}
+	return &processLookup{bureau: b, symbols: symbols}, nil
+}
+
+// processLookup serves a process plugin's handshake under the loader's symbol names
+type processLookup struct {
+	bureau  *processBureau
+	symbols map[string]interface{}
+}
+
+func (l *processLookup) Lookup(symName string) (plugin.Symbol, error) {
+	if sym, ok := l.symbols[symName]; ok {
+		return sym, nil
+	}
+	return nil, fmt.Errorf("plugin process does not provide symbol %s", symName)
+}
+
+// processBureau is the host side of a process plugin
+type processBureau struct {
+	name     string
+	version  string
+	path     string
+	client   *rpc.Client
+	cmd      *exec.Cmd
+	exited   chan struct{} // closed once the process has exited
+	exitErr  error         // set before exited is closed
+	killOnce sync.Once
+}
+
+func (b *processBureau) Name() string {
+	return b.name
+}
+
+func (b *processBureau) Version() string {
+	return b.version
+}
+
+func (b *processBureau) Init(args ...interface{}) error {
+	var reply errorReply
+	if err := b.client.Call(processService+".Init", initRequest{Args: args}, &reply); err != nil {
+		return b.callError(err)
+	}
+	if reply.Error != "" {
+		return errors.New(reply.Error)
+	}
+	return nil
+}
+
+// Configure passes options to the plugin; a child built before Configure existed has no
+// such method and is left alone
+func (b *processBureau) Configure(options map[string]interface{}) error {
+	var reply errorReply
+	if err := b.client.Call(processService+".Configure", configureRequest{Options: options}, &reply); err != nil {
+		var serverErr rpc.ServerError
+		if errors.As(err, &serverErr) && strings.HasPrefix(string(serverErr), "rpc: can't find method") {
+			return nil
+		}
+		return b.callError(err)
+	}
+	if reply.Error != "" {
+		return errors.New(reply.Error)
+	}
+	return nil
+}
+
+// Free asks the plugin to release its resources; the backend stops the process when the
+// plugin is unloaded
+func (b *processBureau) Free() error {
+	var reply errorReply
+	if err := b.client.Call(processService+".Free", struct{}{}, &reply); err != nil {
+		return b.callError(err)
+	}
+	if reply.Error != "" {
+		return errors.New(reply.Error)
+	}
+	return nil
+}
+
+func (b *processBureau) exitChan() <-chan struct{} {
+	return b.exited
+}
+
+func (b *processBureau) exitError() error {
+	return b.exitErr
+}
+
+// kill closes the connection, which lets a healthy child exit on its own, and kills the
+// process if it has not exited shortly after
+func (b *processBureau) kill() {
+	b.killOnce.Do(func() {
+		b.client.Close()
+		select {
+		case <-b.exited:
+			return
+		case <-time.After(processExitGrace):
+		}
+		b.cmd.Process.Kill()
+		<-b.exited
+	})
+}
+
+// callError reports a failed RPC. Errors raised by the child are returned as they are;
+// anything else means the connection is gone, usually because the process died.
+func (b *processBureau) callError(err error) error {
+	var serverErr rpc.ServerError
+	if errors.As(err, &serverErr) {
+		return err
+	}
+	select {
+	case <-b.exited:
+		if b.exitErr != nil {
+			err = b.exitErr
+		}
+	case <-time.After(processExitGrace):
+	}
+	name := b.name
+	if name == "" {
+		name = b.path
+	}
+	return ErrProcessExited{Name: name, Err: err}
+}
+
+// invoker returns the InvokeFunc proxying calls of the named function to the child
+func (b *processBureau) invoker(name string, sig FuncSignature) InvokeFunc {
+	var resultType reflect.Type
+	if len(sig.Results) > 0 {
+		resultType = basicTypes[sig.Results[0]]
+	}
+	return func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		req := callRequest{Func: name, Args: make([]json.RawMessage, len(args))}
+		for i, arg := range args {
+			raw, err := json.Marshal(arg)
+			if err != nil {
+				return nil, ErrInvalidArgument{Func: name, Err: fmt.Errorf("argument %d cannot be sent to a process plugin: %w", i, err)}
+			}
+			req.Args[i] = raw
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			req.Deadline = deadline
+		}
+		req.CallID, _ = CallIDFromContext(ctx)
+
+		var reply callReply
+		call := b.client.Go(processService+".Call", req, &reply, make(chan *rpc.Call, 1))
+		select {
+		case <-call.Done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if call.Error != nil {
+			return nil, b.callError(call.Error)
+		}
+		if reply.Error != "" {
+			if reply.DeadlineExceeded {
+				return nil, context.DeadlineExceeded
+			}
+			if reply.InvalidArgument {
+				return nil, ErrInvalidArgument{Func: name, Err: errors.New(reply.Error)}
+			}
+			return nil, errors.New(reply.Error)
+		}
+		return decodeResult(reply.Result, resultType)
+	}
+}
+
+// basicTypes are the result types restored exactly from JSON. Other results arrive as
+// the generic values encoding/json produces: float64, string, bool, []interface{} and
+// map[string]interface{}.
+var basicTypes = func() map[string]reflect.Type {
+	types := make(map[string]reflect.Type)
+	for _, v := range []interface{}{
+		false, "", int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0), float32(0), float64(0),
+	} {
+		t := reflect.TypeOf(v)
+		types[t.String()] = t
+	}
+	return types
+}()
+
+// decodeResult decodes a function result into t, or into a generic value when t is nil
+func decodeResult(raw json.RawMessage, t reflect.Type) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if t == nil {
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+		return v, nil
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(raw, v.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode result as %s: %w", t, err)
+	}
+	return v.Elem().Interface(), nil
+}
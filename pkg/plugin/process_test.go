@@ -0,0 +1,217 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// processHelperEnv makes TestProcessPluginHelper serve calcBureau instead of skipping
+const processHelperEnv = "CHAMELEON_TEST_PROCESS_PLUGIN"
+
+type calcBureau struct {
+	region string
+}
+
+func (c *calcBureau) Name() string                   { return "calc" }
+func (c *calcBureau) Version() string                { return "1.0.0" }
+func (c *calcBureau) Init(args ...interface{}) error { return nil }
+func (c *calcBureau) Free() error                    { return nil }
+
+func (c *calcBureau) Configure(options map[string]interface{}) error {
+	c.region, _ = options["region"].(string)
+	return nil
+}
+
+func (c *calcBureau) Divide(ctx context.Context, a, b int) (int, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+func (c *calcBureau) Greet(ctx context.Context, name string) (string, error) {
+	fmt.Println("greeting", name)
+	return "hello " + name, nil
+}
+
+// calcFunctions mirrors a generated function map, asserting argument types
+func calcFunctions(c *calcBureau) map[string]InvokeFunc {
+	return map[string]InvokeFunc{
+		"Divide": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			a, ok1 := args[0].(int)
+			b, ok2 := args[1].(int)
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("arguments must be int, got %T and %T", args[0], args[1])
+			}
+			return c.Divide(ctx, a, b)
+		},
+		"Greet": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			name, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("argument 0 must be string")
+			}
+			return c.Greet(ctx, name)
+		},
+		"Wait": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		"CallID": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			id, _ := CallIDFromContext(ctx)
+			return id, nil
+		},
+		"Region": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			return c.region, nil
+		},
+		"Pid": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			return strconv.Itoa(os.Getpid()), nil
+		},
+		"Crash": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			os.Exit(3)
+			return nil, nil
+		},
+	}
+}
+
+// TestProcessPluginHelper is the child process of the process backend tests
+func TestProcessPluginHelper(t *testing.T) {
+	if os.Getenv(processHelperEnv) == "" {
+		t.Skip("only runs as a plugin process")
+	}
+	c := &calcBureau{}
+	ServeProcess(ProcessPlugin{Bureau: c, Functions: calcFunctions(c)})
+	os.Exit(0)
+}
+
+// useProcessHelper runs this test binary as the plugin process for any path
+func useProcessHelper(t testing.TB) {
+	t.Helper()
+	orig := processCommand
+	processCommand = func(path string) *exec.Cmd {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestProcessPluginHelper$")
+		cmd.Env = append(os.Environ(), processHelperEnv+"=1")
+		return cmd
+	}
+	t.Cleanup(func() { processCommand = orig })
+}
+
+func TestProcessBackend(t *testing.T) {
+	useProcessHelper(t)
+	logger := &testLogger{}
+	m := newTestManager(t, func(config *Config) {
+		config.PluginConfigs["calc"] = PluginSpecificConfig{
+			CircuitBreaker: CircuitBreakerConfig{Enabled: true, MaxFailures: 3, ResetInterval: time.Minute, OpenDuration: time.Minute},
+			Options:        map[string]interface{}{OptionBackend: BackendProcess, "region": "us"},
+		}
+	}, WithLogger(logger))
+	loadTestPlugin(t, m, "calc", "calc")
+	if plugins := m.ListPlugins(); len(plugins) != 1 || plugins[0].Name != "calc" || plugins[0].Version != "1.0.0" {
+		t.Fatalf("ListPlugins() = %+v, want calc 1.0.0", plugins)
+	}
+	ctx := context.Background()
+
+	// Arguments arrive with their declared types and basic results come back typed
+	if result, err := m.Call(ctx, "calc", "Divide", 9, 3); err != nil || result != 3 {
+		t.Errorf("Call(Divide) = %v (%T), %v, want 3", result, result, err)
+	}
+	if result, err := m.Call(ctx, "calc", "Greet", "host"); err != nil || result != "hello host" {
+		t.Errorf("Call(Greet) = %v, %v, want hello host", result, err)
+	}
+	if result, err := m.Call(WithCallID(ctx, "request-42"), "calc", "CallID"); err != nil || result != "request-42" {
+		t.Errorf("Call(CallID) = %v, %v, want the caller's call ID in the plugin process", result, err)
+	}
+	// Options reach the plugin process on load and when they change
+	if result, err := m.Call(ctx, "calc", "Region"); err != nil || result != "us" {
+		t.Errorf("Call(Region) = %v, %v, want us", result, err)
+	}
+	config := m.currentConfig().PluginConfigs["calc"]
+	config.Options = map[string]interface{}{OptionBackend: BackendProcess, "region": "eu"}
+	if err := m.UpdatePluginConfig("calc", config); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := m.Call(ctx, "calc", "Region"); err != nil || result != "eu" {
+		t.Errorf("Call(Region) after UpdatePluginConfig = %v, %v, want eu", result, err)
+	}
+	waitFor(t, "plugin stdout to be logged", func() bool {
+		return logger.has("INFO: Plugin process output")
+	})
+
+	// Failures are reported like in-process ones and count towards the breaker
+	if _, err := m.Call(ctx, "calc", "Divide", 1, 0); err == nil || !strings.Contains(err.Error(), "division by zero") {
+		t.Errorf("Call(Divide by zero) error = %v", err)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := m.Call(timeoutCtx, "calc", "Wait"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Call(Wait) error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// A crash ends the plugin process, not the host
+	var exited ErrProcessExited
+	if _, err := m.Call(ctx, "calc", "Crash"); !errors.As(err, &exited) {
+		t.Errorf("Call(Crash) error = %v, want ErrProcessExited", err)
+	}
+	if !m.IsCircuitBreakerOpen("calc") {
+		t.Error("Expected three failures to open the breaker")
+	}
+}
+
+func TestProcessBackend_FreeStopsProcess(t *testing.T) {
+	useProcessHelper(t)
+	m, cleanup := setupTestManager(t, func(config *Config) {
+		config.DefaultPluginConfig.Options = map[string]interface{}{OptionBackend: BackendProcess}
+	})
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "calc.so")
+	if err := os.WriteFile(path, []byte("calc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+	b := m.currentPlugin("calc").bureau.(*processBureau)
+
+	if err := m.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	select {
+	case <-b.exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Plugin process still running after Close")
+	}
+}
+
+func TestPluginBackend(t *testing.T) {
+	library := filepath.Join(t.TempDir(), "lib.so")
+	if err := os.WriteFile(library, []byte("not an executable"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	process := PluginSpecificConfig{Options: map[string]interface{}{OptionBackend: BackendProcess}}
+
+	tests := []struct {
+		name   string
+		path   string
+		config PluginSpecificConfig
+		want   string
+	}{
+		{name: "executable", path: os.Args[0], want: BackendProcess},
+		{name: "other file", path: library, want: BackendNative},
+		{name: "option wins", path: library, config: process, want: BackendProcess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pluginBackend(tt.path, tt.config); got != tt.want {
+				t.Errorf("pluginBackend(%s) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+// Package promexport exports the plugins of a plugin.Manager and their call metrics to
+// Prometheus:
+//
+//	registry.MustRegister(promexport.NewCollector(manager))
+//
+// It is a module of its own, so programs that do not use it do not depend on the
+// Prometheus client.
+package promexport
+
+import (
+	"maps"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/zyanho/chameleon/pkg/plugin"
+)
+
+// Metric names exported by the collector of NewCollector. They, and their
+// labels, are stable: dashboards and alerts may rely on them.
+const (
+	// chameleon_plugin_info is 1 for every loaded plugin; labels plugin, version, state
+	promPluginInfo = "chameleon_plugin_info"
+	// chameleon_plugin_calls_total counts calls that reached a function; labels plugin,
+	// version, method
+	promCalls = "chameleon_plugin_calls_total"
+	// chameleon_plugin_call_errors_total counts the calls that failed; labels plugin,
+	// version, method
+	promCallErrors = "chameleon_plugin_call_errors_total"
+	// chameleon_plugin_call_timeouts_total counts the failed calls that timed out; labels
+	// plugin, version, method
+	promCallTimeouts = "chameleon_plugin_call_timeouts_total"
+	// chameleon_plugin_call_duration_seconds is the histogram of call durations, failed
+	// calls included; labels plugin, version, method
+	promCallDuration = "chameleon_plugin_call_duration_seconds"
+	// chameleon_plugin_breaker_rejections_total counts calls refused by an open circuit
+	// breaker; label plugin
+	promBreakerRejections = "chameleon_plugin_breaker_rejections_total"
+	// chameleon_plugin_breaker_state is 0 while the circuit breaker is closed, 1 while it
+	// is open and 2 while it is half-open; label plugin
+	promBreakerState = "chameleon_plugin_breaker_state"
+	// chameleon_plugin_in_flight_calls is the number of calls running; label plugin
+	promInFlight = "chameleon_plugin_in_flight_calls"
+	// chameleon_plugin_method_in_flight_calls is the number of calls running in a
+	// function; labels plugin, method
+	promMethodInFlight = "chameleon_plugin_method_in_flight_calls"
+	// chameleon_plugin_method_max_in_flight_calls is the most calls that ran at once in a
+	// function since metrics were reset; labels plugin, method
+	promMethodMaxInFlight = "chameleon_plugin_method_max_in_flight_calls"
+	// chameleon_plugin_concurrency_limit is the number of calls allowed at once, 0 for no
+	// limit; label plugin
+	promConcurrencyLimit = "chameleon_plugin_concurrency_limit"
+	// chameleon_plugin_concurrency_rejections_total counts calls refused by the concurrency
+	// limit; label plugin
+	promConcurrencyRejections = "chameleon_plugin_concurrency_rejections_total"
+	// chameleon_plugin_operations_total counts loads, inits, frees, reloads and upgrades,
+	// apart from calls; labels plugin, operation
+	promOperations = "chameleon_plugin_operations_total"
+	// chameleon_plugin_operation_failures_total counts the operations that failed; labels
+	// plugin, operation
+	promOperationFailures = "chameleon_plugin_operation_failures_total"
+	// chameleon_plugin_operation_duration_seconds sums the durations of the operations, as
+	// a summary without quantiles; labels plugin, operation
+	promOperationDuration = "chameleon_plugin_operation_duration_seconds"
+)
+
+// collector exports a manager's plugin metrics, read afresh at every scrape
+type collector struct {
+	m *plugin.Manager
+	// descs describe the metrics of plugins without MetricLabels
+	descs *promDescs
+	// labeled describe the metrics of each plugin with MetricLabels
+	labeled sync.Map // map[string]*labeledDescs
+}
+
+// promDescs describe the exported metrics, with the const labels of a plugin
+type promDescs struct {
+	info                  *prometheus.Desc
+	calls                 *prometheus.Desc
+	callErrors            *prometheus.Desc
+	callTimeouts          *prometheus.Desc
+	callDuration          *prometheus.Desc
+	breakerRejections     *prometheus.Desc
+	breakerState          *prometheus.Desc
+	inFlight              *prometheus.Desc
+	methodInFlight        *prometheus.Desc
+	methodMaxInFlight     *prometheus.Desc
+	concurrencyLimit      *prometheus.Desc
+	concurrencyRejections *prometheus.Desc
+	operations            *prometheus.Desc
+	operationFailures     *prometheus.Desc
+	operationDuration     *prometheus.Desc
+}
+
+// labeledDescs are the descriptors of a plugin and the labels they were made with
+type labeledDescs struct {
+	from  map[string]string
+	descs *promDescs
+}
+
+// NewCollector returns a collector of the plugins of m and of their call
+// metrics, to register with a prometheus.Registerer. Per-function metrics are only
+// exported while metrics are enabled, and only for loaded plugins: metrics retained for
+// plugins since unloaded produce no series.
+//
+// The series of a plugin carry its MetricLabels. As those differ by plugin and change on
+// reload, the collector describes no metrics up front, which makes it an unchecked
+// collector to the registry.
+func NewCollector(m *plugin.Manager) prometheus.Collector {
+	return &collector{m: m, descs: newPromDescs(nil)}
+}
+
+func newPromDescs(labels prometheus.Labels) *promDescs {
+	pluginOnly := []string{"plugin"}
+	method := []string{"plugin", "method"}
+	versionMethod := []string{"plugin", "version", "method"}
+	operation := []string{"plugin", "operation"}
+	return &promDescs{
+		info: prometheus.NewDesc(promPluginInfo, "Loaded plugins.", []string{"plugin", "version", "state"}, labels),
+		calls: prometheus.NewDesc(promCalls,
+			"Calls that reached a plugin function.", versionMethod, labels),
+		callErrors: prometheus.NewDesc(promCallErrors,
+			"Calls to a plugin function that returned an error.", versionMethod, labels),
+		callTimeouts: prometheus.NewDesc(promCallTimeouts,
+			"Calls to a plugin function that timed out.", versionMethod, labels),
+		callDuration: prometheus.NewDesc(promCallDuration,
+			"Duration of calls to a plugin function, failed calls included.", versionMethod, labels),
+		breakerRejections: prometheus.NewDesc(promBreakerRejections,
+			"Calls refused by the plugin's open circuit breaker.", pluginOnly, labels),
+		breakerState: prometheus.NewDesc(promBreakerState,
+			"State of the plugin's circuit breaker: 0 closed, 1 open, 2 half-open.", pluginOnly, labels),
+		inFlight: prometheus.NewDesc(promInFlight,
+			"Calls to the plugin running now.", pluginOnly, labels),
+		methodInFlight: prometheus.NewDesc(promMethodInFlight,
+			"Calls to a plugin function running now.", method, labels),
+		methodMaxInFlight: prometheus.NewDesc(promMethodMaxInFlight,
+			"Most calls to a plugin function that ran at once since metrics were reset.", method, labels),
+		concurrencyLimit: prometheus.NewDesc(promConcurrencyLimit,
+			"Calls to the plugin allowed at once; 0 means no limit.", pluginOnly, labels),
+		concurrencyRejections: prometheus.NewDesc(promConcurrencyRejections,
+			"Calls refused by the plugin's concurrency limit.", pluginOnly, labels),
+		operations: prometheus.NewDesc(promOperations,
+			"Loads, inits, frees, reloads and upgrades of the plugin.", operation, labels),
+		operationFailures: prometheus.NewDesc(promOperationFailures,
+			"Operations on the plugin that failed.", operation, labels),
+		operationDuration: prometheus.NewDesc(promOperationDuration,
+			"Duration of operations on the plugin; upgrades are not timed.", operation, labels),
+	}
+}
+
+// descsFor returns the descriptors of a plugin's metrics, made anew when its labels change
+func (c *collector) descsFor(name string) *promDescs {
+	labels := c.m.MetricLabels(name)
+	if len(labels) == 0 {
+		return c.descs
+	}
+	if val, ok := c.labeled.Load(name); ok && maps.Equal(val.(*labeledDescs).from, labels) {
+		return val.(*labeledDescs).descs
+	}
+	descs := newPromDescs(labels)
+	c.labeled.Store(name, &labeledDescs{from: labels, descs: descs})
+	return descs
+}
+
+// Describe implements prometheus.Collector. It sends no descriptors: see
+// NewCollector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	for _, info := range c.m.ListPlugins() {
+		name := info.Name
+		d := c.descsFor(name)
+		ch <- prometheus.MustNewConstMetric(d.info, prometheus.GaugeValue, 1, name, info.Version, info.State.String())
+		ch <- prometheus.MustNewConstMetric(d.breakerState, prometheus.GaugeValue, float64(info.Breaker), name)
+		ch <- prometheus.MustNewConstMetric(d.inFlight, prometheus.GaugeValue, float64(c.m.GetInFlight(name)), name)
+		if concurrency, err := c.m.GetConcurrencyInfo(name); err == nil {
+			ch <- prometheus.MustNewConstMetric(d.concurrencyLimit, prometheus.GaugeValue, float64(concurrency.Limit), name)
+			ch <- prometheus.MustNewConstMetric(d.concurrencyRejections, prometheus.CounterValue, float64(concurrency.Rejected), name)
+		}
+
+		snapshot, err := c.m.GetMetricsSnapshot(name)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(d.breakerRejections, prometheus.CounterValue,
+			float64(snapshot.BreakerRejections), name)
+		// Calls are broken down by version; sum without (version) collapses them
+		for version, v := range snapshot.Versions {
+			for fn, s := range v.Methods {
+				ch <- prometheus.MustNewConstMetric(d.calls, prometheus.CounterValue, float64(s.Count), name, version, fn)
+				ch <- prometheus.MustNewConstMetric(d.callErrors, prometheus.CounterValue, float64(s.Errors), name, version, fn)
+				ch <- prometheus.MustNewConstMetric(d.callTimeouts, prometheus.CounterValue, float64(s.Timeouts), name, version, fn)
+				ch <- durationHistogram(d.callDuration, s, name, version, fn)
+			}
+		}
+		for fn, s := range snapshot.Methods {
+			ch <- prometheus.MustNewConstMetric(d.methodInFlight, prometheus.GaugeValue, float64(s.InFlight), name, fn)
+			ch <- prometheus.MustNewConstMetric(d.methodMaxInFlight, prometheus.GaugeValue, float64(s.MaxInFlight), name, fn)
+		}
+		for op, s := range snapshot.Operations {
+			ch <- prometheus.MustNewConstMetric(d.operations, prometheus.CounterValue, float64(s.Count), name, string(op))
+			ch <- prometheus.MustNewConstMetric(d.operationFailures, prometheus.CounterValue, float64(s.Failures), name, string(op))
+			ch <- prometheus.MustNewConstSummary(d.operationDuration, uint64(s.Count), s.TotalTime.Seconds(), nil, name, string(op))
+		}
+	}
+}
+
+// durationHistogram converts a method's latency histogram, whose counts are per bucket,
+// to Prometheus' cumulative buckets in seconds
+func durationHistogram(desc *prometheus.Desc, s plugin.MethodSnapshot, name, version, fn string) prometheus.Metric {
+	h := s.Histogram
+	buckets := make(map[float64]uint64, len(h.Bounds))
+	var cumulative uint64
+	for i, bound := range h.Bounds {
+		cumulative += uint64(h.Counts[i])
+		buckets[bound.Seconds()] = cumulative
+	}
+	count := uint64(h.Total())
+	sum := s.TotalTime.Seconds()
+	return prometheus.MustNewConstHistogram(desc, count, sum, buckets, name, version, fn)
+}
@@ -0,0 +1,256 @@
+package promexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/zyanho/chameleon/pkg/plugin"
+)
+
+// bureau is a plugin served from memory
+type bureau struct{ name, version string }
+
+func (b bureau) Name() string                 { return b.name }
+func (b bureau) Version() string              { return b.version }
+func (bureau) Init(args ...interface{}) error { return nil }
+func (bureau) Free() error                    { return nil }
+
+// pay succeeds, or fails as its argument asks
+func pay(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "declined":
+			return nil, errors.New("declined")
+		case "timeout":
+			return nil, context.DeadlineExceeded
+		}
+	}
+	return "ok", nil
+}
+
+// newTestManager creates a manager whose plugins load from backend, its config changed
+// by configure
+func newTestManager(t *testing.T, backend *plugin.MemoryBackend, configure func(*plugin.Config)) *plugin.Manager {
+	t.Helper()
+	config := plugin.DefaultConfig()
+	config.PluginDir = t.TempDir()
+	config.AllowHotReload = false
+	config.DefaultPluginConfig.Options = map[string]interface{}{plugin.OptionBackend: "memory"}
+	if configure != nil {
+		configure(config)
+	}
+	m, err := plugin.NewManager(context.Background(), config, plugin.WithBackend("memory", backend))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+// register makes a plugin loadable from "mem/<name>" and loads it
+func register(t *testing.T, m *plugin.Manager, backend *plugin.MemoryBackend, name, version string) {
+	t.Helper()
+	backend.Register("mem/"+name, bureau{name: name, version: version}, map[string]plugin.InvokeFunc{"Pay": pay})
+	if err := m.LoadPlugin("mem/" + name); err != nil {
+		t.Fatalf("LoadPlugin(%s) error = %v", name, err)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	backend := plugin.NewMemoryBackend()
+	m := newTestManager(t, backend, func(config *plugin.Config) {
+		config.EnableMetrics = true
+		// Every call falls in the first bucket, whatever it takes
+		config.MetricsBuckets = []time.Duration{time.Hour, 2 * time.Hour}
+		config.DefaultPluginConfig.MaxConcurrentCalls = 8
+	})
+	register(t, m, backend, "payments", "1.0.0")
+
+	ctx := context.Background()
+	m.Call(ctx, "payments", "Pay")
+	m.Call(ctx, "payments", "Pay", "declined")
+	m.Call(ctx, "payments", "Pay", "timeout")
+	if err := m.TripBreaker("payments", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	m.Call(ctx, "payments", "Pay")
+
+	collector := NewCollector(m)
+	expected := `
+# HELP chameleon_plugin_breaker_rejections_total Calls refused by the plugin's open circuit breaker.
+# TYPE chameleon_plugin_breaker_rejections_total counter
+chameleon_plugin_breaker_rejections_total{plugin="payments"} 1
+# HELP chameleon_plugin_breaker_state State of the plugin's circuit breaker: 0 closed, 1 open, 2 half-open.
+# TYPE chameleon_plugin_breaker_state gauge
+chameleon_plugin_breaker_state{plugin="payments"} 1
+# HELP chameleon_plugin_call_errors_total Calls to a plugin function that returned an error.
+# TYPE chameleon_plugin_call_errors_total counter
+chameleon_plugin_call_errors_total{method="Pay",plugin="payments",version="1.0.0"} 2
+# HELP chameleon_plugin_call_timeouts_total Calls to a plugin function that timed out.
+# TYPE chameleon_plugin_call_timeouts_total counter
+chameleon_plugin_call_timeouts_total{method="Pay",plugin="payments",version="1.0.0"} 1
+# HELP chameleon_plugin_calls_total Calls that reached a plugin function.
+# TYPE chameleon_plugin_calls_total counter
+chameleon_plugin_calls_total{method="Pay",plugin="payments",version="1.0.0"} 3
+# HELP chameleon_plugin_concurrency_limit Calls to the plugin allowed at once; 0 means no limit.
+# TYPE chameleon_plugin_concurrency_limit gauge
+chameleon_plugin_concurrency_limit{plugin="payments"} 8
+# HELP chameleon_plugin_in_flight_calls Calls to the plugin running now.
+# TYPE chameleon_plugin_in_flight_calls gauge
+chameleon_plugin_in_flight_calls{plugin="payments"} 0
+# HELP chameleon_plugin_info Loaded plugins.
+# TYPE chameleon_plugin_info gauge
+chameleon_plugin_info{plugin="payments",state="active",version="1.0.0"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		promBreakerRejections, promBreakerState, promCallErrors, promCallTimeouts,
+		promCalls, promConcurrencyLimit, promInFlight, promPluginInfo); err != nil {
+		t.Error(err)
+	}
+
+	// The durations vary, the buckets they fall in do not
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	var histograms int
+	for _, family := range families {
+		if family.GetName() != promCallDuration {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			histograms++
+			h := metric.GetHistogram()
+			if h.GetSampleCount() != 3 || len(h.GetBucket()) != 2 ||
+				h.GetBucket()[0].GetUpperBound() != 3600 || h.GetBucket()[0].GetCumulativeCount() != 3 {
+				t.Errorf("Call duration histogram = %v, want 3 calls in the first bucket", h)
+			}
+		}
+	}
+	if histograms != 1 {
+		t.Errorf("Gathered %d call duration histograms, want 1", histograms)
+	}
+
+	// Control-plane operations carry an operation label; their durations vary
+	operations := `
+# HELP chameleon_plugin_operation_failures_total Operations on the plugin that failed.
+# TYPE chameleon_plugin_operation_failures_total counter
+chameleon_plugin_operation_failures_total{operation="init",plugin="payments"} 0
+chameleon_plugin_operation_failures_total{operation="load",plugin="payments"} 0
+# HELP chameleon_plugin_operations_total Loads, inits, frees, reloads and upgrades of the plugin.
+# TYPE chameleon_plugin_operations_total counter
+chameleon_plugin_operations_total{operation="init",plugin="payments"} 1
+chameleon_plugin_operations_total{operation="load",plugin="payments"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(operations),
+		promOperations, promOperationFailures); err != nil {
+		t.Error(err)
+	}
+	if got := testutil.CollectAndCount(collector, promOperationDuration); got != 2 {
+		t.Errorf("Collected %d operation durations, want 2", got)
+	}
+	if problems, err := testutil.CollectAndLint(collector); err != nil || len(problems) > 0 {
+		t.Errorf("Lint problems %v, error %v", problems, err)
+	}
+
+	// Per-function metrics go while metrics are disabled; the plugin metrics stay
+	m.DisableMetrics()
+	if got := testutil.CollectAndCount(collector, promCalls); got != 0 {
+		t.Errorf("Collected %d call counters with metrics disabled", got)
+	}
+	if got := testutil.CollectAndCount(collector, promPluginInfo); got != 1 {
+		t.Errorf("Collected %d info metrics with metrics disabled, want 1", got)
+	}
+}
+
+func TestCollector_UnloadedPlugins(t *testing.T) {
+	for _, retain := range []bool{false, true} {
+		t.Run(fmt.Sprintf("retain=%v", retain), func(t *testing.T) {
+			backend := plugin.NewMemoryBackend()
+			m := newTestManager(t, backend, func(config *plugin.Config) { config.RetainMetricsOnUnload = retain })
+			register(t, m, backend, "payments", "1.0.0")
+			if _, err := m.Call(context.Background(), "payments", "Pay"); err != nil {
+				t.Fatal(err)
+			}
+
+			collector := NewCollector(m)
+			if got := testutil.CollectAndCount(collector, promCalls); got != 1 {
+				t.Fatalf("Collected %d call counters, want 1", got)
+			}
+			if err := m.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := testutil.CollectAndCount(collector); got != 0 {
+				t.Errorf("Collected %d series after the plugin was freed, want none", got)
+			}
+			_, err := m.GetMetricsSnapshot("payments")
+			if retain && err != nil {
+				t.Errorf("GetMetricsSnapshot() error = %v, want the retained metrics", err)
+			}
+			if !retain && err == nil {
+				t.Error("GetMetricsSnapshot() succeeded, want the metrics dropped")
+			}
+		})
+	}
+}
+
+func TestCollector_MetricLabels(t *testing.T) {
+	backend := plugin.NewMemoryBackend()
+	m := newTestManager(t, backend, func(config *plugin.Config) {
+		config.EnableMetrics = true
+		config.DefaultPluginConfig.MetricLabels = map[string]string{"env": "prod"}
+		config.PluginConfigs["payments"] = plugin.PluginSpecificConfig{MetricLabels: map[string]string{"team": "billing", "tier": "1"}}
+	})
+	register(t, m, backend, "payments", "1.0.0")
+	register(t, m, backend, "audit", "1.0.0")
+	if _, err := m.Call(context.Background(), "payments", "Pay"); err != nil {
+		t.Fatal(err)
+	}
+
+	collector := NewCollector(m)
+	expected := `
+# HELP chameleon_plugin_calls_total Calls that reached a plugin function.
+# TYPE chameleon_plugin_calls_total counter
+chameleon_plugin_calls_total{env="prod",method="Pay",plugin="payments",team="billing",tier="1",version="1.0.0"} 1
+# HELP chameleon_plugin_info Loaded plugins.
+# TYPE chameleon_plugin_info gauge
+chameleon_plugin_info{env="prod",plugin="audit",state="active",version="1.0.0"} 1
+chameleon_plugin_info{env="prod",plugin="payments",state="active",team="billing",tier="1",version="1.0.0"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), promCalls, promPluginInfo); err != nil {
+		t.Error(err)
+	}
+
+	// A reload with other labels moves the plugin's series to them
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := m.UpdatePluginConfig("payments", plugin.PluginSpecificConfig{MetricLabels: map[string]string{"team": "billing", "tier": "2"}}); err != nil {
+		t.Fatal(err)
+	}
+	register(t, m, backend, "payments", "2.0.0")
+	expected = `
+# HELP chameleon_plugin_info Loaded plugins.
+# TYPE chameleon_plugin_info gauge
+chameleon_plugin_info{env="prod",plugin="audit",state="active",version="1.0.0"} 1
+chameleon_plugin_info{env="prod",plugin="payments",state="active",team="billing",tier="2",version="2.0.0"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), promPluginInfo); err != nil {
+		t.Error(err)
+	}
+	if problems, err := testutil.GatherAndLint(registry); err != nil || len(problems) > 0 {
+		t.Errorf("Lint problems %v, error %v", problems, err)
+	}
+}
@@ -0,0 +1,228 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// lifecycleMethods are Bureau methods that are never exposed as callable functions
+var lifecycleMethods = map[string]bool{
+	"Name":    true,
+	"Version": true,
+	"Init":    true,
+	"Free":    true,
+}
+
+// reflectFunctions builds InvokeFuncs for the exported methods of the value behind a Bureau.
+// Methods must take a context.Context first and return at most a value and an error;
+// methods with other signatures are skipped and reported.
+func reflectFunctions(b Bureau) (map[string]InvokeFunc, []string) {
+	v := reflect.ValueOf(b)
+	t := v.Type()
+
+	funcs := make(map[string]InvokeFunc)
+	var skipped []string
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+		if lifecycleMethods[method.Name] {
+			continue
+		}
+		fn, err := reflectInvokeFunc(method.Name, v.Method(i))
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", method.Name, err))
+			continue
+		}
+		funcs[method.Name] = fn
+	}
+	return funcs, skipped
+}
+
+// reflectSignatures describes the methods backing reflected functions
+func reflectSignatures(b Bureau, funcs map[string]InvokeFunc) map[string]FuncSignature {
+	v := reflect.ValueOf(b)
+	sigs := make(map[string]FuncSignature, len(funcs))
+	for name := range funcs {
+		if method := v.MethodByName(name); method.IsValid() {
+			sigs[name] = reflectSignature(method.Type())
+		}
+	}
+	return sigs
+}
+
+// reflectSignature describes a function type whose first parameter is a context.Context
+func reflectSignature(mt reflect.Type) FuncSignature {
+	sig := FuncSignature{Variadic: mt.IsVariadic()}
+	for i := 1; i < mt.NumIn(); i++ {
+		sig.Params = append(sig.Params, mt.In(i).String())
+	}
+	for i := 0; i < mt.NumOut(); i++ {
+		sig.Results = append(sig.Results, mt.Out(i).String())
+	}
+	return sig
+}
+
+// reflectInvokeFunc wraps a bound method value into an InvokeFunc
+func reflectInvokeFunc(name string, method reflect.Value) (InvokeFunc, error) {
+	mt := method.Type()
+	if mt.NumIn() == 0 || mt.In(0) != contextType {
+		return nil, fmt.Errorf("first parameter must be context.Context")
+	}
+	switch mt.NumOut() {
+	case 0, 1:
+	case 2:
+		if mt.Out(1) != errorType {
+			return nil, fmt.Errorf("second result must be error")
+		}
+	default:
+		return nil, fmt.Errorf("too many results")
+	}
+
+	params := mt.NumIn() - 1
+	return func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		if mt.IsVariadic() {
+			if len(args) < params-1 {
+				return nil, ErrInvalidArgument{Func: name, Err: fmt.Errorf("%s requires at least %d arguments, got %d", name, params-1, len(args))}
+			}
+		} else if len(args) != params {
+			return nil, ErrInvalidArgument{Func: name, Err: fmt.Errorf("%s requires %d arguments, got %d", name, params, len(args))}
+		}
+
+		in := make([]reflect.Value, 0, len(args)+1)
+		in = append(in, reflect.ValueOf(ctx))
+		for i, arg := range args {
+			var target reflect.Type
+			if mt.IsVariadic() && i >= params-1 {
+				target = mt.In(mt.NumIn() - 1).Elem()
+			} else {
+				target = mt.In(i + 1)
+			}
+			val, err := coerceArg(arg, target)
+			if err != nil {
+				return nil, ErrInvalidArgument{Func: name, Err: fmt.Errorf("%s argument %d: %w", name, i, err)}
+			}
+			in = append(in, val)
+		}
+
+		out := method.Call(in)
+		return unpackResults(mt, out)
+	}, nil
+}
+
+// unpackResults maps reflected results onto the InvokeFunc return values
+func unpackResults(mt reflect.Type, out []reflect.Value) (interface{}, error) {
+	switch len(out) {
+	case 0:
+		return nil, nil
+	case 1:
+		if mt.Out(0) == errorType {
+			err, _ := out[0].Interface().(error)
+			return nil, err
+		}
+		return out[0].Interface(), nil
+	default:
+		err, _ := out[1].Interface().(error)
+		return out[0].Interface(), err
+	}
+}
+
+// coerceArg converts an argument to the parameter type, allowing lossless numeric conversions
+func coerceArg(arg interface{}, target reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		switch target.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return reflect.Zero(target), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot use nil as %s", target)
+	}
+
+	v := reflect.ValueOf(arg)
+	if v.Type().AssignableTo(target) {
+		return v, nil
+	}
+	if isNumeric(v.Kind()) && isNumeric(target.Kind()) {
+		return coerceNumber(v, target)
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %T as %s", arg, target)
+}
+
+func isNumeric(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// coerceNumber converts between numeric kinds, rejecting overflow and fractional truncation
+func coerceNumber(v reflect.Value, target reflect.Type) (reflect.Value, error) {
+	out := reflect.New(target).Elem()
+	switch target.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch {
+		case v.CanInt():
+			n = v.Int()
+		case v.CanUint():
+			if v.Uint() > math.MaxInt64 {
+				return reflect.Value{}, fmt.Errorf("%v overflows %s", v, target)
+			}
+			n = int64(v.Uint())
+		default:
+			f := v.Float()
+			// math.MaxInt64 rounds up to 1<<63 as a float64, which int64 cannot hold
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= 1<<63 {
+				return reflect.Value{}, fmt.Errorf("%v is not a valid %s", f, target)
+			}
+			n = int64(f)
+		}
+		if out.OverflowInt(n) {
+			return reflect.Value{}, fmt.Errorf("%v overflows %s", n, target)
+		}
+		out.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch {
+		case v.CanInt():
+			f = float64(v.Int())
+		case v.CanUint():
+			f = float64(v.Uint())
+		default:
+			f = v.Float()
+		}
+		if out.OverflowFloat(f) {
+			return reflect.Value{}, fmt.Errorf("%v overflows %s", f, target)
+		}
+		out.SetFloat(f)
+	default:
+		var n uint64
+		switch {
+		case v.CanInt():
+			if v.Int() < 0 {
+				return reflect.Value{}, fmt.Errorf("%v is negative, cannot use as %s", v, target)
+			}
+			n = uint64(v.Int())
+		case v.CanUint():
+			n = v.Uint()
+		default:
+			f := v.Float()
+			if f != math.Trunc(f) || f < 0 || f >= 1<<64 {
+				return reflect.Value{}, fmt.Errorf("%v is not a valid %s", f, target)
+			}
+			n = uint64(f)
+		}
+		if out.OverflowUint(n) {
+			return reflect.Value{}, fmt.Errorf("%v overflows %s", n, target)
+		}
+		out.SetUint(n)
+	}
+	return out, nil
+}
@@ -0,0 +1,318 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// A plugin configured with Replicas > 1 runs several independent instances of the same
+// artifact. Slot 0 is the instance registered in Manager.plugins, whose breaker is the
+// one in Manager.breakers, so code that deals with a single instance keeps working; Call
+// spreads calls over all slots.
+
+// replica is one instance serving a replicated plugin, with its own breaker
+type replica struct {
+	instance *PluginInstance
+	breaker  *CircuitBreaker
+}
+
+// replicaSet holds the replicas of a plugin
+type replicaSet struct {
+	mu       sync.RWMutex
+	replicas []*replica
+	next     atomic.Uint32 // round-robin start for the next pick
+}
+
+// pick returns the healthy replica with the fewest calls in flight, rotating between
+// equally loaded ones. Replicas that are restarting, failed or whose breaker refuses
+// calls to fn are skipped.
+func (s *replicaSet) pick(name, fn string) (*replica, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := len(s.replicas)
+	start := int(s.next.Add(1) % uint32(n))
+	candidates := make([]*replica, 0, n)
+	restarting := false
+	for i := 0; i < n; i++ {
+		r := s.replicas[(start+i)%n]
+		if r.instance.restarting.Load() {
+			restarting = true
+			continue
+		}
+		if r.instance.State() == StateActive {
+			candidates = append(candidates, r)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].instance.GetRefs() < candidates[j].instance.GetRefs()
+	})
+	for _, r := range candidates {
+		if methodBreaker(r.breaker, r.instance, fn).Allow() {
+			return r, nil
+		}
+	}
+
+	switch {
+	case len(candidates) > 0:
+		return nil, ErrCircuitOpen{Name: name}
+	case restarting:
+		return nil, ErrPluginRestarting{Name: name}
+	default:
+		// Every replica was given up on; the call reports why
+		return s.replicas[start], nil
+	}
+}
+
+// slot returns the index of instance in the set, or -1
+func (s *replicaSet) slot(instance *PluginInstance) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i, r := range s.replicas {
+		if r.instance == instance {
+			return i
+		}
+	}
+	return -1
+}
+
+// snapshot returns the current replicas
+func (s *replicaSet) snapshot() []*replica {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*replica(nil), s.replicas...)
+}
+
+// replicaCount returns how many instances of a plugin the config asks for
+func (c PluginSpecificConfig) replicaCount() int {
+	if c.Replicas < 1 {
+		return 1
+	}
+	return c.Replicas
+}
+
+// replicaSetFor returns the replicas of a plugin, or nil when it runs a single instance
+func (m *Manager) replicaSetFor(name string) *replicaSet {
+	if val, ok := m.replicas.Load(name); ok {
+		return val.(*replicaSet)
+	}
+	return nil
+}
+
+// replicaInfo describes the replicas serving a plugin; a plugin without replicas has one
+func (m *Manager) replicaInfo(name string, instance *PluginInstance) []ReplicaInfo {
+	replicas := []*replica{{instance: instance}}
+	if set := m.replicaSetFor(name); set != nil {
+		replicas = set.snapshot()
+	} else if val, ok := m.breakers.Load(name); ok {
+		replicas[0].breaker = val.(*CircuitBreaker)
+	}
+	info := make([]ReplicaInfo, 0, len(replicas))
+	for _, r := range replicas {
+		ri := ReplicaInfo{Version: r.instance.version, State: r.instance.State(), RefCount: r.instance.GetRefs()}
+		if r.breaker != nil {
+			ri.Breaker = r.breaker.State()
+		}
+		info = append(info, ri)
+	}
+	return info
+}
+
+// slotOf returns the slot instance occupies among the plugin's registered instances:
+// 0 for the instance in Manager.plugins, or -1 if it is not registered
+func (m *Manager) slotOf(name string, instance *PluginInstance) int {
+	if set := m.replicaSetFor(name); set != nil {
+		return set.slot(instance)
+	}
+	if val, ok := m.plugins.Load(name); ok && val.(*PluginInstance) == instance {
+		return 0
+	}
+	return -1
+}
+
+// replaceSlot registers instance and breaker in a slot and returns what they replaced.
+// Slot 0 also updates Manager.plugins and Manager.breakers; a slot past the end is appended.
+func (m *Manager) replaceSlot(name string, set *replicaSet, slot int, instance *PluginInstance, breaker *CircuitBreaker) (*PluginInstance, *CircuitBreaker) {
+	var prevInstance *PluginInstance
+	var prevBreaker *CircuitBreaker
+	if set != nil {
+		set.mu.Lock()
+		if slot < len(set.replicas) {
+			prevInstance, prevBreaker = set.replicas[slot].instance, set.replicas[slot].breaker
+			set.replicas[slot] = &replica{instance: instance, breaker: breaker}
+		} else {
+			set.replicas = append(set.replicas, &replica{instance: instance, breaker: breaker})
+		}
+		set.mu.Unlock()
+	}
+	if slot == 0 {
+		if prev, loaded := m.breakers.Swap(name, breaker); loaded {
+			prevBreaker = prev.(*CircuitBreaker)
+		}
+		m.pluginPaths.Store(name, instance.path)
+		if prev, loaded := m.plugins.Swap(name, instance); loaded {
+			prevInstance = prev.(*PluginInstance)
+		}
+	}
+	return prevInstance, prevBreaker
+}
+
+// retire deprecates a replaced instance so the GC frees it once its calls finish
+func (m *Manager) retire(name string, instance *PluginInstance, breaker *CircuitBreaker) {
+	if breaker != nil {
+		breaker.Close()
+	}
+	if instance == nil {
+		return
+	}
+	instance.deprecate()
+	m.deprecated.Store(instance, name)
+	m.loader.removeShadow(instance.ShadowPath())
+}
+
+// newReplica initializes a plugin opened for a replica and gives it its own breaker
+func (m *Manager) newReplica(name, path string, plugin *Plugin, config *PluginSpecificConfig, prev *replica) (*PluginInstance, *CircuitBreaker, error) {
+	initDuration, err := m.initPlugin(name, plugin, config)
+	if err != nil {
+		m.discard(path, plugin)
+		return nil, nil, ErrPluginInit{Name: name, Err: err}
+	}
+	breaker := m.newBreaker(name, config.CircuitBreaker)
+	instance := &PluginInstance{
+		Plugin:       plugin,
+		state:        StateActive,
+		version:      plugin.Version(),
+		path:         path,
+		hash:         plugin.hash,
+		source:       m.fetchedSource(plugin.hash),
+		initDuration: initDuration,
+		loadedAt:     m.clock.Now(),
+	}
+	if prev != nil {
+		if config.CircuitBreaker.CarryOverOnReload {
+			breaker.inherit(prev.breaker)
+		}
+		instance.previousLoadAt = prev.instance.loadedAt
+	}
+	return instance, breaker, nil
+}
+
+// registerReplicas registers a plugin that runs or ran several replicas. A new plugin
+// starts all replicas before any is registered; an upgrade replaces the replicas one at
+// a time, so the others keep serving. A failed step stops the upgrade, leaving the
+// replicas replaced so far on the new version. The caller holds the name lock.
+func (m *Manager) registerReplicas(name, path string, plugin *Plugin, config *PluginSpecificConfig, oldInstance *PluginInstance, actor string) error {
+	n := config.replicaCount()
+	if n > 1 && plugin.backend != nil && plugin.backend.Capabilities().InProcess {
+		// The Go runtime opens each plugin package once per process, so in-process
+		// replicas would all share one Bureau
+		m.discard(path, plugin)
+		err := fmt.Errorf("plugin %s: %d replicas need a backend that runs plugins outside the host, such as %q",
+			name, n, BackendProcess)
+		m.emit(Event{Type: EventLoadFailed, Plugin: name, Version: plugin.Version(), Path: path, Err: err,
+			actor: actor, hash: plugin.hash})
+		return err
+	}
+
+	set := m.replicaSetFor(name)
+	var old []*replica
+	if set != nil {
+		old = set.snapshot()
+	} else if oldInstance != nil {
+		breaker, _ := m.breakers.Load(name)
+		old = []*replica{{instance: oldInstance, breaker: breaker.(*CircuitBreaker)}}
+	}
+	if set == nil && n > 1 {
+		set = &replicaSet{}
+		if len(old) > 0 {
+			set.replicas = old
+		}
+	}
+
+	failed := func(err error) error {
+		failure := Event{Type: EventLoadFailed, Plugin: name, Version: plugin.Version(), Path: path, Err: err,
+			actor: actor, hash: plugin.hash}
+		if len(old) > 0 {
+			failure.Type = EventUpgradeFailed
+		}
+		m.emit(failure)
+		return err
+	}
+	open := func(i int) (*Plugin, error) {
+		if i == 0 {
+			return plugin, nil
+		}
+		return m.loader.loadReplica(m.ctx, plugin, path, *config)
+	}
+
+	if len(old) == 0 {
+		// Start every replica before registering any
+		replicas := make([]*replica, 0, n)
+		for i := 0; i < n; i++ {
+			p, err := open(i)
+			if err == nil {
+				var instance *PluginInstance
+				var breaker *CircuitBreaker
+				if instance, breaker, err = m.newReplica(name, path, p, config, nil); err == nil {
+					replicas = append(replicas, &replica{instance: instance, breaker: breaker})
+					continue
+				}
+			}
+			for _, r := range replicas {
+				r.breaker.Close()
+				m.discard(path, r.instance.Plugin)
+			}
+			return failed(err)
+		}
+		set.replicas = replicas
+		m.replicas.Store(name, set)
+		m.breakers.Store(name, replicas[0].breaker)
+		m.pluginPaths.Store(name, path)
+		m.plugins.Store(name, replicas[0].instance)
+		for _, r := range replicas {
+			m.supervise(name, r.instance, *config)
+		}
+		m.emit(Event{Type: EventLoaded, Plugin: name, Version: plugin.Version(), Path: path, actor: actor, hash: plugin.hash})
+		return nil
+	}
+
+	if set != nil {
+		m.replicas.Store(name, set)
+	}
+	for i := 0; i < n; i++ {
+		var prev *replica
+		if i < len(old) {
+			prev = old[i]
+		}
+		p, err := open(i)
+		if err != nil {
+			return failed(err)
+		}
+		instance, breaker, err := m.newReplica(name, path, p, config, prev)
+		if err != nil {
+			return failed(err)
+		}
+		prevInstance, prevBreaker := m.replaceSlot(name, set, i, instance, breaker)
+		m.retire(name, prevInstance, prevBreaker)
+		m.supervise(name, instance, *config)
+		m.pluginLogger(name).Info("Replaced plugin replica", "replica", i, "version", instance.version)
+	}
+
+	// Replicas beyond the new count are retired; a single instance needs no set
+	if set != nil {
+		set.mu.Lock()
+		extra := set.replicas[n:]
+		set.replicas = set.replicas[:n:n]
+		set.mu.Unlock()
+		for _, r := range extra {
+			m.retire(name, r.instance, r.breaker)
+		}
+		if n == 1 {
+			m.replicas.Delete(name)
+		}
+	}
+	m.emit(Event{Type: EventUpgraded, Plugin: name, Version: plugin.Version(), Path: path, actor: actor, hash: plugin.hash})
+	return nil
+}
@@ -0,0 +1,161 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newReplicatedCalc loads the calc process plugin with the given number of replicas
+func newReplicatedCalc(t *testing.T, replicas int) (*Manager, string, <-chan Event) {
+	t.Helper()
+	useProcessHelper(t)
+	m := newTestManager(t, func(config *Config) {
+		config.GCInterval = 0
+		config.GCGracePeriod = 0
+		config.PluginConfigs["calc"] = replicatedCalcConfig(replicas)
+	}, WithLogger(&testLogger{}))
+	events, unsubscribe := m.Subscribe(16)
+	t.Cleanup(unsubscribe)
+	path := loadTestPlugin(t, m, "calc", "calc")
+	return m, path, events
+}
+
+func replicatedCalcConfig(replicas int) PluginSpecificConfig {
+	return PluginSpecificConfig{
+		CircuitBreaker: CircuitBreakerConfig{Enabled: true, MaxFailures: 1, ResetInterval: time.Minute, OpenDuration: time.Minute},
+		RestartPolicy:  RestartPolicy{MaxRestarts: 2, Backoff: 300 * time.Millisecond, Window: time.Minute},
+		Replicas:       replicas,
+		Options:        map[string]interface{}{OptionBackend: BackendProcess},
+	}
+}
+
+// callerPids calls Pid n times and counts the answers of each replica process
+func callerPids(t *testing.T, m *Manager, n int) map[string]int {
+	t.Helper()
+	pids := make(map[string]int)
+	for i := 0; i < n; i++ {
+		pid, err := m.Call(context.Background(), "calc", "Pid")
+		if err != nil {
+			t.Fatalf("Call(Pid) error = %v", err)
+		}
+		pids[pid.(string)]++
+	}
+	return pids
+}
+
+func TestReplicas_SpreadCalls(t *testing.T) {
+	m, _, _ := newReplicatedCalc(t, 3)
+
+	plugins := m.ListPlugins()
+	if len(plugins) != 1 || len(plugins[0].Replicas) != 3 {
+		t.Fatalf("ListPlugins() = %+v, want calc with 3 replicas", plugins)
+	}
+	for i, r := range plugins[0].Replicas {
+		if r.State != StateActive || r.Version != "1.0.0" || r.Breaker != StateClosed {
+			t.Errorf("Replica %d = %+v, want an active 1.0.0 replica with a closed breaker", i, r)
+		}
+	}
+
+	// Idle replicas take turns
+	pids := callerPids(t, m, 6)
+	if len(pids) != 3 {
+		t.Fatalf("Calls reached %d processes, want 3: %v", len(pids), pids)
+	}
+	for pid, calls := range pids {
+		if calls != 2 {
+			t.Errorf("Process %s served %d calls, want 2", pid, calls)
+		}
+	}
+}
+
+func TestReplicas_CrashedReplica(t *testing.T) {
+	m, _, events := newReplicatedCalc(t, 2)
+	ctx := context.Background()
+
+	m.Call(ctx, "calc", "Crash")
+	// The surviving replica serves every call while the other restarts
+	waitFor(t, "a replica to fail", func() bool {
+		for _, r := range m.ListPlugins()[0].Replicas {
+			if r.State == StateFailed {
+				return true
+			}
+		}
+		return false
+	})
+	if pids := callerPids(t, m, 4); len(pids) != 1 {
+		t.Errorf("Calls during the restart reached %v, want one process", pids)
+	}
+	if m.IsCircuitBreakerOpen("calc") {
+		t.Error("Expected the breaker to count as closed while a replica serves calls")
+	}
+
+	awaitEvent(t, events, EventRestarted)
+	if pids := callerPids(t, m, 4); len(pids) != 2 {
+		t.Errorf("Calls after the restart reached %v, want two processes", pids)
+	}
+}
+
+func TestReplicas_RollingUpgrade(t *testing.T) {
+	m, path, events := newReplicatedCalc(t, 2)
+	before := callerPids(t, m, 2)
+
+	// New content under a larger replica count replaces every replica and adds one
+	if err := os.WriteFile(path, []byte("calc v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := replicatedCalcConfig(3)
+	config.AllowDowngrade = true
+	if err := m.LoadPluginWithConfig(path, &config); err != nil {
+		t.Fatalf("LoadPluginWithConfig() error = %v", err)
+	}
+	awaitEvent(t, events, EventUpgraded)
+
+	after := callerPids(t, m, 3)
+	if len(after) != 3 {
+		t.Fatalf("Calls after the upgrade reached %v, want three processes", after)
+	}
+	for pid := range after {
+		if before[pid] > 0 {
+			t.Errorf("Process %s survived the upgrade", pid)
+		}
+	}
+	if freed, err := m.GCNow(); freed != 2 || err != nil {
+		t.Errorf("GCNow() = %d, %v; want the 2 replaced replicas freed", freed, err)
+	}
+
+	// Back to a single instance
+	if err := os.WriteFile(path, []byte("calc v3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.Replicas = 1
+	if err := m.LoadPluginWithConfig(path, &config); err != nil {
+		t.Fatalf("LoadPluginWithConfig() error = %v", err)
+	}
+	if plugins := m.ListPlugins(); len(plugins) != 1 || len(plugins[0].Replicas) != 1 {
+		t.Errorf("ListPlugins() = %+v, want calc with 1 replica", plugins)
+	}
+	if pids := callerPids(t, m, 3); len(pids) != 1 {
+		t.Errorf("Calls reached %v, want one process", pids)
+	}
+}
+
+func TestReplicas_RejectsInProcessBackend(t *testing.T) {
+	backend := NewMemoryBackend()
+	backend.Register("mem/payments", &fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{"Pay": returning("ok")})
+	m := newTestManager(t, func(config *Config) {
+		config.PluginConfigs["payments"] = PluginSpecificConfig{
+			Replicas: 2,
+			Options:  map[string]interface{}{OptionBackend: "memory"},
+		}
+	}, WithBackend("memory", backend))
+
+	if err := m.LoadPlugin("mem/payments"); err == nil || !strings.Contains(err.Error(), "replicas") {
+		t.Errorf("LoadPlugin() error = %v, want a replicas error", err)
+	}
+	if plugins := m.ListPlugins(); len(plugins) != 0 {
+		t.Errorf("ListPlugins() = %+v, want none", plugins)
+	}
+}
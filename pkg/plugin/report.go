@@ -0,0 +1,47 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SkippedEntry is a directory entry that was not loaded during a directory scan
+type SkippedEntry struct {
+	Path   string
+	Reason string
+}
+
+// LoadFailure is a plugin file that failed to load during a directory scan
+type LoadFailure struct {
+	Path string
+	Name string // manifest or file name; the declared name may be unknown if loading failed early
+	Err  error
+}
+
+// LoadReport summarizes the most recent scan of the plugin directory and, at startup,
+// the plugins restored from Config.StateFile
+type LoadReport struct {
+	Loaded   []string // names of the plugins registered after the scan
+	Restored []string // names of the plugins restored from the state file
+	Failed   []LoadFailure
+	Skipped  []SkippedEntry
+}
+
+// Err joins the errors of all failed loads, or returns nil
+func (r *LoadReport) Err() error {
+	errs := make([]error, 0, len(r.Failed))
+	for _, f := range r.Failed {
+		errs = append(errs, fmt.Errorf("%s: %w", f.Path, f.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// skip records an entry that was not loaded
+func (r *LoadReport) skip(path, reason string) {
+	r.Skipped = append(r.Skipped, SkippedEntry{Path: path, Reason: reason})
+}
+
+// LoadReport returns the report of the most recent plugin directory scan, or nil if none ran
+func (m *Manager) LoadReport() *LoadReport {
+	return m.loadReport.Load()
+}
@@ -0,0 +1,62 @@
+package plugin
+
+import "time"
+
+// Spans of the rolling windows of recent calls kept for every function
+const (
+	rollingShort = time.Minute
+	rollingLong  = 5 * time.Minute
+)
+
+// recentCalls counts the calls and failures of a function over the last minute and the
+// last five minutes. Each span is a timeWindow of windowBuckets buckets, 6s and 30s wide,
+// which moves on lazily as calls are recorded and counts read; together they take about
+// 220 bytes.
+type recentCalls struct {
+	short *timeWindow
+	long  *timeWindow
+}
+
+func newRecentCalls(now func() time.Time) *recentCalls {
+	return &recentCalls{
+		short: newTimeWindow(rollingShort, now),
+		long:  newTimeWindow(rollingLong, now),
+	}
+}
+
+func (r *recentCalls) record(failed bool) {
+	if r == nil {
+		return
+	}
+	r.short.record(failed)
+	r.long.record(failed)
+}
+
+// copy returns windows holding the counts so far, for snapshots
+func (r *recentCalls) copy() *recentCalls {
+	if r == nil {
+		return nil
+	}
+	return &recentCalls{short: r.short.copy(), long: r.long.copy()}
+}
+
+// rates returns the calls per second over the short and long spans and the share of
+// them that failed
+func (r *recentCalls) rates() (short, shortErrors, long, longErrors float64) {
+	if r == nil {
+		return 0, 0, 0, 0
+	}
+	short, shortErrors = windowRates(r.short, rollingShort)
+	long, longErrors = windowRates(r.long, rollingLong)
+	return short, shortErrors, long, longErrors
+}
+
+// windowRates returns the calls per second of a window spanning d and the share of them
+// that failed
+func windowRates(w *timeWindow, d time.Duration) (rate, errorRate float64) {
+	calls, failures := w.counts()
+	if calls == 0 {
+		return 0, 0
+	}
+	return float64(calls) / d.Seconds(), float64(failures) / float64(calls)
+}
@@ -0,0 +1,280 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semVersion is a parsed semantic version (https://semver.org). Build metadata is dropped
+// because it does not take part in precedence.
+type semVersion struct {
+	major, minor, patch uint64
+	pre                 []string
+}
+
+// parseSemver parses a version such as "v1.2.3-rc.1+build5". A leading "v" is accepted
+// and missing minor or patch components default to zero.
+func parseSemver(s string) (semVersion, error) {
+	var v semVersion
+	raw := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		if err := validateIdentifiers(s[i+1:], false); err != nil {
+			return v, fmt.Errorf("invalid version %q: build metadata: %w", raw, err)
+		}
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		pre := s[i+1:]
+		if err := validateIdentifiers(pre, true); err != nil {
+			return v, fmt.Errorf("invalid version %q: pre-release: %w", raw, err)
+		}
+		v.pre = strings.Split(pre, ".")
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 || s == "" {
+		return v, fmt.Errorf("invalid version %q", raw)
+	}
+	nums := []*uint64{&v.major, &v.minor, &v.patch}
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil || (len(part) > 1 && part[0] == '0') {
+			return v, fmt.Errorf("invalid version %q: bad numeric component %q", raw, part)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// validateIdentifiers checks dot-separated pre-release or build identifiers
+func validateIdentifiers(s string, noLeadingZero bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return fmt.Errorf("empty identifier")
+		}
+		numeric := true
+		for _, c := range id {
+			switch {
+			case c >= '0' && c <= '9':
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-':
+				numeric = false
+			default:
+				return fmt.Errorf("invalid character %q in %q", c, id)
+			}
+		}
+		if noLeadingZero && numeric && len(id) > 1 && id[0] == '0' {
+			return fmt.Errorf("numeric identifier %q has a leading zero", id)
+		}
+	}
+	return nil
+}
+
+// compare returns -1, 0 or 1 following semver precedence rules
+func (v semVersion) compare(o semVersion) int {
+	if c := compareUint(v.major, o.major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.minor, o.minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.patch, o.patch); c != 0 {
+		return c
+	}
+
+	// A version without pre-release has higher precedence than one with
+	switch {
+	case len(v.pre) == 0 && len(o.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(o.pre) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.pre) && i < len(o.pre); i++ {
+		if c := comparePreIdentifier(v.pre[i], o.pre[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.pre)), uint64(len(o.pre)))
+}
+
+// comparePreIdentifier compares numeric identifiers numerically and others lexically;
+// numeric identifiers always have lower precedence than alphanumeric ones
+func comparePreIdentifier(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return compareUint(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareVersions parses and compares two version strings
+func compareVersions(a, b string) (int, error) {
+	va, err := parseSemver(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseSemver(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.compare(vb), nil
+}
+
+// versionConstraint is a parsed constraint such as ">=1.2.0 <2.0.0 || ^3.1".
+// Comparators separated by spaces (or commas) must all hold; "||" separates alternatives.
+type versionConstraint [][]versionComparator
+
+// versionComparator is a single operator and version
+type versionComparator struct {
+	op      string
+	version semVersion
+}
+
+// parseVersionConstraint parses a constraint supporting exact pins, =, !=, >, >=, <, <=,
+// caret (^1.2.3 allows >=1.2.3 <2.0.0) and tilde (~1.2.3 allows >=1.2.3 <1.3.0)
+func parseVersionConstraint(s string) (versionConstraint, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+
+	var vc versionConstraint
+	for _, alt := range strings.Split(s, "||") {
+		fields := strings.Fields(strings.ReplaceAll(alt, ",", " "))
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid version constraint %q: empty alternative", s)
+		}
+
+		var group []versionComparator
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			// Allow a space between operator and version, e.g. ">= 1.2.0"
+			if isConstraintOperator(field) && i+1 < len(fields) {
+				field += fields[i+1]
+				i++
+			}
+			comparators, err := parseComparator(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+			}
+			group = append(group, comparators...)
+		}
+		vc = append(vc, group)
+	}
+	return vc, nil
+}
+
+func isConstraintOperator(s string) bool {
+	switch s {
+	case "=", "!=", ">", ">=", "<", "<=", "^", "~":
+		return true
+	}
+	return false
+}
+
+// parseComparator expands a single term into one or more comparators
+func parseComparator(term string) ([]versionComparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, candidate) {
+			op = candidate
+			break
+		}
+	}
+	raw := strings.TrimPrefix(term, op)
+	v, err := parseSemver(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "^":
+		upper := semVersion{major: v.major + 1}
+		switch {
+		case v.major == 0 && v.minor == 0 && countComponents(raw) == 3:
+			upper = semVersion{patch: v.patch + 1}
+		case v.major == 0 && countComponents(raw) >= 2:
+			upper = semVersion{minor: v.minor + 1}
+		}
+		return []versionComparator{{">=", v}, {"<", upper}}, nil
+	case "~":
+		upper := semVersion{major: v.major, minor: v.minor + 1}
+		if countComponents(raw) == 1 {
+			upper = semVersion{major: v.major + 1}
+		}
+		return []versionComparator{{">=", v}, {"<", upper}}, nil
+	case "":
+		op = "="
+	}
+	return []versionComparator{{op, v}}, nil
+}
+
+// countComponents counts the numeric components given in a version string
+func countComponents(raw string) int {
+	core := strings.TrimPrefix(raw, "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	return strings.Count(core, ".") + 1
+}
+
+// check reports whether the version satisfies the constraint
+func (vc versionConstraint) check(version string) (bool, error) {
+	v, err := parseSemver(version)
+	if err != nil {
+		return false, err
+	}
+	for _, group := range vc {
+		if matchesAll(group, v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchesAll reports whether the version satisfies every comparator in the group
+func matchesAll(group []versionComparator, v semVersion) bool {
+	for _, c := range group {
+		cmp := v.compare(c.version)
+		ok := false
+		switch c.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
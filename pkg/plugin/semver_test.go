@@ -0,0 +1,83 @@
+package plugin
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.0", 1},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"2.0.0-rc.1", "2.0.0", -1},
+		{"2.0.0", "2.0.0-rc.1", 1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+		{"1.2.3+build5", "1.2.3+build9", 0},
+		{"1.2.4+build1", "1.2.3", 1},
+	}
+
+	for _, tt := range tests {
+		got, err := compareVersions(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("compareVersions(%q, %q) error = %v", tt.a, tt.b, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseSemver_Invalid(t *testing.T) {
+	for _, v := range []string{"", "latest", "1.2.3.4", "1.x.0", "01.2.3", "1.2.3-", "1.2.3-rc..1", "1.2.3+", "1.2.3-01"} {
+		if _, err := parseSemver(v); err == nil {
+			t.Errorf("parseSemver(%q) expected error", v)
+		}
+	}
+}
+
+func TestVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=1.2.0 <2.0.0", "1.5.3", true},
+		{">=1.2.0 <2.0.0", "2.0.0", false},
+		{">=1.2.0, <2.0.0", "1.1.9", false},
+		{">= 1.2.0 < 2.0.0", "1.2.0", true},
+		{"^1.2.3", "1.9.0", true},
+		{"^1.2.3", "2.0.0", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{"~1", "1.9.0", true},
+		{"1.4.2", "1.4.2", true},
+		{"=1.4.2", "1.4.3", false},
+		{"!=1.4.2", "1.4.3", true},
+		{"^1.0 || ^3.0", "3.1.0", true},
+		{"^1.0 || ^3.0", "2.1.0", false},
+	}
+
+	for _, tt := range tests {
+		vc, err := parseVersionConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("parseVersionConstraint(%q) error = %v", tt.constraint, err)
+			continue
+		}
+		got, err := vc.check(tt.version)
+		if err != nil {
+			t.Errorf("check(%q) error = %v", tt.version, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q check(%q) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
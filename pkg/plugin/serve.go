@@ -0,0 +1,186 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// ProcessPlugin is what a process plugin serves to its host. The main function generated
+// by 'chameleon generate' fills it from the symbols a native build exports.
+type ProcessPlugin struct {
+	Bureau Bureau
+	// Functions are the functions the host may call; nil builds them from the Bureau's
+	// methods via reflection, as the loader does for native plugins without a function map
+	Functions map[string]InvokeFunc
+	// Signatures describe the functions; nil reflects them from the Bureau's methods
+	Signatures map[string]FuncSignature
+	Metadata   *Metadata
+}
+
+// ServeProcess serves a process plugin to the host over stdin and stdout and returns once
+// the host disconnects. Anything the plugin writes to os.Stdout goes to stderr, which the
+// host logs. Run directly rather than by a host, it prints an explanation and exits.
+func ServeProcess(p ProcessPlugin) {
+	if os.Getenv(processCookieKey) != processCookieValue {
+		fmt.Fprintln(os.Stderr, "This is a chameleon process plugin. It is started by a host application and cannot be run directly.")
+		os.Exit(1)
+	}
+
+	if p.Functions == nil {
+		p.Functions, _ = reflectFunctions(p.Bureau)
+	}
+	// Signatures also tell the host which result types to restore from JSON
+	if p.Signatures == nil {
+		p.Signatures = reflectSignatures(p.Bureau, p.Functions)
+	}
+
+	conn := processConn{Reader: os.Stdin, Writer: os.Stdout, closers: []io.Closer{os.Stdin, os.Stdout}}
+	os.Stdout = os.Stderr
+
+	server := rpc.NewServer()
+	if err := server.RegisterName(processService, &processServer{plugin: p}); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to serve plugin:", err)
+		os.Exit(1)
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(conn))
+}
+
+// processServer is the child side of a process plugin
+type processServer struct {
+	plugin ProcessPlugin
+}
+
+func (s *processServer) Describe(_ struct{}, reply *describeReply) error {
+	reply.APIVersion = APIVersion
+	reply.Name = s.plugin.Bureau.Name()
+	reply.Version = s.plugin.Bureau.Version()
+	for name := range s.plugin.Functions {
+		reply.Functions = append(reply.Functions, name)
+	}
+	sort.Strings(reply.Functions)
+	reply.Signatures = s.plugin.Signatures
+	reply.Metadata = s.plugin.Metadata
+	return nil
+}
+
+func (s *processServer) Init(req initRequest, reply *errorReply) error {
+	if err := s.plugin.Bureau.Init(req.Args...); err != nil {
+		reply.Error = err.Error()
+	}
+	return nil
+}
+
+func (s *processServer) Configure(req configureRequest, reply *errorReply) error {
+	if c, ok := s.plugin.Bureau.(Configurable); ok {
+		if err := c.Configure(req.Options); err != nil {
+			reply.Error = err.Error()
+		}
+	}
+	return nil
+}
+
+func (s *processServer) Free(_ struct{}, reply *errorReply) error {
+	if err := s.plugin.Bureau.Free(); err != nil {
+		reply.Error = err.Error()
+	}
+	return nil
+}
+
+func (s *processServer) Call(req callRequest, reply *callReply) error {
+	fn, ok := s.plugin.Functions[req.Func]
+	if !ok {
+		reply.Error = ErrFuncNotFound{Name: req.Func, Plugin: s.plugin.Bureau.Name()}.Error()
+		return nil
+	}
+	args, err := s.decodeArgs(req.Func, req.Args)
+	if err != nil {
+		reply.Error = err.Error()
+		reply.InvalidArgument = true
+		return nil
+	}
+
+	ctx := context.Background()
+	if req.CallID != "" {
+		ctx = WithCallID(ctx, req.CallID)
+	}
+	if !req.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, req.Deadline)
+		defer cancel()
+	}
+	result, err := invokeRecovered(ctx, fn, args)
+	if err != nil {
+		reply.Error = err.Error()
+		reply.DeadlineExceeded = errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil
+		reply.InvalidArgument = errors.As(err, new(ErrInvalidArgument))
+		return nil
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		reply.Error = fmt.Sprintf("result cannot be sent to the host: %v", err)
+		return nil
+	}
+	reply.Result = raw
+	return nil
+}
+
+// decodeArgs decodes call arguments into the parameter types of the Bureau method of the
+// same name, so functions receive the types a native host would pass. Arguments without
+// a matching method decode into generic values.
+func (s *processServer) decodeArgs(name string, raws []json.RawMessage) ([]interface{}, error) {
+	var mt reflect.Type
+	if method := reflect.ValueOf(s.plugin.Bureau).MethodByName(name); method.IsValid() {
+		mt = method.Type()
+	}
+	args := make([]interface{}, len(raws))
+	for i, raw := range raws {
+		t := paramType(mt, i)
+		if t == nil {
+			if err := json.Unmarshal(raw, &args[i]); err != nil {
+				return nil, fmt.Errorf("failed to decode argument %d: %w", i, err)
+			}
+			continue
+		}
+		v := reflect.New(t)
+		if err := json.Unmarshal(raw, v.Interface()); err != nil {
+			return nil, fmt.Errorf("argument %d must be %s: %w", i, t, err)
+		}
+		args[i] = v.Elem().Interface()
+	}
+	return args, nil
+}
+
+// paramType returns the type of argument i, not counting the leading context, of a
+// method of type mt, or nil when there is none
+func paramType(mt reflect.Type, i int) reflect.Type {
+	if mt == nil || mt.NumIn() == 0 || mt.In(0) != contextType {
+		return nil
+	}
+	n := mt.NumIn() - 1
+	if mt.IsVariadic() && i >= n-1 {
+		return mt.In(mt.NumIn() - 1).Elem()
+	}
+	if i < n {
+		return mt.In(i + 1)
+	}
+	return nil
+}
+
+// invokeRecovered calls fn, turning a panic into an error so one bad call does not end
+// the process
+func invokeRecovered(ctx context.Context, fn InvokeFunc, args []interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin function panicked: %v", r)
+		}
+	}()
+	return fn(ctx, args...)
+}
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultShadowDirName is the directory under os.TempDir() holding shadow copies
+const DefaultShadowDirName = "chameleon"
+
+// shadowDirBase returns the configured shadow directory or the default one
+func shadowDirBase(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	return filepath.Join(os.TempDir(), DefaultShadowDirName)
+}
+
+// shadowStore copies plugin artifacts to unique paths so every load opens a path
+// the Go runtime has not seen before. Each store owns a "<pid>-*" directory under
+// the base so leftovers from crashed processes can be told apart from live ones.
+type shadowStore struct {
+	dir string
+	seq atomic.Uint64
+}
+
+// newShadowStore removes orphaned directories under base and creates a fresh one for this process
+func newShadowStore(base string, logger Logger) (*shadowStore, error) {
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create shadow directory: %w", err)
+	}
+	cleanOrphanShadowDirs(base, logger)
+
+	dir, err := os.MkdirTemp(base, strconv.Itoa(os.Getpid())+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shadow directory: %w", err)
+	}
+	return &shadowStore{dir: dir}, nil
+}
+
+// copy copies the plugin at path into the store under a name unique to this load. The
+// copy is hashed as it is written and rejected unless it has the given SHA-256, so
+// what is opened is what was hashed even if path changes meanwhile.
+func (s *shadowStore) copy(path, hash string) (string, error) {
+	ext := filepath.Ext(path)
+	name := strings.TrimSuffix(filepath.Base(path), ext)
+	prefix := hash
+	if len(prefix) > 16 {
+		prefix = prefix[:16]
+	}
+	dst := filepath.Join(s.dir, fmt.Sprintf("%s-%s-%d%s", name, prefix, s.seq.Add(1), ext))
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open plugin for shadow copy: %w", err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to open plugin for shadow copy: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".copy-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create shadow copy: %w", err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write shadow copy: %w", err)
+	}
+	// Executables run by the process backend must stay executable
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write shadow copy: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write shadow copy: %w", err)
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != hash {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("plugin %s changed while it was being loaded", path)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to place shadow copy: %w", err)
+	}
+	return dst, nil
+}
+
+// remove deletes a shadow copy; the mapped plugin code stays valid after unlinking
+func (s *shadowStore) remove(shadowPath string) error {
+	if shadowPath == "" {
+		return nil
+	}
+	if err := os.Remove(shadowPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// close deletes the store's directory and every copy in it
+func (s *shadowStore) close() error {
+	return os.RemoveAll(s.dir)
+}
+
+// cleanOrphanShadowDirs removes shadow directories left behind by processes that are no longer running
+func cleanOrphanShadowDirs(base string, logger Logger) {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		logger.Warn("Failed to scan shadow directory", "dir", base, "error", err)
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pidStr, _, ok := strings.Cut(entry.Name(), "-")
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil || pid == os.Getpid() || processAlive(pid) {
+			continue
+		}
+		dir := filepath.Join(base, entry.Name())
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warn("Failed to remove orphaned shadow directory", "dir", dir, "error", err)
+			continue
+		}
+		logger.Debug("Removed orphaned shadow directory", "dir", dir)
+	}
+}
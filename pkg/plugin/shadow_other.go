@@ -0,0 +1,9 @@
+//go:build !unix
+
+package plugin
+
+// processAlive assumes other processes are alive where they cannot be probed,
+// so their shadow directories are never removed
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestShadowCopy_LoadUpgradeClose(t *testing.T) {
+	v1 := &fakeBureau{name: "payments", version: "1.0.0"}
+	v2 := &fakeBureau{name: "payments", version: "2.0.0"}
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(v1, map[string]InvokeFunc{"Pay": returning("v1")}),
+		"v2": newFakeLib(v2, map[string]InvokeFunc{"Pay": returning("v2")}),
+	})
+
+	shadowBase := t.TempDir()
+
+	// Leftovers from a dead process are removed; those of live processes are kept
+	orphan := filepath.Join(shadowBase, "99999999-abc")
+	live := filepath.Join(shadowBase, strconv.Itoa(os.Getppid())+"-abc")
+	for _, dir := range []string{orphan, live} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := newTestManager(t, func(config *Config) {
+		config.ShadowCopy = true
+		config.ShadowDir = shadowBase
+	})
+
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("Expected orphaned shadow directory to be removed, stat error = %v", err)
+	}
+	if _, err := os.Stat(live); err != nil {
+		t.Errorf("Expected live process shadow directory to be kept: %v", err)
+	}
+
+	path := loadTestPlugin(t, m, "payments", "v1")
+	first := shadowPathOf(t, m, "payments")
+	if first == path || filepath.Dir(filepath.Dir(first)) != shadowBase {
+		t.Fatalf("Shadow path %s should be a copy under %s", first, shadowBase)
+	}
+	if got, _ := m.GetPluginPath("payments"); got != path {
+		t.Errorf("GetPluginPath() = %s, want original path %s", got, path)
+	}
+
+	// An in-place deploy reuses the path but gets a new shadow copy
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	second := shadowPathOf(t, m, "payments")
+	if second == first {
+		t.Fatal("Expected a new shadow copy for the upgraded plugin")
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("Expected previous shadow copy to be removed, stat error = %v", err)
+	}
+	if result, err := m.Call(context.Background(), "payments", "Pay"); err != nil || result != "v2" {
+		t.Errorf("Call() = %v, %v, want v2", result, err)
+	}
+
+	m.Close()
+	if _, err := os.Stat(filepath.Dir(second)); !os.IsNotExist(err) {
+		t.Errorf("Expected shadow directory to be removed on Close, stat error = %v", err)
+	}
+}
+
+func TestShadowStore_CopyRejectsChangedContent(t *testing.T) {
+	store, err := newShadowStore(t.TempDir(), &testLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.close()
+
+	path := filepath.Join(t.TempDir(), "payments.so")
+	if err := os.WriteFile(path, []byte("v1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := fileSHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is replaced after it was hashed
+	if err := os.WriteFile(path, []byte("v2"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.copy(path, hash); err == nil {
+		t.Fatal("Expected copy of changed content to be rejected")
+	}
+	if entries, _ := os.ReadDir(store.dir); len(entries) != 0 {
+		t.Errorf("Expected no copy left behind, got %d entries", len(entries))
+	}
+
+	// A copy of the hashed content keeps the file mode
+	if err := os.WriteFile(path, []byte("v1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	shadow, err := store.copy(path, hash)
+	if err != nil {
+		t.Fatalf("copy() error = %v", err)
+	}
+	if info, err := os.Stat(shadow); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected executable shadow copy, got %v, %v", info, err)
+	}
+}
+
+// shadowPathOf returns the shadow path reported for a loaded plugin
+func shadowPathOf(t *testing.T, m *Manager, name string) string {
+	t.Helper()
+	for _, info := range m.ListPlugins() {
+		if info.Name == name {
+			if info.ShadowPath == "" {
+				t.Fatalf("Plugin %s has no shadow path", name)
+			}
+			return info.ShadowPath
+		}
+	}
+	t.Fatalf("Plugin %s not loaded", name)
+	return ""
+}
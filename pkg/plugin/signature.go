@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// signatureSuffix is appended to a plugin path to locate its detached signature
+const signatureSuffix = ".sig"
+
+// VerifySignature checks the detached ed25519 signature in "<path>.sig" over the
+// content of the file at path. The signature may be stored raw or base64 encoded.
+// Verification succeeds if any of the given keys validates the signature.
+func VerifySignature(path string, keys ...ed25519.PublicKey) error {
+	if len(keys) == 0 {
+		return ErrInvalidSignature{Path: path, Reason: "no trusted public keys"}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plugin: %w", err)
+	}
+	return verifySignature(path, content, keys)
+}
+
+// verifySignature checks the signature in "<path>.sig" over content, which the caller
+// read from path or from a copy of it
+func verifySignature(path string, content []byte, keys []ed25519.PublicKey) error {
+	sig, err := readSignatureFile(path + signatureSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrInvalidSignature{Path: path, Reason: "signature file missing"}
+		}
+		return ErrInvalidSignature{Path: path, Reason: err.Error()}
+	}
+
+	for _, key := range keys {
+		if len(key) == ed25519.PublicKeySize && ed25519.Verify(key, content, sig) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature{Path: path, Reason: "signature does not match any trusted key"}
+}
+
+// readSignatureFile reads a raw or base64 encoded ed25519 signature
+func readSignatureFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == ed25519.SignatureSize {
+		return data, nil
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("malformed signature file %s", path)
+	}
+	return sig, nil
+}
+
+// trustedKeys converts the configured public keys for use with VerifySignature
+func trustedKeys(raw [][]byte) []ed25519.PublicKey {
+	keys := make([]ed25519.PublicKey, 0, len(raw))
+	for _, k := range raw {
+		keys = append(keys, ed25519.PublicKey(k))
+	}
+	return keys
+}
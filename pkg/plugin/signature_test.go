@@ -0,0 +1,118 @@
+package plugin
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "payments.so")
+	content := []byte("plugin content")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var sigErr ErrInvalidSignature
+	if err := VerifySignature(path, pub); !errors.As(err, &sigErr) {
+		t.Fatalf("Expected unsigned plugin to be rejected, got %v", err)
+	}
+
+	// Raw signature
+	if err := os.WriteFile(path+".sig", ed25519.Sign(priv, content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySignature(path, otherPub, pub); err != nil {
+		t.Errorf("VerifySignature() error = %v", err)
+	}
+	if err := VerifySignature(path, otherPub); !errors.As(err, &sigErr) {
+		t.Errorf("Expected untrusted key to be rejected, got %v", err)
+	}
+
+	// Base64 signature over tampered content
+	encoded := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte("other content")))
+	if err := os.WriteFile(path+".sig", []byte(encoded+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySignature(path, pub); !errors.As(err, &sigErr) {
+		t.Errorf("Expected bad signature to be rejected, got %v", err)
+	}
+}
+
+func TestLoadPlugin_RequiresSignature(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+	})
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, cleanup := setupTestManager(t, func(config *Config) { config.TrustedPublicKeys = [][]byte{pub} })
+	defer cleanup()
+
+	path := filepath.Join(m.currentConfig().PluginDir, "payments.so")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var sigErr ErrInvalidSignature
+	if err := m.LoadPlugin(path); !errors.As(err, &sigErr) {
+		t.Fatalf("Expected ErrInvalidSignature, got %v", err)
+	}
+
+	if err := os.WriteFile(path+".sig", ed25519.Sign(priv, []byte("v1")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatalf("LoadPlugin() error = %v", err)
+	}
+}
+
+func TestLoader_VerifySignatureOfOpenedCopy(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, cleanup := setupTestManager(t)
+	defer cleanup()
+	config := m.currentConfig().Clone()
+	config.TrustedPublicKeys = [][]byte{pub}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "payments.so")
+	shadow := filepath.Join(dir, "payments-copy.so")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".sig", ed25519.Sign(priv, []byte("v1")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature covers the file that is opened, not the one it was copied from
+	if err := os.WriteFile(shadow, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var sigErr ErrInvalidSignature
+	if err := m.loader.verify(config, path, shadow, ""); !errors.As(err, &sigErr) {
+		t.Fatalf("Expected ErrInvalidSignature for a copy with other content, got %v", err)
+	}
+	if err := os.WriteFile(shadow, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.loader.verify(config, path, shadow, ""); err != nil {
+		t.Fatalf("verify() error = %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// slogLogger adapts a slog.Logger to Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to l, or to slog.Default() when l is nil. The
+// arguments are passed on as slog key-value pairs, and the logger is a FieldLogger whose
+// children come from l.With.
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &slogLogger{logger: l}
+}
+
+func (l *slogLogger) Debug(msg string, args ...interface{}) { l.log(slog.LevelDebug, msg, args) }
+func (l *slogLogger) Info(msg string, args ...interface{})  { l.log(slog.LevelInfo, msg, args) }
+func (l *slogLogger) Warn(msg string, args ...interface{})  { l.log(slog.LevelWarn, msg, args) }
+func (l *slogLogger) Error(msg string, args ...interface{}) { l.log(slog.LevelError, msg, args) }
+
+func (l *slogLogger) With(args ...interface{}) Logger {
+	return &slogLogger{logger: l.logger.With(args...)}
+}
+
+// log hands an entry to the slog handler with the caller of the Logger method as its
+// source, rather than this adapter or the manager's logger forwarding to it
+func (l *slogLogger) log(level slog.Level, msg string, args []interface{}) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [4]uintptr
+	n := runtime.Callers(3, pcs[:]) // skip Callers, log and the Logger method
+	pc := pcs[0]
+	for _, p := range pcs[:n] {
+		if frame, _ := runtime.CallersFrames([]uintptr{p}).Next(); !isSwapLoggerFrame(frame.Function) {
+			pc = p
+			break
+		}
+	}
+	r := slog.NewRecord(time.Now(), level, msg, pc)
+	r.Add(args...)
+	_ = l.logger.Handler().Handle(ctx, r)
+}
+
+// isSwapLoggerFrame reports whether function is a method of the manager's logger
+func isSwapLoggerFrame(function string) bool {
+	return strings.Contains(function, "/chameleon/pkg/plugin.(*swapLogger).") ||
+		strings.Contains(function, "/chameleon/pkg/plugin.(*swapChild).")
+}
+
+// loggerHandler adapts a Logger to slog.Handler
+type loggerHandler struct {
+	logger Logger
+	group  string // prefix of the keys, from WithGroup: "a.b."
+}
+
+// NewSlogHandler returns a slog.Handler writing to l, for hosts that want slog backed by
+// the plugin logger. Levels below Info go to Debug, below Warn to Info, below Error to
+// Warn and the rest to Error; l filters them. Attributes become key-value arguments, with
+// the keys of groups joined by dots, e.g. "request.id".
+func NewSlogHandler(l Logger) slog.Handler {
+	return &loggerHandler{logger: l}
+}
+
+// Enabled reports true: the Logger interface has no way to ask, so l filters the levels
+func (h *loggerHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *loggerHandler) Handle(_ context.Context, r slog.Record) error {
+	args := make([]interface{}, 0, 2*r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		args = appendAttr(args, h.group, a)
+		return true
+	})
+	switch {
+	case r.Level < slog.LevelInfo:
+		h.logger.Debug(r.Message, args...)
+	case r.Level < slog.LevelWarn:
+		h.logger.Info(r.Message, args...)
+	case r.Level < slog.LevelError:
+		h.logger.Warn(r.Message, args...)
+	default:
+		h.logger.Error(r.Message, args...)
+	}
+	return nil
+}
+
+func (h *loggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var args []interface{}
+	for _, a := range attrs {
+		args = appendAttr(args, h.group, a)
+	}
+	if len(args) == 0 {
+		return h
+	}
+	return &loggerHandler{logger: LoggerWith(h.logger, args...), group: h.group}
+}
+
+func (h *loggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &loggerHandler{logger: h.logger, group: h.group + name + "."}
+}
+
+// appendAttr appends a as key-value arguments, its key prefixed with group. Groups are
+// flattened and empty attributes dropped, as slog handlers do.
+func appendAttr(args []interface{}, group string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return args
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(args, group+a.Key, a.Value.Any())
+	}
+	if a.Key != "" {
+		group += a.Key + "."
+	}
+	for _, ga := range a.Value.Group() {
+		args = appendAttr(args, group, ga)
+	}
+	return args
+}
@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: true})
+	logger := LoggerWith(NewSlogLogger(slog.New(handler)), "plugin", "payments")
+
+	logger.Debug("Plugin freed")
+	logger.Info("Plugin loaded", "version", "1.0.0")
+	logger.Warn("Slow plugin call", "function", "Pay", "duration", 1500*time.Millisecond)
+	logger.Error("Plugin process exited", "restarts", 3)
+
+	entries := jsonLines(t, &buf)
+	if len(entries) != 4 {
+		t.Fatalf("wrote %d entries, want 4:\n%s", len(entries), buf.String())
+	}
+	for i, level := range []string{"DEBUG", "INFO", "WARN", "ERROR"} {
+		if entries[i]["level"] != level || entries[i]["plugin"] != "payments" {
+			t.Errorf("entry %d = %v, want %s for plugin payments", i, entries[i], level)
+		}
+	}
+	if e := entries[2]; e["function"] != "Pay" || e["duration"] != float64(1500*time.Millisecond) {
+		t.Errorf("Slow plugin call entry = %v, want the function and duration", e)
+	}
+	if e := entries[3]; e["restarts"] != float64(3) {
+		t.Errorf("Plugin process exited entry = %v, want restarts 3", e)
+	}
+	// The source is the caller, not the adapter
+	if source, _ := entries[0]["source"].(map[string]interface{}); !strings.HasSuffix(source["file"].(string), "slog_test.go") {
+		t.Errorf("source = %v, want slog_test.go", entries[0]["source"])
+	}
+
+	// Also when the manager's logger forwards to it
+	buf.Reset()
+	LoggerWith(newSwapLogger(NewSlogLogger(slog.New(handler))), "plugin", "payments").Info("Plugin loaded")
+	if entries := jsonLines(t, &buf); len(entries) != 1 {
+		t.Errorf("wrote %d entries through the manager's logger, want 1", len(entries))
+	} else if source, _ := entries[0]["source"].(map[string]interface{}); !strings.HasSuffix(source["file"].(string), "slog_test.go") {
+		t.Errorf("source through the manager's logger = %v, want slog_test.go", entries[0]["source"])
+	}
+
+	if l := NewSlogLogger(nil).(*slogLogger); l.logger != slog.Default() {
+		t.Error("NewSlogLogger(nil) does not write to slog.Default()")
+	}
+	buf.Reset()
+	NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))).Debug("Filtered out")
+	if buf.Len() != 0 {
+		t.Errorf("Debug wrote %q at the handler's Info level", buf.String())
+	}
+}
+
+func TestNewSlogHandler(t *testing.T) {
+	logger := &testLogger{}
+	l := slog.New(NewSlogHandler(logger))
+
+	l.Log(context.Background(), slog.LevelDebug-4, "Trace")
+	l.Info("Plugin loaded", "plugin", "payments", slog.Int("replicas", 2))
+	l.Log(context.Background(), slog.LevelInfo+2, "Notice")
+	l.Warn("Slow plugin call")
+	l.Log(context.Background(), slog.LevelError+4, "Fatal")
+
+	var entries []string
+	logger.mu.Lock()
+	entries = append(entries, logger.entries...)
+	logger.mu.Unlock()
+	want := []string{"DEBUG: Trace", "INFO: Plugin loaded", "INFO: Notice", "WARN: Slow plugin call", "ERROR: Fatal"}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %v, want %v", entries, want)
+	}
+	fields := logger.fields("INFO: Plugin loaded")
+	if len(fields) != 1 || fields[0]["plugin"] != "payments" || fields[0]["replicas"] != int64(2) {
+		t.Errorf("Plugin loaded fields = %v, want plugin payments and 2 replicas", fields)
+	}
+
+	// Attributes and groups bound to the slog logger come first, keyed by their groups
+	l.With("plugin", "payments").WithGroup("request").With("id", 7).Error("Call failed",
+		"function", "Pay", slog.Group("card", "last4", "4242"), slog.Group("", "inline", true), slog.Attr{})
+	got := logger.fields("ERROR: Call failed")
+	wantFields := []map[string]interface{}{{
+		"plugin": "payments", "request.id": int64(7), "request.function": "Pay",
+		"request.card.last4": "4242", "request.inline": true,
+	}}
+	if !reflect.DeepEqual(got, wantFields) {
+		t.Errorf("Call failed fields = %v, want %v", got, wantFields)
+	}
+}
@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"sync"
+	"time"
+)
+
+// slowCallLog logs the calls to a plugin slower than its SlowCallThreshold, at most
+// SlowCallLogRate lines a second
+type slowCallLog struct {
+	mu         sync.Mutex
+	threshold  time.Duration // zero disables the log
+	interval   time.Duration // least time between lines; zero logs every slow call
+	last       time.Time     // when the last line was written
+	suppressed int64         // slow calls not logged since the last line
+}
+
+// configure applies a plugin's slow-call settings
+func (l *slowCallLog) configure(config PluginSpecificConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.threshold = config.SlowCallThreshold
+	l.interval = 0
+	if config.SlowCallLogRate > 0 {
+		l.interval = time.Duration(float64(time.Second) / config.SlowCallLogRate)
+	}
+}
+
+// admit reports whether a call that took duration should be logged at now, with the
+// number of slow calls left out since the last line
+func (l *slowCallLog) admit(duration time.Duration, now time.Time) (threshold time.Duration, suppressed int64, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.threshold <= 0 || duration <= l.threshold {
+		return 0, 0, false
+	}
+	if l.interval > 0 && !l.last.IsZero() && now.Sub(l.last) < l.interval {
+		l.suppressed++
+		return 0, 0, false
+	}
+	suppressed, l.suppressed = l.suppressed, 0
+	l.last = now
+	return l.threshold, suppressed, true
+}
+
+// configureSlowCalls applies a plugin's slow-call settings, creating its log on first load
+func (m *Manager) configureSlowCalls(name string, config *PluginSpecificConfig) {
+	val, _ := m.slowCalls.LoadOrStore(name, &slowCallLog{})
+	val.(*slowCallLog).configure(*config)
+}
+
+// logSlowCall warns of a call slower than its plugin's SlowCallThreshold. The arguments
+// are counted, never logged, as they may hold data that must not reach the logs.
+func (m *Manager) logSlowCall(pluginName, version, funcName, callID string, args int, duration time.Duration, err error) {
+	val, ok := m.slowCalls.Load(pluginName)
+	if !ok {
+		return
+	}
+	threshold, suppressed, ok := val.(*slowCallLog).admit(duration, m.clock.Now())
+	if !ok {
+		return
+	}
+	fields := []interface{}{"version", version, "function", funcName, "call_id", callID, "duration", duration,
+		"threshold", threshold, "args", args, "success", err == nil}
+	if suppressed > 0 {
+		fields = append(fields, "suppressed", suppressed)
+	}
+	m.pluginLogger(pluginName).Warn("Slow plugin call", fields...)
+}
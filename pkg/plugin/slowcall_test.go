@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
+)
+
+// newSlowCallManager loads a plugin whose Slow function sleeps and fails when asked to,
+// logging to logger
+func newSlowCallManager(t *testing.T, pc PluginSpecificConfig, logger Logger, opts ...ManagerOption) *Manager {
+	t.Helper()
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{
+			"Fast": returning("ok"),
+			"Slow": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+				time.Sleep(20 * time.Millisecond)
+				if len(args) > 0 && args[0] == "fail" {
+					return nil, errors.New("declined")
+				}
+				return "ok", nil
+			},
+		}),
+	})
+	m := newTestManager(t, func(config *Config) {
+		config.PluginConfigs["payments"] = pc
+	}, append(opts, WithLogger(logger))...)
+	loadTestPlugin(t, m, "payments", "v1")
+	return m
+}
+
+func TestManager_SlowCallThreshold(t *testing.T) {
+	logger := &testLogger{}
+	m := newSlowCallManager(t, PluginSpecificConfig{SlowCallThreshold: 10 * time.Millisecond}, logger)
+
+	ctx := context.Background()
+	if _, err := m.Call(ctx, "payments", "Fast"); err != nil {
+		t.Fatal(err)
+	}
+	m.Call(ctx, "payments", "Slow", "fail", "card-4242")
+
+	warnings := logger.fields("WARN: Slow plugin call")
+	if len(warnings) != 1 {
+		t.Fatalf("Slow call warnings = %v, want one", warnings)
+	}
+	fields := warnings[0]
+	if fields["plugin"] != "payments" || fields["function"] != "Slow" || fields["args"] != 2 || fields["success"] != false {
+		t.Errorf("Slow call warning = %v, want payments.Slow with 2 arguments, failed", fields)
+	}
+	if d, _ := fields["duration"].(time.Duration); d < 20*time.Millisecond {
+		t.Errorf("Slow call duration = %v, want at least 20ms", fields["duration"])
+	}
+	for _, v := range fields {
+		if v == "card-4242" || v == "fail" {
+			t.Errorf("Slow call warning %v logs an argument", fields)
+		}
+	}
+}
+
+func TestManager_SlowCallLogRate(t *testing.T) {
+	logger := &testLogger{}
+	fake := clocktest.NewFake(time.Date(2024, 11, 16, 12, 0, 0, 0, time.UTC))
+	m := newSlowCallManager(t, PluginSpecificConfig{
+		SlowCallThreshold: 10 * time.Millisecond,
+		SlowCallLogRate:   1,
+	}, logger, WithClock(fake))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := m.Call(ctx, "payments", "Slow"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if warnings := logger.fields("WARN: Slow plugin call"); len(warnings) != 1 {
+		t.Fatalf("Slow call warnings = %v within a second, want one", warnings)
+	}
+
+	fake.Advance(time.Second)
+	if _, err := m.Call(ctx, "payments", "Slow"); err != nil {
+		t.Fatal(err)
+	}
+	warnings := logger.fields("WARN: Slow plugin call")
+	if len(warnings) != 2 {
+		t.Fatalf("Slow call warnings = %v after a second, want two", warnings)
+	}
+	if warnings[1]["suppressed"] != int64(2) || warnings[1]["success"] != true {
+		t.Errorf("Second warning = %v, want 2 calls suppressed and success", warnings[1])
+	}
+}
@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// stateFileVersion is the format version written to Config.StateFile
+const stateFileVersion = 1
+
+// stateFile is the JSON document kept at Config.StateFile
+type stateFile struct {
+	Version int          `json:"version"`
+	Plugins []stateEntry `json:"plugins"`
+}
+
+// stateEntry records one loaded plugin. Entries marked disabled by hand are kept in the
+// file but not restored.
+type stateEntry struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Source   string `json:"source,omitempty"`
+	Version  string `json:"version"`
+	Hash     string `json:"hash,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// stateStore writes the loaded-plugin set to Config.StateFile
+type stateStore struct {
+	path string
+	mu   sync.Mutex
+	// ready is set once the saved state has been restored; earlier snapshots would drop
+	// the entries not yet replayed
+	ready bool
+	// disabled entries are written back as they were read
+	disabled []stateEntry
+}
+
+// readStateFile reads the saved state. A missing file is an empty state; a corrupted one
+// is renamed aside so it does not prevent startup.
+func (m *Manager) readStateFile(path string) []stateEntry {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		m.logger.Error("Failed to read state file, starting without it", "path", path, "error", err)
+		return nil
+	}
+
+	var state stateFile
+	if err = json.Unmarshal(data, &state); err == nil && state.Version != stateFileVersion {
+		err = fmt.Errorf("unsupported version %d", state.Version)
+	}
+	if err != nil {
+		aside := path + ".corrupt-" + time.Now().UTC().Format("20060102T150405")
+		m.logger.Error("State file is corrupted, moving it aside", "path", path, "movedTo", aside, "error", err)
+		if err := os.Rename(path, aside); err != nil {
+			m.logger.Warn("Failed to move corrupted state file aside", "path", path, "error", err)
+		}
+		return nil
+	}
+	return state.Plugins
+}
+
+// restoreState loads the plugins saved in Config.StateFile that startup has not loaded,
+// recording what it restored, skipped or failed to load in the report. Entries whose file
+// is gone or no longer matches the saved hash are skipped. Failures never fail startup.
+func (m *Manager) restoreState(report *LoadReport) {
+	entries := m.readStateFile(m.state.path)
+
+	var candidates []loadCandidate
+	for _, entry := range entries {
+		entry := entry
+		if entry.Disabled {
+			m.state.disabled = append(m.state.disabled, entry)
+			continue
+		}
+		if _, loaded := m.plugins.Load(entry.Name); loaded {
+			continue
+		}
+		candidates = append(candidates, loadCandidate{
+			path: entry.Path,
+			name: entry.Name,
+			load: func() error { return m.restoreEntry(entry, report) },
+		})
+	}
+	m.orderCandidates(candidates)
+	for _, c := range candidates {
+		c.load()
+	}
+
+	m.state.mu.Lock()
+	m.state.ready = true
+	m.state.mu.Unlock()
+	m.saveState()
+}
+
+// restoreEntry loads one saved plugin after checking its file still holds what was saved
+func (m *Manager) restoreEntry(entry stateEntry, report *LoadReport) error {
+	if entry.Hash != "" {
+		hash, err := fileSHA256(resolvePath(entry.Path))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			m.pluginLogger(entry.Name).Warn("Plugin saved in the state file no longer exists", "path", entry.Path)
+			report.skip(entry.Path, "saved plugin file no longer exists")
+			return nil
+		case err != nil:
+			report.Failed = append(report.Failed, LoadFailure{Path: entry.Path, Name: entry.Name, Err: err})
+			return err
+		case hash != entry.Hash:
+			m.pluginLogger(entry.Name).Warn("Plugin saved in the state file has changed, not restoring it",
+				"path", entry.Path, "saved", entry.Hash, "actual", hash)
+			report.skip(entry.Path, "saved plugin file has changed")
+			return nil
+		}
+		if entry.Source != "" {
+			m.fetched.Store(entry.Hash, entry.Source)
+		}
+	}
+
+	if err := m.loadPlugin(entry.Path, nil, loadOptions{actor: ActorRestore}); err != nil {
+		m.pluginLogger(entry.Name).Error("Failed to restore plugin", "path", entry.Path, "error", err)
+		report.Failed = append(report.Failed, LoadFailure{Path: entry.Path, Name: entry.Name, Err: err})
+		return err
+	}
+	report.Restored = append(report.Restored, entry.Name)
+	return nil
+}
+
+// saveState writes the loaded plugins to Config.StateFile, leaving out those whose
+// configuration opts out with Persist
+func (m *Manager) saveState() {
+	m.state.mu.Lock()
+	defer m.state.mu.Unlock()
+	if !m.state.ready || m.ctx.Err() != nil {
+		return
+	}
+
+	state := stateFile{Version: stateFileVersion, Plugins: []stateEntry{}}
+	loaded := make(map[string]bool)
+	m.plugins.Range(func(key, value interface{}) bool {
+		name, instance := key.(string), value.(*PluginInstance)
+		loaded[name] = true
+		if !m.currentConfig().GetPluginConfig(name).persisted() {
+			return true
+		}
+		state.Plugins = append(state.Plugins, stateEntry{
+			Name:    name,
+			Path:    instance.path,
+			Source:  instance.source,
+			Version: instance.version,
+			Hash:    instance.hash,
+		})
+		return true
+	})
+	for _, entry := range m.state.disabled {
+		if !loaded[entry.Name] {
+			state.Plugins = append(state.Plugins, entry)
+		}
+	}
+	sort.Slice(state.Plugins, func(i, j int) bool { return state.Plugins[i].Name < state.Plugins[j].Name })
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(m.state.path, data)
+	}
+	if err != nil {
+		m.logger.Warn("Failed to save plugin state", "path", m.state.path, "error", err)
+	}
+}
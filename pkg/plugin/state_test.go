@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readState(t *testing.T, path string) stateFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("State file %s: %v", data, err)
+	}
+	return state
+}
+
+func TestState_Restore(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+	})
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	path := filepath.Join(t.TempDir(), "payments.so")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newTestManager(t, func(c *Config) { c.StateFile = stateFile })
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	state := readState(t, stateFile)
+	if len(state.Plugins) != 1 || state.Plugins[0].Name != "payments" || state.Plugins[0].Path != path ||
+		state.Plugins[0].Version != "1.0.0" || state.Plugins[0].Hash == "" {
+		t.Fatalf("Saved state = %+v, want payments 1.0.0", state)
+	}
+	m.Close()
+	if len(readState(t, stateFile).Plugins) != 1 {
+		t.Errorf("Close dropped plugins from the state file")
+	}
+
+	restored := newTestManager(t, func(c *Config) { c.StateFile = stateFile })
+	if report := restored.LoadReport(); len(report.Restored) != 1 || report.Restored[0] != "payments" {
+		t.Errorf("Restored = %v, want payments", report.Restored)
+	}
+	if p, ok := restored.GetPluginPath("payments"); !ok || p != path {
+		t.Errorf("Restored plugin path = %q, %v; want %q", p, ok, path)
+	}
+}
+
+func writeState(t *testing.T, path string, entries ...stateEntry) {
+	t.Helper()
+	data, err := json.Marshal(stateFile{Version: stateFileVersion, Plugins: entries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestState_SkipsChangedAndMissingFiles(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+	})
+	dir := t.TempDir()
+	stateFile := filepath.Join(dir, "state.json")
+	payments := filepath.Join(dir, "payments.so")
+	if err := os.WriteFile(payments, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeState(t, stateFile,
+		stateEntry{Name: "payments", Path: payments, Version: "1.0.0", Hash: "0123"},
+		stateEntry{Name: "orders", Path: filepath.Join(dir, "orders.so"), Version: "1.0.0", Hash: "4567"})
+
+	m := newTestManager(t, func(c *Config) { c.StateFile = stateFile })
+	if report := m.LoadReport(); len(report.Restored) != 0 || len(report.Skipped) != 2 || len(report.Failed) != 0 {
+		t.Errorf("Report = %+v, want both entries skipped", report)
+	}
+	if len(m.ListPlugins()) != 0 {
+		t.Errorf("Loaded %+v, want nothing", m.ListPlugins())
+	}
+}
+
+func TestState_CorruptedFile(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(stateFile, []byte(`{"version": 1, "plugins": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newTestManager(t, func(c *Config) { c.StateFile = stateFile })
+
+	if aside, _ := filepath.Glob(stateFile + ".corrupt-*"); len(aside) != 1 {
+		t.Errorf("Corrupted state files moved aside = %v, want one", aside)
+	}
+	if state := readState(t, stateFile); len(state.Plugins) != 0 {
+		t.Errorf("New state = %+v, want empty", state)
+	}
+}
+
+func TestState_PersistOptOutAndDisabledEntries(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+	})
+	dir := t.TempDir()
+	stateFile := filepath.Join(dir, "state.json")
+	path := filepath.Join(dir, "payments.so")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	disabled := stateEntry{Name: "orders", Path: filepath.Join(dir, "orders.so"), Version: "1.0.0", Disabled: true}
+	writeState(t, stateFile, disabled)
+
+	persist := false
+	m := newTestManager(t, func(c *Config) {
+		c.StateFile = stateFile
+		c.PluginConfigs["payments"] = PluginSpecificConfig{Persist: &persist}
+	})
+	if report := m.LoadReport(); len(report.Restored) != 0 || len(report.Skipped) != 0 {
+		t.Errorf("Report = %+v, want the disabled entry left alone", report)
+	}
+	if err := m.LoadPlugin(path); err != nil {
+		t.Fatal(err)
+	}
+	if state := readState(t, stateFile); len(state.Plugins) != 1 || state.Plugins[0] != disabled {
+		t.Errorf("Saved state = %+v, want only the disabled entry", state)
+	}
+}
@@ -0,0 +1,62 @@
+package plugin
+
+import "time"
+
+// ManagerStats is a rollup of the manager's plugins and activity for dashboards and
+// status endpoints
+type ManagerStats struct {
+	// Plugins is the number of plugin names registered
+	Plugins int `json:"plugins"`
+	// PluginsByState counts the registered plugins by state, and under "deprecated" the
+	// replaced instances still awaiting garbage collection
+	PluginsByState map[string]int `json:"plugins_by_state"`
+	// Calls and Errors count the calls to plugin functions since the manager started, and
+	// those that failed; calls refused by a breaker or to unknown functions are left out.
+	// Unlike the metrics they are never reset.
+	Calls  int64 `json:"calls"`
+	Errors int64 `json:"errors"`
+	// OpenBreakers and HalfOpenBreakers count the plugins whose circuit breaker is in
+	// that state
+	OpenBreakers     int `json:"open_breakers"`
+	HalfOpenBreakers int `json:"half_open_breakers"`
+	// HotReloads counts the loads of files the watcher saw change that succeeded
+	HotReloads int64 `json:"hot_reloads"`
+	// Upgrades counts the loaded plugins replaced by a new instance
+	Upgrades  int64         `json:"upgrades"`
+	StartedAt time.Time     `json:"started_at"`
+	Uptime    time.Duration `json:"uptime_ns"`
+}
+
+// Stats returns a rollup of the manager's plugins and activity. It only reads counters
+// and takes no lock shared with calls, so status endpoints may call it at any rate.
+func (m *Manager) Stats() ManagerStats {
+	now := m.clock.Now()
+	stats := ManagerStats{
+		PluginsByState: make(map[string]int),
+		Calls:          m.calls.Load(),
+		Errors:         m.callErrors.Load(),
+		HotReloads:     m.hotReloads.Load(),
+		Upgrades:       m.upgrades.Load(),
+		StartedAt:      m.startedAt,
+		Uptime:         now.Sub(m.startedAt),
+	}
+	m.plugins.Range(func(key, value interface{}) bool {
+		stats.Plugins++
+		stats.PluginsByState[value.(*PluginInstance).State().String()]++
+		return true
+	})
+	m.deprecated.Range(func(key, value interface{}) bool {
+		stats.PluginsByState[StateDeprecated.String()]++
+		return true
+	})
+	m.breakers.Range(func(key, value interface{}) bool {
+		switch value.(*CircuitBreaker).State() {
+		case StateOpen:
+			stats.OpenBreakers++
+		case StateHalfOpen:
+			stats.HalfOpenBreakers++
+		}
+		return true
+	})
+	return stats
+}
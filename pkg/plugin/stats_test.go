@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
+)
+
+func TestManager_Stats(t *testing.T) {
+	funcs := map[string]InvokeFunc{
+		"Pay": returning("ok"),
+		"Fail": func(ctx context.Context, args ...interface{}) (interface{}, error) {
+			return nil, errors.New("declined")
+		},
+	}
+	useFakeOpener(t, map[string]fakeLib{
+		"v1": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, funcs),
+		"v2": newFakeLib(&fakeBureau{name: "payments", version: "2.0.0"}, funcs),
+	})
+	started := time.Date(2024, 11, 16, 12, 0, 0, 0, time.UTC)
+	fake := clocktest.NewFake(started)
+	m := newTestManager(t, func(config *Config) {
+		config.GCInterval = 0
+		config.FileStabilityWindow = 0
+	}, WithClock(fake))
+	path := loadTestPlugin(t, m, "payments", "v1")
+
+	ctx := context.Background()
+	for _, fn := range []string{"Pay", "Fail", "Missing"} {
+		m.Call(ctx, "payments", fn)
+	}
+	// The watcher's reload upgrades the plugin and leaves v1 for the collector
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.handleNewPlugin(path)
+	if err := m.TripBreaker("payments", "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	m.Call(ctx, "payments", "Pay")
+	m.ResetMetrics()
+	fake.Advance(time.Hour)
+
+	stats := m.Stats()
+	want := ManagerStats{
+		Plugins:        1,
+		PluginsByState: map[string]int{"active": 1, "deprecated": 1},
+		Calls:          2,
+		Errors:         1,
+		OpenBreakers:   1,
+		HotReloads:     1,
+		Upgrades:       1,
+		StartedAt:      started,
+		Uptime:         time.Hour,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ManagerStats
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, stats) {
+		t.Errorf("decoded stats = %+v, want %+v", decoded, stats)
+	}
+}
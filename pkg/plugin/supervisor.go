@@ -0,0 +1,205 @@
+package plugin
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// exitReporter is implemented by bureaus whose code runs in a process that can exit on
+// its own, such as process plugins
+type exitReporter interface {
+	// exitChan is closed once the process has exited
+	exitChan() <-chan struct{}
+	// exitError tells how the process exited once exitChan is closed
+	exitError() error
+}
+
+// restartHistory records when a plugin was restarted, so restarts are counted across
+// the instances they create
+type restartHistory struct {
+	mu    sync.Mutex
+	times []time.Time
+}
+
+// recent drops restarts older than window and returns how many remain
+func (h *restartHistory) recent(window time.Duration, now time.Time) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if window > 0 {
+		kept := h.times[:0]
+		for _, t := range h.times {
+			if now.Sub(t) < window {
+				kept = append(kept, t)
+			}
+		}
+		h.times = kept
+	}
+	return len(h.times)
+}
+
+func (h *restartHistory) add(t time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.times = append(h.times, t)
+}
+
+// errRestartSuperseded stops a restart when the plugin was replaced or unloaded meanwhile
+var errRestartSuperseded = errors.New("plugin was replaced while restarting")
+
+// supervise watches the process behind a registered instance and restarts the plugin
+// under its policy when the process exits while the instance is still active
+func (m *Manager) supervise(name string, instance *PluginInstance, config PluginSpecificConfig) {
+	reporter, ok := instance.bureau.(exitReporter)
+	if !ok {
+		return
+	}
+	m.eg.Go(func() error {
+		select {
+		case <-m.ctx.Done():
+			return nil
+		case <-reporter.exitChan():
+		}
+		if m.ctx.Err() != nil || !m.markFailed(name, instance) {
+			// The instance was retired and its process stopped deliberately
+			return nil
+		}
+		m.pluginLogger(name).Error("Plugin process exited", "version", instance.version,
+			"error", reporter.exitError())
+		m.restartLoop(name, instance, config, reporter.exitError())
+		return nil
+	})
+}
+
+// markFailed moves a registered active instance to StateFailed. It reports false when
+// the instance is no longer registered or has been retired.
+func (m *Manager) markFailed(name string, instance *PluginInstance) bool {
+	unlockName := m.nameLocks.lock(name)
+	defer unlockName()
+	if m.slotOf(name, instance) < 0 {
+		return false
+	}
+	instance.Lock()
+	defer instance.Unlock()
+	if instance.state != StateActive {
+		return false
+	}
+	instance.state = StateFailed
+	return true
+}
+
+// restartLoop restarts a failed plugin with exponential backoff until a restart succeeds,
+// the policy's limit is reached or the manager closes
+func (m *Manager) restartLoop(name string, failed *PluginInstance, config PluginSpecificConfig, cause error) {
+	policy := config.RestartPolicy
+	if policy.MaxRestarts <= 0 {
+		m.emit(Event{Type: EventGaveUp, Plugin: name, Version: failed.version, Path: failed.path, Err: cause,
+			actor: ActorSupervisor, hash: failed.hash})
+		return
+	}
+
+	historyVal, _ := m.restarts.LoadOrStore(name, &restartHistory{})
+	history := historyVal.(*restartHistory)
+
+	failed.restarting.Store(true)
+	defer failed.restarting.Store(false)
+
+	lastErr := cause
+	for {
+		attempts := history.recent(policy.Window, m.clock.Now())
+		if attempts >= policy.MaxRestarts {
+			m.pluginLogger(name).Error("Giving up on restarting plugin", "restarts", attempts,
+				"window", policy.Window, "error", lastErr)
+			m.emit(Event{Type: EventGaveUp, Plugin: name, Version: failed.version, Path: failed.path, Err: lastErr,
+				actor: ActorSupervisor, hash: failed.hash})
+			return
+		}
+
+		backoff := policy.Backoff
+		for i := 0; i < attempts && backoff < time.Hour; i++ {
+			backoff *= 2
+		}
+		m.pluginLogger(name).Info("Restarting plugin", "attempt", attempts+1, "backoff", backoff)
+		timer := m.clock.NewTimer(backoff)
+		select {
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+
+		history.add(m.clock.Now())
+		instance, err := m.restart(name, failed, config)
+		if errors.Is(err, errRestartSuperseded) || m.ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			m.pluginLogger(name).Info("Plugin restarted", "version", instance.version)
+			m.emit(Event{Type: EventRestarted, Plugin: name, Version: instance.version, Path: instance.path,
+				actor: ActorSupervisor, hash: instance.hash})
+			return
+		}
+		m.pluginLogger(name).Warn("Failed to restart plugin", "error", err)
+		lastErr = err
+	}
+}
+
+// restart loads the failed plugin again, checks it as loadPlugin does, runs Init with its
+// original arguments and registers the new instance in the failed one's place with a fresh circuit breaker
+func (m *Manager) restart(name string, failed *PluginInstance, config PluginSpecificConfig) (*PluginInstance, error) {
+	unlockPath := m.pathLocks.lock(resolvePath(failed.path))
+	defer unlockPath()
+	unlockName := m.nameLocks.lock(name)
+	defer unlockName()
+	slot := m.slotOf(name, failed)
+	if slot < 0 {
+		return nil, errRestartSuperseded
+	}
+
+	var plugin *Plugin
+	var err error
+	if slot == 0 {
+		// The cached plugin is the dead one
+		m.loader.evictPlugin(failed.Plugin)
+		plugin, err = m.loader.Load(m.ctx, failed.path, config)
+	} else {
+		plugin, err = m.loader.loadReplica(m.ctx, failed.Plugin, failed.path, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The file may have been replaced since the plugin was loaded
+	if _, err := m.checkPlugin(name, plugin, &config); err != nil {
+		m.discard(failed.path, plugin)
+		return nil, err
+	}
+	initDuration, err := m.initPlugin(name, plugin, &config)
+	if err != nil {
+		m.discard(failed.path, plugin)
+		return nil, ErrPluginInit{Name: name, Err: err}
+	}
+
+	instance := &PluginInstance{
+		Plugin:         plugin,
+		state:          StateActive,
+		version:        plugin.Version(),
+		path:           failed.path,
+		hash:           plugin.hash,
+		source:         m.fetchedSource(plugin.hash),
+		initDuration:   initDuration,
+		loadedAt:       m.clock.Now(),
+		previousLoadAt: failed.loadedAt,
+	}
+	breaker := m.newBreaker(name, config.CircuitBreaker)
+	if _, prev := m.replaceSlot(name, m.replicaSetFor(name), slot, instance, breaker); prev != nil {
+		prev.Close()
+	}
+
+	// The old process is gone, so there is nothing for Free to release
+	m.loader.evictPlugin(failed.Plugin)
+	if err := failed.unload(); err != nil {
+		m.pluginLogger(name).Warn("Failed to unload crashed plugin", "error", err)
+	}
+	m.supervise(name, instance, config)
+	return instance, nil
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zyanho/chameleon/pkg/clock/clocktest"
+)
+
+// newSupervisedCalc loads the calc process plugin under the given restart policy
+func newSupervisedCalc(t *testing.T, policy RestartPolicy, opts ...ManagerOption) (*Manager, <-chan Event) {
+	t.Helper()
+	useProcessHelper(t)
+	m := newTestManager(t, func(config *Config) {
+		config.PluginConfigs["calc"] = PluginSpecificConfig{
+			CircuitBreaker: CircuitBreakerConfig{Enabled: true, MaxFailures: 1, ResetInterval: time.Minute, OpenDuration: time.Minute},
+			RestartPolicy:  policy,
+			Options:        map[string]interface{}{OptionBackend: BackendProcess},
+		}
+	}, append([]ManagerOption{WithLogger(&testLogger{})}, opts...)...)
+	events, unsubscribe := m.Subscribe(16)
+	t.Cleanup(unsubscribe)
+	loadTestPlugin(t, m, "calc", "calc")
+	return m, events
+}
+
+// awaitEvent returns the next event of the given type
+func awaitEvent(t *testing.T, events <-chan Event, typ EventType) Event {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == typ {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for a %s event", typ)
+		}
+	}
+}
+
+func TestSupervisor_RestartsCrashedProcess(t *testing.T) {
+	m, events := newSupervisedCalc(t, RestartPolicy{MaxRestarts: 2, Backoff: 300 * time.Millisecond, Window: time.Minute})
+	ctx := context.Background()
+
+	var exited ErrProcessExited
+	if _, err := m.Call(ctx, "calc", "Crash"); !errors.As(err, &exited) {
+		t.Fatalf("Call(Crash) error = %v, want ErrProcessExited", err)
+	}
+
+	// Calls fail fast while the supervisor waits to restart the plugin
+	var restarting ErrPluginRestarting
+	waitFor(t, "calls to be refused while restarting", func() bool {
+		_, err := m.Call(ctx, "calc", "Divide", 4, 2)
+		return errors.As(err, &restarting)
+	})
+	if plugins := m.ListPlugins(); len(plugins) != 1 || plugins[0].State != StateFailed {
+		t.Errorf("ListPlugins() = %+v, want calc in StateFailed", plugins)
+	}
+
+	if e := awaitEvent(t, events, EventRestarted); e.Plugin != "calc" || e.Version != "1.0.0" {
+		t.Errorf("Restarted event = %+v", e)
+	}
+	// The breaker opened by the crash starts over with the new process
+	if m.IsCircuitBreakerOpen("calc") {
+		t.Error("Expected the breaker to be reset by the restart")
+	}
+	if result, err := m.Call(ctx, "calc", "Divide", 4, 2); err != nil || result != 2 {
+		t.Errorf("Call(Divide) after restart = %v, %v, want 2", result, err)
+	}
+	if plugins := m.ListPlugins(); len(plugins) != 1 || plugins[0].State != StateActive {
+		t.Errorf("ListPlugins() = %+v, want calc active", plugins)
+	}
+}
+
+func TestSupervisor_GivesUp(t *testing.T) {
+	m, events := newSupervisedCalc(t, RestartPolicy{MaxRestarts: 1, Backoff: 10 * time.Millisecond, Window: time.Minute})
+	ctx := context.Background()
+
+	m.Call(ctx, "calc", "Crash")
+	awaitEvent(t, events, EventRestarted)
+	m.Call(ctx, "calc", "Crash")
+	if e := awaitEvent(t, events, EventGaveUp); e.Plugin != "calc" || e.Err == nil {
+		t.Errorf("GaveUp event = %+v, want calc with the exit error", e)
+	}
+
+	if plugins := m.ListPlugins(); len(plugins) != 1 || plugins[0].State != StateFailed {
+		t.Errorf("ListPlugins() = %+v, want calc in StateFailed", plugins)
+	}
+	// With no restart coming, calls report the dead process
+	var restarting ErrPluginRestarting
+	if _, err := m.Call(ctx, "calc", "Divide", 4, 2); err == nil || errors.As(err, &restarting) {
+		t.Errorf("Call() after giving up error = %v, want the process exit", err)
+	}
+}
+
+func TestSupervisor_RestartChecksAndClock(t *testing.T) {
+	clk := clocktest.NewFake(time.Time{})
+	m, events := newSupervisedCalc(t, RestartPolicy{MaxRestarts: 1, Backoff: time.Hour, Window: 24 * time.Hour},
+		WithClock(clk))
+	ctx := context.Background()
+
+	m.Call(ctx, "calc", "Crash")
+	var restarting ErrPluginRestarting
+	waitFor(t, "calls to be refused while restarting", func() bool {
+		_, err := m.Call(ctx, "calc", "Divide", 4, 2)
+		return errors.As(err, &restarting)
+	})
+
+	// A restart is checked like a load
+	val, _ := m.plugins.Load("calc")
+	failed := val.(*PluginInstance)
+	config := m.currentConfig().GetPluginConfig("calc")
+	config.VersionConstraint = ">=2.0.0"
+	var constraintErr ErrVersionConstraint
+	if _, err := m.restart("calc", failed, config); !errors.As(err, &constraintErr) {
+		t.Errorf("restart() error = %v, want ErrVersionConstraint", err)
+	}
+	if plugins := m.ListPlugins(); len(plugins) != 1 || plugins[0].State != StateFailed {
+		t.Fatalf("ListPlugins() = %+v, want calc still in StateFailed", plugins)
+	}
+
+	// The backoff runs on the manager's clock, which has not moved
+	time.Sleep(100 * time.Millisecond)
+	if plugins := m.ListPlugins(); plugins[0].State != StateFailed {
+		t.Fatal("Restarted before the backoff passed on the manager's clock")
+	}
+	waitFor(t, "the restart after the backoff", func() bool {
+		clk.Advance(time.Hour)
+		for {
+			select {
+			case e := <-events:
+				if e.Type == EventRestarted {
+					return true
+				}
+			default:
+				return false
+			}
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// SortKey is the figure TopMethods ranks methods by, highest first
+type SortKey int
+
+const (
+	// SortByAvgTime ranks methods by their average call duration
+	SortByAvgTime SortKey = iota
+	// SortByP95 ranks methods by their estimated 95th percentile call duration
+	SortByP95
+	// SortByTotalTime ranks methods by the time spent in all their calls
+	SortByTotalTime
+	// SortByCount ranks methods by their number of calls
+	SortByCount
+	// SortByErrorRate ranks methods by the share of their calls that failed
+	SortByErrorRate
+)
+
+func (k SortKey) String() string {
+	switch k {
+	case SortByAvgTime:
+		return "avg"
+	case SortByP95:
+		return "p95"
+	case SortByTotalTime:
+		return "total"
+	case SortByCount:
+		return "calls"
+	case SortByErrorRate:
+		return "error-rate"
+	default:
+		return "unknown"
+	}
+}
+
+// MethodReport holds the figures of one method of one plugin version, as ranked by
+// TopMethods
+type MethodReport struct {
+	Plugin    string        `json:"plugin"`
+	Version   string        `json:"version"`
+	Method    string        `json:"method"`
+	Count     int64         `json:"count"`
+	Errors    int64         `json:"errors"`
+	ErrorRate float64       `json:"error_rate"`
+	AvgTime   time.Duration `json:"avg_time_ns"`
+	P95       time.Duration `json:"p95_ns"`
+	TotalTime time.Duration `json:"total_time_ns"`
+}
+
+// TopMethods returns the n methods ranking highest by key across the loaded plugins, or
+// all of them when n is zero or less. Each version of a plugin is ranked on its own, among
+// those that keep metrics (see Config.MetricsVersions). Ties go to the method with more
+// calls, then by plugin, version and method name, so the order is deterministic. The
+// figures of a plugin come from one snapshot of its metrics; nil is returned while
+// metrics are disabled.
+func (m *Manager) TopMethods(key SortKey, n int) []MethodReport {
+	var reports []MethodReport
+	for _, info := range m.ListPlugins() {
+		snapshot, err := m.GetMetricsSnapshot(info.Name)
+		if err != nil {
+			continue
+		}
+		for version, v := range snapshot.Versions {
+			for method, s := range v.Methods {
+				reports = append(reports, MethodReport{
+					Plugin:    info.Name,
+					Version:   version,
+					Method:    method,
+					Count:     s.Count,
+					Errors:    s.Errors,
+					ErrorRate: s.ErrorRate(),
+					AvgTime:   s.AvgTime,
+					P95:       s.Quantile(0.95),
+					TotalTime: s.TotalTime,
+				})
+			}
+		}
+	}
+
+	slices.SortFunc(reports, func(a, b MethodReport) int {
+		if c := cmp.Compare(b.figure(key), a.figure(key)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Plugin, b.Plugin); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Version, b.Version); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Method, b.Method)
+	})
+	if n > 0 && len(reports) > n {
+		reports = reports[:n]
+	}
+	return reports
+}
+
+// figure returns the figure of r that key ranks by
+func (r MethodReport) figure(key SortKey) float64 {
+	switch key {
+	case SortByP95:
+		return float64(r.P95)
+	case SortByTotalTime:
+		return float64(r.TotalTime)
+	case SortByCount:
+		return float64(r.Count)
+	case SortByErrorRate:
+		return r.ErrorRate
+	default:
+		return float64(r.AvgTime)
+	}
+}
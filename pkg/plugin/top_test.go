@@ -0,0 +1,88 @@
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestManager_TopMethods(t *testing.T) {
+	useFakeOpener(t, map[string]fakeLib{
+		"payments": newFakeLib(&fakeBureau{name: "payments", version: "1.0.0"}, map[string]InvokeFunc{}),
+		"ledger":   newFakeLib(&fakeBureau{name: "ledger", version: "2.0.0"}, map[string]InvokeFunc{}),
+	})
+	m := newTestManager(t, func(config *Config) { config.EnableMetrics = true })
+	for _, name := range []string{"payments", "ledger"} {
+		path := filepath.Join(m.currentConfig().PluginDir, name+".so")
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.LoadPlugin(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Pay is called most and longest in all, Refund is the slowest and always fails, and
+	// the ties between the versions of Pay and between Get and Post fall to the names
+	declined := errors.New("declined")
+	record := func(plugin, version, fn string, calls int, d time.Duration, failed int) {
+		for i := 0; i < calls; i++ {
+			var err error
+			if i < failed {
+				err = declined
+			}
+			m.metrics.RecordVersionCall(plugin, version, fn, d, err)
+		}
+	}
+	record("payments", "0.9.0", "Pay", 30, time.Millisecond, 0)
+	record("payments", "1.0.0", "Pay", 30, time.Millisecond, 0)
+	record("payments", "1.0.0", "Refund", 1, 20*time.Millisecond, 1)
+	record("ledger", "2.0.0", "Post", 2, 5*time.Millisecond, 1)
+	record("ledger", "2.0.0", "Get", 2, 5*time.Millisecond, 1)
+
+	order := func(reports []MethodReport) []string {
+		var names []string
+		for _, r := range reports {
+			names = append(names, r.Plugin+"/"+r.Version+"/"+r.Method)
+		}
+		return names
+	}
+	slowest := []string{"payments/1.0.0/Refund", "ledger/2.0.0/Get", "ledger/2.0.0/Post", "payments/0.9.0/Pay", "payments/1.0.0/Pay"}
+	busiest := []string{"payments/0.9.0/Pay", "payments/1.0.0/Pay", "payments/1.0.0/Refund", "ledger/2.0.0/Get", "ledger/2.0.0/Post"}
+	tests := []struct {
+		key  SortKey
+		want []string
+	}{
+		{SortByAvgTime, slowest},
+		{SortByP95, slowest},
+		{SortByTotalTime, busiest},
+		{SortByCount, []string{"payments/0.9.0/Pay", "payments/1.0.0/Pay", "ledger/2.0.0/Get", "ledger/2.0.0/Post", "payments/1.0.0/Refund"}},
+		{SortByErrorRate, slowest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key.String(), func(t *testing.T) {
+			if got := order(m.TopMethods(tt.key, 0)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TopMethods(%v) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	top := m.TopMethods(SortByErrorRate, 2)
+	want := []MethodReport{
+		{Plugin: "payments", Version: "1.0.0", Method: "Refund", Count: 1, Errors: 1, ErrorRate: 1,
+			AvgTime: 20 * time.Millisecond, P95: 20 * time.Millisecond, TotalTime: 20 * time.Millisecond},
+		{Plugin: "ledger", Version: "2.0.0", Method: "Get", Count: 2, Errors: 1, ErrorRate: 0.5,
+			AvgTime: 5 * time.Millisecond, P95: 5 * time.Millisecond, TotalTime: 10 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(top, want) {
+		t.Errorf("TopMethods(SortByErrorRate, 2) = %+v, want %+v", top, want)
+	}
+
+	m.DisableMetrics()
+	if got := m.TopMethods(SortByCount, 0); got != nil {
+		t.Errorf("TopMethods() = %v with metrics disabled, want nil", got)
+	}
+}
@@ -1,5 +1,7 @@
 package plugin
 
+import "time"
+
 // PluginInfo contains basic information about a loaded plugin
 type PluginInfo struct {
 	Name     string
@@ -7,4 +9,91 @@ type PluginInfo struct {
 	State    PluginState
 	RefCount int32
 	Path     string
+	Hash     string    // SHA-256 of the plugin artifact
+	Manifest *Manifest // nil when the plugin has no manifest
+	Metadata *Metadata // nil when the plugin exports no Metadata symbol
+	// ShadowPath is the private copy the plugin was opened from when Config.ShadowCopy or a
+	// verification option is set
+	ShadowPath string
+	// SourceURL is the URL LoadPluginFromURL downloaded the artifact from
+	SourceURL string
+	// Breaker is the state of the plugin's circuit breaker, that of the first replica for
+	// a plugin with replicas
+	Breaker CircuitState
+	// Replicas describes each instance serving the plugin, see PluginSpecificConfig.Replicas
+	Replicas []ReplicaInfo
+	// Size is the size of the artifact in bytes; it and OpenDuration are zero for backends
+	// without artifacts
+	Size int64
+	// OpenDuration is how long the backend took to open the plugin
+	OpenDuration time.Duration
+	// InitDuration is how long Init took, retries included
+	InitDuration time.Duration
+	// LoadedAt is when the plugin was registered
+	LoadedAt time.Time
+	// PreviousLoadAt is the LoadedAt of the instance this one replaced on an upgrade or
+	// restart, zero for a first load
+	PreviousLoadAt time.Time
+}
+
+// ReplicaInfo describes one instance serving a plugin
+type ReplicaInfo struct {
+	Version  string
+	State    PluginState
+	RefCount int32        // calls in flight
+	Breaker  CircuitState // state of the replica's circuit breaker
+}
+
+// BreakerInfo describes a circuit breaker and its statistics
+type BreakerInfo struct {
+	Enabled             bool
+	State               CircuitState
+	Trips               int64 // times the breaker opened
+	Rejected            int64 // calls refused while it was open or half-open
+	Failures            int32 // failures counted toward opening it
+	Timeouts            int32 // timeouts counted toward MaxTimeouts
+	ConsecutiveFailures int32 // failures since the last successful call
+	LastFailure         time.Time
+	// LastTransition is the last state change, or when the breaker was created
+	LastTransition time.Time
+	// NextProbeIn is the time until an open breaker admits a trial call; zero otherwise
+	NextProbeIn time.Duration
+	// Reason is the reason given to TripBreaker, until the breaker closes
+	Reason string
+	// ConcurrencyRejections counts the calls the plugin's concurrency limit refused before
+	// they reached the breaker; only GetBreakerInfo fills it in
+	ConcurrencyRejections int64
+	// Methods holds the breakers of the functions called so far under BreakerScopeMethod
+	Methods map[string]BreakerInfo
+}
+
+// Metadata is the optional self-description a plugin exports as
+// "var Metadata = plugin.Metadata{...}"
+type Metadata struct {
+	Description      string
+	Author           string
+	DocumentationURL string
+	Capabilities     []string
+}
+
+// HasCapability reports whether the metadata lists the capability tag
+func (md *Metadata) HasCapability(capability string) bool {
+	if md == nil {
+		return false
+	}
+	for _, c := range md.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// FuncSignature describes a plugin function's parameters and results, not counting the
+// leading context.Context. Types are written as in Go source, e.g. "int" or "[]string";
+// a variadic final parameter is written as its slice type.
+type FuncSignature struct {
+	Params   []string
+	Results  []string
+	Variadic bool
 }
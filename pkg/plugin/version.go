@@ -0,0 +1,38 @@
+package plugin
+
+import "fmt"
+
+// APIVersion is the version of the contract between host and plugin: the Bureau
+// interface, the InvokeFunc signature and the exported symbol layout. It is bumped
+// whenever that contract changes incompatibly.
+const APIVersion = 1
+
+// MinSupportedAPIVersion is the oldest plugin API version this host can load
+const MinSupportedAPIVersion = 1
+
+// apiVersionSymbol is the exported symbol generated plugins use to declare their APIVersion
+const apiVersionSymbol = "ChameleonAPIVersion"
+
+// checkAPIVersion validates the API version a plugin was built against
+func checkAPIVersion(pluginAPI int) error {
+	if pluginAPI < MinSupportedAPIVersion || pluginAPI > APIVersion {
+		return ErrIncompatibleAPIVersion{PluginAPI: pluginAPI, HostAPI: APIVersion}
+	}
+	return nil
+}
+
+// lookupAPIVersion reads the API version symbol; ok is false when the plugin predates it
+func lookupAPIVersion(plug symbolLookup) (version int, ok bool, err error) {
+	sym, err := plug.Lookup(apiVersionSymbol)
+	if err != nil {
+		return 0, false, nil
+	}
+	switch v := sym.(type) {
+	case *int:
+		return *v, true, nil
+	case int:
+		return v, true, nil
+	default:
+		return 0, false, fmt.Errorf("%s is not an int: got type %T", apiVersionSymbol, sym)
+	}
+}
@@ -0,0 +1,110 @@
+package oci
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dockerHubKey is the key the Docker configuration stores Docker Hub credentials under
+const dockerHubKey = "https://index.docker.io/v1/"
+
+// dockerConfig is the part of the Docker configuration holding registry credentials
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// dockerConfigPath returns the Docker configuration file, honoring DOCKER_CONFIG
+func dockerConfigPath() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".docker", "config.json"), nil
+}
+
+// dockerCredentials looks up the credentials for host the way the Docker CLI does: a
+// credential helper configured for the host, then the default credential store, then
+// the inline auths. No configuration means anonymous access.
+func dockerCredentials(host string) (string, string, error) {
+	path, err := dockerConfigPath()
+	if err != nil {
+		return "", "", nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	var cfg dockerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return "", "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	key := host
+	if host == "docker.io" || host == "registry-1.docker.io" {
+		key = dockerHubKey
+	}
+	if helper := cfg.CredHelpers[host]; helper != "" {
+		return credentialHelper(helper, key)
+	}
+	if cfg.CredsStore != "" {
+		return credentialHelper(cfg.CredsStore, key)
+	}
+	for _, k := range []string{key, "https://" + key, "http://" + key} {
+		entry, ok := cfg.Auths[k]
+		if !ok {
+			continue
+		}
+		if entry.Auth == "" {
+			return entry.Username, entry.Password, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid auth for %s in %s: %w", k, path, err)
+		}
+		username, password, _ := strings.Cut(string(decoded), ":")
+		return username, password, nil
+	}
+	return "", "", nil
+}
+
+// credentialHelper asks "docker-credential-<helper>" for the credentials of a server
+func credentialHelper(helper, server string) (string, string, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// Helpers report unknown servers on stdout and exit non-zero
+		if strings.Contains(string(out), "credentials not found") {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("docker-credential-%s: %w: %s", helper, err, strings.TrimSpace(stderr.String()))
+	}
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return "", "", fmt.Errorf("docker-credential-%s returned invalid output: %w", helper, err)
+	}
+	return creds.Username, creds.Secret, nil
+}
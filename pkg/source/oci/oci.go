@@ -0,0 +1,333 @@
+// Package oci lets plugin.Manager.LoadPluginFromURL fetch plugins from OCI registries:
+//
+//	m, err := plugin.NewManager(ctx, config, plugin.WithArtifactSource("oci", oci.New()))
+//	err = m.LoadPluginFromURL(ctx, "oci://registry.example.com/plugins/hello:1.2.0", plugin.FetchOptions{})
+//
+// A plugin is pushed as an artifact whose manifest holds the plugin file as a layer, e.g.
+// with "oras push registry.example.com/plugins/hello:1.2.0 hello.so". A manifest with
+// several layers selects one by its file name in the URL fragment, as in
+// "oci://registry.example.com/plugins/bundle:1.2.0#hello.so". References may pin the
+// manifest digest with "@sha256:...". Credentials come from the Docker configuration,
+// including its credential helpers.
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/zyanho/chameleon/pkg/plugin"
+)
+
+// Media types of the manifests a Source accepts
+const (
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// titleAnnotation holds a layer's file name
+const titleAnnotation = "org.opencontainers.image.title"
+
+// maxManifestSize bounds downloaded manifests
+const maxManifestSize = 4 << 20
+
+// Source serves oci://registry/repository[:tag|@digest][#file] URLs
+type Source struct {
+	// Client sends registry requests; nil uses http.DefaultClient
+	Client *http.Client
+	// PlainHTTP talks to registries over HTTP instead of HTTPS, e.g. a local test registry
+	PlainHTTP bool
+	// Credentials returns the username and password for a registry host, empty for
+	// anonymous access; nil reads the Docker configuration
+	Credentials func(host string) (username, password string, err error)
+}
+
+// New creates a Source that uses the Docker configuration for credentials
+func New() *Source {
+	return &Source{}
+}
+
+// reference is a parsed oci:// URL
+type reference struct {
+	host       string
+	repository string
+	reference  string // tag or digest
+	file       string // layer title selected by the fragment
+}
+
+// parseRef parses an oci:// URL; the tag defaults to "latest"
+func parseRef(ref string) (reference, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return reference{}, err
+	}
+	repo := strings.TrimPrefix(u.Path, "/")
+	if u.Scheme != "oci" || u.Host == "" || repo == "" {
+		return reference{}, fmt.Errorf("invalid OCI URL %q, want oci://registry/repository:tag", ref)
+	}
+
+	r := reference{host: u.Host, file: u.Fragment, reference: "latest"}
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo, r.reference = repo[:i], repo[i+1:]
+	} else if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo, r.reference = repo[:i], repo[i+1:]
+	}
+	if repo == "" || r.reference == "" {
+		return reference{}, fmt.Errorf("invalid OCI URL %q, want oci://registry/repository:tag", ref)
+	}
+	// Docker Hub serves its API from another host and keeps official images under "library/"
+	if r.host == "docker.io" {
+		r.host = "registry-1.docker.io"
+		if !strings.Contains(repo, "/") {
+			repo = "library/" + repo
+		}
+	}
+	r.repository = repo
+	return r, nil
+}
+
+// manifest is the part of an image manifest a Source reads
+type manifest struct {
+	Layers []descriptor `json:"layers"`
+}
+
+// descriptor is the part of a layer descriptor a Source reads
+type descriptor struct {
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// Fetch resolves the manifest ref names and opens the plugin layer. The descriptor
+// carries the layer's digest, so the download is verified against it.
+func (s *Source) Fetch(ctx context.Context, ref string) (io.ReadCloser, plugin.Descriptor, error) {
+	r, err := parseRef(ref)
+	if err != nil {
+		return nil, plugin.Descriptor{}, err
+	}
+	sess := &session{source: s, host: r.host}
+
+	resp, err := sess.get(ctx, "/v2/"+r.repository+"/manifests/"+r.reference, mediaTypeOCIManifest+", "+mediaTypeDockerManifest)
+	if err != nil {
+		return nil, plugin.Descriptor{}, err
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	resp.Body.Close()
+	if err != nil {
+		return nil, plugin.Descriptor{}, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if len(data) > maxManifestSize {
+		return nil, plugin.Descriptor{}, fmt.Errorf("manifest is over the %d byte limit", maxManifestSize)
+	}
+	if want, ok := strings.CutPrefix(r.reference, "sha256:"); ok {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != want {
+			return nil, plugin.Descriptor{}, fmt.Errorf("manifest digest is sha256:%s, want %s", got, r.reference)
+		}
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, plugin.Descriptor{}, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	layer, err := selectLayer(m.Layers, r.file)
+	if err != nil {
+		return nil, plugin.Descriptor{}, err
+	}
+
+	if algorithm, encoded, ok := strings.Cut(layer.Digest, ":"); !ok || algorithm == "" || encoded == "" ||
+		strings.ContainsAny(layer.Digest, "/?#") {
+		return nil, plugin.Descriptor{}, fmt.Errorf("manifest names an invalid layer digest %q", layer.Digest)
+	}
+
+	resp, err = sess.get(ctx, "/v2/"+r.repository+"/blobs/"+layer.Digest, "")
+	if err != nil {
+		return nil, plugin.Descriptor{}, err
+	}
+	return resp.Body, plugin.Descriptor{
+		Digest: layer.Digest,
+		Size:   layer.Size,
+		Name:   layer.Annotations[titleAnnotation],
+	}, nil
+}
+
+// selectLayer returns the only layer, or the one titled file
+func selectLayer(layers []descriptor, file string) (descriptor, error) {
+	if file == "" {
+		if len(layers) != 1 {
+			return descriptor{}, fmt.Errorf("manifest has %d layers; name the plugin file in the URL fragment", len(layers))
+		}
+		return layers[0], nil
+	}
+	for _, layer := range layers {
+		if layer.Annotations[titleAnnotation] == file {
+			return layer, nil
+		}
+	}
+	return descriptor{}, fmt.Errorf("manifest has no layer titled %q", file)
+}
+
+// session sends the requests of one Fetch, authenticating once the registry asks for it
+type session struct {
+	source *Source
+	host   string
+	auth   string // Authorization header, once known
+}
+
+// get requests path on the registry, answering an authentication challenge if needed
+func (s *session) get(ctx context.Context, path, accept string) (*http.Response, error) {
+	resp, err := s.do(ctx, path, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && s.auth == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := s.authenticate(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = s.do(ctx, path, accept); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s%s: %s", s.host, path, resp.Status)
+	}
+	return resp, nil
+}
+
+func (s *session) do(ctx context.Context, path, accept string) (*http.Response, error) {
+	scheme := "https"
+	if s.source.PlainHTTP {
+		scheme = "http"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+s.host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if s.auth != "" {
+		req.Header.Set("Authorization", s.auth)
+	}
+	return s.client().Do(req)
+}
+
+func (s *session) client() *http.Client {
+	if s.source.Client != nil {
+		return s.source.Client
+	}
+	return http.DefaultClient
+}
+
+// authenticate answers a Basic or Bearer challenge with the registry's credentials
+func (s *session) authenticate(ctx context.Context, challenge string) error {
+	username, password, err := s.credentials()
+	if err != nil {
+		return fmt.Errorf("failed to get credentials for %s: %w", s.host, err)
+	}
+
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if username == "" && password == "" {
+			return fmt.Errorf("registry %s requires credentials", s.host)
+		}
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(username, password)
+		s.auth = req.Header.Get("Authorization")
+		return nil
+	case "bearer":
+		token, err := s.token(ctx, params, username, password)
+		if err != nil {
+			return err
+		}
+		s.auth = "Bearer " + token
+		return nil
+	default:
+		return fmt.Errorf("registry %s asks for unsupported authentication %q", s.host, challenge)
+	}
+}
+
+// token requests a bearer token from the realm a challenge names
+func (s *session) token(ctx context.Context, params map[string]string, username, password string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("registry %s names an invalid token realm %q", s.host, params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a registry token: %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse the registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", errors.New("registry token response holds no token")
+}
+
+func (s *session) credentials() (string, string, error) {
+	if s.source.Credentials != nil {
+		return s.source.Credentials(s.host)
+	}
+	return dockerCredentials(s.host)
+}
+
+// parseChallenge splits a WWW-Authenticate header into its lower-cased scheme and parameters
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(rest, "=")
+		key = strings.ToLower(strings.Trim(key, " ,"))
+		rest = strings.TrimLeft(rest, " ")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		rest = strings.TrimLeft(rest, " ,")
+		if key != "" {
+			params[key] = value
+		}
+	}
+	return strings.ToLower(scheme), params
+}
@@ -0,0 +1,184 @@
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// testRegistry serves one repository behind token authentication for user:pass
+func testRegistry(t *testing.T, layers map[string][]byte) (*httptest.Server, []byte) {
+	t.Helper()
+	var descs []map[string]interface{}
+	blobs := make(map[string][]byte)
+	for title, content := range layers {
+		d := digestOf(content)
+		blobs[d] = content
+		descs = append(descs, map[string]interface{}{
+			"mediaType":   "application/octet-stream",
+			"digest":      d,
+			"size":        len(content),
+			"annotations": map[string]string{titleAnnotation: title},
+		})
+	}
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     mediaTypeOCIManifest,
+		"layers":        descs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" || r.URL.Query().Get("scope") != "repository:plugins/hello:pull" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			io.WriteString(w, `{"token":"t0k3n"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="test",scope="repository:plugins/hello:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v2/plugins/hello/manifests/1.0.0" || r.URL.Path == "/v2/plugins/hello/manifests/"+digestOf(manifest):
+			w.Header().Set("Content-Type", mediaTypeOCIManifest)
+			w.Write(manifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/plugins/hello/blobs/"):
+			blob, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/plugins/hello/blobs/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(blob)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, manifest
+}
+
+func TestSourceFetch(t *testing.T) {
+	content := []byte("plugin bytes")
+	srv, manifest := testRegistry(t, map[string][]byte{"hello.so": content})
+	host := strings.TrimPrefix(srv.URL, "https://")
+	src := &Source{
+		Client: srv.Client(),
+		Credentials: func(h string) (string, string, error) {
+			if h != host {
+				t.Errorf("Credentials(%q), want %q", h, host)
+			}
+			return "user", "pass", nil
+		},
+	}
+
+	for _, ref := range []string{
+		"oci://" + host + "/plugins/hello:1.0.0",
+		"oci://" + host + "/plugins/hello@" + digestOf(manifest),
+	} {
+		body, desc, err := src.Fetch(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("Fetch(%s) error = %v", ref, err)
+		}
+		got, _ := io.ReadAll(body)
+		body.Close()
+		if string(got) != string(content) {
+			t.Errorf("Fetch(%s) body = %q, want %q", ref, got, content)
+		}
+		if desc.Digest != digestOf(content) || desc.Size != int64(len(content)) || desc.Name != "hello.so" {
+			t.Errorf("Fetch(%s) descriptor = %+v", ref, desc)
+		}
+	}
+
+	// A pinned manifest digest must match what the registry serves
+	pinned := "oci://" + host + "/plugins/hello@" + digestOf([]byte("other"))
+	if _, _, err := src.Fetch(context.Background(), pinned); err == nil {
+		t.Error("Expected an error for a manifest that does not match its pinned digest")
+	}
+}
+
+func TestSourceFetch_SelectsLayer(t *testing.T) {
+	srv, _ := testRegistry(t, map[string][]byte{"hello.so": []byte("hello"), "world.so": []byte("world")})
+	host := strings.TrimPrefix(srv.URL, "https://")
+	src := &Source{Client: srv.Client(), Credentials: func(string) (string, string, error) { return "user", "pass", nil }}
+
+	if _, _, err := src.Fetch(context.Background(), "oci://"+host+"/plugins/hello:1.0.0"); err == nil || !strings.Contains(err.Error(), "2 layers") {
+		t.Errorf("Fetch() error = %v, want an ambiguous layer error", err)
+	}
+	body, desc, err := src.Fetch(context.Background(), "oci://"+host+"/plugins/hello:1.0.0#world.so")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	defer body.Close()
+	if got, _ := io.ReadAll(body); string(got) != "world" || desc.Name != "world.so" {
+		t.Errorf("Fetch() = %q, %+v, want the world.so layer", got, desc)
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want reference
+	}{
+		{"oci://localhost:5000/plugins/hello:1.0.0", reference{host: "localhost:5000", repository: "plugins/hello", reference: "1.0.0"}},
+		{"oci://ghcr.io/acme/hello", reference{host: "ghcr.io", repository: "acme/hello", reference: "latest"}},
+		{"oci://ghcr.io/acme/hello@sha256:abc#hello.so", reference{host: "ghcr.io", repository: "acme/hello", reference: "sha256:abc", file: "hello.so"}},
+		{"oci://docker.io/hello:1", reference{host: "registry-1.docker.io", repository: "library/hello", reference: "1"}},
+	}
+	for _, tt := range tests {
+		got, err := parseRef(tt.ref)
+		if err != nil || got != tt.want {
+			t.Errorf("parseRef(%q) = %+v, %v; want %+v", tt.ref, got, err, tt.want)
+		}
+	}
+	for _, ref := range []string{"oci://ghcr.io", "https://ghcr.io/acme/hello", "oci://ghcr.io/acme/hello:"} {
+		if _, err := parseRef(ref); err == nil {
+			t.Errorf("parseRef(%q) succeeded, want an error", ref)
+		}
+	}
+}
+
+func TestDockerCredentials(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	config := `{"auths": {
+		"registry.example.com": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("user:pa:ss")) + `"},
+		"https://index.docker.io/v1/": {"username": "hub", "password": "secret"}
+	}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct{ host, user, pass string }{
+		{"registry.example.com", "user", "pa:ss"},
+		{"docker.io", "hub", "secret"},
+		{"registry-1.docker.io", "hub", "secret"},
+		{"other.example.com", "", ""},
+	}
+	for _, tt := range tests {
+		user, pass, err := dockerCredentials(tt.host)
+		if err != nil || user != tt.user || pass != tt.pass {
+			t.Errorf("dockerCredentials(%q) = %q, %q, %v; want %q, %q", tt.host, user, pass, err, tt.user, tt.pass)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestSourceFetch(t *testing.T) {
+	content := []byte("plugin bytes")
+	sum := sha256.Sum256(content)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/artifacts/plugins/hello.so" {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Header().Set("x-amz-checksum-sha256", base64.StdEncoding.EncodeToString(sum[:]))
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	src := NewFromClient(awss3.New(awss3.Options{
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+	}))
+
+	body, desc, err := src.Fetch(context.Background(), "s3://artifacts/plugins/hello.so")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	defer body.Close()
+	got, err := io.ReadAll(body)
+	if err != nil || string(got) != string(content) {
+		t.Fatalf("Fetch() body = %q, %v", got, err)
+	}
+	if want := "sha256:" + hex.EncodeToString(sum[:]); desc.Digest != want || desc.Size != int64(len(content)) || desc.Name != "hello.so" {
+		t.Errorf("Fetch() descriptor = %+v, want digest %s, size %d and name hello.so", desc, want, len(content))
+	}
+
+	if _, _, err := src.Fetch(context.Background(), "s3://artifacts/plugins/missing.so"); err == nil {
+		t.Error("Expected an error for a missing object")
+	}
+	if _, _, err := src.Fetch(context.Background(), "s3://artifacts"); err == nil {
+		t.Error("Expected an error for a URL without a key")
+	}
+}